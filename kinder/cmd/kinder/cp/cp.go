@@ -44,7 +44,9 @@ func NewCommand() *cobra.Command {
 			"    @cpN 	the secondary control plane nodes \n" +
 			"    @w* 	all the worker nodes\n" +
 			"    @lb 	the external load balancer\n" +
-			"    @etcd 	the external etcd",
+			"    @etcd 	the external etcd\n" +
+			"    @registry 	the external registry\n" +
+			"  NODE_NAME and NODE_SELECTOR can be prefixed by clustername/ for targeting another cluster, e.g. cluster2/@cp1",
 		Short: "Copy files/folders between a node and the local filesystem",
 		Long:  "kinder cp is a \"topology aware\" wrapper on docker cp",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -17,11 +17,19 @@ limitations under the License.
 package cluster
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
 const (
@@ -29,15 +37,91 @@ const (
 	workerNodesFlagName       = "worker-nodes"
 )
 
+// topologyFlagNames are the flags defining settings also defined in a topology file, that can't be used with --topology
+var topologyFlagNames = []string{
+	controlPlaneNodesFlagName, workerNodesFlagName, "bootstrap-control-plane", "image",
+	"external-etcd", "external-etcd-image", "external-etcd-data-dir", "external-etcd-nodes", "external-load-balancer", "load-balancer", "external-registry",
+	"extra-port-mappings", "extra-port-mappings-role", "apiserver-bind-port", "network", "dns", "dns-search", "dns-option",
+	"worker-labels", "worker-taints", "worker-pool", "node-command", "node-name",
+	"apiserver-extra-args", "controller-manager-extra-args", "scheduler-extra-args", "apiserver-tls-min-version", "apiserver-tls-cipher-suites",
+	"control-plane-cpus", "control-plane-memory", "worker-cpus", "worker-memory",
+}
+
 type flagpole struct {
-	Name                 string
-	ImageName            string
-	Workers              int
-	ControlPlanes        int
-	Retain               bool
-	ExternalEtcd         bool
-	ExternalLoadBalancer bool
-	Volumes              []string
+	Name                       string
+	ImageName                  string
+	Workers                    int
+	ControlPlanes              int
+	BootstrapControlPlane      int
+	Retain                     bool
+	Recreate                   bool
+	ExternalEtcd               bool
+	ExternalLoadBalancer       bool
+	LoadBalancer               string
+	Volumes                    []string
+	ExtraPortMappings          []string
+	ExtraPortMappingsRoles     []string
+	APIServerBindPort          int32
+	ExternalEtcdImage          string
+	ExternalEtcdDataDir        string
+	ExternalEtcdNodes          int
+	ExternalEtcdEndpoints      []string
+	ExternalEtcdCAFile         string
+	ExternalEtcdCertFile       string
+	ExternalEtcdKeyFile        string
+	Output                     string
+	WorkerLabels               []string
+	WorkerTaints               []string
+	WorkerPools                []string
+	ExternalRegistry           bool
+	NodeCommands               []string
+	NodeNames                  []string
+	SandboxImage               string
+	APIServerExtraArgs         []string
+	ControllerManagerExtraArgs []string
+	SchedulerExtraArgs         []string
+	DNS                        []string
+	DNSSearch                  []string
+	DNSOptions                 []string
+	ControlPlaneCPUs           string
+	ControlPlaneMemory         string
+	WorkerCPUs                 string
+	WorkerMemory               string
+	Network                    string
+	IPFamily                   string
+	EvictionHard               []string
+	NodeSysctls                []string
+	EvictionSoft               []string
+	EvictionSoftGracePeriod    time.Duration
+	RelaxEviction              bool
+	KubeProxyMode              string
+	SkipKubeProxy              bool
+	NodeCIDRMaskSize           int
+	NodeCIDRMaskSizeIPv6       int
+	CoreDNSImage               string
+	CoreDNSReplicas            int
+	EtcdVersion                string
+	EtcdQuotaBackendBytes      string
+	EtcdAutoCompactionMode     string
+	EtcdAutoCompactionRetain   string
+	Timeout                    time.Duration
+	TLSMinVersion              string
+	TLSCipherSuites            []string
+	ClusterSigningDuration     time.Duration
+	KubeletServingCertRotation bool
+	ServiceAccountIssuer       string
+	ServiceAccountKey          string
+	CACert                     string
+	CAKey                      string
+	EncryptionProvider         string
+	AuditPolicy                string
+	AuditLogPath               string
+	BootstrapManifests         []string
+	WaitDaemonSets             []string
+	CNI                        string
+	KubeadmClusterName         string
+	IgnorePreflightErrors      string
+	Topology                   string
 }
 
 // NewCommand returns a new cobra.Command for cluster creation
@@ -63,11 +147,21 @@ func NewCommand() *cobra.Command {
 		controlPlaneNodesFlagName, 1,
 		"number of control-plane nodes in the cluster",
 	)
+	cmd.Flags().IntVar(
+		&flags.BootstrapControlPlane,
+		"bootstrap-control-plane", 0,
+		"number of the control-plane node where kubeadm init is executed, e.g. 2; defaults to the first control-plane node",
+	)
 	cmd.Flags().IntVar(
 		&flags.Workers,
 		workerNodesFlagName, 0,
 		"number of worker nodes in the cluster",
 	)
+	cmd.Flags().StringVar(
+		&flags.Topology,
+		"topology", "",
+		"a kinder topology file defining roles, counts, images, labels, taints and networking of the cluster; it can't be used with the flags defining the same settings",
+	)
 	cmd.Flags().StringVar(
 		&flags.ImageName,
 		"image", "",
@@ -78,22 +172,346 @@ func NewCommand() *cobra.Command {
 		"retain", false,
 		"retain nodes for debugging when cluster creation fails",
 	)
+	cmd.Flags().BoolVar(
+		&flags.Recreate,
+		"recreate", false,
+		"delete the existing cluster with the same name, if any, after validating the flags for the new cluster; the node image and the network of the existing cluster are reused if not set",
+	)
 	cmd.Flags().BoolVar(
 		&flags.ExternalEtcd,
 		"external-etcd", false,
 		"create an external etcd container and setup kubeadm for using it",
 	)
+	cmd.Flags().StringVar(
+		&flags.ExternalEtcdImage,
+		"external-etcd-image", "",
+		"the image, or the image tag, for the external etcd (default to the etcd image required by the Kubernetes version in the node image)",
+	)
+	cmd.Flags().StringVar(
+		&flags.ExternalEtcdDataDir,
+		"external-etcd-data-dir", "",
+		"a host directory to be mounted as data dir for the external etcd",
+	)
+	cmd.Flags().IntVar(
+		&flags.ExternalEtcdNodes,
+		"external-etcd-nodes", 0,
+		"the number of members of the external etcd cluster, that must be odd (default 1)",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.ExternalEtcdEndpoints,
+		"external-etcd-endpoints", nil,
+		"the client endpoints of an existing external etcd not managed by kinder, e.g. https://10.0.0.10:2379; the endpoints must be reachable from the nodes",
+	)
+	cmd.Flags().StringVar(
+		&flags.ExternalEtcdCAFile,
+		"external-etcd-ca-file", "",
+		"a host file with the CA certificate of the external etcd, required with https --external-etcd-endpoints",
+	)
+	cmd.Flags().StringVar(
+		&flags.ExternalEtcdCertFile,
+		"external-etcd-cert-file", "",
+		"a host file with the client certificate used by the API server for the external etcd, required with https --external-etcd-endpoints",
+	)
+	cmd.Flags().StringVar(
+		&flags.ExternalEtcdKeyFile,
+		"external-etcd-key-file", "",
+		"a host file with the key of the external etcd client certificate, required with https --external-etcd-endpoints",
+	)
 	cmd.Flags().BoolVar(
 		&flags.ExternalLoadBalancer,
 		"external-load-balancer", false,
 		"add an external load balancer to the cluster (implicit if number of control-plane nodes>1)",
 	)
+	cmd.Flags().StringVar(
+		&flags.LoadBalancer,
+		"load-balancer", "",
+		"the implementation of the external load balancer, one of [haproxy, nginx, envoy] (default haproxy)",
+	)
+	cmd.Flags().BoolVar(
+		&flags.ExternalRegistry,
+		"external-registry", false,
+		"add a local image registry to the cluster, and configure containerd on the nodes for using it as a mirror for all the registries",
+	)
 	cmd.Flags().StringSliceVar(
 		&flags.Volumes,
 		"volume", nil,
 		"mount a volume on node containers",
 	)
 
+	cmd.Flags().StringArrayVar(
+		&flags.ExtraPortMappings,
+		"extra-port-mappings", nil,
+		"map a node container port to the host, in the containerPort:hostPort[:protocol] form (protocol is one of tcp, udp; default tcp)",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.ExtraPortMappingsRoles,
+		"extra-port-mappings-role", []string{constants.WorkerNodeRoleValue},
+		fmt.Sprintf("the role of the nodes extra port mappings should be applied to. Use one of [%s, %s]", constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue),
+	)
+
+	cmd.Flags().Int32Var(
+		&flags.APIServerBindPort,
+		"apiserver-bind-port", constants.APIServerPort,
+		"the port the API server binds to on control-plane nodes",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&flags.WorkerLabels,
+		"worker-labels", nil,
+		"a Kubernetes label, in the key=value form, to be assigned to worker nodes at join time",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.WorkerPools,
+		"worker-pool", nil,
+		"a pool of worker nodes, in the name=NAME;count=N[;labels=key=value,...][;taints=key=value:Effect,...] form, with Kubernetes labels and taints assigned at join time",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.WorkerTaints,
+		"worker-taints", nil,
+		"a Kubernetes taint, in the key=value:Effect form, to be assigned to worker nodes at join time",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.NodeCommands,
+		"node-command", nil,
+		fmt.Sprintf("override the container command of the nodes, in the TARGET=COMMAND form, where TARGET is a role [%s, %s] or a node name; the command is executed by /bin/sh and it must end with \"exec %s\"",
+			constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue, constants.NodeEntrypoint),
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.NodeNames,
+		"node-name", nil,
+		"set the Kubernetes node name used at init/join time for a node, in the NODE=NAME form, where NODE is the name of a node container and NAME a DNS-1123 label; by default the container name is used",
+	)
+	cmd.Flags().StringVar(
+		&flags.SandboxImage,
+		"sandbox-image", "",
+		"the CRI sandbox (pause) image to be used by the container runtime in the nodes; only the containerd runtime is supported",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.APIServerExtraArgs,
+		"apiserver-extra-args", nil,
+		"an extra arg, in the key=value form, for the API server; values for a repeated key are joined in a comma separated list",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.ControllerManagerExtraArgs,
+		"controller-manager-extra-args", nil,
+		"an extra arg, in the key=value form, for the controller manager; values for a repeated key are joined in a comma separated list",
+	)
+	cmd.Flags().StringVar(
+		&flags.TLSMinVersion,
+		"apiserver-tls-min-version", "",
+		"the minimum TLS version supported by the API server. Use one of [VersionTLS10, VersionTLS11, VersionTLS12, VersionTLS13]",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.TLSCipherSuites,
+		"apiserver-tls-cipher-suites", nil,
+		"the cipher suites for the API server; they must be compatible with the key type used by kubeadm, that is checked at init time",
+	)
+	cmd.Flags().DurationVar(
+		&flags.ClusterSigningDuration,
+		"cluster-signing-duration", 0,
+		"the duration of the certificates signed by the controller manager, e.g. the kubelet client certificates; e.g. 1h",
+	)
+	cmd.Flags().BoolVar(
+		&flags.KubeletServingCertRotation,
+		"kubelet-serving-cert-rotation", false,
+		"enable serverTLSBootstrap in the KubeletConfiguration, so the kubelet serving certificates are signed by the controller manager and rotated; kinder approves the certificate signing requests at init and join time, and the kubelet-serving-csr-approve action approves the ones created by the rotation",
+	)
+	cmd.Flags().StringVar(
+		&flags.ServiceAccountIssuer,
+		"service-account-issuer", "",
+		"the issuer of the service account tokens, set as the API server service-account-issuer extra arg; e.g. https://issuer.example.com",
+	)
+	cmd.Flags().StringVar(
+		&flags.ServiceAccountKey,
+		"service-account-key", "",
+		"a file on the host with an existing service account signing key, RSA or ECDSA in PEM form, to be used instead of generating a new one",
+	)
+	cmd.Flags().StringVar(
+		&flags.CACert,
+		"ca-cert", "",
+		"a file on the host with an existing CA certificate in PEM form, that kubeadm uses for signing the cluster certificates instead of generating a new CA; it requires --ca-key",
+	)
+	cmd.Flags().StringVar(
+		&flags.CAKey,
+		"ca-key", "",
+		"a file on the host with the key of the --ca-cert CA, RSA or ECDSA in PEM form",
+	)
+	cmd.Flags().StringVar(
+		&flags.EncryptionProvider,
+		"encryption-provider", "",
+		"the provider for encrypting secrets at rest, one of [aescbc, secretbox]; an encryption config with a random key is generated",
+	)
+	cmd.Flags().StringVar(
+		&flags.AuditPolicy,
+		"audit-policy", "",
+		"a file on the host with the API server audit policy; enables audit logging, with a policy logging the metadata of all the requests if not set",
+	)
+	cmd.Flags().StringVar(
+		&flags.AuditLogPath,
+		"audit-log-path", "",
+		"the path of the API server audit log on the control-plane nodes; enables audit logging, with /var/log/kubernetes/audit/audit.log as a default",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.BootstrapManifests,
+		"bootstrap-manifest", nil,
+		"a manifest, as a host path or an http(s) URL, to be applied by kubeadm-init once the control-plane is ready, e.g. a CNI plugin; manifests are applied in order",
+	)
+	cmd.Flags().StringVar(
+		&flags.IgnorePreflightErrors,
+		"ignore-preflight-errors", "",
+		"list of kubeadm preflight errors to be skipped by kubeadm-init, e.g. Swap; defaults to "+constants.KubeadmIgnorePreflightErrors,
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.WaitDaemonSets,
+		"wait-daemonset", nil,
+		"a DaemonSet, in the [namespace/]name form, to be waited for rolling out on all the nodes after the bootstrap manifests are applied and after join, e.g. kube-system/calico-node",
+	)
+	cmd.Flags().StringVar(
+		&flags.CNI,
+		"cni", "",
+		fmt.Sprintf("the CNI plugin installed by kubeadm-init, one of %s, or a custom manifest as a host path or an http(s) URL (default %s)", cni.Known(), cni.Default),
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeadmClusterName,
+		"kubeadm-cluster-name", "",
+		"the cluster name in the kubeadm ClusterConfiguration and in the kubeconfig files generated by kubeadm, a valid DNS label; defaults to the cluster name",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.SchedulerExtraArgs,
+		"scheduler-extra-args", nil,
+		"an extra arg, in the key=value form, for the scheduler; values for a repeated key are joined in a comma separated list",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.DNS,
+		"dns", nil,
+		"a DNS server for the nodes, overriding the DNS servers inherited from the host",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.DNSSearch,
+		"dns-search", nil,
+		"a DNS search domain for the nodes, overriding the search domains inherited from the host",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.DNSOptions,
+		"dns-option", nil,
+		"a DNS resolver option for the nodes, e.g. ndots:1, overriding the options inherited from the host",
+	)
+	cmd.Flags().StringVar(
+		&flags.ControlPlaneCPUs,
+		"control-plane-cpus", "",
+		"the CPU limit for the control-plane node containers, e.g. 1.5",
+	)
+	cmd.Flags().StringVar(
+		&flags.ControlPlaneMemory,
+		"control-plane-memory", "",
+		"the memory limit for the control-plane node containers, with an optional b, k, m or g unit, e.g. 2g",
+	)
+	cmd.Flags().StringVar(
+		&flags.WorkerCPUs,
+		"worker-cpus", "",
+		"the CPU limit for the worker node containers, e.g. 1.5",
+	)
+	cmd.Flags().StringVar(
+		&flags.WorkerMemory,
+		"worker-memory", "",
+		"the memory limit for the worker node containers, with an optional b, k, m or g unit, e.g. 2g",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.NodeSysctls,
+		"node-sysctls", nil,
+		"a sysctl set on the K8s nodes before the kubelet starts and at every node restart, in the key=value form, e.g. net.core.somaxconn=1024",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.EvictionHard,
+		"eviction-hard", nil,
+		"a kubelet hard eviction threshold, in the signal<quantity form, e.g. memory.available<100Mi",
+	)
+	cmd.Flags().StringArrayVar(
+		&flags.EvictionSoft,
+		"eviction-soft", nil,
+		"a kubelet soft eviction threshold, in the signal<quantity form, e.g. nodefs.available<5%; requires --eviction-soft-grace-period",
+	)
+	cmd.Flags().DurationVar(
+		&flags.EvictionSoftGracePeriod,
+		"eviction-soft-grace-period", 0,
+		"the grace period for the kubelet soft eviction thresholds",
+	)
+	cmd.Flags().BoolVar(
+		&flags.RelaxEviction,
+		"relax-eviction", false,
+		"use CI friendly kubelet hard eviction thresholds, preventing small nodes from flapping into NotReady under disk pressure",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeProxyMode,
+		"kube-proxy-mode", "",
+		"the kube-proxy mode. Use one of [iptables, ipvs, nftables]; this flag can't be used with --skip-kube-proxy",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SkipKubeProxy,
+		"skip-kube-proxy", false,
+		"do not install the kube-proxy addon, e.g. when testing a CNI replacing kube-proxy; this is preserved across upgrades",
+	)
+	cmd.Flags().IntVar(
+		&flags.NodeCIDRMaskSize,
+		"node-cidr-mask-size", 0,
+		"the size of the IPv4 pod CIDR allocated to each node, e.g. 26, used by ipv4 and dual-stack clusters only; it must allow a pod CIDR for each node within the 192.168.0.0/16 pod subnet",
+	)
+	cmd.Flags().IntVar(
+		&flags.NodeCIDRMaskSizeIPv6,
+		"node-cidr-mask-size-ipv6", 0,
+		"the size of the IPv6 pod CIDR allocated to each node, e.g. 64, used by ipv6 and dual-stack clusters only; it must allow a pod CIDR for each node within the fd00:10:244::/56 pod subnet",
+	)
+	cmd.Flags().StringVar(
+		&flags.CoreDNSImage,
+		"coredns-image", "",
+		"the CoreDNS image in the repository/coredns:tag form, e.g. for air-gapped tests; the image should be pre-loaded in the node image",
+	)
+	cmd.Flags().IntVar(
+		&flags.CoreDNSReplicas,
+		"coredns-replicas", 0,
+		"the number of CoreDNS replicas; the CoreDNS deployment is scaled after init",
+	)
+	cmd.Flags().StringVar(
+		&flags.EtcdVersion,
+		"etcd-version", "",
+		"the local etcd image tag, e.g. 3.5.12-0, for using an etcd version other than the default for the Kubernetes version; the image should be pre-loaded in the node image",
+	)
+	cmd.Flags().StringVar(
+		&flags.EtcdQuotaBackendBytes,
+		"etcd-quota-backend-bytes", "",
+		"the etcd backend quota as a quantity, e.g. 2Gi; it applies both to the local etcd and to the external etcd",
+	)
+	cmd.Flags().StringVar(
+		&flags.EtcdAutoCompactionMode,
+		"etcd-auto-compaction-mode", "",
+		"the etcd auto-compaction mode [periodic, revision]; it requires --etcd-auto-compaction-retention",
+	)
+	cmd.Flags().StringVar(
+		&flags.EtcdAutoCompactionRetain,
+		"etcd-auto-compaction-retention", "",
+		"the etcd auto-compaction retention, that is a duration, e.g. 1h, or a number of hours for the periodic mode, and a number of revisions for the revision mode",
+	)
+	cmd.Flags().StringVar(
+		&flags.Network,
+		"network", "",
+		"the user defined docker network for the cluster containers; the network is created if it does not exist",
+	)
+	cmd.Flags().StringVar(
+		&flags.IPFamily,
+		"ip-family", "ipv4",
+		"the IP family of the cluster, one of ipv4, ipv6 or dualstack; ipv6 and dualstack require a --network with IPv6 enabled",
+	)
+	cmd.Flags().DurationVar(
+		&flags.Timeout,
+		"timeout", 0,
+		"the overall deadline for cluster creation, e.g. 5m; when exceeded, cluster creation fails and nodes are deleted unless --retain is set",
+	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "",
+		"on success, print a summary of the created cluster in the given format. Use one of [json, yaml]",
+	)
+
 	cmd.MarkFlagRequired("image")
 
 	return cmd
@@ -106,19 +524,118 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		return errors.Errorf("flags --%s and --%s should not be a negative number", controlPlaneNodesFlagName, workerNodesFlagName)
 	}
 
+	if flags.Output != "" && flags.Output != "json" && flags.Output != "yaml" {
+		return errors.Errorf("invalid output format %q. Use one of [json, yaml]", flags.Output)
+	}
+
+	var extraPortMappings []status.PortMapping
+	for _, v := range flags.ExtraPortMappings {
+		p, err := status.ParsePortMapping(v)
+		if err != nil {
+			return err
+		}
+		extraPortMappings = append(extraPortMappings, p)
+	}
+
+	// options from the topology file are applied after the options from flags, so they take precedence
+	// over the flag defaults; flags defining the same settings are rejected
+	var topologyOptions []manager.CreateOption
+	if flags.Topology != "" {
+		for _, name := range topologyFlagNames {
+			if cmd.Flags().Changed(name) {
+				return errors.Errorf("flag --%s can't be used with --topology, set it in the topology file instead", name)
+			}
+		}
+		topology, err := manager.LoadTopology(flags.Topology)
+		if err != nil {
+			return err
+		}
+		if topologyOptions, err = topology.CreateOptions(); err != nil {
+			return err
+		}
+	}
+
 	// get a kinder cluster manager
-	if err = manager.CreateCluster(
-		flags.Name,
+	options := []manager.CreateOption{
 		manager.ControlPlanes(flags.ControlPlanes),
+		manager.BootstrapControlPlane(flags.BootstrapControlPlane),
 		manager.Workers(flags.Workers),
 		manager.Image(flags.ImageName),
 		manager.ExternalLoadBalancer(flags.ExternalLoadBalancer),
+		manager.LoadBalancer(flags.LoadBalancer),
 		manager.ExternalEtcd(flags.ExternalEtcd),
+		manager.ExternalEtcdImage(flags.ExternalEtcdImage),
+		manager.ExternalEtcdDataDir(flags.ExternalEtcdDataDir),
+		manager.ExternalEtcdNodes(flags.ExternalEtcdNodes),
+		manager.ExternalEtcdEndpoints(flags.ExternalEtcdEndpoints, flags.ExternalEtcdCAFile, flags.ExternalEtcdCertFile, flags.ExternalEtcdKeyFile),
+		manager.ExternalRegistry(flags.ExternalRegistry),
 		manager.Retain(flags.Retain),
+		manager.Recreate(flags.Recreate),
 		manager.Volumes(flags.Volumes),
-	); err != nil {
+		manager.ExtraPortMappings(extraPortMappings, flags.ExtraPortMappingsRoles...),
+		manager.APIServerBindPort(flags.APIServerBindPort),
+		manager.WorkerLabels(flags.WorkerLabels),
+		manager.WorkerTaints(flags.WorkerTaints),
+		manager.WorkerPools(flags.WorkerPools),
+		manager.NodeCommands(flags.NodeCommands),
+		manager.NodeNames(flags.NodeNames),
+		manager.SandboxImage(flags.SandboxImage),
+		manager.DNS(flags.DNS, flags.DNSSearch, flags.DNSOptions),
+		manager.Resources(
+			status.NodeResources{CPUs: flags.ControlPlaneCPUs, Memory: flags.ControlPlaneMemory},
+			status.NodeResources{CPUs: flags.WorkerCPUs, Memory: flags.WorkerMemory},
+		),
+		manager.Network(flags.Network),
+		manager.IPFamily(flags.IPFamily),
+		manager.KubeletEviction(flags.EvictionHard, flags.EvictionSoft, flags.EvictionSoftGracePeriod, flags.RelaxEviction),
+		manager.NodeSysctls(flags.NodeSysctls),
+		manager.ControlPlaneExtraArgs(flags.APIServerExtraArgs, flags.ControllerManagerExtraArgs, flags.SchedulerExtraArgs),
+		manager.KubeProxy(flags.KubeProxyMode, flags.SkipKubeProxy),
+		manager.NodeCIDRMaskSize(flags.NodeCIDRMaskSize, flags.NodeCIDRMaskSizeIPv6),
+		manager.CoreDNS(flags.CoreDNSImage, flags.CoreDNSReplicas),
+		manager.EtcdVersion(flags.EtcdVersion),
+		manager.EtcdQuotaAndCompaction(flags.EtcdQuotaBackendBytes, flags.EtcdAutoCompactionMode, flags.EtcdAutoCompactionRetain),
+		manager.Timeout(flags.Timeout),
+		manager.APIServerTLS(flags.TLSMinVersion, flags.TLSCipherSuites),
+		manager.ClusterSigningDuration(flags.ClusterSigningDuration),
+		manager.KubeletServingCertRotation(flags.KubeletServingCertRotation),
+		manager.ServiceAccount(flags.ServiceAccountIssuer, flags.ServiceAccountKey),
+		manager.ClusterCA(flags.CACert, flags.CAKey),
+		manager.EncryptionAtRest(flags.EncryptionProvider),
+		manager.AuditLogging(flags.AuditPolicy, flags.AuditLogPath),
+		manager.BootstrapManifests(flags.BootstrapManifests),
+		manager.WaitDaemonSets(flags.WaitDaemonSets),
+		manager.CNI(flags.CNI),
+		manager.KubeadmClusterName(flags.KubeadmClusterName),
+		manager.IgnorePreflightErrors(flags.IgnorePreflightErrors),
+	}
+	options = append(options, topologyOptions...)
+
+	// with a machine readable output, progress messages and logs go to stderr so stdout can be parsed
+	if flags.Output != "" {
+		options = append(options, manager.ProgressOutput(os.Stderr))
+		exec.SetEchoOutput(os.Stderr)
+		log.SetOutput(os.Stderr)
+	}
+	if err = manager.CreateCluster(flags.Name, options...); err != nil {
 		return errors.Wrap(err, "failed to create cluster")
 	}
 
+	if flags.Output != "" {
+		c, err := status.FromDocker(flags.Name)
+		if err != nil {
+			return err
+		}
+		summary, err := c.Summary()
+		if err != nil {
+			return err
+		}
+		out, err := summary.Format(flags.Output)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	}
+
 	return nil
 }
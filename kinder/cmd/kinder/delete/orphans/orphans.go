@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package orphans implements the `orphans` command for verifying that no containers are left behind by delete
+package orphans
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+type flagpole struct {
+	Name   string
+	Remove bool
+}
+
+// NewCommand returns a new cobra.Command for verifying that no orphaned containers are left behind by delete
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "orphans",
+		Short: "Verifies that no orphaned containers are left behind by delete",
+		Long: "Lists the containers named like the nodes of a cluster, that is with the cluster name followed by a dash " +
+			"as a prefix, but without the cluster label, e.g. an external etcd created before the label was applied; " +
+			"such containers are not deleted by kinder delete cluster. The command fails if orphaned containers " +
+			"are found, unless they are deleted with --remove",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.Name, "name",
+		constants.DefaultClusterName,
+		"cluster name",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Remove, "remove",
+		false, "delete the orphaned containers, together with their volumes",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	orphans, err := manager.OrphanContainers(flags.Name, flags.Remove)
+	if err != nil {
+		return errors.Wrapf(err, "failed to verify orphaned containers for cluster %s", flags.Name)
+	}
+	if len(orphans) == 0 {
+		fmt.Printf("No orphaned containers found for cluster %s\n", flags.Name)
+		return nil
+	}
+	if flags.Remove {
+		fmt.Printf("Deleted orphaned containers for cluster %s: %s\n", flags.Name, strings.Join(orphans, ", "))
+		return nil
+	}
+	return errors.Errorf("found orphaned containers for cluster %s: %s. Use --remove for deleting them", flags.Name, strings.Join(orphans, ", "))
+}
@@ -28,23 +28,82 @@ import (
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
 	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/failure"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
 )
 
 type flagpole struct {
-	Name                  string
-	UsePhases             bool
-	UpgradeVersion        string
-	CopyCerts             string
-	Discovery             string
-	OnlyNode              string
-	DryRun                bool
-	VLevel                int
-	PatchesDir            string
-	Wait                  time.Duration
-	IgnorePreflightErrors string
-	KubeadmConfigVersion  string
-	FeatureGate           string
-	EncryptionAlgorithm   string
+	Name                   string
+	UsePhases              bool
+	UpgradeVersion         string
+	UpgradeNodes           []string
+	PartitionNodes         []string
+	PullImages             []string
+	DigestImages           []string
+	Drain                  bool
+	DrainIgnoreDaemonSets  bool
+	DrainDeleteEmptyDir    bool
+	DrainGracePeriod       time.Duration
+	PullNodes              []string
+	ChaosComponent         string
+	ChaosNode              string
+	ChaosVerify            bool
+	CopyCerts              string
+	Discovery              string
+	OnlyNode               string
+	IncludeIneligible      bool
+	BootstrapControlPlane  string
+	DryRun                 bool
+	VLevel                 int
+	PatchesDir             string
+	Wait                   time.Duration
+	IgnorePreflightErrors  string
+	KubeadmConfigVersion   string
+	FeatureGate            string
+	EncryptionAlgorithm    string
+	DNSDomain              string
+	WaitCoreDNS            bool
+	WaitAllNodes           bool
+	RefreshCertsAfter      time.Duration
+	JoinParallelism        int
+	CgroupDriver           string
+	CRISocket              string
+	Force                  bool
+	Fix                    bool
+	APIServerCertSANs      []string
+	CorruptCertMode        string
+	APIResources           []string
+	KubeadmOutputDir       string
+	SnapshotPath           string
+	CollectDir             string
+	MigrateConfig          string
+	KubeConfigSA           string
+	KubeConfigUser         string
+	KubeConfigGroups       []string
+	KubeConfigOutput       string
+	MigrateOutput          string
+	SkipVersionSkewCheck   bool
+	ValidateConfig         bool
+	SmokeTestImage         string
+	SmokeTestService       bool
+	DNSCheckImage          string
+	DNSCheckExternalName   string
+	KubeletServingCSRWatch bool
+	E2EFocus               string
+	E2ESkip                string
+	E2EImage               string
+	E2EBinary              string
+	E2EResultsDir          string
+	PodStartupLatency      bool
+	PodStartupImage        string
+	PodStartupPrePull      bool
+	PodStartupMaxLatency   time.Duration
+	TLSMinVersion          string
+	TLSCipherSuites        []string
+	KubeadmBinary          string
+	KubeadmBinaryPath      string
+	ResourceUsageFile      string
+	ResourceUsageInterval  time.Duration
 }
 
 // NewCommand returns a new cobra.Command for exec
@@ -73,6 +132,16 @@ func NewCommand() *cobra.Command {
 		"only-node",
 		"", "exec the action only on the selected node",
 	)
+	cmd.Flags().BoolVar(
+		&flags.IncludeIneligible,
+		"include-ineligible", false,
+		"exec the action also on nodes excluded from actions by the io.x-k8s.kinder.ineligible=true docker label or the /kinder/ineligible file",
+	)
+	cmd.Flags().StringVar(
+		&flags.BootstrapControlPlane,
+		"bootstrap-control-plane", "",
+		"name of the control-plane node used as bootstrap control plane, overriding the io.x-k8s.kinder.bootstrap-control-plane=true docker label",
+	)
 	cmd.Flags().BoolVar(
 		&flags.DryRun,
 		"dry-run", false,
@@ -82,11 +151,203 @@ func NewCommand() *cobra.Command {
 		&flags.UsePhases, "use-phases",
 		false, "use the kubeadm phases subcommands instead of the kubeadm top-level commands",
 	)
+	cmd.Flags().BoolVar(
+		&flags.WaitCoreDNS, "wait-coredns",
+		false, "after join, wait for CoreDNS to be ready; this requires a working CNI plugin",
+	)
+	cmd.Flags().BoolVar(
+		&flags.WaitAllNodes, "wait-all-nodes",
+		false, "after join, wait for all the nodes in the cluster to be Ready",
+	)
+	cmd.Flags().DurationVar(
+		&flags.RefreshCertsAfter, "refresh-certs-after",
+		0, "with --copy-certs=auto, refresh the uploaded certificates before joining a control-plane node if this interval elapsed since the last refresh; 0 disables refreshes",
+	)
+	cmd.Flags().IntVar(
+		&flags.JoinParallelism, "join-parallelism",
+		4, "the maximum number of worker nodes joining at the same time; control-plane nodes always join one at a time",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Force, "force",
+		false, "skip safety checks, e.g. the etcd quorum check when removing an external etcd member or the check for the last schedulable node when draining; for kubeadm-join, reset and join again nodes already joined",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Drain, "drain",
+		false, "for kubeadm-reset and kubeadm-upgrade, cordon and drain nodes before disrupting them; with kubeadm-upgrade, nodes are uncordoned after the upgrade",
+	)
+	cmd.Flags().BoolVar(
+		&flags.DrainIgnoreDaemonSets, "drain-ignore-daemonsets",
+		true, "with --drain, ignore DaemonSet-managed pods",
+	)
+	cmd.Flags().BoolVar(
+		&flags.DrainDeleteEmptyDir, "drain-delete-emptydir-data",
+		true, "with --drain, evict also pods using emptyDir volumes, deleting the local data",
+	)
+	cmd.Flags().DurationVar(
+		&flags.DrainGracePeriod, "drain-grace-period",
+		-1*time.Second, "with --drain, the termination grace period for evicted pods; a negative value uses the pod default",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Fix, "fix",
+		false, "attempt to fix the issues detected, e.g. load the missing kernel modules and set the missing sysctls in preflight",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.APIServerCertSANs, "apiserver-cert-sans",
+		nil, "the only SANs for the API server certificate generated by the apiserver-cert action, in addition to the API server virtual IP",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeadmOutputDir, "kubeadm-output-dir",
+		"", "the host directory where the output of kubeadm init and join is written, one file for each node, normalized so the output of two runs can be compared with kinder get kubeadm-output-diff",
+	)
+	cmd.Flags().StringVar(
+		&flags.CorruptCertMode, "corrupt-cert-mode",
+		string(actions.CorruptCertExpired), fmt.Sprintf("how the corrupt-cert action corrupts the API server certificate. Use one of %v", actions.KnownCorruptCertModes()),
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.APIResources, "api-resources",
+		nil, "the resources the api-resources-check action expects to be served, in the groupVersion/resource form, e.g. batch/v1/cronjobs; use the ! prefix for resources expected to not be served",
+	)
+	cmd.Flags().StringVar(
+		&flags.SnapshotPath, "snapshot-path",
+		"", "the host path of the etcd snapshot saved by the etcd-snapshot action or used by the etcd-restore action",
+	)
+	cmd.Flags().StringVar(
+		&flags.CollectDir, "collect-dir",
+		"", "the host directory where the collect-config and audit-log actions save the collected artifacts",
+	)
+	cmd.Flags().StringVar(
+		&flags.MigrateConfig, "migrate-config",
+		"", "the host path of the kubeadm config migrated by the kubeadm-config-migrate action",
+	)
+	cmd.Flags().StringVar(
+		&flags.MigrateOutput, "migrate-output",
+		"", "the host path where the kubeadm-config-migrate action writes the migrated kubeadm config; if not set, the migrated config is printed",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeConfigSA, "kubeconfig-service-account",
+		"", "the service account, in the namespace/name form, for the kubeconfig generated by the scoped-kubeconfig action",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeConfigUser, "kubeconfig-user",
+		"", "the user for the kubeconfig generated by the scoped-kubeconfig action, authenticated by a client certificate signed by the cluster CA",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.KubeConfigGroups, "kubeconfig-groups",
+		nil, "the groups of the user for the kubeconfig generated by the scoped-kubeconfig action",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeConfigOutput, "kubeconfig-output",
+		"", "the host path where the scoped-kubeconfig action writes the kubeconfig; if not set, the kubeconfig is printed",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SkipVersionSkewCheck, "skip-version-skew-check",
+		false, "skip the version skew check executed before the kubeadm-join and kubeadm-upgrade actions, e.g. for testing unsupported skews",
+	)
+	cmd.Flags().BoolVar(
+		&flags.ValidateConfig, "validate-config",
+		false, "run kubeadm config validate against the kubeadm config before kubeadm init, join and upgrade, like the kubeadm-config-validate action",
+	)
+	cmd.Flags().StringVar(
+		&flags.SmokeTestImage, "smoke-test-image",
+		"", "the workload image used by the smoke-test action, e.g. for air-gapped environments; the image must serve HTTP on port 80 and provide nslookup and wget",
+	)
+	cmd.Flags().BoolVar(
+		&flags.SmokeTestService, "smoke-test-service",
+		false, "test also pod-to-service connectivity in the smoke-test action",
+	)
+	cmd.Flags().StringVar(
+		&flags.DNSCheckImage, "dns-check-image",
+		"", "the image of the pod resolving names in the dns-check action; the image must provide nslookup",
+	)
+	cmd.Flags().StringVar(
+		&flags.DNSCheckExternalName, "dns-check-external-name",
+		"kubernetes.io", "the external name resolved by the dns-check action for checking forwarding to the upstream resolvers; set it empty to skip the check, e.g. for air-gapped environments",
+	)
+	cmd.Flags().BoolVar(
+		&flags.KubeletServingCSRWatch, "kubelet-serving-csr-watch",
+		false, "keep approving the certificate signing requests created by the kubelet serving certificate rotation in the kubelet-serving-csr-approve action, until each kubelet rotated its certificate; requires --cluster-signing-duration at create time",
+	)
+	cmd.Flags().StringVar(
+		&flags.E2EFocus, "e2e-focus",
+		"", "the regular expression selecting the e2e tests run by the e2e-subset action",
+	)
+	cmd.Flags().StringVar(
+		&flags.E2ESkip, "e2e-skip",
+		"", "the regular expression selecting the e2e tests skipped by the e2e-subset action",
+	)
+	cmd.Flags().StringVar(
+		&flags.E2EImage, "e2e-image",
+		"", "the image providing the e2e test binary for the e2e-subset action, e.g. a pre-pulled image for offline use; if not set, the conformance image for the Kubernetes version of the cluster is used",
+	)
+	cmd.Flags().StringVar(
+		&flags.E2EBinary, "e2e-binary",
+		"", "the path of the e2e test binary in the e2e-subset image (default /usr/local/bin/e2e.test)",
+	)
+	cmd.Flags().StringVar(
+		&flags.E2EResultsDir, "e2e-results-dir",
+		"", "the host directory where the e2e-subset action writes the test results; if not set, a temporary directory is used",
+	)
+	cmd.Flags().BoolVar(
+		&flags.PodStartupLatency, "pod-startup-latency",
+		false, "measure the pod startup latency on each worker node after kubeadm-join",
+	)
+	cmd.Flags().StringVar(
+		&flags.PodStartupImage, "pod-startup-image",
+		"", "the pod image used for measuring the pod startup latency; the image must keep running. Defaults to the sandbox image of the node",
+	)
+	cmd.Flags().BoolVar(
+		&flags.PodStartupPrePull, "pod-startup-pre-pull",
+		false, "pull the pod image on the node before measuring the pod startup latency, so the pull time is not measured",
+	)
+	cmd.Flags().DurationVar(
+		&flags.PodStartupMaxLatency, "pod-startup-max-latency",
+		0, "fail if the pod startup latency exceeds this value; 0 disables the check",
+	)
 	cmd.Flags().StringVar(
 		&flags.UpgradeVersion,
 		"upgrade-version", "",
 		"defines the target upgrade version (it should match the version of upgrades binaries)",
 	)
+	cmd.Flags().StringSliceVar(
+		&flags.UpgradeNodes,
+		"upgrade-nodes", nil,
+		"node selectors for upgrading only a subset of nodes, e.g. @cp1; the bootstrap control-plane must be upgraded first",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.PartitionNodes,
+		"partition-nodes", nil,
+		"node selectors for the nodes to partition from the other nodes in the network-partition action, e.g. @cp1",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.PullImages,
+		"pull-images", nil,
+		"the images pulled by the pull-images action; if not set, the images listed by kubeadm config images list are pulled",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.PullNodes,
+		"pull-nodes", nil,
+		"node selectors for pulling images only on a subset of nodes in the pull-images action, e.g. @w*",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.DigestImages,
+		"digest-images", nil,
+		"the images checked by the image-digest-check action; if not set, all the images available on the nodes are checked",
+	)
+	cmd.Flags().StringVar(
+		&flags.ChaosComponent,
+		"chaos-component", "",
+		fmt.Sprintf("the control-plane component killed by the chaos action, one of %s; random if not set", actions.KnownChaosComponents()),
+	)
+	cmd.Flags().StringVar(
+		&flags.ChaosNode,
+		"chaos-node", "",
+		"node selector for the control-plane node targeted by the chaos action, e.g. @cp2; random if not set",
+	)
+	cmd.Flags().BoolVar(
+		&flags.ChaosVerify,
+		"chaos-verify", false,
+		"verify that the component killed by the chaos action is recreated and the control-plane is healthy",
+	)
 	cmd.Flags().StringVar(
 		&flags.CopyCerts,
 		"copy-certs", string(actions.CopyCertsModeManual),
@@ -110,12 +371,12 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().StringVar(
 		&flags.PatchesDir,
 		"patches", flags.PatchesDir,
-		"the patches directory to be used for init, join and upgrade",
+		"the patches directory to be used for init, join and upgrade. Sub directories named after a node can contain patches and a kubeadm-config.yaml file applied to that node only; sub directories not matching a node are rejected",
 	)
 	cmd.Flags().StringVar(
 		&flags.IgnorePreflightErrors,
 		"ignore-preflight-errors", constants.KubeadmIgnorePreflightErrors,
-		"list of kubeadm preflight errors to skip",
+		"list of kubeadm preflight errors to skip; kubeadm-init defaults to the list defined at create time, if any",
 	)
 	cmd.Flags().StringVar(
 		&flags.KubeadmConfigVersion,
@@ -134,27 +395,59 @@ func NewCommand() *cobra.Command {
 		"kubeadm-encryption-algorithm", "",
 		"the encryption algorithm used by kubeadm for private keys in the cluster",
 	)
+	cmd.Flags().StringVar(
+		&flags.TLSMinVersion,
+		"apiserver-tls-min-version", "",
+		"the minimum TLS version supported by the API server. Use one of [VersionTLS10, VersionTLS11, VersionTLS12, VersionTLS13]",
+	)
+	cmd.Flags().StringSliceVar(
+		&flags.TLSCipherSuites,
+		"apiserver-tls-cipher-suites", nil,
+		"the cipher suites for the API server; they must be compatible with the key type defined by --kubeadm-encryption-algorithm (default RSA)",
+	)
+	cmd.Flags().StringVar(
+		&flags.DNSDomain,
+		"dns-domain", constants.DefaultDNSDomain,
+		"the DNS domain used for the cluster services and for the API server certificate SANs",
+	)
+	cmd.Flags().StringVar(
+		&flags.CgroupDriver,
+		"cgroup-driver", constants.DefaultCgroupDriver,
+		"the cgroup driver used by the kubelet. Use one of [systemd, cgroupfs]; it should match the cgroup driver of the container runtime in the node image",
+	)
+	cmd.Flags().StringVar(
+		&flags.CRISocket,
+		"cri-socket", "",
+		"the CRI socket used by kubeadm init and join, e.g. unix:///run/containerd/containerd.sock; if not set, the CRI socket of the container runtime in the node image is used",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeadmBinary,
+		"kubeadm-binary", "",
+		"the host path of a kubeadm binary to copy into the nodes and to use for the action, e.g. a locally-built kubeadm",
+	)
+	cmd.Flags().StringVar(
+		&flags.KubeadmBinaryPath,
+		"kubeadm-binary-path", "",
+		"the path on the nodes of the kubeadm binary to use for the action, instead of the kubeadm binary in the PATH",
+	)
+	cmd.Flags().StringVar(
+		&flags.ResourceUsageFile,
+		"resource-usage-file", "",
+		"the host file where the CPU and memory usage of the control-plane nodes, sampled while the action runs, is written as a CSV time series",
+	)
+	cmd.Flags().DurationVar(
+		&flags.ResourceUsageInterval,
+		"resource-usage-interval", 2*time.Second,
+		"the interval between resource usage samples when --resource-usage-file is set",
+	)
 	return cmd
 }
 
 func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
-	// validate UpgradeVersion flag
-	var upgradeVersion *K8sVersion.Version
-	if flags.UpgradeVersion != "" {
-		upgradeVersion, err = K8sVersion.ParseSemantic(flags.UpgradeVersion)
-		if err != nil {
-			return err
-		}
-	}
-
-	discovery := actions.DiscoveryMode(strings.ToLower(flags.Discovery))
-	if err := actions.ValidateDiscoveryMode(discovery); err != nil {
-		return err
-	}
-
-	copyCerts := actions.CopyCertsMode(strings.ToLower(flags.CopyCerts))
-	if err := actions.ValidateCopyCertsMode(copyCerts); err != nil {
-		return err
+	// validate flags; validation errors have a dedicated exit code
+	upgradeVersion, err := validateFlags(flags)
+	if err != nil {
+		return failure.WithCategory(err, failure.Validation)
 	}
 
 	// get a kinder cluster manager
@@ -163,6 +456,18 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 		return errors.Wrapf(err, "failed to create a kinder cluster manager for %s", flags.Name)
 	}
 
+	// eventually, instruct the cluster manager to run commands also on nodes excluded from actions by label
+	if flags.IncludeIneligible {
+		o.IncludeIneligible()
+	}
+
+	// eventually, instruct the cluster manager to use another bootstrap control plane
+	if flags.BootstrapControlPlane != "" {
+		if err := o.BootstrapControlPlane(flags.BootstrapControlPlane); err != nil {
+			return err
+		}
+	}
+
 	// eventually, instruct the cluster manager to run only commands on one node
 	if flags.OnlyNode != "" {
 		if err := o.OnlyNode(flags.OnlyNode); err != nil {
@@ -177,24 +482,162 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) (err error) {
 		flags.Wait = 0
 	}
 
+	// eventually, instruct the cluster manager to capture the kubeadm output; this is set before the kubeadm
+	// binary, because commands are matched by the kubeadm command name
+	if flags.KubeadmOutputDir != "" {
+		o.CaptureKubeadmOutput()
+	}
+
+	// eventually, instruct the cluster manager to use a kubeadm binary different from the one in the PATH,
+	// copying it from the host into the nodes if required
+	if flags.KubeadmBinaryPath != "" {
+		o.KubeadmBinary(flags.KubeadmBinaryPath)
+	}
+	if flags.KubeadmBinary != "" {
+		if err := o.CopyKubeadmBinary(flags.KubeadmBinary); err != nil {
+			return err
+		}
+	}
+
+	// when the preflight errors to skip are not set explicitly, kubeadm-init uses the ones defined at create time, if any
+	ignorePreflightErrors := flags.IgnorePreflightErrors
+	if !cmd.Flags().Changed("ignore-preflight-errors") {
+		ignorePreflightErrors = ""
+	}
+
 	// executed the requested action
 	action := args[0]
 	err = o.DoAction(action,
 		actions.UsePhases(flags.UsePhases),
-		actions.CopyCerts(copyCerts),
-		actions.Discovery(discovery),
+		actions.CopyCerts(actions.CopyCertsMode(strings.ToLower(flags.CopyCerts))),
+		actions.Discovery(actions.DiscoveryMode(strings.ToLower(flags.Discovery))),
 		actions.Wait(flags.Wait),
+		actions.WaitCoreDNS(flags.WaitCoreDNS),
+		actions.WaitAllNodes(flags.WaitAllNodes),
+		actions.RefreshCertsAfter(flags.RefreshCertsAfter),
+		actions.JoinParallelism(flags.JoinParallelism),
 		actions.UpgradeVersion(upgradeVersion),
+		actions.UpgradeNodes(flags.UpgradeNodes),
+		actions.PartitionNodes(flags.PartitionNodes),
+		actions.PullImagesOptions(flags.PullImages, flags.PullNodes),
+		actions.DigestImages(flags.DigestImages),
+		actions.Drain(actions.DrainOptions{
+			Enabled:            flags.Drain,
+			IgnoreDaemonSets:   flags.DrainIgnoreDaemonSets,
+			DeleteEmptyDirData: flags.DrainDeleteEmptyDir,
+			GracePeriod:        flags.DrainGracePeriod,
+			Force:              flags.Force,
+		}),
+		actions.ChaosOptions(flags.ChaosComponent, flags.ChaosNode, flags.ChaosVerify),
 		actions.VLevel(flags.VLevel),
 		actions.PatchesDir(flags.PatchesDir),
-		actions.IgnorePreflightErrors(flags.IgnorePreflightErrors),
+		actions.IgnorePreflightErrors(ignorePreflightErrors),
 		actions.KubeadmConfigVersion(flags.KubeadmConfigVersion),
 		actions.FeatureGate(flags.FeatureGate),
 		actions.EncryptionAlgorithm(flags.EncryptionAlgorithm),
+		actions.DNSDomain(flags.DNSDomain),
+		actions.CgroupDriver(flags.CgroupDriver),
+		actions.CRISocket(flags.CRISocket),
+		actions.Force(flags.Force),
+		actions.Fix(flags.Fix),
+		actions.APIServerCertSANs(flags.APIServerCertSANs),
+		actions.CertCorruption(actions.CorruptCertMode(strings.ToLower(flags.CorruptCertMode))),
+		actions.ExpectedAPIResources(flags.APIResources),
+		actions.SnapshotPath(flags.SnapshotPath),
+		actions.CollectDir(flags.CollectDir),
+		actions.MigrateConfig(flags.MigrateConfig, flags.MigrateOutput),
+		actions.ScopedKubeConfigOptions(flags.KubeConfigSA, flags.KubeConfigUser, flags.KubeConfigGroups, flags.KubeConfigOutput),
+		actions.SkipVersionSkewCheck(flags.SkipVersionSkewCheck),
+		actions.ValidateConfig(flags.ValidateConfig),
+		actions.SmokeTestOptions(flags.SmokeTestImage, flags.SmokeTestService),
+		actions.DNSCheckOptions(flags.DNSCheckImage, flags.DNSCheckExternalName),
+		actions.KubeletServingCSRWatch(flags.KubeletServingCSRWatch),
+		actions.E2ETests(actions.E2ESubsetOptions{
+			Focus:      flags.E2EFocus,
+			Skip:       flags.E2ESkip,
+			Image:      flags.E2EImage,
+			Binary:     flags.E2EBinary,
+			ResultsDir: flags.E2EResultsDir,
+		}),
+		actions.PodStartup(flags.PodStartupLatency, actions.PodStartupOptions{
+			Image:      flags.PodStartupImage,
+			PrePull:    flags.PodStartupPrePull,
+			MaxLatency: flags.PodStartupMaxLatency,
+		}),
+		actions.APIServerTLS(flags.TLSMinVersion, flags.TLSCipherSuites),
+		actions.ResourceUsage(flags.ResourceUsageFile, flags.ResourceUsageInterval),
 	)
+
+	// the kubeadm output is written also if the action fails, so failures can be compared as well
+	if flags.KubeadmOutputDir != "" {
+		if werr := o.WriteKubeadmOutput(flags.KubeadmOutputDir, action); werr != nil && err == nil {
+			err = werr
+		}
+	}
 	if err != nil {
 		return errors.Wrapf(err, "failed to exec action %s", action)
 	}
 
 	return nil
 }
+
+// validateFlags validates the flags before touching the cluster, and returns the parsed upgrade version, if any
+func validateFlags(flags *flagpole) (*K8sVersion.Version, error) {
+	// validate UpgradeVersion flag
+	var upgradeVersion *K8sVersion.Version
+	if flags.UpgradeVersion != "" {
+		var err error
+		upgradeVersion, err = K8sVersion.ParseSemantic(flags.UpgradeVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if flags.DNSDomain == "" {
+		return nil, errors.New("flag --dns-domain can not be empty")
+	}
+
+	if flags.CgroupDriver != "systemd" && flags.CgroupDriver != "cgroupfs" {
+		return nil, errors.Errorf("invalid --cgroup-driver %q. Use one of [systemd, cgroupfs]", flags.CgroupDriver)
+	}
+
+	if flags.CRISocket != "" {
+		if err := kubeadm.ValidateCRISocket(flags.CRISocket); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := actions.ValidateDiscoveryMode(actions.DiscoveryMode(strings.ToLower(flags.Discovery))); err != nil {
+		return nil, err
+	}
+
+	if err := actions.ValidateCopyCertsMode(actions.CopyCertsMode(strings.ToLower(flags.CopyCerts))); err != nil {
+		return nil, err
+	}
+
+	if err := actions.ValidateChaosComponent(flags.ChaosComponent); err != nil {
+		return nil, err
+	}
+
+	if err := actions.ValidateCorruptCertMode(actions.CorruptCertMode(strings.ToLower(flags.CorruptCertMode))); err != nil {
+		return nil, err
+	}
+
+	if err := actions.ValidateAPIResourceExpectations(flags.APIResources); err != nil {
+		return nil, err
+	}
+
+	if flags.JoinParallelism < 1 {
+		return nil, errors.New("flag --join-parallelism must be greater than 0")
+	}
+
+	if flags.KubeadmBinary != "" && flags.KubeadmBinaryPath != "" {
+		return nil, errors.New("flags --kubeadm-binary and --kubeadm-binary-path are mutually exclusive")
+	}
+
+	if flags.ResourceUsageFile != "" && flags.ResourceUsageInterval <= 0 {
+		return nil, errors.New("flag --resource-usage-interval must be a positive duration")
+	}
+
+	return upgradeVersion, nil
+}
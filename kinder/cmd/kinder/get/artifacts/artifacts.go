@@ -38,6 +38,7 @@ type flagpole struct {
 	OnlyKubelet  bool
 	OnlyBinaries bool
 	OnlyImages   bool
+	Checksums    []string
 }
 
 // NewCommand returns a new cobra.Command for exec
@@ -80,6 +81,10 @@ func NewCommand() *cobra.Command {
 		onlyImagesFLagName, false,
 		"Gets only the kube-apiserver, kube-scheduler, kube-controller-manager and kube-proxy image tarballs (instead of all artifacts)",
 	)
+	cmd.Flags().StringSliceVar(&flags.Checksums,
+		"checksum", nil,
+		"the expected SHA-256 checksum of a Kubernetes binary, in the BINARY=SHA256 form; binaries from release and ci builds are always verified against the published checksums",
+	)
 
 	return cmd
 }
@@ -98,16 +103,22 @@ func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
 		dst = args[1]
 	}
 
+	checksums, err := extract.ParseChecksums(flags.Checksums)
+	if err != nil {
+		return err
+	}
+
 	// Build an artifact extractor customized with the command options
 	e := extract.NewExtractor(src, dst,
 		extract.OnlyKubeadm(flags.OnlyKubeadm),
 		extract.OnlyKubelet(flags.OnlyKubelet),
 		extract.OnlyKubernetesBinaries(flags.OnlyBinaries),
 		extract.OnlyKubernetesImages(flags.OnlyImages),
+		extract.WithChecksums(checksums),
 	)
 
 	// Extracts the artifacts from the source
-	_, err := e.Extract()
+	_, err = e.Extract()
 	if err != nil {
 		return errors.Wrapf(err, "failed to gets build artifacts for %s version", src)
 	}
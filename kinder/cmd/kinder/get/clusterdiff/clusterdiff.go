@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterdiff
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// NewCommand returns a new cobra.Command for comparing two clusters
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(2),
+		Use:   "cluster-diff LEFT RIGHT",
+		Short: "Compares the node roles, versions and settings of two clusters",
+		Long: "Compares the node roles, versions and settings of two clusters, and prints the differences in JSON format; " +
+			"nodes are matched by name without the cluster name prefix",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(cmd, args)
+		},
+	}
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	diff, err := status.DiffClusters(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the cluster diff")
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -17,28 +17,52 @@ limitations under the License.
 package clusters
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"text/tabwriter"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 )
 
+type flagpole struct {
+	Output string
+}
+
 // NewCommand returns a new cobra.Command for getting the list of clusters
 func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "clusters",
 		Short: "Lists existing kind clusters by their name",
 		Long:  "Lists existing kind clusters by their name",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runE(cmd, args)
+			return runE(flags, cmd, args)
 		},
 	}
+
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "",
+		"output format; use wide for printing also the cluster metadata recorded at create time, or json",
+	)
 	return cmd
 }
 
-func runE(cmd *cobra.Command, args []string) error {
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	switch flags.Output {
+	case "":
+	case "wide", "json":
+		return printClustersDetailed(flags.Output)
+	default:
+		return errors.Errorf("invalid output format %q. Use one of [wide, json]", flags.Output)
+	}
+
 	clusters, err := status.ListClusters()
 	if err != nil {
 		return err
@@ -48,3 +72,35 @@ func runE(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// printClustersDetailed prints the clusters with the metadata recorded at create time
+func printClustersDetailed(output string) error {
+	clusters, err := status.ListClustersDetailed()
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		out, err := json.MarshalIndent(clusters, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode the list of clusters")
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNODES\tIP FAMILY\tKUBERNETES VERSION")
+	for _, c := range clusters {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", c.Name, c.Nodes, valueOrUnknown(string(c.IPFamily)), valueOrUnknown(c.KubernetesVersion))
+	}
+	return w.Flush()
+}
+
+// valueOrUnknown returns the value, or unknown for metadata not recorded at create time
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -20,7 +20,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/artifacts"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/clusterdiff"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/clusters"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/imageinfo"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/images"
+	"k8s.io/kubeadm/kinder/cmd/kinder/get/kubeadmoutputdiff"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/kubeconfigpath"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get/nodes"
 )
@@ -30,8 +34,8 @@ func NewCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "get",
-		Short: "Gets one of [clusters, nodes, kubeconfig-path, artifacts]",
-		Long:  "Gets one of [clusters, nodes, kubeconfig-path, artifacts]",
+		Short: "Gets one of [clusters, nodes, kubeconfig-path, artifacts, images, image-info, cluster-diff, kubeadm-output-diff]",
+		Long:  "Gets one of [clusters, nodes, kubeconfig-path, artifacts, images, image-info, cluster-diff, kubeadm-output-diff]",
 	}
 
 	cmd.AddCommand(clusters.NewCommand())
@@ -42,5 +46,9 @@ func NewCommand() *cobra.Command {
 
 	// add kinder only commands
 	cmd.AddCommand(artifacts.NewCommand())
+	cmd.AddCommand(images.NewCommand())
+	cmd.AddCommand(imageinfo.NewCommand())
+	cmd.AddCommand(clusterdiff.NewCommand())
+	cmd.AddCommand(kubeadmoutputdiff.NewCommand())
 	return cmd
 }
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageinfo
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// NewCommand returns a new cobra.Command for getting the kinder relevant metadata of a node image
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "image-info IMAGE",
+		Short: "Prints the kinder relevant metadata of a node image, in JSON format",
+		Long: "Prints the kinder relevant metadata of a node image, like the Kubernetes and kubeadm versions,\n" +
+			"the container runtime, the default CNI, the base distribution and the pre-loaded images",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(cmd, args)
+		},
+	}
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	info, err := status.InspectNodeImage(args[0])
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the node image metadata")
+	}
+	fmt.Println(string(out))
+
+	return nil
+}
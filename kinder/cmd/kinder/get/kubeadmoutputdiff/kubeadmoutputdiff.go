@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadmoutputdiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+// NewCommand returns a new cobra.Command for comparing the kubeadm output captured in two dirs
+func NewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(2),
+		Use:   "kubeadm-output-diff LEFT_DIR RIGHT_DIR",
+		Short: "Compares the kubeadm output captured in two dirs by kinder do --kubeadm-output-dir",
+		Long: "Compares the normalized kubeadm output captured in two dirs by kinder do --kubeadm-output-dir, e.g. for two " +
+			"kubeadm versions, and prints the differences; files are matched by name, and the command fails if there are differences",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(cmd, args)
+		},
+	}
+	return cmd
+}
+
+func runE(cmd *cobra.Command, args []string) error {
+	left, err := readOutputs(args[0])
+	if err != nil {
+		return err
+	}
+	right, err := readOutputs(args[1])
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for name := range left {
+		names[name] = true
+	}
+	for name := range right {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var different []string
+	for _, name := range sorted {
+		l, inLeft := left[name]
+		r, inRight := right[name]
+		switch {
+		case !inLeft:
+			fmt.Printf("%s: only in %s\n", name, args[1])
+		case !inRight:
+			fmt.Printf("%s: only in %s\n", name, args[0])
+		default:
+			diff := kubeadm.DiffOutput(l, r)
+			if len(diff) == 0 {
+				continue
+			}
+			fmt.Printf("--- %s\n+++ %s\n%s\n", filepath.Join(args[0], name), filepath.Join(args[1], name), strings.Join(diff, "\n"))
+		}
+		different = append(different, name)
+	}
+
+	if len(different) > 0 {
+		return errors.Errorf("the kubeadm output is different for %s", strings.Join(different, ", "))
+	}
+	fmt.Println("The kubeadm output is the same")
+	return nil
+}
+
+// readOutputs reads the kubeadm output files in a dir, by file name
+func readOutputs(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the kubeadm output files in %s", dir)
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no kubeadm output files found in %s", dir)
+	}
+
+	outputs := map[string]string{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the kubeadm output file %s", f)
+		}
+		outputs[filepath.Base(f)] = string(b)
+	}
+	return outputs, nil
+}
@@ -17,8 +17,12 @@ limitations under the License.
 package nodes
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"text/tabwriter"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
@@ -26,7 +30,8 @@ import (
 )
 
 type flagpole struct {
-	Name string
+	Name   string
+	Output string
 }
 
 // NewCommand returns a new cobra.Command for getting the list of nodes in a cluster
@@ -47,17 +52,74 @@ func NewCommand() *cobra.Command {
 		&flags.Name,
 		"name", constants.DefaultClusterName, "cluster name",
 	)
+	cmd.Flags().StringVarP(
+		&flags.Output,
+		"output", "o", "",
+		"output format; use wide for printing also the role, the IP and the versions of each node, json for the same in a machine readable format, or dot for printing the cluster topology as a Graphviz DOT graph, e.g. for piping it to dot -Tpng",
+	)
 	return cmd
 }
 
 func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	switch flags.Output {
+	case "", "wide", "json", "dot":
+	default:
+		return errors.Errorf("invalid output format %q. Use one of [wide, json, dot]", flags.Output)
+	}
+
 	cluster, err := status.FromDocker(flags.Name)
 	if err != nil {
 		return err
 	}
+	if err := cluster.CheckExists(); err != nil {
+		return err
+	}
+
+	switch flags.Output {
+	case "dot":
+		return cluster.WriteDOT(os.Stdout)
+	case "wide", "json":
+		return printNodesDetailed(cluster, flags.Output)
+	}
 
 	for _, node := range cluster.AllNodes() {
 		fmt.Println(node.Name())
 	}
 	return nil
 }
+
+// printNodesDetailed prints the nodes with their role, IP and versions
+func printNodesDetailed(cluster *status.Cluster, output string) error {
+	nodes, err := cluster.NodesDetails()
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		out, err := json.MarshalIndent(nodes, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode the list of nodes")
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tROLE\tIP\tKUBERNETES VERSION\tKUBEADM VERSION")
+	for _, n := range nodes {
+		ip := n.IPv4
+		if ip == "" {
+			ip = n.IPv6
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", n.Name, n.Role, valueOrNone(ip), valueOrNone(n.KubernetesVersion), valueOrNone(n.KubeadmVersion))
+	}
+	return w.Flush()
+}
+
+// valueOrNone returns the value, or a dash for values not available, e.g. versions on an external etcd
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package archive implements the `archive` command for importing a cluster
+package archive
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager"
+)
+
+type flagpole struct {
+	Name   string
+	Retain bool
+}
+
+// NewCommand returns a new cobra.Command for importing a cluster from a tarball
+func NewCommand() *cobra.Command {
+	flags := &flagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "archive [flags] FILE",
+		Short: "Imports a cluster from a tarball",
+		Long: "Imports a cluster from a tarball created by kinder export archive, creating again the node containers " +
+			"from the exported node filesystems",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runE(flags, cmd, args)
+		},
+	}
+	cmd.Flags().StringVar(
+		&flags.Name, "name",
+		"",
+		"cluster name; if set, it should match the name of the exported cluster, because clusters can't be renamed on import",
+	)
+	cmd.Flags().BoolVar(
+		&flags.Retain, "retain",
+		false,
+		"retain nodes for debugging when cluster import fails",
+	)
+	return cmd
+}
+
+func runE(flags *flagpole, cmd *cobra.Command, args []string) error {
+	if err := manager.ImportCluster(args[0], flags.Name, flags.Retain); err != nil {
+		return errors.Wrapf(err, "failed to import %s", args[0])
+	}
+	return nil
+}
@@ -26,12 +26,17 @@ import (
 	"k8s.io/kubeadm/kinder/cmd/kinder/build"
 	"k8s.io/kubeadm/kinder/cmd/kinder/cp"
 	"k8s.io/kubeadm/kinder/cmd/kinder/create"
+	"k8s.io/kubeadm/kinder/cmd/kinder/delete/orphans"
 	"k8s.io/kubeadm/kinder/cmd/kinder/do"
 	"k8s.io/kubeadm/kinder/cmd/kinder/exec"
+	exportarchive "k8s.io/kubeadm/kinder/cmd/kinder/export/archive"
 	"k8s.io/kubeadm/kinder/cmd/kinder/get"
+	"k8s.io/kubeadm/kinder/cmd/kinder/importcmd"
 	"k8s.io/kubeadm/kinder/cmd/kinder/test"
 	"k8s.io/kubeadm/kinder/cmd/kinder/version"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	kinderexec "k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/failure"
 	kindcmd "sigs.k8s.io/kind/pkg/cmd"
 	kinddelete "sigs.k8s.io/kind/pkg/cmd/kind/delete"
 	kindexport "sigs.k8s.io/kind/pkg/cmd/kind/export"
@@ -41,7 +46,9 @@ const defaultLevel = log.WarnLevel
 
 // Flags for the kinder command
 type Flags struct {
-	LogLevel string
+	LogLevel      string
+	LogFormat     string
+	CommandOutput string
 }
 
 // NewCommand returns a new cobra.Command implementing the root command for kinder
@@ -71,13 +78,32 @@ func NewCommand() *cobra.Command {
 		defaultLevel.String(),
 		"logrus log level [panic, fatal, error, warning, info, debug, trace]",
 	)
+	cmd.PersistentFlags().StringVar(
+		&flags.LogFormat,
+		"log-format",
+		string(kinderexec.LogFormatText),
+		"the format of the progress messages [text, json]; with json, progress messages are logged with fields like node, action, phase and duration, and the log level is at least info",
+	)
+	cmd.PersistentFlags().StringVar(
+		&flags.CommandOutput,
+		"command-output",
+		string(kinderexec.CommandOutputEcho),
+		"how the output of commands executed on nodes, e.g. kubeadm, is printed [echo, tagged, none]; tagged prefixes each line with the node name, or logs it with json",
+	)
 
 	logger := kindcmd.NewLogger()
 	ioStreams := kindcmd.StandardIOStreams()
 
-	// add kind top level subcommands re-used without changes
-	cmd.AddCommand(kinddelete.NewCommand(logger, ioStreams))
-	cmd.AddCommand(kindexport.NewCommand(logger, ioStreams))
+	// add kind commands extended in kinder
+	deleteCmd := kinddelete.NewCommand(logger, ioStreams)
+	deleteCmd.AddCommand(orphans.NewCommand())
+	cmd.AddCommand(deleteCmd)
+
+	exportCmd := kindexport.NewCommand(logger, ioStreams)
+	exportCmd.Short = "Exports one of [kubeconfig, logs, archive]"
+	exportCmd.Long = "Exports one of [kubeconfig, logs, archive]"
+	exportCmd.AddCommand(exportarchive.NewCommand())
+	cmd.AddCommand(exportCmd)
 
 	// add kind commands customized in kind
 	cmd.AddCommand(build.NewCommand())
@@ -89,6 +115,7 @@ func NewCommand() *cobra.Command {
 	cmd.AddCommand(cp.NewCommand())
 	cmd.AddCommand(do.NewCommand())
 	cmd.AddCommand(exec.NewCommand())
+	cmd.AddCommand(importcmd.NewCommand())
 	cmd.AddCommand(test.NewCommand())
 
 	return cmd
@@ -102,6 +129,18 @@ func runE(flags *Flags, cmd *cobra.Command, args []string) error {
 	} else {
 		level = parsed
 	}
+
+	if err := kinderexec.SetLogMode(kinderexec.LogFormat(flags.LogFormat), kinderexec.CommandOutput(flags.CommandOutput)); err != nil {
+		return err
+	}
+	if kinderexec.IsJSONLog() {
+		log.SetFormatter(&log.JSONFormatter{})
+		// progress messages are logged at info level
+		if level < log.InfoLevel {
+			level = log.InfoLevel
+		}
+	}
+
 	log.SetLevel(level)
 	return nil
 }
@@ -121,7 +160,8 @@ func Main() {
 		FullTimestamp:   true,
 		TimestampFormat: "15:04:05",
 	})
+	// exit codes depend on the failure category, so CI can branch on it
 	if err := Run(); err != nil {
-		os.Exit(1)
+		os.Exit(failure.ExitCode(err))
 	}
 }
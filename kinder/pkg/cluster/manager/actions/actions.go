@@ -25,9 +25,12 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
 // action registry defines the list of available actions and the corresponding entry point.
@@ -37,22 +40,46 @@ var actionRegistry = map[string]func(*status.Cluster, *RunOptions) error{
 		// to invoke it separately as well
 		return LoadBalancer(c, c.ControlPlanes()...)
 	},
+	"loadbalancer-status": func(c *status.Cluster, flags *RunOptions) error {
+		return LoadBalancerStatus(c)
+	},
 	"kubeadm-config": func(c *status.Cluster, flags *RunOptions) error {
 		// Nb. this action is invoked automatically at kubeadm init/join time, but it is possible
 		// to invoke it separately as well
-		return KubeadmConfig(c, flags.kubeadmConfigVersion, flags.copyCertsMode, flags.discoveryMode, flags.featureGate, flags.encryptionAlgorithm, c.K8sNodes().EligibleForActions()...)
+		return KubeadmConfig(c, flags.kubeadmConfigVersion, flags.copyCertsMode, flags.discoveryMode, flags.featureGate, flags.encryptionAlgorithm, flags.dnsDomain, flags.cgroupDriver, flags.criSocket, flags.tlsMinVersion, flags.tlsCipherSuites, flags.patchesDir, c.K8sNodes().EligibleForActions()...)
+	},
+	"kubeadm-config-check": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmConfigCheck(c)
+	},
+	"scoped-kubeconfig": func(c *status.Cluster, flags *RunOptions) error {
+		return ScopedKubeConfig(c, flags.kubeConfigServiceAccount, flags.kubeConfigUser, flags.kubeConfigGroups, flags.kubeConfigOutput)
+	},
+	"kubeadm-config-validate": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmConfigValidate(c)
+	},
+	"kubeadm-config-migrate": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmConfigMigrate(c, flags.migrateConfig, flags.migrateOutput)
+	},
+	"cni": func(c *status.Cluster, flags *RunOptions) error {
+		// Nb. this action is invoked automatically at kubeadm init time, but it is possible
+		// to invoke it separately as well
+		return InstallCNI(c, flags.wait)
 	},
 	"kubeadm-init": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmInit(c, flags.usePhases, flags.copyCertsMode, flags.kubeadmConfigVersion, flags.patchesDir, flags.ignorePreflightErrors, flags.featureGate, flags.encryptionAlgorithm, flags.wait, flags.vLevel)
+		return KubeadmInit(c, flags.kubeadmInitOptions())
 	},
 	"kubeadm-join": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmJoin(c, flags.usePhases, flags.copyCertsMode, flags.discoveryMode, flags.kubeadmConfigVersion, flags.patchesDir, flags.ignorePreflightErrors, flags.wait, flags.vLevel)
+		return KubeadmJoin(c, flags.kubeadmJoinOptions())
+	},
+	"kubeadm-refresh-certs": func(c *status.Cluster, flags *RunOptions) error {
+		_, err := KubeadmRefreshCerts(c, flags.vLevel)
+		return err
 	},
 	"kubeadm-upgrade": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmUpgrade(c, flags.upgradeVersion, flags.patchesDir, flags.featureGate, flags.wait, flags.vLevel)
+		return KubeadmUpgrade(c, flags.kubeadmUpgradeOptions())
 	},
 	"kubeadm-reset": func(c *status.Cluster, flags *RunOptions) error {
-		return KubeadmReset(c, flags.vLevel)
+		return KubeadmReset(c, flags.drain, flags.wait, flags.vLevel)
 	},
 	"copy-certs": func(c *status.Cluster, flags *RunOptions) error {
 		return CopyCertificates(c)
@@ -60,11 +87,92 @@ var actionRegistry = map[string]func(*status.Cluster, *RunOptions) error{
 	"setup-external-ca": func(c *status.Cluster, flags *RunOptions) error {
 		return SetupExternalCA(c, flags.vLevel)
 	},
+	"kube-proxy-mode-check": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeProxyModeCheck(c)
+	},
+	"rbac-check": func(c *status.Cluster, flags *RunOptions) error {
+		return RBACCheck(c)
+	},
 	"cluster-info": func(c *status.Cluster, flags *RunOptions) error {
 		return CluterInfo(c)
 	},
 	"smoke-test": func(c *status.Cluster, flags *RunOptions) error {
-		return SmokeTest(c, flags.wait)
+		return SmokeTest(c, flags.smokeTestImage, flags.smokeTestService, flags.wait)
+	},
+	"dns-check": func(c *status.Cluster, flags *RunOptions) error {
+		return DNSCheck(c, flags.dnsCheckImage, flags.dnsCheckExternalName, flags.wait)
+	},
+	"e2e-subset": func(c *status.Cluster, flags *RunOptions) error {
+		return E2ESubset(c, flags.e2eSubset)
+	},
+	"pod-startup-latency": func(c *status.Cluster, flags *RunOptions) error {
+		return PodStartupLatency(c, flags.podStartup, flags.wait)
+	},
+	"time-to-ready": func(c *status.Cluster, flags *RunOptions) error {
+		return TimeToReady(c, flags.wait)
+	},
+	"pull-images": func(c *status.Cluster, flags *RunOptions) error {
+		return PullImages(c, flags.pullImages, flags.pullNodes)
+	},
+	"image-digest-check": func(c *status.Cluster, flags *RunOptions) error {
+		return ImageDigestCheck(c, flags.digestImages)
+	},
+	"network-partition": func(c *status.Cluster, flags *RunOptions) error {
+		return NetworkPartition(c, flags.partitionNodes)
+	},
+	"network-partition-status": func(c *status.Cluster, flags *RunOptions) error {
+		return NetworkPartitionStatus(c)
+	},
+	"network-heal": func(c *status.Cluster, flags *RunOptions) error {
+		return NetworkHeal(c)
+	},
+	"chaos": func(c *status.Cluster, flags *RunOptions) error {
+		return Chaos(c, flags.chaosComponent, flags.chaosNode, flags.chaosVerify, flags.wait)
+	},
+	"version-skew-check": func(c *status.Cluster, flags *RunOptions) error {
+		return VersionSkewCheck(c)
+	},
+	"api-resources-check": func(c *status.Cluster, flags *RunOptions) error {
+		return APIResourcesCheck(c, flags.apiResources)
+	},
+	"apiserver-cert-check": func(c *status.Cluster, flags *RunOptions) error {
+		return APIServerCertCheck(c)
+	},
+	"etcd-tls-check": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdTLSCheck(c)
+	},
+	"apiserver-cert": func(c *status.Cluster, flags *RunOptions) error {
+		return APIServerCert(c, flags.apiServerCertSANs)
+	},
+	"kubelet-serving-csr-approve": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeletServingCSRApprove(c, flags.kubeletServingCSRWatch, flags.wait)
+	},
+	"corrupt-cert": func(c *status.Cluster, flags *RunOptions) error {
+		return CorruptCert(c, flags.corruptCertMode)
+	},
+	"preflight": func(c *status.Cluster, flags *RunOptions) error {
+		return Preflight(c, flags.fix)
+	},
+	"etcd-add-member": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdAddMember(c)
+	},
+	"etcd-remove-member": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdRemoveMember(c, flags.force)
+	},
+	"certs-renew": func(c *status.Cluster, flags *RunOptions) error {
+		return KubeadmCertsRenew(c, flags.wait)
+	},
+	"collect-config": func(c *status.Cluster, flags *RunOptions) error {
+		return CollectConfig(c, flags.collectDir)
+	},
+	"audit-log": func(c *status.Cluster, flags *RunOptions) error {
+		return AuditLog(c, flags.collectDir)
+	},
+	"etcd-snapshot": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdSnapshot(c, flags.snapshotPath)
+	},
+	"etcd-restore": func(c *status.Cluster, flags *RunOptions) error {
+		return EtcdRestore(c, flags.snapshotPath, flags.wait)
 	},
 }
 
@@ -89,6 +197,28 @@ func UsePhases(usePhases bool) Option {
 	}
 }
 
+// WaitAllNodes option instructs kubeadm join to wait for all the nodes in the cluster to be Ready before returning
+func WaitAllNodes(waitAllNodes bool) Option {
+	return func(r *RunOptions) {
+		r.waitAllNodes = waitAllNodes
+	}
+}
+
+// RefreshCertsAfter option instructs kubeadm join to refresh the uploaded certificates before joining a control-plane
+// node, if more than the given interval elapsed since the last refresh; this applies only to the automatic copy certs mode
+func RefreshCertsAfter(interval time.Duration) Option {
+	return func(r *RunOptions) {
+		r.refreshCertsAfter = interval
+	}
+}
+
+// WaitCoreDNS option instructs kubeadm join to wait for CoreDNS to be ready before returning
+func WaitCoreDNS(waitCoreDNS bool) Option {
+	return func(r *RunOptions) {
+		r.waitCoreDNS = waitCoreDNS
+	}
+}
+
 // CopyCerts option instructs kubeadm init/join actions to use use different methods for copying certs when initializing the cluster and
 // when joining control-plane nodes
 func CopyCerts(copyCertsMode CopyCertsMode) Option {
@@ -104,6 +234,13 @@ func Wait(wait time.Duration) Option {
 	}
 }
 
+// UpgradeNodes option instructs kubeadm upgrade to upgrade only the nodes matching the given node selectors, e.g. @cp1
+func UpgradeNodes(nodeSelectors []string) Option {
+	return func(r *RunOptions) {
+		r.upgradeNodes = nodeSelectors
+	}
+}
+
 // UpgradeVersion option instructs kubeadm actions to use wait for cluster state (nodes, pods) to converge to the desired state
 func UpgradeVersion(upgradeVersion *K8sVersion.Version) Option {
 	return func(r *RunOptions) {
@@ -132,7 +269,8 @@ func PatchesDir(patchesDir string) Option {
 	}
 }
 
-// IgnorePreflightErrors sets which errors to ignore during kubeadm preflight
+// IgnorePreflightErrors sets which errors to ignore during kubeadm preflight; when empty, kubeadm init
+// uses the ones defined at create time, if any, while the other kubeadm commands use the kinder default list
 func IgnorePreflightErrors(ignorePreflightErrors string) Option {
 	return func(r *RunOptions) {
 		r.ignorePreflightErrors = ignorePreflightErrors
@@ -167,19 +305,352 @@ func EncryptionAlgorithm(algorithm string) Option {
 	}
 }
 
+// DNSDomain option sets the DNS domain used for the cluster services
+func DNSDomain(dnsDomain string) Option {
+	return func(r *RunOptions) {
+		r.dnsDomain = dnsDomain
+	}
+}
+
+// CRISocket option sets the CRI socket used by kubeadm init and join, instead of the default CRI socket for the node runtime
+func CRISocket(criSocket string) Option {
+	return func(r *RunOptions) {
+		r.criSocket = criSocket
+	}
+}
+
+// CgroupDriver option sets the cgroup driver used by the kubelet
+func CgroupDriver(cgroupDriver string) Option {
+	return func(r *RunOptions) {
+		r.cgroupDriver = cgroupDriver
+	}
+}
+
+// Force option instructs actions to skip safety checks, e.g. the etcd quorum check when removing a member,
+// or to reset and join again nodes already joined when executing kubeadm join
+func Force(force bool) Option {
+	return func(r *RunOptions) {
+		r.force = force
+	}
+}
+
+// Fix option instructs actions to attempt fixing the issues detected, e.g. the missing kernel modules and sysctls in preflight
+func Fix(fix bool) Option {
+	return func(r *RunOptions) {
+		r.fix = fix
+	}
+}
+
+// APIServerCertSANs option sets the SANs for the API server certificate generated by the apiserver-cert action
+func APIServerCertSANs(sans []string) Option {
+	return func(r *RunOptions) {
+		r.apiServerCertSANs = sans
+	}
+}
+
+// ExpectedAPIResources option sets the resources the api-resources-check action expects to be served or not served,
+// in the [!]groupVersion/resource form
+func ExpectedAPIResources(expectations []string) Option {
+	return func(r *RunOptions) {
+		r.apiResources = expectations
+	}
+}
+
+// CertCorruption option sets how the corrupt-cert action corrupts the API server certificate
+func CertCorruption(mode CorruptCertMode) Option {
+	return func(r *RunOptions) {
+		r.corruptCertMode = mode
+	}
+}
+
+// APIServerTLS option sets the TLS min version and the cipher suites used by the API server
+func APIServerTLS(minVersion string, cipherSuites []string) Option {
+	return func(r *RunOptions) {
+		r.tlsMinVersion = minVersion
+		r.tlsCipherSuites = cipherSuites
+	}
+}
+
+// SnapshotPath option sets the host path of the etcd snapshot used by the etcd-snapshot and etcd-restore actions
+func SnapshotPath(path string) Option {
+	return func(r *RunOptions) {
+		r.snapshotPath = path
+	}
+}
+
+// CollectDir option sets the host directory where the collect-config action saves the kubeadm configuration artifacts
+func CollectDir(dir string) Option {
+	return func(r *RunOptions) {
+		r.collectDir = dir
+	}
+}
+
+// MigrateConfig option sets the host path of the kubeadm config migrated by the kubeadm-config-migrate action,
+// and the host path where the migrated config is written; if the latter is empty, the migrated config is printed
+func MigrateConfig(configPath, outputPath string) Option {
+	return func(r *RunOptions) {
+		r.migrateConfig = configPath
+		r.migrateOutput = outputPath
+	}
+}
+
+// ScopedKubeConfigOptions option sets the service account, in the namespace/name form, or the user and groups
+// for the kubeconfig generated by the scoped-kubeconfig action, and the host path where it is written;
+// if the latter is empty, the kubeconfig is printed
+func ScopedKubeConfigOptions(serviceAccount, user string, groups []string, outputPath string) Option {
+	return func(r *RunOptions) {
+		r.kubeConfigServiceAccount = serviceAccount
+		r.kubeConfigUser = user
+		r.kubeConfigGroups = groups
+		r.kubeConfigOutput = outputPath
+	}
+}
+
+// SkipVersionSkewCheck option instructs the kubeadm-join and kubeadm-upgrade actions to not check the version
+// skew of the cluster before running, e.g. for testing unsupported skews
+func SkipVersionSkewCheck(skip bool) Option {
+	return func(r *RunOptions) {
+		r.skipVersionSkewCheck = skip
+	}
+}
+
+// ValidateConfig option instructs the kubeadm-init, kubeadm-join and kubeadm-upgrade actions to run kubeadm config
+// validate against the kubeadm config before running kubeadm, like the kubeadm-config-validate action
+func ValidateConfig(validate bool) Option {
+	return func(r *RunOptions) {
+		r.validateConfig = validate
+	}
+}
+
+// KubeletServingCSRWatch option instructs the kubelet-serving-csr-approve action to keep approving the certificate
+// signing requests created by the kubelet serving certificate rotation, until each kubelet rotated its certificate
+func KubeletServingCSRWatch(watch bool) Option {
+	return func(r *RunOptions) {
+		r.kubeletServingCSRWatch = watch
+	}
+}
+
+// PartitionNodes option instructs the network-partition action to partition the nodes matching the given node selectors
+func PartitionNodes(nodeSelectors []string) Option {
+	return func(r *RunOptions) {
+		r.partitionNodes = nodeSelectors
+	}
+}
+
+// PullImagesOptions option sets the images pulled by the pull-images action, and the node selectors for the nodes
+// where they are pulled
+func PullImagesOptions(images, nodeSelectors []string) Option {
+	return func(r *RunOptions) {
+		r.pullImages = images
+		r.pullNodes = nodeSelectors
+	}
+}
+
+// DigestImages option sets the images checked by the image-digest-check action; if empty, all the images are checked
+func DigestImages(images []string) Option {
+	return func(r *RunOptions) {
+		r.digestImages = images
+	}
+}
+
+// Drain option instructs the kubeadm-reset and kubeadm-upgrade actions to drain nodes before disrupting them
+func Drain(opts DrainOptions) Option {
+	return func(r *RunOptions) {
+		r.drain = opts
+	}
+}
+
+// ChaosOptions option sets the control-plane component and the node selector for the chaos action, and instructs
+// the chaos action to verify the component is recreated; empty component and node selector mean random targets
+func ChaosOptions(component, nodeSelector string, verify bool) Option {
+	return func(r *RunOptions) {
+		r.chaosComponent = component
+		r.chaosNode = nodeSelector
+		r.chaosVerify = verify
+	}
+}
+
+// SmokeTestOptions option sets the workload image used by the smoke-test action, e.g. for air-gapped environments,
+// and instructs the smoke-test action to test also pod-to-service connectivity
+func SmokeTestOptions(image string, serviceConnectivity bool) Option {
+	return func(r *RunOptions) {
+		r.smokeTestImage = image
+		r.smokeTestService = serviceConnectivity
+	}
+}
+
+// DNSCheckOptions option sets the image of the pod resolving names in the dns-check action, and the external name
+// resolved for checking forwarding to the upstream resolvers; an empty external name skips the check
+func DNSCheckOptions(image, externalName string) Option {
+	return func(r *RunOptions) {
+		r.dnsCheckImage = image
+		r.dnsCheckExternalName = externalName
+	}
+}
+
+// E2ETests option sets the e2e tests run by the e2e-subset action, and the image providing the e2e test binary
+func E2ETests(opts E2ESubsetOptions) Option {
+	return func(r *RunOptions) {
+		r.e2eSubset = opts
+	}
+}
+
+// PodStartup option sets how the pod-startup-latency action measures pod startup latency, and instructs
+// the kubeadm-join action to measure it on each worker node after join
+func PodStartup(afterJoin bool, opts PodStartupOptions) Option {
+	return func(r *RunOptions) {
+		r.podStartup = opts
+		r.measurePodStartup = afterJoin
+	}
+}
+
+// JoinParallelism option sets the maximum number of worker nodes joining at the same time in the kubeadm-join action;
+// control-plane nodes always join one at a time
+func JoinParallelism(parallelism int) Option {
+	return func(r *RunOptions) {
+		r.joinParallelism = parallelism
+	}
+}
+
+// ResourceUsage option instructs actions.Run to sample the CPU and memory usage of the control-plane node containers
+// every interval while the action runs, and to write the samples as a CSV time series to the given host file
+func ResourceUsage(path string, interval time.Duration) Option {
+	return func(r *RunOptions) {
+		r.resourceUsagePath = path
+		r.resourceUsageInterval = interval
+	}
+}
+
 // RunOptions holds options supplied to actions.Run
 type RunOptions struct {
-	usePhases             bool
-	copyCertsMode         CopyCertsMode
-	discoveryMode         DiscoveryMode
-	wait                  time.Duration
-	upgradeVersion        *K8sVersion.Version
-	vLevel                int
-	patchesDir            string
-	ignorePreflightErrors string
-	kubeadmConfigVersion  string
-	featureGate           string
-	encryptionAlgorithm   string
+	usePhases              bool
+	copyCertsMode          CopyCertsMode
+	discoveryMode          DiscoveryMode
+	wait                   time.Duration
+	waitCoreDNS            bool
+	waitAllNodes           bool
+	refreshCertsAfter      time.Duration
+	joinParallelism        int
+	upgradeVersion         *K8sVersion.Version
+	upgradeNodes           []string
+	partitionNodes         []string
+	pullImages             []string
+	pullNodes              []string
+	digestImages           []string
+	drain                  DrainOptions
+	chaosComponent         string
+	chaosNode              string
+	chaosVerify            bool
+	vLevel                 int
+	patchesDir             string
+	ignorePreflightErrors  string
+	kubeadmConfigVersion   string
+	featureGate            string
+	encryptionAlgorithm    string
+	dnsDomain              string
+	cgroupDriver           string
+	criSocket              string
+	force                  bool
+	fix                    bool
+	apiServerCertSANs      []string
+	corruptCertMode        CorruptCertMode
+	apiResources           []string
+	snapshotPath           string
+	collectDir             string
+	migrateConfig          string
+	skipVersionSkewCheck   bool
+	validateConfig         bool
+	migrateOutput          string
+	smokeTestImage         string
+	smokeTestService       bool
+	dnsCheckImage          string
+	dnsCheckExternalName   string
+	kubeletServingCSRWatch bool
+	e2eSubset              E2ESubsetOptions
+	podStartup             PodStartupOptions
+	measurePodStartup      bool
+	tlsMinVersion          string
+	tlsCipherSuites        []string
+	resourceUsagePath      string
+	resourceUsageInterval  time.Duration
+
+	kubeConfigServiceAccount string
+	kubeConfigUser           string
+	kubeConfigGroups         []string
+	kubeConfigOutput         string
+}
+
+// ignorePreflightErrorsOrDefault returns the preflight errors to ignore, or the kinder default list if not set
+func (r *RunOptions) ignorePreflightErrorsOrDefault() string {
+	if r.ignorePreflightErrors == "" {
+		return constants.KubeadmIgnorePreflightErrors
+	}
+	return r.ignorePreflightErrors
+}
+
+// kubeadmInitOptions returns the options for the kubeadm-init action
+func (r *RunOptions) kubeadmInitOptions() KubeadmInitOptions {
+	return KubeadmInitOptions{
+		UsePhases:             r.usePhases,
+		CopyCertsMode:         r.copyCertsMode,
+		ConfigVersion:         r.kubeadmConfigVersion,
+		PatchesDir:            r.patchesDir,
+		IgnorePreflightErrors: r.ignorePreflightErrors,
+		FeatureGate:           r.featureGate,
+		EncryptionAlgorithm:   r.encryptionAlgorithm,
+		DNSDomain:             r.dnsDomain,
+		CgroupDriver:          r.cgroupDriver,
+		CRISocket:             r.criSocket,
+		TLSMinVersion:         r.tlsMinVersion,
+		TLSCipherSuites:       r.tlsCipherSuites,
+		ValidateConfig:        r.validateConfig,
+		Wait:                  r.wait,
+		VLevel:                r.vLevel,
+	}
+}
+
+// kubeadmJoinOptions returns the options for the kubeadm-join action
+func (r *RunOptions) kubeadmJoinOptions() KubeadmJoinOptions {
+	return KubeadmJoinOptions{
+		UsePhases:             r.usePhases,
+		CopyCertsMode:         r.copyCertsMode,
+		DiscoveryMode:         r.discoveryMode,
+		ConfigVersion:         r.kubeadmConfigVersion,
+		PatchesDir:            r.patchesDir,
+		IgnorePreflightErrors: r.ignorePreflightErrorsOrDefault(),
+		CRISocket:             r.criSocket,
+		WaitCoreDNS:           r.waitCoreDNS,
+		WaitAllNodes:          r.waitAllNodes,
+		Force:                 r.force,
+		PodStartup:            r.podStartupAfterJoin(),
+		RefreshCertsAfter:     r.refreshCertsAfter,
+		ValidateConfig:        r.validateConfig,
+		WorkerParallelism:     r.joinParallelism,
+		Wait:                  r.wait,
+		VLevel:                r.vLevel,
+	}
+}
+
+// kubeadmUpgradeOptions returns the options for the kubeadm-upgrade action
+func (r *RunOptions) kubeadmUpgradeOptions() KubeadmUpgradeOptions {
+	return KubeadmUpgradeOptions{
+		UpgradeVersion: r.upgradeVersion,
+		NodeSelectors:  r.upgradeNodes,
+		PatchesDir:     r.patchesDir,
+		FeatureGate:    r.featureGate,
+		Drain:          r.drain,
+		ValidateConfig: r.validateConfig,
+		Wait:           r.wait,
+		VLevel:         r.vLevel,
+	}
+}
+
+// podStartupAfterJoin returns the options for measuring pod startup latency after join, if requested
+func (r *RunOptions) podStartupAfterJoin() *PodStartupOptions {
+	if !r.measurePodStartup {
+		return nil
+	}
+	return &r.podStartup
 }
 
 // DiscoveryMode defines discovery mode supported by kubeadm join
@@ -239,6 +710,10 @@ const (
 
 	// CopyCertsModeAuto copies certs using the --upload-certs / --certificate-key functionality
 	CopyCertsModeAuto = CopyCertsMode("auto")
+
+	// CopyCertsModeExternalCA copies the shared certs and kubeconfig files for clusters without the CA key,
+	// where the node certificates are pre-generated, e.g. by the setup-external-ca action
+	CopyCertsModeExternalCA = CopyCertsMode("external-ca")
 )
 
 // KnownCopyCertsMode returns the list of known CopyCertsMode
@@ -247,6 +722,7 @@ func KnownCopyCertsMode() []string {
 		string(CopyCertsModeNone),
 		string(CopyCertsModeManual),
 		string(CopyCertsModeAuto),
+		string(CopyCertsModeExternalCA),
 	}
 }
 
@@ -256,6 +732,7 @@ func ValidateCopyCertsMode(t CopyCertsMode) error {
 	case CopyCertsModeNone:
 	case CopyCertsModeManual:
 	case CopyCertsModeAuto:
+	case CopyCertsModeExternalCA:
 	default:
 		return errors.Errorf("invalid copy-certs mode. Use one of %s", KnownCopyCertsMode())
 	}
@@ -264,14 +741,64 @@ func ValidateCopyCertsMode(t CopyCertsMode) error {
 
 // Run executes one action
 func Run(c *status.Cluster, action string, options ...Option) error {
-	flags := &RunOptions{}
+	flags := &RunOptions{
+		dnsDomain:    constants.DefaultDNSDomain,
+		cgroupDriver: constants.DefaultCgroupDriver,
+	}
 	for _, o := range options {
 		o(flags)
 	}
 
 	if a, ok := actionRegistry[action]; ok {
-		return a(c, flags)
+		warnModifiedNodes(c)
+
+		if blocking, ok := versionSkewCheckedActions[action]; ok && !flags.skipVersionSkewCheck {
+			if err := preActionVersionSkewCheck(c, action, blocking); err != nil {
+				return err
+			}
+		}
+
+		// the action name is added to the JSON log entries, together with the action duration
+		exec.SetLogAction(action)
+		defer exec.SetLogAction("")
+		// eventually, sample the resource usage of the control-plane nodes while the action runs
+		var recorder *resourceUsageRecorder
+		if flags.resourceUsagePath != "" {
+			var err error
+			if recorder, err = startResourceUsageRecorder(c.ControlPlanes(), flags.resourceUsagePath, flags.resourceUsageInterval); err != nil {
+				return err
+			}
+		}
+
+		start := time.Now()
+		err := a(c, flags)
+		logger := exec.Logger("").WithField("duration", time.Since(start).String())
+		if recorder != nil {
+			if rerr := recorder.Stop(); rerr != nil {
+				log.Warnf("failed to record the resource usage: %v", rerr)
+			}
+			logger = logger.WithField("resourceUsage", flags.resourceUsagePath)
+		}
+		// with the text log format, the error is printed only once by the caller
+		if err != nil {
+			if exec.IsJSONLog() {
+				logger.WithError(err).Error("action failed")
+			}
+		} else {
+			logger.Info("action completed")
+		}
+		return err
 	}
 
 	return errors.Errorf("%s is not a valid action name. Use one of %s", action, KnownActions())
 }
+
+// warnModifiedNodes warns when executing actions on nodes created with a command override, e.g. for
+// fault injection, because they might not behave as expected
+func warnModifiedNodes(c *status.Cluster) {
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if command, err := n.CommandOverride(); err == nil && command != "" {
+			log.Warnf("node %s was created with a command override, so it might not behave as expected", n.Name())
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// APIResources holds the resources served by the API server, indexed by groupVersion, e.g. v1 or apps/v1;
+// subresources, e.g. pods/log, are not included
+type APIResources map[string][]string
+
+// GroupVersions returns the served groupVersions, sorted
+func (r APIResources) GroupVersions() []string {
+	groupVersions := make([]string, 0, len(r))
+	for gv := range r {
+		groupVersions = append(groupVersions, gv)
+	}
+	sort.Strings(groupVersions)
+	return groupVersions
+}
+
+// IsServed returns true if the resource is served at the groupVersion, e.g. cronjobs at batch/v1
+func (r APIResources) IsServed(groupVersion, resource string) bool {
+	for _, res := range r[groupVersion] {
+		if res == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertServed returns an error if the served state of the resource at the groupVersion is not the expected one
+func (r APIResources) AssertServed(groupVersion, resource string, served bool) error {
+	if r.IsServed(groupVersion, resource) == served {
+		return nil
+	}
+	if served {
+		if _, ok := r[groupVersion]; !ok {
+			return errors.Errorf("%s/%s is not served: the API server does not serve %s", groupVersion, resource, groupVersion)
+		}
+		return errors.Errorf("%s/%s is not served", groupVersion, resource)
+	}
+	return errors.Errorf("%s/%s is served", groupVersion, resource)
+}
+
+// GetAPIResources queries the discovery endpoints of the API server, using the admin kubeconfig on the bootstrap
+// control-plane node and the API server endpoint, and returns the served groupVersions and resources;
+// this is the programmatic equivalent of kubectl api-resources for all the served versions.
+func GetAPIResources(c *status.Cluster) (APIResources, error) {
+	endpoint, err := c.APIServerEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	cp1 := c.BootstrapControlPlane()
+	getRaw := func(path string) ([]byte, error) {
+		lines, err := cp1.Command(
+			"kubectl", fmt.Sprintf("--kubeconfig=%s", adminConfPath), fmt.Sprintf("--server=https://%s", endpoint), "get", "--raw", path,
+		).Silent().RunAndCapture()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query the discovery endpoint %s", path)
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+
+	core, err := getRaw("/api")
+	if err != nil {
+		return nil, err
+	}
+	groups, err := getRaw("/apis")
+	if err != nil {
+		return nil, err
+	}
+	groupVersions, err := parseServedGroupVersions(core, groups)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := APIResources{}
+	for _, gv := range groupVersions {
+		path := "/apis/" + gv
+		if !strings.Contains(gv, "/") {
+			path = "/api/" + gv
+		}
+		raw, err := getRaw(path)
+		if err != nil {
+			return nil, err
+		}
+		if resources[gv], err = parseAPIResourceList(raw); err != nil {
+			return nil, errors.Wrapf(err, "invalid discovery response for %s", gv)
+		}
+	}
+	return resources, nil
+}
+
+// parseServedGroupVersions returns the groupVersions listed by the /api and /apis discovery endpoints
+func parseServedGroupVersions(core, groups []byte) ([]string, error) {
+	var apiVersions struct {
+		Versions []string `json:"versions"`
+	}
+	if err := json.Unmarshal(core, &apiVersions); err != nil {
+		return nil, errors.Wrap(err, "invalid /api discovery response")
+	}
+	var apiGroupList struct {
+		Groups []struct {
+			Versions []struct {
+				GroupVersion string `json:"groupVersion"`
+			} `json:"versions"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal(groups, &apiGroupList); err != nil {
+		return nil, errors.Wrap(err, "invalid /apis discovery response")
+	}
+
+	groupVersions := append([]string{}, apiVersions.Versions...)
+	for _, g := range apiGroupList.Groups {
+		for _, v := range g.Versions {
+			groupVersions = append(groupVersions, v.GroupVersion)
+		}
+	}
+	return groupVersions, nil
+}
+
+// parseAPIResourceList returns the sorted resources in a groupVersion discovery response, without subresources
+func parseAPIResourceList(raw []byte) ([]string, error) {
+	var list struct {
+		Resources []struct {
+			Name string `json:"name"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	resources := []string{}
+	for _, r := range list.Resources {
+		if strings.Contains(r.Name, "/") {
+			continue
+		}
+		resources = append(resources, r.Name)
+	}
+	sort.Strings(resources)
+	return resources, nil
+}
+
+// APIResourceExpectation defines a resource expected to be served, or not served, at a groupVersion
+type APIResourceExpectation struct {
+	GroupVersion string
+	Resource     string
+	Served       bool
+}
+
+func (e APIResourceExpectation) String() string {
+	if e.Served {
+		return fmt.Sprintf("%s/%s", e.GroupVersion, e.Resource)
+	}
+	return fmt.Sprintf("!%s/%s", e.GroupVersion, e.Resource)
+}
+
+// ParseAPIResourceExpectation parses an expectation in the groupVersion/resource form, e.g. batch/v1/cronjobs or
+// v1/pods for the core group; the ! prefix expects the resource to not be served, e.g. !policy/v1beta1/podsecuritypolicies
+func ParseAPIResourceExpectation(s string) (APIResourceExpectation, error) {
+	e := APIResourceExpectation{Served: !strings.HasPrefix(s, "!")}
+	v := strings.TrimPrefix(s, "!")
+	i := strings.LastIndex(v, "/")
+	if i <= 0 || i == len(v)-1 || strings.Count(v, "/") > 2 {
+		return e, errors.Errorf("invalid API resource %q. Use the [!]groupVersion/resource form, e.g. batch/v1/cronjobs or !policy/v1beta1/podsecuritypolicies", s)
+	}
+	e.GroupVersion, e.Resource = v[:i], v[i+1:]
+	return e, nil
+}
+
+// ValidateAPIResourceExpectations validates a list of API resource expectations
+func ValidateAPIResourceExpectations(expectations []string) error {
+	for _, s := range expectations {
+		if _, err := ParseAPIResourceExpectation(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// APIResourcesCheck lists the groupVersions and the resources served by the API server, and asserts the given
+// resources are served or not served, e.g. for testing API deprecations and removals across versions
+func APIResourcesCheck(c *status.Cluster, expectations []string) error {
+	resources, err := GetAPIResources(c)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUPVERSION\tRESOURCES")
+	for _, gv := range resources.GroupVersions() {
+		fmt.Fprintf(w, "%s\t%s\n", gv, strings.Join(resources[gv], ","))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, s := range expectations {
+		e, err := ParseAPIResourceExpectation(s)
+		if err != nil {
+			return err
+		}
+		if err := resources.AssertServed(e.GroupVersion, e.Resource, e.Served); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("API resources check failed:\n%s", strings.Join(failed, "\n"))
+	}
+	if len(expectations) > 0 {
+		exec.Printf("\nAPI resources check passed for %s\n", strings.Join(expectations, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseServedGroupVersions(t *testing.T) {
+	core := []byte(`{"kind":"APIVersions","versions":["v1"]}`)
+	groups := []byte(`{"kind":"APIGroupList","groups":[
+		{"name":"apps","versions":[{"groupVersion":"apps/v1","version":"v1"}]},
+		{"name":"batch","versions":[{"groupVersion":"batch/v1","version":"v1"},{"groupVersion":"batch/v1beta1","version":"v1beta1"}]}
+	]}`)
+	groupVersions, err := parseServedGroupVersions(core, groups)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"v1", "apps/v1", "batch/v1", "batch/v1beta1"}
+	if !reflect.DeepEqual(groupVersions, expected) {
+		t.Errorf("expected %v, got %v", expected, groupVersions)
+	}
+
+	if _, err := parseServedGroupVersions([]byte("not json"), groups); err == nil {
+		t.Error("expected error for an invalid /api response")
+	}
+}
+
+func TestParseAPIResourceList(t *testing.T) {
+	raw := []byte(`{"kind":"APIResourceList","groupVersion":"v1","resources":[
+		{"name":"pods","namespaced":true},{"name":"pods/log","namespaced":true},{"name":"nodes","namespaced":false}
+	]}`)
+	resources, err := parseAPIResourceList(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"nodes", "pods"}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("expected %v, got %v", expected, resources)
+	}
+}
+
+func TestAPIResourcesAssertServed(t *testing.T) {
+	resources := APIResources{
+		"v1":       {"nodes", "pods"},
+		"batch/v1": {"cronjobs", "jobs"},
+	}
+	tests := []struct {
+		expectation   string
+		expectedError bool
+	}{
+		{expectation: "v1/pods"},
+		{expectation: "batch/v1/cronjobs"},
+		{expectation: "!batch/v1beta1/cronjobs"},
+		{expectation: "!policy/v1beta1/podsecuritypolicies"},
+		{expectation: "batch/v1beta1/cronjobs", expectedError: true},
+		{expectation: "!v1/pods", expectedError: true},
+		{expectation: "batch/v1/deployments", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expectation, func(t *testing.T) {
+			e, err := ParseAPIResourceExpectation(test.expectation)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = resources.AssertServed(e.GroupVersion, e.Resource, e.Served)
+			if (err != nil) != test.expectedError {
+				t.Errorf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestParseAPIResourceExpectation(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      APIResourceExpectation
+		expectedError bool
+	}{
+		{input: "v1/pods", expected: APIResourceExpectation{GroupVersion: "v1", Resource: "pods", Served: true}},
+		{input: "apps/v1/deployments", expected: APIResourceExpectation{GroupVersion: "apps/v1", Resource: "deployments", Served: true}},
+		{input: "!policy/v1beta1/podsecuritypolicies", expected: APIResourceExpectation{GroupVersion: "policy/v1beta1", Resource: "podsecuritypolicies"}},
+		{input: "pods", expectedError: true},
+		{input: "apps/v1/", expectedError: true},
+		{input: "a/b/c/d", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			e, err := ParseAPIResourceExpectation(test.input)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err == nil && e != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, e)
+			}
+		})
+	}
+}
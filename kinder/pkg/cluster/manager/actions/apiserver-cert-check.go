@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// apiServerCertDialTimeout is the timeout for connecting to the API server when reading the served certificate
+const apiServerCertDialTimeout = 10 * time.Second
+
+// APIServerCertCheck verifies that the certificate served by the API server on each control-plane node includes
+// the node advertise address and the control-plane endpoint as SANs, so clients don't fail with
+// "certificate is valid for X, not Y"; mismatches are reported with the actual SAN list of the certificate.
+func APIServerCertCheck(c *status.Cluster) error {
+	endpoint, err := c.APIServerEndpoint()
+	if err != nil {
+		return err
+	}
+	endpointHost, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "invalid control-plane endpoint %s", endpoint)
+	}
+
+	var mismatches []string
+	for _, cp := range c.ControlPlanes().EligibleForActions() {
+		ipv4, ipv6, err := cp.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node %s", cp.Name())
+		}
+		advertiseAddress := ipv4
+		if c.Settings != nil && c.Settings.IPFamily == status.IPv6Family {
+			advertiseAddress = ipv6
+		}
+
+		cert, err := servedAPIServerCert(c, cp)
+		if err != nil {
+			return err
+		}
+
+		missing := missingCertSANs(cert, []string{advertiseAddress, endpointHost})
+		if len(missing) == 0 {
+			cp.Infof("the API server certificate includes the advertise address %s and the control-plane endpoint %s", advertiseAddress, endpointHost)
+			continue
+		}
+		mismatch := fmt.Sprintf("node %s: the API server certificate is missing the SANs [%s], and it is valid for [%s]",
+			cp.Name(), strings.Join(missing, ", "), strings.Join(certSANs(cert), ", "))
+		cp.Infof("%s", mismatch)
+		mismatches = append(mismatches, mismatch)
+	}
+
+	if len(mismatches) > 0 {
+		return errors.Errorf("API server certificate SANs mismatch; add the missing SANs to apiServer.certSANs in the kubeadm config:\n%s", strings.Join(mismatches, "\n"))
+	}
+	exec.Println("API server certificates SANs are valid")
+	return nil
+}
+
+// servedAPIServerCert returns the certificate served by the API server on a control-plane node, connecting
+// from the host via the host port mapped to the API server bind port
+func servedAPIServerCert(c *status.Cluster, cp *status.Node) (*x509.Certificate, error) {
+	hostPort, err := cp.Ports(c.APIServerBindPort())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the API server host port for node %s", cp.Name())
+	}
+
+	address := net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort))
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: apiServerCertDialTimeout}, "tcp", address, &tls.Config{
+		// the certificate is only inspected, so it is not verified
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to the API server on node %s", cp.Name())
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.Errorf("the API server on node %s didn't serve a certificate", cp.Name())
+	}
+	return certs[0], nil
+}
+
+// missingCertSANs returns the names, DNS names or IPs, the certificate isn't valid for
+func missingCertSANs(cert *x509.Certificate, names []string) []string {
+	var missing []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if err := cert.VerifyHostname(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// certSANs returns the DNS names and the IPs of a certificate
+func certSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/x509"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestMissingCertSANs(t *testing.T) {
+	cert := &x509.Certificate{
+		DNSNames:    []string{"kubernetes", "kind-control-plane-1", "*.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.96.0.1"), net.ParseIP("172.17.0.3"), net.ParseIP("fc00::3")},
+	}
+
+	tests := []struct {
+		name     string
+		names    []string
+		expected []string
+	}{
+		{
+			name:  "all the names are included",
+			names: []string{"172.17.0.3", "fc00::3", "kind-control-plane-1"},
+		},
+		{
+			name:  "wildcard DNS name",
+			names: []string{"lb.example.com"},
+		},
+		{
+			name:     "missing advertise address",
+			names:    []string{"172.17.0.4", "172.17.0.3"},
+			expected: []string{"172.17.0.4"},
+		},
+		{
+			name:     "missing names are not duplicated",
+			names:    []string{"172.17.0.2", "172.17.0.2", "", "kind-lb"},
+			expected: []string{"172.17.0.2", "kind-lb"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := missingCertSANs(cert, test.names); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+
+	expectedSANs := []string{"kubernetes", "kind-control-plane-1", "*.example.com", "10.96.0.1", "172.17.0.3", "fc00::3"}
+	if got := certSANs(cert); !reflect.DeepEqual(got, expectedSANs) {
+		t.Errorf("expected SANs %v, got %v", expectedSANs, got)
+	}
+}
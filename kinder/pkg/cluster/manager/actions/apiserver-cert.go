@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+const (
+	apiServerCertPath = "/etc/kubernetes/pki/apiserver.crt"
+	apiServerKeyPath  = "/etc/kubernetes/pki/apiserver.key"
+	caCertPath        = "/etc/kubernetes/pki/ca.crt"
+	caKeyPath         = "/etc/kubernetes/pki/ca.key"
+
+	// defaultServiceSubnet is the kubeadm default service subnet
+	defaultServiceSubnet = "10.96.0.0/12"
+)
+
+// APIServerCert replaces the API server serving certificate on the control-plane nodes with a certificate having only
+// the given SANs and the kubernetes service IP, that in-cluster clients require, e.g. for negative tests of SAN validation.
+// Differently from kubeadm, the node IPs and the kubernetes service DNS names are not added.
+func APIServerCert(c *status.Cluster, sans []string) error {
+	if len(sans) == 0 {
+		return errors.New("at least one SAN is required. Use --apiserver-cert-sans")
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	caCert, err := cp1.Command("cat", caCertPath).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s from node %s", caCertPath, cp1.Name())
+	}
+	caKey, err := cp1.Command("cat", caKeyPath).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s from node %s. The CA key is required for signing the certificate", caKeyPath, cp1.Name())
+	}
+
+	serviceSubnet, err := clusterServiceSubnet(c)
+	if err != nil {
+		return err
+	}
+	virtualIP, err := apiServerVirtualIP(serviceSubnet)
+	if err != nil {
+		return err
+	}
+
+	dnsNames, ips := apiServerCertSANs(sans, virtualIP)
+	certPEM, keyPEM, err := signAPIServerCert([]byte(strings.Join(caCert, "\n")), []byte(strings.Join(caKey, "\n")), dnsNames, ips)
+	if err != nil {
+		return err
+	}
+
+	for _, cp := range c.ControlPlanes().EligibleForActions() {
+		cp.Infof("writing API server certificate with SANs %s", strings.Join(sans, ", "))
+		if err := cp.WriteFile(apiServerCertPath, certPEM); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", apiServerCertPath, cp.Name())
+		}
+		if err := cp.WriteFile(apiServerKeyPath, keyPEM); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", apiServerKeyPath, cp.Name())
+		}
+	}
+	return nil
+}
+
+// clusterServiceSubnet returns the service subnet of the cluster, as defined in the kubeadm-config ConfigMap, so
+// also custom subnets set with patches are considered; if not defined, the service subnet kinder sets for the
+// IP family of the cluster is used, or the kubeadm default
+func clusterServiceSubnet(c *status.Cluster) (string, error) {
+	live, err := readLiveClusterConfiguration(c.BootstrapControlPlane())
+	if err != nil {
+		return "", err
+	}
+	if live.Networking.ServiceSubnet != "" {
+		return live.Networking.ServiceSubnet, nil
+	}
+
+	if c.Settings != nil {
+		if _, serviceSubnet := clusterSubnets(c.Settings.IPFamily); serviceSubnet != "" {
+			return serviceSubnet, nil
+		}
+	}
+	return defaultServiceSubnet, nil
+}
+
+// apiServerVirtualIP returns the API server virtual IP, that is the first IP of the service subnet; with dual-stack,
+// the first subnet in the list, that is the primary IP family, is used like in kubeadm
+func apiServerVirtualIP(serviceSubnet string) (net.IP, error) {
+	subnet := strings.TrimSpace(strings.Split(serviceSubnet, ",")[0])
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid service subnet %q", serviceSubnet)
+	}
+	ip := make(net.IP, len(ipNet.IP))
+	copy(ip, ipNet.IP)
+	ip[len(ip)-1]++
+	if !ipNet.Contains(ip) {
+		return nil, errors.Errorf("the service subnet %q is too small", serviceSubnet)
+	}
+	return ip, nil
+}
+
+// apiServerCertSANs splits the given SANs into DNS names and IPs, and adds the mandatory API server virtual IP
+func apiServerCertSANs(sans []string, virtualIP net.IP) ([]string, []net.IP) {
+	var dnsNames []string
+	ips := []net.IP{virtualIP}
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			if !ip.Equal(ips[0]) {
+				ips = append(ips, ip)
+			}
+			continue
+		}
+		dnsNames = append(dnsNames, san)
+	}
+	return dnsNames, ips
+}
+
+// signAPIServerCert creates a new key and an API server serving certificate with exactly the given SANs,
+// signed by the given CA; certificate and key are returned PEM encoded
+func signAPIServerCert(caCertPEM, caKeyPEM []byte, dnsNames []string, ips []net.IP) ([]byte, []byte, error) {
+	return signCert(caCertPEM, caKeyPEM, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kube-apiserver"},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+}
+
+// signCert creates a new key and a certificate from the given template, signed by the given CA; the serial number
+// is set by this function, and, if not set in the template, also the validity, with the certificate expiring
+// together with the CA. Certificate and key are returned PEM encoded
+func signCert(caCertPEM, caKeyPEM []byte, template *x509.Certificate) ([]byte, []byte, error) {
+	block, _ := pem.Decode(caCertPEM)
+	if block == nil {
+		return nil, nil, errors.New("failed to decode the CA certificate")
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse the CA certificate")
+	}
+
+	caKey, err := parsePrivateKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to generate the key for %s", template.Subject.CommonName)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate the certificate serial number")
+	}
+
+	template.SerialNumber = serial
+	if template.NotAfter.IsZero() {
+		template.NotBefore = time.Now().Add(-5 * time.Minute).UTC()
+		template.NotAfter = caCert.NotAfter
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to sign the certificate for %s", template.Subject.CommonName)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// parsePrivateKey parses a PEM encoded private key in the PKCS1, EC or PKCS8 form
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("failed to decode the CA key")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse the CA key")
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("the CA key can't be used for signing")
+		}
+		return signer, nil
+	default:
+		return nil, errors.Errorf("unknown CA key type %q", block.Type)
+	}
+}
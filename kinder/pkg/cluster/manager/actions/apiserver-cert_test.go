@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAPIServerCertSANs(t *testing.T) {
+	tests := []struct {
+		name             string
+		sans             []string
+		expectedDNSNames []string
+		expectedIPs      []string
+	}{
+		{
+			name:        "only the virtual IP",
+			expectedIPs: []string{"10.96.0.1"},
+		},
+		{
+			name:             "DNS names and IPs",
+			sans:             []string{"example.com", "172.17.0.2"},
+			expectedDNSNames: []string{"example.com"},
+			expectedIPs:      []string{"10.96.0.1", "172.17.0.2"},
+		},
+		{
+			name:        "the virtual IP is not duplicated",
+			sans:        []string{"10.96.0.1"},
+			expectedIPs: []string{"10.96.0.1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dnsNames, ips := apiServerCertSANs(test.sans, net.ParseIP("10.96.0.1"))
+			if !reflect.DeepEqual(dnsNames, test.expectedDNSNames) {
+				t.Fatalf("expected DNS names: %v, found %v", test.expectedDNSNames, dnsNames)
+			}
+			var ipStrings []string
+			for _, ip := range ips {
+				ipStrings = append(ipStrings, ip.String())
+			}
+			if !reflect.DeepEqual(ipStrings, test.expectedIPs) {
+				t.Fatalf("expected IPs: %v, found %v", test.expectedIPs, ipStrings)
+			}
+		})
+	}
+}
+
+func TestAPIServerVirtualIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		serviceSubnet string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:          "kubeadm default",
+			serviceSubnet: "10.96.0.0/12",
+			expected:      "10.96.0.1",
+		},
+		{
+			name:          "custom subnet",
+			serviceSubnet: "10.100.8.0/22",
+			expected:      "10.100.8.1",
+		},
+		{
+			name:          "IPv6",
+			serviceSubnet: "fd00:10:96::/112",
+			expected:      "fd00:10:96::1",
+		},
+		{
+			name:          "dual-stack uses the primary IP family",
+			serviceSubnet: "fd00:10:96::/112,10.96.0.0/12",
+			expected:      "fd00:10:96::1",
+		},
+		{
+			name:          "invalid subnet",
+			serviceSubnet: "10.96.0.0",
+			expectedError: true,
+		},
+		{
+			name:          "subnet too small",
+			serviceSubnet: "10.96.0.1/32",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ip, err := apiServerVirtualIP(test.serviceSubnet)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v", test.expectedError, err)
+			}
+			if err == nil && ip.String() != test.expected {
+				t.Errorf("expected %s, found %s", test.expected, ip)
+			}
+		})
+	}
+}
+
+func TestSignAPIServerCert(t *testing.T) {
+	caCertPEM, caKeyPEM := newTestCA(t)
+
+	certPEM, keyPEM, err := signAPIServerCert(caCertPEM, caKeyPEM, []string{"example.com"}, []net.IP{net.ParseIP("10.96.0.1")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := parsePrivateKey(keyPEM); err != nil {
+		t.Fatalf("unexpected error parsing the key: %v", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cert.DNSNames, []string{"example.com"}) || len(cert.IPAddresses) != 1 {
+		t.Fatalf("unexpected SANs: %v %v", cert.DNSNames, cert.IPAddresses)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCertPEM)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "example.com"}); err != nil {
+		t.Fatalf("failed to verify the certificate: %v", err)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "kubernetes.default"}); err == nil {
+		t.Fatal("expected the certificate to be invalid for kubernetes.default")
+	}
+}
+
+// newTestCA returns the PEM encoded certificate and key of a CA valid for one hour
+func newTestCA(t *testing.T) ([]byte, []byte) {
+	// creates a CA using an EC key, to check CA keys different from the API server key are supported
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubernetes"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	caKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER})
+	return caCertPEM, caKeyPEM
+}
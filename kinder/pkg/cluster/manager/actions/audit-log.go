@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// AuditLog action saves in a directory on the host the API server audit log of each control-plane node
+// eligible for actions, each one in a sub directory named like the node, so it can be used for assertions
+// on the audit policy; audit logging must be enabled at create time.
+func AuditLog(c *status.Cluster, dir string) error {
+	dir, err := absCollectDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var collected []string
+	for _, n := range c.ControlPlanes().EligibleForActions() {
+		logPath, err := n.AuditLogPath()
+		if err != nil {
+			return err
+		}
+		if logPath == "" {
+			return errors.Errorf("audit logging is not enabled on node %s. Use --audit-policy or --audit-log-path at create time", n.Name())
+		}
+
+		n.Infof("collecting the audit log %s", logPath)
+		path, err := collectNodeFile(n, logPath, dir)
+		if err != nil {
+			return err
+		}
+		collected = append(collected, path)
+	}
+
+	exec.Printf("\nCollected audit logs:\n  %s\n", strings.Join(collected, "\n  "))
+	return nil
+}
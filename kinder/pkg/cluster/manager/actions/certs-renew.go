@@ -0,0 +1,249 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// renewedKubeConfigs are the kubeconfig files with an embedded client certificate renewed by kubeadm certs renew;
+// super-admin.conf exists only for kubeadm v1.29 and newer, so missing files are skipped
+var renewedKubeConfigs = []string{"admin.conf", "super-admin.conf", "controller-manager.conf", "scheduler.conf"}
+
+// certRenewal records the expiry of the API server serving certificate on a node before and after renewal
+type certRenewal struct {
+	node      string
+	oldExpiry time.Time
+	newExpiry time.Time
+}
+
+// certRenewals is the list of renewals executed by KubeadmCertsRenew
+type certRenewals []certRenewal
+
+func (r certRenewals) String() string {
+	var b strings.Builder
+	for _, x := range r {
+		fmt.Fprintf(&b, "%s: %s -> %s\n", x.node, x.oldExpiry.UTC().Format(time.RFC3339), x.newExpiry.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// KubeadmCertsRenew renews all the certificates managed by kubeadm on the control-plane nodes, restarts the
+// control-plane static pods, and checks that the API server serves a certificate with a later expiry than before,
+// and that the client certificates embedded in the kubeconfig files were renewed and authenticate.
+// Nodes are renewed one at a time, so the etcd quorum is preserved with stacked etcd.
+func KubeadmCertsRenew(c *status.Cluster, wait time.Duration) error {
+	var renewals certRenewals
+	for _, cp := range c.ControlPlanes().EligibleForActions() {
+		renewal, err := kubeadmCertsRenew(c, cp, wait)
+		if err != nil {
+			return err
+		}
+		renewals = append(renewals, *renewal)
+	}
+
+	log.Infof("API server certificate expiry before and after renewal:\n%s", renewals)
+	return nil
+}
+
+func kubeadmCertsRenew(c *status.Cluster, cp *status.Node, wait time.Duration) (*certRenewal, error) {
+	oldExpiry, err := servedCertExpiry(c, cp)
+	if err != nil {
+		return nil, err
+	}
+	oldKubeConfigExpiry, err := kubeConfigsCertExpiry(cp)
+	if err != nil {
+		return nil, err
+	}
+
+	cp.Infof("renewing certificates")
+	if err := cp.Command("kubeadm", "certs", "renew", "all").RunWithEcho(); err != nil {
+		return nil, errors.Wrapf(err, "failed to renew certificates on node %s", cp.Name())
+	}
+
+	// the control-plane components read the certificates at startup, so static pods must be restarted
+	if err := stopControlPlane(cp); err != nil {
+		return nil, err
+	}
+	if err := startControlPlane(cp); err != nil {
+		return nil, err
+	}
+
+	if err := waitNewControlPlaneNodeReady(c, cp, wait); err != nil {
+		return nil, err
+	}
+
+	var newExpiry time.Time
+	cp.Infof("waiting for the API server to serve the renewed certificate (timeout %s)", wait)
+	if pass := waitFor(c, cp, wait, func(c *status.Cluster, n *status.Node) bool {
+		expiry, err := servedCertExpiry(c, n)
+		if err != nil || !expiry.After(oldExpiry) {
+			return false
+		}
+		newExpiry = expiry
+		exec.Printf("API server on node %s serves the renewed certificate\n", n.Name())
+		return true
+	}); !pass {
+		return nil, timeoutErrorf("the API server on node %s does not serve a certificate expiring after %s", cp.Name(), oldExpiry.UTC().Format(time.RFC3339))
+	}
+	exec.Println()
+
+	if err := verifyRenewedKubeConfigs(cp, oldKubeConfigExpiry); err != nil {
+		return nil, err
+	}
+
+	return &certRenewal{node: cp.Name(), oldExpiry: oldExpiry, newExpiry: newExpiry}, nil
+}
+
+// servedCertExpiry returns the expiry of the serving certificate of the API server on a control-plane node,
+// connecting via the host port mapped to the API server bind port
+func servedCertExpiry(c *status.Cluster, cp *status.Node) (time.Time, error) {
+	port, err := cp.Ports(c.APIServerBindPort())
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to get the API server host port for node %s", cp.Name())
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))), &tls.Config{
+		// the certificate is inspected only, without sending any request
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to connect to the API server on node %s", cp.Name())
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return time.Time{}, errors.Errorf("the API server on node %s did not serve any certificate", cp.Name())
+	}
+	return certs[0].NotAfter, nil
+}
+
+// kubeConfigsCertExpiry returns the expiry of the client certificate embedded in each kubeconfig renewed by kubeadm
+// on a control-plane node
+func kubeConfigsCertExpiry(cp *status.Node) (map[string]time.Time, error) {
+	expiry := map[string]time.Time{}
+	for _, name := range renewedKubeConfigs {
+		path := filepath.Join("/etc/kubernetes", name)
+		if err := cp.Command("test", "-f", path).Silent().Run(); err != nil {
+			continue
+		}
+		lines, err := cp.Command("cat", path).Silent().RunAndCapture()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s on node %s", path, cp.Name())
+		}
+		notAfter, err := kubeConfigClientCertExpiry([]byte(strings.Join(lines, "\n")))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the client certificate in %s on node %s", path, cp.Name())
+		}
+		expiry[path] = notAfter
+	}
+	return expiry, nil
+}
+
+// verifyRenewedKubeConfigs checks that the client certificate embedded in each kubeconfig was renewed, that it is
+// still valid, and that it authenticates against the API server; all the kubeconfigs failing the checks are reported
+func verifyRenewedKubeConfigs(cp *status.Node, oldExpiry map[string]time.Time) error {
+	newExpiry, err := kubeConfigsCertExpiry(cp)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for path := range oldExpiry {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, path := range paths {
+		if problem := kubeConfigRenewalProblem(oldExpiry[path], newExpiry[path], time.Now()); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", path, problem))
+			continue
+		}
+		// a trivial API call, not allowed to anonymous users, proves the client certificate authenticates
+		if err := cp.Command("kubectl", fmt.Sprintf("--kubeconfig=%s", path), "get", "--raw", "/api").Silent().Run(); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: the client certificate does not authenticate against the API server", path))
+			continue
+		}
+		exec.Printf("%s on node %s: client certificate renewed, expiring %s\n", path, cp.Name(), newExpiry[path].UTC().Format(time.RFC3339))
+	}
+	exec.Println()
+
+	if len(problems) > 0 {
+		return errors.Errorf("kubeconfig files on node %s were not correctly renewed:\n%s", cp.Name(), strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// kubeConfigRenewalProblem returns a description of the problem with the client certificate of a kubeconfig after
+// renewal, given the certificate expiry before and after renewal, or an empty string if there are no problems
+func kubeConfigRenewalProblem(oldExpiry, newExpiry, now time.Time) string {
+	switch {
+	case newExpiry.IsZero():
+		return "the kubeconfig was removed"
+	case !newExpiry.After(oldExpiry):
+		return fmt.Sprintf("the client certificate was not renewed, it expires %s", newExpiry.UTC().Format(time.RFC3339))
+	case !now.Before(newExpiry):
+		return fmt.Sprintf("the client certificate expired %s", newExpiry.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
+
+// kubeConfigClientCertExpiry returns the expiry of the client certificate embedded in the user of the current
+// context of a kubeconfig
+func kubeConfigClientCertExpiry(raw []byte) (time.Time, error) {
+	config, err := clientcmd.Load(raw)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to parse the kubeconfig")
+	}
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return time.Time{}, errors.Errorf("the current context %q is not defined", config.CurrentContext)
+	}
+	authInfo, ok := config.AuthInfos[context.AuthInfo]
+	if !ok || len(authInfo.ClientCertificateData) == 0 {
+		return time.Time{}, errors.Errorf("the user %q does not have an embedded client certificate", context.AuthInfo)
+	}
+
+	block, _ := pem.Decode(authInfo.ClientCertificateData)
+	if block == nil {
+		return time.Time{}, errors.New("failed to decode the client certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to parse the client certificate")
+	}
+	return cert.NotAfter, nil
+}
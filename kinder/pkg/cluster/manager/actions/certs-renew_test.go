@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCertRenewalsString(t *testing.T) {
+	old := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	renewals := certRenewals{
+		{node: "kind-control-plane-1", oldExpiry: old, newExpiry: old.Add(time.Hour)},
+		{node: "kind-control-plane-2", oldExpiry: old, newExpiry: old.Add(2 * time.Hour)},
+	}
+
+	expected := "kind-control-plane-1: 2025-01-01T10:00:00Z -> 2025-01-01T11:00:00Z\n" +
+		"kind-control-plane-2: 2025-01-01T10:00:00Z -> 2025-01-01T12:00:00Z\n"
+	if s := renewals.String(); s != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, s)
+	}
+}
+
+func TestKubeConfigRenewalProblem(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		oldExpiry       time.Time
+		newExpiry       time.Time
+		expectedProblem bool
+	}{
+		{
+			name:      "renewed",
+			oldExpiry: now.Add(time.Hour),
+			newExpiry: now.Add(365 * 24 * time.Hour),
+		},
+		{
+			name:            "not renewed",
+			oldExpiry:       now.Add(time.Hour),
+			newExpiry:       now.Add(time.Hour),
+			expectedProblem: true,
+		},
+		{
+			name:            "renewed but expired",
+			oldExpiry:       now.Add(-2 * time.Hour),
+			newExpiry:       now.Add(-time.Hour),
+			expectedProblem: true,
+		},
+		{
+			name:            "removed",
+			oldExpiry:       now.Add(time.Hour),
+			expectedProblem: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := kubeConfigRenewalProblem(tt.oldExpiry, tt.newExpiry, now)
+			if (problem != "") != tt.expectedProblem {
+				t.Errorf("expected problem %v, got %q", tt.expectedProblem, problem)
+			}
+		})
+	}
+}
+
+func TestKubeConfigClientCertExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubernetes-admin"},
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeConfig := func(currentContext string) []byte {
+		return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: admin
+  user:
+    client-certificate-data: %s
+- name: token
+  user:
+    token: token
+contexts:
+- name: admin@kind
+  context:
+    cluster: kind
+    user: admin
+- name: token@kind
+  context:
+    cluster: kind
+    user: token
+current-context: %s
+`, base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), currentContext))
+	}
+
+	expiry, err := kubeConfigClientCertExpiry(kubeConfig("admin@kind"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !expiry.Equal(notAfter) {
+		t.Errorf("expected expiry %s, got %s", notAfter, expiry)
+	}
+
+	if _, err := kubeConfigClientCertExpiry(kubeConfig("token@kind")); err == nil {
+		t.Error("expected error for a user without an embedded client certificate")
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// chaosComponents defines the control-plane components the chaos action can kill, by short name
+var chaosComponents = map[string]string{
+	"apiserver":          "kube-apiserver",
+	"controller-manager": "kube-controller-manager",
+	"scheduler":          "kube-scheduler",
+	"etcd":               "etcd",
+}
+
+// KnownChaosComponents returns the list of control-plane components the chaos action can kill
+func KnownChaosComponents() []string {
+	return []string{"apiserver", "controller-manager", "scheduler", "etcd"}
+}
+
+// ValidateChaosComponent validates the control-plane component the chaos action kills; empty means a random component
+func ValidateChaosComponent(component string) error {
+	if component == "" {
+		return nil
+	}
+	if _, ok := chaosComponents[component]; !ok {
+		return errors.Errorf("invalid chaos component %q. Use one of %s", component, KnownChaosComponents())
+	}
+	return nil
+}
+
+// Chaos action kills the container of a control-plane component on a control-plane node, for control-plane
+// resilience testing; the component and the node are selected at random if not specified. The kubelet restarts
+// the container of the static pod, and if verify is set, the action waits for the static pod to be running again
+// and for the control-plane on the node to be healthy.
+func Chaos(c *status.Cluster, component, nodeSelector string, verify bool, wait time.Duration) error {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	cp, err := chaosTargetNode(c, nodeSelector, rnd)
+	if err != nil {
+		return err
+	}
+	component, err = chaosTargetComponent(component, c.UsesExternalEtcd(), rnd)
+	if err != nil {
+		return err
+	}
+	container := chaosComponents[component]
+
+	oldID, err := runningContainerID(cp, container)
+	if err != nil {
+		return err
+	}
+
+	cp.Infof("killing the %s container %s", container, oldID)
+	if err := cp.Command("crictl", "stop", "--timeout=0", oldID).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to kill the %s container on node %s", container, cp.Name())
+	}
+	exec.Printf("Killed %s on node %s\n", container, cp.Name())
+
+	if !verify {
+		return nil
+	}
+
+	cp.Infof("waiting for %s to be recreated (timeout %s)", container, wait)
+	if pass := waitFor(c, cp, wait,
+		containerIsRecreated(container, oldID),
+		staticPodIsReady(container),
+	); !pass {
+		return withRecentWarningEvents(c, controlPlaneTimeoutError(cp, fmt.Sprintf("timeout: %s was not recreated", container)))
+	}
+	return waitNewControlPlaneNodeReady(c, cp, wait)
+}
+
+// chaosTargetNode returns the control-plane node eligible for actions matching the node selector, or a random
+// control-plane node eligible for actions if there is no node selector; if the node selector matches
+// more than one control-plane node, one of them is selected at random
+func chaosTargetNode(c *status.Cluster, nodeSelector string, rnd *rand.Rand) (*status.Node, error) {
+	candidates := c.ControlPlanes().EligibleForActions()
+	if nodeSelector != "" {
+		nodes, err := c.SelectNodes(nodeSelector)
+		if err != nil {
+			return nil, err
+		}
+		selected := map[string]bool{}
+		for _, n := range nodes {
+			selected[n.Name()] = true
+		}
+		var matching status.NodeList
+		for _, n := range candidates {
+			if selected[n.Name()] {
+				matching = append(matching, n)
+			}
+		}
+		candidates = matching
+	}
+	if len(candidates) == 0 {
+		return nil, errors.Errorf("no control-plane nodes eligible for actions match %q", nodeSelector)
+	}
+	return candidates[rnd.Intn(len(candidates))], nil
+}
+
+// chaosTargetComponent returns the given control-plane component, or a random one if the component is empty;
+// etcd is not a target when the cluster uses an external etcd, because it does not run on control-plane nodes
+func chaosTargetComponent(component string, externalEtcd bool, rnd *rand.Rand) (string, error) {
+	if err := ValidateChaosComponent(component); err != nil {
+		return "", err
+	}
+	if component == "etcd" && externalEtcd {
+		return "", errors.New("etcd can't be selected as chaos component, the cluster uses an external etcd")
+	}
+	if component != "" {
+		return component, nil
+	}
+
+	var components []string
+	for _, c := range KnownChaosComponents() {
+		if c == "etcd" && externalEtcd {
+			continue
+		}
+		components = append(components, c)
+	}
+	return components[rnd.Intn(len(components))], nil
+}
+
+// runningContainerID returns the ID of the running container with the given name on a node
+func runningContainerID(n *status.Node, container string) (string, error) {
+	lines, err := n.Command("crictl", "ps", "-q", "--state=running", "--name", fmt.Sprintf("^%s$", container)).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list the %s containers on node %s", container, n.Name())
+	}
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+		return "", errors.Errorf("there is no running %s container on node %s", container, n.Name())
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
+
+// containerIsRecreated implement a function that test when a container with the given name, different from
+// the killed one, is running
+func containerIsRecreated(container, oldID string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		id, err := runningContainerID(n, container)
+		if err != nil || id == oldID {
+			return false
+		}
+		exec.Printf("Container %s is running again (%s)\n", container, id)
+		return true
+	}
+}
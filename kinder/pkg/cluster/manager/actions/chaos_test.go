@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestChaosTargetComponent(t *testing.T) {
+	tests := []struct {
+		name          string
+		component     string
+		externalEtcd  bool
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:      "explicit component",
+			component: "controller-manager",
+			expected:  "controller-manager",
+		},
+		{
+			name:      "etcd",
+			component: "etcd",
+			expected:  "etcd",
+		},
+		{
+			name:          "etcd with external etcd",
+			component:     "etcd",
+			externalEtcd:  true,
+			expectedError: true,
+		},
+		{
+			name:          "unknown component",
+			component:     "kubelet",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := chaosTargetComponent(test.component, test.externalEtcd, rand.New(rand.NewSource(1)))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestChaosTargetComponentRandom(t *testing.T) {
+	for _, externalEtcd := range []bool{false, true} {
+		rnd := rand.New(rand.NewSource(1))
+		seen := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			got, err := chaosTargetComponent("", externalEtcd, rnd)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := chaosComponents[got]; !ok {
+				t.Fatalf("unexpected component %q", got)
+			}
+			seen[got] = true
+		}
+		if seen["etcd"] == externalEtcd {
+			t.Errorf("externalEtcd=%v: etcd selected: %v", externalEtcd, seen["etcd"])
+		}
+		if len(seen) < 3 {
+			t.Errorf("externalEtcd=%v: expected all the components to be selected, got %v", externalEtcd, seen)
+		}
+	}
+}
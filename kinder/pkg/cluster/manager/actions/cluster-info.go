@@ -24,6 +24,7 @@ import (
 
 	versionutils "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
 var (
@@ -55,15 +56,15 @@ func CluterInfo(c *status.Cluster) error {
 	).RunWithEcho(); err != nil {
 		return err
 	}
-	fmt.Println()
+	exec.Println()
 
-	if c.ExternalEtcd() == nil {
+	if !c.UsesExternalEtcd() {
 		// NB. before v1.13 local etcd is listening on localhost only; after v1.13
 		// local etcd is listening on localhost and on the advertise address; we are
 		// using localhost to accommodate both the use cases
 
 		etcdArgs := []string{
-			"--kubeconfig=/etc/kubernetes/admin.conf", "exec", "-n=kube-system", fmt.Sprintf("etcd-%s", c.BootstrapControlPlane().Name()),
+			"--kubeconfig=/etc/kubernetes/admin.conf", "exec", "-n=kube-system", fmt.Sprintf("etcd-%s", c.BootstrapControlPlane().KubeNodeName()),
 			"--",
 		}
 
@@ -106,7 +107,7 @@ func CluterInfo(c *status.Cluster) error {
 			return err
 		}
 	} else {
-		fmt.Println("using external etcd")
+		exec.Println("using external etcd")
 	}
 
 	return nil
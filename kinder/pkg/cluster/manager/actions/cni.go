@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions/assets"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// InstallCNI installs the CNI plugin defined at create time, kindnet by default, and waits for its DaemonSet to roll
+// out on all the nodes (this action is automatically executed during kubeadm-init)
+func InstallCNI(c *status.Cluster, wait time.Duration) error {
+	cp1 := c.BootstrapControlPlane()
+	plugin, err := installCNI(cp1)
+	if err != nil {
+		return err
+	}
+	return waitCNIReady(c, cp1, plugin, wait)
+}
+
+// installCNI installs the CNI plugin defined at create time on the bootstrap control-plane node
+func installCNI(cp1 *status.Node) (*cni.Plugin, error) {
+	name, err := cp1.CNI()
+	if err != nil {
+		return nil, err
+	}
+	plugin, err := cni.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// kindnet is installed from the manifest embedded in kinder, so it does not require network access
+	if plugin.Script == "" {
+		manifest, err := kindnetManifest(cp1)
+		if err != nil {
+			return nil, err
+		}
+		cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
+		cp1.Infof("applying kindnet version 0.5.4")
+		cmd.Stdin(strings.NewReader(manifest))
+		if err := cmd.RunWithEcho(); err != nil {
+			return nil, err
+		}
+		return &plugin, nil
+	}
+
+	cp1.Infof("installing the %s CNI plugin", plugin.Name)
+	if err := cp1.Command(
+		"env", "KUBECONFIG=/etc/kubernetes/admin.conf",
+		"bash", "-c", "set -o pipefail; "+plugin.Script,
+	).RunWithEcho(); err != nil {
+		return nil, errors.Wrapf(err, "failed to install the %s CNI plugin", plugin.Name)
+	}
+	exec.Println()
+	return &plugin, nil
+}
+
+// kindnetManifest returns the kindnet manifest with the pod subnet for the IP family of the cluster; kindnet
+// supports a single IP family, so dual-stack clusters get IPv4 pod networking only
+func kindnetManifest(cp1 *status.Node) (string, error) {
+	family, err := cp1.IPFamily()
+	if err != nil {
+		return "", err
+	}
+	switch family {
+	case status.IPv6Family:
+		return strings.Replace(assets.KindnetManifest054, constants.KinderPodSubnet, constants.KinderPodSubnetIPv6, 1), nil
+	case status.DualStackFamily:
+		log.Warnf("kindnet supports a single IP family, pods get IPv4 addresses only; use --cni with a dual-stack CNI plugin for dual-stack pod networking")
+	}
+	return assets.KindnetManifest054, nil
+}
+
+// waitCNIReady waits for the DaemonSet of the CNI plugin to roll out on all the nodes; custom plugins are not
+// waited for, use the DaemonSets defined at create time instead
+func waitCNIReady(c *status.Cluster, n *status.Node, plugin *cni.Plugin, wait time.Duration) error {
+	if plugin.DaemonSet == "" {
+		return nil
+	}
+
+	n.Infof("waiting for the %s DaemonSet %s to roll out (timeout %s)", plugin.Name, plugin.DaemonSet, wait)
+	if pass := waitFor(c, n, wait, daemonSetIsReady(plugin.DaemonSet)); !pass {
+		return withRecentWarningEvents(c, timeoutErrorf("the %s DaemonSet %s did not reach target state", plugin.Name, plugin.DaemonSet))
+	}
+	exec.Println()
+	return nil
+}
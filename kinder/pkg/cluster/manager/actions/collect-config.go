@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+const (
+	// kubeadmFlagsEnvPath defines the path of the kubelet flags file written by kubeadm init/join on each node
+	kubeadmFlagsEnvPath = "/var/lib/kubelet/kubeadm-flags.env"
+
+	// adminConfPath defines the path of the admin kubeconfig file written by kubeadm init on control-plane nodes
+	adminConfPath = "/etc/kubernetes/admin.conf"
+
+	// kubeadmConfigMapFile defines the name of the file where the kubeadm-config ConfigMap is saved
+	kubeadmConfigMapFile = "kubeadm-config.yaml"
+)
+
+// CollectConfig action saves in a directory on the host the configuration artifacts generated by kubeadm,
+// for audit and debugging: the kubeadm-config ConfigMap and the admin.conf file of the bootstrap control-plane,
+// and the kubeadm-flags.env file of each K8s node eligible for actions, each one in a sub directory named like
+// the node. Nb. admin.conf grants cluster-admin access to the cluster.
+func CollectConfig(c *status.Cluster, dir string) error {
+	dir, err := absCollectDir(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrapf(err, "failed to create the directory %s", dir)
+	}
+
+	var collected []string
+
+	cp1 := c.BootstrapControlPlane()
+	cp1.Infof("collecting the %s ConfigMap", "kubeadm-config")
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"get", "configmap", "kubeadm-config", "-n=kube-system", "-o=yaml",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the kubeadm-config ConfigMap")
+	}
+	configMapPath := filepath.Join(dir, kubeadmConfigMapFile)
+	if err := os.WriteFile(configMapPath, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %s", configMapPath)
+	}
+	collected = append(collected, configMapPath)
+
+	path, err := collectNodeFile(cp1, adminConfPath, dir)
+	if err != nil {
+		return err
+	}
+	collected = append(collected, path)
+
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		path, err := collectNodeFile(n, kubeadmFlagsEnvPath, dir)
+		if err != nil {
+			// nodes not yet initialized or joined do not have the kubelet flags file
+			log.Warnf("Skipping the kubelet flags of node %s: %v", n.Name(), err)
+			continue
+		}
+		collected = append(collected, path)
+	}
+
+	exec.Printf("\nCollected kubeadm configuration artifacts:\n  %s\n", strings.Join(collected, "\n  "))
+	return nil
+}
+
+// collectNodeFile copies a file from a node into a sub directory of dir named like the node,
+// and returns the path of the copy
+func collectNodeFile(n *status.Node, source, dir string) (string, error) {
+	if err := n.Command("test", "-f", source).Silent().Run(); err != nil {
+		return "", errors.Errorf("the file %s does not exist on node %s", source, n.Name())
+	}
+
+	nodeDir := filepath.Join(dir, n.Name())
+	if err := os.MkdirAll(nodeDir, 0700); err != nil {
+		return "", errors.Wrapf(err, "failed to create the directory %s", nodeDir)
+	}
+	dest := filepath.Join(nodeDir, filepath.Base(source))
+	if err := n.CopyFrom(source, dest); err != nil {
+		return "", errors.Wrapf(err, "failed to copy %s from node %s", source, n.Name())
+	}
+	return dest, nil
+}
+
+func absCollectDir(dir string) (string, error) {
+	if dir == "" {
+		return "", errors.New("the directory for the collected artifacts is required. Use --collect-dir")
+	}
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid directory %q", dir)
+	}
+	return path, nil
+}
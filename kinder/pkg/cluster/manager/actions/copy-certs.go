@@ -17,13 +17,14 @@ limitations under the License.
 package actions
 
 import (
-	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
 const etcKubernetes = "/etc/kubernetes"
@@ -47,7 +48,7 @@ func copyCertificatesToNode(c *status.Cluster, n *status.Node) error {
 		"front-proxy-ca.crt", "front-proxy-ca.key",
 		"sa.pub", "sa.key",
 	}
-	if c.ExternalEtcd() == nil {
+	if !c.UsesExternalEtcd() {
 		fileNames = append(fileNames, "etcd/ca.crt", "etcd/ca.key")
 	}
 
@@ -60,6 +61,58 @@ func copyCertificatesToNode(c *status.Cluster, n *status.Node) error {
 	return nil
 }
 
+// externalCANodeFiles returns the files in /etc/kubernetes that should be pre-generated on a control-plane node
+// joining a cluster without the CA key, because kubeadm can't sign them
+func externalCANodeFiles(externalEtcd bool) []string {
+	fileNames := []string{
+		"kubelet.conf",
+		"pki/apiserver.crt", "pki/apiserver.key",
+		"pki/apiserver-kubelet-client.crt", "pki/apiserver-kubelet-client.key",
+		"pki/front-proxy-client.crt", "pki/front-proxy-client.key",
+	}
+	if !externalEtcd {
+		fileNames = append(fileNames,
+			"pki/apiserver-etcd-client.crt", "pki/apiserver-etcd-client.key",
+			"pki/etcd/server.crt", "pki/etcd/server.key",
+			"pki/etcd/peer.crt", "pki/etcd/peer.key",
+			"pki/etcd/healthcheck-client.crt", "pki/etcd/healthcheck-client.key",
+		)
+	}
+	return fileNames
+}
+
+// isExternalCA returns true if the cluster uses an external CA, that is the bootstrap control-plane
+// has the CA certificate but not the CA key
+func isExternalCA(c *status.Cluster) bool {
+	cp1 := c.BootstrapControlPlane()
+	if err := cp1.Command("test", "-f", filepath.Join(etcKubernetes, "pki", "ca.crt")).Silent().Run(); err != nil {
+		return false
+	}
+	return cp1.Command("test", "-f", filepath.Join(etcKubernetes, "pki", "ca.key")).Silent().Run() != nil
+}
+
+// copyExternalCACertificatesToNode copies the shared certificates and kubeconfig files from the bootstrap node to a
+// control-plane node joining a cluster without the CA key, and checks that the node certificates are pre-generated
+func copyExternalCACertificatesToNode(c *status.Cluster, n *status.Node) error {
+	if err := copyCertificatesToNode(c, n); err != nil {
+		return err
+	}
+	if err := copyKubeconfigFilesToNode(c, n); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, fileName := range externalCANodeFiles(c.UsesExternalEtcd()) {
+		if err := n.Command("test", "-f", filepath.Join(etcKubernetes, fileName)).Silent().Run(); err != nil {
+			missing = append(missing, fileName)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("files %s are missing on node %s. Without the CA key, they should be generated before join, e.g. by the setup-external-ca action", strings.Join(missing, ", "), n.Name())
+	}
+	return nil
+}
+
 // copyCAToNode copies the root CA cert and key to a node
 func copyCAToNode(c *status.Cluster, n *status.Node) error {
 	fileNames := []string{"ca.crt", "ca.key"}
@@ -88,23 +141,15 @@ func copyKubeconfigFilesToNode(c *status.Cluster, n *status.Node) error {
 func copyBootstrapEtcKubernetesFilesToNode(c *status.Cluster, n *status.Node, basePath string, fileNames, filesToWarn []string) error {
 	n.Infof("Importing cluster certificates from %s", c.BootstrapControlPlane().Name())
 
-	// creates the folder tree for pre-loading necessary cluster certificates and kubeconfig files
-	// on the joining node
-	if err := n.Command("mkdir", "-p", etcKubernetes+"/pki/etcd").Silent().Run(); err != nil {
-		return errors.Wrap(err, "failed to create pki folder")
-	}
-
 	// copies certificates from the bootstrap control plane node to the joining node
 	for _, fileName := range fileNames {
-		fmt.Printf("%s\n", fileName)
+		exec.Printf("%s\n", fileName)
 
 		// sets the path of the certificate into a node
 		containerPath := filepath.Join(etcKubernetes, basePath, fileName)
 
-		// copies from bootstrap control plane node to tmp area
-		lines, err := c.BootstrapControlPlane().Command(
-			"cat", containerPath,
-		).Silent().RunAndCapture()
+		// reads the file from the bootstrap control plane node
+		contents, err := c.BootstrapControlPlane().ReadFile(containerPath)
 		if err != nil {
 			// assume the file is missing; check if this file should cause a warning
 			// instead of erroring out (e.g. missing ca.key)
@@ -118,14 +163,23 @@ func copyBootstrapEtcKubernetesFilesToNode(c *status.Cluster, n *status.Node, ba
 			if !isWarnFile {
 				return errors.Wrapf(err, "failed to read file %s from %s", fileName, c.BootstrapControlPlane().Name())
 			}
-			fmt.Printf("Missing file %s on node %s\n", fileName, c.BootstrapControlPlane().Name())
+			exec.Printf("Missing file %s on node %s\n", fileName, c.BootstrapControlPlane().Name())
 			continue
 		}
-		// copies from tmp area to joining node
-		if err := n.WriteFile(containerPath, []byte(strings.Join(lines, "\n"))); err != nil {
+		// writes the file on the joining node, creating the folder tree if missing
+		if err := n.WriteFileWithPerm(containerPath, contents, etcKubernetesFilePerm(fileName)); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// etcKubernetesFilePerm returns the permissions used by kubeadm for files in /etc/kubernetes, that is
+// 0600 for private keys and kubeconfig files, and 0644 for certificates and public keys
+func etcKubernetesFilePerm(fileName string) os.FileMode {
+	if strings.HasSuffix(fileName, ".key") || strings.HasSuffix(fileName, ".conf") {
+		return 0600
+	}
+	return 0644
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEtcKubernetesFilePerm(t *testing.T) {
+	tests := []struct {
+		fileName string
+		expected os.FileMode
+	}{
+		{fileName: "ca.crt", expected: 0644},
+		{fileName: "ca.key", expected: 0600},
+		{fileName: "sa.pub", expected: 0644},
+		{fileName: "etcd/ca.key", expected: 0600},
+		{fileName: "admin.conf", expected: 0600},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			if perm := etcKubernetesFilePerm(test.fileName); perm != test.expected {
+				t.Errorf("expected %o, got %o", test.expected, perm)
+			}
+		})
+	}
+}
+
+func TestExternalCANodeFiles(t *testing.T) {
+	tests := []struct {
+		name         string
+		externalEtcd bool
+		contains     string
+		expected     bool
+	}{
+		{name: "apiserver cert", contains: "pki/apiserver.crt", expected: true},
+		{name: "kubelet kubeconfig", contains: "kubelet.conf", expected: true},
+		{name: "stacked etcd server cert", contains: "pki/etcd/server.crt", expected: true},
+		{name: "external etcd server cert", externalEtcd: true, contains: "pki/etcd/server.crt", expected: false},
+		{name: "external etcd apiserver client cert", externalEtcd: true, contains: "pki/apiserver-etcd-client.crt", expected: false},
+		{name: "shared CA cert", contains: "pki/ca.crt", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found := false
+			for _, fileName := range externalCANodeFiles(test.externalEtcd) {
+				if fileName == test.contains {
+					found = true
+				}
+			}
+			if found != test.expected {
+				t.Errorf("expected %s in the node files to be %t, got %t", test.contains, test.expected, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// CorruptCertMode defines how the corrupt-cert action corrupts the API server serving certificate
+type CorruptCertMode string
+
+const (
+	// CorruptCertExpired replaces the certificate with a certificate signed by the cluster CA, that expired one hour ago;
+	// the SANs are copied from the current certificate, so expiration is the only problem of the certificate
+	CorruptCertExpired = CorruptCertMode("expired")
+
+	// CorruptCertWrongSAN replaces the certificate with a certificate signed by the cluster CA, that is valid only
+	// for a SAN not used by the cluster
+	CorruptCertWrongSAN = CorruptCertMode("wrong-san")
+
+	// CorruptCertMalformed replaces the certificate with a PEM block that can't be parsed as a certificate
+	CorruptCertMalformed = CorruptCertMode("malformed")
+)
+
+// corruptCertWrongSAN defines the only SAN of the certificate written by the corrupt-cert action in wrong-san mode
+const corruptCertWrongSAN = "wrong-san.kinder.invalid"
+
+// malformedCertPEM defines the certificate written by the corrupt-cert action in malformed mode
+const malformedCertPEM = "-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydGlmaWNhdGU=\n-----END CERTIFICATE-----\n"
+
+// KnownCorruptCertModes returns the modes supported by the corrupt-cert action
+func KnownCorruptCertModes() []CorruptCertMode {
+	return []CorruptCertMode{CorruptCertExpired, CorruptCertWrongSAN, CorruptCertMalformed}
+}
+
+// ValidateCorruptCertMode validates a mode for the corrupt-cert action
+func ValidateCorruptCertMode(mode CorruptCertMode) error {
+	for _, m := range KnownCorruptCertModes() {
+		if mode == m {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid corrupt-cert mode %q. Use one of %v", mode, KnownCorruptCertModes())
+}
+
+// CorruptCert action replaces the API server serving certificate on the control-plane nodes with an expired
+// certificate, a certificate with a wrong SAN or a malformed certificate, and then restarts the kube-apiserver,
+// so the reaction of kubeadm and of the kubelet can be observed; this is intended for negative tests, e.g.
+// asserting that the cluster recovers after the certs-renew action.
+func CorruptCert(c *status.Cluster, mode CorruptCertMode) error {
+	if err := ValidateCorruptCertMode(mode); err != nil {
+		return err
+	}
+
+	var caCertPEM, caKeyPEM []byte
+	if mode != CorruptCertMalformed {
+		cp1 := c.BootstrapControlPlane()
+		caCert, err := cp1.Command("cat", caCertPath).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s from node %s", caCertPath, cp1.Name())
+		}
+		caKey, err := cp1.Command("cat", caKeyPath).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s from node %s. The CA key is required for signing the certificate", caKeyPath, cp1.Name())
+		}
+		caCertPEM, caKeyPEM = []byte(strings.Join(caCert, "\n")), []byte(strings.Join(caKey, "\n"))
+	}
+
+	for _, cp := range c.ControlPlanes().EligibleForActions() {
+		certPEM, keyPEM := []byte(malformedCertPEM), []byte(nil)
+		if mode != CorruptCertMalformed {
+			// the certificate is signed for each node, because the SANs of the current certificate include
+			// the node name and IPs
+			currentCert, err := cp.Command("cat", apiServerCertPath).Silent().RunAndCapture()
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %s from node %s", apiServerCertPath, cp.Name())
+			}
+			if certPEM, keyPEM, err = signCorruptAPIServerCert(caCertPEM, caKeyPEM, []byte(strings.Join(currentCert, "\n")), mode, time.Now()); err != nil {
+				return errors.Wrapf(err, "failed to sign the %s certificate for node %s", mode, cp.Name())
+			}
+		}
+
+		cp.Infof("writing %s API server certificate", mode)
+		if err := cp.WriteFile(apiServerCertPath, certPEM); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", apiServerCertPath, cp.Name())
+		}
+		// the malformed certificate is written without a key, so the key matching the original certificate is preserved
+		if keyPEM != nil {
+			if err := cp.WriteFile(apiServerKeyPath, keyPEM); err != nil {
+				return errors.Wrapf(err, "failed to write %s on node %s", apiServerKeyPath, cp.Name())
+			}
+		}
+
+		// the kube-apiserver reloads valid serving certificates automatically, but the malformed certificate is
+		// detected only at startup, so the container is stopped and the kubelet restarts it
+		id, err := runningContainerID(cp, "kube-apiserver")
+		if err != nil {
+			return err
+		}
+		cp.Infof("restarting the kube-apiserver container %s", id)
+		if err := cp.Command("crictl", "stop", "--timeout=0", id).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to restart the kube-apiserver on node %s", cp.Name())
+		}
+		exec.Printf("Replaced the API server certificate with a %s certificate on node %s\n", mode, cp.Name())
+	}
+	return nil
+}
+
+// signCorruptAPIServerCert creates a new key and an API server serving certificate signed by the given CA,
+// that is expired at the given time with the same SANs of the current certificate, or valid only for a SAN
+// not used by the cluster, depending on the mode; certificate and key are returned PEM encoded
+func signCorruptAPIServerCert(caCertPEM, caKeyPEM, currentCertPEM []byte, mode CorruptCertMode, now time.Time) ([]byte, []byte, error) {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kube-apiserver"},
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	switch mode {
+	case CorruptCertExpired:
+		// the SANs are the ones of the current certificate, so expiration is the only problem of the certificate
+		block, _ := pem.Decode(currentCertPEM)
+		if block == nil {
+			return nil, nil, errors.New("failed to decode the current API server certificate")
+		}
+		current, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to parse the current API server certificate")
+		}
+		template.DNSNames = current.DNSNames
+		template.IPAddresses = current.IPAddresses
+		template.NotBefore = now.Add(-2 * time.Hour).UTC()
+		template.NotAfter = now.Add(-time.Hour).UTC()
+	case CorruptCertWrongSAN:
+		template.DNSNames = []string{corruptCertWrongSAN}
+	default:
+		return nil, nil, errors.Errorf("the %s certificate can't be signed", mode)
+	}
+	return signCert(caCertPEM, caKeyPEM, template)
+}
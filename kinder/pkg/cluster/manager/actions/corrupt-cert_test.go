@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSignCorruptAPIServerCert(t *testing.T) {
+	caCertPEM, caKeyPEM := newTestCA(t)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCertPEM)
+	caBlock, _ := pem.Decode(caCertPEM)
+	ca, err := x509.ParseCertificate(caBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// the current certificate, with the SANs expected by the cluster
+	currentCertPEM, _, err := signAPIServerCert(caCertPEM, caKeyPEM, []string{"kubernetes", "kubernetes.default", "kinder-control-plane-1"}, []net.IP{net.ParseIP("10.96.0.1"), net.ParseIP("172.17.0.2")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		mode            CorruptCertMode
+		dnsName         string
+		expectedInvalid x509.InvalidReason
+		expectedError   bool
+	}{
+		{
+			mode:            CorruptCertExpired,
+			dnsName:         "kubernetes.default",
+			expectedInvalid: x509.Expired,
+		},
+		{
+			mode:    CorruptCertWrongSAN,
+			dnsName: "kubernetes.default",
+		},
+		{
+			mode:          CorruptCertMalformed,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			certPEM, keyPEM, err := signCorruptAPIServerCert(caCertPEM, caKeyPEM, currentCertPEM, test.mode, now)
+			if err != nil {
+				if !test.expectedError {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if test.expectedError {
+				t.Fatal("expected error, found none")
+			}
+			if _, err := parsePrivateKey(keyPEM); err != nil {
+				t.Fatalf("unexpected error parsing the key: %v", err)
+			}
+
+			block, _ := pem.Decode(certPEM)
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: test.dnsName, CurrentTime: now})
+			if err == nil {
+				t.Fatalf("expected the certificate to be invalid for %s", test.dnsName)
+			}
+			if test.expectedInvalid != 0 {
+				if invalid, ok := err.(x509.CertificateInvalidError); !ok || invalid.Reason != test.expectedInvalid {
+					t.Fatalf("unexpected verification error: %v", err)
+				}
+			} else if _, ok := err.(x509.HostnameError); !ok {
+				t.Fatalf("expected a hostname error, found: %v", err)
+			}
+
+			// checks the expired certificate has the same SANs of the current certificate
+			if test.mode == CorruptCertExpired {
+				if !reflect.DeepEqual(cert.DNSNames, []string{"kubernetes", "kubernetes.default", "kinder-control-plane-1"}) {
+					t.Fatalf("unexpected DNS names: %v", cert.DNSNames)
+				}
+				if len(cert.IPAddresses) != 2 || !cert.IPAddresses[1].Equal(net.ParseIP("172.17.0.2")) {
+					t.Fatalf("unexpected IPs: %v", cert.IPAddresses)
+				}
+			}
+
+			// checks the certificate is signed by the cluster CA, so the corruption is the only problem
+			if err := cert.CheckSignatureFrom(ca); err != nil {
+				t.Fatalf("failed to verify the certificate signature: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateCorruptCertMode(t *testing.T) {
+	for _, mode := range KnownCorruptCertModes() {
+		if err := ValidateCorruptCertMode(mode); err != nil {
+			t.Fatalf("unexpected error for mode %s: %v", mode, err)
+		}
+	}
+	if err := ValidateCorruptCertMode("revoked"); err == nil {
+		t.Fatal("expected error for an unknown mode, found none")
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/failure"
+)
+
+// diagnosticsTailLines defines the number of log lines inlined in errors for each failing component
+const diagnosticsTailLines = 20
+
+// recentWarningEventsLimit defines the number of recent warning events attached to timeout errors
+const recentWarningEventsLimit = 10
+
+// controlPlaneStaticPods defines the static pods checked when collecting diagnostics for a control-plane node
+var controlPlaneStaticPods = []string{"etcd", "kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+
+// componentLogs holds the logs collected for a component running on a node; for static pods, the logs of the
+// previous container are collected as well, so the cause of a crash is available even after a restart
+type componentLogs struct {
+	name          string
+	attempts      int
+	running       bool
+	lines         []string
+	previousLines []string
+}
+
+// failing returns true if the component is not running or it was restarted
+func (l *componentLogs) failing() bool {
+	return !l.running || l.attempts > 1
+}
+
+// controlPlaneTimeoutError returns an error for a control-plane node not reaching the target state,
+// including the tail of the logs of the failing static pods and of the kubelet; the full logs
+// are saved in a temporary directory on the host. The error has the timeout failure category.
+func controlPlaneTimeoutError(n *status.Node, message string) error {
+	logs := collectControlPlaneLogs(n)
+
+	dir, err := saveComponentLogs(n.Name(), logs)
+	if err != nil {
+		n.Infof("failed to save diagnostics: %v", err)
+	}
+	return failure.WithCategory(errors.New(formatDiagnostics(message, n.Name(), dir, logs)), failure.Timeout)
+}
+
+// collectControlPlaneLogs collects the logs of the latest and of the previous container of each control-plane
+// static pod and the kubelet journal; errors are ignored, because diagnostics are collected on a best effort basis
+func collectControlPlaneLogs(n *status.Node) []*componentLogs {
+	var logs []*componentLogs
+	for _, pod := range controlPlaneStaticPods {
+		if l := collectStaticPodLogs(n, pod); l != nil {
+			logs = append(logs, l)
+		}
+	}
+
+	journal, err := n.Command("journalctl", "-u", "kubelet", "--no-pager").Silent().RunAndCapture()
+	if err == nil {
+		logs = append(logs, &componentLogs{name: "kubelet", attempts: 1, running: true, lines: journal})
+	}
+	return logs
+}
+
+// collectStaticPodLogs collects, using the CRI, the logs of the latest container of a static pod and, if the
+// container was restarted, the logs of the previous container, that usually contain the cause of a crash loop;
+// nil is returned if the containers can't be listed
+func collectStaticPodLogs(n *status.Node, pod string) *componentLogs {
+	filter := fmt.Sprintf("^%s$", pod)
+	l := &componentLogs{name: pod}
+
+	ids, err := n.Command("crictl", "ps", "-a", "-q", "--name", filter).Silent().RunAndCapture()
+	if err != nil {
+		return nil
+	}
+	l.attempts = len(ids)
+
+	running, err := n.Command("crictl", "ps", "-q", "--name", filter).Silent().RunAndCapture()
+	l.running = err == nil && len(running) > 0
+
+	// crictl lists the most recent containers first; the kubelet keeps at least the last exited container
+	// of each pod, so its logs are available until the next restart
+	if len(ids) > 0 {
+		l.lines, _ = n.Command("crictl", "logs", strings.TrimSpace(ids[0])).Silent().RunAndCapture()
+	}
+	if len(ids) > 1 {
+		l.previousLines, _ = n.Command("crictl", "logs", strings.TrimSpace(ids[1])).Silent().RunAndCapture()
+	}
+	return l
+}
+
+// saveComponentLogs saves the logs in a temporary directory and returns its path
+func saveComponentLogs(node string, logs []*componentLogs) (string, error) {
+	dir, err := os.MkdirTemp("", fmt.Sprintf("kinder-%s-", node))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create the diagnostics directory")
+	}
+	for _, l := range logs {
+		content := strings.Join(l.lines, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(dir, l.name+".log"), []byte(content), os.FileMode(0644)); err != nil {
+			return "", errors.Wrapf(err, "failed to save the %s logs", l.name)
+		}
+		if len(l.previousLines) > 0 {
+			content := strings.Join(l.previousLines, "\n") + "\n"
+			if err := os.WriteFile(filepath.Join(dir, l.name+".previous.log"), []byte(content), os.FileMode(0644)); err != nil {
+				return "", errors.Wrapf(err, "failed to save the %s previous logs", l.name)
+			}
+		}
+	}
+	return dir, nil
+}
+
+// formatDiagnostics returns the error message with a pointer to the saved logs and the tail of the
+// logs of the failing static pods, including the previous container if restarted; the tail of the
+// kubelet journal is always included
+func formatDiagnostics(message, node, dir string, logs []*componentLogs) string {
+	var b strings.Builder
+	b.WriteString(message)
+	if dir != "" {
+		fmt.Fprintf(&b, "\nlogs for node %s saved in %s", node, dir)
+	}
+	for _, l := range logs {
+		if l.name != "kubelet" && !l.failing() {
+			continue
+		}
+
+		if l.name != "kubelet" && l.attempts == 0 {
+			fmt.Fprintf(&b, "\n--- %s: no containers found ---", l.name)
+			continue
+		}
+
+		state := "running"
+		if !l.running {
+			state = "not running"
+		}
+		if l.name == "kubelet" {
+			fmt.Fprintf(&b, "\n--- %s (last %d lines) ---", l.name, diagnosticsTailLines)
+		} else {
+			fmt.Fprintf(&b, "\n--- %s: %s, %d attempts (last %d lines) ---", l.name, state, l.attempts, diagnosticsTailLines)
+		}
+		for _, line := range tailLines(l.lines, diagnosticsTailLines) {
+			fmt.Fprintf(&b, "\n%s", line)
+		}
+
+		if len(l.previousLines) > 0 {
+			fmt.Fprintf(&b, "\n--- %s: previous attempt (last %d lines) ---", l.name, diagnosticsTailLines)
+			for _, line := range tailLines(l.previousLines, diagnosticsTailLines) {
+				fmt.Fprintf(&b, "\n%s", line)
+			}
+		}
+	}
+	return b.String()
+}
+
+// withRecentWarningEvents attaches the recent warning events in the cluster to a timeout error, if any;
+// events are collected on a best effort basis, so failures reading them are ignored. The failure category of err is preserved
+func withRecentWarningEvents(c *status.Cluster, err error) error {
+	events, eventsErr := c.Events(true, recentWarningEventsLimit)
+	if eventsErr != nil || len(events) == 0 {
+		return err
+	}
+	return failure.WithCategory(errors.New(formatWarningEvents(err.Error(), events)), failure.CategoryOf(err))
+}
+
+// formatWarningEvents returns the error message followed by the given warning events, one per line
+func formatWarningEvents(message string, events []status.Event) string {
+	var b strings.Builder
+	b.WriteString(message)
+	fmt.Fprintf(&b, "\n--- recent warning events (last %d) ---", len(events))
+	for _, e := range events {
+		fmt.Fprintf(&b, "\n%s", e)
+	}
+	return b.String()
+}
+
+// tailLines returns the last n lines
+func tailLines(lines []string, n int) []string {
+	if len(lines) <= n {
+		return lines
+	}
+	return lines[len(lines)-n:]
+}
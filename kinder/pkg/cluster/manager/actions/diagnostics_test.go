@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+func TestFormatDiagnostics(t *testing.T) {
+	var longLog []string
+	for i := 1; i <= 30; i++ {
+		longLog = append(longLog, fmt.Sprintf("line %d", i))
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		logs     []*componentLogs
+		expected string
+	}{
+		{
+			name:     "no logs",
+			expected: "timeout",
+		},
+		{
+			name: "healthy components are skipped",
+			dir:  "/tmp/kinder-cp-1",
+			logs: []*componentLogs{
+				{name: "kube-apiserver", attempts: 1, running: true, lines: []string{"ok"}},
+				{name: "kubelet", attempts: 1, running: true, lines: []string{"kubelet ok"}},
+			},
+			expected: "timeout\nlogs for node cp saved in /tmp/kinder-cp-1\n--- kubelet (last 20 lines) ---\nkubelet ok",
+		},
+		{
+			name: "failing components are inlined",
+			logs: []*componentLogs{
+				{name: "etcd"},
+				{name: "kube-apiserver", attempts: 3, running: false, lines: []string{"panic"}},
+				{name: "kube-scheduler", attempts: 2, running: true, lines: []string{"restarted"}},
+			},
+			expected: "timeout\n--- etcd: no containers found ---" +
+				"\n--- kube-apiserver: not running, 3 attempts (last 20 lines) ---\npanic" +
+				"\n--- kube-scheduler: running, 2 attempts (last 20 lines) ---\nrestarted",
+		},
+		{
+			name: "previous attempt of restarted components is inlined",
+			logs: []*componentLogs{
+				{name: "kube-apiserver", attempts: 2, running: true, lines: []string{"starting"}, previousLines: []string{"panic: invalid flag"}},
+			},
+			expected: "timeout\n--- kube-apiserver: running, 2 attempts (last 20 lines) ---\nstarting" +
+				"\n--- kube-apiserver: previous attempt (last 20 lines) ---\npanic: invalid flag",
+		},
+		{
+			name: "logs are truncated",
+			logs: []*componentLogs{
+				{name: "kube-apiserver", attempts: 2, running: false, lines: longLog[:21]},
+			},
+			expected: "timeout\n--- kube-apiserver: not running, 2 attempts (last 20 lines) ---" +
+				"\nline 2\nline 3\nline 4\nline 5\nline 6\nline 7\nline 8\nline 9\nline 10\nline 11" +
+				"\nline 12\nline 13\nline 14\nline 15\nline 16\nline 17\nline 18\nline 19\nline 20\nline 21",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if message := formatDiagnostics("timeout", "cp", test.dir, test.logs); message != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, message)
+			}
+		})
+	}
+}
+
+func TestFormatWarningEvents(t *testing.T) {
+	events := []status.Event{
+		{
+			Timestamp: time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC),
+			Type:      "Warning",
+			Namespace: "kube-system",
+			Object:    "pod/coredns-1",
+			Reason:    "FailedScheduling",
+			Message:   "0/1 nodes are available",
+		},
+	}
+
+	expected := "timeout: Node did not reach target state\n" +
+		"--- recent warning events (last 1) ---\n" +
+		"2024-05-01T10:00:30Z Warning kube-system/pod/coredns-1 FailedScheduling: 0/1 nodes are available"
+	if message := formatWarningEvents("timeout: Node did not reach target state", events); message != expected {
+		t.Errorf("expected message:\n%s\nfound:\n%s", expected, message)
+	}
+}
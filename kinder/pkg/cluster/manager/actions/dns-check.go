@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+const (
+	// defaultDNSCheckImage defines the image of the pod resolving names in the dns-check action, if not differently
+	// specified; busybox 1.28 is used because nslookup in later versions does not honor the search path
+	defaultDNSCheckImage = "busybox:1.28"
+
+	// dnsCheckPodName defines the name of the throwaway pod used by the dns-check action
+	dnsCheckPodName = "kinder-dns-check"
+)
+
+// dnsLookup defines a name resolved by the dns-check action
+type dnsLookup struct {
+	// kind describes the lookup, e.g. cluster service
+	kind string
+	name string
+}
+
+// dnsLookupResult defines the result of a name resolved by the dns-check action
+type dnsLookupResult struct {
+	dnsLookup
+	output []string
+	err    error
+}
+
+// DNSCheck action verifies that CoreDNS resolves both cluster names, e.g. kubernetes.default, and,
+// if set, an external name, checking forwarding to the upstream resolvers; use an empty external name
+// for air-gapped environments. Names are resolved from a throwaway pod, using the DNS domain of the cluster,
+// and the failed lookups are reported.
+func DNSCheck(c *status.Cluster, image, externalName string, wait time.Duration) error {
+	cp1 := c.BootstrapControlPlane()
+
+	dnsDomain, err := clusterDNSDomain(c)
+	if err != nil {
+		return err
+	}
+
+	if image == "" {
+		image = defaultDNSCheckImage
+	}
+
+	// cleanups garbage from previous checks
+	cleanupDNSCheck(cp1)
+	defer cleanupDNSCheck(cp1)
+
+	cp1.Infof("starting pod %s with image %s", dnsCheckPodName, image)
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"run", dnsCheckPodName, fmt.Sprintf("--image=%s", image), "--restart=Never",
+		"--command", "--", "sleep", "3600",
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to create pod %s", dnsCheckPodName)
+	}
+
+	if pass := waitFor(c, cp1, wait,
+		podIsRunning(cp1, dnsCheckPodName),
+	); !pass {
+		return withRecentWarningEvents(c, timeoutErrorf("pod %s did not reach the Running state", dnsCheckPodName))
+	}
+	exec.Println()
+
+	var results []dnsLookupResult
+	for _, l := range dnsCheckLookups(dnsDomain, externalName) {
+		cp1.Infof("resolving %s %s", l.kind, l.name)
+		output, err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+			"exec", dnsCheckPodName, "--", "nslookup", l.name,
+		).Silent().RunAndCapture()
+		results = append(results, dnsLookupResult{dnsLookup: l, output: output, err: err})
+	}
+
+	exec.Printf("%s", formatDNSCheckResults(results))
+	if failed := failedDNSLookups(results); len(failed) > 0 {
+		return errors.Errorf("DNS check failed: %s can not be resolved", strings.Join(failed, ", "))
+	}
+	exec.Printf("\nDNS check passed!\n")
+	return nil
+}
+
+// dnsCheckLookups returns the names resolved by the dns-check action: the kubernetes service, both via
+// the search path and as a fully qualified name, and the external name, if any
+func dnsCheckLookups(dnsDomain, externalName string) []dnsLookup {
+	lookups := []dnsLookup{
+		{kind: "cluster service", name: "kubernetes.default"},
+		{kind: "cluster service", name: fmt.Sprintf("kubernetes.default.svc.%s", dnsDomain)},
+	}
+	if externalName != "" {
+		lookups = append(lookups, dnsLookup{kind: "external name", name: externalName})
+	}
+	return lookups
+}
+
+// formatDNSCheckResults returns a summary of the lookups, with one line for each name;
+// the output of failed lookups is included for diagnostics
+func formatDNSCheckResults(results []dnsLookupResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		if r.err == nil {
+			fmt.Fprintf(&b, "%s %s: resolved\n", r.kind, r.name)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %s: failed (%v)\n", r.kind, r.name, r.err)
+		for _, l := range r.output {
+			fmt.Fprintf(&b, "  %s\n", l)
+		}
+	}
+	return b.String()
+}
+
+// failedDNSLookups returns the names that were not resolved
+func failedDNSLookups(results []dnsLookupResult) []string {
+	var failed []string
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r.name)
+		}
+	}
+	return failed
+}
+
+func cleanupDNSCheck(cp1 *status.Node) {
+	cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"delete", "pod", dnsCheckPodName, "--ignore-not-found", "--grace-period=0", "--force",
+	).Silent().Run()
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDNSCheckLookups(t *testing.T) {
+	tests := []struct {
+		name         string
+		externalName string
+		expected     []string
+	}{
+		{
+			name:     "external name skipped",
+			expected: []string{"kubernetes.default", "kubernetes.default.svc.cluster.local"},
+		},
+		{
+			name:         "external name",
+			externalName: "kubernetes.io",
+			expected:     []string{"kubernetes.default", "kubernetes.default.svc.cluster.local", "kubernetes.io"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var names []string
+			for _, l := range dnsCheckLookups("cluster.local", test.externalName) {
+				names = append(names, l.name)
+			}
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected %v, found %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestDNSCheckResults(t *testing.T) {
+	results := []dnsLookupResult{
+		{dnsLookup: dnsLookup{kind: "cluster service", name: "kubernetes.default"}},
+		{
+			dnsLookup: dnsLookup{kind: "external name", name: "kubernetes.io"},
+			output:    []string{"nslookup: can't resolve 'kubernetes.io'"},
+			err:       errors.New("exit status 1"),
+		},
+	}
+
+	expectedSummary := "cluster service kubernetes.default: resolved\n" +
+		"external name kubernetes.io: failed (exit status 1)\n" +
+		"  nslookup: can't resolve 'kubernetes.io'\n"
+	if summary := formatDNSCheckResults(results); summary != expectedSummary {
+		t.Errorf("expected summary:\n%s\nfound:\n%s", expectedSummary, summary)
+	}
+
+	if failed := failedDNSLookups(results); !reflect.DeepEqual(failed, []string{"kubernetes.io"}) {
+		t.Errorf("expected failed lookups [kubernetes.io], found %v", failed)
+	}
+}
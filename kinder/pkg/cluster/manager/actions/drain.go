@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// DrainOptions defines how nodes are drained before disruptive actions, e.g. kubeadm-reset and kubeadm-upgrade
+type DrainOptions struct {
+	// Enabled instructs actions to drain nodes before disrupting them, and to uncordon nodes afterwards when possible
+	Enabled bool
+	// IgnoreDaemonSets ignores DaemonSet-managed pods, that can't be evicted
+	IgnoreDaemonSets bool
+	// DeleteEmptyDirData allows evicting pods using emptyDir volumes, deleting the local data
+	DeleteEmptyDirData bool
+	// GracePeriod is the termination grace period for evicted pods; a negative value uses the pod default
+	GracePeriod time.Duration
+	// Force allows draining the last schedulable node in the cluster
+	Force bool
+}
+
+// drainNode cordons and drains a node with kubectl drain, waiting for the evictions to complete up to
+// the given timeout; draining the last schedulable node is refused, unless forced, because evicted pods
+// would have no node to be rescheduled on. Nodes disrupted in the same run, e.g. reset, are not considered
+// for rescheduling evicted pods, and the check is skipped when all the nodes in the cluster are disrupted,
+// because in this case the workloads are removed anyway.
+func drainNode(c *status.Cluster, n *status.Node, opts DrainOptions, timeout time.Duration, disrupted ...*status.Node) error {
+	cp1 := c.BootstrapControlPlane()
+
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "nodes",
+		`-o=jsonpath={range .items[*]}{.metadata.name}{"\t"}{.spec.unschedulable}{"\t"}{range .spec.taints[*]}{.effect}{","}{end}{"\n"}{end}`,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the nodes for draining node %s", n.Name())
+	}
+	excluded := []string{n.KubeNodeName()}
+	for _, d := range disrupted {
+		excluded = append(excluded, d.KubeNodeName())
+	}
+	if remaining, schedulable := schedulableNodesAfterDrain(lines, excluded...); remaining > 0 && schedulable == 0 && !opts.Force {
+		return errors.Errorf("draining node %s would leave no schedulable nodes in the cluster. Use --force to drain it anyway", n.Name())
+	}
+
+	n.Infof("Draining node")
+	args := []string{
+		"--kubeconfig=/etc/kubernetes/admin.conf", "drain", n.KubeNodeName(),
+		fmt.Sprintf("--ignore-daemonsets=%t", opts.IgnoreDaemonSets),
+		fmt.Sprintf("--delete-emptydir-data=%t", opts.DeleteEmptyDirData),
+		fmt.Sprintf("--grace-period=%d", gracePeriodSeconds(opts.GracePeriod)),
+		fmt.Sprintf("--timeout=%s", timeout),
+	}
+	if err := cp1.Command("kubectl", args...).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "failed to drain node %s", n.Name())
+	}
+	return nil
+}
+
+// uncordonNode marks a node as schedulable again after it was drained
+func uncordonNode(c *status.Cluster, n *status.Node) error {
+	n.Infof("Uncordoning node")
+	if err := c.BootstrapControlPlane().Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "uncordon", n.KubeNodeName(),
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to uncordon node %s", n.Name())
+	}
+	return nil
+}
+
+// gracePeriodSeconds returns the kubectl drain --grace-period value for a duration; negative
+// durations are mapped to -1, that is the pod default
+func gracePeriodSeconds(d time.Duration) int {
+	if d < 0 {
+		return -1
+	}
+	return int(d / time.Second)
+}
+
+// schedulableNodesAfterDrain returns the number of nodes other than the drained ones, and how many of them are
+// schedulable, given a list of nodes in the "name<TAB>unschedulable<TAB>taint effects" form; nodes cordoned
+// or with NoSchedule/NoExecute taints are not schedulable
+func schedulableNodesAfterDrain(lines []string, drained ...string) (remaining, schedulable int) {
+	isDrained := map[string]bool{}
+	for _, d := range drained {
+		isDrained[d] = true
+	}
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if fields[0] == "" || isDrained[fields[0]] {
+			continue
+		}
+		remaining++
+		if len(fields) > 1 && fields[1] == "true" {
+			continue
+		}
+		if len(fields) > 2 && hasNoScheduleTaint(strings.Split(fields[2], ",")) {
+			continue
+		}
+		schedulable++
+	}
+	return remaining, schedulable
+}
+
+func hasNoScheduleTaint(effects []string) bool {
+	for _, e := range effects {
+		if e == "NoSchedule" || e == "NoExecute" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulableNodesAfterDrain(t *testing.T) {
+	cases := []struct {
+		name     string
+		lines    []string
+		drained  string
+		expected int
+	}{
+		{
+			name: "other workers available",
+			lines: []string{
+				"kind-control-plane-1\t\tNoSchedule,",
+				"kind-worker-1\t\t",
+				"kind-worker-2\t\t",
+			},
+			drained:  "kind-worker-1",
+			expected: 1,
+		},
+		{
+			name: "last worker",
+			lines: []string{
+				"kind-control-plane-1\t\tNoSchedule,",
+				"kind-worker-1\t\t",
+			},
+			drained:  "kind-worker-1",
+			expected: 0,
+		},
+		{
+			name: "other worker cordoned",
+			lines: []string{
+				"kind-worker-1\t\t",
+				"kind-worker-2\ttrue\t",
+			},
+			drained:  "kind-worker-1",
+			expected: 0,
+		},
+		{
+			name: "prefer no schedule taint is schedulable",
+			lines: []string{
+				"kind-worker-1\t\t",
+				"kind-worker-2\t\tPreferNoSchedule,",
+			},
+			drained:  "kind-worker-1",
+			expected: 1,
+		},
+		{
+			name: "single node cluster",
+			lines: []string{
+				"kind-control-plane-1\t\t",
+			},
+			drained:  "kind-control-plane-1",
+			expected: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, got := schedulableNodesAfterDrain(c.lines, c.drained); got != c.expected {
+				t.Errorf("expected %d, got %d", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestSchedulableNodesAfterDrainMany(t *testing.T) {
+	lines := []string{
+		"kind-control-plane-1\t\tNoSchedule,",
+		"kind-worker-1\ttrue\t",
+		"kind-worker-2\t\t",
+	}
+
+	cases := []struct {
+		name                string
+		drained             []string
+		expectedRemaining   int
+		expectedSchedulable int
+	}{
+		{
+			name:                "one node drained",
+			drained:             []string{"kind-worker-2"},
+			expectedRemaining:   2,
+			expectedSchedulable: 0,
+		},
+		{
+			name:                "partial reset excludes the nodes reset in the same run",
+			drained:             []string{"kind-worker-1", "kind-worker-2"},
+			expectedRemaining:   1,
+			expectedSchedulable: 0,
+		},
+		{
+			name:                "last node of a whole cluster reset",
+			drained:             []string{"kind-control-plane-1", "kind-worker-1", "kind-worker-2"},
+			expectedRemaining:   0,
+			expectedSchedulable: 0,
+		},
+		{
+			name:                "unknown node",
+			drained:             []string{"kind-worker-3"},
+			expectedRemaining:   3,
+			expectedSchedulable: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			remaining, schedulable := schedulableNodesAfterDrain(lines, c.drained...)
+			if remaining != c.expectedRemaining || schedulable != c.expectedSchedulable {
+				t.Errorf("expected %d remaining and %d schedulable nodes, got %d and %d", c.expectedRemaining, c.expectedSchedulable, remaining, schedulable)
+			}
+		})
+	}
+}
+
+func TestGracePeriodSeconds(t *testing.T) {
+	cases := []struct {
+		duration time.Duration
+		expected int
+	}{
+		{duration: -1 * time.Second, expected: -1},
+		{duration: 0, expected: 0},
+		{duration: 30 * time.Second, expected: 30},
+	}
+
+	for _, c := range cases {
+		t.Run(c.duration.String(), func(t *testing.T) {
+			if got := gracePeriodSeconds(c.duration); got != c.expected {
+				t.Errorf("expected %d, got %d", c.expected, got)
+			}
+		})
+	}
+}
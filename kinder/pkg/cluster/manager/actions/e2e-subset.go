@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes/common"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+const (
+	// defaultE2EImageRepository defines the repository of the image running the e2e tests, if not differently
+	// specified; the image tag is the Kubernetes version of the cluster
+	defaultE2EImageRepository = "registry.k8s.io/conformance"
+
+	// defaultE2EBinary defines the path of the e2e test binary in the image, if not differently specified
+	defaultE2EBinary = "/usr/local/bin/e2e.test"
+
+	// e2eResultsMountPath defines the path in the test container where the results directory is mounted
+	e2eResultsMountPath = "/tmp/results"
+)
+
+// E2ESubsetOptions defines the e2e tests run by the e2e-subset action
+type E2ESubsetOptions struct {
+	// Focus and Skip are the regular expressions selecting the tests to run; Focus is required
+	Focus string
+	Skip  string
+
+	// Image is the image providing the e2e test binary; if empty, the conformance image for the Kubernetes
+	// version of the cluster is used. Set it to a pre-pulled image for offline use
+	Image string
+
+	// Binary is the path of the e2e test binary in the image; if empty, defaultE2EBinary is used
+	Binary string
+
+	// ResultsDir is the host directory where the kubeconfig used by the tests and the test results are written;
+	// if empty, a temporary directory is used
+	ResultsDir string
+}
+
+// E2ESubset action runs a subset of the Kubernetes e2e tests, selected by focus and skip regular expressions,
+// in a container connected to the cluster network; the tests use the admin kubeconfig, with the API server
+// endpoint reachable from the nodes. The path of the test results is printed both on success and on failure.
+func E2ESubset(c *status.Cluster, opts E2ESubsetOptions) error {
+	if opts.Focus == "" {
+		return errors.New("the focus regular expression for the e2e tests is required. Use --e2e-focus")
+	}
+	for _, r := range []string{opts.Focus, opts.Skip} {
+		if _, err := regexp.Compile(r); err != nil {
+			return errors.Wrapf(err, "invalid regular expression %q for the e2e tests", r)
+		}
+	}
+
+	cp1 := c.BootstrapControlPlane()
+
+	image := opts.Image
+	if image == "" {
+		version, err := cp1.KubeVersion()
+		if err != nil {
+			return err
+		}
+		image = fmt.Sprintf("%s:%s", defaultE2EImageRepository, kubernetesVersionToImageTag(version))
+	}
+
+	resultsDir, err := e2eResultsDir(opts.ResultsDir)
+	if err != nil {
+		return err
+	}
+
+	// the tests run on the cluster network, so they use the API server endpoint reachable from the nodes
+	endpoint, err := c.APIServerEndpoint()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the API server endpoint")
+	}
+	adminConf, err := cp1.Command("cat", adminConfPath).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s from node %s", adminConfPath, cp1.Name())
+	}
+	kubeconfig := e2eKubeConfig(strings.Join(adminConf, "\n")+"\n", fmt.Sprintf("https://%s", endpoint))
+	if err := os.WriteFile(filepath.Join(resultsDir, "kubeconfig"), []byte(kubeconfig), 0600); err != nil {
+		return errors.Wrap(err, "failed to write the kubeconfig for the e2e tests")
+	}
+
+	network, err := c.Network()
+	if err != nil {
+		return err
+	}
+	if network == "" {
+		network = common.DefaultNetwork
+	}
+
+	cp1.Infof("running e2e tests with focus %q and skip %q using image %s", opts.Focus, opts.Skip, image)
+	if err := exec.NewHostCmd("docker", e2eSubsetArgs(opts, image, network, resultsDir)...).RunWithEcho(); err != nil {
+		return errors.Wrapf(err, "e2e tests failed; results are in %s", resultsDir)
+	}
+
+	exec.Printf("\ne2e tests passed! results are in %s\n", resultsDir)
+	return nil
+}
+
+// e2eResultsDir returns the absolute path of the results directory, creating it if required;
+// if dir is empty, a temporary directory is created
+func e2eResultsDir(dir string) (string, error) {
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "kinder-e2e-")
+		if err != nil {
+			return "", errors.Wrap(err, "failed to create a directory for the e2e results")
+		}
+		return tmp, nil
+	}
+	path, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid directory %q", dir)
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return "", errors.Wrapf(err, "failed to create the directory %s", path)
+	}
+	return path, nil
+}
+
+// kubeConfigServerRegexp matches the server field of the clusters in a kubeconfig file
+var kubeConfigServerRegexp = regexp.MustCompile(`(?m)^(\s*server:\s*).*$`)
+
+// e2eKubeConfig returns the kubeconfig with the server of all the clusters set to the given URL
+func e2eKubeConfig(kubeconfig, server string) string {
+	return kubeConfigServerRegexp.ReplaceAllString(kubeconfig, "${1}"+server)
+}
+
+// e2eSubsetArgs returns the docker run arguments for running the e2e test binary; the results directory
+// is mounted in the container, and it contains the kubeconfig used by the tests
+func e2eSubsetArgs(opts E2ESubsetOptions, image, network, resultsDir string) []string {
+	binary := opts.Binary
+	if binary == "" {
+		binary = defaultE2EBinary
+	}
+
+	args := []string{
+		"run", "--rm",
+		"--network", network,
+		"--volume", fmt.Sprintf("%s:%s", resultsDir, e2eResultsMountPath),
+		"--entrypoint", binary,
+		image,
+		fmt.Sprintf("--kubeconfig=%s/kubeconfig", e2eResultsMountPath),
+		fmt.Sprintf("--report-dir=%s", e2eResultsMountPath),
+		"--disable-log-dump",
+		fmt.Sprintf("--ginkgo.focus=%s", opts.Focus),
+	}
+	if opts.Skip != "" {
+		args = append(args, fmt.Sprintf("--ginkgo.skip=%s", opts.Skip))
+	}
+	return args
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestE2EKubeConfig(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Q0E=
+    server: https://kind-control-plane:6443
+  name: kind
+kind: Config
+`
+	expected := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Q0E=
+    server: https://172.18.0.3:6443
+  name: kind
+kind: Config
+`
+	if found := e2eKubeConfig(kubeconfig, "https://172.18.0.3:6443"); found != expected {
+		t.Errorf("expected:\n%s\nfound:\n%s", expected, found)
+	}
+}
+
+func TestE2ESubsetArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     E2ESubsetOptions
+		expected []string
+	}{
+		{
+			name: "defaults",
+			opts: E2ESubsetOptions{Focus: `\[sig-network\] DNS`},
+			expected: []string{
+				"run", "--rm", "--network", "kind", "--volume", "/results:/tmp/results",
+				"--entrypoint", "/usr/local/bin/e2e.test", "registry.k8s.io/conformance:v1.31.0",
+				"--kubeconfig=/tmp/results/kubeconfig", "--report-dir=/tmp/results", "--disable-log-dump",
+				`--ginkgo.focus=\[sig-network\] DNS`,
+			},
+		},
+		{
+			name: "skip and binary",
+			opts: E2ESubsetOptions{Focus: "Conformance", Skip: "Serial", Binary: "/e2e.test"},
+			expected: []string{
+				"run", "--rm", "--network", "kind", "--volume", "/results:/tmp/results",
+				"--entrypoint", "/e2e.test", "registry.k8s.io/conformance:v1.31.0",
+				"--kubeconfig=/tmp/results/kubeconfig", "--report-dir=/tmp/results", "--disable-log-dump",
+				"--ginkgo.focus=Conformance", "--ginkgo.skip=Serial",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := e2eSubsetArgs(test.opts, "registry.k8s.io/conformance:v1.31.0", "kind", "/results")
+			if !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected %v, found %v", test.expected, args)
+			}
+		})
+	}
+}
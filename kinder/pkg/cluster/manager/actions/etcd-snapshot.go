@@ -0,0 +1,448 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	versionutils "k8s.io/apimachinery/pkg/util/version"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes/common"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+const (
+	// etcdSnapshotPath defines the path of the etcd snapshot on stacked etcd nodes;
+	// /var/lib/etcd is mounted in the etcd static pod, so the snapshot is written on the node
+	etcdSnapshotPath = "/var/lib/etcd/kinder-snapshot.db"
+
+	// etcdRestoreSnapshotPath and etcdRestoreDataDir define the paths used on stacked etcd nodes for restoring a snapshot
+	etcdRestoreSnapshotPath = "/var/lib/kinder-etcd-snapshot.db"
+	etcdRestoreDataDir      = "/var/lib/kinder-etcd-restore"
+
+	// etcdBackupDataDir defines the path where the etcd data dir is preserved after restoring a snapshot on stacked etcd nodes
+	etcdBackupDataDir = "/var/lib/etcd.kinder-backup"
+
+	// externalEtcdSnapshotPath defines the path of the etcd snapshot on external etcd nodes
+	externalEtcdSnapshotPath = "/kinder-snapshot.db"
+
+	// manifestsBackupDir defines the path where static pod manifests are moved for stopping the control-plane
+	manifestsBackupDir = "/etc/kubernetes/kinder-manifests-backup"
+
+	// controlPlaneStopTimeout defines the timeout for the control-plane containers to stop
+	controlPlaneStopTimeout = 2 * time.Minute
+)
+
+// EtcdSnapshot saves a snapshot of etcd to the given host path; with stacked etcd the snapshot is taken
+// from the bootstrap control-plane, otherwise from the first external etcd member.
+func EtcdSnapshot(c *status.Cluster, snapshotPath string) error {
+	if err := checkEtcdManagedByKinder(c); err != nil {
+		return err
+	}
+	snapshotPath, err := absSnapshotPath(snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	if etcd := c.ExternalEtcd(); etcd != nil {
+		etcd.Infof("saving etcd snapshot to %s", snapshotPath)
+		if lines, err := etcdctl(etcd, "snapshot", "save", externalEtcdSnapshotPath).RunAndCapture(); err != nil {
+			return errors.Wrapf(err, "failed to save the etcd snapshot: %s", strings.Join(lines, "\n"))
+		}
+		return etcd.CopyFrom(externalEtcdSnapshotPath, snapshotPath)
+	}
+
+	cp1 := c.BootstrapControlPlane()
+	id, err := etcdContainerID(cp1)
+	if err != nil {
+		return err
+	}
+
+	cp1.Infof("saving etcd snapshot to %s", snapshotPath)
+	args := append([]string{"exec", id, "etcdctl", "--endpoints=https://127.0.0.1:2379"}, etcdCertArgsNew...)
+	args = append(args, "snapshot", "save", etcdSnapshotPath)
+	if lines, err := cp1.Command("crictl", args...).Silent().RunAndCapture(); err != nil {
+		return errors.Wrapf(err, "failed to save the etcd snapshot: %s", strings.Join(lines, "\n"))
+	}
+	defer func() {
+		_ = cp1.Command("rm", "-f", etcdSnapshotPath).Silent().Run()
+	}()
+
+	return cp1.CopyFrom(etcdSnapshotPath, snapshotPath)
+}
+
+// EtcdRestore restores an etcd snapshot saved on the host by EtcdSnapshot, following the kubeadm
+// disaster recovery procedure: the control-plane is stopped, the snapshot is restored on all the
+// etcd members, and then the control-plane is restarted and checked for being healthy.
+// The previous stacked etcd data dir is preserved on each node in /var/lib/etcd.kinder-backup.
+func EtcdRestore(c *status.Cluster, snapshotPath string, wait time.Duration) error {
+	if err := checkEtcdManagedByKinder(c); err != nil {
+		return err
+	}
+	snapshotPath, err := absSnapshotPath(snapshotPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(snapshotPath); err != nil {
+		return errors.Wrapf(err, "invalid etcd snapshot %q", snapshotPath)
+	}
+
+	external := c.UsesExternalEtcd()
+
+	cps := c.ControlPlanes().EligibleForActions()
+
+	// stacked etcd members are restored using the image of the etcd static pod
+	var etcdImage string
+	if !external {
+		if etcdImage, err = stackedEtcdImage(c.BootstrapControlPlane()); err != nil {
+			return err
+		}
+		for _, cp := range cps {
+			if cri, err := cp.CRI(); err != nil || cri != status.ContainerdRuntime {
+				return errors.Errorf("restoring a snapshot with stacked etcd is supported only for nodes with the %s container runtime", status.ContainerdRuntime)
+			}
+		}
+	}
+
+	for _, cp := range cps {
+		if err := stopControlPlane(cp); err != nil {
+			return err
+		}
+	}
+
+	if external {
+		if err := restoreExternalEtcd(c, snapshotPath); err != nil {
+			return err
+		}
+	} else {
+		if err := restoreStackedEtcd(cps, snapshotPath, etcdImage); err != nil {
+			return err
+		}
+	}
+
+	for _, cp := range cps {
+		if err := startControlPlane(cp); err != nil {
+			return err
+		}
+	}
+
+	// verifies the cluster is healthy after restore
+	for _, cp := range cps {
+		if err := waitNewControlPlaneNodeReady(c, cp, wait); err != nil {
+			return err
+		}
+		if !external {
+			if pass := waitFor(c, cp, wait, staticPodIsReady("etcd")); !pass {
+				return timeoutErrorf("etcd did not reach target state")
+			}
+		}
+	}
+
+	if external {
+		ips, err := externalEtcdIPs(c)
+		if err != nil {
+			return err
+		}
+		return updateKubeadmConfigEtcdEndpoints(c.BootstrapControlPlane(), externalEtcdEndpoints(ips))
+	}
+	return nil
+}
+
+// restoreStackedEtcd restores the snapshot on each control-plane node; the etcd image is executed with
+// ctr, because etcdutl is not installed on the nodes
+func restoreStackedEtcd(cps status.NodeList, snapshotPath, etcdImage string) error {
+	var names, ips []string
+	for _, cp := range cps {
+		ipv4, _, err := cp.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get IP for node: %s", cp.Name())
+		}
+		names = append(names, cp.KubeNodeName())
+		ips = append(ips, ipv4)
+	}
+	initialCluster := etcdInitialCluster(names, ips, "https")
+
+	for i, cp := range cps {
+		cp.Infof("restoring etcd snapshot %s", snapshotPath)
+		if err := cp.CopyTo(snapshotPath, etcdRestoreSnapshotPath); err != nil {
+			return errors.Wrapf(err, "failed to copy the etcd snapshot to node %s", cp.Name())
+		}
+
+		args := []string{
+			"--namespace=k8s.io", "run", "--rm", "--net-host",
+			"--mount", "type=bind,src=/var/lib,dst=/var/lib,options=rbind:rw",
+		}
+		tool := etcdRestoreTool(etcdImage)
+		if tool == "etcdctl" {
+			args = append(args, "--env", "ETCDCTL_API=3")
+		}
+		args = append(args, etcdImage, "kinder-etcd-restore", tool)
+		args = append(args, etcdRestoreArgs(etcdRestoreSnapshotPath, etcdRestoreDataDir, names[i], initialCluster, etcdPeerURL(ips[i], "https"))...)
+		if err := cp.Command("ctr", args...).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to restore the etcd snapshot on node %s", cp.Name())
+		}
+
+		// replaces the etcd data dir, preserving the previous one
+		if err := cp.Command(
+			"sh", "-c",
+			fmt.Sprintf("rm -rf %[1]s && mv /var/lib/etcd %[1]s && mv %[2]s /var/lib/etcd && rm -f %[3]s", etcdBackupDataDir, etcdRestoreDataDir, etcdRestoreSnapshotPath),
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to replace the etcd data dir on node %s", cp.Name())
+		}
+	}
+	return nil
+}
+
+// restoreExternalEtcd restores the snapshot in a new data dir on the host for each member, next to the snapshot,
+// and then re-creates the external etcd members using them; the kube-apiserver manifests are updated in case the
+// member IPs changed
+func restoreExternalEtcd(c *status.Cluster, snapshotPath string) error {
+	etcds := c.ExternalEtcds()
+	etcd := etcds[0]
+	image, err := etcd.EtcdImage()
+	if err != nil {
+		return err
+	}
+
+	// the member names and peer URLs should match the ones used when creating the external etcd members;
+	// members are re-created with consecutive names, that might differ from the current ones after
+	// adding and removing members
+	members := make([]string, 0, len(etcds))
+	for i := 1; i <= len(etcds); i++ {
+		members = append(members, common.ExternalEtcdMemberName(c.Name(), i))
+	}
+	initialCluster := common.ExternalEtcdInitialCluster(members)
+
+	dataDirs := make([]string, 0, len(members))
+	for _, member := range members {
+		dataDir := filepath.Join(filepath.Dir(snapshotPath), fmt.Sprintf("%s-restore", member))
+		if _, err := os.Stat(dataDir); err == nil {
+			return errors.Errorf("the directory %s for restoring the etcd snapshot already exists", dataDir)
+		}
+		if err := os.MkdirAll(dataDir, 0700); err != nil {
+			return errors.Wrapf(err, "failed to create the directory %s", dataDir)
+		}
+
+		etcd.Infof("restoring etcd snapshot %s in %s", snapshotPath, dataDir)
+		peerURL := common.ExternalEtcdPeerURL(member)
+		args := []string{
+			"run", "--rm",
+			"--volume", fmt.Sprintf("%s:/snapshot.db:ro", snapshotPath),
+			"--volume", fmt.Sprintf("%s:/restore", dataDir),
+		}
+		tool := etcdRestoreTool(image)
+		if tool == "etcdctl" {
+			args = append(args, "--env", "ETCDCTL_API=3")
+		}
+		args = append(args, image, tool)
+		args = append(args, etcdRestoreArgs("/snapshot.db", "/restore/data", member, initialCluster, peerURL)...)
+		if err := exec.NewHostCmd("docker", args...).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to restore the etcd snapshot for member %s", member)
+		}
+		dataDirs = append(dataDirs, filepath.Join(dataDir, "data"))
+	}
+
+	for _, m := range etcds {
+		if err := exec.NewHostCmd("docker", "rm", "-f", "-v", m.Name()).Run(); err != nil {
+			return errors.Wrapf(err, "failed to delete node %s", m.Name())
+		}
+	}
+
+	// the re-created external etcd keeps the quota and auto-compaction settings defined at create time
+	etcdExtraArgs, err := c.BootstrapControlPlane().EtcdExtraArgs()
+	if err != nil {
+		return err
+	}
+
+	network, err := c.Network()
+	if err != nil {
+		return err
+	}
+	cri, err := c.BootstrapControlPlane().CRI()
+	if err != nil {
+		return err
+	}
+	createHelper, err := nodes.NewCreateHelper(cri, network)
+	if err != nil {
+		return err
+	}
+	if len(members) == 1 {
+		if err := createHelper.CreateExternalEtcd(c.Name(), members[0], image, dataDirs[0], etcdExtraArgs); err != nil {
+			return errors.Wrapf(err, "failed to re-create node %s", members[0])
+		}
+	} else {
+		if err := createHelper.CreateExternalEtcdClusterWithDataDirs(c.Name(), image, dataDirs, etcdExtraArgs); err != nil {
+			return errors.Wrap(err, "failed to re-create the external etcd members")
+		}
+	}
+
+	// reads the cluster nodes again, so the new members are used
+	if err := c.Refresh(); err != nil {
+		return err
+	}
+	ips, err := externalEtcdIPs(c)
+	if err != nil {
+		return err
+	}
+	endpoints := strings.Join(externalEtcdEndpoints(ips), ",")
+
+	for _, cp := range c.ControlPlanes().EligibleForActions() {
+		manifest := filepath.Join(manifestsBackupDir, "kube-apiserver.yaml")
+		if err := cp.Command("test", "-f", manifest).Silent().Run(); err != nil {
+			continue
+		}
+		if err := cp.Command(
+			"sed", "-i", fmt.Sprintf("s#--etcd-servers=.*#--etcd-servers=%s#", endpoints), manifest,
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to update etcd endpoints on node %s", cp.Name())
+		}
+	}
+	return nil
+}
+
+// checkEtcdManagedByKinder returns an error if the cluster uses an external etcd not managed by kinder,
+// whose snapshots can't be saved or restored by kinder
+func checkEtcdManagedByKinder(c *status.Cluster) error {
+	if c.ExternalEtcd() == nil && c.UsesExternalEtcd() {
+		return errors.New("the cluster uses an external etcd not managed by kinder; snapshots should be saved and restored directly on the etcd cluster")
+	}
+	return nil
+}
+
+// stopControlPlane stops the control-plane components on a node, by moving the static pod manifests
+// away from the kubelet manifests dir, and waits for the etcd and kube-apiserver containers to stop
+func stopControlPlane(n *status.Node) error {
+	n.Infof("stopping the control-plane")
+	if err := n.Command(
+		"sh", "-c",
+		fmt.Sprintf("mkdir -p %[1]s && mv /etc/kubernetes/manifests/*.yaml %[1]s/", manifestsBackupDir),
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to move the static pod manifests on node %s", n.Name())
+	}
+
+	stopped := common.TryUntil(time.Now().Add(controlPlaneStopTimeout), func() bool {
+		lines, err := n.Command("crictl", "ps", "-q", "--name", "^(etcd|kube-apiserver)$").Silent().RunAndCapture()
+		if err == nil && len(lines) == 0 {
+			return true
+		}
+		time.Sleep(time.Second)
+		return false
+	})
+	if !stopped {
+		return errors.Errorf("the control-plane on node %s did not stop in %v", n.Name(), controlPlaneStopTimeout)
+	}
+	return nil
+}
+
+// startControlPlane starts the control-plane components on a node stopped by stopControlPlane
+func startControlPlane(n *status.Node) error {
+	n.Infof("starting the control-plane")
+	if err := n.Command(
+		"sh", "-c",
+		fmt.Sprintf("mv %[1]s/*.yaml /etc/kubernetes/manifests/ && rmdir %[1]s", manifestsBackupDir),
+	).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to restore the static pod manifests on node %s", n.Name())
+	}
+	return nil
+}
+
+// etcdContainerID returns the ID of the etcd container running on a control-plane node
+func etcdContainerID(n *status.Node) (string, error) {
+	lines, err := n.Command("crictl", "ps", "-q", "--name", "^etcd$").Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the etcd container on node %s", n.Name())
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("expected one etcd container running on node %s, got %d", n.Name(), len(lines))
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
+
+// stackedEtcdImage returns the image used by the etcd static pod on a control-plane node
+func stackedEtcdImage(n *status.Node) (string, error) {
+	lines, err := n.Command("sed", "-n", `s/^ *image: *//p`, "/etc/kubernetes/manifests/etcd.yaml").Silent().RunAndCapture()
+	if err != nil || len(lines) != 1 {
+		return "", errors.Errorf("failed to get the etcd image on node %s: %v", n.Name(), err)
+	}
+	return strings.Trim(strings.TrimSpace(lines[0]), `"'`), nil
+}
+
+func absSnapshotPath(snapshotPath string) (string, error) {
+	if snapshotPath == "" {
+		return "", errors.New("the etcd snapshot path is required. Use --snapshot-path")
+	}
+	path, err := filepath.Abs(snapshotPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid etcd snapshot path %q", snapshotPath)
+	}
+	return path, nil
+}
+
+func externalEtcdEndpoints(ips []string) []string {
+	var endpoints []string
+	for _, ip := range ips {
+		endpoints = append(endpoints, kubeadm.ExternalEtcdEndpoint(ip))
+	}
+	return endpoints
+}
+
+// etcdRestoreTool returns the etcd tool to be used for restoring a snapshot with the given etcd image;
+// etcdutl is used since etcd v3.5, while etcdctl is used for older versions
+func etcdRestoreTool(image string) string {
+	tag := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(tag, ":")
+	if i < 0 {
+		return "etcdutl"
+	}
+	version, err := versionutils.ParseGeneric(tag[i+1:])
+	if err != nil || version.AtLeast(versionutils.MustParseGeneric("v3.5.0")) {
+		return "etcdutl"
+	}
+	return "etcdctl"
+}
+
+// etcdRestoreArgs returns the args for restoring an etcd snapshot for a member
+func etcdRestoreArgs(snapshotPath, dataDir, name, initialCluster, peerURL string) []string {
+	return []string{
+		"snapshot", "restore", snapshotPath,
+		fmt.Sprintf("--data-dir=%s", dataDir),
+		fmt.Sprintf("--name=%s", name),
+		fmt.Sprintf("--initial-cluster=%s", initialCluster),
+		fmt.Sprintf("--initial-advertise-peer-urls=%s", peerURL),
+	}
+}
+
+// etcdInitialCluster returns the etcd initial cluster for members with the given names and IPs
+func etcdInitialCluster(names, ips []string, scheme string) string {
+	var members []string
+	for i := range names {
+		members = append(members, fmt.Sprintf("%s=%s", names[i], etcdPeerURL(ips[i], scheme)))
+	}
+	return strings.Join(members, ",")
+}
+
+func etcdPeerURL(ip, scheme string) string {
+	return fmt.Sprintf("%s://%s:2380", scheme, ip)
+}
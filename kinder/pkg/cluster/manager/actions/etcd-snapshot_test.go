@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestEtcdRestoreTool(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{image: "registry.k8s.io/etcd:3.5.12-0", expected: "etcdutl"},
+		{image: "registry.k8s.io/etcd:3.4.13-0", expected: "etcdctl"},
+		{image: "localhost:5000/etcd:v3.6.0", expected: "etcdutl"},
+		{image: "localhost:5000/etcd", expected: "etcdutl"},
+		{image: "registry.k8s.io/etcd:latest", expected: "etcdutl"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			if tool := etcdRestoreTool(test.image); tool != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, tool)
+			}
+		})
+	}
+}
+
+func TestEtcdInitialCluster(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		ips      []string
+		expected string
+	}{
+		{
+			name:     "one member",
+			names:    []string{"kind-control-plane"},
+			ips:      []string{"172.17.0.2"},
+			expected: "kind-control-plane=https://172.17.0.2:2380",
+		},
+		{
+			name:     "multiple members",
+			names:    []string{"kind-control-plane", "kind-control-plane2"},
+			ips:      []string{"172.17.0.2", "172.17.0.3"},
+			expected: "kind-control-plane=https://172.17.0.2:2380,kind-control-plane2=https://172.17.0.3:2380",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if initialCluster := etcdInitialCluster(test.names, test.ips, "https"); initialCluster != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, initialCluster)
+			}
+		})
+	}
+}
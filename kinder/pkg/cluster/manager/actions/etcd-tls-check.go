@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+const (
+	// etcdManifestPath is the path of the static pod manifest of the stacked etcd on control-plane nodes
+	etcdManifestPath = "/etc/kubernetes/manifests/etcd.yaml"
+
+	// etcdCAPath is the path of the etcd CA certificate generated by kubeadm
+	etcdCAPath = "/etc/kubernetes/pki/etcd/ca.crt"
+)
+
+// EtcdTLSCheck verifies that etcd uses TLS for client and peer communication, with client certificate
+// authentication and the expected CA; for stacked etcd, the etcd static pod manifest on each control-plane node
+// is inspected, while for external etcd the args of each member are inspected. All the insecure settings are reported.
+func EtcdTLSCheck(c *status.Cluster) error {
+	var insecure []string
+	if c.ExternalEtcd() == nil && c.UsesExternalEtcd() {
+		// the args of an external etcd not managed by kinder can't be inspected, so only the endpoints are checked
+		cp1 := c.BootstrapControlPlane()
+		insecure = append(insecure, reportEtcdTLSViolations(cp1, etcdEndpointsTLSViolations(c.Settings.ExternalEtcdEndpoints))...)
+	} else if c.ExternalEtcd() != nil {
+		for _, n := range c.ExternalEtcds().EligibleForActions() {
+			args, err := externalEtcdArgs(n)
+			if err != nil {
+				return err
+			}
+			// the external etcd is not using certificates generated by kubeadm, so the CA can't be checked
+			insecure = append(insecure, reportEtcdTLSViolations(n, etcdTLSViolations(args, ""))...)
+		}
+	} else {
+		for _, cp := range c.ControlPlanes().EligibleForActions() {
+			args, err := stackedEtcdArgs(cp)
+			if err != nil {
+				return err
+			}
+			insecure = append(insecure, reportEtcdTLSViolations(cp, etcdTLSViolations(args, etcdCAPath))...)
+		}
+	}
+
+	if len(insecure) > 0 {
+		return errors.Errorf("etcd has insecure TLS settings:\n%s", strings.Join(insecure, "\n"))
+	}
+	exec.Println("etcd uses TLS for client and peer communication")
+	return nil
+}
+
+// reportEtcdTLSViolations prints the insecure settings of the etcd on a node, if any, and returns them
+// prefixed by the node name
+func reportEtcdTLSViolations(n *status.Node, violations []string) []string {
+	if len(violations) == 0 {
+		n.Infof("etcd uses TLS for client and peer communication")
+		return nil
+	}
+	var insecure []string
+	for _, v := range violations {
+		n.Infof("insecure etcd setting: %s", v)
+		insecure = append(insecure, fmt.Sprintf("node %s: %s", n.Name(), v))
+	}
+	return insecure
+}
+
+// stackedEtcdArgs returns the args of the etcd container in the etcd static pod manifest on a control-plane node
+func stackedEtcdArgs(cp *status.Node) (map[string]string, error) {
+	data, err := cp.ReadFile(etcdManifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the etcd manifest on node %s; is the cluster initialized?", cp.Name())
+	}
+
+	var pod struct {
+		Spec struct {
+			Containers []struct {
+				Name    string   `json:"name"`
+				Command []string `json:"command"`
+				Args    []string `json:"args"`
+			} `json:"containers"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &pod); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the etcd manifest on node %s", cp.Name())
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "etcd" {
+			return parseEtcdArgs(append(container.Command, container.Args...)), nil
+		}
+	}
+	return nil, errors.Errorf("the etcd manifest on node %s does not have an etcd container", cp.Name())
+}
+
+// externalEtcdArgs returns the args of the etcd process of an external etcd member
+func externalEtcdArgs(n *status.Node) (map[string]string, error) {
+	lines, err := host.InspectContainer(n.Name(), "{{json .Config.Cmd}}")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect the external etcd member %s", n.Name())
+	}
+	var cmd []string
+	if err := json.Unmarshal([]byte(strings.Join(lines, "")), &cmd); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the command of the external etcd member %s", n.Name())
+	}
+	return parseEtcdArgs(cmd), nil
+}
+
+// parseEtcdArgs returns the etcd flags in the given command, both in the --flag=value and in the --flag value
+// forms; flags without a value are considered set to true
+func parseEtcdArgs(command []string) map[string]string {
+	args := map[string]string{}
+	for i := 0; i < len(command); i++ {
+		arg := command[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		arg = strings.TrimLeft(arg, "-")
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+			args[parts[0]] = parts[1]
+			continue
+		}
+		if i+1 < len(command) && !strings.HasPrefix(command[i+1], "-") {
+			args[arg] = command[i+1]
+			i++
+			continue
+		}
+		args[arg] = "true"
+	}
+	return args
+}
+
+// etcdTLSViolations returns the insecure etcd settings in the given flags: client and peer URLs must use https,
+// client certificate authentication must be enabled, serving certificates must be set, self-signed auto TLS must
+// not be used and, if expectedCA is set, the trusted CA files must be the expected CA
+func etcdTLSViolations(args map[string]string, expectedCA string) []string {
+	var violations []string
+
+	for _, flag := range []string{"listen-client-urls", "advertise-client-urls", "listen-peer-urls", "initial-advertise-peer-urls"} {
+		value, ok := args[flag]
+		if !ok {
+			violations = append(violations, fmt.Sprintf("--%s is not set", flag))
+			continue
+		}
+		for _, u := range strings.Split(value, ",") {
+			if !strings.HasPrefix(u, "https://") {
+				violations = append(violations, fmt.Sprintf("--%s %s does not use TLS", flag, u))
+			}
+		}
+	}
+
+	for _, flag := range []string{"client-cert-auth", "peer-client-cert-auth"} {
+		if args[flag] != "true" {
+			violations = append(violations, fmt.Sprintf("--%s is not enabled", flag))
+		}
+	}
+
+	for _, flag := range []string{"cert-file", "key-file", "peer-cert-file", "peer-key-file"} {
+		if args[flag] == "" {
+			violations = append(violations, fmt.Sprintf("--%s is not set", flag))
+		}
+	}
+
+	for _, flag := range []string{"trusted-ca-file", "peer-trusted-ca-file"} {
+		value := args[flag]
+		switch {
+		case value == "":
+			violations = append(violations, fmt.Sprintf("--%s is not set", flag))
+		case expectedCA != "" && value != expectedCA:
+			violations = append(violations, fmt.Sprintf("--%s is %s, expected %s", flag, value, expectedCA))
+		}
+	}
+
+	for _, flag := range []string{"auto-tls", "peer-auto-tls"} {
+		if args[flag] == "true" {
+			violations = append(violations, fmt.Sprintf("--%s is enabled, so etcd uses self-signed certificates", flag))
+		}
+	}
+
+	return violations
+}
+
+// etcdEndpointsTLSViolations returns the client endpoints of an external etcd not managed by kinder that don't use TLS
+func etcdEndpointsTLSViolations(endpoints []string) []string {
+	var violations []string
+	for _, e := range endpoints {
+		if !strings.HasPrefix(e, "https://") {
+			violations = append(violations, fmt.Sprintf("endpoint %s does not use TLS", e))
+		}
+	}
+	return violations
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+// kubeadmEtcdCommand is the etcd command generated by kubeadm for stacked etcd
+var kubeadmEtcdCommand = []string{
+	"etcd",
+	"--advertise-client-urls=https://172.18.0.3:2379",
+	"--cert-file=/etc/kubernetes/pki/etcd/server.crt",
+	"--client-cert-auth=true",
+	"--data-dir=/var/lib/etcd",
+	"--initial-advertise-peer-urls=https://172.18.0.3:2380",
+	"--key-file=/etc/kubernetes/pki/etcd/server.key",
+	"--listen-client-urls=https://127.0.0.1:2379,https://172.18.0.3:2379",
+	"--listen-metrics-urls=http://127.0.0.1:2381",
+	"--listen-peer-urls=https://172.18.0.3:2380",
+	"--peer-cert-file=/etc/kubernetes/pki/etcd/peer.crt",
+	"--peer-client-cert-auth=true",
+	"--peer-key-file=/etc/kubernetes/pki/etcd/peer.key",
+	"--peer-trusted-ca-file=/etc/kubernetes/pki/etcd/ca.crt",
+	"--trusted-ca-file=/etc/kubernetes/pki/etcd/ca.crt",
+}
+
+func TestParseEtcdArgs(t *testing.T) {
+	got := parseEtcdArgs([]string{"etcd", "--name", "kind-etcd", "--client-cert-auth", "--data-dir=/var/lib/etcd", "--debug"})
+	expected := map[string]string{
+		"name":             "kind-etcd",
+		"client-cert-auth": "true",
+		"data-dir":         "/var/lib/etcd",
+		"debug":            "true",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestEtcdTLSViolations(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(args map[string]string)
+		expectedCA string
+		expected   []string
+	}{
+		{
+			name:       "kubeadm stacked etcd",
+			expectedCA: etcdCAPath,
+		},
+		{
+			name: "plain http client URL",
+			mutate: func(args map[string]string) {
+				args["listen-client-urls"] = "https://127.0.0.1:2379,http://0.0.0.0:2379"
+			},
+			expectedCA: etcdCAPath,
+			expected:   []string{"--listen-client-urls http://0.0.0.0:2379 does not use TLS"},
+		},
+		{
+			name:     "client cert auth disabled",
+			mutate:   func(args map[string]string) { args["client-cert-auth"] = "false" },
+			expected: []string{"--client-cert-auth is not enabled"},
+		},
+		{
+			name:       "unexpected CA",
+			mutate:     func(args map[string]string) { args["peer-trusted-ca-file"] = "/etc/kubernetes/pki/ca.crt" },
+			expectedCA: etcdCAPath,
+			expected:   []string{"--peer-trusted-ca-file is /etc/kubernetes/pki/ca.crt, expected /etc/kubernetes/pki/etcd/ca.crt"},
+		},
+		{
+			name:     "peer auto TLS",
+			mutate:   func(args map[string]string) { args["peer-auto-tls"] = "true" },
+			expected: []string{"--peer-auto-tls is enabled, so etcd uses self-signed certificates"},
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			args := parseEtcdArgs(kubeadmEtcdCommand)
+			if rt.mutate != nil {
+				rt.mutate(args)
+			}
+			if got := etcdTLSViolations(args, rt.expectedCA); !reflect.DeepEqual(got, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEtcdTLSViolationsExternalEtcd(t *testing.T) {
+	// the external etcd created by kinder is insecure by design
+	args := parseEtcdArgs([]string{
+		"etcd",
+		"--name", "kind-etcd",
+		"--advertise-client-urls", "http://127.0.0.1:2379",
+		"--listen-client-urls", "http://0.0.0.0:2379",
+		"--listen-peer-urls", "http://0.0.0.0:2380",
+		"--initial-advertise-peer-urls", "http://kind-etcd:2380",
+	})
+	if got := etcdTLSViolations(args, ""); len(got) != 12 {
+		t.Errorf("expected 12 insecure settings, got %d: %v", len(got), got)
+	}
+}
+
+func TestEtcdEndpointsTLSViolations(t *testing.T) {
+	got := etcdEndpointsTLSViolations([]string{"https://10.0.0.10:2379", "http://10.0.0.11:2379"})
+	expected := []string{"endpoint http://10.0.0.11:2379 does not use TLS"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,494 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes/common"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+// EtcdAddMember adds a member to the external etcd cluster, and then reconfigures the
+// control-plane nodes for using all the external etcd members.
+func EtcdAddMember(c *status.Cluster) error {
+	etcd := c.ExternalEtcd()
+	if etcd == nil {
+		return errors.New("the cluster does not have an external etcd. Use kinder create cluster --external-etcd")
+	}
+
+	if err := checkEtcdCanScale(etcd); err != nil {
+		return err
+	}
+
+	// ensures all the existing members are connected to the external etcd network, so they
+	// can reach the new member by name
+	if err := ensureExternalEtcdNetwork(c); err != nil {
+		return err
+	}
+
+	// uses the same etcd image of the existing members
+	image, err := etcd.EtcdImage()
+	if err != nil {
+		return err
+	}
+
+	// uses the same etcd quota and auto-compaction settings of the existing members
+	etcdExtraArgs, err := c.BootstrapControlPlane().EtcdExtraArgs()
+	if err != nil {
+		return err
+	}
+
+	network, err := c.Network()
+	if err != nil {
+		return err
+	}
+
+	// uses the same container runtime of the cluster nodes
+	cri, err := c.BootstrapControlPlane().CRI()
+	if err != nil {
+		return err
+	}
+
+	createHelper, err := nodes.NewCreateHelper(cri, network)
+	if err != nil {
+		return err
+	}
+
+	// all the lookups are completed before adding the new member, because a member that is
+	// registered but never started might cause the etcd cluster to lose quorum
+	name := nextEtcdMemberName(c)
+	etcd.Infof("adding etcd member %s", name)
+
+	lines, err := etcdctl(etcd,
+		"member", "add", name, fmt.Sprintf("--peer-urls=%s", common.ExternalEtcdPeerURL(name)),
+	).RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to add etcd member %s: %s", name, strings.Join(lines, "\n"))
+	}
+
+	initialCluster, err := parseEtcdInitialCluster(lines)
+	if err == nil {
+		err = createHelper.CreateExternalEtcdMember(c.Name(), name, image, initialCluster, etcdExtraArgs)
+	}
+	if err != nil {
+		rollbackEtcdMember(etcd, name, lines)
+		return errors.Wrapf(err, "failed to create etcd member %s", name)
+	}
+
+	return updateExternalEtcdEndpoints(c)
+}
+
+// EtcdRemoveMember removes the last member from the external etcd cluster, and then reconfigures the
+// control-plane nodes for using the remaining external etcd members.
+// The removal is refused if it drops the etcd cluster below quorum, unless force is set.
+func EtcdRemoveMember(c *status.Cluster, force bool) error {
+	members := c.ExternalEtcds()
+	if len(members) == 0 {
+		return errors.New("the cluster does not have an external etcd. Use kinder create cluster --external-etcd")
+	}
+
+	target := members[len(members)-1]
+
+	healthy := 0
+	targetHealthy := false
+	for _, m := range members {
+		running, err := m.IsRunning()
+		if err != nil {
+			return err
+		}
+		if running {
+			healthy++
+			if m == target {
+				targetHealthy = true
+			}
+		}
+	}
+
+	if err := checkEtcdQuorum(len(members), healthy, targetHealthy); err != nil {
+		if !force {
+			return errors.Wrap(err, "refusing to remove the etcd member. Use --force to remove it anyway")
+		}
+		target.Infof("WARNING: %v", err)
+	}
+
+	// the member should be removed using another, running member
+	var etcd *status.Node
+	for _, m := range members {
+		if m == target {
+			continue
+		}
+		if running, _ := m.IsRunning(); running {
+			etcd = m
+			break
+		}
+	}
+	if etcd == nil {
+		return errors.New("there are no running etcd members for removing the member")
+	}
+
+	target.Infof("removing etcd member %s", target.Name())
+
+	lines, err := etcdctl(etcd, "member", "list").RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list etcd members: %s", strings.Join(lines, "\n"))
+	}
+
+	id, err := parseEtcdMemberID(lines, target.KubeNodeName())
+	if err != nil {
+		return err
+	}
+
+	if lines, err := etcdctl(etcd, "member", "remove", id).RunAndCapture(); err != nil {
+		return errors.Wrapf(err, "failed to remove etcd member %s: %s", target.Name(), strings.Join(lines, "\n"))
+	}
+
+	if err := exec.NewHostCmd(
+		"docker",
+		"rm",
+		"-f", // force the container to be deleted now
+		"-v", // delete volumes
+		target.Name(),
+	).Run(); err != nil {
+		return errors.Wrapf(err, "failed to delete node %s", target.Name())
+	}
+
+	// if only one member is left, the external etcd network is not required anymore
+	if len(members) == 2 {
+		removeExternalEtcdNetwork(c.Name(), etcd)
+	}
+
+	return updateExternalEtcdEndpoints(c)
+}
+
+// rollbackEtcdMember removes a member that was added to the etcd cluster but that failed to start.
+// Errors are not blocking, because the original error is more relevant for the user.
+func rollbackEtcdMember(etcd *status.Node, name string, addLines []string) {
+	id, err := parseEtcdAddedMemberID(addLines)
+	if err != nil {
+		etcd.Infof("WARNING: failed to remove etcd member %s. Please remove it manually: %v", name, err)
+		return
+	}
+	if lines, err := etcdctl(etcd, "member", "remove", id).RunAndCapture(); err != nil {
+		etcd.Infof("WARNING: failed to remove etcd member %s. Please remove it manually: %v\n%s", name, err, strings.Join(lines, "\n"))
+	}
+}
+
+// etcdctl returns a command executing etcdctl on an external etcd member
+func etcdctl(n *status.Node, args ...string) *exec.NodeCmd {
+	return n.Command("etcdctl", append([]string{"--endpoints=http://127.0.0.1:2379"}, args...)...).Silent()
+}
+
+// checkEtcdCanScale checks that the external etcd was created with peer URLs reachable from other members
+func checkEtcdCanScale(n *status.Node) error {
+	lines, err := etcdctl(n, "member", "list").RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list etcd members: %s", strings.Join(lines, "\n"))
+	}
+	for _, l := range lines {
+		if strings.Contains(l, "http://localhost:2380") {
+			return errors.New("the external etcd was created with peer URLs not reachable from other members. Please re-create the cluster")
+		}
+	}
+	return nil
+}
+
+// ensureExternalEtcdNetwork creates the docker network used for communication between external etcd members,
+// if it does not exist yet, and connects all the existing members to it
+func ensureExternalEtcdNetwork(c *status.Cluster) error {
+	network := common.ExternalEtcdNetwork(c.Name())
+	if err := exec.NewHostCmd("docker", "network", "inspect", network).Run(); err != nil {
+		if err := exec.NewHostCmd(
+			"docker", "network", "create",
+			"--label", fmt.Sprintf("%s=%s", constants.ClusterLabelKey, c.Name()),
+			network,
+		).Run(); err != nil {
+			return errors.Wrapf(err, "failed to create the %s network", network)
+		}
+	}
+
+	for _, m := range c.ExternalEtcds() {
+		lines, err := host.InspectContainer(m.Name(), fmt.Sprintf("{{if index .NetworkSettings.Networks %q}}true{{end}}", network))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get networks for node %s", m.Name())
+		}
+		if len(lines) == 1 && strings.Trim(lines[0], "'") == "true" {
+			continue
+		}
+		if err := exec.NewHostCmd("docker", "network", "connect", network, m.Name()).Run(); err != nil {
+			return errors.Wrapf(err, "failed to connect node %s to the %s network", m.Name(), network)
+		}
+	}
+	return nil
+}
+
+// removeExternalEtcdNetwork removes the docker network used for communication between external etcd members.
+// Errors are not blocking, because the remaining member can be still reached using the default network.
+func removeExternalEtcdNetwork(cluster string, remaining *status.Node) {
+	network := common.ExternalEtcdNetwork(cluster)
+	if err := exec.NewHostCmd("docker", "network", "disconnect", network, remaining.Name()).Run(); err != nil {
+		remaining.Infof("WARNING: failed to disconnect from the %s network: %v", network, err)
+		return
+	}
+	if err := exec.NewHostCmd("docker", "network", "rm", network).Run(); err != nil {
+		remaining.Infof("WARNING: failed to remove the %s network: %v", network, err)
+	}
+}
+
+// nextEtcdMemberName returns the name for a new external etcd member
+func nextEtcdMemberName(c *status.Cluster) string {
+	existing := map[string]bool{}
+	for _, m := range c.ExternalEtcds() {
+		existing[m.Name()] = true
+	}
+	for i := 2; ; i++ {
+		name := common.ExternalEtcdMemberName(c.Name(), i)
+		if !existing[name] {
+			return name
+		}
+	}
+}
+
+// checkEtcdQuorum checks that removing a member does not drop the etcd cluster below quorum
+func checkEtcdQuorum(members, healthy int, removedHealthy bool) error {
+	if members <= 1 {
+		return errors.New("the last etcd member can not be removed")
+	}
+
+	remaining := members - 1
+	remainingHealthy := healthy
+	if removedHealthy {
+		remainingHealthy--
+	}
+
+	quorum := remaining/2 + 1
+	if remainingHealthy < quorum {
+		return errors.Errorf("removing the member leaves %d healthy members out of %d, below the quorum of %d", remainingHealthy, remaining, quorum)
+	}
+	return nil
+}
+
+// parseEtcdInitialCluster parses the output of etcdctl member add, and returns the value
+// of ETCD_INITIAL_CLUSTER to be used for starting the new member
+func parseEtcdInitialCluster(lines []string) (string, error) {
+	for _, l := range lines {
+		if strings.HasPrefix(l, "ETCD_INITIAL_CLUSTER=") {
+			return strings.Trim(strings.TrimPrefix(l, "ETCD_INITIAL_CLUSTER="), `"`), nil
+		}
+	}
+	return "", errors.New("failed to parse ETCD_INITIAL_CLUSTER from etcdctl member add output")
+}
+
+// parseEtcdAddedMemberID parses the output of etcdctl member add, and returns the ID of the new member
+func parseEtcdAddedMemberID(lines []string) (string, error) {
+	for _, l := range lines {
+		// e.g. Member 8e9e05c52164694d added to cluster cdf818194e3a8c32
+		fields := strings.Fields(l)
+		if len(fields) >= 3 && fields[0] == "Member" && fields[2] == "added" {
+			return fields[1], nil
+		}
+	}
+	return "", errors.New("failed to parse the member ID from etcdctl member add output")
+}
+
+// parseEtcdMemberID parses the output of etcdctl member list, and returns the ID of the member
+// with the given name
+func parseEtcdMemberID(lines []string, name string) (string, error) {
+	for _, l := range lines {
+		fields := strings.Split(l, ",")
+		if len(fields) < 3 {
+			continue
+		}
+		if strings.TrimSpace(fields[2]) == name {
+			return strings.TrimSpace(fields[0]), nil
+		}
+	}
+	return "", errors.Errorf("failed to find etcd member %s", name)
+}
+
+// externalEtcdIPs returns the IPs of the external etcd members
+func externalEtcdIPs(c *status.Cluster) ([]string, error) {
+	var ips []string
+	for _, m := range c.ExternalEtcds() {
+		ipv4, ipv6, err := m.IP()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get IP for node: %s", m.Name())
+		}
+
+		// configure the right protocol addresses
+		if c.Settings != nil && c.Settings.IPFamily == status.IPv6Family {
+			ipv4 = ipv6
+		}
+		ips = append(ips, ipv4)
+	}
+	return ips, nil
+}
+
+// updateExternalEtcdEndpoints reconfigures the control-plane nodes already initialized for using
+// the current list of external etcd members, by updating the kube-apiserver manifests and
+// the ClusterConfiguration stored in the kubeadm-config ConfigMap.
+func updateExternalEtcdEndpoints(c *status.Cluster) error {
+	// reads the cluster nodes again, so it includes the current list of external etcd members
+	if err := c.Refresh(); err != nil {
+		return err
+	}
+
+	ips, err := externalEtcdIPs(c)
+	if err != nil {
+		return err
+	}
+
+	var endpoints []string
+	for _, ip := range ips {
+		endpoints = append(endpoints, kubeadm.ExternalEtcdEndpoint(ip))
+	}
+
+	initialized := false
+	for _, cp := range c.ControlPlanes() {
+		// skip nodes where kubeadm init/join was not executed yet; the kubeadm config
+		// generated for those nodes will include the new endpoints.
+		if err := cp.Command("test", "-f", "/etc/kubernetes/manifests/kube-apiserver.yaml").Silent().Run(); err != nil {
+			continue
+		}
+		initialized = true
+
+		cp.Infof("updating etcd endpoints to %s", strings.Join(endpoints, ","))
+		if err := cp.Command(
+			"sed", "-i", fmt.Sprintf("s#--etcd-servers=.*#--etcd-servers=%s#", strings.Join(endpoints, ",")),
+			"/etc/kubernetes/manifests/kube-apiserver.yaml",
+		).Silent().Run(); err != nil {
+			return errors.Wrapf(err, "failed to update etcd endpoints on node %s", cp.Name())
+		}
+	}
+
+	if !initialized {
+		return nil
+	}
+
+	return updateKubeadmConfigEtcdEndpoints(c.BootstrapControlPlane(), endpoints)
+}
+
+// updateKubeadmConfigEtcdEndpoints updates the external etcd endpoints in the ClusterConfiguration
+// stored in the kubeadm-config ConfigMap, so they are used by following kubeadm join/upgrade
+func updateKubeadmConfigEtcdEndpoints(cp1 *status.Node, endpoints []string) error {
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+		"get", "configmap", "kubeadm-config", "-o=jsonpath={.data.ClusterConfiguration}",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the kubeadm-config ConfigMap")
+	}
+
+	clusterConfiguration, err := setEtcdEndpoints(strings.Join(lines, "\n"), endpoints)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]string{
+			"ClusterConfiguration": clusterConfiguration,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the kubeadm-config ConfigMap patch")
+	}
+
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+		"patch", "configmap", "kubeadm-config", "--type=merge", fmt.Sprintf("--patch=%s", patch),
+	).Silent().Run(); err != nil {
+		return errors.Wrap(err, "failed to update the kubeadm-config ConfigMap")
+	}
+	return nil
+}
+
+// setEtcdEndpoints sets the external etcd endpoints in a ClusterConfiguration
+func setEtcdEndpoints(clusterConfiguration string, endpoints []string) (string, error) {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal([]byte(clusterConfiguration), &config); err != nil {
+		return "", errors.Wrap(err, "failed to decode the ClusterConfiguration")
+	}
+
+	etcd, _ := config["etcd"].(map[string]interface{})
+	external, _ := etcd["external"].(map[string]interface{})
+	if external == nil {
+		return "", errors.New("the ClusterConfiguration does not use an external etcd")
+	}
+	external["endpoints"] = endpoints
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode the ClusterConfiguration")
+	}
+	return string(out), nil
+}
+
+// externalEtcdHealthTimeout is the timeout for each request checking the health of an external etcd endpoint
+const externalEtcdHealthTimeout = 10
+
+// checkExternalEtcdEndpoints verifies that the external etcd not managed by kinder, if any, is reachable and healthy
+// from a control-plane node using the client certificates copied at create time, so kubeadm init doesn't fail
+// later waiting for the API server
+func checkExternalEtcdEndpoints(c *status.Cluster, n *status.Node) error {
+	for _, e := range c.Settings.ExternalEtcdEndpoints {
+		args := []string{"-sS", fmt.Sprintf("--max-time=%d", externalEtcdHealthTimeout)}
+		if strings.HasPrefix(e, "https://") {
+			args = append(args,
+				fmt.Sprintf("--cacert=%s", kubeadm.ExternalEtcdCAFile),
+				fmt.Sprintf("--cert=%s", kubeadm.ExternalEtcdCertFile),
+				fmt.Sprintf("--key=%s", kubeadm.ExternalEtcdKeyFile),
+			)
+		}
+		lines, err := n.Command("curl", append(args, strings.TrimSuffix(e, "/")+"/health")...).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "failed to connect to the external etcd endpoint %s from node %s. Check the endpoint is reachable from the nodes, and that the client certificates are signed by the etcd CA", e, n.Name())
+		}
+		if err := parseEtcdHealth(strings.Join(lines, "\n")); err != nil {
+			return errors.Wrapf(err, "the external etcd endpoint %s is not healthy", e)
+		}
+		n.Infof("external etcd endpoint %s is healthy", e)
+	}
+	return nil
+}
+
+// parseEtcdHealth returns an error if the response of the etcd /health endpoint doesn't report a healthy member
+func parseEtcdHealth(response string) error {
+	var health struct {
+		Health string `json:"health"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(response), &health); err != nil {
+		return errors.Wrapf(err, "invalid health response %q", response)
+	}
+	if health.Health != "true" {
+		if health.Reason != "" {
+			return errors.Errorf("health %s: %s", health.Health, health.Reason)
+		}
+		return errors.Errorf("health %s", health.Health)
+	}
+	return nil
+}
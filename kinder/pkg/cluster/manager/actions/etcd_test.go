@@ -0,0 +1,253 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckEtcdQuorum(t *testing.T) {
+	tests := []struct {
+		name           string
+		members        int
+		healthy        int
+		removedHealthy bool
+		expectedError  bool
+	}{
+		{
+			name:          "invalid: last member",
+			members:       1,
+			healthy:       1,
+			expectedError: true,
+		},
+		{
+			name:           "valid: 3 healthy members",
+			members:        3,
+			healthy:        3,
+			removedHealthy: true,
+		},
+		{
+			name:           "valid: 2 healthy members",
+			members:        2,
+			healthy:        2,
+			removedHealthy: true,
+		},
+		{
+			name:           "valid: removing the unhealthy member",
+			members:        3,
+			healthy:        2,
+			removedHealthy: false,
+		},
+		{
+			name:           "invalid: removing an healthy member when another is unhealthy",
+			members:        3,
+			healthy:        2,
+			removedHealthy: true,
+			expectedError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkEtcdQuorum(test.members, test.healthy, test.removedHealthy)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestParseEtcdInitialCluster(t *testing.T) {
+	tests := []struct {
+		name           string
+		inputLines     []string
+		expectedResult string
+		expectedError  bool
+	}{
+		{
+			name: "valid",
+			inputLines: []string{
+				"Member 8e9e05c52164694d added to cluster cdf818194e3a8c32",
+				"",
+				`ETCD_NAME="kind-etcd2"`,
+				`ETCD_INITIAL_CLUSTER="kind-etcd=http://kind-etcd:2380,kind-etcd2=http://kind-etcd2:2380"`,
+				`ETCD_INITIAL_ADVERTISE_PEER_URLS="http://kind-etcd2:2380"`,
+				`ETCD_INITIAL_CLUSTER_STATE="existing"`,
+			},
+			expectedResult: "kind-etcd=http://kind-etcd:2380,kind-etcd2=http://kind-etcd2:2380",
+		},
+		{
+			name:          "invalid: missing ETCD_INITIAL_CLUSTER",
+			inputLines:    []string{`ETCD_NAME="kind-etcd2"`},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := parseEtcdInitialCluster(test.inputLines)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if result != test.expectedResult {
+				t.Fatalf("expected result: %q, found %q", test.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestParseEtcdMemberID(t *testing.T) {
+	lines := []string{
+		"8e9e05c52164694d, started, kind-etcd, http://kind-etcd:2380, http://127.0.0.1:2379, false",
+		"91bc3c398fb3c146, started, kind-etcd2, http://kind-etcd2:2380, http://127.0.0.1:2379, false",
+	}
+
+	tests := []struct {
+		name          string
+		member        string
+		expectedID    string
+		expectedError bool
+	}{
+		{
+			name:       "valid: first member",
+			member:     "kind-etcd",
+			expectedID: "8e9e05c52164694d",
+		},
+		{
+			name:       "valid: second member",
+			member:     "kind-etcd2",
+			expectedID: "91bc3c398fb3c146",
+		},
+		{
+			name:          "invalid: unknown member",
+			member:        "kind-etcd3",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := parseEtcdMemberID(lines, test.member)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if id != test.expectedID {
+				t.Fatalf("expected ID: %q, found %q", test.expectedID, id)
+			}
+		})
+	}
+}
+
+func TestParseEtcdAddedMemberID(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputLines    []string
+		expectedID    string
+		expectedError bool
+	}{
+		{
+			name: "valid",
+			inputLines: []string{
+				"Member 8e9e05c52164694d added to cluster cdf818194e3a8c32",
+				"",
+				`ETCD_NAME="kind-etcd2"`,
+			},
+			expectedID: "8e9e05c52164694d",
+		},
+		{
+			name:          "invalid: missing member line",
+			inputLines:    []string{`ETCD_NAME="kind-etcd2"`},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := parseEtcdAddedMemberID(test.inputLines)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if id != test.expectedID {
+				t.Fatalf("expected ID: %q, found %q", test.expectedID, id)
+			}
+		})
+	}
+}
+
+func TestSetEtcdEndpoints(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedError bool
+	}{
+		{
+			name: "valid",
+			input: "apiVersion: kubeadm.k8s.io/v1beta3\n" +
+				"kind: ClusterConfiguration\n" +
+				"etcd:\n" +
+				"  external:\n" +
+				"    endpoints:\n" +
+				"    - http://172.17.0.2:2379\n",
+		},
+		{
+			name: "invalid: local etcd",
+			input: "apiVersion: kubeadm.k8s.io/v1beta3\n" +
+				"kind: ClusterConfiguration\n" +
+				"etcd:\n" +
+				"  local:\n" +
+				"    dataDir: /var/lib/etcd\n",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := setEtcdEndpoints(test.input, []string{"http://172.17.0.2:2379", "http://172.17.0.3:2379"})
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !strings.Contains(out, "- http://172.17.0.3:2379") || !strings.Contains(out, "kind: ClusterConfiguration") {
+				t.Fatalf("unexpected ClusterConfiguration:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestParseEtcdHealth(t *testing.T) {
+	tests := []struct {
+		response      string
+		expectedError bool
+	}{
+		{response: `{"health":"true","reason":""}`},
+		{response: `{"health":"false","reason":"RAFT NO LEADER"}`, expectedError: true},
+		{response: `{"health":"false"}`, expectedError: true},
+		{response: `404 page not found`, expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.response, func(t *testing.T) {
+			err := parseEtcdHealth(test.response)
+			if (err != nil) != test.expectedError {
+				t.Errorf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
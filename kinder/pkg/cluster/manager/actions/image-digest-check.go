@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// digestReport defines the digests of images, by normalized image name and node name;
+// an empty digest means the image has no registry digest, e.g. because it was loaded from a tarball
+type digestReport struct {
+	nodes   []string
+	digests map[string]map[string]string
+	// explicit is true if the images to check are given; in this case, images missing on a node are reported
+	explicit bool
+}
+
+// newDigestReport collects the digests of the given images from the images available on each node,
+// or the digests of all the images if no image is given
+func newDigestReport(nodeImages []status.NodeImages, images []string) digestReport {
+	r := digestReport{
+		digests:  map[string]map[string]string{},
+		explicit: len(images) > 0,
+	}
+	for _, image := range images {
+		r.digests[normalizeImageRef(image)] = map[string]string{}
+	}
+
+	for _, ni := range nodeImages {
+		r.nodes = append(r.nodes, ni.Node)
+		for _, image := range ni.Images {
+			// skip dangling images, e.g. <none>:<none> in docker
+			if strings.Contains(image.Name, "<none>") {
+				continue
+			}
+			name := normalizeImageRef(image.Name)
+			if _, ok := r.digests[name]; !ok {
+				if r.explicit {
+					continue
+				}
+				r.digests[name] = map[string]string{}
+			}
+			r.digests[name][ni.Node] = image.Digest
+		}
+	}
+	return r
+}
+
+// mismatched returns the images with different digests across nodes, sorted by name;
+// with explicit images, images missing on some nodes are considered mismatched as well
+func (r digestReport) mismatched() []string {
+	var res []string
+	for image, byNode := range r.digests {
+		if r.explicit && len(byNode) != len(r.nodes) {
+			res = append(res, image)
+			continue
+		}
+		digests := map[string]bool{}
+		for _, d := range byNode {
+			digests[d] = true
+		}
+		if len(digests) > 1 {
+			res = append(res, image)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// String returns the report sorted by image name; for images with different digests across nodes,
+// the digest on each node is printed
+func (r digestReport) String() string {
+	mismatched := map[string]bool{}
+	for _, image := range r.mismatched() {
+		mismatched[image] = true
+	}
+
+	images := make([]string, 0, len(r.digests))
+	for image := range r.digests {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	var b strings.Builder
+	for _, image := range images {
+		byNode := r.digests[image]
+		if !mismatched[image] {
+			for _, d := range byNode {
+				fmt.Fprintf(&b, "%s: %s\n", image, digestOrNone(d))
+				break
+			}
+			if len(byNode) == 0 {
+				fmt.Fprintf(&b, "%s: not available on any node\n", image)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%s: digests differ across nodes\n", image)
+		for _, n := range r.nodes {
+			d, ok := byNode[n]
+			if !ok {
+				if r.explicit {
+					fmt.Fprintf(&b, "  %s: missing\n", n)
+				}
+				continue
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", n, digestOrNone(d))
+		}
+	}
+	return b.String()
+}
+
+func digestOrNone(digest string) string {
+	if digest == "" {
+		return "<none>"
+	}
+	return digest
+}
+
+// normalizeImageRef returns the fully qualified form of an image reference, e.g. docker.io/library/nginx:latest
+// for nginx, so references listed by different container runtimes can be compared
+func normalizeImageRef(ref string) string {
+	if !strings.Contains(ref, "@") {
+		if i := strings.LastIndex(ref, ":"); i < 0 || strings.Contains(ref[i:], "/") {
+			ref += ":latest"
+		}
+	}
+
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io/library/" + ref
+	}
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "docker.io/" + ref
+	}
+	return ref
+}
+
+// ImageDigestCheck action verifies that the given images have the same digest on all the K8s nodes eligible
+// for actions, or that all the images have the same digest if no image is given, e.g. for detecting tags that
+// drifted between pulls; nodes with different digests are reported, and the action fails.
+func ImageDigestCheck(c *status.Cluster, images []string) error {
+	nodeList := c.K8sNodes().EligibleForActions()
+	log.Infof("Checking image digests on %d nodes", len(nodeList))
+
+	var nodeImages []status.NodeImages
+	for _, n := range nodeList {
+		list, err := n.Images()
+		if err != nil {
+			return err
+		}
+		nodeImages = append(nodeImages, status.NodeImages{Node: n.Name(), Images: list})
+	}
+
+	report := newDigestReport(nodeImages, images)
+	exec.Printf("\nImage digest report:\n%s", report)
+	if mismatched := report.mismatched(); len(mismatched) > 0 {
+		return errors.Errorf("%d images have different digests across nodes: %s", len(mismatched), strings.Join(mismatched, ", "))
+	}
+	return nil
+}
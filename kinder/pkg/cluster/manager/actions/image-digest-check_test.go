@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+func TestNormalizeImageRef(t *testing.T) {
+	cases := []struct {
+		ref      string
+		expected string
+	}{
+		{ref: "nginx", expected: "docker.io/library/nginx:latest"},
+		{ref: "nginx:1.25", expected: "docker.io/library/nginx:1.25"},
+		{ref: "bitnami/nginx:1.25", expected: "docker.io/bitnami/nginx:1.25"},
+		{ref: "registry.k8s.io/pause:3.9", expected: "registry.k8s.io/pause:3.9"},
+		{ref: "localhost:5000/pause", expected: "localhost:5000/pause:latest"},
+		{ref: "localhost/pause:3.9", expected: "localhost/pause:3.9"},
+		{ref: "nginx@sha256:abc", expected: "docker.io/library/nginx@sha256:abc"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.ref, func(t *testing.T) {
+			if got := normalizeImageRef(c.ref); got != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestDigestReportMismatched(t *testing.T) {
+	nodeImages := []status.NodeImages{
+		{
+			Node: "kind-control-plane",
+			Images: []status.Image{
+				{Name: "docker.io/library/nginx:latest", Digest: "sha256:aaa"},
+				{Name: "registry.k8s.io/pause:3.9", Digest: "sha256:ppp"},
+				{Name: "registry.k8s.io/etcd:3.5.12-0", Digest: "sha256:eee"},
+			},
+		},
+		{
+			Node: "kind-worker",
+			Images: []status.Image{
+				{Name: "nginx:latest", Digest: "sha256:bbb"},
+				{Name: "registry.k8s.io/pause:3.9", Digest: "sha256:ppp"},
+				{Name: "<none>:<none>", Digest: ""},
+			},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		images   []string
+		expected []string
+	}{
+		{
+			name:     "all images, compared only where available",
+			images:   nil,
+			expected: []string{"docker.io/library/nginx:latest"},
+		},
+		{
+			name:     "explicit image with same digest",
+			images:   []string{"registry.k8s.io/pause:3.9"},
+			expected: nil,
+		},
+		{
+			name:     "explicit image with different digest",
+			images:   []string{"nginx"},
+			expected: []string{"docker.io/library/nginx:latest"},
+		},
+		{
+			name:     "explicit image missing on a node",
+			images:   []string{"registry.k8s.io/etcd:3.5.12-0"},
+			expected: []string{"registry.k8s.io/etcd:3.5.12-0"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := newDigestReport(nodeImages, c.images)
+			if got := r.mismatched(); !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, got)
+			}
+		})
+	}
+}
@@ -18,19 +18,168 @@ package actions
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
-// checkImagesForVersion pre-loaded images available on the node (this will report missing images, if any)
+// checkImagesForVersion pre-loaded images available on the node (this will report missing images, if any, and
+// images pre-loaded for an architecture different from the node architecture, if any)
 func checkImagesForVersion(n *status.Node, version string) error {
 	n.Infof("Checking pre-loaded images")
 
+	missing, wrongArch, err := checkNodeImagesForVersion(n, version)
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		exec.Printf("Some of the required images are not pre-loaded into the container runtime:\n%s\n", strings.Join(missing, "\n"))
+	}
+	if len(wrongArch) > 0 {
+		// the kubelet uses images already available, so images for the wrong architecture are not pulled again
+		// and fail at runtime; they must be pre-loaded again for the node architecture
+		arch, _ := n.Arch()
+		exec.Printf("Some of the required images are pre-loaded into the container runtime for an architecture different from the node architecture %s; pre-load them again for %s:\n%s\n", arch, arch, strings.Join(wrongArch, "\n"))
+	}
+	if len(missing) == 0 && len(wrongArch) == 0 {
+		exec.Println("All the requested images are already pre-loaded into the container runtime")
+	}
+	return nil
+}
+
+// imagesReport defines the required images not pre-loaded into the container runtime, or pre-loaded for the
+// wrong architecture, by node name
+type imagesReport map[string][]string
+
+// String returns the report sorted by node name
+func (r imagesReport) String() string {
+	names := make([]string, 0, len(r))
+	for n := range r {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, n := range names {
+		fmt.Fprintf(&b, "%s:\n", n)
+		for _, i := range r[n] {
+			fmt.Fprintf(&b, "  %s\n", i)
+		}
+	}
+	return b.String()
+}
+
+// checkPreloadedImages checks the images pre-loaded on all the nodes concurrently, each node for the
+// Kubernetes version installed on it, and prints a consolidated report of the missing images, if any, and
+// of the images pre-loaded for an architecture different from the node architecture, if any; the report of
+// missing images is returned. An error is returned if the check fails on any node, so callers can stop before
+// touching the nodes.
+func checkPreloadedImages(nodes status.NodeList) (imagesReport, error) {
+	if len(nodes) == 0 {
+		return imagesReport{}, nil
+	}
+	log.Infof("Checking pre-loaded images on %d nodes", len(nodes))
+
+	missing := make([][]string, len(nodes))
+	wrongArch := make([][]string, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *status.Node) {
+			defer wg.Done()
+			version, err := n.KubeVersion()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			missing[i], wrongArch[i], errs[i] = checkNodeImagesForVersion(n, version)
+		}(i, n)
+	}
+	wg.Wait()
+
+	report, archReport := imagesReport{}, imagesReport{}
+	for i, n := range nodes {
+		if errs[i] != nil {
+			return nil, errors.Wrapf(errs[i], "failed to check pre-loaded images on node %s", n.Name())
+		}
+		if len(missing[i]) > 0 {
+			report[n.Name()] = missing[i]
+		}
+		if len(wrongArch[i]) > 0 {
+			arch, _ := n.Arch()
+			archReport[fmt.Sprintf("%s (%s)", n.Name(), arch)] = wrongArch[i]
+		}
+	}
+
+	if len(report) > 0 {
+		exec.Printf("Some of the required images are not pre-loaded into the container runtime:\n%s", report)
+	}
+	if len(archReport) > 0 {
+		exec.Printf("Some of the required images are pre-loaded into the container runtime for an architecture different from the node architecture; pre-load them again for the node architecture:\n%s", archReport)
+	}
+	if len(report) == 0 && len(archReport) == 0 {
+		exec.Println("All the requested images are already pre-loaded into the container runtime")
+	}
+	return report, nil
+}
+
+// checkNodeImagesForVersion returns the images required by kubeadm for a Kubernetes version, and by the container
+// runtime, that are not pre-loaded into the container runtime of a node, and the ones pre-loaded for an architecture
+// different from the node architecture, with their architecture
+func checkNodeImagesForVersion(n *status.Node, version string) ([]string, []string, error) {
+	expected, err := expectedImagesForVersion(n, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// gets the list of images already pre-loaded in the node
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	actionHelper, err := nodes.NewActionHelper(nodeCRI)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err := actionHelper.GetImages(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("List of images already pre-loaded in the node %s\n", current)
+
+	missing := missingImages(expected, current)
+
+	// checks the architecture of the pre-loaded images, because on mixed-arch clusters an image can be
+	// pre-loaded for the wrong architecture, and the kubelet fails to run it
+	nodeArch, err := n.Arch()
+	if err != nil {
+		return nil, nil, err
+	}
+	imageArchs := map[string]string{}
+	for _, image := range presentImages(expected, missing) {
+		if imageArchs[image], err = actionHelper.GetImageArch(n, image); err != nil {
+			return nil, nil, err
+		}
+	}
+	log.Debugf("Architecture of the images pre-loaded in the node %s: %v\n", n.Name(), imageArchs)
+
+	return missing, wrongArchImages(imageArchs, nodeArch), nil
+}
+
+// expectedImagesForVersion returns the images required by kubeadm for a Kubernetes version, and by the container
+// runtime, taking into account the images configured at create time
+func expectedImagesForVersion(n *status.Node, version string) ([]string, error) {
 	imageListCmd := fmt.Sprintf("kubeadm config images list --kubernetes-version=%s 2>/dev/null", version)
 
 	// gets the list of images kubeadm is going to use
@@ -38,47 +187,120 @@ func checkImagesForVersion(n *status.Node, version string) error {
 		"bash", "-c", imageListCmd,
 	).Silent().RunAndCapture()
 	if err != nil {
-		return errors.Wrapf(err, "failed to read expected images for version %s from %s", version, n.Name())
+		return nil, errors.Wrapf(err, "failed to read expected images for version %s from %s", version, n.Name())
 	}
 	log.Debugf("List of images kubeadm is going to use %s\n", expected)
 
-	// gets the list of images already pre-loaded in the node
-	nodeCRI, err := n.CRI()
+	// the sandbox image configured at create time is used by the container runtime in place of
+	// the pause image required by kubeadm, so it is expected as well
+	sandboxImage, err := n.SandboxImage()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	expected = withSandboxImage(expected, sandboxImage)
 
-	actionHelper, err := nodes.NewActionHelper(nodeCRI)
+	// the CoreDNS image configured at create time is used in place of the default CoreDNS image
+	coreDNSImage, err := n.CoreDNSImage()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	expected = withCoreDNSImage(expected, coreDNSImage)
 
-	current, err := actionHelper.GetImages(n)
+	// the etcd version configured at create time is used in place of the default etcd version
+	etcdVersion, err := n.EtcdVersion()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	log.Debugf("List of images already pre-loaded in the node %s\n", current)
+	expected = withEtcdVersion(expected, etcdVersion)
 
-	// Compare expected and current image and report result
-	var currentMap = map[string]string{}
+	return expected, nil
+}
+
+// missingImages returns the expected images not included in the current images
+func missingImages(expected, current []string) []string {
+	var currentMap = map[string]bool{}
 	for _, c := range current {
-		currentMap[c] = c
+		currentMap[c] = true
 	}
 
 	var missing = []string{}
 	for _, e := range expected {
-		if _, ok := currentMap[e]; ok {
-			continue
+		if !currentMap[e] {
+			missing = append(missing, e)
 		}
+	}
+	return missing
+}
 
-		missing = append(missing, e)
+// presentImages returns the expected images not included in the missing images
+func presentImages(expected, missing []string) []string {
+	var missingMap = map[string]bool{}
+	for _, m := range missing {
+		missingMap[m] = true
 	}
 
-	if len(missing) > 0 {
-		fmt.Printf("Some of the required images are not pre-loaded into the container runtime:\n%s\n", strings.Join(missing, "\n"))
-		return nil
+	var present []string
+	for _, e := range expected {
+		if !missingMap[e] {
+			present = append(present, e)
+		}
 	}
+	return present
+}
 
-	fmt.Println("All the requested images are already pre-loaded into the container runtime")
-	return nil
+// wrongArchImages returns the images with an architecture different from the node architecture, sorted and
+// with their architecture; images with an unknown architecture are ignored
+func wrongArchImages(imageArchs map[string]string, nodeArch string) []string {
+	var wrongArch []string
+	for image, arch := range imageArchs {
+		if arch != "" && nodeArch != "" && arch != nodeArch {
+			wrongArch = append(wrongArch, fmt.Sprintf("%s (%s)", image, arch))
+		}
+	}
+	sort.Strings(wrongArch)
+	return wrongArch
+}
+
+// withSandboxImage replaces the default sandbox (pause) image in the list of expected images with the given image,
+// if any; the kubeadm pause image is not required when the container runtime uses a different sandbox image
+func withSandboxImage(expected []string, sandboxImage string) []string {
+	if sandboxImage == "" {
+		return expected
+	}
+	images := make([]string, 0, len(expected)+1)
+	for _, e := range expected {
+		if !strings.Contains(e, "/pause:") && e != sandboxImage {
+			images = append(images, e)
+		}
+	}
+	return append(images, sandboxImage)
+}
+
+// withCoreDNSImage replaces the default CoreDNS image in the list of expected images with the given image, if any
+func withCoreDNSImage(expected []string, coreDNSImage string) []string {
+	if coreDNSImage == "" {
+		return expected
+	}
+	images := make([]string, 0, len(expected)+1)
+	for _, e := range expected {
+		if !strings.Contains(e, "/coredns:") {
+			images = append(images, e)
+		}
+	}
+	return append(images, coreDNSImage)
+}
+
+// withEtcdVersion replaces the tag of the default etcd image in the list of expected images with the given version, if any
+func withEtcdVersion(expected []string, etcdVersion string) []string {
+	if etcdVersion == "" {
+		return expected
+	}
+	images := make([]string, 0, len(expected))
+	for _, e := range expected {
+		if i := strings.LastIndex(e, "/etcd:"); i >= 0 {
+			e = fmt.Sprintf("%s/etcd:%s", e[:i], etcdVersion)
+		}
+		images = append(images, e)
+	}
+	return images
 }
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingImages(t *testing.T) {
+	expected := []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/pause:3.9", "registry.k8s.io/coredns/coredns:v1.11.1"}
+	current := []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/coredns/coredns:v1.11.1"}
+
+	missing := missingImages(expected, current)
+	if !reflect.DeepEqual(missing, []string{"registry.k8s.io/pause:3.9"}) {
+		t.Errorf("unexpected missing images: %v", missing)
+	}
+	if missing := missingImages(current, expected); len(missing) != 0 {
+		t.Errorf("expected no missing images, got %v", missing)
+	}
+}
+
+func TestImagesReportString(t *testing.T) {
+	report := imagesReport{
+		"kind-worker-1":        {"registry.k8s.io/kube-proxy:v1.30.0"},
+		"kind-control-plane-2": {"registry.k8s.io/etcd:3.5.12-0", "registry.k8s.io/pause:3.9"},
+	}
+	expected := "kind-control-plane-2:\n  registry.k8s.io/etcd:3.5.12-0\n  registry.k8s.io/pause:3.9\nkind-worker-1:\n  registry.k8s.io/kube-proxy:v1.30.0\n"
+	if report.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, report.String())
+	}
+}
+
+func TestWithCoreDNSImage(t *testing.T) {
+	expected := []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/coredns/coredns:v1.11.1", "registry.k8s.io/pause:3.9"}
+
+	images := withCoreDNSImage(expected, "localhost:5000/coredns:1.11.3")
+	if !reflect.DeepEqual(images, []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/pause:3.9", "localhost:5000/coredns:1.11.3"}) {
+		t.Errorf("unexpected images: %v", images)
+	}
+	if images := withCoreDNSImage(expected, ""); !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images to be unchanged, got %v", images)
+	}
+}
+
+func TestWithSandboxImage(t *testing.T) {
+	expected := []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/pause:3.9"}
+
+	images := withSandboxImage(expected, "localhost:5000/pause:3.10")
+	if !reflect.DeepEqual(images, []string{"registry.k8s.io/kube-proxy:v1.30.0", "localhost:5000/pause:3.10"}) {
+		t.Errorf("unexpected images: %v", images)
+	}
+	if images := withSandboxImage(expected, "registry.k8s.io/pause:3.9"); !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images to be unchanged, got %v", images)
+	}
+	if images := withSandboxImage(expected, ""); !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images to be unchanged, got %v", images)
+	}
+}
+
+func TestWithEtcdVersion(t *testing.T) {
+	expected := []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/etcd:3.5.12-0"}
+
+	images := withEtcdVersion(expected, "3.5.15-0")
+	if !reflect.DeepEqual(images, []string{"registry.k8s.io/kube-proxy:v1.30.0", "registry.k8s.io/etcd:3.5.15-0"}) {
+		t.Errorf("unexpected images: %v", images)
+	}
+	if images := withEtcdVersion(expected, ""); !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images to be unchanged, got %v", images)
+	}
+}
+
+func TestWrongArchImages(t *testing.T) {
+	imageArchs := map[string]string{
+		"registry.k8s.io/pause:3.9":          "amd64",
+		"registry.k8s.io/kube-proxy:v1.30.0": "arm64",
+		"registry.k8s.io/etcd:3.5.12-0":      "s390x",
+		"registry.k8s.io/coredns:v1.11.1":    "",
+	}
+
+	wrongArch := wrongArchImages(imageArchs, "amd64")
+	if !reflect.DeepEqual(wrongArch, []string{"registry.k8s.io/etcd:3.5.12-0 (s390x)", "registry.k8s.io/kube-proxy:v1.30.0 (arm64)"}) {
+		t.Errorf("unexpected images for the wrong architecture: %v", wrongArch)
+	}
+	if wrongArch := wrongArchImages(imageArchs, ""); len(wrongArch) != 0 {
+		t.Errorf("expected no images for the wrong architecture with an unknown node architecture, got %v", wrongArch)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// defaultKubeProxyMode is the mode used by kube-proxy on Linux when the mode is not set
+const defaultKubeProxyMode = "iptables"
+
+// kubeProxyArtifacts defines the artifacts created by kube-proxy on a node, that depend on the proxy mode
+type kubeProxyArtifacts struct {
+	// ipvsInterface is true if the kube-ipvs0 dummy interface exists, that is created in ipvs mode
+	ipvsInterface bool
+	// nftTable is true if the kube-proxy nftables table exists, that is created in nftables mode
+	nftTable bool
+	// iptablesChain is true if the KUBE-SERVICES iptables chain exists, that is created in iptables mode
+	// (and in ipvs mode too)
+	iptablesChain bool
+}
+
+// mode returns the proxy mode matching the artifacts, or an empty string if there are no artifacts
+func (a kubeProxyArtifacts) mode() string {
+	switch {
+	case a.ipvsInterface:
+		return "ipvs"
+	case a.nftTable:
+		return "nftables"
+	case a.iptablesChain:
+		return "iptables"
+	}
+	return ""
+}
+
+// kubeProxyModeMismatch returns a description of the mismatch between the expected mode and the mode
+// reported by kube-proxy or detected from the artifacts on the node, or an empty string if they match;
+// an empty reported mode means kube-proxy didn't report its mode
+func kubeProxyModeMismatch(expected, reported string, artifacts kubeProxyArtifacts) string {
+	if reported != "" && reported != expected {
+		return fmt.Sprintf("kube-proxy is running in %s mode", reported)
+	}
+	detected := artifacts.mode()
+	if detected == "" {
+		return "no kube-proxy rules found"
+	}
+	if detected != expected {
+		return fmt.Sprintf("found %s rules", detected)
+	}
+	return ""
+}
+
+// KubeProxyModeCheck verifies that kube-proxy is running in the mode configured at create time on each K8s node
+// eligible for actions, by checking both the mode reported by kube-proxy and the rules on the node, e.g. ipvs
+// interfaces, nftables tables or iptables chains; this catches kube-proxy silently falling back to another
+// mode, e.g. because a kernel module is missing.
+func KubeProxyModeCheck(c *status.Cluster) error {
+	if c.Settings.SkipKubeProxy {
+		exec.Println("The cluster was created without kube-proxy")
+		return nil
+	}
+
+	expected := c.Settings.KubeProxyMode
+	if expected == "" {
+		expected = defaultKubeProxyMode
+	}
+	log.Infof("Checking kube-proxy is running in %s mode", expected)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tREPORTED\tRULES\tSTATUS")
+	var failed []string
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		reported := kubeProxyReportedMode(n)
+		artifacts := kubeProxyNodeArtifacts(n)
+
+		result := "ok"
+		if mismatch := kubeProxyModeMismatch(expected, reported, artifacts); mismatch != "" {
+			result = mismatch
+			failed = append(failed, n.Name())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", n.Name(), valueOrUnknown(reported), valueOrUnknown(artifacts.mode()), result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("kube-proxy is not running in %s mode on nodes %s", expected, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// kubeProxyReportedMode returns the mode reported by kube-proxy on its metrics endpoint, or an empty string
+// if the endpoint can't be reached
+func kubeProxyReportedMode(n *status.Node) string {
+	lines, err := n.Command("curl", "-sf", "http://127.0.0.1:10249/proxyMode").Silent().RunAndCapture()
+	if err != nil || len(lines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(lines[0])
+}
+
+// kubeProxyNodeArtifacts returns the kube-proxy artifacts existing on a node
+func kubeProxyNodeArtifacts(n *status.Node) kubeProxyArtifacts {
+	return kubeProxyArtifacts{
+		ipvsInterface: n.Command("ip", "link", "show", "kube-ipvs0").Silent().Run() == nil,
+		nftTable:      n.Command("nft", "list", "table", "ip", "kube-proxy").Silent().Run() == nil,
+		iptablesChain: n.Command("iptables", "-t", "nat", "-n", "-L", "KUBE-SERVICES").Silent().Run() == nil,
+	}
+}
+
+func valueOrUnknown(v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestKubeProxyModeMismatch(t *testing.T) {
+	cases := []struct {
+		name      string
+		expected  string
+		reported  string
+		artifacts kubeProxyArtifacts
+		mismatch  bool
+	}{
+		{
+			name:      "iptables mode",
+			expected:  "iptables",
+			reported:  "iptables",
+			artifacts: kubeProxyArtifacts{iptablesChain: true},
+		},
+		{
+			name:      "ipvs mode, with iptables chains",
+			expected:  "ipvs",
+			reported:  "ipvs",
+			artifacts: kubeProxyArtifacts{ipvsInterface: true, iptablesChain: true},
+		},
+		{
+			name:      "nftables mode, mode not reported",
+			expected:  "nftables",
+			artifacts: kubeProxyArtifacts{nftTable: true},
+		},
+		{
+			name:      "ipvs fell back to iptables",
+			expected:  "ipvs",
+			reported:  "iptables",
+			artifacts: kubeProxyArtifacts{iptablesChain: true},
+			mismatch:  true,
+		},
+		{
+			name:      "rules do not match the expected mode",
+			expected:  "nftables",
+			artifacts: kubeProxyArtifacts{iptablesChain: true},
+			mismatch:  true,
+		},
+		{
+			name:     "no rules",
+			expected: "iptables",
+			reported: "iptables",
+			mismatch: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mismatch := kubeProxyModeMismatch(c.expected, c.reported, c.artifacts)
+			if (mismatch != "") != c.mismatch {
+				t.Errorf("expected mismatch %t, got %q", c.mismatch, mismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+// liveClusterConfiguration defines the subset of the ClusterConfiguration compared by KubeadmConfigCheck
+type liveClusterConfiguration struct {
+	APIVersion           string `json:"apiVersion"`
+	ClusterName          string `json:"clusterName"`
+	KubernetesVersion    string `json:"kubernetesVersion"`
+	ControlPlaneEndpoint string `json:"controlPlaneEndpoint"`
+	Networking           struct {
+		PodSubnet     string `json:"podSubnet"`
+		ServiceSubnet string `json:"serviceSubnet"`
+		DNSDomain     string `json:"dnsDomain"`
+	} `json:"networking"`
+	Proxy struct {
+		Disabled bool `json:"disabled"`
+	} `json:"proxy"`
+	Etcd struct {
+		Local struct {
+			ImageTag string `json:"imageTag"`
+		} `json:"local"`
+	} `json:"etcd"`
+	DNS struct {
+		ImageRepository string `json:"imageRepository"`
+		ImageTag        string `json:"imageTag"`
+	} `json:"dns"`
+}
+
+// readLiveClusterConfiguration reads the ClusterConfiguration from the kubeadm-config ConfigMap
+func readLiveClusterConfiguration(cp1 *status.Node) (liveClusterConfiguration, error) {
+	var live liveClusterConfiguration
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+		"get", "configmap", "kubeadm-config", "-o=jsonpath={.data.ClusterConfiguration}",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return live, errors.Wrap(err, "failed to read the kubeadm-config ConfigMap")
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &live); err != nil {
+		return live, errors.Wrap(err, "failed to decode the ClusterConfiguration")
+	}
+	return live, nil
+}
+
+// KubeadmConfigCheck reads the ClusterConfiguration from the kubeadm-config ConfigMap, and compares networking
+// and versions with the values kinder uses for generating the kubeadm config, as derived from the cluster settings.
+// Discrepancies, e.g. due to manual edits of the live config, are reported as an error.
+func KubeadmConfigCheck(c *status.Cluster) error {
+	cp1 := c.BootstrapControlPlane()
+
+	// the feature gates and the DNS domain are set when running kubeadm-init and are not recorded in
+	// the cluster settings, so they are not compared
+	expected, err := newKubeadmConfigData(c, "" /* feature-gates */, "" /* encryptionAlgorithm */, "" /* dnsDomain */, "" /* cgroupDriver */, "" /* tlsMinVersion */, nil /* tlsCipherSuites */)
+	if err != nil {
+		return err
+	}
+
+	// the Kubernetes version is the version of kubeadm installed on the node, that differs from the version
+	// in the node image after upgrades
+	kubeadmVersion, err := cp1.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+	expected.KubernetesVersion = kubeadmVersion.String()
+
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+		"get", "configmap", "kubeadm-config", "-o=jsonpath={.data.ClusterConfiguration}",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the kubeadm-config ConfigMap")
+	}
+
+	discrepancies, err := diffClusterConfiguration(expected, strings.Join(lines, "\n"))
+	if err != nil {
+		return err
+	}
+
+	// the cluster name propagates to the kubeconfig files generated by kubeadm
+	lines, err = cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "config", "view", "-o=jsonpath={.clusters[0].name}",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the cluster name in admin.conf")
+	}
+	if kubeConfigCluster := strings.TrimSpace(strings.Join(lines, "")); kubeConfigCluster != expected.ClusterName {
+		discrepancies = append(discrepancies, fmt.Sprintf("admin.conf cluster name: expected %q, found %q", expected.ClusterName, kubeConfigCluster))
+	}
+	if len(discrepancies) > 0 {
+		return errors.Errorf("the kubeadm-config ConfigMap does not match the cluster settings:\n%s", strings.Join(discrepancies, "\n"))
+	}
+
+	exec.Println("The kubeadm-config ConfigMap matches the cluster settings")
+	return nil
+}
+
+// diffClusterConfiguration returns the discrepancies between the values expected by kinder and a ClusterConfiguration;
+// networking values not set by kinder are defaulted by kubeadm, so they are not compared, as well as the feature gates
+// and the DNS domain, that are not recorded in the cluster settings
+func diffClusterConfiguration(expected kubeadm.ConfigData, clusterConfiguration string) ([]string, error) {
+	var live liveClusterConfiguration
+	if err := yaml.Unmarshal([]byte(clusterConfiguration), &live); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the ClusterConfiguration")
+	}
+
+	var discrepancies []string
+	diff := func(field, expected, live string) {
+		if expected != live {
+			discrepancies = append(discrepancies, fmt.Sprintf("%s: expected %q, found %q", field, expected, live))
+		}
+	}
+
+	if !sameVersion(expected.KubernetesVersion, live.KubernetesVersion) {
+		diff("kubernetesVersion", expected.KubernetesVersion, live.KubernetesVersion)
+	}
+	diff("clusterName", expected.ClusterName, live.ClusterName)
+	diff("controlPlaneEndpoint", expected.ControlPlaneEndpoint, live.ControlPlaneEndpoint)
+	diff("networking.podSubnet", expected.PodSubnet, live.Networking.PodSubnet)
+	if expected.ServiceSubnet != "" {
+		diff("networking.serviceSubnet", expected.ServiceSubnet, live.Networking.ServiceSubnet)
+	}
+
+	// the kube-proxy addon can be disabled in the ClusterConfiguration only starting from v1beta4
+	if live.APIVersion != "kubeadm.k8s.io/v1beta3" {
+		diff("proxy.disabled", strconv.FormatBool(expected.SkipKubeProxy), strconv.FormatBool(live.Proxy.Disabled))
+	}
+	diff("etcd.local.imageTag", expected.EtcdImageTag, live.Etcd.Local.ImageTag)
+	diff("dns.imageRepository", expected.CoreDNSImageRepository, live.DNS.ImageRepository)
+	diff("dns.imageTag", expected.CoreDNSImageTag, live.DNS.ImageTag)
+
+	return discrepancies, nil
+}
+
+// sameVersion returns true if two versions are semantically equal, ignoring the v prefix and build metadata
+func sameVersion(a, b string) bool {
+	va, err := K8sVersion.ParseSemantic(a)
+	if err != nil {
+		return a == b
+	}
+	cmp, err := va.Compare(b)
+	return err == nil && cmp == 0
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+func TestDiffClusterConfiguration(t *testing.T) {
+	expected := kubeadm.ConfigData{
+		KubernetesVersion:    "1.30.2",
+		ControlPlaneEndpoint: "172.18.0.2:6443",
+		PodSubnet:            "192.168.0.0/16",
+	}
+
+	tests := []struct {
+		name                 string
+		expected             kubeadm.ConfigData
+		clusterConfiguration string
+		expectedDiff         []string
+		expectedError        bool
+	}{
+		{
+			name:     "matching config",
+			expected: expected,
+			clusterConfiguration: `apiVersion: kubeadm.k8s.io/v1beta4
+kind: ClusterConfiguration
+kubernetesVersion: v1.30.2
+controlPlaneEndpoint: 172.18.0.2:6443
+networking:
+  podSubnet: 192.168.0.0/16
+  serviceSubnet: 10.96.0.0/12
+  dnsDomain: cluster.local
+`,
+		},
+		{
+			name:     "edited config, ignoring settings not recorded at create time",
+			expected: expected,
+			clusterConfiguration: `apiVersion: kubeadm.k8s.io/v1beta4
+kubernetesVersion: v1.30.3
+controlPlaneEndpoint: 172.18.0.3:6443
+networking:
+  podSubnet: 10.244.0.0/16
+  dnsDomain: example.com
+featureGates:
+  EtcdLearnerMode: false
+proxy:
+  disabled: true
+dns:
+  imageRepository: registry.example.com
+  imageTag: v1.11.1
+`,
+			expectedDiff: []string{
+				`kubernetesVersion: expected "1.30.2", found "v1.30.3"`,
+				`controlPlaneEndpoint: expected "172.18.0.2:6443", found "172.18.0.3:6443"`,
+				`networking.podSubnet: expected "192.168.0.0/16", found "10.244.0.0/16"`,
+				`proxy.disabled: expected "false", found "true"`,
+				`dns.imageRepository: expected "", found "registry.example.com"`,
+				`dns.imageTag: expected "", found "v1.11.1"`,
+			},
+		},
+		{
+			name: "edited cluster name",
+			expected: func() kubeadm.ConfigData {
+				e := expected
+				e.ClusterName = "kinder-multi-1"
+				return e
+			}(),
+			clusterConfiguration: `apiVersion: kubeadm.k8s.io/v1beta4
+clusterName: kubernetes
+kubernetesVersion: v1.30.2
+controlPlaneEndpoint: 172.18.0.2:6443
+networking:
+  podSubnet: 192.168.0.0/16
+  dnsDomain: cluster.local
+`,
+			expectedDiff: []string{
+				`clusterName: expected "kinder-multi-1", found "kubernetes"`,
+			},
+		},
+		{
+			name: "proxy is not compared for v1beta3",
+			expected: func() kubeadm.ConfigData {
+				e := expected
+				e.SkipKubeProxy = true
+				return e
+			}(),
+			clusterConfiguration: `apiVersion: kubeadm.k8s.io/v1beta3
+kubernetesVersion: v1.30.2
+controlPlaneEndpoint: 172.18.0.2:6443
+networking:
+  podSubnet: 192.168.0.0/16
+  dnsDomain: cluster.local
+`,
+		},
+		{
+			name:                 "invalid config",
+			expected:             expected,
+			clusterConfiguration: "kubernetesVersion: [",
+			expectedError:        true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			diff, err := diffClusterConfiguration(rt.expected, rt.clusterConfiguration)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error %t, got %v", rt.expectedError, err)
+			}
+			if !reflect.DeepEqual(diff, rt.expectedDiff) {
+				t.Errorf("expected diff:\n%v\ngot:\n%v", rt.expectedDiff, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+const (
+	// migrateOldConfigPath and migrateNewConfigPath define the paths on the node of the kubeadm config
+	// to migrate and of the migrated kubeadm config
+	migrateOldConfigPath = "/kinder/migrate/old-config.yaml"
+	migrateNewConfigPath = "/kinder/migrate/new-config.yaml"
+)
+
+// KubeadmConfigMigrate action migrates a kubeadm config file on the host to the newest kubeadm config API version
+// supported by the kubeadm binary in a node, using kubeadm config migrate, e.g. for testing config API upgrade paths
+// without a full cluster. The first K8s node eligible for actions is used, and kubeadm init is not required.
+// The migrated config is written to outputPath on the host, or printed if outputPath is empty.
+func KubeadmConfigMigrate(c *status.Cluster, configPath, outputPath string) error {
+	if configPath == "" {
+		return errors.New("the kubeadm config to migrate is required. Use --migrate-config")
+	}
+	oldConfig, err := os.ReadFile(configPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the kubeadm config to migrate")
+	}
+
+	nodes := c.K8sNodes().EligibleForActions()
+	if len(nodes) == 0 {
+		return errors.New("there are no K8s nodes eligible for actions")
+	}
+
+	newConfig, err := kubeadmConfigMigrate(nodes[0], oldConfig)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		fmt.Printf("%s", newConfig)
+		return nil
+	}
+	if err := os.WriteFile(outputPath, newConfig, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write the migrated kubeadm config to %s", outputPath)
+	}
+	exec.Printf("Migrated kubeadm config written to %s\n", outputPath)
+	return nil
+}
+
+// kubeadmConfigMigrate copies a kubeadm config in a node, runs kubeadm config migrate, and returns the migrated config
+func kubeadmConfigMigrate(n *status.Node, oldConfig []byte) ([]byte, error) {
+	if err := n.WriteFile(migrateOldConfigPath, oldConfig); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = n.Command("rm", "-f", migrateOldConfigPath, migrateNewConfigPath).Silent().Run()
+	}()
+
+	n.Infof("migrating the kubeadm config")
+	if err := n.Command(
+		"kubeadm", "config", "migrate",
+		fmt.Sprintf("--old-config=%s", migrateOldConfigPath),
+		fmt.Sprintf("--new-config=%s", migrateNewConfigPath),
+	).RunWithEcho(); err != nil {
+		return nil, errors.Wrapf(err, "failed to migrate the kubeadm config on node %s", n.Name())
+	}
+
+	return n.ReadFile(migrateNewConfigPath)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// kubeadmConfigValidateMinVersion is the first kubeadm version supporting kubeadm config validate;
+// pre-releases of this version are included
+var kubeadmConfigValidateMinVersion = K8sVersion.MustParseSemantic("v1.28.0-0")
+
+// supportsConfigValidate returns true if the kubeadm version supports kubeadm config validate
+func supportsConfigValidate(kubeadmVersion *K8sVersion.Version) bool {
+	return kubeadmVersion.AtLeast(kubeadmConfigValidateMinVersion)
+}
+
+// KubeadmConfigValidate action runs kubeadm config validate against the kubeadm config on each K8s node
+// eligible for actions, e.g. for catching config mistakes like an invalid feature gate or CIDR before
+// running kubeadm join or kubeadm upgrade. Nodes with a kubeadm version not supporting kubeadm config validate
+// are skipped.
+func KubeadmConfigValidate(c *status.Cluster) error {
+	var failed []string
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if err := validateKubeadmConfig(n); err != nil {
+			exec.Println(err)
+			failed = append(failed, n.Name())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("the kubeadm config is not valid on nodes %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// validateKubeadmConfig runs kubeadm config validate against the kubeadm config on a node, if supported
+// by the kubeadm version installed on the node
+func validateKubeadmConfig(n *status.Node) error {
+	kubeadmVersion, err := n.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+	if !supportsConfigValidate(kubeadmVersion) {
+		n.Infof("Skipping kubeadm config validation, not supported by kubeadm %s", kubeadmVersion)
+		return nil
+	}
+
+	if err := n.Command("test", "-f", constants.KubeadmConfigPath).Silent().Run(); err != nil {
+		return errors.Errorf("the kubeadm config %s does not exist on node %s. Please run the kubeadm-config action first", constants.KubeadmConfigPath, n.Name())
+	}
+
+	n.Infof("Validating the kubeadm config")
+	lines, err := n.Command(
+		"kubeadm", "config", "validate", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "invalid kubeadm config on node %s:\n%s", n.Name(), strings.Join(lines, "\n"))
+	}
+	return nil
+}
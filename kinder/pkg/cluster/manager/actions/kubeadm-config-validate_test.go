@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestSupportsConfigValidate(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected bool
+	}{
+		{version: "v1.27.3", expected: false},
+		{version: "v1.28.0-alpha.2", expected: true},
+		{version: "v1.28.0", expected: true},
+		{version: "v1.31.1", expected: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.version, func(t *testing.T) {
+			if got := supportsConfigValidate(K8sVersion.MustParseSemantic(c.version)); got != c.expected {
+				t.Errorf("expected %t, got %t", c.expected, got)
+			}
+		})
+	}
+}
@@ -22,8 +22,11 @@ import (
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
@@ -35,130 +38,408 @@ type kubeadmConfigOptions struct {
 	configVersion string
 	copyCertsMode CopyCertsMode
 	discoveryMode DiscoveryMode
+	patchesDir    string
+	// criSocket is the CRI socket used by kubeadm, if different from the default CRI socket for the node runtime
+	criSocket string
+	// caCertHash is the hash of the cluster CA used by joining nodes for validating the CA during token discovery
+	caCertHash string
 }
 
 // KubeadmInitConfig action writes the InitConfiguration into /kind/kubeadm.conf file on all the K8s nodes in the cluster.
 // Please note that this action is automatically executed at create time, but it is possible
 // to invoke it separately as well.
-func KubeadmInitConfig(c *status.Cluster, kubeadmConfigVersion string, copyCertsMode CopyCertsMode, featureGate, encryptionAlgorithm string, nodes ...*status.Node) error {
+func KubeadmInitConfig(c *status.Cluster, kubeadmConfigVersion string, copyCertsMode CopyCertsMode, featureGate, encryptionAlgorithm, dnsDomain, cgroupDriver, criSocket, tlsMinVersion string, tlsCipherSuites []string, patchesDir string, nodes ...*status.Node) error {
 	// defaults everything not relevant for the Init Config
-	return KubeadmConfig(c, kubeadmConfigVersion, copyCertsMode, TokenDiscovery, featureGate, encryptionAlgorithm, nodes...)
+	return KubeadmConfig(c, kubeadmConfigVersion, copyCertsMode, TokenDiscovery, featureGate, encryptionAlgorithm, dnsDomain, cgroupDriver, criSocket, tlsMinVersion, tlsCipherSuites, patchesDir, nodes...)
 }
 
 // KubeadmJoinConfig action writes the JoinConfiguration into /kind/kubeadm.conf file on all the K8s nodes in the cluster.
 // Please note that this action is automatically executed at create time, but it is possible
 // to invoke it separately as well.
-func KubeadmJoinConfig(c *status.Cluster, kubeadmConfigVersion string, copyCertsMode CopyCertsMode, discoveryMode DiscoveryMode, nodes ...*status.Node) error {
+func KubeadmJoinConfig(c *status.Cluster, kubeadmConfigVersion string, copyCertsMode CopyCertsMode, discoveryMode DiscoveryMode, patchesDir, criSocket string, nodes ...*status.Node) error {
+	// uses the DNS domain of the cluster, as defined at init time
+	// NB. this requires that kubeadm init is already completed on the BootstrapControlPlane
+	dnsDomain, err := clusterDNSDomain(c)
+	if err != nil {
+		return err
+	}
+
+	// uses the kubelet cgroup driver of the bootstrap control-plane, as defined at init time
+	cgroupDriver, err := clusterCgroupDriver(c)
+	if err != nil {
+		return err
+	}
+
 	// defaults everything not relevant for the join Config
-	return KubeadmConfig(c, kubeadmConfigVersion, copyCertsMode, discoveryMode, "" /* feature-gates */, "" /* encryptionAlgorithm */, nodes...)
+	configData, err := newKubeadmConfigData(c, "" /* feature-gates */, "" /* encryptionAlgorithm */, dnsDomain, cgroupDriver, "" /* tlsMinVersion */, nil /* tlsCipherSuites */)
+	if err != nil {
+		return err
+	}
+
+	configOptions := kubeadmConfigOptions{
+		configVersion: kubeadmConfigVersion,
+		copyCertsMode: copyCertsMode,
+		discoveryMode: discoveryMode,
+		patchesDir:    patchesDir,
+		criSocket:     criSocket,
+	}
+
+	// with token discovery, joining nodes validate the cluster CA using the CA cert hash
+	// NB. this requires that kubeadm init is already completed on the BootstrapControlPlane
+	if discoveryMode == TokenDiscovery {
+		caCertHash, err := getCACertHash(c.BootstrapControlPlane())
+		if err != nil {
+			return errors.Wrap(err, "failed to compute the CA cert hash. Please ensure that kubeadm-init is already completed")
+		}
+		configOptions.caCertHash = caCertHash
+	}
+
+	return writeKubeadmConfigs(c, configData, configOptions, nodes...)
+}
+
+// clusterDNSDomain returns the DNS domain of the cluster as defined in the kubeadm-config ConfigMap,
+// or the kubeadm default if not set
+func clusterDNSDomain(c *status.Cluster) (string, error) {
+	live, err := readLiveClusterConfiguration(c.BootstrapControlPlane())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the cluster DNS domain. Please ensure that kubeadm-init is already completed")
+	}
+	if live.Networking.DNSDomain == "" {
+		return constants.DefaultDNSDomain, nil
+	}
+	return live.Networking.DNSDomain, nil
+}
+
+// kubeletConfigPath is the path of the kubelet config written by kubeadm init and join
+const kubeletConfigPath = "/var/lib/kubelet/config.yaml"
+
+// clusterCgroupDriver returns the kubelet cgroup driver of the bootstrap control-plane, as defined in the kubelet
+// config written by kubeadm init
+func clusterCgroupDriver(c *status.Cluster) (string, error) {
+	config, err := c.BootstrapControlPlane().ReadFile(kubeletConfigPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the kubelet cgroup driver. Please ensure that kubeadm-init is already completed")
+	}
+	return kubeletCgroupDriver(config)
+}
+
+// kubeletCgroupDriver returns the cgroup driver set in a KubeletConfiguration, or the kinder default if not set
+func kubeletCgroupDriver(config []byte) (string, error) {
+	var kubeletConfig struct {
+		CgroupDriver string `json:"cgroupDriver"`
+	}
+	if err := yaml.Unmarshal(config, &kubeletConfig); err != nil {
+		return "", errors.Wrap(err, "failed to decode the kubelet config")
+	}
+	if kubeletConfig.CgroupDriver == "" {
+		return constants.DefaultCgroupDriver, nil
+	}
+	return kubeletConfig.CgroupDriver, nil
+}
+
+// getCACertHash returns the hash of the cluster CA on the bootstrap control-plane, as used by kubeadm for token discovery
+func getCACertHash(cp1 *status.Node) (string, error) {
+	caCert, err := cp1.ReadFile(caCertPath)
+	if err != nil {
+		return "", err
+	}
+	return kubeadm.CACertHash(caCert)
 }
 
 // KubeadmConfig action writes the /kind/kubeadm.conf file on all the K8s nodes in the cluster.
 // Please note that this action is automatically executed at create time, but it is possible
 // to invoke it separately as well.
-func KubeadmConfig(c *status.Cluster, kubeadmConfigVersion string, copyCertsMode CopyCertsMode, discoveryMode DiscoveryMode, featureGate, encryptionAlgorithm string, nodes ...*status.Node) error {
+func KubeadmConfig(c *status.Cluster, kubeadmConfigVersion string, copyCertsMode CopyCertsMode, discoveryMode DiscoveryMode, featureGate, encryptionAlgorithm, dnsDomain, cgroupDriver, criSocket, tlsMinVersion string, tlsCipherSuites []string, patchesDir string, nodes ...*status.Node) error {
+	configData, err := newKubeadmConfigData(c, featureGate, encryptionAlgorithm, dnsDomain, cgroupDriver, tlsMinVersion, tlsCipherSuites)
+	if err != nil {
+		return err
+	}
+
+	// create configOptions with all the kinder flags that impact on the kubeadm config generation
+	configOptions := kubeadmConfigOptions{
+		configVersion: kubeadmConfigVersion,
+		copyCertsMode: copyCertsMode,
+		discoveryMode: discoveryMode,
+		patchesDir:    patchesDir,
+		criSocket:     criSocket,
+	}
+
+	return writeKubeadmConfigs(c, configData, configOptions, nodes...)
+}
+
+// writeKubeadmConfigs writes the kubeadm config file on the given K8s nodes
+func writeKubeadmConfigs(c *status.Cluster, configData kubeadm.ConfigData, configOptions kubeadmConfigOptions, nodes ...*status.Node) error {
+	for _, node := range nodes {
+		if err := writeKubeadmConfig(c, node, configData, configOptions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RenderKubeadmConfigOptions defines the kinder flags used by RenderKubeadmConfig, like for the kubeadm-config action
+type RenderKubeadmConfigOptions struct {
+	// ConfigVersion is the kubeadm config API version
+	ConfigVersion string
+	// CopyCertsMode defines how certificates are shared between control-plane nodes
+	CopyCertsMode CopyCertsMode
+	// FeatureGate is a kubeadm feature gate, in the key=value form
+	FeatureGate string
+	// EncryptionAlgorithm is the key type used by kubeadm for certificates
+	EncryptionAlgorithm string
+	// DNSDomain is the cluster DNS domain
+	DNSDomain string
+	// CgroupDriver is the kubelet cgroup driver
+	CgroupDriver string
+	// CRISocket is the CRI socket used by kubeadm, if different from the default CRI socket for the node runtime
+	CRISocket string
+	// TLSMinVersion is the minimum TLS version of the API server
+	TLSMinVersion string
+	// TLSCipherSuites are the cipher suites of the API server
+	TLSCipherSuites []string
+	// PatchesDir is the host directory with kubeadm patches
+	PatchesDir string
+}
+
+// RenderKubeadmConfig returns the ClusterConfiguration, InitConfiguration and JoinConfiguration for a node,
+// generated from the current cluster topology and settings like the kubeadm-config action does; this allows
+// to regenerate a consistent config after topology changes, e.g. a new control-plane endpoint.
+// Differently from the kubeadm-config action, the config is not written on the node and token discovery is used.
+func RenderKubeadmConfig(c *status.Cluster, n *status.Node, options RenderKubeadmConfigOptions) (string, error) {
+	configData, err := newKubeadmConfigData(c, options.FeatureGate, options.EncryptionAlgorithm, options.DNSDomain, options.CgroupDriver, options.TLSMinVersion, options.TLSCipherSuites)
+	if err != nil {
+		return "", err
+	}
+	if err := setNodeConfigData(c, n, &configData); err != nil {
+		return "", err
+	}
+
+	configOptions := kubeadmConfigOptions{
+		configVersion: options.ConfigVersion,
+		copyCertsMode: options.CopyCertsMode,
+		discoveryMode: TokenDiscovery,
+		patchesDir:    options.PatchesDir,
+		criSocket:     options.CRISocket,
+	}
+
+	patched, err := buildKubeadmConfig(c, n, configData, configOptions)
+	if err != nil {
+		return "", err
+	}
+
+	return selectYamlFramentByKind(patched,
+		"ClusterConfiguration",
+		"InitConfiguration",
+		"JoinConfiguration",
+		"KubeletConfiguration",
+		"KubeProxyConfiguration"), nil
+}
+
+// newKubeadmConfigData returns the ConfigData for the kubeadm config template, with the values common to all the nodes
+func newKubeadmConfigData(c *status.Cluster, featureGate, encryptionAlgorithm, dnsDomain, cgroupDriver, tlsMinVersion string, tlsCipherSuites []string) (kubeadm.ConfigData, error) {
 	cp1 := c.BootstrapControlPlane()
 
 	// get installed kubernetes version from the node image
 	kubeVersion, err := cp1.KubeVersion()
 	if err != nil {
-		return errors.Wrap(err, "failed to get kubernetes version from node")
+		return kubeadm.ConfigData{}, errors.Wrap(err, "failed to get kubernetes version from node")
 	}
 
 	// gets the IP of the bootstrap control plane node
-	controlPlaneIP, controlPlaneIPV6, err := c.BootstrapControlPlane().IP()
+	controlPlaneIP, controlPlaneIPV6, err := cp1.IP()
 	if err != nil {
-		return errors.Wrapf(err, "failed to get IP for node: %s", c.BootstrapControlPlane().Name())
+		return kubeadm.ConfigData{}, errors.Wrapf(err, "failed to get IP for node: %s", cp1.Name())
 	}
 
-	// get the control plane endpoint, in case the cluster has an external load balancer in
+	// get the control plane endpoint, that is the external load balancer in case the cluster has one in
 	// front of the control-plane nodes
-	controlPlaneEndpoint, controlPlaneEndpointIPv6, ControlPlanePort, err := getControlPlaneAddress(c)
+	controlPlaneEndpoint, err := c.APIServerEndpoint()
 	if err != nil {
-		return err
+		return kubeadm.ConfigData{}, err
 	}
 
 	// configure the right protocol addresses
 	if c.Settings.IPFamily == status.IPv6Family {
 		controlPlaneIP = controlPlaneIPV6
-		controlPlaneEndpoint = controlPlaneEndpointIPv6
 	}
 
+	// dual-stack clusters are rejected at create time when the Kubernetes version in the node image is
+	// known; the version is checked again here, for node images without version metadata
+	if c.Settings.IPFamily == status.DualStackFamily {
+		if err := validateDualStackVersion(kubeVersion); err != nil {
+			return kubeadm.ConfigData{}, err
+		}
+	}
+	podSubnet, serviceSubnet := clusterSubnets(c.Settings.IPFamily)
+
 	featureGateName := ""
 	featureGateValue := ""
 	if len(featureGate) > 0 {
 		split := strings.Split(featureGate, "=")
 		if len(split) != 2 {
-			return errors.New("feature gate must be formatted as 'key=value'")
+			return kubeadm.ConfigData{}, errors.New("feature gate must be formatted as 'key=value'")
 		}
 		featureGateName = split[0]
 		featureGateValue = split[1]
 	}
 
-	// create configData with all the configurations supported by the kubeadm config template implemented in kind
-	configData := kubeadm.ConfigData{
-		ClusterName:          c.Name(),
-		KubernetesVersion:    kubeVersion,
-		ControlPlaneEndpoint: fmt.Sprintf("%s:%d", controlPlaneEndpoint, ControlPlanePort),
-		APIBindPort:          constants.APIServerPort,
-		APIServerAddress:     controlPlaneIP,
-		Token:                constants.Token,
-		PodSubnet:            "192.168.0.0/16", // default for kindnet
-		ControlPlane:         true,
-		IPv6:                 c.Settings.IPFamily == status.IPv6Family,
-		FeatureGateName:      featureGateName,
-		FeatureGateValue:     featureGateValue,
-		EncryptionAlgorithm:  encryptionAlgorithm,
+	// a rootless control-plane can't bind privileged ports
+	if featureGateName == "RootlessControlPlane" && featureGateValue == "true" && c.APIServerBindPort() < 1024 {
+		return kubeadm.ConfigData{}, errors.Errorf("the API server bind port %d is a privileged port, and it can't be used with the RootlessControlPlane feature gate", c.APIServerBindPort())
 	}
 
-	// create configOptions with all the kinder flags that impact on the kubeadm config generation
-	configOptions := kubeadmConfigOptions{
-		configVersion: kubeadmConfigVersion,
-		copyCertsMode: copyCertsMode,
-		discoveryMode: discoveryMode,
+	// if not set, uses the API server TLS settings defined at create time, if any
+	createMinVersion, createCipherSuites, err := cp1.APIServerTLS()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
+	}
+	if tlsMinVersion == "" {
+		tlsMinVersion = createMinVersion
+	}
+	if len(tlsCipherSuites) == 0 {
+		tlsCipherSuites = createCipherSuites
 	}
 
-	// writs the kubeadm config file on all the K8s nodes.
-	for _, node := range nodes {
-		if err := writeKubeadmConfig(c, node, configData, configOptions); err != nil {
-			return err
+	// the API server cipher suites must be compatible with the key type used by kubeadm
+	if err := kubeadm.ValidateAPIServerTLS(encryptionAlgorithm, tlsMinVersion, tlsCipherSuites); err != nil {
+		return kubeadm.ConfigData{}, err
+	}
+
+	// the extra args for the control-plane components are defined at create time
+	extraArgValues, err := cp1.ControlPlaneExtraArgs()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
+	}
+	extraArgs := map[string][]kubeadm.ExtraArg{}
+	for component, values := range extraArgValues {
+		args, err := kubeadm.ParseExtraArgs(values)
+		if err != nil {
+			return kubeadm.ConfigData{}, errors.Wrapf(err, "invalid %s extra args", component)
 		}
+		extraArgs[component] = args
 	}
 
-	return nil
-}
+	// the kubelet eviction settings are defined at create time
+	kubeletEviction, err := cp1.KubeletEviction()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
+	}
 
-// getControlPlaneAddress return the join address that is the control plane endpoint in case the cluster has
-// an external load balancer in front of the control-plane nodes, otherwise the address of the
-// bootstrap control plane node.
-func getControlPlaneAddress(c *status.Cluster) (string, string, int, error) {
-	// get the control plane endpoint, in case the cluster has an external load balancer in
-	// front of the control-plane nodes
-	if c.ExternalLoadBalancer() != nil {
-		// gets the IP of the load balancer
-		loadBalancerIP, loadBalancerIPV6, err := c.ExternalLoadBalancer().IP()
+	// the CoreDNS image is defined at create time
+	coreDNSImage, err := cp1.CoreDNSImage()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
+	}
+	coreDNSImageRepository, coreDNSImageTag := "", ""
+	if coreDNSImage != "" {
+		coreDNSImageRepository, coreDNSImageTag, err = kubeadm.ParseCoreDNSImage(coreDNSImage)
 		if err != nil {
-			return "", "", 0, errors.Wrapf(err, "failed to get IP for node: %s", c.ExternalLoadBalancer().Name())
+			return kubeadm.ConfigData{}, err
 		}
+	}
 
-		return loadBalancerIP, loadBalancerIPV6, constants.ControlPlanePort, nil
+	// the local etcd version is defined at create time
+	etcdVersion, err := cp1.EtcdVersion()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
 	}
 
-	// gets the IP of the bootstrap control plane node
-	controlPlaneIP, controlPlaneIPV6, err := c.BootstrapControlPlane().IP()
+	// the local etcd quota and auto-compaction settings are defined at create time; with an external etcd,
+	// the settings are passed to the external etcd containers instead
+	var etcdExtraArgs []kubeadm.ExtraArg
+	if !c.UsesExternalEtcd() {
+		values, err := cp1.EtcdExtraArgs()
+		if err != nil {
+			return kubeadm.ConfigData{}, err
+		}
+		if etcdExtraArgs, err = kubeadm.ParseExtraArgs(values); err != nil {
+			return kubeadm.ConfigData{}, errors.Wrap(err, "invalid etcd extra args")
+		}
+	}
+
+	// the provider used for encrypting secrets at rest is defined at create time
+	encryptionProvider, err := cp1.EncryptionProvider()
 	if err != nil {
-		return "", "", 0, errors.Wrapf(err, "failed to get IP for node: %s", c.BootstrapControlPlane().Name())
+		return kubeadm.ConfigData{}, err
 	}
 
-	return controlPlaneIP, controlPlaneIPV6, constants.APIServerPort, nil
+	// the API server audit log path is defined at create time
+	auditLogPath, err := cp1.AuditLogPath()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
+	}
+
+	// the cluster name in the kubeadm config is defined at create time, and defaults to the kinder cluster name
+	clusterName, err := cp1.KubeadmClusterName()
+	if err != nil {
+		return kubeadm.ConfigData{}, err
+	}
+	if clusterName == "" {
+		clusterName = c.Name()
+	}
+
+	// create configData with all the configurations supported by the kubeadm config template implemented in kind
+	return kubeadm.ConfigData{
+		ClusterName:                clusterName,
+		KubernetesVersion:          kubeVersion,
+		ControlPlaneEndpoint:       controlPlaneEndpoint,
+		APIBindPort:                int(c.APIServerBindPort()),
+		APIServerAddress:           controlPlaneIP,
+		Token:                      constants.Token,
+		PodSubnet:                  podSubnet,
+		ServiceSubnet:              serviceSubnet,
+		DNSDomain:                  dnsDomain,
+		CgroupDriver:               cgroupDriver,
+		ControlPlane:               true,
+		IPv6:                       c.Settings.IPFamily == status.IPv6Family,
+		DualStack:                  c.Settings.IPFamily == status.DualStackFamily,
+		FeatureGateName:            featureGateName,
+		FeatureGateValue:           featureGateValue,
+		EncryptionAlgorithm:        encryptionAlgorithm,
+		TLSMinVersion:              tlsMinVersion,
+		TLSCipherSuites:            tlsCipherSuites,
+		ExtraArgs:                  extraArgs,
+		KubeletEviction:            kubeletEviction,
+		SkipKubeProxy:              c.Settings.SkipKubeProxy,
+		KubeletServingCertRotation: c.Settings.KubeletServingCertRotation,
+		KubeProxyMode:              c.Settings.KubeProxyMode,
+		CoreDNSImageRepository:     coreDNSImageRepository,
+		CoreDNSImageTag:            coreDNSImageTag,
+		EtcdImageTag:               etcdVersion,
+		EtcdExtraArgs:              etcdExtraArgs,
+		EncryptionProvider:         encryptionProvider,
+		AuditLogPath:               auditLogPath,
+	}, nil
 }
 
-// writeKubeadmConfig writes the /kind/kubeadm.conf file on a node
-func writeKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData, options kubeadmConfigOptions) error {
-	n.Infof("Preparing %s", constants.KubeadmConfigPath)
+// clusterSubnets returns the pod and service subnets set in the kubeadm config for an IP family; IPv4 clusters
+// use the kubeadm default service subnet
+func clusterSubnets(family status.ClusterIPFamily) (podSubnet, serviceSubnet string) {
+	switch family {
+	case status.IPv6Family:
+		return constants.KinderPodSubnetIPv6, constants.KinderServiceSubnetIPv6
+	case status.DualStackFamily:
+		return constants.KinderPodSubnet + "," + constants.KinderPodSubnetIPv6,
+			constants.KinderServiceSubnet + "," + constants.KinderServiceSubnetIPv6
+	}
+	return constants.KinderPodSubnet, ""
+}
 
-	// Amends the ConfigData struct with node specific settings
+// validateDualStackVersion checks that the Kubernetes version supports dual-stack clusters
+func validateDualStackVersion(kubeVersion string) error {
+	v, err := K8sVersion.ParseSemantic(kubeVersion)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Kubernetes version %q", kubeVersion)
+	}
+	if v.LessThan(kubeadm.DualStackMinVersion) {
+		return errors.Errorf("dual-stack clusters require Kubernetes %s or later, the node image has %s", kubeadm.DualStackMinVersion, kubeVersion)
+	}
+	return nil
+}
 
+// setNodeConfigData amends the ConfigData with node specific settings
+func setNodeConfigData(c *status.Cluster, n *status.Node, data *kubeadm.ConfigData) error {
 	// control plane/worker role
 	data.ControlPlane = n.IsControlPlane()
 
@@ -169,8 +450,32 @@ func writeKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigDa
 	}
 
 	data.NodeAddress = nodeAddress
-	if c.Settings.IPFamily == status.IPv6Family {
+	data.NodeAddressIPv6 = ""
+	switch c.Settings.IPFamily {
+	case status.IPv6Family:
 		data.NodeAddress = nodeAddressIPv6
+	case status.DualStackFamily:
+		if nodeAddressIPv6 == "" {
+			return errors.Errorf("node %s has no IPv6 address, that is required by dual-stack clusters", n.Name())
+		}
+		data.NodeAddressIPv6 = nodeAddressIPv6
+	}
+
+	// the Kubernetes node name, if different from the container hostname
+	data.NodeName = ""
+	if name := n.KubeNodeName(); name != n.Name() {
+		data.NodeName = name
+	}
+	return nil
+}
+
+// writeKubeadmConfig writes the /kind/kubeadm.conf file on a node
+func writeKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData, options kubeadmConfigOptions) error {
+	n.Infof("Preparing %s", constants.KubeadmConfigPath)
+
+	// Amends the ConfigData struct with node specific settings
+	if err := setNodeConfigData(c, n, &data); err != nil {
+		return err
 	}
 
 	// Gets the kubeadm config customize for this node
@@ -191,6 +496,29 @@ func writeKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigDa
 
 // getKubeadmConfig generates the kubeadm config customized for a specific node
 func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData, options kubeadmConfigOptions) (string, error) {
+	patched, err := buildKubeadmConfig(c, n, data, options)
+	if err != nil {
+		return "", err
+	}
+
+	// Select the objects that are relevant for a specific node;
+	// if the node is the bootstrap control plane, then all the objects used as init time
+	if n == c.BootstrapControlPlane() {
+		return selectYamlFramentByKind(patched,
+			"ClusterConfiguration",
+			"InitConfiguration",
+			"KubeletConfiguration",
+			"KubeProxyConfiguration"), nil
+	}
+
+	// otherwise select only the JoinConfiguration
+	return selectYamlFramentByKind(patched,
+		"JoinConfiguration",
+	), nil
+}
+
+// buildKubeadmConfig generates all the kubeadm config objects for a node, with all the kinder specific patches applied
+func buildKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData, options kubeadmConfigOptions) (string, error) {
 	kubeadmVersion, err := n.KubeadmVersion()
 	if err != nil {
 		return "", err
@@ -234,6 +562,16 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 
 	patches = append(patches, criPatches...)
 
+	// if requested, add patches for using a specific CRI socket, e.g. when the node has multiple CRI sockets;
+	// these patches are applied after the CRI patches, so they take precedence over the default for the node runtime
+	if options.criSocket != "" {
+		criSocketPatches, err := kubeadm.GetCRISocketPatches(kubeadmConfigVersion, options.criSocket)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, criSocketPatches...)
+	}
+
 	// if requested automatic copy certs and the node is a controlplane node,
 	// add patches for adding the certificateKey value
 	// NB. this is a no-op in case of kubeadm config API older than v1beta2, because
@@ -287,20 +625,35 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 		}
 	}
 
-	// if the cluster is using an external etcd node, add patches for configuring access
+	// if requested to use token discovery with CA validation and not the first control-plane,
+	// add patches for validating the cluster CA using the CA cert hash
+	if options.discoveryMode == TokenDiscovery && options.caCertHash != "" && !(n == c.BootstrapControlPlane()) {
+		caCertHashPatch, err := kubeadm.GetTokenDiscoveryCACertHashPatch(kubeadmConfigVersion, options.caCertHash)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, caCertHashPatch)
+	}
+
+	// if the cluster is using external etcd nodes, add patches for configuring access
 	// to external etcd cluster
 	if c.ExternalEtcd() != nil {
-		externalEtcdIP, externalEtcdIPV6, err := c.ExternalEtcd().IP()
+		externalEtcdIPs, err := externalEtcdIPs(c)
 		if err != nil {
-			return "", errors.Wrapf(err, "failed to get IP for node: %s", c.ExternalEtcd().Name())
+			return "", err
 		}
 
-		// configure the right protocol addresses
-		if c.Settings.IPFamily == status.IPv6Family {
-			externalEtcdIP = externalEtcdIPV6
+		externalEtcdPatch, err := kubeadm.GetExternalEtcdPatch(kubeadmConfigVersion, externalEtcdIPs...)
+		if err != nil {
+			return "", err
 		}
 
-		externalEtcdPatch, err := kubeadm.GetExternalEtcdPatch(kubeadmConfigVersion, externalEtcdIP)
+		patches = append(patches, externalEtcdPatch)
+	} else if endpoints := c.Settings.ExternalEtcdEndpoints; len(endpoints) > 0 {
+		// the external etcd is not managed by kinder, and the client certificates, if any, are copied
+		// on the control-plane nodes at create time
+		useTLS := strings.HasPrefix(endpoints[0], "https://")
+		externalEtcdPatch, err := kubeadm.GetExternalEtcdEndpointsPatch(kubeadmConfigVersion, endpoints, useTLS)
 		if err != nil {
 			return "", err
 		}
@@ -308,6 +661,17 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 		patches = append(patches, externalEtcdPatch)
 	}
 
+	// if the node is a worker, add patches for the labels and the taints defined at create time
+	if n.IsWorker() {
+		nodeLabelsAndTaintsPatch, err := getNodeLabelsAndTaintsPatch(n, kubeadmConfigVersion)
+		if err != nil {
+			return "", err
+		}
+		if nodeLabelsAndTaintsPatch != nil {
+			jsonPatches = append(jsonPatches, *nodeLabelsAndTaintsPatch)
+		}
+	}
+
 	// encryption algorithm
 	if len(data.EncryptionAlgorithm) > 0 {
 		encryptionAlgorithmPatch, err := kubeadm.GetEncryptionAlgorithmPatch(kubeadmConfigVersion, data.EncryptionAlgorithm)
@@ -317,26 +681,70 @@ func getKubeadmConfig(c *status.Cluster, n *status.Node, data kubeadm.ConfigData
 		patches = append(patches, encryptionAlgorithmPatch)
 	}
 
-	// apply patches
-	patched, err := kubeadm.Build(rawconfig, patches, jsonPatches)
-	if err != nil {
-		return "", err
+	// extra args for the control-plane components, including the API server TLS min version and cipher suites
+	// and the extra args set by the template; each patch replaces the component extraArgs, so there is a single
+	// patch for each component
+	for _, component := range []string{kubeadm.APIServerComponent, kubeadm.ControllerManagerComponent, kubeadm.SchedulerComponent} {
+		args := kubeadm.ComponentExtraArgs(&data, component)
+		if len(args) == 0 {
+			continue
+		}
+		extraArgsPatch, err := kubeadm.GetExtraArgsPatch(kubeadmConfigVersion, component, args)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, extraArgsPatch)
 	}
 
-	// Select the objects that are relevant for a specific node;
-	// if the node is the bootstrap control plane, then all the objects used as init time
-	if n == c.BootstrapControlPlane() {
-		return selectYamlFramentByKind(patched,
-			"ClusterConfiguration",
-			"InitConfiguration",
-			"KubeletConfiguration",
-			"KubeProxyConfiguration"), nil
+	// extra args for the local etcd, e.g. the backend quota
+	if len(data.EtcdExtraArgs) > 0 {
+		etcdExtraArgsPatch, err := kubeadm.GetEtcdExtraArgsPatch(kubeadmConfigVersion, data.EtcdExtraArgs)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, etcdExtraArgsPatch)
 	}
 
-	// otherwise select only the JoinConfiguration
-	return selectYamlFramentByKind(patched,
-		"JoinConfiguration",
-	), nil
+	// the API server extra volumes: the encryption config dir for encrypting secrets at rest,
+	// and the audit policy and audit log dirs
+	var apiServerVolumes []kubeadm.HostPathMount
+	if data.EncryptionProvider != "" {
+		apiServerVolumes = append(apiServerVolumes, kubeadm.EncryptionConfigVolume)
+	}
+	if data.AuditLogPath != "" {
+		apiServerVolumes = append(apiServerVolumes, kubeadm.AuditVolumes(data.AuditLogPath)...)
+	}
+	if len(apiServerVolumes) > 0 {
+		extraVolumesPatch, err := kubeadm.GetAPIServerExtraVolumesPatch(kubeadmConfigVersion, apiServerVolumes...)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, extraVolumesPatch)
+	}
+
+	// kubelet eviction settings
+	if len(data.KubeletEviction) > 0 {
+		kubeletEvictionPatch, err := kubeadm.GetKubeletEvictionPatch(data.KubeletEviction)
+		if err != nil {
+			return "", err
+		}
+		patches = append(patches, kubeletEvictionPatch)
+	}
+
+	// kubelet serving certificates TLS bootstrap
+	if data.KubeletServingCertRotation {
+		patches = append(patches, kubeadm.GetKubeletServingCertRotationPatch())
+	}
+
+	// node specific patches, if any, are applied on top of all the other patches
+	nodePatches, err := kubeadm.NodeConfigPatches(options.patchesDir, n.Name())
+	if err != nil {
+		return "", err
+	}
+	patches = append(patches, nodePatches...)
+
+	// apply patches
+	return kubeadm.Build(rawconfig, patches, jsonPatches)
 }
 
 func createDiscoveryFile(c *status.Cluster, n *status.Node, discoveryMode DiscoveryMode) error {
@@ -426,3 +834,33 @@ func selectYamlFramentByKind(rawconfig string, kind ...string) string {
 
 	return strings.Join(config, yamlSeparator)
 }
+
+// getNodeLabelsAndTaintsPatch returns the patch for the labels and the taints defined for a node at create time, if any
+func getNodeLabelsAndTaintsPatch(n *status.Node, kubeadmConfigVersion string) (*kubeadm.PatchJSON6902, error) {
+	labels, err := n.NodeLabels()
+	if err != nil {
+		return nil, err
+	}
+	taintValues, err := n.NodeTaints()
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 && len(taintValues) == 0 {
+		return nil, nil
+	}
+
+	var taints []kubeadm.Taint
+	for _, v := range taintValues {
+		taint, err := kubeadm.ParseNodeTaint(v)
+		if err != nil {
+			return nil, err
+		}
+		taints = append(taints, taint)
+	}
+
+	patch, err := kubeadm.GetNodeLabelsAndTaintsPatch(kubeadmConfigVersion, labels, taints)
+	if err != nil {
+		return nil, err
+	}
+	return &patch, nil
+}
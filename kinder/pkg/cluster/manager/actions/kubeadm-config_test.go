@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestKubeletCgroupDriver(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "systemd",
+			config:   "apiVersion: kubelet.config.k8s.io/v1beta1\nkind: KubeletConfiguration\ncgroupDriver: systemd\n",
+			expected: "systemd",
+		},
+		{
+			name:     "cgroupfs",
+			config:   "apiVersion: kubelet.config.k8s.io/v1beta1\nkind: KubeletConfiguration\ncgroupDriver: cgroupfs\n",
+			expected: "cgroupfs",
+		},
+		{
+			name:     "not set",
+			config:   "apiVersion: kubelet.config.k8s.io/v1beta1\nkind: KubeletConfiguration\n",
+			expected: "systemd",
+		},
+		{
+			name:          "invalid config",
+			config:        "cgroupDriver: [",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			driver, err := kubeletCgroupDriver([]byte(test.config))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if driver != test.expected {
+				t.Errorf("expected cgroup driver %q, got %q", test.expected, driver)
+			}
+		})
+	}
+}
@@ -19,8 +19,6 @@ package actions
 import (
 	"bytes"
 	"fmt"
-	"log"
-	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -28,18 +26,61 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
-	"k8s.io/kubeadm/kinder/pkg/cluster/manager/actions/assets"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
 )
 
+// KubeadmInitOptions defines the kinder flags used by the kubeadm-init action
+type KubeadmInitOptions struct {
+	// UsePhases triggers the execution of the init workflow by invoking single phases
+	UsePhases bool
+	// CopyCertsMode defines how certificates are shared between control-plane nodes
+	CopyCertsMode CopyCertsMode
+	// ConfigVersion is the kubeadm config API version
+	ConfigVersion string
+	// PatchesDir is the host directory with kubeadm patches
+	PatchesDir string
+	// IgnorePreflightErrors is the comma separated list of the kubeadm preflight errors to be ignored; if not set,
+	// the list defined at create time or the kinder default list is used
+	IgnorePreflightErrors string
+	// FeatureGate is a kubeadm feature gate, in the key=value form
+	FeatureGate string
+	// EncryptionAlgorithm is the key type used by kubeadm for certificates
+	EncryptionAlgorithm string
+	// DNSDomain is the cluster DNS domain
+	DNSDomain string
+	// CgroupDriver is the kubelet cgroup driver
+	CgroupDriver string
+	// CRISocket is the CRI socket used by kubeadm, if different from the default CRI socket for the node runtime
+	CRISocket string
+	// TLSMinVersion is the minimum TLS version of the API server; if not set, the version defined at create
+	// time, if any, is used
+	TLSMinVersion string
+	// TLSCipherSuites are the cipher suites of the API server; if not set, the cipher suites defined at create
+	// time, if any, are used
+	TLSCipherSuites []string
+	// ValidateConfig runs kubeadm config validate against the generated kubeadm config before kubeadm init
+	ValidateConfig bool
+	// Wait is the timeout for the control-plane to be ready
+	Wait time.Duration
+	// VLevel is the kubeadm log level
+	VLevel int
+}
+
 // KubeadmInit executes the kubeadm init workflow including also post init task
 // like installing the CNI network plugin
-func KubeadmInit(c *status.Cluster, usePhases bool, copyCertsMode CopyCertsMode, kubeadmConfigVersion, patchesDir, ignorePreflightErrors, featureGates, encryptionAlgorithm string, wait time.Duration, vLevel int) (err error) {
+func KubeadmInit(c *status.Cluster, opts KubeadmInitOptions) (err error) {
 	cp1 := c.BootstrapControlPlane()
 
-	if err := copyPatchesToNode(cp1, patchesDir); err != nil {
+	if err := validatePatchesDir(c, opts.PatchesDir); err != nil {
+		return err
+	}
+
+	if err := copyPatchesToNode(cp1, opts.PatchesDir); err != nil {
 		return err
 	}
 
@@ -53,35 +94,68 @@ func KubeadmInit(c *status.Cluster, usePhases bool, copyCertsMode CopyCertsMode,
 		return err
 	}
 
+	// warns if the kubelet cgroup driver does not match the one used by the container runtime on the node
+	checkCgroupDriver(cp1, opts.CgroupDriver)
+
+	// re-installs the certificates installed at create time, if any, because kubeadm reset wipes them
+	if err := restorePKIBackup(cp1); err != nil {
+		return err
+	}
+
+	// checks the external etcd not managed by kinder, if any, is reachable with the client certificates
+	if err := checkExternalEtcdEndpoints(c, cp1); err != nil {
+		return err
+	}
+
+	// if not set, uses the preflight errors to ignore defined at create time, if any, or the kinder default list
+	ignorePreflightErrors := opts.IgnorePreflightErrors
+	if ignorePreflightErrors == "" {
+		if ignorePreflightErrors, err = cp1.IgnorePreflightErrors(); err != nil {
+			return err
+		}
+		if ignorePreflightErrors == "" {
+			ignorePreflightErrors = constants.KubeadmIgnorePreflightErrors
+		} else {
+			cp1.Infof("ignoring the kubeadm preflight errors defined at create time: %s", ignorePreflightErrors)
+		}
+	}
+
 	// prepares the kubeadm config on this node
-	if err := KubeadmInitConfig(c, kubeadmConfigVersion, copyCertsMode, featureGates, encryptionAlgorithm, cp1); err != nil {
+	if err := KubeadmInitConfig(c, opts.ConfigVersion, opts.CopyCertsMode, opts.FeatureGate, opts.EncryptionAlgorithm, opts.DNSDomain, opts.CgroupDriver, opts.CRISocket, opts.TLSMinVersion, opts.TLSCipherSuites, opts.PatchesDir, cp1); err != nil {
 		return err
 	}
 
+	// if requested, checks the kubeadm config is valid before starting the init workflow
+	if opts.ValidateConfig {
+		if err := validateKubeadmConfig(cp1); err != nil {
+			return err
+		}
+	}
+
 	// prepares the loadbalancer config
 	if err := LoadBalancer(c, cp1); err != nil {
 		return err
 	}
 
 	// execs the kubeadm init workflow
-	if usePhases {
-		err = kubeadmInitWithPhases(cp1, copyCertsMode, ignorePreflightErrors, vLevel)
+	if opts.UsePhases {
+		err = kubeadmInitWithPhases(cp1, c.APIServerBindPort(), opts.CopyCertsMode, ignorePreflightErrors, c.Settings.SkipKubeProxy, opts.VLevel)
 	} else {
-		err = kubeadmInit(cp1, copyCertsMode, ignorePreflightErrors, vLevel)
+		err = kubeadmInit(cp1, opts.CopyCertsMode, ignorePreflightErrors, c.Settings.SkipKubeProxy, opts.VLevel)
 	}
 	if err != nil {
 		return err
 	}
 
 	// completes post init task by installing the CNI network plugin
-	if err := postInit(c, wait); err != nil {
+	if err := postInit(c, opts.Wait); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func kubeadmInit(cp1 *status.Node, copyCertsMode CopyCertsMode, ignorePreflightErrors string, vLevel int) error {
+func kubeadmInit(cp1 *status.Node, copyCertsMode CopyCertsMode, ignorePreflightErrors string, skipKubeProxy bool, vLevel int) error {
 	initArgs := []string{
 		"init",
 		fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
@@ -94,6 +168,10 @@ func kubeadmInit(cp1 *status.Node, copyCertsMode CopyCertsMode, ignorePreflightE
 			// NB. certificate key is passed via the config file)
 		)
 	}
+	if skipKubeProxy {
+		// NB. with kubeadm config v1beta4 the kube-proxy addon is disabled also in the ClusterConfiguration
+		initArgs = append(initArgs, "--skip-phases=addon/kube-proxy")
+	}
 
 	if err := cp1.Command(
 		"kubeadm", initArgs...,
@@ -104,30 +182,30 @@ func kubeadmInit(cp1 *status.Node, copyCertsMode CopyCertsMode, ignorePreflightE
 	return nil
 }
 
-func kubeadmInitWithPhases(cp1 *status.Node, copyCertsMode CopyCertsMode, ignorePreflightErrors string, vLevel int) error {
+func kubeadmInitWithPhases(cp1 *status.Node, apiServerBindPort int32, copyCertsMode CopyCertsMode, ignorePreflightErrors string, skipKubeProxy bool, vLevel int) error {
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "preflight", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 		fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase preflight failed")
 	}
 
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "kubelet-start", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase kubelet-start failed")
 	}
 
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "certs", "all", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase certs failed")
 	}
 
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "kubeconfig", "all", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase kubeconfig failed")
 	}
 
 	controlplaneArgs := []string{
@@ -136,7 +214,7 @@ func kubeadmInitWithPhases(cp1 *status.Node, copyCertsMode CopyCertsMode, ignore
 	if err := cp1.Command(
 		"kubeadm", controlplaneArgs...,
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase control-plane failed")
 	}
 
 	etcdArgs := []string{
@@ -145,21 +223,21 @@ func kubeadmInitWithPhases(cp1 *status.Node, copyCertsMode CopyCertsMode, ignore
 	if err := cp1.Command(
 		"kubeadm", etcdArgs...,
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase etcd failed")
 	}
 
 	cp1.Infof("waiting for the api server to start")
 	if err := cp1.Command(
 		"/bin/bash", "-c", //use shell to get $(...) resolved into the container
-		fmt.Sprintf("while [[ \"$(curl -k https://localhost:%d/healthz -s -o /dev/null -w ''%%{http_code}'')\" != \"200\" ]]; do sleep 1; done", constants.APIServerPort),
+		fmt.Sprintf("while [[ \"$(curl -k https://localhost:%d/healthz -s -o /dev/null -w ''%%{http_code}'')\" != \"200\" ]]; do sleep 1; done", apiServerBindPort),
 	).Silent().Run(); err != nil {
-		return err
+		return errors.Wrap(err, "failed waiting for the API server to start")
 	}
 
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "upload-config", "all", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase upload-config failed")
 	}
 
 	if copyCertsMode == CopyCertsModeAuto {
@@ -171,26 +249,30 @@ func kubeadmInitWithPhases(cp1 *status.Node, copyCertsMode CopyCertsMode, ignore
 		if err := cp1.Command(
 			"kubeadm", uploadCertsArgs...,
 		).RunWithEcho(); err != nil {
-			return err
+			return errors.Wrap(err, "kubeadm init phase upload-certs failed")
 		}
 	}
 
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "mark-control-plane", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase mark-control-plane failed")
 	}
 
 	if err := cp1.Command(
 		"kubeadm", "init", "phase", "bootstrap-token", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase bootstrap-token failed")
 	}
 
+	addons := "all"
+	if skipKubeProxy {
+		addons = "coredns"
+	}
 	if err := cp1.Command(
-		"kubeadm", "init", "phase", "addon", "all", fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
+		"kubeadm", "init", "phase", "addon", addons, fmt.Sprintf("--config=%s", constants.KubeadmConfigPath), fmt.Sprintf("--v=%d", vLevel),
 	).RunWithEcho(); err != nil {
-		return err
+		return errors.Wrap(err, "kubeadm init phase addon failed")
 	}
 
 	return nil
@@ -203,11 +285,9 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 		return err
 	}
 
-	// Apply a CNI plugin using a hardcoded manifest
-	cmd := cp1.Command("kubectl", "apply", "--kubeconfig=/etc/kubernetes/admin.conf", "-f", "-")
-	cp1.Infof("applying kindnet version 0.5.4")
-	cmd.Stdin(strings.NewReader(assets.KindnetManifest054))
-	if err := cmd.RunWithEcho(); err != nil {
+	// Apply the CNI plugin defined at create time, kindnet by default
+	plugin, err := installCNI(cp1)
+	if err != nil {
 		return err
 	}
 
@@ -223,6 +303,20 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 		}
 	}
 
+	// scales the CoreDNS deployment to the number of replicas defined at create time, if any
+	replicas, err := cp1.CoreDNSReplicas()
+	if err != nil {
+		return err
+	}
+	if replicas > 0 {
+		if err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+			"scale", "deployment/coredns", fmt.Sprintf("--replicas=%d", replicas),
+		).RunWithEcho(); err != nil {
+			return errors.Wrap(err, "failed to scale the CoreDNS deployment")
+		}
+	}
+
 	//TODO: add the default storage class
 	//if err := addDefaultStorageClass(node); err != nil {
 	//	return errors.Wrap(err, "failed to add default storage class")
@@ -232,7 +326,30 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 		return err
 	}
 
-	fmt.Printf(
+	// approves the kubelet serving certificate, if signed by the controller manager as defined at create time
+	if c.Settings.KubeletServingCertRotation {
+		if err := approveKubeletServingCSRs(c, status.NodeList{cp1}, wait); err != nil {
+			return err
+		}
+	}
+
+	if err := waitCNIReady(c, cp1, plugin, wait); err != nil {
+		return err
+	}
+
+	if err := verifyEncryptionAtRest(c); err != nil {
+		return err
+	}
+
+	if err := applyBootstrapManifests(cp1); err != nil {
+		return err
+	}
+
+	if err := waitDaemonSetsReady(c, cp1, wait); err != nil {
+		return err
+	}
+
+	exec.Printf(
 		"Cluster creation complete. You can now use the cluster with:\n\n"+
 
 			"export KUBECONFIG=\"$(kinder get kubeconfig-path --name=%q)\"\n"+
@@ -243,6 +360,83 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 	return nil
 }
 
+// applyBootstrapManifests applies in order the manifests defined at create time, if any, once the control-plane
+// is ready, and reports the result of each manifest; the first failure stops the process
+func applyBootstrapManifests(cp1 *status.Node) error {
+	manifests, err := cp1.BootstrapManifests()
+	if err != nil {
+		return err
+	}
+	for i, m := range manifests {
+		cp1.Infof("applying bootstrap manifest %d/%d %s", i+1, len(manifests), m)
+		if err := cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "apply", "-f", m,
+		).RunWithEcho(); err != nil {
+			return errors.Wrapf(err, "failed to apply bootstrap manifest %d/%d %s", i+1, len(manifests), m)
+		}
+		exec.Printf("Bootstrap manifest %s applied\n", m)
+	}
+	if len(manifests) > 0 {
+		exec.Println()
+	}
+	return nil
+}
+
+// encryptionCheckSecret is the secret created for checking that secrets are encrypted at rest
+const encryptionCheckSecret = "kinder-encryption-check"
+
+// verifyEncryptionAtRest checks that newly created secrets are stored encrypted in etcd, if an encryption
+// provider is defined at create time, by creating a secret and reading the value stored in etcd
+func verifyEncryptionAtRest(c *status.Cluster) error {
+	cp1 := c.BootstrapControlPlane()
+	provider, err := cp1.EncryptionProvider()
+	if err != nil {
+		return err
+	}
+	if provider == "" {
+		return nil
+	}
+	if c.ExternalEtcd() == nil && c.UsesExternalEtcd() {
+		cp1.Infof("skipping the check for secrets encrypted at rest with %s, etcd is not managed by kinder", provider)
+		return nil
+	}
+
+	cp1.Infof("verifying secrets are encrypted at rest with %s", provider)
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=default",
+		"create", "secret", "generic", encryptionCheckSecret, "--from-literal=key=value",
+	).Silent().Run(); err != nil {
+		return errors.Wrap(err, "failed to create the secret for checking encryption at rest")
+	}
+	defer func() {
+		_ = cp1.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=default",
+			"delete", "secret", encryptionCheckSecret, "--ignore-not-found",
+		).Silent().Run()
+	}()
+
+	key := fmt.Sprintf("/registry/secrets/default/%s", encryptionCheckSecret)
+	var lines []string
+	if etcd := c.ExternalEtcd(); etcd != nil {
+		lines, err = etcdctl(etcd, "get", key, "--print-value-only").RunAndCapture()
+	} else {
+		args := append([]string{
+			"--kubeconfig=/etc/kubernetes/admin.conf", "exec", "-n=kube-system", fmt.Sprintf("etcd-%s", cp1.KubeNodeName()),
+			"--", "etcdctl", "--endpoints=https://127.0.0.1:2379",
+		}, etcdCertArgsNew...)
+		lines, err = cp1.Command("kubectl", append(args, "get", key, "--print-value-only")...).Silent().RunAndCapture()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read the secret for checking encryption at rest from etcd")
+	}
+
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], kubeadm.EncryptedSecretPrefix(provider)) {
+		return errors.Errorf("secrets are not encrypted at rest with %s; the value stored in etcd doesn't start with %q", provider, kubeadm.EncryptedSecretPrefix(provider))
+	}
+	exec.Printf("Secrets are encrypted at rest with %s\n\n", provider)
+	return nil
+}
+
 // copyKubeConfigToHost copies the admin.conf file to the host in order to make the cluster
 // usable with kubectl.
 // the kubeconfig file created by kubeadm internally to the node must be modified in order to use
@@ -250,44 +444,33 @@ func postInit(c *status.Cluster, wait time.Duration) error {
 func copyKubeConfigToHost(c *status.Cluster) error {
 	c.BootstrapControlPlane().Infof("copying the admin.conf file to the host")
 
-	hostPort, err := getAPIServerPort(c)
+	endpoint, err := c.APIServerHostEndpoint()
 	if err != nil {
 		return errors.Wrap(err, "failed to get kubeconfig from node")
 	}
 
-	if err := writeKubeConfig(c, hostPort); err != nil {
+	if err := writeKubeConfig(c, endpoint); err != nil {
 		return errors.Wrap(err, "failed to get kubeconfig from node")
 	}
 
 	return nil
 }
 
-// getAPIServerPort returns the port on the host on which the APIServer is exposed
-func getAPIServerPort(c *status.Cluster) (int32, error) {
-	// select the external loadbalancer first
-	if c.ExternalLoadBalancer() != nil {
-		return c.ExternalLoadBalancer().Ports(constants.ControlPlanePort)
-	}
-
-	// fallback to the bootstrap control plane
-	return c.BootstrapControlPlane().Ports(constants.APIServerPort)
-}
-
 // matches kubeconfig server entry like:
 //
 //	server: https://172.17.0.2:6443
 //
 // which we rewrite to:
 //
-//	server: https://$ADDRESS:$PORT
+//	server: https://$ENDPOINT
 var serverAddressRE = regexp.MustCompile(`^(\s+server:) https://.*:\d+$`)
 
 // writeKubeConfig writes a fixed KUBECONFIG to dest
 // this should only be called on a control plane node
 // While copying to the host machine the control plane address
-// is replaced with local host and the control plane port with
-// a randomly generated port reserved during node creation.
-func writeKubeConfig(c *status.Cluster, hostPort int32) error {
+// is replaced with the API server endpoint reachable from the host, that is
+// local host and a randomly generated port reserved during node creation.
+func writeKubeConfig(c *status.Cluster, endpoint string) error {
 	lines, err := c.BootstrapControlPlane().Command("cat", "/etc/kubernetes/admin.conf").Silent().RunAndCapture()
 	if err != nil {
 		return errors.Wrap(err, "failed to get kubeconfig from node")
@@ -298,8 +481,7 @@ func writeKubeConfig(c *status.Cluster, hostPort int32) error {
 	for _, line := range lines {
 		match := serverAddressRE.FindStringSubmatch(line)
 		if len(match) > 1 {
-			addr := net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort))
-			line = fmt.Sprintf("%s https://%s", match[1], addr)
+			line = fmt.Sprintf("%s https://%s", match[1], endpoint)
 		}
 		buff.WriteString(line)
 		buff.WriteString("\n")
@@ -316,6 +498,45 @@ func writeKubeConfig(c *status.Cluster, hostPort int32) error {
 	return os.WriteFile(dest, buff.Bytes(), 0600)
 }
 
+// checkCgroupDriver warns if the cgroup driver for the kubelet does not match the cgroup driver
+// of the container runtime on the node; a mismatch will most likely prevent the kubelet to start.
+func checkCgroupDriver(n *status.Node, cgroupDriver string) {
+	runtimeCgroupDriver, err := n.CgroupDriver()
+	if err != nil {
+		log.Warnf("unable to detect the cgroup driver of the container runtime on %s: %v", n.Name(), err)
+		return
+	}
+	if runtimeCgroupDriver != cgroupDriver {
+		log.Warnf("the kubelet cgroup driver %q does not match the cgroup driver %q of the container runtime on %s; the kubelet will likely fail to start", cgroupDriver, runtimeCgroupDriver, n.Name())
+	}
+}
+
+// validatePatchesDir checks the content of the patches directory, if defined, before it is copied to any node;
+// this allows to detect malformed patches before kubeadm fails in the middle of a workflow.
+func validatePatchesDir(c *status.Cluster, dir string) error {
+	if len(dir) == 0 {
+		return nil
+	}
+
+	var nodeNames []string
+	for _, n := range c.K8sNodes() {
+		nodeNames = append(nodeNames, n.Name())
+	}
+	return kubeadm.ValidatePatchesDir(dir, nodeNames)
+}
+
+// restorePKIBackup copies the certificates installed at create time from the kinder PKI backup dir to
+// /etc/kubernetes/pki on a control-plane node, if any; existing files are preserved
+func restorePKIBackup(n *status.Node) error {
+	if err := n.Command("test", "-d", constants.PKIBackupDir).Silent().Run(); err != nil {
+		return nil
+	}
+	if err := n.Command("cp", "-a", "-n", constants.PKIBackupDir+"/.", constants.PKIDir).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to re-install the certificates from %s on node %s", constants.PKIBackupDir, n.Name())
+	}
+	return nil
+}
+
 func copyPatchesToNode(n *status.Node, dir string) error {
 	// always create the target patch directory on the node since it's always
 	// defined in the kubeadm config.
@@ -332,11 +553,36 @@ func copyPatchesToNode(n *status.Node, dir string) error {
 	n.Infof("Importing patches from %s", dir)
 	files, err := os.ReadDir(dir)
 	if err != nil {
-		log.Fatal(err)
+		return errors.Wrapf(err, "failed to read patches directory %s", dir)
+	}
+
+	if err := copyPatchFilesToNode(n, dir, files); err != nil {
+		return err
 	}
 
+	// copy node specific patches, if any, on top of the common patches
+	nodeDir := filepath.Join(dir, n.Name())
+	nodeFiles, err := os.ReadDir(nodeDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read patches directory %s", nodeDir)
+	}
+
+	n.Infof("Importing node specific patches from %s", nodeDir)
+	return copyPatchFilesToNode(n, nodeDir, nodeFiles)
+}
+
+func copyPatchFilesToNode(n *status.Node, dir string, files []os.DirEntry) error {
 	for _, file := range files {
-		fmt.Printf("%s\n", file.Name())
+		// sub directories are node specific patches directories, and the kubeadm config patch
+		// file is not consumed by kubeadm (it is applied when generating the kubeadm config)
+		if file.IsDir() || file.Name() == kubeadm.NodeConfigPatchFile {
+			continue
+		}
+
+		exec.Printf("%s\n", file.Name())
 
 		hostPath := filepath.Join(dir, file.Name())
 		nodePath := filepath.Join(constants.PatchesDir, file.Name())
@@ -17,77 +17,342 @@ limitations under the License.
 package actions
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/failure"
 )
 
-// KubeadmJoin executes the kubeadm join workflow both for control-plane nodes and
-// worker nodes
-func KubeadmJoin(c *status.Cluster, usePhases bool, copyCertsMode CopyCertsMode, discoveryMode DiscoveryMode, kubeadmConfigVersion, patchesDir, ignorePreflightErrors string, wait time.Duration, vLevel int) (err error) {
-	if err := joinControlPlanes(c, usePhases, copyCertsMode, discoveryMode, kubeadmConfigVersion, patchesDir, ignorePreflightErrors, wait, vLevel); err != nil {
-		return err
+// JoinOutcome defines the outcome of kubeadm join on a node
+type JoinOutcome string
+
+const (
+	// JoinSucceeded is the outcome of a node successfully joined
+	JoinSucceeded = JoinOutcome("success")
+
+	// JoinFailed is the outcome of a node that failed to join
+	JoinFailed = JoinOutcome("failed")
+
+	// JoinSkipped is the outcome of a node not joined because of a previous failure
+	JoinSkipped = JoinOutcome("skipped")
+
+	// JoinAlreadyJoined is the outcome of a node not joined because it already joined the cluster,
+	// e.g. when re-running join after a transient failure
+	JoinAlreadyJoined = JoinOutcome("already-joined")
+)
+
+// NodeJoinResult defines the result of kubeadm join on a node
+type NodeJoinResult struct {
+	Node    string
+	Outcome JoinOutcome
+	Err     error
+
+	// PhaseOutputs holds the output of each kubeadm join phase executed on the node, by phase name;
+	// it is set only when joining with phases, and it includes the output of the failed phase, if any
+	PhaseOutputs map[string]string
+
+	// PodStartupLatency holds the latency from a pod targeted at the node being scheduled to the pod running;
+	// it is set only when measuring pod startup latency after join, for worker nodes successfully joined
+	PodStartupLatency time.Duration
+}
+
+// JoinResult defines the result of kubeadm join on all the nodes attempted
+type JoinResult struct {
+	Nodes []NodeJoinResult
+}
+
+func (r *JoinResult) add(n *status.Node, outcome JoinOutcome, phaseOutputs map[string]string, err error) {
+	r.Nodes = append(r.Nodes, NodeJoinResult{Node: n.Name(), Outcome: outcome, Err: err, PhaseOutputs: phaseOutputs})
+}
+
+func (r *JoinResult) skip(nodes ...*status.Node) {
+	for _, n := range nodes {
+		r.add(n, JoinSkipped, nil, nil)
 	}
+}
 
-	if err := joinWorkers(c, usePhases, discoveryMode, wait, kubeadmConfigVersion, patchesDir, ignorePreflightErrors, vLevel); err != nil {
-		return err
+// PhaseOutput returns the output of a kubeadm join phase executed on a node, e.g. control-plane-prepare;
+// an empty string is returned if the phase was not executed on the node
+func (r *JoinResult) PhaseOutput(node, phase string) string {
+	for _, n := range r.Nodes {
+		if n.Node == node {
+			return n.PhaseOutputs[phase]
+		}
 	}
-	return nil
+	return ""
 }
 
-func joinControlPlanes(c *status.Cluster, usePhases bool, copyCertsMode CopyCertsMode, discoveryMode DiscoveryMode, kubeadmConfigVersion, patchesDir, ignorePreflightErrors string, wait time.Duration, vLevel int) (err error) {
-	cpX := []*status.Node{c.BootstrapControlPlane()}
+// NodesWithOutcome returns the name of the nodes with the given outcome
+func (r *JoinResult) NodesWithOutcome(outcome JoinOutcome) []string {
+	var nodes []string
+	for _, n := range r.Nodes {
+		if n.Outcome == outcome {
+			nodes = append(nodes, n.Node)
+		}
+	}
+	return nodes
+}
 
-	for _, cp2 := range c.SecondaryControlPlanes().EligibleForActions() {
-		if err := copyPatchesToNode(cp2, patchesDir); err != nil {
-			return err
+// Category returns the failure category of the join result: partial if some nodes joined the cluster
+// and other nodes failed, none otherwise, so the category of the error, e.g. a timeout, applies
+func (r *JoinResult) Category() failure.Category {
+	joined := len(r.NodesWithOutcome(JoinSucceeded)) + len(r.NodesWithOutcome(JoinAlreadyJoined))
+	if joined > 0 && len(r.NodesWithOutcome(JoinFailed)) > 0 {
+		return failure.Partial
+	}
+	return failure.None
+}
+
+// String returns a summary of the join result, with one line for each node
+func (r *JoinResult) String() string {
+	var b strings.Builder
+	for _, n := range r.Nodes {
+		fmt.Fprintf(&b, "%s: %s", n.Node, n.Outcome)
+		if n.PodStartupLatency > 0 {
+			fmt.Fprintf(&b, " (pod startup latency %s)", n.PodStartupLatency)
+		}
+		if n.Err != nil {
+			fmt.Fprintf(&b, " (%v)", n.Err)
 		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
 
-		// if not automatic copy certs, simulate manual copy
-		if copyCertsMode == CopyCertsModeManual {
-			if err := copyCertificatesToNode(c, cp2); err != nil {
-				return err
-			}
+// KubeadmJoinOptions defines the kinder flags used by the kubeadm-join action
+type KubeadmJoinOptions struct {
+	// UsePhases triggers the execution of the join workflow by invoking single phases
+	UsePhases bool
+	// CopyCertsMode defines how certificates are shared between control-plane nodes
+	CopyCertsMode CopyCertsMode
+	// DiscoveryMode defines the discovery mode used by the joining nodes
+	DiscoveryMode DiscoveryMode
+	// ConfigVersion is the kubeadm config API version
+	ConfigVersion string
+	// PatchesDir is the host directory with kubeadm patches
+	PatchesDir string
+	// IgnorePreflightErrors is the comma separated list of the kubeadm preflight errors to be ignored
+	IgnorePreflightErrors string
+	// CRISocket is the CRI socket used by kubeadm, if different from the default CRI socket for the node runtime
+	CRISocket string
+	// WaitCoreDNS waits for CoreDNS to be ready after join
+	WaitCoreDNS bool
+	// WaitAllNodes waits for all the nodes in the cluster to be Ready after join
+	WaitAllNodes bool
+	// Force resets and joins again the nodes already joined
+	Force bool
+	// PodStartup, if set, defines how to measure the pod startup latency on each worker node after join
+	PodStartup *PodStartupOptions
+	// RefreshCertsAfter is the interval after which the uploaded certificates are refreshed before joining
+	// a control-plane node; zero disables refreshes
+	RefreshCertsAfter time.Duration
+	// ValidateConfig runs kubeadm config validate against the generated kubeadm config before kubeadm join
+	ValidateConfig bool
+	// WorkerParallelism is the maximum number of worker nodes joining at the same time
+	WorkerParallelism int
+	// Wait is the timeout for the nodes to be ready
+	Wait time.Duration
+	// VLevel is the kubeadm log level
+	VLevel int
+}
+
+// KubeadmJoin executes the kubeadm join workflow both for control-plane nodes and
+// worker nodes; the result is printed on failure, or when measuring pod startup latency.
+// Failures leaving some nodes joined and others not have the partial failure category
+func KubeadmJoin(c *status.Cluster, opts KubeadmJoinOptions) (err error) {
+	result, err := KubeadmJoinWithResult(c, opts)
+	if (err != nil || opts.PodStartup != nil) && len(result.Nodes) > 0 {
+		log.Infof("kubeadm join result:\n%s", result)
+	}
+	if err != nil {
+		return failure.WithCategory(err, result.Category())
+	}
+	return nil
+}
+
+// KubeadmJoinWithResult executes the kubeadm join workflow both for control-plane nodes and
+// worker nodes, and returns the outcome for each node attempted; nodes following a failure are skipped.
+// Control-plane nodes join one at a time, while up to WorkerParallelism worker nodes join at the same time.
+// With the automatic copy certs mode, uploaded certificates are refreshed before joining a control-plane node
+// if more than RefreshCertsAfter elapsed since the last refresh; zero disables refreshes.
+// Nodes already joined, e.g. when re-running join after a transient failure, are skipped, or they are reset
+// and joined again if Force is set. If PodStartup is set, the pod startup latency is measured on each worker
+// node after join; latencies exceeding the maximum latency are reported as join failures.
+// The returned result is never nil.
+func KubeadmJoinWithResult(c *status.Cluster, opts KubeadmJoinOptions) (*JoinResult, error) {
+	result := &JoinResult{}
+
+	// validates patches before touching any node, so a malformed patch can't leave the cluster half-joined
+	if err := validatePatchesDir(c, opts.PatchesDir); err != nil {
+		return result, failure.WithCategory(err, failure.Validation)
+	}
+
+	// without the CA key, certificates can't be uploaded nor generated at join time
+	if err := validateCopyCertsModeForCA(c, opts.CopyCertsMode); err != nil {
+		return result, failure.WithCategory(err, failure.Validation)
+	}
+
+	// checks pre-loaded images on all the nodes to join at once, so failures are reported before joining
+	// any node instead of being discovered node by node; missing images are reported, but they are not blocking
+	// because they can be pulled at join time
+	if _, err := checkPreloadedImages(append(c.SecondaryControlPlanes().EligibleForActions(), c.Workers().EligibleForActions()...)); err != nil {
+		result.skip(c.SecondaryControlPlanes().EligibleForActions()...)
+		result.skip(c.Workers().EligibleForActions()...)
+		return result, err
+	}
+
+	if err := joinControlPlanes(c, result, opts); err != nil {
+		result.skip(c.Workers().EligibleForActions()...)
+		return result, err
+	}
+
+	if err := joinWorkers(c, result, opts); err != nil {
+		return result, err
+	}
+
+	// approves the kubelet serving certificates of the joined nodes, if signed by the controller manager as
+	// defined at create time
+	if c.Settings.KubeletServingCertRotation {
+		joined := append(c.SecondaryControlPlanes().EligibleForActions(), c.Workers().EligibleForActions()...)
+		if err := approveKubeletServingCSRs(c, joined, opts.Wait); err != nil {
+			return result, err
 		}
+	}
 
-		// checks pre-loaded images available on the node (this will report missing images, if any)
-		kubeVersion, err := cp2.KubeVersion()
-		if err != nil {
-			return err
+	// if requested, waits for all the nodes in the cluster to be Ready, so callers get a single guarantee
+	// that the cluster is fully formed instead of per-node checks that can race with slow registrations
+	if opts.WaitAllNodes {
+		expected := len(c.ControlPlanes()) + len(c.Workers())
+		if err := waitAllNodesReady(c, c.BootstrapControlPlane(), expected, opts.Wait); err != nil {
+			return result, err
 		}
+	}
 
-		if err := checkImagesForVersion(cp2, kubeVersion); err != nil {
-			return err
+	// waits for the DaemonSets defined at create time, if any, to be rolled out also on the joined nodes
+	if err := waitDaemonSetsReady(c, c.BootstrapControlPlane(), opts.Wait); err != nil {
+		return result, err
+	}
+
+	// if requested, waits for CoreDNS to be ready, so it is possible to use service DNS resolution
+	// immediately after join; this is opt-in because clusters without a CNI plugin won't satisfy it
+	if opts.WaitCoreDNS {
+		if err := waitCoreDNSReady(c, c.BootstrapControlPlane(), opts.Wait); err != nil {
+			return result, err
 		}
+	}
+	return result, nil
+}
+
+func joinControlPlanes(c *status.Cluster, result *JoinResult, opts KubeadmJoinOptions) error {
+	cpX := []*status.Node{c.BootstrapControlPlane()}
+
+	// uploaded certificates are used only by the automatic copy certs mode
+	refresher := &certsRefresher{}
+	if opts.CopyCertsMode == CopyCertsModeAuto {
+		refresher.interval = opts.RefreshCertsAfter
+	}
 
-		// prepares the kubeadm config on this node
-		if err := KubeadmJoinConfig(c, kubeadmConfigVersion, copyCertsMode, discoveryMode, cp2); err != nil {
+	cps := c.SecondaryControlPlanes().EligibleForActions()
+	for i, cp2 := range cps {
+		skip, err := checkJoined(c, cp2, opts.Force, opts.VLevel)
+		if err != nil {
+			result.add(cp2, JoinFailed, nil, err)
+			result.skip(cps[i+1:]...)
 			return err
 		}
+		if skip {
+			result.add(cp2, JoinAlreadyJoined, nil, nil)
+			cpX = append(cpX, cp2)
+			continue
+		}
 
-		// executes the kubeadm join control-plane workflow
-		if usePhases {
-			err = kubeadmJoinControlPlaneWithPhases(cp2, ignorePreflightErrors, vLevel)
-		} else {
-			err = kubeadmJoinControlPlane(cp2, ignorePreflightErrors, vLevel)
+		if err := refresher.refreshIfDue(c, opts.VLevel); err != nil {
+			result.add(cp2, JoinFailed, nil, err)
+			result.skip(cps[i+1:]...)
+			return err
 		}
+		phaseOutputs, err := joinControlPlane(c, cp2, cpX, opts)
 		if err != nil {
+			result.add(cp2, JoinFailed, phaseOutputs, err)
+			result.skip(cps[i+1:]...)
 			return err
 		}
-
-		// updates the loadbalancer config with the new cp node
+		result.add(cp2, JoinSucceeded, phaseOutputs, nil)
 		cpX = append(cpX, cp2)
-		if err := LoadBalancer(c, cpX...); err != nil {
-			return err
+	}
+	return nil
+}
+
+func joinControlPlane(c *status.Cluster, cp2 *status.Node, cpX []*status.Node, opts KubeadmJoinOptions) (phaseOutputs map[string]string, err error) {
+	if err := copyPatchesToNode(cp2, opts.PatchesDir); err != nil {
+		return nil, err
+	}
+
+	// re-installs the certificates installed at create time, if any, because kubeadm reset wipes them
+	if err := restorePKIBackup(cp2); err != nil {
+		return nil, err
+	}
+
+	// if not automatic copy certs, simulate manual copy
+	if opts.CopyCertsMode == CopyCertsModeManual {
+		if err := copyCertificatesToNode(c, cp2); err != nil {
+			return nil, err
 		}
+	}
 
-		if err := waitNewControlPlaneNodeReady(c, cp2, wait); err != nil {
-			return err
+	// without the CA key, copy the shared certs and check the node certs are pre-generated
+	if opts.CopyCertsMode == CopyCertsModeExternalCA {
+		if err := copyExternalCACertificatesToNode(c, cp2); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+
+	// prepares the kubeadm config on this node
+	if err := KubeadmJoinConfig(c, opts.ConfigVersion, opts.CopyCertsMode, opts.DiscoveryMode, opts.PatchesDir, opts.CRISocket, cp2); err != nil {
+		return nil, err
+	}
+
+	// if requested, checks the kubeadm config is valid before starting the join workflow
+	if opts.ValidateConfig {
+		if err := validateKubeadmConfig(cp2); err != nil {
+			return nil, err
+		}
+	}
+
+	// checks the API server endpoint used for join is reachable from this node
+	if err := waitAPIServerReachable(c, cp2, opts.Wait); err != nil {
+		return nil, err
+	}
+
+	// executes the kubeadm join control-plane workflow
+	if opts.UsePhases {
+		phaseOutputs, err = kubeadmJoinControlPlaneWithPhases(cp2, opts.IgnorePreflightErrors, opts.VLevel)
+	} else {
+		err = kubeadmJoinControlPlane(cp2, opts.IgnorePreflightErrors, opts.VLevel)
+	}
+	if err != nil {
+		return phaseOutputs, err
+	}
+
+	// updates the loadbalancer config with the new cp node
+	if err := LoadBalancer(c, append(cpX, cp2)...); err != nil {
+		return phaseOutputs, err
+	}
+
+	if err := waitNewControlPlaneNodeReady(c, cp2, opts.Wait); err != nil {
+		return phaseOutputs, err
+	}
+
+	// checks the load balancer is sending traffic to the new cp node
+	return phaseOutputs, waitLoadBalancerBackendUp(c, cp2, opts.Wait)
 }
 
 func kubeadmJoinControlPlane(cp *status.Node, ignorePreflightErrors string, vLevel int) (err error) {
@@ -98,142 +363,353 @@ func kubeadmJoinControlPlane(cp *status.Node, ignorePreflightErrors string, vLev
 		fmt.Sprintf("--v=%d", vLevel),
 	}
 
-	if err := cp.Command(
+	return cp.Command(
 		"kubeadm", joinArgs...,
-	).RunWithEcho(); err != nil {
-		return err
-	}
+	).TailOnError(kubeadmErrorTailLines).RunWithEcho()
+}
 
-	return nil
+func kubeadmJoinControlPlaneWithPhases(cp *status.Node, ignorePreflightErrors string, vLevel int) (map[string]string, error) {
+	return runJoinPhases(cp, []joinPhase{
+		{
+			name: "preflight",
+			args: []string{
+				"join", "phase", "preflight",
+				fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+				fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
+				fmt.Sprintf("--v=%d", vLevel),
+			},
+		},
+		{
+			name: "control-plane-prepare",
+			args: []string{
+				"join", "phase", "control-plane-prepare", "all",
+				fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+				fmt.Sprintf("--v=%d", vLevel),
+			},
+		},
+		{
+			name: "kubelet-start",
+			args: []string{
+				"join", "phase", "kubelet-start",
+				fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+				fmt.Sprintf("--v=%d", vLevel),
+			},
+		},
+		{
+			name: "control-plane-join",
+			args: []string{
+				"join", "phase", "control-plane-join", "all",
+				fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+				fmt.Sprintf("--v=%d", vLevel),
+			},
+		},
+	})
 }
 
-func kubeadmJoinControlPlaneWithPhases(cp *status.Node, ignorePreflightErrors string, vLevel int) (err error) {
-	// kubeadm join phase preflight
-	preflightArgs := []string{
-		"join", "phase", "preflight",
-		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
-		fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
-		fmt.Sprintf("--v=%d", vLevel),
-	}
+// kubeadmErrorTailLines defines the number of lines of kubeadm output reported in errors
+const kubeadmErrorTailLines = 20
 
-	if err := cp.Command(
-		"kubeadm", preflightArgs...,
-	).RunWithEcho(); err != nil {
-		return err
-	}
+// joinPhase defines a kubeadm join phase and the kubeadm args for executing it
+type joinPhase struct {
+	name string
+	args []string
+}
 
-	// kubeadm join phase control-plane-prepare
-	prepareArgs := []string{
-		"join", "phase", "control-plane-prepare", "all",
-		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
-		fmt.Sprintf("--v=%d", vLevel),
+// runJoinPhases executes the kubeadm join phases on a node, echoing the output to screen, and returns
+// the output of each phase executed, by phase name; execution stops at the first failed phase
+func runJoinPhases(n *status.Node, phases []joinPhase) (map[string]string, error) {
+	outputs := map[string]string{}
+	for _, p := range phases {
+		var out bytes.Buffer
+		err := n.Command("kubeadm", p.args...).TailOnError(kubeadmErrorTailLines).RunWithEchoTo(&out)
+		outputs[p.name] = out.String()
+		if err != nil {
+			return outputs, err
+		}
 	}
+	return outputs, nil
+}
 
-	if err := cp.Command(
-		"kubeadm", prepareArgs...,
-	).RunWithEcho(); err != nil {
-		return err
+// joinWorkers joins the worker nodes, with up to WorkerParallelism nodes joining at the same time; differently from
+// control-plane nodes, worker nodes can join concurrently because they don't change the load balancer config.
+// After a failure, the nodes not started yet are skipped, while the nodes already joining complete; the errors
+// of all the failed nodes are returned.
+func joinWorkers(c *status.Cluster, result *JoinResult, opts KubeadmJoinOptions) error {
+	workers := c.Workers().EligibleForActions()
+	if opts.WorkerParallelism > 1 && len(workers) > 1 {
+		log.Infof("Joining %d worker nodes, up to %d at the same time", len(workers), opts.WorkerParallelism)
 	}
 
-	// kubeadm join phase kubelet-start
-	if err := cp.Command(
-		"kubeadm", "join", "phase", "kubelet-start",
-		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
-		fmt.Sprintf("--v=%d", vLevel),
-	).RunWithEcho(); err != nil {
-		return err
+	results := make([]*NodeJoinResult, len(workers))
+	runBounded(len(workers), opts.WorkerParallelism, func(i int) bool {
+		r := joinWorkerWithResult(c, workers[i], opts)
+		results[i] = &r
+		return r.Outcome != JoinFailed
+	})
+
+	var failed []string
+	var firstErr error
+	for i, w := range workers {
+		if results[i] == nil {
+			result.skip(w)
+			continue
+		}
+		result.Nodes = append(result.Nodes, *results[i])
+		if err := results[i].Err; err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed = append(failed, fmt.Sprintf("%s: %v", w.Name(), err))
+		}
+	}
+	if len(failed) > 1 {
+		// the failure category of the first error, e.g. a timeout, applies to the aggregated error
+		return failure.WithCategory(errors.Errorf("failed to join %d worker nodes:\n%s", len(failed), strings.Join(failed, "\n")), failure.CategoryOf(firstErr))
 	}
+	return firstErr
+}
 
-	// kubeadm join phase control-plane-join
-	controlPlaneArgs := []string{
-		"join", "phase", "control-plane-join", "all",
-		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
-		fmt.Sprintf("--v=%d", vLevel),
+// joinWorkerWithResult joins a worker node, unless it already joined the cluster, and measures the pod startup
+// latency on the node after join, if requested
+func joinWorkerWithResult(c *status.Cluster, w *status.Node, opts KubeadmJoinOptions) NodeJoinResult {
+	skip, err := checkJoined(c, w, opts.Force, opts.VLevel)
+	if err != nil {
+		return NodeJoinResult{Node: w.Name(), Outcome: JoinFailed, Err: err}
+	}
+	if skip {
+		return NodeJoinResult{Node: w.Name(), Outcome: JoinAlreadyJoined}
 	}
 
-	if err := cp.Command(
-		"kubeadm", controlPlaneArgs...,
-	).RunWithEcho(); err != nil {
-		return err
+	phaseOutputs, err := joinWorker(c, w, opts)
+	if err != nil {
+		return NodeJoinResult{Node: w.Name(), Outcome: JoinFailed, Err: err, PhaseOutputs: phaseOutputs}
 	}
 
-	return nil
+	r := NodeJoinResult{Node: w.Name(), Outcome: JoinSucceeded, PhaseOutputs: phaseOutputs}
+	if opts.PodStartup == nil {
+		return r
+	}
+	if r.PodStartupLatency, r.Err = measurePodStartupLatency(c, w, *opts.PodStartup, opts.Wait); r.Err != nil {
+		r.Outcome = JoinFailed
+	}
+	return r
 }
 
-func joinWorkers(c *status.Cluster, usePhases bool, discoveryMode DiscoveryMode, wait time.Duration, kubeadmConfigVersion, patchesDir, ignorePreflightErrors string, vLevel int) (err error) {
-	for _, w := range c.Workers().EligibleForActions() {
-		// checks pre-loaded images available on the node (this will report missing images, if any)
-		kubeVersion, err := w.KubeVersion()
-		if err != nil {
-			return err
-		}
+// runBounded runs fn for the indexes from 0 to n-1, with up to parallelism runs at the same time and in index
+// order; after a run returns false, the runs not started yet are not started, while the runs already started
+// complete. With parallelism 1, runs are sequential and stop at the first failure.
+func runBounded(n, parallelism int, fn func(i int) bool) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
 
-		if err := copyPatchesToNode(w, patchesDir); err != nil {
-			return err
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		stopped bool
+	)
+	slots := make(chan struct{}, parallelism)
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		mu.Lock()
+		stop := stopped
+		mu.Unlock()
+		if stop {
+			<-slots
+			break
 		}
 
-		if err := checkImagesForVersion(w, kubeVersion); err != nil {
-			return err
-		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if !fn(i) {
+				mu.Lock()
+				stopped = true
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
 
-		// prepares the kubeadm config on this node
-		if err := KubeadmJoinConfig(c, kubeadmConfigVersion, CopyCertsModeNone, discoveryMode, w); err != nil {
-			return err
-		}
+func joinWorker(c *status.Cluster, w *status.Node, opts KubeadmJoinOptions) (phaseOutputs map[string]string, err error) {
+	if err := copyPatchesToNode(w, opts.PatchesDir); err != nil {
+		return nil, err
+	}
 
-		// executes the kubeadm join workflow
-		if usePhases {
-			err = kubeadmJoinWorkerWithPhases(w, ignorePreflightErrors, vLevel)
-		} else {
-			err = kubeadmJoinWorker(w, ignorePreflightErrors, vLevel)
-		}
-		if err != nil {
-			return err
-		}
+	// prepares the kubeadm config on this node
+	if err := KubeadmJoinConfig(c, opts.ConfigVersion, CopyCertsModeNone, opts.DiscoveryMode, opts.PatchesDir, opts.CRISocket, w); err != nil {
+		return nil, err
+	}
 
-		if err := waitNewWorkerNodeReady(c, w, wait); err != nil {
-			return err
+	// if requested, checks the kubeadm config is valid before starting the join workflow
+	if opts.ValidateConfig {
+		if err := validateKubeadmConfig(w); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+
+	// checks the API server endpoint used for join is reachable from this node
+	if err := waitAPIServerReachable(c, w, opts.Wait); err != nil {
+		return nil, err
+	}
+
+	// executes the kubeadm join workflow
+	if opts.UsePhases {
+		phaseOutputs, err = kubeadmJoinWorkerWithPhases(w, opts.IgnorePreflightErrors, opts.VLevel)
+	} else {
+		err = kubeadmJoinWorker(w, opts.IgnorePreflightErrors, opts.VLevel)
+	}
+	if err != nil {
+		return phaseOutputs, err
+	}
+
+	return phaseOutputs, waitNewWorkerNodeReady(c, w, opts.Wait)
 }
 
 func kubeadmJoinWorker(w *status.Node, ignorePreflightErrors string, vLevel int) (err error) {
-	if err := w.Command(
+	return w.Command(
 		"kubeadm", "join",
 		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
 		fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
 		fmt.Sprintf("--v=%d", vLevel),
-	).RunWithEcho(); err != nil {
-		return err
-	}
+	).TailOnError(kubeadmErrorTailLines).RunWithEcho()
+}
+
+func kubeadmJoinWorkerWithPhases(w *status.Node, ignorePreflightErrors string, vLevel int) (map[string]string, error) {
+	// NB. kubeadm join phase control-plane-prepare and control-plane-join should not be executed when joining a worker node
+	return runJoinPhases(w, []joinPhase{
+		{
+			name: "preflight",
+			args: []string{
+				"join", "phase", "preflight",
+				fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+				fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
+				fmt.Sprintf("--v=%d", vLevel),
+			},
+		},
+		{
+			name: "kubelet-start",
+			args: []string{
+				"join", "phase", "kubelet-start",
+				fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+				fmt.Sprintf("--v=%d", vLevel),
+			},
+		},
+	})
+}
 
+// validateCopyCertsModeForCA checks that the external CA copy certs mode is used only for clusters without the CA key,
+// and that the automatic copy certs mode, requiring the CA key, is not used for those clusters
+func validateCopyCertsModeForCA(c *status.Cluster, copyCertsMode CopyCertsMode) error {
+	if copyCertsMode != CopyCertsModeExternalCA && copyCertsMode != CopyCertsModeAuto {
+		return nil
+	}
+	if len(c.SecondaryControlPlanes().EligibleForActions()) == 0 {
+		return nil
+	}
+	externalCA := isExternalCA(c)
+	if copyCertsMode == CopyCertsModeExternalCA && !externalCA {
+		return errors.Errorf("the %s copy certs mode can be used only for clusters without the CA key, e.g. after the setup-external-ca action", CopyCertsModeExternalCA)
+	}
+	if copyCertsMode == CopyCertsModeAuto && externalCA {
+		return errors.Errorf("the %s copy certs mode can't be used for clusters without the CA key. Use the %s copy certs mode", CopyCertsModeAuto, CopyCertsModeExternalCA)
+	}
 	return nil
 }
 
-func kubeadmJoinWorkerWithPhases(w *status.Node, ignorePreflightErrors string, vLevel int) (err error) {
-	// kubeadm join phase preflight
-	if err := w.Command(
-		"kubeadm", "join", "phase", "preflight",
-		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
-		fmt.Sprintf("--ignore-preflight-errors=%s", ignorePreflightErrors),
-		fmt.Sprintf("--v=%d", vLevel),
-	).RunWithEcho(); err != nil {
-		return err
+// checkJoined detects nodes that already joined the cluster, e.g. when re-running join after a transient failure,
+// and returns true if the node should not be joined again; if force is set, already joined nodes are reset
+// and deleted from the cluster, so they can be joined again
+func checkJoined(c *status.Cluster, n *status.Node, force bool, vLevel int) (bool, error) {
+	joined, err := isJoined(c, n)
+	if err != nil {
+		return false, err
+	}
+	switch joinedNodeActionFor(joined, force) {
+	case joinNode:
+		return false, nil
+	case skipJoinedNode:
+		n.Infof("Node already joined the cluster, skipping")
+		return true, nil
 	}
 
-	// NB. kubeadm join phase control-plane-prepare should not be executed when joining a worker node
+	n.Infof("Node already joined the cluster, resetting")
+	if err := n.Command(
+		"kubeadm", "reset", "--force", fmt.Sprintf("--v=%d", vLevel),
+	).TailOnError(kubeadmErrorTailLines).RunWithEcho(); err != nil {
+		return false, errors.Wrapf(err, "failed to reset node %s", n.Name())
+	}
+	if err := c.BootstrapControlPlane().Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"delete", "node", n.KubeNodeName(), "--ignore-not-found",
+	).Silent().Run(); err != nil {
+		return false, errors.Wrapf(err, "failed to delete node %s from the cluster", n.Name())
+	}
+	return false, nil
+}
 
-	// kubeadm join phase kubelet-start
-	if err := w.Command(
-		"kubeadm", "join", "phase", "kubelet-start",
-		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
-		fmt.Sprintf("--v=%d", vLevel),
-	).RunWithEcho(); err != nil {
-		return err
+// isJoined returns true if a node already joined the cluster, that is the kubelet is running with the kubeconfig
+// file written by kubeadm join and the node is registered in the API server; for control-plane nodes,
+// the API server static pod manifest should exist as well
+func isJoined(c *status.Cluster, n *status.Node) (bool, error) {
+	for _, f := range joinedNodeFiles(n.IsControlPlane()) {
+		if err := n.Command("test", "-f", f).Silent().Run(); err != nil {
+			return false, nil
+		}
+	}
+	if err := n.Command("systemctl", "is-active", "--quiet", "kubelet").Silent().Run(); err != nil {
+		return false, nil
+	}
+
+	lines, err := c.BootstrapControlPlane().Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"get", "nodes", fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.KubeNodeName()), "-o=name",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to check if node %s is registered in the cluster", n.Name())
 	}
+	return isRegisteredNode(lines), nil
+}
 
-	// NB. kubeadm join phase control-plane-join should not be executed when joining a worker node
+// joinedNodeAction defines what to do with a node before joining it
+type joinedNodeAction int
 
-	return nil
+const (
+	// joinNode is for nodes not joined yet
+	joinNode joinedNodeAction = iota
+	// skipJoinedNode is for nodes already joined, that are not joined again
+	skipJoinedNode
+	// resetJoinedNode is for nodes already joined, that are reset and joined again
+	resetJoinedNode
+)
+
+// joinedNodeActionFor returns what to do with a node before joining it; already joined nodes are skipped,
+// unless force is set
+func joinedNodeActionFor(joined, force bool) joinedNodeAction {
+	switch {
+	case !joined:
+		return joinNode
+	case force:
+		return resetJoinedNode
+	default:
+		return skipJoinedNode
+	}
+}
+
+// joinedNodeFiles returns the files written by kubeadm join, that exist on already joined nodes
+func joinedNodeFiles(controlPlane bool) []string {
+	files := []string{"/etc/kubernetes/kubelet.conf"}
+	if controlPlane {
+		files = append(files, "/etc/kubernetes/manifests/kube-apiserver.yaml")
+	}
+	return files
+}
+
+// isRegisteredNode returns true if the output of kubectl get nodes -o=name lists a node
+func isRegisteredNode(lines []string) bool {
+	return strings.TrimSpace(strings.Join(lines, "")) != ""
 }
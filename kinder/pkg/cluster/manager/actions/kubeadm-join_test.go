@@ -0,0 +1,304 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/failure"
+)
+
+func TestJoinResult(t *testing.T) {
+	result := &JoinResult{
+		Nodes: []NodeJoinResult{
+			{Node: "kind-control-plane-2", Outcome: JoinSucceeded},
+			{Node: "kind-control-plane-3", Outcome: JoinFailed, Err: errors.New("preflight failed")},
+			{Node: "kind-worker-1", Outcome: JoinSkipped},
+			{Node: "kind-worker-2", Outcome: JoinSkipped},
+			{Node: "kind-worker-3", Outcome: JoinSucceeded, PodStartupLatency: 2 * time.Second},
+			{Node: "kind-worker-4", Outcome: JoinAlreadyJoined},
+		},
+	}
+
+	tests := []struct {
+		outcome  JoinOutcome
+		expected []string
+	}{
+		{outcome: JoinSucceeded, expected: []string{"kind-control-plane-2", "kind-worker-3"}},
+		{outcome: JoinFailed, expected: []string{"kind-control-plane-3"}},
+		{outcome: JoinSkipped, expected: []string{"kind-worker-1", "kind-worker-2"}},
+		{outcome: JoinAlreadyJoined, expected: []string{"kind-worker-4"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.outcome), func(t *testing.T) {
+			nodes := result.NodesWithOutcome(test.outcome)
+			if !reflect.DeepEqual(nodes, test.expected) {
+				t.Fatalf("expected nodes: %v, found %v", test.expected, nodes)
+			}
+		})
+	}
+
+	expectedSummary := "kind-control-plane-2: success\n" +
+		"kind-control-plane-3: failed (preflight failed)\n" +
+		"kind-worker-1: skipped\n" +
+		"kind-worker-2: skipped\n" +
+		"kind-worker-3: success (pod startup latency 2s)\n" +
+		"kind-worker-4: already-joined\n"
+	if result.String() != expectedSummary {
+		t.Fatalf("expected summary:\n%s\nfound:\n%s", expectedSummary, result.String())
+	}
+}
+
+func TestJoinedNodeActionFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		joined   bool
+		force    bool
+		expected joinedNodeAction
+	}{
+		{name: "not joined", expected: joinNode},
+		{name: "not joined with force", force: true, expected: joinNode},
+		{name: "already joined", joined: true, expected: skipJoinedNode},
+		{name: "already joined with force", joined: true, force: true, expected: resetJoinedNode},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if action := joinedNodeActionFor(test.joined, test.force); action != test.expected {
+				t.Errorf("expected action %d, found %d", test.expected, action)
+			}
+		})
+	}
+}
+
+func TestJoinedNodeFiles(t *testing.T) {
+	if files := joinedNodeFiles(false); !reflect.DeepEqual(files, []string{"/etc/kubernetes/kubelet.conf"}) {
+		t.Errorf("unexpected files for worker nodes: %v", files)
+	}
+	if files := joinedNodeFiles(true); !reflect.DeepEqual(files, []string{"/etc/kubernetes/kubelet.conf", "/etc/kubernetes/manifests/kube-apiserver.yaml"}) {
+		t.Errorf("unexpected files for control-plane nodes: %v", files)
+	}
+}
+
+func TestIsRegisteredNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected bool
+	}{
+		{name: "registered", lines: []string{"node/kind-worker-1"}, expected: true},
+		{name: "not registered", lines: nil},
+		{name: "empty output", lines: []string{"", " "}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if registered := isRegisteredNode(test.lines); registered != test.expected {
+				t.Errorf("expected registered %t, found %t", test.expected, registered)
+			}
+		})
+	}
+}
+
+func TestJoinResultCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []NodeJoinResult
+		expected failure.Category
+	}{
+		{
+			name: "all nodes joined",
+			nodes: []NodeJoinResult{
+				{Node: "kind-control-plane-2", Outcome: JoinSucceeded},
+				{Node: "kind-worker-1", Outcome: JoinAlreadyJoined},
+			},
+			expected: failure.None,
+		},
+		{
+			name: "first node failed",
+			nodes: []NodeJoinResult{
+				{Node: "kind-control-plane-2", Outcome: JoinFailed},
+				{Node: "kind-worker-1", Outcome: JoinSkipped},
+			},
+			expected: failure.None,
+		},
+		{
+			name: "some nodes joined",
+			nodes: []NodeJoinResult{
+				{Node: "kind-control-plane-2", Outcome: JoinSucceeded},
+				{Node: "kind-worker-1", Outcome: JoinFailed},
+				{Node: "kind-worker-2", Outcome: JoinSkipped},
+			},
+			expected: failure.Partial,
+		},
+		{
+			name: "some nodes already joined",
+			nodes: []NodeJoinResult{
+				{Node: "kind-control-plane-2", Outcome: JoinAlreadyJoined},
+				{Node: "kind-worker-1", Outcome: JoinFailed},
+			},
+			expected: failure.Partial,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := &JoinResult{Nodes: test.nodes}
+			if category := result.Category(); category != test.expected {
+				t.Errorf("expected category %q, found %q", test.expected, category)
+			}
+		})
+	}
+}
+
+func TestJoinResultPhaseOutput(t *testing.T) {
+	result := &JoinResult{
+		Nodes: []NodeJoinResult{
+			{
+				Node:    "kind-control-plane-2",
+				Outcome: JoinSucceeded,
+				PhaseOutputs: map[string]string{
+					"preflight":             "[preflight] Running pre-flight checks\n",
+					"control-plane-prepare": "[certs] Generating \"apiserver\" certificate and key\n",
+				},
+			},
+			{Node: "kind-worker-1", Outcome: JoinSucceeded},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		node     string
+		phase    string
+		expected string
+	}{
+		{
+			name:     "phase executed",
+			node:     "kind-control-plane-2",
+			phase:    "control-plane-prepare",
+			expected: "[certs] Generating \"apiserver\" certificate and key\n",
+		},
+		{
+			name:  "phase not executed",
+			node:  "kind-control-plane-2",
+			phase: "control-plane-join",
+		},
+		{
+			name:  "node joined without phases",
+			node:  "kind-worker-1",
+			phase: "preflight",
+		},
+		{
+			name:  "unknown node",
+			node:  "kind-worker-2",
+			phase: "preflight",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if output := result.PhaseOutput(test.node, test.phase); output != test.expected {
+				t.Fatalf("expected output: %q, found %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestRunBounded(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		parallelism int
+		failing     int
+		expectedRun []int
+	}{
+		{
+			name:        "sequential",
+			n:           4,
+			parallelism: 1,
+			failing:     -1,
+			expectedRun: []int{0, 1, 2, 3},
+		},
+		{
+			name:        "sequential stops at the first failure",
+			n:           4,
+			parallelism: 1,
+			failing:     1,
+			expectedRun: []int{0, 1},
+		},
+		{
+			name:        "parallel",
+			n:           8,
+			parallelism: 3,
+			failing:     -1,
+			expectedRun: []int{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:        "invalid parallelism is sequential",
+			n:           2,
+			parallelism: 0,
+			failing:     0,
+			expectedRun: []int{0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+			ran := make([]bool, test.n)
+			runBounded(test.n, test.parallelism, func(i int) bool {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				ran[i] = true
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return i != test.failing
+			})
+
+			var got []int
+			for i, ok := range ran {
+				if ok {
+					got = append(got, i)
+				}
+			}
+			if !reflect.DeepEqual(got, test.expectedRun) {
+				t.Errorf("expected runs %v, got %v", test.expectedRun, got)
+			}
+			limit := test.parallelism
+			if limit < 1 {
+				limit = 1
+			}
+			if maxRunning > limit {
+				t.Errorf("expected at most %d concurrent runs, got %d", limit, maxRunning)
+			}
+		})
+	}
+}
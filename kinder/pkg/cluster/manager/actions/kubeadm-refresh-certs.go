@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// KubeadmRefreshCerts uploads again the control-plane certificates to the kubeadm-certs secret, using
+// kubeadm init phase upload-certs on the bootstrap control-plane, and returns the certificate key.
+// Uploaded certificates expire after two hours, so this allows to join control-plane nodes late
+// in a long workflow without re-running kubeadm init.
+func KubeadmRefreshCerts(c *status.Cluster, vLevel int) (string, error) {
+	cp1 := c.BootstrapControlPlane()
+
+	var out bytes.Buffer
+	if err := cp1.Command(
+		"kubeadm", "init", "phase", "upload-certs", "--upload-certs",
+		fmt.Sprintf("--config=%s", constants.KubeadmConfigPath),
+		fmt.Sprintf("--v=%d", vLevel),
+	).TailOnError(kubeadmErrorTailLines).RunWithEchoTo(&out); err != nil {
+		return "", errors.Wrap(err, "kubeadm init phase upload-certs failed")
+	}
+
+	return parseCertificateKey(strings.Split(out.String(), "\n"))
+}
+
+// certificateKeyRegexp matches a certificate key, that is a hex encoded 32 bytes AES key
+var certificateKeyRegexp = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// parseCertificateKey returns the certificate key printed by kubeadm init phase upload-certs
+// on the line following "Using certificate key:"
+func parseCertificateKey(lines []string) (string, error) {
+	for i, l := range lines {
+		if !strings.Contains(l, "Using certificate key:") {
+			continue
+		}
+		for _, next := range lines[i+1:] {
+			key := strings.TrimSpace(next)
+			if key == "" {
+				continue
+			}
+			if !certificateKeyRegexp.MatchString(key) {
+				return "", errors.Errorf("invalid certificate key %q in the kubeadm output", key)
+			}
+			return key, nil
+		}
+	}
+	return "", errors.New("certificate key not found in the kubeadm output")
+}
+
+// certsRefresher refreshes the uploaded certificates before joining control-plane nodes,
+// if more than the given interval elapsed since the last refresh; an interval of zero disables refreshes
+type certsRefresher struct {
+	interval    time.Duration
+	lastRefresh time.Time
+}
+
+// isDue returns true if the certificates should be refreshed at the given time; the first refresh is always due,
+// because the time of the upload at init is not known
+func (r *certsRefresher) isDue(now time.Time) bool {
+	if r.interval <= 0 {
+		return false
+	}
+	return r.lastRefresh.IsZero() || now.Sub(r.lastRefresh) >= r.interval
+}
+
+// refreshIfDue refreshes the uploaded certificates, if due
+func (r *certsRefresher) refreshIfDue(c *status.Cluster, vLevel int) error {
+	if !r.isDue(time.Now()) {
+		return nil
+	}
+	if _, err := KubeadmRefreshCerts(c, vLevel); err != nil {
+		return err
+	}
+	r.lastRefresh = time.Now()
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCertificateKey(t *testing.T) {
+	key := "0123456789012345678901234567890123456789012345678901234567890123"
+	tests := []struct {
+		name          string
+		lines         []string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "key on the next line",
+			lines: []string{
+				"[upload-certs] Storing the certificates in Secret \"kubeadm-certs\" in the \"kube-system\" Namespace",
+				"[upload-certs] Using certificate key:",
+				key,
+			},
+			expected: key,
+		},
+		{
+			name:     "empty lines are skipped",
+			lines:    []string{"[upload-certs] Using certificate key:", "", "  " + key, ""},
+			expected: key,
+		},
+		{
+			name:          "key not found",
+			lines:         []string{"[upload-certs] Storing the certificates in Secret \"kubeadm-certs\""},
+			expectedError: true,
+		},
+		{
+			name:          "invalid key",
+			lines:         []string{"[upload-certs] Using certificate key:", "not-a-key"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := parseCertificateKey(test.lines)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if key != test.expected {
+				t.Errorf("expected key %q, got %q", test.expected, key)
+			}
+		})
+	}
+}
+
+func TestCertsRefresherIsDue(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		lastRefresh time.Time
+		expected    bool
+	}{
+		{
+			name:     "disabled",
+			expected: false,
+		},
+		{
+			name:     "never refreshed",
+			interval: time.Hour,
+			expected: true,
+		},
+		{
+			name:        "refreshed recently",
+			interval:    time.Hour,
+			lastRefresh: now.Add(-30 * time.Minute),
+			expected:    false,
+		},
+		{
+			name:        "refreshed too long ago",
+			interval:    time.Hour,
+			lastRefresh: now.Add(-61 * time.Minute),
+			expected:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &certsRefresher{interval: test.interval, lastRefresh: test.lastRefresh}
+			if due := r.isDue(now); due != test.expected {
+				t.Errorf("expected due %v, got %v", test.expected, due)
+			}
+		})
+	}
+}
@@ -18,14 +18,39 @@ package actions
 
 import (
 	"fmt"
+	"time"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 )
 
-// KubeadmReset executes the kubeadm reset workflow
-func KubeadmReset(c *status.Cluster, vLevel int) error {
+// KubeadmReset executes the kubeadm reset workflow; if requested, nodes joined to the cluster are drained
+// before reset. When draining, nodes are reset in reverse provisioning order, so the bootstrap control-plane
+// is still available for draining the other nodes; nodes reset in the same run are not considered for
+// rescheduling the evicted pods, and draining the last node is not refused when all the nodes are reset.
+func KubeadmReset(c *status.Cluster, drain DrainOptions, wait time.Duration, vLevel int) error {
+	nodes := c.K8sNodes().EligibleForActions()
+	if drain.Enabled {
+		reversed := make(status.NodeList, 0, len(nodes))
+		for i := len(nodes) - 1; i >= 0; i-- {
+			reversed = append(reversed, nodes[i])
+		}
+		nodes = reversed
+	}
+
 	//TODO: implements kubeadm reset with phases
-	for _, n := range c.K8sNodes().EligibleForActions() {
+	for _, n := range nodes {
+		if drain.Enabled {
+			joined, err := isJoined(c, n)
+			if err != nil {
+				return err
+			}
+			if joined {
+				if err := drainNode(c, n, drain, wait, nodes...); err != nil {
+					return err
+				}
+			}
+		}
+
 		if err := n.Command(
 			"kubeadm", "reset", "--force", fmt.Sprintf("--v=%d", vLevel),
 		).RunWithEcho(); err != nil {
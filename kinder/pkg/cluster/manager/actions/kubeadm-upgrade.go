@@ -19,46 +19,116 @@ package actions
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
+// KubeadmUpgradeOptions defines the kinder flags used by the kubeadm-upgrade action
+type KubeadmUpgradeOptions struct {
+	// UpgradeVersion is the target version, that should match the version of the upgrade binaries
+	UpgradeVersion *K8sVersion.Version
+	// NodeSelectors select the nodes to upgrade, e.g. @cp1; all the nodes are upgraded if not set
+	NodeSelectors []string
+	// PatchesDir is the host directory with kubeadm patches
+	PatchesDir string
+	// FeatureGate is a kubeadm feature gate, in the key=value form
+	FeatureGate string
+	// Drain defines how nodes are drained before upgrading the kubelet
+	Drain DrainOptions
+	// ValidateConfig runs kubeadm config validate with the upgraded kubeadm binary before upgrading the
+	// bootstrap control-plane node
+	ValidateConfig bool
+	// Wait is the timeout for the nodes to be ready
+	Wait time.Duration
+	// VLevel is the kubeadm log level
+	VLevel int
+}
+
 // KubeadmUpgrade executes the kubeadm upgrade workflow, including also deployment of new
-// kubeadm/kubelet/kubectl binaries; for sake of simplicity, drain/uncordon when upgrading nodes
-// is not executed.
+// kubeadm/kubelet/kubectl binaries; if requested, nodes are drained before upgrading the kubelet
+// and uncordoned afterwards.
 //
 // The implementation assumes that the kubeadm/kubelet/kubectl binaries and all the necessary images
 // for the new kubernetes version are available in the /kinder/upgrade/{version} folder.
-func KubeadmUpgrade(c *status.Cluster, upgradeVersion *K8sVersion.Version, patchesDir string, featureGate string, wait time.Duration, vLevel int) (err error) {
-	if upgradeVersion == nil {
+//
+// If node selectors are given, only the selected nodes are upgraded, e.g. for testing version skew
+// scenarios; a warning is printed if the resulting version skew is not supported.
+func KubeadmUpgrade(c *status.Cluster, opts KubeadmUpgradeOptions) (err error) {
+	if opts.UpgradeVersion == nil {
 		return errors.New("kubeadm-upgrade actions requires the --upgrade-version parameter to be set")
 	}
 
-	preloadUpgradeImages(c, upgradeVersion)
-	nodeList := c.K8sNodes().EligibleForActions()
+	if err := validatePatchesDir(c, opts.PatchesDir); err != nil {
+		return err
+	}
+	patchesArgs, err := upgradePatchesArgs(opts.UpgradeVersion, opts.PatchesDir)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := selectK8sNodes(c, opts.NodeSelectors)
+	if err != nil {
+		return err
+	}
+	if len(opts.NodeSelectors) > 0 {
+		if err := checkPartialUpgrade(c, nodeList, opts.UpgradeVersion); err != nil {
+			return err
+		}
+	}
+
+	// kubeadm does not support downgrades, so the upgrade version is checked against all the nodes
+	// before upgrading any of them
+	var versions []nodeVersion
+	for _, n := range nodeList {
+		kubeadmVersion, err := n.KubeadmVersion()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the kubeadm version for node %s", n.Name())
+		}
+		versions = append(versions, nodeVersion{name: n.Name(), controlPlane: n.IsControlPlane(), version: kubeadmVersion})
+	}
+	if err := checkDowngrade(versions, opts.UpgradeVersion); err != nil {
+		return err
+	}
+
+	preloadUpgradeImages(c, opts.UpgradeVersion)
 
 	for _, n := range nodeList {
-		if err := copyPatchesToNode(n, patchesDir); err != nil {
+		if err := copyPatchesToNode(n, opts.PatchesDir); err != nil {
+			return err
+		}
+
+		if err := upgradeKubeadmBinary(n, opts.UpgradeVersion); err != nil {
 			return err
 		}
 
-		if err := upgradeKubeadmBinary(n, upgradeVersion); err != nil {
+		// fails before upgrading the node if the images for the upgrade version are not available, so the
+		// node is not left half upgraded
+		if err := checkUpgradeImages(n, opts.UpgradeVersion); err != nil {
 			return err
 		}
 
 		if n.Name() == c.BootstrapControlPlane().Name() {
-			if err := kubeadmUpgradePlan(c, n, upgradeVersion, featureGate, vLevel); err != nil {
+			// if requested, checks the kubeadm config is still valid for the upgraded kubeadm binary
+			if opts.ValidateConfig {
+				if err := validateKubeadmConfig(n); err != nil {
+					return err
+				}
+			}
+			if err := kubeadmUpgradePlan(c, n, opts.UpgradeVersion, opts.FeatureGate, opts.VLevel); err != nil {
 				return err
 			}
-			err = kubeadmUpgradeApply(c, n, upgradeVersion, patchesDir, featureGate, wait, vLevel)
+			err = kubeadmUpgradeApply(c, n, opts.UpgradeVersion, patchesArgs, opts.FeatureGate, opts.Wait, opts.VLevel)
 		} else {
-			err = kubeadmUpgradeNode(c, n, upgradeVersion, patchesDir, wait, vLevel)
+			err = kubeadmUpgradeNode(c, n, opts.UpgradeVersion, patchesArgs, opts.Wait, opts.VLevel)
 		}
 		if err != nil {
 			return err
@@ -66,14 +136,200 @@ func KubeadmUpgrade(c *status.Cluster, upgradeVersion *K8sVersion.Version, patch
 	}
 
 	for _, n := range nodeList {
-		if err := upgradeKubeletKubectl(c, n, upgradeVersion, wait); err != nil {
+		if opts.Drain.Enabled {
+			if err := drainNode(c, n, opts.Drain, opts.Wait); err != nil {
+				return err
+			}
+		}
+		if err := upgradeKubeletKubectl(c, n, opts.UpgradeVersion, opts.Wait); err != nil {
 			return err
 		}
+		if opts.Drain.Enabled {
+			if err := uncordonNode(c, n); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// selectK8sNodes returns the K8s nodes eligible for actions matching at least one of the node selectors,
+// in provisioning order; all the K8s nodes eligible for actions are returned if there are no node selectors
+func selectK8sNodes(c *status.Cluster, nodeSelectors []string) (status.NodeList, error) {
+	if len(nodeSelectors) == 0 {
+		return c.K8sNodes().EligibleForActions(), nil
+	}
+
+	selected := map[string]bool{}
+	for _, s := range nodeSelectors {
+		nodes, err := c.SelectNodes(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if !n.IsControlPlane() && !n.IsWorker() {
+				return nil, errors.Errorf("the node selector %q matches node %s, that is not a K8s node", s, n.Name())
+			}
+			selected[n.Name()] = true
+		}
+	}
+
+	var nodeList status.NodeList
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		if selected[n.Name()] {
+			nodeList = append(nodeList, n)
+		}
+	}
+	if len(nodeList) == 0 {
+		return nil, errors.Errorf("no nodes eligible for actions match %v", nodeSelectors)
+	}
+	return nodeList, nil
+}
+
+// checkPartialUpgrade checks that the bootstrap control-plane, where kubeadm upgrade apply is executed,
+// is upgraded before any other node, and prints a warning if the version skew between the control-plane
+// and the kubelets after the upgrade is not supported
+func checkPartialUpgrade(c *status.Cluster, nodeList status.NodeList, upgradeVersion *K8sVersion.Version) error {
+	selected := map[string]bool{}
+	for _, n := range nodeList {
+		selected[n.Name()] = true
+	}
+
+	var versions []nodeVersion
+	for _, n := range c.K8sNodes() {
+		v := upgradeVersion
+		if !selected[n.Name()] {
+			kubeVersion, err := n.KubeVersion()
+			if err != nil {
+				return errors.Wrapf(err, "failed to get the Kubernetes version for node %s", n.Name())
+			}
+			if v, err = K8sVersion.ParseSemantic(kubeVersion); err != nil {
+				return errors.Wrapf(err, "failed to parse the Kubernetes version for node %s", n.Name())
+			}
+			if n.Name() == c.BootstrapControlPlane().Name() && v.String() != upgradeVersion.String() {
+				return errors.Errorf("the bootstrap control-plane node %s must be upgraded before the other nodes, because it is where kubeadm upgrade apply is executed", n.Name())
+			}
+		}
+		versions = append(versions, nodeVersion{name: n.Name(), controlPlane: n.IsControlPlane(), version: v})
+	}
+
+	for _, w := range versionSkewWarnings(versions) {
+		log.Warnf("unsupported version skew after the upgrade: %s", w)
+	}
+	return nil
+}
+
+// checkDowngrade checks that the upgrade version is not older than the kubeadm version of any node; nodes
+// already on the upgrade version are accepted, so the action can be re-run after a partial upgrade
+func checkDowngrade(nodes []nodeVersion, upgradeVersion *K8sVersion.Version) error {
+	for _, n := range nodes {
+		if upgradeVersion.LessThan(n.version) {
+			return errors.Errorf("the upgrade version v%s is older than the kubeadm version v%s on node %s, and kubeadm does not support downgrades", upgradeVersion, n.version, n.name)
+		}
+	}
+	return nil
+}
+
+// upgradePatchesArgs returns the kubeadm upgrade args for using the patches copied to the nodes, if a patches
+// directory is defined; patches are supported since kubeadm v1.19, with the --experimental-patches flag renamed
+// to --patches in v1.22
+func upgradePatchesArgs(upgradeVersion *K8sVersion.Version, patchesDir string) ([]string, error) {
+	if patchesDir == "" {
+		return nil, nil
+	}
+	if upgradeVersion.LessThan(K8sVersion.MustParseSemantic("v1.19.0-0")) {
+		return nil, errors.Errorf("patches are not supported by kubeadm v%s; use kubeadm v1.19 or newer", upgradeVersion)
 	}
+	if upgradeVersion.LessThan(K8sVersion.MustParseSemantic("v1.22.0-0")) {
+		return []string{fmt.Sprintf("--experimental-patches=%s", constants.PatchesDir)}, nil
+	}
+	return []string{fmt.Sprintf("--patches=%s", constants.PatchesDir)}, nil
+}
 
+// checkUpgradeImages checks that all the images required for the upgrade version are pre-loaded on the node,
+// for the node architecture
+func checkUpgradeImages(n *status.Node, upgradeVersion *K8sVersion.Version) error {
+	missing, wrongArch, err := checkNodeImagesForVersion(n, upgradeVersion.String())
+	if err != nil {
+		return errors.Wrapf(err, "failed to check the images for the upgrade on node %s", n.Name())
+	}
+	if len(missing) > 0 || len(wrongArch) > 0 {
+		return errors.Errorf("the images required for upgrading node %s to v%s are not pre-loaded for the node architecture: %s",
+			n.Name(), upgradeVersion, strings.Join(append(missing, wrongArch...), ", "))
+	}
 	return nil
 }
 
+// nodeVersion defines the Kubernetes version of a node, used for checking the version skew
+type nodeVersion struct {
+	name         string
+	controlPlane bool
+	version      *K8sVersion.Version
+}
+
+// maxKubeletSkew defines the maximum number of minor versions the kubelet can be older than the API server
+const maxKubeletSkew = 3
+
+// versionSkewWarnings returns the violations of the Kubernetes version skew policy after an upgrade, as reported
+// by apiServerSkewViolations; each control-plane node hosts an API server and each node hosts a kubelet, all with
+// the node version
+func versionSkewWarnings(nodes []nodeVersion) []string {
+	var apiServers []nodeVersion
+	for _, n := range nodes {
+		if n.controlPlane {
+			apiServers = append(apiServers, n)
+		}
+	}
+	return apiServerSkewViolations(apiServers, nodes)
+}
+
+// apiServerSkewViolations returns the violations of the Kubernetes version skew policy between API servers and
+// kubelets, that is API servers within one minor version of each other, and kubelets not newer than the oldest
+// API server and not older than maxKubeletSkew minor versions
+func apiServerSkewViolations(apiServers, kubelets []nodeVersion) []string {
+	oldest, newest := apiServerRange(apiServers)
+	if oldest == nil {
+		return nil
+	}
+
+	var violations []string
+	if minorOf(newest.version)-minorOf(oldest.version) > 1 {
+		violations = append(violations, fmt.Sprintf("the API server on node %s (v%s) is more than one minor version newer than the API server on node %s (v%s)",
+			newest.name, newest.version, oldest.name, oldest.version))
+	}
+	for _, k := range kubelets {
+		switch skew := minorOf(oldest.version) - minorOf(k.version); {
+		case skew < 0:
+			violations = append(violations, fmt.Sprintf("the kubelet on node %s (v%s) is newer than the API server on node %s (v%s)",
+				k.name, k.version, oldest.name, oldest.version))
+		case skew > maxKubeletSkew:
+			violations = append(violations, fmt.Sprintf("the kubelet on node %s (v%s) is more than %d minor versions older than the API server on node %s (v%s)",
+				k.name, k.version, maxKubeletSkew, oldest.name, oldest.version))
+		}
+	}
+	return violations
+}
+
+// apiServerRange returns the oldest and the newest API servers, or nil if there are no API servers
+func apiServerRange(apiServers []nodeVersion) (oldest, newest *nodeVersion) {
+	for i := range apiServers {
+		a := &apiServers[i]
+		if oldest == nil || minorOf(a.version) < minorOf(oldest.version) {
+			oldest = a
+		}
+		if newest == nil || minorOf(a.version) > minorOf(newest.version) {
+			newest = a
+		}
+	}
+	return oldest, newest
+}
+
+// minorOf returns a comparable index of the major and minor version
+func minorOf(v *K8sVersion.Version) int {
+	return int(v.Major())*1000 + int(v.Minor())
+}
+
 func preloadUpgradeImages(c *status.Cluster, upgradeVersion *K8sVersion.Version) {
 	srcFolder := filepath.Join("/kinder", "upgrade", fmt.Sprintf("v%s", upgradeVersion))
 
@@ -85,24 +341,24 @@ func preloadUpgradeImages(c *status.Cluster, upgradeVersion *K8sVersion.Version)
 		n.Infof("pre-loading images required for the upgrade")
 		nodeCRI, err := n.CRI()
 		if err != nil {
-			fmt.Printf("error detecting CRI: %v", err)
+			exec.Printf("error detecting CRI: %v", err)
 			continue
 		}
 
 		actionHelper, err := nodes.NewActionHelper(nodeCRI)
 		if err != nil {
-			fmt.Printf("error creating the action helper: %v", err)
+			exec.Printf("error creating the action helper: %v", err)
 			continue
 		}
 
 		if err := actionHelper.PreLoadUpgradeImages(n, srcFolder); err != nil {
-			fmt.Printf("error PreLoadUpgradeImages: %v", err)
+			exec.Printf("error PreLoadUpgradeImages: %v", err)
 			continue
 		}
 
 		// checks pre-loaded images available on the node (this will report missing images, if any)
 		if err := checkImagesForVersion(n, upgradeVersion.String()); err != nil {
-			fmt.Printf("error ReportImages: %v", err)
+			exec.Printf("error ReportImages: %v", err)
 			continue
 		}
 	}
@@ -140,13 +396,11 @@ func kubeadmUpgradePlan(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8s
 	return nil
 }
 
-func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8sVersion.Version, patchesDir string, featureGate string, wait time.Duration, vLevel int) error {
+func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8sVersion.Version, patchesArgs []string, featureGate string, wait time.Duration, vLevel int) error {
 	applyArgs := []string{
 		"upgrade", "apply", "-f", fmt.Sprintf("v%s", upgradeVersion), fmt.Sprintf("--v=%d", vLevel),
 	}
-	if patchesDir != "" {
-		applyArgs = append(applyArgs, "--patches", constants.PatchesDir)
-	}
+	applyArgs = append(applyArgs, patchesArgs...)
 	if len(featureGate) > 0 {
 		applyArgs = append(applyArgs, fmt.Sprintf("--feature-gates=%s", featureGate))
 	}
@@ -156,6 +410,12 @@ func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8
 		return err
 	}
 
+	if c.Settings.SkipKubeProxy {
+		if err := removeKubeProxy(cp1); err != nil {
+			return err
+		}
+	}
+
 	if err := waitControlPlaneUpgraded(c, cp1, upgradeVersion, wait); err != nil {
 		return err
 	}
@@ -163,7 +423,24 @@ func kubeadmUpgradeApply(c *status.Cluster, cp1 *status.Node, upgradeVersion *K8
 	return nil
 }
 
-func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, patchesDir string, wait time.Duration, vLevel int) error {
+// removeKubeProxy ensures the kube-proxy addon is not reintroduced by kubeadm upgrade apply in clusters
+// created without kube-proxy; kubeadm skips the addon upgrade if the kube-proxy ConfigMap does not exist,
+// but removing leftovers makes this independent from the kubeadm version in use.
+func removeKubeProxy(cp1 *status.Node) error {
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "-n=kube-system",
+		"delete", "daemonset/kube-proxy", "configmap/kube-proxy", "--ignore-not-found",
+	).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrap(err, "failed to remove the kube-proxy addon")
+	}
+	if len(lines) > 0 {
+		log.Warnf("The kube-proxy addon was reintroduced by the upgrade in a cluster created without kube-proxy, and it was removed")
+	}
+	return nil
+}
+
+func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVersion.Version, patchesArgs []string, wait time.Duration, vLevel int) error {
 	// waitKubeletHasRBAC waits for the kubelet to have access to the expected config map
 	// please note that this is a temporary workaround for a problem we are observing on upgrades while
 	// executing node upgrades immediately after control-plane upgrade.
@@ -175,9 +452,7 @@ func kubeadmUpgradeNode(c *status.Cluster, n *status.Node, upgradeVersion *K8sVe
 	nodeArgs := []string{
 		"upgrade", "node", fmt.Sprintf("--v=%d", vLevel),
 	}
-	if patchesDir != "" {
-		nodeArgs = append(nodeArgs, fmt.Sprintf("--patches=%s", constants.PatchesDir))
-	}
+	nodeArgs = append(nodeArgs, patchesArgs...)
 	if err := n.Command(
 		"kubeadm", nodeArgs...,
 	).RunWithEcho(); err != nil {
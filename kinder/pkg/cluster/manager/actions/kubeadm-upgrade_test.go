@@ -0,0 +1,168 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestCheckDowngrade(t *testing.T) {
+	node := func(name, version string) nodeVersion {
+		return nodeVersion{name: name, version: K8sVersion.MustParseSemantic(version)}
+	}
+
+	tests := []struct {
+		name           string
+		nodes          []nodeVersion
+		upgradeVersion string
+		expectedError  bool
+	}{
+		{
+			name:           "upgrade to the next minor",
+			nodes:          []nodeVersion{node("cp1", "v1.29.3"), node("w1", "v1.29.3")},
+			upgradeVersion: "v1.30.0",
+		},
+		{
+			name:           "re-run after a partial upgrade",
+			nodes:          []nodeVersion{node("cp1", "v1.30.0"), node("w1", "v1.29.3")},
+			upgradeVersion: "v1.30.0",
+		},
+		{
+			name:           "downgrade of one node",
+			nodes:          []nodeVersion{node("cp1", "v1.30.0"), node("w1", "v1.30.1")},
+			upgradeVersion: "v1.30.0",
+			expectedError:  true,
+		},
+		{
+			name:           "upgrade to a pre-release of the current version",
+			nodes:          []nodeVersion{node("cp1", "v1.30.0")},
+			upgradeVersion: "v1.30.0-rc.1",
+			expectedError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkDowngrade(test.nodes, K8sVersion.MustParseSemantic(test.upgradeVersion))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestVersionSkewWarnings(t *testing.T) {
+	cp := func(name, version string) nodeVersion {
+		return nodeVersion{name: name, controlPlane: true, version: K8sVersion.MustParseSemantic(version)}
+	}
+	w := func(name, version string) nodeVersion {
+		return nodeVersion{name: name, version: K8sVersion.MustParseSemantic(version)}
+	}
+
+	tests := []struct {
+		name     string
+		nodes    []nodeVersion
+		expected int
+	}{
+		{
+			name:  "all nodes on the same version",
+			nodes: []nodeVersion{cp("cp1", "v1.30.0"), cp("cp2", "v1.30.0"), w("w1", "v1.30.0")},
+		},
+		{
+			name:  "cp1 upgraded, the others on the previous minor",
+			nodes: []nodeVersion{cp("cp1", "v1.30.0"), cp("cp2", "v1.29.3"), w("w1", "v1.29.3")},
+			// the kubelet on cp1 is newer than the API server on cp2
+			expected: 1,
+		},
+		{
+			name:  "control-plane upgraded, workers three minors older",
+			nodes: []nodeVersion{cp("cp1", "v1.30.0"), cp("cp2", "v1.30.0"), w("w1", "v1.27.0")},
+		},
+		{
+			name:     "workers four minors older",
+			nodes:    []nodeVersion{cp("cp1", "v1.30.0"), w("w1", "v1.26.0")},
+			expected: 1,
+		},
+		{
+			name:  "API servers two minors apart",
+			nodes: []nodeVersion{cp("cp1", "v1.30.0"), cp("cp2", "v1.28.0")},
+			// the API server skew, and the kubelet on cp1 newer than the API server on cp2
+			expected: 2,
+		},
+		{
+			name:  "patch upgrade",
+			nodes: []nodeVersion{cp("cp1", "v1.30.1"), cp("cp2", "v1.30.0"), w("w1", "v1.30.0")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings := versionSkewWarnings(test.nodes)
+			if len(warnings) != test.expected {
+				t.Errorf("expected %d warnings, got %d: %v", test.expected, len(warnings), warnings)
+			}
+		})
+	}
+}
+
+func TestUpgradePatchesArgs(t *testing.T) {
+	tests := []struct {
+		name           string
+		upgradeVersion string
+		patchesDir     string
+		expected       []string
+		expectedError  bool
+	}{
+		{
+			name:           "no patches",
+			upgradeVersion: "v1.18.0",
+		},
+		{
+			name:           "patches not supported",
+			upgradeVersion: "v1.18.5",
+			patchesDir:     "/tmp/patches",
+			expectedError:  true,
+		},
+		{
+			name:           "experimental patches",
+			upgradeVersion: "v1.19.0-rc.1",
+			patchesDir:     "/tmp/patches",
+			expected:       []string{"--experimental-patches=/kinder/patches"},
+		},
+		{
+			name:           "patches",
+			upgradeVersion: "v1.22.0",
+			patchesDir:     "/tmp/patches",
+			expected:       []string{"--patches=/kinder/patches"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := upgradePatchesArgs(K8sVersion.MustParseSemantic(test.upgradeVersion), test.patchesDir)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v", test.expectedError, err)
+			}
+			if !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("expected %v, found %v", test.expected, args)
+			}
+		})
+	}
+}
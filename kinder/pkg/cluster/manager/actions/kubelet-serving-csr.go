@@ -0,0 +1,226 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+// kubeletServingSigner is the signer of the kubelet serving certificates; differently from the kubelet client
+// certificates, the certificate signing requests for this signer are not approved by the controller manager
+const kubeletServingSigner = "kubernetes.io/kubelet-serving"
+
+// kubeletServingCSR defines a certificate signing request for a kubelet serving certificate
+type kubeletServingCSR struct {
+	name     string
+	node     string
+	approved bool
+	denied   bool
+}
+
+// kubeletServingCSRWatchInterval defines how often the certificate signing requests are checked when watching
+// for the requests created by the kubelet serving certificate rotation
+const kubeletServingCSRWatchInterval = 10 * time.Second
+
+// KubeletServingCSRApprove action approves the pending certificate signing requests for the kubelet serving
+// certificates of the K8s nodes, and waits for each node to have an approved request; this is required when
+// serverTLSBootstrap is enabled in the KubeletConfiguration, e.g. by the kubelet-serving-cert-rotation create flag,
+// both after the kubelet starts and every time the kubelet rotates the serving certificate.
+// If watch is set, the action then keeps approving the requests created by the certificate rotation until each
+// kubelet rotated its serving certificate at least once, as derived from the cluster signing duration.
+func KubeletServingCSRApprove(c *status.Cluster, watch bool, wait time.Duration) error {
+	nodes := c.K8sNodes().EligibleForActions()
+	if err := approveKubeletServingCSRs(c, nodes, wait); err != nil {
+		return err
+	}
+	if !watch {
+		return nil
+	}
+
+	extraArgs, err := c.BootstrapControlPlane().ControlPlaneExtraArgs()
+	if err != nil {
+		return err
+	}
+	window, err := kubeletServingRotationWindow(extraArgs)
+	if err != nil {
+		return err
+	}
+	return watchKubeletServingCSRs(c, window)
+}
+
+// kubeletServingRotationWindow returns how long it takes for the kubelet to rotate a serving certificate signed
+// by the controller manager, given the controller manager extra args defined at create time; the kubelet rotates
+// the certificate at 70-90% of the certificate duration, that is the cluster signing duration, so one more minute
+// is allowed for the kubelet to create the certificate signing request.
+func kubeletServingRotationWindow(extraArgs map[string][]string) (time.Duration, error) {
+	for _, v := range extraArgs[kubeadm.ControllerManagerComponent] {
+		if !strings.HasPrefix(v, kubeadm.ClusterSigningDurationArg+"=") {
+			continue
+		}
+		duration, err := time.ParseDuration(strings.TrimPrefix(v, kubeadm.ClusterSigningDurationArg+"="))
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid %s controller manager extra arg", kubeadm.ClusterSigningDurationArg)
+		}
+		return duration*9/10 + time.Minute, nil
+	}
+	return 0, errors.New("the cluster signing duration is not set, so the kubelet serving certificates are not rotated within a test. Use --cluster-signing-duration when creating the cluster")
+}
+
+// watchKubeletServingCSRs approves the certificate signing requests for the kubelet serving certificates created
+// during the given time window, e.g. when the kubelet rotates the serving certificate
+func watchKubeletServingCSRs(c *status.Cluster, window time.Duration) error {
+	cp1 := c.BootstrapControlPlane()
+
+	cp1.Infof("approving the kubelet serving certificate signing requests created in the next %s", window)
+	approved := 0
+	deadline := time.Now().Add(window)
+	for time.Now().Before(deadline) {
+		time.Sleep(kubeletServingCSRWatchInterval)
+		csrs, err := listKubeletServingCSRs(cp1)
+		if err != nil {
+			return err
+		}
+		n, err := approvePendingKubeletServingCSRs(cp1, csrs)
+		approved += n
+		if err != nil {
+			return err
+		}
+	}
+	exec.Printf("Approved %d certificate signing requests for rotated kubelet serving certificates\n", approved)
+	return nil
+}
+
+// approvePendingKubeletServingCSRs approves the given certificate signing requests not yet approved or denied,
+// and returns the number of requests approved
+func approvePendingKubeletServingCSRs(cp *status.Node, csrs []*kubeletServingCSR) (int, error) {
+	approved := 0
+	for _, csr := range csrs {
+		if csr.approved || csr.denied {
+			continue
+		}
+		if err := cp.Command(
+			"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "certificate", "approve", csr.name,
+		).Silent().Run(); err != nil {
+			return approved, errors.Wrapf(err, "failed to approve the certificate signing request %s", csr.name)
+		}
+		exec.Printf("Approved the certificate signing request %s for node %s\n", csr.name, csr.node)
+		csr.approved = true
+		approved++
+	}
+	return approved, nil
+}
+
+// approveKubeletServingCSRs approves the pending certificate signing requests for the kubelet serving certificates
+// of the given nodes, until each node has an approved request
+func approveKubeletServingCSRs(c *status.Cluster, nodes status.NodeList, wait time.Duration) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	cp1 := c.BootstrapControlPlane()
+	nodeNames := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		nodeNames = append(nodeNames, n.KubeNodeName())
+	}
+
+	cp1.Infof("approving the kubelet serving certificate signing requests for %d nodes (timeout %s)", len(nodes), wait)
+	if pass := waitFor(c, cp1, wait,
+		func(c *status.Cluster, n *status.Node) bool {
+			csrs, err := listKubeletServingCSRs(n)
+			if err != nil {
+				return false
+			}
+			if _, err := approvePendingKubeletServingCSRs(n, csrs); err != nil {
+				return false
+			}
+			return len(nodesWithoutApprovedCSR(nodeNames, csrs)) == 0
+		},
+	); !pass {
+		var pending []string
+		if csrs, err := listKubeletServingCSRs(cp1); err == nil {
+			pending = nodesWithoutApprovedCSR(nodeNames, csrs)
+		}
+		return timeoutErrorf("the kubelet did not request a serving certificate on nodes %s. Check serverTLSBootstrap is enabled in the KubeletConfiguration", strings.Join(pending, ", "))
+	}
+	exec.Println()
+	return nil
+}
+
+// listKubeletServingCSRs returns the certificate signing requests for the kubelet serving certificates
+func listKubeletServingCSRs(cp *status.Node) ([]*kubeletServingCSR, error) {
+	lines, err := cp.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "get", "csr",
+		`-o=jsonpath={range .items[*]}{.metadata.name} {.spec.signerName} {.spec.username} {.status.conditions[*].type}{"\n"}{end}`,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the certificate signing requests")
+	}
+	return parseKubeletServingCSRs(lines), nil
+}
+
+// parseKubeletServingCSRs parses the certificate signing requests, one for each line with the request name, the signer
+// name, the requesting user and the condition types, and returns the ones for the kubelet serving certificates
+func parseKubeletServingCSRs(lines []string) []*kubeletServingCSR {
+	var csrs []*kubeletServingCSR
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != kubeletServingSigner || !strings.HasPrefix(fields[2], "system:node:") {
+			continue
+		}
+		csr := &kubeletServingCSR{
+			name: fields[0],
+			node: strings.TrimPrefix(fields[2], "system:node:"),
+		}
+		for _, condition := range fields[3:] {
+			switch condition {
+			case "Approved":
+				csr.approved = true
+			case "Denied", "Failed":
+				csr.denied = true
+			}
+		}
+		csrs = append(csrs, csr)
+	}
+	return csrs
+}
+
+// nodesWithoutApprovedCSR returns the Kubernetes node names without an approved certificate signing request for
+// the kubelet serving certificate, sorted
+func nodesWithoutApprovedCSR(nodeNames []string, csrs []*kubeletServingCSR) []string {
+	approved := map[string]bool{}
+	for _, csr := range csrs {
+		if csr.approved {
+			approved[csr.node] = true
+		}
+	}
+
+	var pending []string
+	for _, n := range nodeNames {
+		if !approved[n] {
+			pending = append(pending, n)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
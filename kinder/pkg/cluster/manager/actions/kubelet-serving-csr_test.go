@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseKubeletServingCSRs(t *testing.T) {
+	lines := []string{
+		"csr-1 kubernetes.io/kube-apiserver-client-kubelet system:bootstrap:abcdef Approved",
+		"csr-2 kubernetes.io/kubelet-serving system:node:kind-control-plane Approved",
+		"csr-3 kubernetes.io/kubelet-serving system:node:kind-worker",
+		"csr-4 kubernetes.io/kubelet-serving system:node:kind-worker2 Denied",
+		"csr-5 kubernetes.io/kubelet-serving system:serviceaccount:default:test",
+		"",
+	}
+
+	csrs := parseKubeletServingCSRs(lines)
+	expected := []*kubeletServingCSR{
+		{name: "csr-2", node: "kind-control-plane", approved: true},
+		{name: "csr-3", node: "kind-worker"},
+		{name: "csr-4", node: "kind-worker2", denied: true},
+	}
+	if !reflect.DeepEqual(csrs, expected) {
+		t.Fatalf("unexpected certificate signing requests: %+v", csrs)
+	}
+
+	pending := nodesWithoutApprovedCSR([]string{"kind-worker2", "kind-control-plane", "kind-worker"}, csrs)
+	if !reflect.DeepEqual(pending, []string{"kind-worker", "kind-worker2"}) {
+		t.Fatalf("unexpected nodes without an approved certificate signing request: %v", pending)
+	}
+}
+
+func TestKubeletServingRotationWindow(t *testing.T) {
+	tests := []struct {
+		name           string
+		extraArgs      map[string][]string
+		expectedWindow time.Duration
+		expectedError  bool
+	}{
+		{
+			name:           "cluster signing duration set",
+			extraArgs:      map[string][]string{"controllerManager": {"v=2", "cluster-signing-duration=10m0s"}},
+			expectedWindow: 10 * time.Minute,
+		},
+		{
+			name:          "cluster signing duration not set",
+			extraArgs:     map[string][]string{"apiServer": {"cluster-signing-duration=10m"}},
+			expectedError: true,
+		},
+		{
+			name:          "invalid cluster signing duration",
+			extraArgs:     map[string][]string{"controllerManager": {"cluster-signing-duration=10"}},
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			window, err := kubeletServingRotationWindow(rt.extraArgs)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error %t, got %v", rt.expectedError, err)
+			}
+			if window != rt.expectedWindow {
+				t.Errorf("expected window %s, got %s", rt.expectedWindow, window)
+			}
+		})
+	}
+}
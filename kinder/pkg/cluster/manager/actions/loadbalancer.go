@@ -18,6 +18,9 @@ package actions
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -25,6 +28,7 @@ import (
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri/host"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 )
 
@@ -52,15 +56,25 @@ func LoadBalancer(c *status.Cluster, nodes ...*status.Node) error {
 			return errors.Wrapf(err, "failed to get IP for node %s", n.Name())
 		}
 		if controlPlaneIPv4 != "" && !ipv6 {
-			backendServers[n.Name()] = fmt.Sprintf("%s:%d", controlPlaneIPv4, constants.APIServerPort)
+			backendServers[n.Name()] = fmt.Sprintf("%s:%d", controlPlaneIPv4, c.APIServerBindPort())
 		}
 		if controlPlaneIPv6 != "" && ipv6 {
-			backendServers[n.Name()] = fmt.Sprintf("[%s]:%d", controlPlaneIPv6, constants.APIServerPort)
+			backendServers[n.Name()] = fmt.Sprintf("[%s]:%d", controlPlaneIPv6, c.APIServerBindPort())
 		}
 	}
 
+	// get the config generator for the load balancer implementation selected at create time
+	implementation, err := c.LoadBalancerImplementation()
+	if err != nil {
+		return err
+	}
+	provider, err := loadbalancer.NewProvider(implementation)
+	if err != nil {
+		return err
+	}
+
 	// create loadbalancer config data
-	loadbalancerConfig, err := loadbalancer.Config(&loadbalancer.ConfigData{
+	loadbalancerConfig, err := provider.Config(&loadbalancer.ConfigData{
 		ControlPlanePort: constants.ControlPlanePort,
 		BackendServers:   backendServers,
 		IPv6:             ipv6,
@@ -70,16 +84,95 @@ func LoadBalancer(c *status.Cluster, nodes ...*status.Node) error {
 	}
 
 	// create loadbalancer config on the node
-	log.Debugf("Writing loadbalancer config on %s...", lb.Name())
+	log.Debugf("Writing %s loadbalancer config on %s...", implementation, lb.Name())
 
-	if err := lb.WriteFile(constants.LoadBalancerConfigPath, []byte(loadbalancerConfig)); err != nil {
+	if err := lb.WriteFile(provider.ConfigPath(), []byte(loadbalancerConfig)); err != nil {
 		return errors.Wrap(err, "failed to copy loadbalancer config to node")
 	}
 
-	// reload the config
-	if err := host.SendSignal("SIGHUP", lb.Name()); err != nil {
-		return errors.Wrap(err, "failed to reload loadbalancer")
+	// validate the config before reloading, so an invalid config can't take the load balancer down
+	validate := provider.ValidateCommand()
+	if err := lb.Command(validate[0], validate[1:]...).Silent().Run(); err != nil {
+		return errors.Wrap(err, "invalid loadbalancer config")
 	}
 
+	return reloadLoadBalancer(c, lb, provider, backendServers)
+}
+
+const (
+	// loadBalancerReloadTimeout is the time to wait for a graceful reload to be reflected in the load balancer stats
+	loadBalancerReloadTimeout = 10 * time.Second
+	// loadBalancerRestartTimeout is the time to wait for the load balancer to stabilize after a restart
+	loadBalancerRestartTimeout = 30 * time.Second
+)
+
+// reloadLoadBalancer gracefully reloads the load balancer config, so existing connections
+// to the control-plane endpoint aren't dropped, and verifies the new backends are in use;
+// if the graceful reload fails or it is not supported by the load balancer implementation, the load balancer
+// is restarted and given time to stabilize.
+func reloadLoadBalancer(c *status.Cluster, lb *status.Node, provider loadbalancer.Provider, backendServers map[string]string) error {
+	if provider.GracefulReload() {
+		if err := host.SendSignal("SIGHUP", lb.Name()); err == nil {
+			if waitFor(c, lb, loadBalancerReloadTimeout, loadBalancerHasBackends(backendServers)) {
+				return nil
+			}
+			log.Warnf("The load balancer on %s did not pick up the new config after a graceful reload, restarting it", lb.Name())
+		} else {
+			log.Warnf("Failed to gracefully reload the load balancer on %s, restarting it: %v", lb.Name(), err)
+		}
+	}
+
+	if err := host.RestartContainer(lb.Name()); err != nil {
+		return errors.Wrap(err, "failed to restart loadbalancer")
+	}
+	if !waitFor(c, lb, loadBalancerRestartTimeout, loadBalancerHasBackends(backendServers)) {
+		return errors.Errorf("the load balancer on %s did not stabilize after restart", lb.Name())
+	}
 	return nil
 }
+
+// loadBalancerHasBackends implement a function that test when the load balancer stats report
+// exactly the expected API server backends
+func loadBalancerHasBackends(backendServers map[string]string) try {
+	return func(c *status.Cluster, n *status.Node) bool {
+		backends, err := c.LoadBalancerBackends()
+		if err != nil {
+			return false
+		}
+		return hasBackends(backends, backendServers)
+	}
+}
+
+// hasBackends returns true if the backends reported by the load balancer match the expected backend servers
+func hasBackends(backends []loadbalancer.BackendServer, backendServers map[string]string) bool {
+	if len(backends) != len(backendServers) {
+		return false
+	}
+	for _, b := range backends {
+		if _, ok := backendServers[b.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadBalancerStatus prints the status of the API server backends of the external load balancer, if present,
+// as reported by the load balancer health checks
+func LoadBalancerStatus(c *status.Cluster) error {
+	if c.ExternalLoadBalancer() == nil {
+		exec.Println("The cluster does not have an external load balancer")
+		return nil
+	}
+
+	backends, err := c.LoadBalancerBackends()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKEND\tSTATUS")
+	for _, b := range backends {
+		fmt.Fprintf(w, "%s\t%s\n", b.Name, b.Status)
+	}
+	return w.Flush()
+}
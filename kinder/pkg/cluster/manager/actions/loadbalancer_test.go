@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
+)
+
+func TestHasBackends(t *testing.T) {
+	expected := map[string]string{
+		"kind-control-plane":  "172.17.0.3:6443",
+		"kind-control-plane2": "172.17.0.4:6443",
+	}
+
+	cases := []struct {
+		name     string
+		backends []loadbalancer.BackendServer
+		want     bool
+	}{
+		{
+			name: "all backends reported",
+			backends: []loadbalancer.BackendServer{
+				{Name: "kind-control-plane", Status: "UP"},
+				{Name: "kind-control-plane2", Status: "DOWN"},
+			},
+			want: true,
+		},
+		{
+			name: "old config still in use",
+			backends: []loadbalancer.BackendServer{
+				{Name: "kind-control-plane", Status: "UP"},
+			},
+			want: false,
+		},
+		{
+			name: "unexpected backend",
+			backends: []loadbalancer.BackendServer{
+				{Name: "kind-control-plane", Status: "UP"},
+				{Name: "kind-control-plane3", Status: "UP"},
+			},
+			want: false,
+		},
+		{
+			name:     "no backends",
+			backends: nil,
+			want:     false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := hasBackends(c.backends, expected); got != c.want {
+				t.Errorf("expected %t, got %t", c.want, got)
+			}
+		})
+	}
+}
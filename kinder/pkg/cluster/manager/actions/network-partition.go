@@ -0,0 +1,235 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+const (
+	// partitionChain defines the iptables chain hosting the rules blocking traffic to/from the other nodes
+	partitionChain = "KINDER-PARTITION"
+
+	// partitionStatePath defines the file recording, on a partitioned node, the nodes it is partitioned from;
+	// the file and the iptables rules are in the node container, so they are removed when the cluster is deleted
+	partitionStatePath = "/kinder/partitioned-from"
+)
+
+// NetworkPartition action partitions the nodes matching the node selectors from the other nodes in the cluster,
+// including the load balancer and the external etcd nodes, by inserting iptables rules blocking the traffic
+// to/from the IPs of the other nodes; the nodes eligible for actions are partitioned if there are no node selectors.
+// Nb. traffic between the partitioned nodes is not blocked, so it is possible to test split-brain scenarios.
+func NetworkPartition(c *status.Cluster, nodeSelectors []string) error {
+	partitioned, err := nodesToPartition(c, nodeSelectors)
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]bool{}
+	for _, n := range partitioned {
+		selected[n.Name()] = true
+	}
+
+	var peers status.NodeList
+	var peerIPs []string
+	for _, n := range c.AllNodes() {
+		if selected[n.Name()] {
+			continue
+		}
+		ipv4, ipv6, err := n.IP()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the IP of node %s", n.Name())
+		}
+		peers = append(peers, n)
+		for _, ip := range []string{ipv4, ipv6} {
+			if ip != "" {
+				peerIPs = append(peerIPs, ip)
+			}
+		}
+	}
+	if len(peers) == 0 {
+		return errors.New("there are no other nodes to partition the selected nodes from")
+	}
+
+	for _, n := range partitioned {
+		// removes the rules of a previous partition, if any, so the action can be re-run with different nodes
+		if err := healNode(n); err != nil {
+			return err
+		}
+
+		// the state is recorded before installing the chain, so the network-heal action removes the rules
+		// even if the partition fails halfway
+		n.Infof("partitioning the node from %s", strings.Join(peers.Names(), ", "))
+		if err := n.WriteFile(partitionStatePath, []byte(strings.Join(peers.Names(), "\n")+"\n")); err != nil {
+			return err
+		}
+		for _, args := range partitionRules(peerIPs) {
+			if err := n.Command(args[0], args[1:]...).Silent().Run(); err != nil {
+				return errors.Wrapf(err, "failed to partition node %s. Use the network-heal action to remove the partial partition", n.Name())
+			}
+		}
+	}
+
+	exec.Printf("\nNodes %s partitioned\n", strings.Join(partitioned.Names(), ", "))
+	return nil
+}
+
+// NetworkHeal action removes the partitions created by the network-partition action on the nodes eligible for actions
+func NetworkHeal(c *status.Cluster) error {
+	for _, n := range c.AllNodes().EligibleForActions() {
+		partitionedFrom, err := partitionState(n)
+		if err != nil {
+			return err
+		}
+		if len(partitionedFrom) == 0 {
+			continue
+		}
+
+		n.Infof("healing the partition from %s", strings.Join(partitionedFrom, ", "))
+		if err := healNode(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NetworkPartitionStatus action prints the nodes each node eligible for actions is partitioned from, if any
+func NetworkPartitionStatus(c *status.Cluster) error {
+	for _, n := range c.AllNodes().EligibleForActions() {
+		partitionedFrom, err := partitionState(n)
+		if err != nil {
+			return err
+		}
+		if len(partitionedFrom) == 0 {
+			exec.Printf("%s: not partitioned\n", n.Name())
+			continue
+		}
+		exec.Printf("%s: partitioned from %s\n", n.Name(), strings.Join(partitionedFrom, ", "))
+	}
+	return nil
+}
+
+// nodesToPartition returns the nodes eligible for actions matching at least one of the node selectors;
+// all the nodes eligible for actions are returned if there are no node selectors
+func nodesToPartition(c *status.Cluster, nodeSelectors []string) (status.NodeList, error) {
+	if len(nodeSelectors) == 0 {
+		nodes := c.AllNodes().EligibleForActions()
+		labeled := 0
+		for _, n := range c.AllNodes() {
+			if n.ExcludedFromActions() {
+				labeled++
+			}
+		}
+		if len(nodes) == len(c.AllNodes())-labeled {
+			return nil, errors.New("the network-partition action requires --partition-nodes or --only-node to select the nodes to partition")
+		}
+		return nodes, nil
+	}
+
+	selected := map[string]bool{}
+	for _, s := range nodeSelectors {
+		nodes, err := c.SelectNodes(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			selected[n.Name()] = true
+		}
+	}
+
+	var nodeList status.NodeList
+	for _, n := range c.AllNodes().EligibleForActions() {
+		if selected[n.Name()] {
+			nodeList = append(nodeList, n)
+		}
+	}
+	if len(nodeList) == 0 {
+		return nil, errors.Errorf("no nodes eligible for the partition match %v", nodeSelectors)
+	}
+	return nodeList, nil
+}
+
+// partitionRules returns the commands creating the partition chain, hooking it into the INPUT and OUTPUT
+// chains, and blocking the traffic to/from the given IPs; ip6tables is used for IPv6 addresses
+func partitionRules(ips []string) [][]string {
+	var rules [][]string
+	for _, iptables := range []string{"iptables", "ip6tables"} {
+		var chainRules [][]string
+		for _, ip := range ips {
+			if isIPv6(ip) != (iptables == "ip6tables") {
+				continue
+			}
+			chainRules = append(chainRules,
+				[]string{iptables, "-A", partitionChain, "-s", ip, "-j", "DROP"},
+				[]string{iptables, "-A", partitionChain, "-d", ip, "-j", "DROP"},
+			)
+		}
+		if len(chainRules) == 0 {
+			continue
+		}
+		rules = append(rules,
+			[]string{iptables, "-N", partitionChain},
+			[]string{iptables, "-I", "INPUT", "-j", partitionChain},
+			[]string{iptables, "-I", "OUTPUT", "-j", partitionChain},
+		)
+		rules = append(rules, chainRules...)
+	}
+	return rules
+}
+
+// healNode removes the partition chain and the partition state from a node, if any
+func healNode(n *status.Node) error {
+	const script = `for iptables in iptables ip6tables; do
+  if $iptables -n -L ` + partitionChain + ` >/dev/null 2>&1; then
+    $iptables -D INPUT -j ` + partitionChain + ` || true
+    $iptables -D OUTPUT -j ` + partitionChain + ` || true
+    $iptables -F ` + partitionChain + ` && $iptables -X ` + partitionChain + ` || exit 1
+  fi
+done
+rm -f ` + partitionStatePath
+	if err := n.Command("sh", "-c", script).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to heal the partition of node %s", n.Name())
+	}
+	return nil
+}
+
+// partitionState returns the nodes a node is partitioned from, as recorded by the network-partition action
+func partitionState(n *status.Node) ([]string, error) {
+	lines, err := n.Command(
+		"sh", "-c", fmt.Sprintf("cat %s 2>/dev/null || true", partitionStatePath),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the partition state of node %s", n.Name())
+	}
+	var nodes []string
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			nodes = append(nodes, l)
+		}
+	}
+	return nodes, nil
+}
+
+func isIPv6(ip string) bool {
+	return strings.Contains(ip, ":")
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartitionRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		ips      []string
+		expected [][]string
+	}{
+		{
+			name:     "no IPs",
+			expected: nil,
+		},
+		{
+			name: "IPv4",
+			ips:  []string{"172.17.0.3"},
+			expected: [][]string{
+				{"iptables", "-N", "KINDER-PARTITION"},
+				{"iptables", "-I", "INPUT", "-j", "KINDER-PARTITION"},
+				{"iptables", "-I", "OUTPUT", "-j", "KINDER-PARTITION"},
+				{"iptables", "-A", "KINDER-PARTITION", "-s", "172.17.0.3", "-j", "DROP"},
+				{"iptables", "-A", "KINDER-PARTITION", "-d", "172.17.0.3", "-j", "DROP"},
+			},
+		},
+		{
+			name: "dual-stack",
+			ips:  []string{"172.17.0.3", "fc00:f853:ccd:e793::3"},
+			expected: [][]string{
+				{"iptables", "-N", "KINDER-PARTITION"},
+				{"iptables", "-I", "INPUT", "-j", "KINDER-PARTITION"},
+				{"iptables", "-I", "OUTPUT", "-j", "KINDER-PARTITION"},
+				{"iptables", "-A", "KINDER-PARTITION", "-s", "172.17.0.3", "-j", "DROP"},
+				{"iptables", "-A", "KINDER-PARTITION", "-d", "172.17.0.3", "-j", "DROP"},
+				{"ip6tables", "-N", "KINDER-PARTITION"},
+				{"ip6tables", "-I", "INPUT", "-j", "KINDER-PARTITION"},
+				{"ip6tables", "-I", "OUTPUT", "-j", "KINDER-PARTITION"},
+				{"ip6tables", "-A", "KINDER-PARTITION", "-s", "fc00:f853:ccd:e793::3", "-j", "DROP"},
+				{"ip6tables", "-A", "KINDER-PARTITION", "-d", "fc00:f853:ccd:e793::3", "-j", "DROP"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules := partitionRules(test.ips)
+			if !reflect.DeepEqual(rules, test.expected) {
+				t.Errorf("expected rules %v, got %v", test.expected, rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// podStartupPollInterval is the interval for checking if the measured pod is running; together with the time
+// required for executing kubectl on the control-plane node, it defines the granularity of the measured latency
+const podStartupPollInterval = 100 * time.Millisecond
+
+// PodStartupOptions defines how pod startup latency is measured on a node
+type PodStartupOptions struct {
+	// Image is the image of the measured pod; if empty, the sandbox (pause) image of the node is used
+	Image string
+
+	// PrePull instructs to pull the image on the node before creating the pod, so the image pull time
+	// is not included in the measured latency
+	PrePull bool
+
+	// MaxLatency is the maximum latency accepted; zero disables the check
+	MaxLatency time.Duration
+}
+
+// PodStartupLatency action measures, for each worker node, the wall-clock latency from a pod targeted at the node
+// being created to the pod running. Latencies exceeding the maximum latency, if set, are reported as an error.
+func PodStartupLatency(c *status.Cluster, opts PodStartupOptions, wait time.Duration) error {
+	for _, w := range c.Workers().EligibleForActions() {
+		latency, err := measurePodStartupLatency(c, w, opts, wait)
+		if err != nil {
+			return err
+		}
+		exec.Printf("%s: pod startup latency %s\n", w.Name(), latency)
+	}
+	return nil
+}
+
+// measurePodStartupLatency deploys a pod targeted at a node, and returns the wall-clock time elapsed from the pod
+// being created to the pod being observed in the Running state
+func measurePodStartupLatency(c *status.Cluster, n *status.Node, opts PodStartupOptions, wait time.Duration) (time.Duration, error) {
+	cp1 := c.BootstrapControlPlane()
+
+	if wait == 0 {
+		return 0, errors.New("a wait timeout is required for measuring the pod startup latency")
+	}
+
+	image := opts.Image
+	if image == "" {
+		var err error
+		if image, err = nodeSandboxImage(n); err != nil {
+			return 0, err
+		}
+	}
+
+	if opts.PrePull {
+		n.Infof("pre-pulling image %s", image)
+		if err := n.Command("crictl", "pull", image).Silent().Run(); err != nil {
+			return 0, errors.Wrapf(err, "failed to pull image %s on node %s", image, n.Name())
+		}
+	}
+
+	// cleanups garbage from previous measurements
+	podName := fmt.Sprintf("kinder-pod-startup-%s", n.Name())
+	cleanupPodStartup(cp1, podName)
+	defer cleanupPodStartup(cp1, podName)
+
+	// the pod tolerates any taint, so the latency can be measured also on tainted nodes
+	n.Infof("measuring pod startup latency with image %s", image)
+	start := time.Now()
+	if err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"run", podName, fmt.Sprintf("--image=%s", image), "--restart=Never",
+		fmt.Sprintf(`--overrides={"spec":{"nodeSelector":{"kubernetes.io/hostname":%q},"tolerations":[{"operator":"Exists"}]}}`, n.KubeNodeName()),
+	).Silent().Run(); err != nil {
+		return 0, errors.Wrapf(err, "failed to create pod %s", podName)
+	}
+
+	running := podIsRunning(cp1, podName)
+	for !running(c, n) {
+		if time.Since(start) > wait {
+			return 0, withRecentWarningEvents(c, timeoutErrorf("pod %s did not reach the Running state on node %s", podName, n.Name()))
+		}
+		time.Sleep(podStartupPollInterval)
+	}
+	latency := time.Since(start)
+
+	if opts.MaxLatency > 0 && latency > opts.MaxLatency {
+		return latency, errors.Errorf("pod startup latency on node %s is %s, exceeding the maximum latency %s", n.Name(), latency, opts.MaxLatency)
+	}
+	return latency, nil
+}
+
+// nodeSandboxImage returns the sandbox (pause) image of the container runtime on a node, that is pre-loaded in the
+// node image; the image configured at create time is used, if any, otherwise the image is read from crictl info
+func nodeSandboxImage(n *status.Node) (string, error) {
+	image, err := n.SandboxImage()
+	if err != nil {
+		return "", err
+	}
+	if image != "" {
+		return image, nil
+	}
+
+	lines, err := n.Command("crictl", "info").Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the container runtime info on node %s", n.Name())
+	}
+	image, err = parseCRISandboxImage(strings.Join(lines, "\n"))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to detect the sandbox image on node %s; set the pod image explicitly", n.Name())
+	}
+	return image, nil
+}
+
+// parseCRISandboxImage returns the sandbox image from the output of crictl info
+func parseCRISandboxImage(info string) (string, error) {
+	var parsed struct {
+		Config struct {
+			SandboxImage string `json:"sandboxImage"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(info), &parsed); err != nil {
+		return "", errors.Wrap(err, "invalid crictl info output")
+	}
+	if parsed.Config.SandboxImage == "" {
+		return "", errors.New("the sandbox image is not reported by crictl info")
+	}
+	return parsed.Config.SandboxImage, nil
+}
+
+// podIsRunning implement a function that test when a pod is running
+func podIsRunning(cp1 *status.Node, podName string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		output := kubectlOutput(cp1,
+			"--kubeconfig=/etc/kubernetes/admin.conf",
+			"get", "pod", podName,
+			"-o=jsonpath='{.status.phase}'",
+		)
+		if strings.Contains(output, "Running") {
+			exec.Printf("Pod %s is running\n", podName)
+			return true
+		}
+		return false
+	}
+}
+
+func cleanupPodStartup(cp1 *status.Node, podName string) {
+	cp1.Command(
+		"kubectl",
+		"--kubeconfig=/etc/kubernetes/admin.conf",
+		"delete", "pod", podName, "--ignore-not-found",
+	).Silent().Run()
+}
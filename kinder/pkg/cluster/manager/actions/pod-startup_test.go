@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestParseCRISandboxImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "containerd",
+			info:     `{"status":{"conditions":[]},"config":{"containerd":{"snapshotter":"overlayfs"},"sandboxImage":"registry.k8s.io/pause:3.10"}}`,
+			expected: "registry.k8s.io/pause:3.10",
+		},
+		{
+			name:        "sandbox image not reported",
+			info:        `{"status":{"conditions":[]},"config":{}}`,
+			expectError: true,
+		},
+		{
+			name:        "invalid output",
+			info:        "not json",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image, err := parseCRISandboxImage(test.info)
+			if (err != nil) != test.expectError {
+				t.Fatalf("expected error %t, got %v", test.expectError, err)
+			}
+			if image != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, image)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+// preflightModules defines the kernel modules required by kubeadm and by the CNI plugin
+var preflightModules = []string{
+	"br_netfilter",
+	"overlay",
+}
+
+// preflightSysctls defines the sysctls required by kubeadm, with the expected value
+var preflightSysctls = map[string]string{
+	"net.bridge.bridge-nf-call-iptables": "1",
+	"net.ipv4.ip_forward":                "1",
+}
+
+// Preflight checks that the kernel modules and the sysctls required by kubeadm are set on the nodes,
+// and reports the missing ones for each node; if fix is true, it attempts to load/set them.
+func Preflight(c *status.Cluster, fix bool) error {
+	var failed []string
+	for _, n := range c.K8sNodes().EligibleForActions() {
+		missing, err := preflightNode(n)
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 && fix {
+			n.Infof("fixing %s", strings.Join(missing, ", "))
+			fixPreflightNode(n, missing)
+
+			if missing, err = preflightNode(n); err != nil {
+				return err
+			}
+		}
+
+		if len(missing) > 0 {
+			n.Infof("missing %s", strings.Join(missing, ", "))
+			failed = append(failed, n.Name())
+			continue
+		}
+		n.Infof("all the required kernel modules and sysctls are set")
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("the required kernel modules and sysctls are not set on nodes %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// preflightNode returns the kernel modules and the sysctls required by kubeadm that are not set on a node
+func preflightNode(n *status.Node) ([]string, error) {
+	modules, err := n.Command("cat", "/proc/modules").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read kernel modules on node %s", n.Name())
+	}
+
+	sysctls := map[string]string{}
+	for key := range preflightSysctls {
+		// sysctls could not exist, e.g. bridge sysctls if br_netfilter is not loaded
+		lines, err := n.Command("sysctl", "-n", key).Silent().RunAndCapture()
+		if err == nil && len(lines) == 1 {
+			sysctls[key] = strings.TrimSpace(lines[0])
+		}
+	}
+
+	return missingPreflightRequirements(modules, sysctls), nil
+}
+
+// fixPreflightNode attempts to load the missing kernel modules and to set the missing sysctls on a node;
+// errors are not blocking, because the node is checked again after fixing
+func fixPreflightNode(n *status.Node, missing []string) {
+	for _, m := range missing {
+		if strings.Contains(m, "=") {
+			if err := n.Command("sysctl", "-w", m).Silent().Run(); err != nil {
+				n.Infof("failed to set sysctl %s: %v", m, err)
+			}
+			continue
+		}
+		if err := n.Command("modprobe", m).Silent().Run(); err != nil {
+			n.Infof("failed to load kernel module %s: %v", m, err)
+		}
+	}
+}
+
+// missingPreflightRequirements returns the kernel modules not listed in /proc/modules and the sysctls
+// without the expected value; missing sysctls are returned in the key=value form.
+// Kernel modules are returned first, because some sysctls exist only after loading the module
+func missingPreflightRequirements(procModules []string, sysctls map[string]string) []string {
+	loaded := map[string]bool{}
+	for _, l := range procModules {
+		if fields := strings.Fields(l); len(fields) > 0 {
+			loaded[fields[0]] = true
+		}
+	}
+
+	var missing []string
+	for _, m := range preflightModules {
+		if !loaded[m] {
+			missing = append(missing, m)
+		}
+	}
+
+	var missingSysctls []string
+	for key, value := range preflightSysctls {
+		if sysctls[key] != value {
+			missingSysctls = append(missingSysctls, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	sort.Strings(missingSysctls)
+
+	return append(missing, missingSysctls...)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingPreflightRequirements(t *testing.T) {
+	tests := []struct {
+		name        string
+		procModules []string
+		sysctls     map[string]string
+		expected    []string
+	}{
+		{
+			name: "all set",
+			procModules: []string{
+				"br_netfilter 32768 0 - Live 0x0000000000000000",
+				"overlay 151552 10 - Live 0x0000000000000000",
+			},
+			sysctls: map[string]string{
+				"net.bridge.bridge-nf-call-iptables": "1",
+				"net.ipv4.ip_forward":                "1",
+			},
+		},
+		{
+			name: "br_netfilter not loaded",
+			procModules: []string{
+				"overlay 151552 10 - Live 0x0000000000000000",
+			},
+			sysctls: map[string]string{
+				"net.ipv4.ip_forward": "1",
+			},
+			expected: []string{"br_netfilter", "net.bridge.bridge-nf-call-iptables=1"},
+		},
+		{
+			name: "ip_forward off",
+			procModules: []string{
+				"br_netfilter 32768 0 - Live 0x0000000000000000",
+				"overlay 151552 10 - Live 0x0000000000000000",
+			},
+			sysctls: map[string]string{
+				"net.bridge.bridge-nf-call-iptables": "1",
+				"net.ipv4.ip_forward":                "0",
+			},
+			expected: []string{"net.ipv4.ip_forward=1"},
+		},
+		{
+			name:     "nothing set",
+			expected: []string{"br_netfilter", "overlay", "net.bridge.bridge-nf-call-iptables=1", "net.ipv4.ip_forward=1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			missing := missingPreflightRequirements(test.procModules, test.sysctls)
+			if !reflect.DeepEqual(missing, test.expected) {
+				t.Fatalf("expected missing: %v, found %v", test.expected, missing)
+			}
+		})
+	}
+}
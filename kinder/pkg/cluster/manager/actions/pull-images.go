@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// imagePullResult defines the result of pulling an image on a node
+type imagePullResult struct {
+	image string
+	err   error
+}
+
+// pullReport defines the results of pulling images, by node name
+type pullReport map[string][]imagePullResult
+
+// String returns the report sorted by node name, with one line for each image
+func (r pullReport) String() string {
+	names := make([]string, 0, len(r))
+	for n := range r {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, n := range names {
+		fmt.Fprintf(&b, "%s:\n", n)
+		for _, p := range r[n] {
+			if p.err != nil {
+				fmt.Fprintf(&b, "  %s: failed (%v)\n", p.image, p.err)
+				continue
+			}
+			fmt.Fprintf(&b, "  %s: pulled\n", p.image)
+		}
+	}
+	return b.String()
+}
+
+// failed returns the number of images that failed to pull
+func (r pullReport) failed() int {
+	failed := 0
+	for _, results := range r {
+		for _, p := range results {
+			if p.err != nil {
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// PullImages action pulls a list of images into the container runtime of the K8s nodes matching the node selectors,
+// or of all the K8s nodes eligible for actions if there are no node selectors, e.g. for offline-then-online test flows.
+// If no images are given, the images required by kubeadm for the Kubernetes version installed on each node are pulled,
+// as listed by kubeadm config images list. Nodes are processed concurrently, and a report by node and image is printed.
+func PullImages(c *status.Cluster, images, nodeSelectors []string) error {
+	nodeList, err := selectK8sNodes(c, nodeSelectors)
+	if err != nil {
+		return err
+	}
+	log.Infof("Pulling images on %d nodes", len(nodeList))
+
+	results := make([][]imagePullResult, len(nodeList))
+	errs := make([]error, len(nodeList))
+	var wg sync.WaitGroup
+	for i, n := range nodeList {
+		wg.Add(1)
+		go func(i int, n *status.Node) {
+			defer wg.Done()
+			results[i], errs[i] = pullImages(n, images)
+		}(i, n)
+	}
+	wg.Wait()
+
+	report := pullReport{}
+	for i, n := range nodeList {
+		if errs[i] != nil {
+			return errors.Wrapf(errs[i], "failed to pull images on node %s", n.Name())
+		}
+		report[n.Name()] = results[i]
+	}
+
+	exec.Printf("\nImage pull report:\n%s", report)
+	if failed := report.failed(); failed > 0 {
+		return errors.Errorf("failed to pull %d images", failed)
+	}
+	return nil
+}
+
+// pullImages pulls the given images on a node, or the images required by kubeadm for the Kubernetes version
+// installed on the node if no images are given; failures pulling single images are included in the results
+func pullImages(n *status.Node, images []string) ([]imagePullResult, error) {
+	if len(images) == 0 {
+		version, err := n.KubeVersion()
+		if err != nil {
+			return nil, err
+		}
+		images, err = expectedImagesForVersion(n, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodeCRI, err := n.CRI()
+	if err != nil {
+		return nil, err
+	}
+	actionHelper, err := nodes.NewActionHelper(nodeCRI)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []imagePullResult
+	for _, image := range images {
+		results = append(results, imagePullResult{image: image, err: actionHelper.PullImage(n, image)})
+	}
+	return results, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPullReport(t *testing.T) {
+	report := pullReport{
+		"kind-worker": {
+			{image: "registry.k8s.io/pause:3.9"},
+			{image: "registry.k8s.io/kube-proxy:v1.30.0", err: errors.New("not found")},
+		},
+		"kind-control-plane": {
+			{image: "registry.k8s.io/pause:3.9"},
+		},
+	}
+
+	expected := "kind-control-plane:\n" +
+		"  registry.k8s.io/pause:3.9: pulled\n" +
+		"kind-worker:\n" +
+		"  registry.k8s.io/pause:3.9: pulled\n" +
+		"  registry.k8s.io/kube-proxy:v1.30.0: failed (not found)\n"
+	if report.String() != expected {
+		t.Errorf("expected report:\n%s\nfound:\n%s", expected, report.String())
+	}
+	if failed := report.failed(); failed != 1 {
+		t.Errorf("expected 1 failed image, got %d", failed)
+	}
+}
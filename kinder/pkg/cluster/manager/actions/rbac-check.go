@@ -0,0 +1,191 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+const (
+	// bootstrapTokenGroup is the group of the kubeadm default bootstrap tokens
+	bootstrapTokenGroup = "system:bootstrappers:kubeadm:default-node-token"
+	// nodesGroup is the group of the kubelets
+	nodesGroup = "system:nodes"
+)
+
+var (
+	// kubeadmClusterAdminsMinVersion is the first kubeadm version binding the kubeadm:cluster-admins group
+	// to the cluster-admin ClusterRole, used in admin.conf instead of system:masters
+	kubeadmClusterAdminsMinVersion = K8sVersion.MustParseSemantic("v1.29.0-0")
+	// kubeletConfigUnversionedMinVersion is the first kubeadm version using kubeadm:kubelet-config as a name for
+	// the kubelet-config Role, instead of kubeadm:kubelet-config-<major>.<minor>
+	kubeletConfigUnversionedMinVersion = K8sVersion.MustParseSemantic("v1.24.0-0")
+)
+
+// rbacObject defines a RBAC object expected after kubeadm init; for bindings, the expected role and
+// subjects are defined, and the subjects are in the Kind:Name form
+type rbacObject struct {
+	kind      string
+	namespace string
+	name      string
+	roleRef   string
+	subjects  []string
+}
+
+func (o rbacObject) String() string {
+	if o.namespace != "" {
+		return fmt.Sprintf("%s %s/%s", o.kind, o.namespace, o.name)
+	}
+	return fmt.Sprintf("%s %s", o.kind, o.name)
+}
+
+// expectedRBACObjects returns the RBAC objects created by the API server and by kubeadm init for a kubeadm version,
+// that are required for admin access, for TLS bootstrap and for kubeadm join
+func expectedRBACObjects(kubeadmVersion *K8sVersion.Version) []rbacObject {
+	// the system:masters binding is created by the API server
+	objects := []rbacObject{
+		{kind: "ClusterRoleBinding", name: "cluster-admin", roleRef: "cluster-admin", subjects: []string{"Group:system:masters"}},
+	}
+	if kubeadmVersion.AtLeast(kubeadmClusterAdminsMinVersion) {
+		objects = append(objects,
+			rbacObject{kind: "ClusterRoleBinding", name: "kubeadm:cluster-admins", roleRef: "cluster-admin", subjects: []string{"Group:kubeadm:cluster-admins"}},
+		)
+	}
+
+	kubeletConfig := "kubeadm:kubelet-config"
+	if !kubeadmVersion.AtLeast(kubeletConfigUnversionedMinVersion) {
+		kubeletConfig = fmt.Sprintf("kubeadm:kubelet-config-%d.%d", kubeadmVersion.Major(), kubeadmVersion.Minor())
+	}
+
+	return append(objects,
+		// TLS bootstrap and certificate auto approval
+		rbacObject{kind: "ClusterRoleBinding", name: "kubeadm:kubelet-bootstrap", roleRef: "system:node-bootstrapper", subjects: []string{"Group:" + bootstrapTokenGroup}},
+		rbacObject{kind: "ClusterRoleBinding", name: "kubeadm:node-autoapprove-bootstrap", roleRef: "system:certificates.k8s.io:certificatesigningrequests:nodeclient", subjects: []string{"Group:" + bootstrapTokenGroup}},
+		rbacObject{kind: "ClusterRoleBinding", name: "kubeadm:node-autoapprove-certificate-rotation", roleRef: "system:certificates.k8s.io:certificatesigningrequests:selfnodeclient", subjects: []string{"Group:" + nodesGroup}},
+		// kubeadm join reads the nodes, the kubeadm-config and the kubelet-config ConfigMaps
+		rbacObject{kind: "ClusterRole", name: "kubeadm:get-nodes"},
+		rbacObject{kind: "ClusterRoleBinding", name: "kubeadm:get-nodes", roleRef: "kubeadm:get-nodes", subjects: []string{"Group:" + bootstrapTokenGroup}},
+		rbacObject{kind: "Role", namespace: "kube-system", name: "kubeadm:nodes-kubeadm-config"},
+		rbacObject{kind: "RoleBinding", namespace: "kube-system", name: "kubeadm:nodes-kubeadm-config", roleRef: "kubeadm:nodes-kubeadm-config", subjects: []string{"Group:" + bootstrapTokenGroup, "Group:" + nodesGroup}},
+		rbacObject{kind: "Role", namespace: "kube-system", name: kubeletConfig},
+		rbacObject{kind: "RoleBinding", namespace: "kube-system", name: kubeletConfig, roleRef: kubeletConfig, subjects: []string{"Group:" + bootstrapTokenGroup, "Group:" + nodesGroup}},
+		// discovery reads the cluster-info ConfigMap anonymously
+		rbacObject{kind: "Role", namespace: "kube-public", name: "kubeadm:bootstrap-signer-clusterinfo"},
+		rbacObject{kind: "RoleBinding", namespace: "kube-public", name: "kubeadm:bootstrap-signer-clusterinfo", roleRef: "kubeadm:bootstrap-signer-clusterinfo", subjects: []string{"User:system:anonymous"}},
+	)
+}
+
+// rbacBinding defines the fields of a RoleBinding or ClusterRoleBinding checked by RBACCheck
+type rbacBinding struct {
+	RoleRef struct {
+		Name string `json:"name"`
+	} `json:"roleRef"`
+	Subjects []struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"subjects"`
+}
+
+// rbacObjectProblem returns a description of the problem with a RBAC object, given its JSON form as read from
+// the cluster, or an empty string if the object is correct; bindings must reference the expected role, and
+// include the expected subjects, while additional subjects are allowed
+func rbacObjectProblem(expected rbacObject, raw []byte) string {
+	if expected.roleRef == "" {
+		return ""
+	}
+
+	var binding rbacBinding
+	if err := json.Unmarshal(raw, &binding); err != nil {
+		return fmt.Sprintf("invalid object: %v", err)
+	}
+
+	var problems []string
+	if binding.RoleRef.Name != expected.roleRef {
+		problems = append(problems, fmt.Sprintf("references %s instead of %s", binding.RoleRef.Name, expected.roleRef))
+	}
+	subjects := map[string]bool{}
+	for _, s := range binding.Subjects {
+		subjects[s.Kind+":"+s.Name] = true
+	}
+	var missing []string
+	for _, s := range expected.subjects {
+		if !subjects[s] {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing subjects %s", strings.Join(missing, ", ")))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// RBACCheck verifies that the RBAC objects required for admin access, for TLS bootstrap and for kubeadm join exist
+// after kubeadm init, and that the bindings reference the expected roles and subjects, e.g. the system:masters
+// binding and the bootstrap token roles; this catches regressions in the kubeadm RBAC bootstrap.
+func RBACCheck(c *status.Cluster) error {
+	cp1 := c.BootstrapControlPlane()
+	kubeadmVersion, err := cp1.KubeadmVersion()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tSTATUS")
+	var failed []string
+	for _, o := range expectedRBACObjects(kubeadmVersion) {
+		args := []string{"--kubeconfig=/etc/kubernetes/admin.conf", "get", strings.ToLower(o.kind), o.name, "-o=json"}
+		if o.namespace != "" {
+			args = append(args, fmt.Sprintf("--namespace=%s", o.namespace))
+		}
+
+		result := "ok"
+		lines, err := cp1.Command("kubectl", args...).Silent().RunAndCapture()
+		if err != nil {
+			result = "missing"
+		} else if problem := rbacObjectProblem(o, []byte(strings.Join(lines, "\n"))); problem != "" {
+			result = problem
+		}
+		if result != "ok" {
+			failed = append(failed, o.String())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.kind, valueOrNone(o.namespace), o.name, result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("missing or incorrect RBAC objects: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func valueOrNone(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
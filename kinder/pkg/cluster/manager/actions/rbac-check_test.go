@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestExpectedRBACObjects(t *testing.T) {
+	tests := []struct {
+		version         string
+		expectedPresent []string
+		expectedAbsent  []string
+	}{
+		{
+			version:         "v1.30.0",
+			expectedPresent: []string{"ClusterRoleBinding kubeadm:cluster-admins", "RoleBinding kube-system/kubeadm:kubelet-config"},
+		},
+		{
+			version:         "v1.29.0-alpha.1",
+			expectedPresent: []string{"ClusterRoleBinding kubeadm:cluster-admins"},
+		},
+		{
+			version:         "v1.28.5",
+			expectedPresent: []string{"ClusterRoleBinding cluster-admin", "Role kube-system/kubeadm:kubelet-config"},
+			expectedAbsent:  []string{"ClusterRoleBinding kubeadm:cluster-admins"},
+		},
+		{
+			version:         "v1.23.17",
+			expectedPresent: []string{"Role kube-system/kubeadm:kubelet-config-1.23"},
+			expectedAbsent:  []string{"Role kube-system/kubeadm:kubelet-config"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			objects := map[string]bool{}
+			for _, o := range expectedRBACObjects(K8sVersion.MustParseSemantic(tt.version)) {
+				objects[o.String()] = true
+			}
+			for _, o := range tt.expectedPresent {
+				if !objects[o] {
+					t.Errorf("expected %s", o)
+				}
+			}
+			for _, o := range tt.expectedAbsent {
+				if objects[o] {
+					t.Errorf("unexpected %s", o)
+				}
+			}
+		})
+	}
+}
+
+func TestRBACObjectProblem(t *testing.T) {
+	binding := rbacObject{
+		kind:     "RoleBinding",
+		name:     "kubeadm:nodes-kubeadm-config",
+		roleRef:  "kubeadm:nodes-kubeadm-config",
+		subjects: []string{"Group:" + bootstrapTokenGroup, "Group:" + nodesGroup},
+	}
+	tests := []struct {
+		name     string
+		expected rbacObject
+		raw      string
+		problem  string
+	}{
+		{
+			name:     "role",
+			expected: rbacObject{kind: "Role", name: "kubeadm:nodes-kubeadm-config"},
+			raw:      `{"kind":"Role"}`,
+		},
+		{
+			name:     "correct binding",
+			expected: binding,
+			raw: `{"roleRef":{"kind":"Role","name":"kubeadm:nodes-kubeadm-config"},"subjects":[` +
+				`{"kind":"Group","name":"system:bootstrappers:kubeadm:default-node-token"},{"kind":"Group","name":"system:nodes"}]}`,
+		},
+		{
+			name:     "additional subjects are allowed",
+			expected: binding,
+			raw: `{"roleRef":{"name":"kubeadm:nodes-kubeadm-config"},"subjects":[{"kind":"User","name":"admin"},` +
+				`{"kind":"Group","name":"system:bootstrappers:kubeadm:default-node-token"},{"kind":"Group","name":"system:nodes"}]}`,
+		},
+		{
+			name:     "wrong role and missing subject",
+			expected: binding,
+			raw:      `{"roleRef":{"name":"view"},"subjects":[{"kind":"Group","name":"system:nodes"}]}`,
+			problem:  "references view instead of kubeadm:nodes-kubeadm-config; missing subjects Group:system:bootstrappers:kubeadm:default-node-token",
+		},
+		{
+			name:     "subject with another kind",
+			expected: binding,
+			raw: `{"roleRef":{"name":"kubeadm:nodes-kubeadm-config"},"subjects":[` +
+				`{"kind":"User","name":"system:bootstrappers:kubeadm:default-node-token"},{"kind":"Group","name":"system:nodes"}]}`,
+			problem: "missing subjects Group:system:bootstrappers:kubeadm:default-node-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problem := rbacObjectProblem(tt.expected, []byte(tt.raw)); problem != tt.problem {
+				t.Errorf("expected %q, got %q", tt.problem, problem)
+			}
+		})
+	}
+}
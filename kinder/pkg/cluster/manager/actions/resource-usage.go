@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+)
+
+// resourceUsageStatsFormat is the docker stats format used for sampling the resource usage of the node containers
+const resourceUsageStatsFormat = "{{.Name}}\t{{.CPUPerc}}\t{{.MemUsage}}"
+
+// resourceUsageSample is the CPU and memory usage of a node container at a point in time
+type resourceUsageSample struct {
+	container   string
+	cpuPercent  float64
+	memoryBytes int64
+}
+
+// resourceUsageRecorder periodically samples the CPU and memory usage of the node containers while an action runs,
+// and writes the samples as a CSV time series to a host file
+type resourceUsageRecorder struct {
+	path string
+	stop chan struct{}
+	done chan error
+}
+
+// startResourceUsageRecorder starts sampling the resource usage of the given nodes every interval;
+// the samples are written to path until Stop is called
+func startResourceUsageRecorder(nodes status.NodeList, path string, interval time.Duration) (*resourceUsageRecorder, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("no nodes for sampling the resource usage")
+	}
+	if interval <= 0 {
+		return nil, errors.Errorf("invalid resource usage sampling interval %s. Use a positive duration", interval)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the resource usage file %s", path)
+	}
+	if _, err := fmt.Fprintln(f, "timestamp,container,cpu_percent,memory_bytes"); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "failed to write the resource usage file %s", path)
+	}
+
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, n.Name())
+	}
+
+	r := &resourceUsageRecorder{
+		path: path,
+		stop: make(chan struct{}),
+		done: make(chan error, 1),
+	}
+	go r.run(f, names, interval)
+
+	log.Infof("Recording the resource usage of %s to %s every %s", strings.Join(names, ", "), path, interval)
+	return r, nil
+}
+
+// run samples the resource usage until the recorder is stopped; failures of single samples, e.g. while a node
+// container restarts, are logged and do not stop the recording
+func (r *resourceUsageRecorder) run(f *os.File, names []string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var err error
+	for err == nil {
+		err = r.sample(f, names)
+
+		select {
+		case <-r.stop:
+			// take a last sample, so the time series covers the whole action
+			if err == nil {
+				err = r.sample(f, names)
+			}
+			if cerr := f.Close(); err == nil && cerr != nil {
+				err = errors.Wrapf(cerr, "failed to write the resource usage file %s", r.path)
+			}
+			r.done <- err
+			return
+		case <-ticker.C:
+		}
+	}
+
+	f.Close()
+	<-r.stop
+	r.done <- err
+}
+
+// sample writes one sample of the resource usage of the containers; only errors writing the file are returned
+func (r *resourceUsageRecorder) sample(f *os.File, names []string) error {
+	timestamp := time.Now().UTC().Format(time.RFC3339Nano)
+	lines, err := host.ContainerStats(resourceUsageStatsFormat, names...)
+	if err != nil {
+		log.Debugf("failed to sample the resource usage: %v %s", err, strings.Join(lines, " "))
+		return nil
+	}
+
+	for _, line := range lines {
+		s, ok, err := parseResourceUsageSample(line)
+		if err != nil {
+			log.Debugf("failed to parse the resource usage sample: %v", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if _, err := fmt.Fprintf(f, "%s,%s,%.2f,%d\n", timestamp, s.container, s.cpuPercent, s.memoryBytes); err != nil {
+			return errors.Wrapf(err, "failed to write the resource usage file %s", r.path)
+		}
+	}
+	return nil
+}
+
+// Stop stops the recording, and returns the error that eventually stopped the recording before
+func (r *resourceUsageRecorder) Stop() error {
+	close(r.stop)
+	return <-r.done
+}
+
+// parseResourceUsageSample parses a line of the docker stats output in the resourceUsageStatsFormat, e.g.
+// "kind-control-plane\t12.34%\t1.2GiB / 7.7GiB"; false is returned for containers without usage data, e.g. stopped
+func parseResourceUsageSample(line string) (resourceUsageSample, bool, error) {
+	fields := strings.Split(strings.TrimSpace(line), "\t")
+	if len(fields) != 3 {
+		return resourceUsageSample{}, false, errors.Errorf("unexpected docker stats output %q", line)
+	}
+
+	name, cpu, memory := fields[0], strings.TrimSpace(fields[1]), strings.TrimSpace(fields[2])
+	if strings.HasPrefix(cpu, "--") {
+		return resourceUsageSample{}, false, nil
+	}
+
+	cpuPercent, err := strconv.ParseFloat(strings.TrimSuffix(cpu, "%"), 64)
+	if err != nil {
+		return resourceUsageSample{}, false, errors.Errorf("invalid CPU usage %q for %s", cpu, name)
+	}
+
+	// the memory usage is reported together with the memory limit, e.g. 1.2GiB / 7.7GiB
+	usage := strings.TrimSpace(strings.SplitN(memory, "/", 2)[0])
+	memoryBytes, err := parseDockerBytes(usage)
+	if err != nil {
+		return resourceUsageSample{}, false, errors.Wrapf(err, "invalid memory usage for %s", name)
+	}
+
+	return resourceUsageSample{container: name, cpuPercent: cpuPercent, memoryBytes: memoryBytes}, true, nil
+}
+
+// parseDockerBytes parses a size in the human readable format used by docker stats, e.g. 1.5GiB or 300kB
+func parseDockerBytes(value string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		// binary suffixes must be checked before the decimal ones sharing the B suffix
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"kB", 1e3}, {"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+	for _, u := range units {
+		if !strings.HasSuffix(value, u.suffix) {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), 64)
+		if err != nil || n < 0 {
+			break
+		}
+		return int64(n*u.multiplier + 0.5), nil
+	}
+	return 0, errors.Errorf("invalid size %q", value)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestParseResourceUsageSample(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		expected      resourceUsageSample
+		expectedOK    bool
+		expectedError bool
+	}{
+		{
+			name:       "binary units",
+			line:       "kinder-control-plane-1\t12.34%\t1.5GiB / 7.7GiB",
+			expected:   resourceUsageSample{container: "kinder-control-plane-1", cpuPercent: 12.34, memoryBytes: 1610612736},
+			expectedOK: true,
+		},
+		{
+			name:       "decimal units",
+			line:       "kinder-control-plane-2\t0.00%\t300kB / 1GB",
+			expected:   resourceUsageSample{container: "kinder-control-plane-2", cpuPercent: 0, memoryBytes: 300000},
+			expectedOK: true,
+		},
+		{
+			name: "stopped container",
+			line: "kinder-control-plane-1\t--\t-- / --",
+		},
+		{
+			name:          "missing fields",
+			line:          "kinder-control-plane-1 12.34%",
+			expectedError: true,
+		},
+		{
+			name:          "invalid CPU usage",
+			line:          "kinder-control-plane-1\tabc%\t1GiB / 2GiB",
+			expectedError: true,
+		},
+		{
+			name:          "invalid memory usage",
+			line:          "kinder-control-plane-1\t1.00%\t1XB / 2GiB",
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok, err := parseResourceUsageSample(tt.line)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if ok != tt.expectedOK {
+				t.Fatalf("expected ok %v, got %v", tt.expectedOK, ok)
+			}
+			if s != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, s)
+			}
+		})
+	}
+}
+
+func TestParseDockerBytes(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      int64
+		expectedError bool
+	}{
+		{value: "512B", expected: 512},
+		{value: "1.5KiB", expected: 1536},
+		{value: "2MiB", expected: 2097152},
+		{value: "1.2GB", expected: 1200000000},
+		{value: "0B", expected: 0},
+		{value: "12", expectedError: true},
+		{value: "-1MiB", expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			n, err := parseDockerBytes(tt.value)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if n != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, n)
+			}
+		})
+	}
+}
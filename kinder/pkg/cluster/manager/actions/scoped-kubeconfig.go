@@ -0,0 +1,164 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// ScopedKubeConfig writes a kubeconfig for a service account, in the namespace/name form, or for a user
+// authenticated by a client certificate with the given groups, e.g. for testing authorization behavior;
+// the kubeconfig is written at the output path, if set, otherwise it is printed.
+func ScopedKubeConfig(c *status.Cluster, serviceAccount, user string, groups []string, outputPath string) error {
+	var (
+		config []byte
+		err    error
+	)
+	switch {
+	case serviceAccount != "" && user != "":
+		return errors.New("a kubeconfig can be generated either for a service account or for a user. Use only one of --kubeconfig-service-account and --kubeconfig-user")
+	case serviceAccount != "":
+		if len(groups) > 0 {
+			return errors.New("groups can be set only for users; the groups of a service account are defined by Kubernetes")
+		}
+		config, err = ServiceAccountKubeConfig(c, serviceAccount)
+	case user != "":
+		config, err = UserKubeConfig(c, user, groups)
+	default:
+		return errors.New("a service account or a user is required. Use --kubeconfig-service-account or --kubeconfig-user")
+	}
+	if err != nil {
+		return err
+	}
+
+	if outputPath == "" {
+		fmt.Print(string(config))
+		return nil
+	}
+	if err := os.WriteFile(outputPath, config, 0600); err != nil {
+		return errors.Wrapf(err, "failed to write the kubeconfig to %s", outputPath)
+	}
+	exec.Printf("Kubeconfig written to %s\n", outputPath)
+	return nil
+}
+
+// ServiceAccountKubeConfig returns a kubeconfig for a service account, in the namespace/name form,
+// authenticated by a token requested with kubectl create token; the token expires with the kubectl default duration
+func ServiceAccountKubeConfig(c *status.Cluster, serviceAccount string) ([]byte, error) {
+	parts := strings.Split(serviceAccount, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, errors.Errorf("invalid service account %q. Use the namespace/name form", serviceAccount)
+	}
+	namespace, name := parts[0], parts[1]
+
+	cp1 := c.BootstrapControlPlane()
+	cp1.Infof("requesting a token for the service account %s", serviceAccount)
+	lines, err := cp1.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf",
+		"create", "token", name, fmt.Sprintf("--namespace=%s", namespace),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to request a token for the service account %s: %s", serviceAccount, strings.Join(lines, "\n"))
+	}
+	if len(lines) != 1 {
+		return nil, errors.Errorf("failed to request a token for the service account %s: unexpected output %q", serviceAccount, strings.Join(lines, "\n"))
+	}
+
+	return scopedKubeConfig(c, fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name), &clientcmdapi.AuthInfo{
+		Token: lines[0],
+	})
+}
+
+// UserKubeConfig returns a kubeconfig for a user, authenticated by a client certificate signed by the cluster CA,
+// with the user as common name and the groups as organizations, so Kubernetes maps them to the user groups
+func UserKubeConfig(c *status.Cluster, user string, groups []string) ([]byte, error) {
+	cp1 := c.BootstrapControlPlane()
+	caCert, err := cp1.Command("cat", caCertPath).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s from node %s", caCertPath, cp1.Name())
+	}
+	caKey, err := cp1.Command("cat", caKeyPath).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s from node %s. The CA key is required for signing the certificate", caKeyPath, cp1.Name())
+	}
+
+	cp1.Infof("generating a client certificate for the user %s with groups [%s]", user, strings.Join(groups, ", "))
+	certPEM, keyPEM, err := signCert([]byte(strings.Join(caCert, "\n")), []byte(strings.Join(caKey, "\n")), &x509.Certificate{
+		Subject:     pkix.Name{CommonName: user, Organization: groups},
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scopedKubeConfig(c, user, &clientcmdapi.AuthInfo{
+		ClientCertificateData: certPEM,
+		ClientKeyData:         keyPEM,
+	})
+}
+
+// scopedKubeConfig returns a kubeconfig for the given user and credentials, using the cluster CA and the API server
+// endpoint reachable from the host
+func scopedKubeConfig(c *status.Cluster, user string, authInfo *clientcmdapi.AuthInfo) ([]byte, error) {
+	cp1 := c.BootstrapControlPlane()
+	caCert, err := cp1.Command("cat", caCertPath).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s from node %s", caCertPath, cp1.Name())
+	}
+
+	endpoint, err := c.APIServerHostEndpoint()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the API server endpoint")
+	}
+
+	config := newScopedKubeConfig(c.Name(), fmt.Sprintf("https://%s", endpoint), []byte(strings.Join(caCert, "\n")+"\n"), user, authInfo)
+	out, err := clientcmd.Write(*config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the kubeconfig")
+	}
+	return out, nil
+}
+
+// newScopedKubeConfig returns a kubeconfig with a single context, named user@cluster, for the given user
+// and credentials
+func newScopedKubeConfig(clusterName, server string, caData []byte, user string, authInfo *clientcmdapi.AuthInfo) *clientcmdapi.Config {
+	contextName := fmt.Sprintf("%s@%s", user, clusterName)
+	config := clientcmdapi.NewConfig()
+	config.Clusters[clusterName] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: caData,
+	}
+	config.AuthInfos[user] = authInfo
+	config.Contexts[contextName] = &clientcmdapi.Context{
+		Cluster:  clusterName,
+		AuthInfo: user,
+	}
+	config.CurrentContext = contextName
+	return config
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestNewScopedKubeConfig(t *testing.T) {
+	loaded := newScopedKubeConfig("kind", "https://127.0.0.1:6443", []byte("ca"), "system:serviceaccount:default:viewer", &clientcmdapi.AuthInfo{Token: "token"})
+
+	if loaded.CurrentContext != "system:serviceaccount:default:viewer@kind" {
+		t.Errorf("unexpected current context %q", loaded.CurrentContext)
+	}
+	context := loaded.Contexts[loaded.CurrentContext]
+	if context == nil || context.Cluster != "kind" || context.AuthInfo != "system:serviceaccount:default:viewer" {
+		t.Fatalf("unexpected context %+v", context)
+	}
+	if cluster := loaded.Clusters["kind"]; cluster == nil || cluster.Server != "https://127.0.0.1:6443" || string(cluster.CertificateAuthorityData) != "ca" {
+		t.Errorf("unexpected cluster %+v", cluster)
+	}
+	if authInfo := loaded.AuthInfos[context.AuthInfo]; authInfo == nil || authInfo.Token != "token" {
+		t.Errorf("unexpected user %+v", authInfo)
+	}
+}
+
+func TestSignCertForUser(t *testing.T) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubernetes"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCertPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	caKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(caKey)})
+
+	certPEM, _, err := signCert(caCertPEM, caKeyPEM, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "jane", Organization: []string{"testers", "viewers"}},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error signing the certificate: %v", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != "jane" || !reflect.DeepEqual(cert.Subject.Organization, []string{"testers", "viewers"}) {
+		t.Errorf("unexpected subject %v", cert.Subject)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCertPEM)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("the certificate is not a valid client certificate signed by the CA: %v", err)
+	}
+}
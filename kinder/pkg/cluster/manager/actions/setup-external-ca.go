@@ -22,11 +22,12 @@ import (
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
 // SetupExternalCA setups certificates and kubeconfig files to be able to create a cluster without CA keys.
 func SetupExternalCA(c *status.Cluster, vLevel int) error {
-	fmt.Println("Setuping external CA for the cluster...")
+	exec.Println("Setuping external CA for the cluster...")
 
 	// gets the IP of the load balancer
 	loadBalancerIP, _, err := c.ExternalLoadBalancer().IP()
@@ -24,32 +24,61 @@ import (
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
+// defaultSmokeTestImage defines the image used by the smoke test workload, if not differently specified;
+// the image must serve HTTP on port 80, and provide nslookup and wget, e.g. via busybox
+const defaultSmokeTestImage = "nginx:1.15.9-alpine"
+
 // SmokeTest actions execute a set of simple test checking proper functioning of
-// deployments, services/type node port, kubectl logs & exec & DNS resolution
-func SmokeTest(c *status.Cluster, wait time.Duration) error {
+// deployments, services/type node port, kubectl logs & exec, DNS resolution, pod-to-pod connectivity and
+// optionally pod-to-service connectivity. The workload image can be set for air-gapped environments;
+// in case of failure, the state of the workload is printed for diagnostics. Names are resolved using
+// the DNS domain of the cluster.
+func SmokeTest(c *status.Cluster, image string, serviceConnectivity bool, wait time.Duration) error {
 	// test are executed on the bootstrap control-plane
 	cp1 := c.BootstrapControlPlane()
 
+	dnsDomain, err := clusterDNSDomain(c)
+	if err != nil {
+		return err
+	}
+
+	if image == "" {
+		image = defaultSmokeTestImage
+	}
+
 	// cleanups garbage from previous test
 	cleanupSmokeTest(cp1)
 
-	// Test deployments
+	if err := smokeTest(c, cp1, dnsDomain, image, serviceConnectivity, wait); err != nil {
+		printSmokeTestDiagnostics(cp1)
+		return errors.Wrap(err, "smoke test failed")
+	}
+
+	// cleanups and print final message
+	cleanupSmokeTest(cp1)
+	exec.Printf("\nSmoke test passed!\n")
+
+	return nil
+}
+
+func smokeTest(c *status.Cluster, cp1 *status.Node, dnsDomain, image string, serviceConnectivity bool, wait time.Duration) error {
+	// Test deployments; two replicas are used for testing pod-to-pod connectivity
 	cp1.Infof("test deployments")
 
 	if err := cp1.Command(
 		"kubectl",
 		"--kubeconfig=/etc/kubernetes/admin.conf",
-		"create", "deployment", "nginx", "--image=nginx:1.15.9-alpine",
+		"create", "deployment", "nginx", fmt.Sprintf("--image=%s", image), "--replicas=2",
 	).RunWithEcho(); err != nil {
 		return err
 	}
 
-	if err := waitForPodsRunning(c, cp1, wait, "nginx", 1); err != nil {
+	if err := waitForPodsRunning(c, cp1, wait, "nginx", 2); err != nil {
 		return err
 	}
-
 	// Test service type NodePort
 	cp1.Infof("test NodePort service")
 
@@ -87,7 +116,7 @@ func SmokeTest(c *status.Cluster, wait time.Duration) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to run kubectl logs")
 	}
-	fmt.Printf("%d logs lines returned\n", len(lines))
+	exec.Printf("%d logs lines returned\n", len(lines))
 
 	// Test kubectl exec
 	cp1.Infof("test kubectl exec")
@@ -98,19 +127,41 @@ func SmokeTest(c *status.Cluster, wait time.Duration) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to run kubectl exec")
 	}
-	fmt.Printf("%d output lines returned\n", len(lines))
+	exec.Printf("%d output lines returned\n", len(lines))
 
 	// Test DNS resolution
 	cp1.Infof("test DNS resolution")
 
-	if len(lines) < 3 || !strings.Contains(lines[3], "kubernetes.default.svc.cluster.local") {
-		return errors.Wrapf(err, "dns resolution error")
+	if len(lines) < 4 || !strings.Contains(lines[3], fmt.Sprintf("kubernetes.default.svc.%s", dnsDomain)) {
+		return errors.Errorf("dns resolution error: kubernetes service does not answer to kubernetes.default.svc.%s", dnsDomain)
 	}
-	fmt.Printf("kubernetes service answers to %s\n", lines[3])
+	exec.Printf("kubernetes service answers to %s\n", lines[3])
 
-	// cleanups and print final message
-	cleanupSmokeTest(cp1)
-	fmt.Printf("\nSmoke test passed!\n")
+	// Test pod-to-pod connectivity
+	cp1.Infof("test pod-to-pod connectivity")
+
+	pods, err := getPodAddresses(cp1, "nginx")
+	if err != nil {
+		return err
+	}
+	if len(pods) < 2 {
+		return errors.Errorf("expected 2 nginx pods with an IP, found %d", len(pods))
+	}
+	if err := httpGetFromPod(cp1, pods[0].name, httpURL(pods[1].ip)); err != nil {
+		return errors.Wrapf(err, "pod %s can't reach pod %s", pods[0].name, pods[1].name)
+	}
+	exec.Printf("pod %s reaches pod %s at %s\n", pods[0].name, pods[1].name, pods[1].ip)
+
+	// Test pod-to-service connectivity, if requested
+	if serviceConnectivity {
+		cp1.Infof("test pod-to-service connectivity")
+
+		service := fmt.Sprintf("nginx.default.svc.%s", dnsDomain)
+		if err := httpGetFromPod(cp1, pods[0].name, httpURL(service)); err != nil {
+			return errors.Wrapf(err, "pod %s can't reach service %s", pods[0].name, service)
+		}
+		exec.Printf("pod %s reaches service %s\n", pods[0].name, service)
+	}
 
 	return nil
 }
@@ -158,3 +209,64 @@ func getPodName(n *status.Node, label string) (string, error) {
 
 	return strings.Trim(lines[0], "'"), nil
 }
+
+// podAddress defines the name and the IP of a pod
+type podAddress struct {
+	name string
+	ip   string
+}
+
+func getPodAddresses(n *status.Node, label string) ([]podAddress, error) {
+	lines, err := n.Command(
+		"kubectl",
+		"--kubeconfig=/etc/kubernetes/admin.conf",
+		"get", "pods", "-l", fmt.Sprintf("app=%s", label), "-o", `jsonpath={range .items[*]}{.metadata.name} {.status.podIP}{"\n"}{end}`,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get pod IPs")
+	}
+	return parsePodAddresses(lines), nil
+}
+
+// parsePodAddresses parses lines in the "name ip" form; pods without an IP are ignored
+func parsePodAddresses(lines []string) []podAddress {
+	var pods []podAddress
+	for _, l := range lines {
+		fields := strings.Fields(strings.Trim(l, "'"))
+		if len(fields) != 2 {
+			continue
+		}
+		pods = append(pods, podAddress{name: fields[0], ip: fields[1]})
+	}
+	return pods
+}
+
+// httpURL returns the HTTP URL for a host, that is an IP address or a DNS name
+func httpURL(host string) string {
+	if strings.Contains(host, ":") {
+		return fmt.Sprintf("http://[%s]/", host)
+	}
+	return fmt.Sprintf("http://%s/", host)
+}
+
+// httpGetFromPod executes an HTTP GET from a pod, using wget
+func httpGetFromPod(n *status.Node, pod, url string) error {
+	return n.Command(
+		"kubectl", "--kubeconfig=/etc/kubernetes/admin.conf", "exec", pod, "--", "wget", "-q", "-O", "/dev/null", "-T", "5", url,
+	).Silent().TailOnError(5).Run()
+}
+
+// printSmokeTestDiagnostics prints the state of the smoke test workload; errors are ignored,
+// because diagnostics are collected on a best effort basis
+func printSmokeTestDiagnostics(cp1 *status.Node) {
+	cp1.Infof("smoke test diagnostics")
+	for _, args := range [][]string{
+		{"get", "deployments,pods,services,endpoints", "-l", "app=nginx", "-o", "wide"},
+		{"describe", "pods", "-l", "app=nginx"},
+		{"get", "events", "--sort-by=.lastTimestamp"},
+	} {
+		_ = cp1.Command(
+			"kubectl", append([]string{"--kubeconfig=/etc/kubernetes/admin.conf"}, args...)...,
+		).RunWithEcho()
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePodAddresses(t *testing.T) {
+	lines := []string{
+		"nginx-7c5ddbdf54-abcde 192.168.1.2",
+		"nginx-7c5ddbdf54-fghij fd00:10:244::3",
+		"nginx-7c5ddbdf54-klmno ",
+	}
+	expected := []podAddress{
+		{name: "nginx-7c5ddbdf54-abcde", ip: "192.168.1.2"},
+		{name: "nginx-7c5ddbdf54-fghij", ip: "fd00:10:244::3"},
+	}
+	if pods := parsePodAddresses(lines); !reflect.DeepEqual(pods, expected) {
+		t.Errorf("expected %v, got %v", expected, pods)
+	}
+}
+
+func TestHTTPURL(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "192.168.1.2", expected: "http://192.168.1.2/"},
+		{host: "fd00:10:244::3", expected: "http://[fd00:10:244::3]/"},
+		{host: "nginx.default.svc.cluster.local", expected: "http://nginx.default.svc.cluster.local/"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			if url := httpURL(test.host); url != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, url)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+)
+
+// timeToReadyPhases defines the phases reported by the time-to-ready action, in order; each phase ends at
+// a milestone read from the cluster, so time spent between kinder commands is accounted to the following phase
+var timeToReadyPhases = []string{"containers-up", "init", "join", "cni-rollout", "all-ready"}
+
+// timeToReadyPhase defines the timing of a phase of the cluster setup
+type timeToReadyPhase struct {
+	Name     string     `json:"name"`
+	End      *time.Time `json:"end,omitempty"`
+	Duration string     `json:"duration,omitempty"`
+	Seconds  *float64   `json:"seconds,omitempty"`
+}
+
+// timeToReadyReport defines the end-to-end timing of the cluster setup, from create to all the nodes being ready
+type timeToReadyReport struct {
+	Cluster           string             `json:"cluster"`
+	KubernetesVersion string             `json:"kubernetesVersion,omitempty"`
+	Nodes             int                `json:"nodes"`
+	Start             time.Time          `json:"start"`
+	End               time.Time          `json:"end"`
+	Total             string             `json:"total"`
+	TotalSeconds      float64            `json:"totalSeconds"`
+	Phases            []timeToReadyPhase `json:"phases"`
+}
+
+// TimeToReady action waits for all the nodes to be ready, and then prints a JSON summary of the time elapsed
+// from the creation of the node containers to the last node becoming ready, with a breakdown by phase.
+// Milestones are read from the node containers and from the Kubernetes objects, so the report works also
+// when create, init and join are executed by separate kinder commands:
+// - containers-up ends when the last node container started
+// - init ends when the CoreDNS deployment was created by kubeadm init
+// - join ends when the last node registered
+// - cni-rollout ends when the last pod of the CNI plugin became ready; not reported for custom plugins
+// - all-ready ends when the last node became ready
+func TimeToReady(c *status.Cluster, wait time.Duration) error {
+	cp1 := c.BootstrapControlPlane()
+	nodes := c.K8sNodes().EligibleForActions()
+
+	if err := waitAllNodesReady(c, cp1, len(nodes), wait); err != nil {
+		return err
+	}
+
+	start, containersUp, err := nodeContainersTiming(nodes)
+	if err != nil {
+		return err
+	}
+
+	milestones := map[string][]time.Time{
+		"containers-up": {containersUp},
+	}
+	queries := map[string][]string{
+		"init":      {"-n=kube-system", "get", "deployment", "coredns", "-o=jsonpath='{.metadata.creationTimestamp}'"},
+		"join":      {"get", "nodes", "-o=jsonpath='{.items[*].metadata.creationTimestamp}'"},
+		"all-ready": {"get", "nodes", "-o=jsonpath='{.items[*].status.conditions[?(@.type == \"Ready\")].lastTransitionTime}'"},
+	}
+	name, err := cp1.CNI()
+	if err != nil {
+		return err
+	}
+	plugin, err := cni.Get(name)
+	if err != nil {
+		return err
+	}
+	if query := cniRolloutQuery(plugin); query != nil {
+		queries["cni-rollout"] = query
+	}
+	for phase, args := range queries {
+		output := kubectlOutput(cp1, append([]string{"--kubeconfig=/etc/kubernetes/admin.conf"}, args...)...)
+		timestamps, err := parseTimestamps(output)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the end of the %s phase", phase)
+		}
+		milestones[phase] = timestamps
+	}
+
+	report := newTimeToReadyReport(start, milestones)
+	report.Cluster = c.Name()
+	report.Nodes = len(nodes)
+	if version, err := cp1.KubeVersion(); err == nil {
+		report.KubernetesVersion = version
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the time-to-ready report")
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// nodeContainersTiming returns the time the first node container was created and the time the last
+// node container started
+func nodeContainersTiming(nodes status.NodeList) (created, started time.Time, err error) {
+	for _, n := range nodes {
+		lines, err := host.InspectContainer(n.Name(), "{{.Created}} {{.State.StartedAt}}")
+		if err != nil {
+			return created, started, errors.Wrapf(err, "failed to inspect node %s", n.Name())
+		}
+		timestamps, err := parseTimestamps(strings.Join(lines, " "))
+		if err != nil || len(timestamps) != 2 {
+			return created, started, errors.Errorf("failed to read the create and start time of node %s", n.Name())
+		}
+		if created.IsZero() || timestamps[0].Before(created) {
+			created = timestamps[0]
+		}
+		if timestamps[1].After(started) {
+			started = timestamps[1]
+		}
+	}
+	return created, started, nil
+}
+
+// parseTimestamps parses RFC3339 timestamps separated by spaces, e.g. the output of a kubectl jsonpath query
+func parseTimestamps(output string) ([]time.Time, error) {
+	timestamps := []time.Time{}
+	for _, f := range strings.Fields(strings.Trim(output, "'")) {
+		t, err := time.Parse(time.RFC3339Nano, f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid timestamp %q", f)
+		}
+		timestamps = append(timestamps, t)
+	}
+	return timestamps, nil
+}
+
+// cniRolloutQuery returns the kubectl args for reading when the pods of the CNI plugin DaemonSet became ready,
+// or nil for custom plugins, whose DaemonSet is not known
+func cniRolloutQuery(plugin cni.Plugin) []string {
+	if plugin.DaemonSet == "" || plugin.Selector == "" {
+		return nil
+	}
+	namespace := strings.SplitN(plugin.DaemonSet, "/", 2)[0]
+	return []string{fmt.Sprintf("-n=%s", namespace), "get", "pods", fmt.Sprintf("-l=%s", plugin.Selector),
+		"-o=jsonpath='{.items[*].status.conditions[?(@.type == \"Ready\")].lastTransitionTime}'"}
+}
+
+// newTimeToReadyReport computes the phases of the cluster setup from the milestones, that are the timestamps
+// for the end of each phase; the latest timestamp of a phase is used, and it is never before the end of the
+// previous phase, e.g. when the CNI pods became ready before the last node joined. Phases without
+// timestamps, e.g. cni-rollout when using a custom CNI, are reported without end and duration.
+func newTimeToReadyReport(start time.Time, milestones map[string][]time.Time) *timeToReadyReport {
+	report := &timeToReadyReport{
+		Start: start,
+		End:   start,
+	}
+	previous := start
+	for _, name := range timeToReadyPhases {
+		phase := timeToReadyPhase{Name: name}
+		if len(milestones[name]) > 0 {
+			end := previous
+			for _, t := range milestones[name] {
+				if t.After(end) {
+					end = t
+				}
+			}
+			seconds := end.Sub(previous).Seconds()
+			phase.End = &end
+			phase.Duration = end.Sub(previous).String()
+			phase.Seconds = &seconds
+			previous = end
+		}
+		report.Phases = append(report.Phases, phase)
+	}
+	report.End = previous
+	report.Total = report.End.Sub(report.Start).String()
+	report.TotalSeconds = report.End.Sub(report.Start).Seconds()
+	return report
+}
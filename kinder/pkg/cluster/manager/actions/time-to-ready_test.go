@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/kubeadm/kinder/pkg/cni"
+)
+
+func TestParseTimestamps(t *testing.T) {
+	timestamps, err := parseTimestamps("'2024-05-01T10:00:00Z 2024-05-01T10:00:05.123456789Z'")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(timestamps) != 2 || timestamps[1].Sub(timestamps[0]) != 5123456789*time.Nanosecond {
+		t.Errorf("unexpected timestamps %v", timestamps)
+	}
+
+	if timestamps, err := parseTimestamps("''"); err != nil || len(timestamps) != 0 {
+		t.Errorf("expected no timestamps, got %v, %v", timestamps, err)
+	}
+
+	if _, err := parseTimestamps("'yesterday'"); err == nil {
+		t.Error("expected error for invalid timestamp, got nil")
+	}
+}
+
+func TestNewTimeToReadyReport(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	tests := []struct {
+		name       string
+		milestones map[string][]time.Time
+		expected   map[string]float64
+		missing    []string
+		total      float64
+	}{
+		{
+			name: "all phases",
+			milestones: map[string][]time.Time{
+				"containers-up": {at(10)},
+				"init":          {at(70)},
+				"join":          {at(60), at(100), at(90)},
+				"cni-rollout":   {at(110), at(120)},
+				"all-ready":     {at(125), at(130)},
+			},
+			expected: map[string]float64{"containers-up": 10, "init": 60, "join": 30, "cni-rollout": 20, "all-ready": 10},
+			total:    130,
+		},
+		{
+			name: "milestones before the end of the previous phase",
+			milestones: map[string][]time.Time{
+				"containers-up": {at(10)},
+				"init":          {at(70)},
+				"join":          {at(60)},
+				"cni-rollout":   {at(65)},
+				"all-ready":     {at(80)},
+			},
+			expected: map[string]float64{"containers-up": 10, "init": 60, "join": 0, "cni-rollout": 0, "all-ready": 10},
+			total:    80,
+		},
+		{
+			name: "no cni milestones",
+			milestones: map[string][]time.Time{
+				"containers-up": {at(10)},
+				"init":          {at(70)},
+				"join":          {at(100)},
+				"all-ready":     {at(130)},
+			},
+			expected: map[string]float64{"containers-up": 10, "init": 60, "join": 30, "all-ready": 30},
+			missing:  []string{"cni-rollout"},
+			total:    130,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			report := newTimeToReadyReport(start, rt.milestones)
+			if len(report.Phases) != len(timeToReadyPhases) {
+				t.Fatalf("expected %d phases, got %d", len(timeToReadyPhases), len(report.Phases))
+			}
+			for _, p := range report.Phases {
+				if expected, ok := rt.expected[p.Name]; ok {
+					if p.Seconds == nil || *p.Seconds != expected {
+						t.Errorf("phase %s: expected %v seconds, got %v", p.Name, expected, p.Seconds)
+					}
+				}
+			}
+			for _, name := range rt.missing {
+				for _, p := range report.Phases {
+					if p.Name == name && (p.End != nil || p.Seconds != nil) {
+						t.Errorf("phase %s: expected no end and duration", name)
+					}
+				}
+			}
+			if report.TotalSeconds != rt.total {
+				t.Errorf("expected total %v seconds, got %v", rt.total, report.TotalSeconds)
+			}
+		})
+	}
+}
+
+func TestCNIRolloutQuery(t *testing.T) {
+	flannel, err := cni.Get(cni.Flannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := cniRolloutQuery(flannel)
+	if len(query) < 4 || query[0] != "-n=kube-flannel" || query[3] != "-l=app=flannel" {
+		t.Errorf("unexpected query for flannel: %v", query)
+	}
+
+	custom, err := cni.Get("/kinder/cni/custom.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query := cniRolloutQuery(custom); query != nil {
+		t.Errorf("expected no query for a custom CNI, got %v", query)
+	}
+}
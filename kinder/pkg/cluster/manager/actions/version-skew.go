@@ -0,0 +1,229 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// versionSkewCheckedActions defines the actions preceded by the version skew check, unless skipped, and whether
+// violations are blocking; violations are only reported before upgrades, because partial upgrades go through
+// transient skews, e.g. when the first control-plane is upgraded before the others
+var versionSkewCheckedActions = map[string]bool{
+	"kubeadm-join":    true,
+	"kubeadm-upgrade": false,
+}
+
+// maxKubeadmSkew defines the maximum number of minor versions kubeadm can be newer than the API server
+const maxKubeadmSkew = 1
+
+// componentVersions defines the versions of the Kubernetes components on a node, used for checking the version skew;
+// apiServer is nil for worker nodes and for control-plane nodes without a running API server, e.g. not yet joined
+type componentVersions struct {
+	name      string
+	kubeadm   *K8sVersion.Version
+	kubelet   *K8sVersion.Version
+	apiServer *K8sVersion.Version
+}
+
+// VersionSkewCheck gathers the kubeadm, kubelet and API server versions on the K8s nodes eligible for actions,
+// and validates them against the Kubernetes version skew policy; the versions are reported, together with any
+// violation, and the action fails if there are violations.
+func VersionSkewCheck(c *status.Cluster) error {
+	versions, err := gatherComponentVersions(c.K8sNodes().EligibleForActions())
+	if err != nil {
+		return err
+	}
+
+	printComponentVersions(versions)
+
+	violations := versionSkewViolations(versions)
+	if len(violations) > 0 {
+		return errors.Errorf("unsupported version skew:\n- %s", strings.Join(violations, "\n- "))
+	}
+	exec.Println("The version skew is supported")
+	return nil
+}
+
+// preActionVersionSkewCheck checks the version skew before running an action, and reports violations;
+// if blocking, violations are returned as an error
+func preActionVersionSkewCheck(c *status.Cluster, action string, blocking bool) error {
+	versions, err := gatherComponentVersions(c.K8sNodes().EligibleForActions())
+	if err != nil {
+		return err
+	}
+
+	violations := versionSkewViolations(versions)
+	if len(violations) == 0 {
+		log.Infof("The version skew is supported, running %s", action)
+		return nil
+	}
+
+	printComponentVersions(versions)
+	if blocking {
+		return errors.Errorf("unsupported version skew, %s is not executed; use --skip-version-skew-check for testing unsupported skews:\n- %s", action, strings.Join(violations, "\n- "))
+	}
+	for _, v := range violations {
+		log.Warnf("unsupported version skew before %s: %s", action, v)
+	}
+	return nil
+}
+
+// gatherComponentVersions returns the versions of the Kubernetes components on the given nodes
+func gatherComponentVersions(nodes status.NodeList) ([]componentVersions, error) {
+	var versions []componentVersions
+	for _, n := range nodes {
+		kubeadmVersion, err := n.KubeadmVersion()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the kubeadm version for node %s", n.Name())
+		}
+		kubeletVersion, err := kubeletVersion(n)
+		if err != nil {
+			return nil, err
+		}
+		v := componentVersions{name: n.Name(), kubeadm: kubeadmVersion, kubelet: kubeletVersion}
+		if n.IsControlPlane() {
+			if v.apiServer, err = apiServerVersion(n); err != nil {
+				return nil, err
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// kubeletVersion returns the version of the kubelet installed on a node
+func kubeletVersion(n *status.Node) (*K8sVersion.Version, error) {
+	// kubelet --version prints e.g. "Kubernetes v1.30.0"
+	lines, err := n.Command("kubelet", "--version").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the kubelet version for node %s", n.Name())
+	}
+	if len(lines) == 0 {
+		return nil, errors.Errorf("failed to get the kubelet version for node %s: empty output", n.Name())
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) == 0 {
+		return nil, errors.Errorf("failed to get the kubelet version for node %s: unexpected output %q", n.Name(), lines[0])
+	}
+	v, err := K8sVersion.ParseSemantic(fields[len(fields)-1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the kubelet version for node %s", n.Name())
+	}
+	return v, nil
+}
+
+// apiServerImageTagRE matches the tag of the kube-apiserver image in the static pod manifest
+var apiServerImageTagRE = regexp.MustCompile(`image:\s*\S*kube-apiserver[^:\s]*:(\S+)`)
+
+// apiServerVersion returns the version of the API server on a control-plane node, as defined by the image in the
+// static pod manifest, or nil if the manifest doesn't exist, e.g. because the node is not yet initialized or joined
+func apiServerVersion(n *status.Node) (*K8sVersion.Version, error) {
+	lines, err := n.Command("sh", "-c", "cat /etc/kubernetes/manifests/kube-apiserver.yaml 2>/dev/null || true").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the API server manifest for node %s", n.Name())
+	}
+	m := apiServerImageTagRE.FindStringSubmatch(strings.Join(lines, "\n"))
+	if m == nil {
+		return nil, nil
+	}
+	v, err := K8sVersion.ParseSemantic(strings.Trim(m[1], `"'`))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the API server version for node %s", n.Name())
+	}
+	return v, nil
+}
+
+// printComponentVersions prints a table with the versions of the Kubernetes components on each node; with the JSON
+// log format, the versions of each node are printed as a JSON log entry instead
+func printComponentVersions(versions []componentVersions) {
+	if exec.IsJSONLog() {
+		for _, v := range versions {
+			fields := log.Fields{"kubeadm": "v" + v.kubeadm.String(), "kubelet": "v" + v.kubelet.String()}
+			if v.apiServer != nil {
+				fields["apiServer"] = "v" + v.apiServer.String()
+			}
+			exec.Logger(v.name).WithFields(fields).Info("component versions")
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(exec.EchoOutput(), 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tKUBEADM\tKUBELET\tAPI SERVER")
+	for _, v := range versions {
+		apiServer := "-"
+		if v.apiServer != nil {
+			apiServer = "v" + v.apiServer.String()
+		}
+		fmt.Fprintf(w, "%s\tv%s\tv%s\t%s\n", v.name, v.kubeadm, v.kubelet, apiServer)
+	}
+	_ = w.Flush()
+}
+
+// versionSkewViolations returns the violations of the Kubernetes version skew policy, that is the violations
+// between API servers and kubelets reported by apiServerSkewViolations, and kubeadm not older than the newest
+// API server and not newer than maxKubeadmSkew minor versions than the oldest API server; kubelets not newer
+// than kubeadm on the same node, and not older than maxKubeletSkew minor versions
+func versionSkewViolations(versions []componentVersions) []string {
+	var violations []string
+
+	// kubeadm and kubelet on the same node
+	for _, v := range versions {
+		switch skew := minorOf(v.kubeadm) - minorOf(v.kubelet); {
+		case skew < 0:
+			violations = append(violations, fmt.Sprintf("the kubelet on node %s (v%s) is newer than kubeadm (v%s)", v.name, v.kubelet, v.kubeadm))
+		case skew > maxKubeletSkew:
+			violations = append(violations, fmt.Sprintf("the kubelet on node %s (v%s) is more than %d minor versions older than kubeadm (v%s)", v.name, v.kubelet, maxKubeletSkew, v.kubeadm))
+		}
+	}
+
+	var apiServers, kubelets []nodeVersion
+	for _, v := range versions {
+		if v.apiServer != nil {
+			apiServers = append(apiServers, nodeVersion{name: v.name, controlPlane: true, version: v.apiServer})
+		}
+		kubelets = append(kubelets, nodeVersion{name: v.name, version: v.kubelet})
+	}
+	violations = append(violations, apiServerSkewViolations(apiServers, kubelets)...)
+
+	// kubeadm and API servers
+	oldest, newest := apiServerRange(apiServers)
+	if oldest == nil {
+		return violations
+	}
+	for _, v := range versions {
+		if minorOf(v.kubeadm) < minorOf(newest.version) {
+			violations = append(violations, fmt.Sprintf("kubeadm on node %s (v%s) is older than the API server on node %s (v%s)",
+				v.name, v.kubeadm, newest.name, newest.version))
+		}
+		if minorOf(v.kubeadm)-minorOf(oldest.version) > maxKubeadmSkew {
+			violations = append(violations, fmt.Sprintf("kubeadm on node %s (v%s) is more than %d minor version newer than the API server on node %s (v%s)",
+				v.name, v.kubeadm, maxKubeadmSkew, oldest.name, oldest.version))
+		}
+	}
+	return violations
+}
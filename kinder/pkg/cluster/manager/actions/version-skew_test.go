@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+func TestVersionSkewViolations(t *testing.T) {
+	cp := func(name, kubeadm, kubelet, apiServer string) componentVersions {
+		v := componentVersions{
+			name:    name,
+			kubeadm: K8sVersion.MustParseSemantic(kubeadm),
+			kubelet: K8sVersion.MustParseSemantic(kubelet),
+		}
+		if apiServer != "" {
+			v.apiServer = K8sVersion.MustParseSemantic(apiServer)
+		}
+		return v
+	}
+	w := func(name, kubeadm, kubelet string) componentVersions {
+		return cp(name, kubeadm, kubelet, "")
+	}
+
+	tests := []struct {
+		name     string
+		versions []componentVersions
+		expected int
+	}{
+		{
+			name:     "all components on the same version",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), w("w1", "v1.30.0", "v1.30.0")},
+		},
+		{
+			name:     "nodes to join, before init",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", ""), w("w1", "v1.30.0", "v1.30.0")},
+		},
+		{
+			name:     "kubeadm one minor newer than the kubelet on the node to join",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), w("w1", "v1.31.0", "v1.30.0")},
+		},
+		{
+			name:     "kubelet newer than kubeadm",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), w("w1", "v1.30.0", "v1.31.0")},
+			// the kubelet is newer than kubeadm, and newer than the API server
+			expected: 2,
+		},
+		{
+			name:     "kubeadm older than the API server",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), w("w1", "v1.29.0", "v1.29.0")},
+			expected: 1,
+		},
+		{
+			name:     "kubeadm two minors newer than the API server",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), w("w1", "v1.32.0", "v1.30.0")},
+			expected: 1,
+		},
+		{
+			name:     "kubelets four minors older",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), w("w1", "v1.30.0", "v1.26.0")},
+			// the kubelet is too old both for kubeadm and for the API server
+			expected: 2,
+		},
+		{
+			name:     "API servers two minors apart",
+			versions: []componentVersions{cp("cp1", "v1.30.0", "v1.30.0", "v1.30.0"), cp("cp2", "v1.30.0", "v1.28.0", "v1.28.0")},
+			// the API server skew, the kubelet on cp1 newer than the API server on cp2, kubeadm on both nodes
+			// more than one minor newer than the API server on cp2
+			expected: 4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			violations := versionSkewViolations(test.versions)
+			if len(violations) != test.expected {
+				t.Errorf("expected %d violations, got %d: %v", test.expected, len(violations), violations)
+			}
+		})
+	}
+}
@@ -27,9 +27,12 @@ import (
 
 	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/failure"
 )
 
-// waitNewControlPlaneNodeReady waits for a new control plane node reaching the target state after init/join
+// waitNewControlPlaneNodeReady waits for a new control plane node reaching the target state after init/join;
+// on timeout, the returned error includes the logs of the failing static pods and of the kubelet
 func waitNewControlPlaneNodeReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
 	n.Infof("waiting for Node and control-plane Pods to become Ready (timeout %s)", wait)
 	if pass := waitFor(c, n, wait,
@@ -38,9 +41,9 @@ func waitNewControlPlaneNodeReady(c *status.Cluster, n *status.Node, wait time.D
 		staticPodIsReady("kube-controller-manager"),
 		staticPodIsReady("kube-scheduler"),
 	); !pass {
-		return errors.New("timeout: Node and control-plane did not reach target state")
+		return withRecentWarningEvents(c, controlPlaneTimeoutError(n, "timeout: Node and control-plane did not reach target state"))
 	}
-	fmt.Println()
+	exec.Println()
 	return nil
 }
 
@@ -48,9 +51,39 @@ func waitForPodsRunning(c *status.Cluster, n *status.Node, wait time.Duration, l
 	if pass := waitFor(c, n, wait,
 		podsAreRunning(n, label, replicas),
 	); !pass {
-		return errors.New("timeout: Node and control-plane did not reach target state")
+		return timeoutErrorf("Node and control-plane did not reach target state")
 	}
-	fmt.Println()
+	exec.Println()
+	return nil
+}
+
+// waitAPIServerReachable waits for the API server endpoint to be reachable from a node before joining it
+func waitAPIServerReachable(c *status.Cluster, n *status.Node, wait time.Duration) error {
+	endpoint, err := c.APIServerEndpoint()
+	if err != nil {
+		return err
+	}
+
+	n.Infof("waiting for the API server endpoint %s to be reachable (timeout %s)", endpoint, wait)
+	if pass := waitFor(c, n, wait,
+		apiServerIsReachable(endpoint),
+	); !pass {
+		return timeoutErrorf("API server endpoint %s is not reachable from node %s", endpoint, n.Name())
+	}
+	exec.Println()
+	return nil
+}
+
+// waitLoadBalancerBackendUp waits for the API server on a control-plane node to be up as a backend of the
+// external load balancer
+func waitLoadBalancerBackendUp(c *status.Cluster, n *status.Node, wait time.Duration) error {
+	n.Infof("waiting for the API server to be up in the load balancer backends (timeout %s)", wait)
+	if pass := waitFor(c, n, wait,
+		loadBalancerBackendIsUp,
+	); !pass {
+		return timeoutErrorf("the API server on node %s is not up in the load balancer backends", n.Name())
+	}
+	exec.Println()
 	return nil
 }
 
@@ -60,9 +93,9 @@ func waitForNodePort(c *status.Cluster, n *status.Node, wait time.Duration, node
 	if pass := waitFor(c, n, wait,
 		nodePortIsReady(n, nodePort),
 	); !pass {
-		return errors.New("timeout: NodePort not ready")
+		return timeoutErrorf("NodePort not ready")
 	}
-	fmt.Println()
+	exec.Println()
 	return nil
 }
 
@@ -72,9 +105,57 @@ func waitNewWorkerNodeReady(c *status.Cluster, n *status.Node, wait time.Duratio
 	if pass := waitFor(c, n, wait,
 		nodeIsReady,
 	); !pass {
-		return errors.New("timeout: Node did not reach target state")
+		return withRecentWarningEvents(c, timeoutErrorf("Node did not reach target state"))
 	}
-	fmt.Println()
+	exec.Println()
+	return nil
+}
+
+// waitAllNodesReady waits for the expected number of nodes in the cluster to be ready
+func waitAllNodesReady(c *status.Cluster, n *status.Node, expected int, wait time.Duration) error {
+	n.Infof("waiting for %d Nodes to become Ready (timeout %s)", expected, wait)
+	if pass := waitFor(c, n, wait,
+		allNodesAreReady(expected),
+	); !pass {
+		return withRecentWarningEvents(c, timeoutErrorf("%d Nodes did not reach target state", expected))
+	}
+	exec.Println()
+	return nil
+}
+
+// waitCoreDNSReady waits for all the CoreDNS replicas to be ready and for the DNS service to have endpoints
+func waitCoreDNSReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
+	n.Infof("waiting for CoreDNS to become Ready (timeout %s)", wait)
+	if pass := waitFor(c, n, wait,
+		coreDNSIsReady,
+		dnsServiceHasEndpoints,
+	); !pass {
+		return withRecentWarningEvents(c, timeoutErrorf("CoreDNS did not reach target state"))
+	}
+	exec.Println()
+	return nil
+}
+
+// waitDaemonSetsReady waits for the DaemonSets defined at create time, if any, to be rolled out on all the nodes
+// they should run on, e.g. so a cluster is considered ready only once the CNI plugin is actually rolled out
+func waitDaemonSetsReady(c *status.Cluster, n *status.Node, wait time.Duration) error {
+	daemonSets, err := n.WaitDaemonSets()
+	if err != nil {
+		return err
+	}
+	if len(daemonSets) == 0 {
+		return nil
+	}
+
+	n.Infof("waiting for DaemonSets %s to roll out (timeout %s)", strings.Join(daemonSets, ", "), wait)
+	var conditions []try
+	for _, ds := range daemonSets {
+		conditions = append(conditions, daemonSetIsReady(ds))
+	}
+	if pass := waitFor(c, n, wait, conditions...); !pass {
+		return withRecentWarningEvents(c, timeoutErrorf("DaemonSets %s did not reach target state", strings.Join(daemonSets, ", ")))
+	}
+	exec.Println()
 	return nil
 }
 
@@ -88,9 +169,9 @@ func waitControlPlaneUpgraded(c *status.Cluster, n *status.Node, upgradeVersion
 		staticPodHasVersion("kube-controller-manager", version),
 		staticPodHasVersion("kube-scheduler", version),
 	); !pass {
-		return errors.New("timeout: control-plane did not reach target state")
+		return timeoutErrorf("control-plane did not reach target state")
 	}
-	fmt.Println()
+	exec.Println()
 	return nil
 }
 
@@ -100,11 +181,12 @@ func waitKubeletUpgraded(c *status.Cluster, n *status.Node, upgradeVersion *K8sV
 
 	n.Infof("waiting for node to restart with the new version (timeout %s)", wait)
 	if pass := waitFor(c, n, wait,
+		kubeletIsHealthy,
 		nodeHasKubernetesVersion(version),
 	); !pass {
-		return errors.New("timeout: node did not reach target state")
+		return timeoutErrorf("node did not reach target state")
 	}
-	fmt.Println()
+	exec.Println()
 	return nil
 }
 
@@ -116,12 +198,17 @@ func waitKubeletHasRBAC(c *status.Cluster, n *status.Node, upgradeVersion *K8sVe
 	if pass := waitFor(c, n, wait,
 		kubeletHasRBAC(upgradeVersion.Major(), upgradeVersion.Minor()),
 	); !pass {
-		return errors.New("timeout: Node did not reach target state")
+		return timeoutErrorf("Node did not reach target state")
 	}
-	fmt.Println()
+	exec.Println()
 	return nil
 }
 
+// timeoutErrorf returns an error for a wait exceeding its timeout, with the timeout failure category
+func timeoutErrorf(format string, args ...interface{}) error {
+	return failure.WithCategory(errors.Errorf("timeout: "+format, args...), failure.Timeout)
+}
+
 // try defines a function that test a condition to be waited for
 type try func(*status.Cluster, *status.Node) bool
 
@@ -130,7 +217,7 @@ type try func(*status.Cluster, *status.Node) bool
 func waitFor(c *status.Cluster, n *status.Node, timeout time.Duration, conditions ...try) bool {
 	// if timeout is 0 or no conditions are defined, exit fast
 	if timeout == time.Duration(0) {
-		fmt.Println("Timeout set 0, skipping wait")
+		exec.Println("Timeout set 0, skipping wait")
 		return true
 	}
 
@@ -184,12 +271,126 @@ func nodeIsReady(c *status.Cluster, n *status.Node) bool {
 		"nodes",
 		"--kubeconfig=/etc/kubernetes/admin.conf",
 		// check for the selected node
-		fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.Name()),
+		fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.KubeNodeName()),
 		// check for status.conditions type:Ready
 		"-o=jsonpath='{.items..status.conditions[?(@.type == \"Ready\")].status}'",
 	)
 	if strings.Contains(output, "True") {
-		fmt.Printf("Node %s is ready\n", n.Name())
+		exec.Printf("Node %s is ready\n", n.Name())
+		return true
+	}
+	return false
+}
+
+// allNodesAreReady implement a function that test when the expected number of nodes is ready
+func allNodesAreReady(expected int) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		output := kubectlOutput(c.BootstrapControlPlane(),
+			"get",
+			"nodes",
+			"--kubeconfig=/etc/kubernetes/admin.conf",
+			// check for status.conditions type:Ready of all the nodes
+			"-o=jsonpath='{.items[*].status.conditions[?(@.type == \"Ready\")].status}'",
+		)
+		if ready := countReadyNodes(output); ready >= expected {
+			exec.Printf("%d Nodes are ready\n", ready)
+			return true
+		}
+		return false
+	}
+}
+
+// countReadyNodes counts the nodes with condition Ready=True in the kubectl output
+func countReadyNodes(output string) int {
+	ready := 0
+	for _, s := range strings.Fields(strings.Trim(output, "'")) {
+		if s == "True" {
+			ready++
+		}
+	}
+	return ready
+}
+
+// coreDNSIsReady implement a function that test when all the replicas of the CoreDNS deployment are ready
+func coreDNSIsReady(c *status.Cluster, n *status.Node) bool {
+	output := kubectlOutput(c.BootstrapControlPlane(),
+		"get",
+		"deployments",
+		"--kubeconfig=/etc/kubernetes/admin.conf",
+		"-n=kube-system",
+		"coredns",
+		"-o=jsonpath='{.status.readyReplicas}/{.spec.replicas}'",
+	)
+	replicas := strings.Split(strings.Trim(output, "'"), "/")
+	if len(replicas) == 2 && replicas[0] != "" && replicas[0] != "0" && replicas[0] == replicas[1] {
+		exec.Printf("CoreDNS has %s ready replicas\n", replicas[0])
+		return true
+	}
+	return false
+}
+
+// daemonSetIsReady implement a function that test when a DaemonSet, in the namespace/name form, has the
+// current generation ready and updated on all the nodes it should run on
+func daemonSetIsReady(daemonSet string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		namespace, name := "kube-system", daemonSet
+		if i := strings.Index(daemonSet, "/"); i >= 0 {
+			namespace, name = daemonSet[:i], daemonSet[i+1:]
+		}
+		output := kubectlOutput(c.BootstrapControlPlane(),
+			"get",
+			"daemonsets",
+			"--kubeconfig=/etc/kubernetes/admin.conf",
+			fmt.Sprintf("-n=%s", namespace),
+			name,
+			"-o=jsonpath='{.metadata.generation}/{.status.observedGeneration}/{.status.desiredNumberScheduled}/{.status.numberReady}/{.status.updatedNumberScheduled}'",
+		)
+		if desired, ok := daemonSetRolledOut(output); ok {
+			exec.Printf("DaemonSet %s is ready on %s nodes\n", daemonSet, desired)
+			return true
+		}
+		return false
+	}
+}
+
+// daemonSetRolledOut parses the generation, observed generation, desired, ready and updated pods of a DaemonSet in
+// the kubectl output, and returns the desired pods and true if the DaemonSet is rolled out on all the desired nodes
+func daemonSetRolledOut(output string) (string, bool) {
+	fields := strings.Split(strings.Trim(output, "'"), "/")
+	if len(fields) != 5 {
+		return "", false
+	}
+	generation, observed, desired, ready, updated := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if generation == "" || generation != observed {
+		return "", false
+	}
+	if desired == "" || desired == "0" || ready != desired || updated != desired {
+		return "", false
+	}
+	return desired, true
+}
+
+// dnsServiceHasEndpoints implement a function that test when the DNS service has ready endpoints
+func dnsServiceHasEndpoints(c *status.Cluster, n *status.Node) bool {
+	output := kubectlOutput(c.BootstrapControlPlane(),
+		"get",
+		"endpoints",
+		"--kubeconfig=/etc/kubernetes/admin.conf",
+		"-n=kube-system",
+		"kube-dns",
+		"-o=jsonpath='{.subsets[*].addresses[*].ip}'",
+	)
+	if strings.Trim(output, "' ") != "" {
+		exec.Println("DNS service has ready endpoints")
+		return true
+	}
+	return false
+}
+
+// kubeletIsHealthy implement a function that test when the kubelet on a node is healthy
+func kubeletIsHealthy(c *status.Cluster, n *status.Node) bool {
+	if ready, _ := n.IsReady(); ready {
+		exec.Printf("kubelet on node %s is healthy\n", n.Name())
 		return true
 	}
 	return false
@@ -203,12 +404,12 @@ func nodeHasKubernetesVersion(version string) func(c *status.Cluster, n *status.
 			"nodes",
 			"--kubeconfig=/etc/kubernetes/admin.conf",
 			// check for the selected node
-			fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.Name()),
+			fmt.Sprintf("-l=kubernetes.io/hostname=%s", n.KubeNodeName()),
 			// check for the kubelet version
 			"-o=jsonpath='{.items..status.nodeInfo.kubeletVersion}'",
 		)
 		if strings.Contains(output, version) {
-			fmt.Printf("Node %s has Kubernetes version %s\n", n.Name(), version)
+			exec.Printf("Node %s has Kubernetes version %s\n", n.Name(), version)
 			return true
 		}
 		return false
@@ -224,12 +425,12 @@ func staticPodIsReady(pod string) func(c *status.Cluster, n *status.Node) bool {
 			"--kubeconfig=/etc/kubernetes/admin.conf",
 			"-n=kube-system",
 			// check for static pods existing on the selected node
-			fmt.Sprintf("%s-%s", pod, n.Name()),
+			fmt.Sprintf("%s-%s", pod, n.KubeNodeName()),
 			// check for status.conditions type:Ready
 			"-o=jsonpath='{.status.conditions[?(@.type == \"Ready\")].status}'",
 		)
 		if strings.Contains(output, "True") {
-			fmt.Printf("Pod %s-%s is ready\n", pod, n.Name())
+			exec.Printf("Pod %s-%s is ready\n", pod, n.Name())
 			return true
 		}
 		return false
@@ -261,7 +462,7 @@ func podsAreRunning(n *status.Node, label string, replicas int) func(c *status.C
 		}
 
 		if running {
-			fmt.Printf("%d pods running!", replicas)
+			exec.Printf("%d pods running!", replicas)
 			return true
 		}
 
@@ -269,6 +470,42 @@ func podsAreRunning(n *status.Node, label string, replicas int) func(c *status.C
 	}
 }
 
+// apiServerIsReachable implements a function that tests if the API server health endpoint answers from a node
+func apiServerIsReachable(endpoint string) func(c *status.Cluster, n *status.Node) bool {
+	return func(c *status.Cluster, n *status.Node) bool {
+		lines, err := n.Command(
+			"curl", "-k", "-s", "-o", "/dev/null", "-w", "%{http_code}", fmt.Sprintf("https://%s/healthz", endpoint),
+		).Silent().RunAndCapture()
+		if err != nil || len(lines) != 1 {
+			return false
+		}
+
+		if lines[0] == "200" {
+			exec.Printf("API server endpoint %s is reachable from node %s\n", endpoint, n.Name())
+			return true
+		}
+
+		return false
+	}
+}
+
+// loadBalancerBackendIsUp implements a function that tests if the API server on a node is up
+// as a backend of the external load balancer
+func loadBalancerBackendIsUp(c *status.Cluster, n *status.Node) bool {
+	backends, err := c.LoadBalancerBackends()
+	if err != nil {
+		return false
+	}
+
+	for _, b := range backends {
+		if b.Name == n.Name() && b.IsUp() {
+			exec.Printf("API server on node %s is up in the load balancer backends\n", n.Name())
+			return true
+		}
+	}
+	return false
+}
+
 // nodePortIsReady implements a function that tests if a nodePort is ready
 func nodePortIsReady(n *status.Node, port string) func(c *status.Cluster, n *status.Node) bool {
 	return func(c *status.Cluster, n *status.Node) bool {
@@ -287,7 +524,7 @@ func nodePortIsReady(n *status.Node, port string) func(c *status.Cluster, n *sta
 		}
 
 		if strings.Trim(lines[0], "\n\r") == "HTTP/1.1 200 OK" {
-			fmt.Printf("node port %s on node %s is ready...", port, n.Name())
+			exec.Printf("node port %s on node %s is ready...", port, n.Name())
 			return true
 		}
 
@@ -304,14 +541,14 @@ func staticPodHasVersion(pod, version string) func(c *status.Cluster, n *status.
 			"--kubeconfig=/etc/kubernetes/admin.conf",
 			"-n=kube-system",
 			// check for static pods existing on the selected node
-			fmt.Sprintf("%s-%s", pod, n.Name()),
+			fmt.Sprintf("%s-%s", pod, n.KubeNodeName()),
 			// check for the node image
 			// NB. this assumes the Pod has only one container only
 			// which is true for the control plane pods
 			"-o=jsonpath='{.spec.containers[0].image}'",
 		)
 		if strings.Contains(output, version) {
-			fmt.Printf("Pod %s-%s has Kubernetes version %s\n", pod, n.Name(), version)
+			exec.Printf("Pod %s-%s has Kubernetes version %s\n", pod, n.Name(), version)
 			return true
 		}
 		return false
@@ -354,7 +591,7 @@ func kubeletHasRBAC(major, minor uint) func(c *status.Cluster, n *status.Node) b
 			return false
 		}
 
-		fmt.Println("kubelet has access to expected config maps")
+		exec.Println("kubelet has access to expected config maps")
 		return true
 	}
 }
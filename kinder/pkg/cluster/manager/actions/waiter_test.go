@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actions
+
+import (
+	"testing"
+)
+
+func TestCountReadyNodes(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected int
+	}{
+		{output: "", expected: 0},
+		{output: "''", expected: 0},
+		{output: "'True'", expected: 1},
+		{output: "'True False Unknown True'", expected: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.output, func(t *testing.T) {
+			if ready := countReadyNodes(test.output); ready != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, ready)
+			}
+		})
+	}
+}
+
+func TestDaemonSetRolledOut(t *testing.T) {
+	tests := []struct {
+		name            string
+		output          string
+		expectedDesired string
+		expectedReady   bool
+	}{
+		{name: "no output", output: "", expectedReady: false},
+		{name: "rolled out", output: "'2/2/3/3/3'", expectedDesired: "3", expectedReady: true},
+		{name: "not all pods ready", output: "'1/1/3/2/3'", expectedReady: false},
+		{name: "not all pods updated", output: "'2/2/3/3/1'", expectedReady: false},
+		{name: "generation not observed yet", output: "'2/1/3/3/3'", expectedReady: false},
+		{name: "no pods scheduled yet", output: "'1/1/0/0/0'", expectedReady: false},
+		{name: "status not set yet", output: "'1////'", expectedReady: false},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			desired, ready := daemonSetRolledOut(rt.output)
+			if ready != rt.expectedReady || desired != rt.expectedDesired {
+				t.Errorf("expected %q %t, got %q %t", rt.expectedDesired, rt.expectedReady, desired, ready)
+			}
+		})
+	}
+}
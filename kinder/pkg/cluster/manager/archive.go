@@ -0,0 +1,779 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes/common"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
+)
+
+const (
+	// archiveMetadataFile is the name of the file describing the cluster in a cluster archive
+	archiveMetadataFile = "cluster.json"
+
+	// archiveRootfsFile is the name of the file with the node container filesystem, as exported by docker export
+	archiveRootfsFile = "rootfs.tar"
+
+	// archiveVarFile is the name of the file with the content of the node /var volume, that is not
+	// included in docker export
+	archiveVarFile = "var.tar"
+
+	// archiveImageRepository is the repository of the images created when importing a cluster archive
+	archiveImageRepository = "kinder-archive"
+)
+
+// clusterArchive defines the content of the cluster archive metadata file
+type clusterArchive struct {
+	// Name of the exported cluster
+	Name string `json:"name"`
+
+	// Settings are the cluster settings recorded at create time
+	Settings status.ClusterSettings `json:"settings"`
+
+	// ExternalLoadBalancer and ExternalRegistry report if the cluster had an external load balancer or
+	// an external registry; those nodes are not exported, but created again on import
+	ExternalLoadBalancer bool `json:"externalLoadBalancer,omitempty"`
+	ExternalRegistry     bool `json:"externalRegistry,omitempty"`
+
+	// LoadBalancer is the implementation of the external load balancer, if any
+	LoadBalancer loadbalancer.Implementation `json:"loadBalancer,omitempty"`
+
+	// LoadBalancerIPs are the IPs of the external load balancer, if any, that are used by the control-plane
+	// endpoint; they are assigned again on import, so certificates and kubeconfig files are still valid
+	LoadBalancerIPs status.NodeIPs `json:"loadBalancerIPs,omitempty"`
+
+	// NetworkSubnets are the subnets of the cluster network, used for creating the network on import
+	// if it does not exist, so the node IPs can be assigned again
+	NetworkSubnets []string `json:"networkSubnets,omitempty"`
+
+	// Nodes are the exported K8s nodes
+	Nodes []archivedNode `json:"nodes"`
+}
+
+// archivedNode defines an exported node, with the settings required for creating the node container again
+type archivedNode struct {
+	// Name of the node, without the cluster name prefix
+	Name string `json:"name"`
+
+	Role              string               `json:"role"`
+	Network           string               `json:"network,omitempty"`
+	APIServerBindPort int32                `json:"apiServerBindPort,omitempty"`
+	ExtraPortMappings []status.PortMapping `json:"extraPortMappings,omitempty"`
+	DNS               status.NodeDNS       `json:"dns,omitempty"`
+	Resources         status.NodeResources `json:"resources,omitempty"`
+
+	// IPs are the node IPs used by kubeadm, if kubeadm was executed on the node; they are assigned again
+	// on import, because they are referenced by static pod manifests, certificates and kubeconfig files
+	IPs status.NodeIPs `json:"ips,omitempty"`
+
+	// Labels are the kinder labels applied at create time
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Entrypoint, Cmd, Env and StopSignal are the node container config, that is lost by docker export
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+	Env        []string `json:"env,omitempty"`
+	StopSignal string   `json:"stopSignal,omitempty"`
+}
+
+// containerConfig defines the subset of the docker container config recorded in cluster archives
+type containerConfig struct {
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	StopSignal string
+	Labels     map[string]string
+}
+
+// containerHostConfig defines the subset of the docker container host config recorded in cluster archives
+type containerHostConfig struct {
+	DNS        []string `json:"Dns"`
+	DNSSearch  []string `json:"DnsSearch"`
+	DNSOptions []string `json:"DnsOptions"`
+}
+
+// ExportCluster exports a stopped cluster into a tarball, with the filesystem of each K8s node and
+// the settings recorded at create time; the tarball can be imported with ImportCluster.
+func ExportCluster(clusterName, archivePath string) error {
+	known, err := status.IsKnown(clusterName)
+	if err != nil {
+		return err
+	}
+	if !known {
+		return errors.Errorf("a cluster with the name %q does not exists", clusterName)
+	}
+
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		return err
+	}
+	if c.ExternalEtcd() != nil {
+		return errors.New("clusters with an external etcd can't be exported")
+	}
+	for _, n := range c.AllNodes() {
+		running, err := n.IsRunning()
+		if err != nil {
+			return err
+		}
+		if running {
+			return errors.Errorf("node %s is running; stop all the cluster nodes before exporting the cluster", n.Name())
+		}
+	}
+
+	settings, err := archiveSettings(c)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "kinder-export-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	a := clusterArchive{
+		Name:                 clusterName,
+		Settings:             *settings,
+		ExternalLoadBalancer: c.ExternalLoadBalancer() != nil,
+		ExternalRegistry:     c.ExternalRegistry() != nil,
+	}
+	if a.ExternalLoadBalancer {
+		if a.LoadBalancer, err = c.LoadBalancerImplementation(); err != nil {
+			return err
+		}
+		if a.LoadBalancerIPs, err = archivedLoadBalancerIPs(c.BootstrapControlPlane(), dir); err != nil {
+			return err
+		}
+	}
+
+	// node IPs can be assigned again on import only on user defined networks
+	network, err := c.Network()
+	if err != nil {
+		return err
+	}
+	defaultNetwork := network == "" || network == common.DefaultNetwork
+	if !defaultNetwork {
+		if a.NetworkSubnets, err = networkSubnets(network); err != nil {
+			return err
+		}
+	}
+	errDefaultNetwork := errors.New("clusters on the default docker network can't be exported, because node IPs can't be assigned again on import. Use kinder create cluster --network")
+	if defaultNetwork && a.LoadBalancerIPs != (status.NodeIPs{}) {
+		return errDefaultNetwork
+	}
+
+	files := []string{archiveMetadataFile}
+	for _, n := range c.K8sNodes() {
+		fmt.Printf("Exporting node %s...\n", n.Name())
+
+		an, err := archiveNode(clusterName, n, dir)
+		if err != nil {
+			return err
+		}
+		if err := validateArchivedNodeName(an.Name); err != nil {
+			return err
+		}
+		if defaultNetwork && an.IPs != (status.NodeIPs{}) {
+			return errDefaultNetwork
+		}
+		a.Nodes = append(a.Nodes, *an)
+
+		nodeDir := path.Join("nodes", an.Name)
+		if err := os.MkdirAll(filepath.Join(dir, nodeDir), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create the directory for node %s", n.Name())
+		}
+
+		rootfs := path.Join(nodeDir, archiveRootfsFile)
+		if err := exec.NewHostCmd(
+			"docker", "export", "--output", filepath.Join(dir, rootfs), n.Name(),
+		).Run(); err != nil {
+			return errors.Wrapf(err, "failed to export node %s", n.Name())
+		}
+
+		// /var is a volume, so it should be copied separately
+		varTar := path.Join(nodeDir, archiveVarFile)
+		f, err := os.Create(filepath.Join(dir, varTar))
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %s", varTar)
+		}
+		err = exec.NewHostCmd(
+			"docker", "cp", fmt.Sprintf("%s:/var", n.Name()), "-",
+		).Stdout(f).Run()
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy /var from node %s", n.Name())
+		}
+
+		files = append(files, rootfs, varTar)
+	}
+
+	metadata, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the cluster archive metadata")
+	}
+	if err := os.WriteFile(filepath.Join(dir, archiveMetadataFile), metadata, 0644); err != nil {
+		return errors.Wrap(err, "failed to write the cluster archive metadata")
+	}
+
+	if err := writeTar(archivePath, dir, files); err != nil {
+		return errors.Wrapf(err, "failed to write %s", archivePath)
+	}
+
+	fmt.Printf("Cluster %s exported to %s\n", clusterName, archivePath)
+	return nil
+}
+
+// ImportCluster recreates a cluster from a tarball created by ExportCluster, with the same node IPs; clusterName,
+// if set, should match the name of the exported cluster, because the node hostnames are referenced by certificates
+// and kubeconfig files. In case of errors nodes are deleted, except if retain is set.
+func ImportCluster(archivePath, clusterName string, retain bool) error {
+	dir, err := os.MkdirTemp("", "kinder-import-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	if err := readTar(archivePath, dir); err != nil {
+		return errors.Wrapf(err, "failed to read %s", archivePath)
+	}
+
+	metadata, err := os.ReadFile(filepath.Join(dir, archiveMetadataFile))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the cluster archive metadata")
+	}
+	var a clusterArchive
+	if err := json.Unmarshal(metadata, &a); err != nil {
+		return errors.Wrap(err, "failed to decode the cluster archive metadata")
+	}
+	if len(a.Nodes) == 0 {
+		return errors.New("the cluster archive does not contain any node")
+	}
+	for _, an := range a.Nodes {
+		if err := validateArchivedNodeName(an.Name); err != nil {
+			return err
+		}
+	}
+
+	if clusterName == "" {
+		clusterName = a.Name
+	}
+	if clusterName != a.Name {
+		return errors.Errorf("the cluster %q can't be imported with a different name, because node hostnames are referenced by certificates and kubeconfig files", a.Name)
+	}
+	known, err := status.IsKnown(clusterName)
+	if err != nil {
+		return err
+	}
+	if known {
+		return errors.Errorf("a cluster with the name %q already exists", clusterName)
+	}
+
+	// creates the node images from the exported node filesystems
+	images := map[string]string{}
+	for _, an := range a.Nodes {
+		image := fmt.Sprintf("%s/%s-%s:latest", archiveImageRepository, clusterName, an.Name)
+		args := []string{"import"}
+		for _, change := range importChanges(an) {
+			args = append(args, "--change", change)
+		}
+		args = append(args, filepath.Join(dir, "nodes", an.Name, archiveRootfsFile), image)
+		if err := exec.NewHostCmd("docker", args...).Run(); err != nil {
+			return errors.Wrapf(err, "failed to import the image for node %s", an.Name)
+		}
+		images[an.Name] = image
+	}
+
+	runtime, err := status.InspectCRIinImage(images[a.Nodes[0].Name])
+	if err != nil {
+		return err
+	}
+	log.Infof("Detected %s container runtime for the imported nodes", runtime)
+
+	network := a.Nodes[0].Network
+	ipv6 := a.Settings.IPFamily == status.IPv6Family || a.Settings.IPFamily == status.DualStackFamily
+	if err := ensureNetwork(network, ipv6, a.NetworkSubnets); err != nil {
+		return err
+	}
+
+	handleErr := func(err error) error {
+		if !retain {
+			if err := deleteNodes(clusterName); err != nil {
+				return err
+			}
+		}
+		log.Error(err)
+		return err
+	}
+
+	createHelper, err := nodes.NewCreateHelper(runtime, network)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Importing nodes %s\n", strings.Repeat("📦", len(a.Nodes)))
+	for _, an := range a.Nodes {
+		name := fmt.Sprintf("%s-%s", clusterName, an.Name)
+		if err := createHelper.CreateNode(clusterName, name, images[an.Name], an.Role, nil, an.ExtraPortMappings, an.APIServerBindPort, an.Labels, an.DNS, an.Resources, an.IPs, ""); err != nil {
+			return handleErr(errors.Wrapf(err, "failed to create node %s", name))
+		}
+
+		// restores the /var volume, that should be done while the node is stopped
+		if err := exec.NewHostCmd("docker", "stop", name).Run(); err != nil {
+			return handleErr(errors.Wrapf(err, "failed to stop node %s", name))
+		}
+		f, err := os.Open(filepath.Join(dir, "nodes", an.Name, archiveVarFile))
+		if err != nil {
+			return handleErr(errors.Wrapf(err, "failed to read /var for node %s", name))
+		}
+		err = exec.NewHostCmd("docker", "cp", "-", fmt.Sprintf("%s:/", name)).Stdin(f).Run()
+		f.Close()
+		if err != nil {
+			return handleErr(errors.Wrapf(err, "failed to restore /var on node %s", name))
+		}
+		if err := exec.NewHostCmd("docker", "start", name).Run(); err != nil {
+			return handleErr(errors.Wrapf(err, "failed to start node %s", name))
+		}
+	}
+
+	if a.ExternalLoadBalancer {
+		// archives created before the load balancer implementation could be selected use the default implementation
+		implementation, err := loadbalancer.ParseImplementation(string(a.LoadBalancer))
+		if err != nil {
+			return handleErr(err)
+		}
+		if err := createHelper.CreateExternalLoadBalancer(clusterName, fmt.Sprintf("%s-%s", clusterName, constants.ExternalLoadBalancerNodeRoleValue), implementation, a.LoadBalancerIPs); err != nil {
+			return handleErr(errors.Wrap(err, "failed to create the external load balancer"))
+		}
+	}
+	if a.ExternalRegistry {
+		if err := createHelper.CreateExternalRegistry(clusterName, fmt.Sprintf("%s-%s", clusterName, constants.ExternalRegistryNodeRoleValue)); err != nil {
+			return handleErr(errors.Wrap(err, "failed to create the external registry"))
+		}
+	}
+
+	// kernel parameters are not part of the exported node filesystems, so the sysctls recorded at create time
+	// are applied again on the imported K8s nodes
+	if len(a.Settings.NodeSysctls) > 0 {
+		c, err := status.FromDocker(clusterName)
+		if err != nil {
+			return handleErr(err)
+		}
+		log.Info("Applying sysctls on the K8s nodes...")
+		for _, n := range c.K8sNodes() {
+			if err := applyNodeSysctls(n, a.Settings.NodeSysctls); err != nil {
+				return handleErr(err)
+			}
+		}
+	}
+
+	// the load balancer is created again without backends, so it should be configured
+	if a.ExternalLoadBalancer {
+		m, err := NewClusterManager(clusterName)
+		if err != nil {
+			return handleErr(err)
+		}
+		if err := m.DoAction("loadbalancer"); err != nil {
+			return handleErr(errors.Wrap(err, "failed to configure the external load balancer"))
+		}
+	}
+
+	fmt.Printf("Cluster %s imported from %s\n", clusterName, archivePath)
+	return nil
+}
+
+// archiveSettings returns the cluster settings recorded at create time; differently from ReadSettings,
+// settings are read from the docker labels only, so it works also when the nodes are stopped
+func archiveSettings(c *status.Cluster) (*status.ClusterSettings, error) {
+	cp1 := c.BootstrapControlPlane()
+	if cp1 == nil {
+		return nil, errors.New("the cluster does not have a bootstrap control-plane node")
+	}
+	settings, err := cp1.ReadClusterSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings.APIServerBindPort, err = cp1.APIServerBindPort(); err != nil {
+		return nil, err
+	}
+	kubeProxy, err := cp1.KubeProxy()
+	if err != nil {
+		return nil, err
+	}
+	if kubeProxy == constants.KubeProxyDisabled {
+		settings.SkipKubeProxy = true
+	} else {
+		settings.KubeProxyMode = kubeProxy
+	}
+	if settings.NodeCIDRMaskSize, settings.NodeCIDRMaskSizeIPv6, err = cp1.NodeCIDRMaskSize(); err != nil {
+		return nil, err
+	}
+	if settings.CNI, err = cp1.CNI(); err != nil {
+		return nil, err
+	}
+	if settings.KubeadmClusterName, err = cp1.KubeadmClusterName(); err != nil {
+		return nil, err
+	}
+	if settings.EtcdExtraArgs, err = cp1.EtcdExtraArgs(); err != nil {
+		return nil, err
+	}
+	if settings.ExternalEtcdEndpoints, err = cp1.ExternalEtcdEndpoints(); err != nil {
+		return nil, err
+	}
+	if settings.KubeletServingCertRotation, err = cp1.KubeletServingCertRotation(); err != nil {
+		return nil, err
+	}
+	if settings.NodeSysctls, err = cp1.NodeSysctls(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// archiveNode returns the settings required for creating again the node container; dir is
+// a temporary directory used for reading files from the stopped node
+func archiveNode(clusterName string, n *status.Node, dir string) (*archivedNode, error) {
+	var config containerConfig
+	if err := inspectJSON(n.Name(), "{{json .Config}}", &config); err != nil {
+		return nil, err
+	}
+	var hostConfig containerHostConfig
+	if err := inspectJSON(n.Name(), "{{json .HostConfig}}", &hostConfig); err != nil {
+		return nil, err
+	}
+
+	network, err := n.Network()
+	if err != nil {
+		return nil, err
+	}
+	portMappings, err := n.ExtraPortMappings()
+	if err != nil {
+		return nil, err
+	}
+	resources, err := n.Resources()
+	if err != nil {
+		return nil, err
+	}
+	var apiServerBindPort int32
+	if n.IsControlPlane() {
+		if apiServerBindPort, err = n.APIServerBindPort(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the node IPs used by kubeadm are read from the kubelet flags, because docker inspect
+	// does not report IPs for stopped containers
+	var ips status.NodeIPs
+	if flags, ok := readStoppedNodeFile(n, kubeletFlagsPath, dir); ok {
+		ips = parseKubeletNodeIPs(flags)
+	}
+
+	return &archivedNode{
+		Name:              strings.TrimPrefix(n.Name(), clusterName+"-"),
+		Role:              n.Role(),
+		Network:           network,
+		APIServerBindPort: apiServerBindPort,
+		ExtraPortMappings: portMappings,
+		DNS: status.NodeDNS{
+			Servers: hostConfig.DNS,
+			Search:  hostConfig.DNSSearch,
+			Options: hostConfig.DNSOptions,
+		},
+		Resources:  resources,
+		IPs:        ips,
+		Labels:     archivedLabels(config.Labels),
+		Entrypoint: config.Entrypoint,
+		Cmd:        config.Cmd,
+		Env:        config.Env,
+		StopSignal: config.StopSignal,
+	}, nil
+}
+
+const (
+	// kubeletFlagsPath is the file with the kubelet flags written by kubeadm, including the node IPs
+	kubeletFlagsPath = "/var/lib/kubelet/kubeadm-flags.env"
+
+	// adminKubeConfigPath is the admin kubeconfig written by kubeadm, with the control-plane endpoint
+	adminKubeConfigPath = "/etc/kubernetes/admin.conf"
+)
+
+// readStoppedNodeFile reads a file from a stopped node, using dir as a temporary directory; false is
+// returned if the file can't be read, e.g. because kubeadm was not executed on the node
+func readStoppedNodeFile(n *status.Node, file, dir string) (string, bool) {
+	target := filepath.Join(dir, fmt.Sprintf("%s-%s", n.Name(), filepath.Base(file)))
+	if err := exec.NewHostCmd("docker", "cp", fmt.Sprintf("%s:%s", n.Name(), file), target).Run(); err != nil {
+		log.Debugf("failed to read %s from node %s: %v", file, n.Name(), err)
+		return "", false
+	}
+	defer os.Remove(target)
+	content, err := os.ReadFile(target)
+	if err != nil {
+		return "", false
+	}
+	return string(content), true
+}
+
+// parseKubeletNodeIPs returns the node IPs set by the node-ip flag in the kubelet flags written by kubeadm
+func parseKubeletNodeIPs(flags string) status.NodeIPs {
+	var ips status.NodeIPs
+	for _, f := range strings.FieldsFunc(flags, func(r rune) bool { return unicode.IsSpace(r) || r == '"' }) {
+		if !strings.HasPrefix(f, "--node-ip=") {
+			continue
+		}
+		for _, ip := range strings.Split(strings.TrimPrefix(f, "--node-ip="), ",") {
+			parsed := net.ParseIP(ip)
+			switch {
+			case parsed == nil:
+			case parsed.To4() != nil:
+				ips.IPv4 = ip
+			default:
+				ips.IPv6 = ip
+			}
+		}
+	}
+	return ips
+}
+
+// archivedLoadBalancerIPs returns the IPs of the external load balancer used by the control-plane endpoint,
+// read from the admin kubeconfig on the bootstrap control-plane, because docker inspect does not report IPs
+// for stopped containers
+func archivedLoadBalancerIPs(cp1 *status.Node, dir string) (status.NodeIPs, error) {
+	kubeconfig, ok := readStoppedNodeFile(cp1, adminKubeConfigPath, dir)
+	if !ok {
+		return status.NodeIPs{}, nil
+	}
+	return parseKubeConfigServerIPs(kubeconfig)
+}
+
+// parseKubeConfigServerIPs returns the IP of the server in the current context of a kubeconfig
+func parseKubeConfigServerIPs(kubeconfig string) (status.NodeIPs, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return status.NodeIPs{}, errors.Wrap(err, "failed to decode the admin kubeconfig")
+	}
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return status.NodeIPs{}, errors.New("the admin kubeconfig does not have a current context")
+	}
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return status.NodeIPs{}, errors.Errorf("the admin kubeconfig does not have the cluster %q", context.Cluster)
+	}
+	server, err := url.Parse(cluster.Server)
+	if err != nil {
+		return status.NodeIPs{}, errors.Wrapf(err, "invalid server %q in the admin kubeconfig", cluster.Server)
+	}
+
+	var ips status.NodeIPs
+	ip := net.ParseIP(server.Hostname())
+	switch {
+	case ip == nil:
+		return status.NodeIPs{}, errors.Errorf("the server %q in the admin kubeconfig is not an IP", cluster.Server)
+	case ip.To4() != nil:
+		ips.IPv4 = ip.String()
+	default:
+		ips.IPv6 = ip.String()
+	}
+	return ips, nil
+}
+
+// networkSubnets returns the subnets of a docker network
+func networkSubnets(network string) ([]string, error) {
+	lines, err := exec.NewHostCmd("docker", "network", "inspect", "--format={{range .IPAM.Config}}{{.Subnet}} {{end}}", network).RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect the %s network", network)
+	}
+	return strings.Fields(strings.Join(lines, " ")), nil
+}
+
+// inspectJSON decodes the json output of docker inspect for a container
+func inspectJSON(container, format string, v interface{}) error {
+	lines, err := host.InspectContainer(container, format)
+	if err != nil {
+		return errors.Wrapf(err, "failed to inspect %s", container)
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), v); err != nil {
+		return errors.Wrapf(err, "failed to decode the inspect output for %s", container)
+	}
+	return nil
+}
+
+// archivedLabels returns the kinder labels to be applied again when importing a node; labels
+// set by the node run args, like the cluster name and the node role, are not included
+func archivedLabels(labels map[string]string) map[string]string {
+	archived := map[string]string{}
+	for k, v := range labels {
+		if !strings.HasPrefix(k, "io.x-k8s.kinder.") {
+			continue
+		}
+		switch k {
+		case constants.NetworkLabelKey, constants.APIServerBindPortLabelKey, constants.ExtraPortMappingsLabelKey, constants.ResourcesLabelKey:
+			continue
+		}
+		archived[k] = v
+	}
+	return archived
+}
+
+// importChanges returns the Dockerfile instructions to be applied by docker import for restoring
+// the node container config, that is lost by docker export
+func importChanges(n archivedNode) []string {
+	var changes []string
+	if len(n.Entrypoint) > 0 {
+		entrypoint, _ := json.Marshal(n.Entrypoint)
+		changes = append(changes, fmt.Sprintf("ENTRYPOINT %s", entrypoint))
+	}
+	if len(n.Cmd) > 0 {
+		cmd, _ := json.Marshal(n.Cmd)
+		changes = append(changes, fmt.Sprintf("CMD %s", cmd))
+	}
+	env := append([]string{}, n.Env...)
+	sort.Strings(env)
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("ENV %s=%q", kv[0], kv[1]))
+	}
+	if n.StopSignal != "" {
+		changes = append(changes, fmt.Sprintf("STOPSIGNAL %s", n.StopSignal))
+	}
+	return changes
+}
+
+// writeTar writes a tarball with the given files, read from dir
+func writeTar(archivePath, dir string, files []string) error {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, name := range files {
+		if err := addTarFile(tw, dir, name); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// addTarFile adds a file, read from dir, to a tarball
+func addTarFile(tw *tar.Writer, dir, name string) error {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// readTar extracts the regular files in a tarball into dir
+func readTar(archivePath, dir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, err := archiveEntryPath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// validateArchivedNodeName checks the name of an archived node can be used as a directory name in the
+// cluster archive, rejecting names with path separators or pointing outside the nodes directory
+func validateArchivedNodeName(name string) error {
+	if name == "" || name == "." || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return errors.Errorf("invalid node name %q in the cluster archive", name)
+	}
+	return nil
+}
+
+// archiveEntryPath returns the path where a tarball entry should be extracted into dir,
+// rejecting entries pointing outside dir
+func archiveEntryPath(dir, name string) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", errors.Errorf("invalid entry %q in the cluster archive", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+func TestArchivedLabels(t *testing.T) {
+	labels := map[string]string{
+		constants.ClusterLabelKey:           "kind",
+		constants.NodeRoleLabelKey:          constants.ControlPlaneNodeRoleValue,
+		constants.NetworkLabelKey:           "kind",
+		constants.APIServerBindPortLabelKey: "6444",
+		constants.ExtraPortMappingsLabelKey: "80:8080:TCP",
+		constants.ResourcesLabelKey:         `{"cpus":"2"}`,
+		constants.NodeLabelsLabelKey:        "foo=bar",
+		constants.KubeProxyLabelKey:         "ipvs",
+		"org.opencontainers.image.title":    "node",
+	}
+	expected := map[string]string{
+		constants.NodeLabelsLabelKey: "foo=bar",
+		constants.KubeProxyLabelKey:  "ipvs",
+	}
+	if got := archivedLabels(labels); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestImportChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     archivedNode
+		expected []string
+	}{
+		{
+			name: "empty config",
+		},
+		{
+			name: "full config",
+			node: archivedNode{
+				Entrypoint: []string{"/usr/local/bin/entrypoint", "/sbin/init"},
+				Cmd:        []string{"--log-level", "debug"},
+				Env:        []string{"container=docker", "PATH=/usr/bin:/bin", "NO_VALUE", "SPACES=a b"},
+				StopSignal: "SIGRTMIN+3",
+			},
+			expected: []string{
+				`ENTRYPOINT ["/usr/local/bin/entrypoint","/sbin/init"]`,
+				`CMD ["--log-level","debug"]`,
+				`ENV PATH="/usr/bin:/bin"`,
+				`ENV SPACES="a b"`,
+				`ENV container="docker"`,
+				`STOPSIGNAL SIGRTMIN+3`,
+			},
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			if got := importChanges(rt.node); !reflect.DeepEqual(got, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, got)
+			}
+		})
+	}
+}
+
+func TestArchiveEntryPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "metadata file",
+			entry:    "cluster.json",
+			expected: filepath.Join("dir", "cluster.json"),
+		},
+		{
+			name:     "node file",
+			entry:    "nodes/control-plane/rootfs.tar",
+			expected: filepath.Join("dir", "nodes", "control-plane", "rootfs.tar"),
+		},
+		{
+			name:    "absolute path",
+			entry:   "/etc/passwd",
+			wantErr: true,
+		},
+		{
+			name:    "path outside dir",
+			entry:   "nodes/../../etc/passwd",
+			wantErr: true,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			got, err := archiveEntryPath("dir", rt.entry)
+			if (err != nil) != rt.wantErr {
+				t.Fatalf("expected error %t, got %v", rt.wantErr, err)
+			}
+			if got != rt.expected {
+				t.Errorf("expected %q, got %q", rt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateArchivedNodeName(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    string
+		wantErr bool
+	}{
+		{
+			name: "node name",
+			node: "control-plane-1",
+		},
+		{
+			name:    "empty name",
+			wantErr: true,
+		},
+		{
+			name:    "path separator",
+			node:    "nodes/worker",
+			wantErr: true,
+		},
+		{
+			name:    "windows path separator",
+			node:    `nodes\worker`,
+			wantErr: true,
+		},
+		{
+			name:    "parent directory",
+			node:    "..",
+			wantErr: true,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			if err := validateArchivedNodeName(rt.node); (err != nil) != rt.wantErr {
+				t.Errorf("expected error %t, got %v", rt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseKubeletNodeIPs(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    string
+		expected status.NodeIPs
+	}{
+		{
+			name:     "ipv4",
+			flags:    `KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=unix:///run/containerd/containerd.sock --node-ip=172.18.0.3 --pod-infra-container-image=registry.k8s.io/pause:3.9"`,
+			expected: status.NodeIPs{IPv4: "172.18.0.3"},
+		},
+		{
+			name:     "ipv6 at the end",
+			flags:    `KUBELET_KUBEADM_ARGS="--node-labels= --node-ip=fc00:f853:ccd:e793::3"`,
+			expected: status.NodeIPs{IPv6: "fc00:f853:ccd:e793::3"},
+		},
+		{
+			name:     "dual-stack",
+			flags:    `KUBELET_KUBEADM_ARGS="--node-ip=172.18.0.3,fc00:f853:ccd:e793::3"`,
+			expected: status.NodeIPs{IPv4: "172.18.0.3", IPv6: "fc00:f853:ccd:e793::3"},
+		},
+		{
+			name:  "no node ip",
+			flags: `KUBELET_KUBEADM_ARGS="--node-labels="`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseKubeletNodeIPs(test.flags); got != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseKubeConfigServerIPs(t *testing.T) {
+	kubeconfig := func(server string) string {
+		return `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: ` + server + `
+  name: kind
+contexts:
+- context:
+    cluster: kind
+    user: kubernetes-admin
+  name: kubernetes-admin@kind
+current-context: kubernetes-admin@kind
+users:
+- name: kubernetes-admin
+  user: {}
+`
+	}
+
+	tests := []struct {
+		name          string
+		kubeconfig    string
+		expected      status.NodeIPs
+		expectedError bool
+	}{
+		{
+			name:       "ipv4",
+			kubeconfig: kubeconfig("https://172.18.0.2:6443"),
+			expected:   status.NodeIPs{IPv4: "172.18.0.2"},
+		},
+		{
+			name:       "ipv6",
+			kubeconfig: kubeconfig("https://[fc00:f853:ccd:e793::2]:6443"),
+			expected:   status.NodeIPs{IPv6: "fc00:f853:ccd:e793::2"},
+		},
+		{
+			name:          "not an IP",
+			kubeconfig:    kubeconfig("https://kind-lb:6443"),
+			expectedError: true,
+		},
+		{
+			name:          "invalid kubeconfig",
+			kubeconfig:    "clusters: foo",
+			expectedError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseKubeConfigServerIPs(test.kubeconfig)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if got != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, got)
+			}
+		})
+	}
+}
@@ -17,31 +17,128 @@ limitations under the License.
 package manager
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/apimachinery/pkg/util/wait"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/cni"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/cri/host"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes"
 	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/failure"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 )
 
 // CreateOptions holds all the options used at create time
 type CreateOptions struct {
-	controlPlanes        int
-	workers              int
-	image                string
-	externalLoadBalancer bool
-	externalEtcd         bool
-	retain               bool
-	volumes              []string
+	controlPlanes              int
+	bootstrapControlPlane      int
+	workers                    int
+	image                      string
+	externalLoadBalancer       bool
+	loadBalancer               string
+	externalEtcd               bool
+	retain                     bool
+	recreate                   bool
+	volumes                    []string
+	extraPortMappings          []status.PortMapping
+	extraPortMappingsRoles     []string
+	apiServerBindPort          int32
+	externalEtcdImage          string
+	externalEtcdDataDir        string
+	externalEtcdNodes          int
+	externalEtcdEndpoints      []string
+	externalEtcdCAFile         string
+	externalEtcdCertFile       string
+	externalEtcdKeyFile        string
+	externalEtcdCerts          map[string][]byte
+	workerLabels               []string
+	workerTaints               []string
+	workerPools                []string
+	externalRegistry           bool
+	nodeCommands               []string
+	nodeNames                  []string
+	nodeImages                 []string
+	nodeIPs                    []string
+	nodeKubernetesVersions     map[string]string
+	sandboxImage               string
+	controlPlaneExtraArgs      map[string][]string
+	dns                        status.NodeDNS
+	controlPlaneResources      status.NodeResources
+	workerResources            status.NodeResources
+	network                    string
+	ipFamily                   status.ClusterIPFamily
+	evictionHard               []string
+	evictionSoft               []string
+	evictionSoftGrace          time.Duration
+	relaxEviction              bool
+	kubeletEviction            map[string]map[string]string
+	nodeSysctls                []string
+	sysctls                    map[string]string
+	kubeProxyMode              string
+	skipKubeProxy              bool
+	nodeCIDRMaskSize           int
+	nodeCIDRMaskSizeIPv6       int
+	cni                        string
+	kubeadmClusterName         string
+	coreDNSImage               string
+	coreDNSReplicas            int
+	etcdVersion                string
+	etcdQuotaBackendBytes      string
+	etcdCompactionMode         string
+	etcdCompactionRetain       string
+	etcdExtraArgs              []string
+	timeout                    time.Duration
+	clusterSigningDuration     time.Duration
+	kubeletServingCertRotation bool
+	serviceAccountIssuer       string
+	serviceAccountKeyFile      string
+	serviceAccountKey          []byte
+	serviceAccountPub          []byte
+	caCertFile                 string
+	caKeyFile                  string
+	caCert                     []byte
+	caKey                      []byte
+	bootstrapManifests         []string
+	waitDaemonSets             []string
+	encryptionProvider         string
+	encryptionConfig           string
+	auditPolicyFile            string
+	auditLogPath               string
+	auditPolicy                []byte
+	tlsMinVersion              string
+	tlsCipherSuites            []string
+	bootstrapManifestFiles     []bootstrapManifest
+	cniManifestFile            *bootstrapManifest
+	kubernetesVersion          string
+	ignorePreflightErrors      string
+	progress                   io.Writer
 }
 
 // CreateOption is a configuration option supplied to Create
@@ -54,6 +151,14 @@ func ControlPlanes(controlPlanes int) CreateOption {
 	}
 }
 
+// BootstrapControlPlane sets the control-plane node, by its 1-based number, where kubeadm init will be executed;
+// if not set, the first control-plane node is used
+func BootstrapControlPlane(number int) CreateOption {
+	return func(c *CreateOptions) {
+		c.bootstrapControlPlane = number
+	}
+}
+
 // Workers sets the number of worker nodes for create
 func Workers(workers int) CreateOption {
 	return func(c *CreateOptions) {
@@ -75,6 +180,48 @@ func ExternalEtcd(externalEtcd bool) CreateOption {
 	}
 }
 
+// ExternalEtcdImage sets the image for the external etcd; it could be a full image reference
+// or only a tag, that is applied to the etcd image required by the Kubernetes version in the node image
+func ExternalEtcdImage(image string) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalEtcdImage = image
+	}
+}
+
+// ExternalEtcdEndpoints sets the client endpoints of an external etcd not managed by kinder, and the host files
+// with the etcd CA and the API server etcd client certificate and key, required when the endpoints use TLS;
+// the files are copied on the control-plane nodes at create time
+func ExternalEtcdEndpoints(endpoints []string, caFile, certFile, keyFile string) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalEtcdEndpoints = endpoints
+		c.externalEtcdCAFile = caFile
+		c.externalEtcdCertFile = certFile
+		c.externalEtcdKeyFile = keyFile
+	}
+}
+
+// ExternalEtcdNodes sets the number of members of the external etcd cluster, that must be odd; it defaults to one
+func ExternalEtcdNodes(nodes int) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalEtcdNodes = nodes
+	}
+}
+
+// ExternalEtcdDataDir sets a host directory to be mounted as data dir for the external etcd
+func ExternalEtcdDataDir(dataDir string) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalEtcdDataDir = dataDir
+	}
+}
+
+// ExternalRegistry instruct create to add a local image registry to the cluster, and to configure
+// the container runtime in the K8s nodes for using it as a mirror for all the registries
+func ExternalRegistry(externalRegistry bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.externalRegistry = externalRegistry
+	}
+}
+
 // ExternalLoadBalancer instruct create to add an external loadbalancer to the cluster.
 // NB. this happens automatically when there are more than two control plane instances, but with this flag
 // it is possible to override the default behaviour
@@ -84,6 +231,14 @@ func ExternalLoadBalancer(externalLoadBalancer bool) CreateOption {
 	}
 }
 
+// LoadBalancer sets the implementation of the external load balancer, e.g. nginx or envoy;
+// when not set, HAProxy is used
+func LoadBalancer(implementation string) CreateOption {
+	return func(c *CreateOptions) {
+		c.loadBalancer = implementation
+	}
+}
+
 // Retain option instructs create cluster to preserve node in case of errors for debugging purposes
 func Retain(retain bool) CreateOption {
 	return func(c *CreateOptions) {
@@ -91,6 +246,15 @@ func Retain(retain bool) CreateOption {
 	}
 }
 
+// Recreate option instructs create cluster to delete an existing cluster with the same name, including the external
+// etcd and load balancer, instead of failing; the existing cluster is deleted only after the options for the new
+// cluster are validated, and its node image and network are reused if not set
+func Recreate(recreate bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.recreate = recreate
+	}
+}
+
 // Volumes option instructs create cluster to add volumes to the node containers
 func Volumes(volumes []string) CreateOption {
 	return func(c *CreateOptions) {
@@ -98,225 +262,2956 @@ func Volumes(volumes []string) CreateOption {
 	}
 }
 
-// CreateCluster creates a new kinder cluster
-func CreateCluster(clusterName string, options ...CreateOption) error {
-	flags := &CreateOptions{}
-	for _, o := range options {
-		o(flags)
+// ExtraPortMappings option instructs create cluster to map additional node container ports to the host;
+// mappings are applied only to nodes with the given roles, or to worker nodes if no role is specified
+func ExtraPortMappings(portMappings []status.PortMapping, roles ...string) CreateOption {
+	return func(c *CreateOptions) {
+		c.extraPortMappings = portMappings
+		c.extraPortMappingsRoles = roles
 	}
+}
 
-	// Check if the cluster name already exists
-	known, err := status.IsKnown(clusterName)
-	if err != nil {
-		return err
+// APIServerBindPort option instructs create cluster to use a non default port for the API server
+// on the control-plane nodes
+func APIServerBindPort(port int32) CreateOption {
+	return func(c *CreateOptions) {
+		c.apiServerBindPort = port
 	}
-	if known {
-		return errors.Errorf("a cluster with the name %q already exists", clusterName)
+}
+
+// WorkerLabels option sets the Kubernetes labels, in the key=value form, to be assigned to worker nodes at join time
+func WorkerLabels(labels []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.workerLabels = labels
 	}
+}
 
-	fmt.Printf("Creating cluster %q ...\n", clusterName)
+// WorkerTaints option sets the Kubernetes taints, in the key=value:Effect form, to be assigned to worker nodes at join time
+func WorkerTaints(taints []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.workerTaints = taints
+	}
+}
 
-	// attempt to explicitly pull the required node image if it doesn't exist locally
-	// we don't care if this errors, we'll still try to run which also pulls
-	ensureNodeImage(flags.image)
+// WorkerPools option sets pools of worker nodes, each one with its own Kubernetes labels and taints assigned at join time;
+// pools are defined in the name=NAME;count=N[;labels=key=value,...][;taints=key=value:Effect,...] form
+func WorkerPools(pools []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.workerPools = pools
+	}
+}
 
-	handleErr := func(err error) error {
-		// In case of errors nodes are deleted (except if retain is explicitly set)
-		if !flags.retain {
-			if c, err := status.FromDocker(clusterName); err != nil {
-				log.Error(err)
-			} else {
-				for _, n := range c.AllNodes() {
-					if err := exec.NewHostCmd(
-						"docker",
-						"rm",
-						"-f", // force the container to be deleted now
-						"-v", // delete volumes
-						n.Name(),
-					).Run(); err != nil {
-						return errors.Wrapf(err, "failed to delete node %s", n.Name())
-					}
-				}
-			}
+// NodeCommands option sets command overrides for the K8s node containers, in the TARGET=COMMAND form, where TARGET
+// is a role or a node name; the command is executed by a shell, and it must exec the kind entrypoint for booting the node
+func NodeCommands(commands []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeCommands = commands
+	}
+}
+
+// NodeNames option sets the Kubernetes node names to be used at init/join time instead of the container names,
+// in the NODE=NAME form, where NODE is the name of a K8s node to be created
+func NodeNames(names []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeNames = names
+	}
+}
+
+// NodeImages option sets node images for single K8s nodes, overriding the cluster node image, in the NODE=IMAGE form,
+// where NODE is the name of a K8s node to be created; all the node images must have the same container runtime
+func NodeImages(images []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeImages = images
+	}
+}
+
+// NodeIPs option sets static IP addresses for single K8s nodes, in the NODE=IP form, where NODE is the name of
+// a K8s node to be created; a node can have an IPv4 and an IPv6 address, and a user defined network is required
+func NodeIPs(ips []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeIPs = ips
+	}
+}
+
+// SandboxImage option sets the CRI sandbox (pause) image to be used by the container runtime in the K8s nodes
+func SandboxImage(image string) CreateOption {
+	return func(c *CreateOptions) {
+		c.sandboxImage = image
+	}
+}
+
+// ControlPlaneExtraArgs option sets the extra args, in the key=value form, for the API server, the controller manager
+// and the scheduler; extra args are set in the kubeadm config at init time
+func ControlPlaneExtraArgs(apiServer, controllerManager, scheduler []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.controlPlaneExtraArgs = map[string][]string{
+			kubeadm.APIServerComponent:         apiServer,
+			kubeadm.ControllerManagerComponent: controllerManager,
+			kubeadm.SchedulerComponent:         scheduler,
 		}
-		log.Error(err)
-		return err
 	}
+}
 
-	// Create node containers as defined in the kind config
-	if err := createNodes(
-		clusterName,
-		flags,
-	); err != nil {
-		return handleErr(errors.Wrap(err, "error creating nodes"))
+// DNS option sets the DNS servers, search domains and options for the K8s node containers, overriding
+// the DNS settings inherited from the host
+func DNS(servers, search, options []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.dns = status.NodeDNS{
+			Servers: servers,
+			Search:  search,
+			Options: options,
+		}
 	}
+}
 
-	fmt.Println()
-	fmt.Printf("Nodes creation complete. You can now continue creating a Kubernetes cluster using\n")
-	fmt.Printf("kinder do, the kinder swiss knife 🚀!\n")
+// Resources option sets the CPU and memory limits for the control-plane and for the worker node containers,
+// in the docker --cpus and --memory format, e.g. 1.5 and 2g
+func Resources(controlPlane, worker status.NodeResources) CreateOption {
+	return func(c *CreateOptions) {
+		c.controlPlaneResources = controlPlane
+		c.workerResources = worker
+	}
+}
 
-	return nil
+// KubeletEviction option sets the kubelet hard and soft eviction thresholds, in the signal<quantity form, and the
+// grace period for soft thresholds; if relax is set, CI friendly hard thresholds are used for the signals not set
+func KubeletEviction(hard, soft []string, softGracePeriod time.Duration, relax bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.evictionHard = hard
+		c.evictionSoft = soft
+		c.evictionSoftGrace = softGracePeriod
+		c.relaxEviction = relax
+	}
 }
 
-func createNodes(clusterName string, flags *CreateOptions) error {
-	// compute the desired nodes, and inform the user that we are setting them up
-	desiredNodes := nodesToCreate(clusterName, flags)
-	numberOfNodes := len(desiredNodes)
-	if flags.externalEtcd {
-		numberOfNodes++
+// NodeSysctls option sets the sysctls, in the key=value form, applied on the K8s nodes before the kubelet starts
+func NodeSysctls(sysctls []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeSysctls = sysctls
 	}
-	fmt.Printf("Preparing nodes %s\n", strings.Repeat("📦", numberOfNodes))
+}
 
-	// detect CRI runtime installed into images before actually creating nodes
-	runtime, err := status.InspectCRIinImage(flags.image)
-	if err != nil {
-		log.Errorf("Error detecting CRI for images %s! %v", flags.image, err)
-		return err
+// KubeProxy option sets the kube-proxy mode, or instructs kinder to not install the kube-proxy addon
+// when skip is set, e.g. when testing a CNI replacing kube-proxy
+func KubeProxy(mode string, skip bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.kubeProxyMode = mode
+		c.skipKubeProxy = skip
 	}
-	log.Infof("Detected %s container runtime for image %s", runtime, flags.image)
+}
 
-	createHelper, err := nodes.NewCreateHelper(runtime)
-	if err != nil {
-		log.Errorf("Error creating NewCreateHelper for CRI %s! %v", flags.image, err)
-		return err
+// NodeCIDRMaskSize option sets the size of the IPv4 and IPv6 pod CIDR allocated to each node, e.g. for simulating
+// small per-node pod ranges; when not set, the kube-controller-manager defaults are used
+func NodeCIDRMaskSize(ipv4, ipv6 int) CreateOption {
+	return func(c *CreateOptions) {
+		c.nodeCIDRMaskSize = ipv4
+		c.nodeCIDRMaskSizeIPv6 = ipv6
 	}
+}
 
-	// create all of the node containers
-	log.Info("Creating nodes...")
-	for _, desiredNode := range desiredNodes {
-		var err error
-		switch desiredNode.Role {
-		case constants.ExternalLoadBalancerNodeRoleValue:
-			err = createHelper.CreateExternalLoadBalancer(clusterName, desiredNode.Name)
-		case constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue:
-			err = createHelper.CreateNode(clusterName, desiredNode.Name, flags.image, desiredNode.Role, flags.volumes)
-		}
-		if err != nil {
-			return errors.Wrapf(err, "error creating node %v", desiredNode)
-		}
+// CNI option sets the CNI plugin installed by kubeadm init, as the name of a known plugin, e.g. calico,
+// or a custom manifest as a host path or an URL; when empty, kindnet is installed
+func CNI(cni string) CreateOption {
+	return func(c *CreateOptions) {
+		c.cni = cni
 	}
+}
 
-	// add an external etcd if explicitly requested
-	if flags.externalEtcd {
-		log.Info("Getting required etcd image...")
-		c, err := status.FromDocker(clusterName)
-		if err != nil {
-			return err
-		}
+// KubeadmClusterName option sets the cluster name in the kubeadm ClusterConfiguration, and thus in the kubeconfig
+// files generated by kubeadm, e.g. for multi-cluster tooling; when empty, the kinder cluster name is used
+func KubeadmClusterName(name string) CreateOption {
+	return func(c *CreateOptions) {
+		c.kubeadmClusterName = name
+	}
+}
 
-		etcdImage, err := c.BootstrapControlPlane().EtcdImage()
-		if err != nil {
-			return err
-		}
+// CoreDNS option sets the CoreDNS image, in the repository/coredns:tag form, and the number of CoreDNS replicas;
+// when not set, the kubeadm defaults are used
+func CoreDNS(image string, replicas int) CreateOption {
+	return func(c *CreateOptions) {
+		c.coreDNSImage = image
+		c.coreDNSReplicas = replicas
+	}
+}
 
-		// attempt to explicitly pull the etcdImage if it doesn't exist locally
-		// we don't care if this errors, we'll still try to run which also pulls
-		_, _ = host.PullImage(etcdImage, 4)
+// EtcdVersion option sets the local etcd image tag, e.g. 3.5.12-0, independently of the Kubernetes version;
+// when not set, the etcd version matching the Kubernetes version is used
+func EtcdVersion(version string) CreateOption {
+	return func(c *CreateOptions) {
+		c.etcdVersion = version
+	}
+}
 
-		log.Info("Creating external etcd...")
-		if err := createHelper.CreateExternalEtcd(clusterName, fmt.Sprintf("%s-etcd", clusterName), etcdImage); err != nil {
-			return err
-		}
+// EtcdQuotaAndCompaction option sets the etcd backend quota, as a quantity, e.g. 2Gi, and the auto-compaction mode
+// and retention, e.g. periodic and 1h; the settings apply both to the local etcd and to the external etcd.
+// When not set, the etcd defaults are used
+func EtcdQuotaAndCompaction(quotaBackendBytes, autoCompactionMode, autoCompactionRetention string) CreateOption {
+	return func(c *CreateOptions) {
+		c.etcdQuotaBackendBytes = quotaBackendBytes
+		c.etcdCompactionMode = autoCompactionMode
+		c.etcdCompactionRetain = autoCompactionRetention
 	}
+}
 
-	// wait for all node containers to have a Running status
-	log.Info("Waiting for all nodes to start...")
-	timeout := time.Second * 40
-	for _, n := range desiredNodes {
-		var lastErr error
-		log.Infof("Waiting for node %s to start...", n.Name)
-		err = wait.PollImmediate(time.Second*1, timeout, func() (bool, error) {
-			lines, err := exec.NewHostCmd(
-				"docker",
-				"container",
-				"inspect",
-				"-f",
-				"'{{.State.Running}}'",
-				n.Name,
-			).RunAndCapture()
-			if err == nil && len(lines) > 0 && lines[0] == `'true'` {
-				return true, nil
-			}
-			lastErr = errors.Errorf("node state is not Running, error: %v, output lines: %+v, ", err, lines)
-			return false, nil
-		})
-		if err != nil {
-			return errors.Wrapf(lastErr, "node %s did not start in %v", n.Name, timeout)
-		}
+// ProgressOutput option sets the writer for the progress messages printed while creating the cluster,
+// e.g. stderr when stdout is reserved for a machine readable output; it defaults to stdout
+func ProgressOutput(w io.Writer) CreateOption {
+	return func(c *CreateOptions) {
+		c.progress = w
 	}
+}
 
-	// get the cluster
-	c, err := status.FromDocker(clusterName)
-	if err != nil {
-		return err
+// Timeout option sets an overall deadline for cluster creation; when the deadline is exceeded, running commands
+// are killed and the cluster creation fails, deleting the nodes unless retain is set. Zero means no deadline.
+func Timeout(timeout time.Duration) CreateOption {
+	return func(c *CreateOptions) {
+		c.timeout = timeout
 	}
+}
 
-	c.Settings = &status.ClusterSettings{
-		IPFamily: status.IPv4Family, // only IPv4 is tested with kinder
+// KubeletServingCertRotation option enables the kubelet serving certificates TLS bootstrap, so the serving certificates
+// are signed by the controller manager, and thus rotated, instead of being self-signed by the kubelet
+func KubeletServingCertRotation(enabled bool) CreateOption {
+	return func(c *CreateOptions) {
+		c.kubeletServingCertRotation = enabled
 	}
+}
 
-	// TODO: the cluster and node settings are currently unused by kinder
-	// Enable these writes if settings have to stored on the nodes
-	//
-	// // write to the nodes the cluster settings that will be re-used by kinder during the cluster lifecycle.
-	//
-	// if err := c.WriteSettings(); err != nil {
-	// 	return err
-	// }
-	//
-	// for _, n := range c.K8sNodes() {
-	// 	if err := n.WriteNodeSettings(&status.NodeSettings{}); err != nil {
-	// 		return err
-	// 	}
-	// }
+// ClusterSigningDuration option sets the duration of the certificates signed by the controller manager, e.g.
+// the kubelet client certificates; the duration is set as a controller manager extra arg. Zero means the default duration.
+func ClusterSigningDuration(duration time.Duration) CreateOption {
+	return func(c *CreateOptions) {
+		c.clusterSigningDuration = duration
+	}
+}
 
-	return nil
+// ServiceAccount option sets the service account issuer, as an API server extra arg, and a file on the host with
+// an existing service account signing key, that is installed on the control-plane nodes so kubeadm init reuses it
+// instead of generating a new one, e.g. for matching the keys published by an external OIDC verifier
+func ServiceAccount(issuer, keyFile string) CreateOption {
+	return func(c *CreateOptions) {
+		c.serviceAccountIssuer = issuer
+		c.serviceAccountKeyFile = keyFile
+	}
 }
 
-// nodeSpec describes a node to create purely from the container aspect
-// this does not include eg starting kubernetes (see actions for that)
-type nodeSpec struct {
-	Name string
-	Role string
+// ClusterCA option sets files on the host with an existing CA certificate and key, RSA or ECDSA, that are installed
+// on the control-plane nodes so kubeadm init reuses the CA for signing the cluster certificates instead of
+// generating a new one, e.g. for testing certificates signed by a corporate CA
+func ClusterCA(certFile, keyFile string) CreateOption {
+	return func(c *CreateOptions) {
+		c.caCertFile = certFile
+		c.caKeyFile = keyFile
+	}
 }
 
-// nodesToCreate return the list of nodes to create for the cluster
-func nodesToCreate(clusterName string, flags *CreateOptions) []nodeSpec {
-	var desiredNodes []nodeSpec
+// BootstrapManifests option sets manifests, as host paths or URLs, to be applied in order by kubeadm init
+// once the control-plane is ready, e.g. a CNI plugin or RBAC rules; manifest files are validated and copied
+// in the control-plane nodes at create time
+func BootstrapManifests(manifests []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.bootstrapManifests = manifests
+	}
+}
 
-	// prepare nodes explicitly
-	for n := 0; n < flags.controlPlanes; n++ {
-		role := constants.ControlPlaneNodeRoleValue
-		desiredNode := nodeSpec{
-			Name: fmt.Sprintf("%s-%s-%d", clusterName, role, n+1),
-			Role: role,
-		}
-		desiredNodes = append(desiredNodes, desiredNode)
+// IgnorePreflightErrors option sets the comma separated list of kubeadm preflight errors to be ignored by
+// kubeadm init, e.g. Swap; when not set, kubeadm init uses the kinder default list
+func IgnorePreflightErrors(ignorePreflightErrors string) CreateOption {
+	return func(c *CreateOptions) {
+		c.ignorePreflightErrors = ignorePreflightErrors
 	}
-	for n := 0; n < flags.workers; n++ {
-		role := constants.WorkerNodeRoleValue
-		desiredNode := nodeSpec{
-			Name: fmt.Sprintf("%s-%s-%d", clusterName, role, n+1),
-			Role: role,
-		}
-		desiredNodes = append(desiredNodes, desiredNode)
+}
+
+// WaitDaemonSets option sets the DaemonSets, in the [namespace/]name form, to be waited for rolling out on all
+// the nodes after the bootstrap manifests are applied and after join, e.g. the DaemonSet of a CNI plugin;
+// the namespace defaults to kube-system
+func WaitDaemonSets(daemonSets []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.waitDaemonSets = daemonSets
 	}
+}
 
-	// add an external load balancer if explicitly requested or if there are multiple control planes
-	if flags.externalLoadBalancer || flags.controlPlanes > 1 {
-		role := constants.ExternalLoadBalancerNodeRoleValue
-		desiredNodes = append(desiredNodes, nodeSpec{
-			Name: fmt.Sprintf("%s-lb", clusterName),
-			Role: role,
-		})
+// EncryptionAtRest option sets the provider used for encrypting secrets at rest, e.g. aescbc; an encryption config
+// with a random key is generated and installed on the control-plane nodes, and the API server is configured to use it
+func EncryptionAtRest(provider string) CreateOption {
+	return func(c *CreateOptions) {
+		c.encryptionProvider = provider
 	}
+}
 
-	return desiredNodes
+// AuditLogging option enables the API server audit logging, using the audit policy from a file on the host
+// and writing the audit log to the given path on the control-plane nodes; when not set, a policy logging the
+// metadata of all the requests and the default audit log path are used
+func AuditLogging(policyFile, logPath string) CreateOption {
+	return func(c *CreateOptions) {
+		c.auditPolicyFile = policyFile
+		c.auditLogPath = logPath
+	}
+}
+
+// APIServerTLS option sets the TLS min version and the cipher suites used by the API server; the settings are
+// applied at init time, when they are checked against the key type used by kubeadm
+func APIServerTLS(minVersion string, cipherSuites []string) CreateOption {
+	return func(c *CreateOptions) {
+		c.tlsMinVersion = minVersion
+		c.tlsCipherSuites = cipherSuites
+	}
+}
+
+// Network option sets the user defined docker network the cluster containers are connected to;
+// the network is created if it does not exist yet
+func Network(name string) CreateOption {
+	return func(c *CreateOptions) {
+		c.network = name
+	}
+}
+
+// IPFamily option sets the IP family of the cluster, one of ipv4 (default), ipv6 or dualstack
+func IPFamily(family string) CreateOption {
+	return func(c *CreateOptions) {
+		c.ipFamily = status.ClusterIPFamily(family)
+	}
+}
+
+// validateCreateOptions validates the create options before creating any container
+func validateCreateOptions(clusterName string, flags *CreateOptions) error {
+	if err := validateExtraPortMappings(clusterName, flags); err != nil {
+		return err
+	}
+
+	if err := validateAPIServerBindPort(flags); err != nil {
+		return err
+	}
+
+	if err := validateExternalEtcd(flags); err != nil {
+		return err
+	}
+
+	if err := validateExternalEtcdEndpoints(flags); err != nil {
+		return err
+	}
+
+	if err := validateWorkerLabelsAndTaints(flags); err != nil {
+		return err
+	}
+
+	if err := validateWorkerPools(flags); err != nil {
+		return err
+	}
+
+	if err := validateNodeCommands(clusterName, flags); err != nil {
+		return err
+	}
+
+	if err := validateNodeNames(clusterName, flags); err != nil {
+		return err
+	}
+
+	if err := validateNodeImages(clusterName, flags); err != nil {
+		return err
+	}
+
+	if err := validateSandboxImage(flags); err != nil {
+		return err
+	}
+
+	if err := validateClusterSigningDuration(flags); err != nil {
+		return err
+	}
+
+	if err := validateAPIServerTLS(flags); err != nil {
+		return err
+	}
+
+	// with the default cluster signing duration of one year, kubelet serving certificates are not rotated
+	// within the timeframe of a test
+	if flags.kubeletServingCertRotation && flags.clusterSigningDuration == 0 {
+		log.Infof("Kubelet serving certificates are signed by the controller manager with the default duration; use the cluster signing duration option for rotating them within a test")
+	}
+
+	if err := validateServiceAccount(flags); err != nil {
+		return err
+	}
+
+	if err := validateClusterCA(flags); err != nil {
+		return err
+	}
+
+	if err := validateBootstrapManifests(flags); err != nil {
+		return err
+	}
+
+	if err := validateWaitDaemonSets(flags); err != nil {
+		return err
+	}
+
+	if err := validateCNI(flags); err != nil {
+		return err
+	}
+
+	if err := validateKubeadmClusterName(flags); err != nil {
+		return err
+	}
+
+	if err := validateEncryptionAtRest(flags); err != nil {
+		return err
+	}
+
+	if err := validateAuditLogging(flags); err != nil {
+		return err
+	}
+
+	if err := validateIPFamily(flags); err != nil {
+		return err
+	}
+
+	if err := validateNodeCIDRMaskSize(flags); err != nil {
+		return err
+	}
+
+	if err := validateControlPlaneExtraArgs(flags); err != nil {
+		return err
+	}
+
+	if err := flags.dns.Validate(); err != nil {
+		return err
+	}
+
+	if err := validateNetwork(flags); err != nil {
+		return err
+	}
+
+	if err := validateNodeIPs(clusterName, flags); err != nil {
+		return err
+	}
+
+	if err := validateLoadBalancer(flags); err != nil {
+		return err
+	}
+
+	if err := validateResources(flags); err != nil {
+		return err
+	}
+
+	if err := validateKubeletEviction(flags); err != nil {
+		return err
+	}
+
+	if err := validateNodeSysctls(flags); err != nil {
+		return err
+	}
+
+	if err := validateKubeProxy(flags); err != nil {
+		return err
+	}
+
+	if err := validateCoreDNS(flags); err != nil {
+		return err
+	}
+
+	if err := validateEtcdVersion(flags); err != nil {
+		return err
+	}
+
+	if err := validateEtcdQuotaAndCompaction(flags); err != nil {
+		return err
+	}
+
+	if err := validateBootstrapControlPlane(flags); err != nil {
+		return err
+	}
+
+	if flags.timeout < 0 {
+		return errors.Errorf("invalid timeout %s. Use a positive duration", flags.timeout)
+	}
+
+	return nil
+}
+
+// CreateCluster creates a new kinder cluster
+func CreateCluster(clusterName string, options ...CreateOption) error {
+	flags := &CreateOptions{progress: os.Stdout}
+	for _, o := range options {
+		o(flags)
+	}
+
+	if flags.recreate {
+		if err := reuseExistingCluster(clusterName, flags); err != nil {
+			return err
+		}
+	}
+
+	// validation errors have a dedicated exit code
+	if err := validateCreateOptions(clusterName, flags); err != nil {
+		return failure.WithCategory(err, failure.Validation)
+	}
+
+	// Check if the cluster name already exists
+	known, err := status.IsKnown(clusterName)
+	if err != nil {
+		return err
+	}
+	if known && !flags.recreate {
+		return errors.Errorf("a cluster with the name %q already exists", clusterName)
+	}
+
+	fmt.Fprintf(flags.progress, "Creating cluster %q ...\n", clusterName)
+	printTopology(nodesToCreate(clusterName, flags), flags)
+
+	// enforce the overall deadline for cluster creation, if any, on all the commands run from now on
+	ctx := context.Background()
+	if flags.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.timeout)
+		defer cancel()
+		exec.SetCommandContext(ctx)
+		defer exec.SetCommandContext(nil)
+	}
+	deadlineErr := func(err error) error {
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.Wrapf(err, "cluster creation exceeded %s", flags.timeout)
+		}
+		return err
+	}
+
+	// attempt to explicitly pull the required node image if it doesn't exist locally
+	// we don't care if this errors, we'll still try to run which also pulls
+	ensureNodeImage(flags.progress, flags.image)
+
+	// reads the Kubernetes version in the node image, so it can be recorded in the cluster metadata labels;
+	// this is not blocking, because the version is read from the nodes when required
+	if lines, err := host.ReadImageFile(flags.image, "/kind/version"); err == nil && len(lines) == 1 {
+		flags.kubernetesVersion = strings.TrimSpace(lines[0])
+	} else {
+		log.Warnf("Failed to read the Kubernetes version in the node image; it won't be recorded in the %s label", constants.KubernetesVersionLabelKey)
+	}
+
+	// the same applies to the node images of single nodes, if any
+	flags.nodeKubernetesVersions = map[string]string{}
+	for _, image := range nodeImagesToCreate(nodesToCreate(clusterName, flags)) {
+		ensureNodeImage(flags.progress, image)
+		if lines, err := host.ReadImageFile(image, "/kind/version"); err == nil && len(lines) == 1 {
+			flags.nodeKubernetesVersions[image] = strings.TrimSpace(lines[0])
+		} else {
+			log.Warnf("Failed to read the Kubernetes version in the node image %s; it won't be recorded in the %s label", image, constants.KubernetesVersionLabelKey)
+		}
+	}
+
+	if err := validateIgnorePreflightErrors(flags); err != nil {
+		return err
+	}
+
+	if err := validateIPFamilyVersion(flags); err != nil {
+		return err
+	}
+
+	// serializes with other kinder processes on this host the critical section of cluster creation, that is
+	// the name uniqueness check and the creation of the network and of the node containers; node setup is
+	// executed after releasing the lock, so concurrent creates proceed in parallel
+	unlock, err := host.LockFile(ctx, createLockPath())
+	if err != nil {
+		return deadlineErr(err)
+	}
+	defer unlock()
+
+	// Check again if the cluster name already exists, now that concurrent creates can't race on it;
+	// when recreating, the existing cluster is deleted now that the options for the new cluster are validated
+	known, err = status.IsKnown(clusterName)
+	if err != nil {
+		return err
+	}
+	if known {
+		if !flags.recreate {
+			return errors.Errorf("a cluster with the name %q already exists", clusterName)
+		}
+		fmt.Fprintf(flags.progress, "Deleting the existing cluster %q ...\n", clusterName)
+		if err := deleteNodes(clusterName); err != nil {
+			return deadlineErr(errors.Wrapf(err, "failed to delete the existing cluster %q", clusterName))
+		}
+	}
+
+	if err := ensureNetwork(flags.network, flags.ipFamily != status.IPv4Family, nil); err != nil {
+		return deadlineErr(err)
+	}
+
+	handleErr := func(err error) error {
+		err = deadlineErr(err)
+
+		// commands for cleaning up are not subject to the cluster creation deadline
+		exec.SetCommandContext(nil)
+
+		// In case of errors nodes are deleted (except if retain is explicitly set)
+		if !flags.retain {
+			if err := deleteNodes(clusterName); err != nil {
+				return err
+			}
+		}
+		log.Error(err)
+		return err
+	}
+
+	// Create node containers as defined in the kind config
+	if err := createNodes(
+		clusterName,
+		flags,
+		unlock,
+	); err != nil {
+		return handleErr(errors.Wrap(err, "error creating nodes"))
+	}
+
+	// prints the Kubernetes version kubeadm init will use, that is the version installed in the node image
+	if c, err := status.FromDocker(clusterName); err == nil && c.BootstrapControlPlane() != nil {
+		if kubeVersion, err := c.BootstrapControlPlane().KubeVersion(); err == nil {
+			fmt.Fprintf(flags.progress, "Kubernetes version in the node image: %s\n", kubeVersion)
+		}
+	}
+
+	fmt.Fprintln(flags.progress)
+	fmt.Fprintf(flags.progress, "Nodes creation complete. You can now continue creating a Kubernetes cluster using\n")
+	fmt.Fprintf(flags.progress, "kinder do, the kinder swiss knife 🚀!\n")
+
+	return nil
+}
+
+// reuseExistingCluster sets the node image and the network of the cluster to be recreated to the ones of the
+// existing cluster with the same name, if any, when they are not set
+func reuseExistingCluster(clusterName string, flags *CreateOptions) error {
+	known, err := status.IsKnown(clusterName)
+	if err != nil || !known {
+		return err
+	}
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		return err
+	}
+
+	if flags.image == "" {
+		var bootstrap *status.Node
+		if bootstrap = c.BootstrapControlPlane(); bootstrap == nil && len(c.K8sNodes()) > 0 {
+			bootstrap = c.K8sNodes()[0]
+		}
+		if bootstrap != nil {
+			image, err := bootstrap.ContainerImage()
+			if err != nil {
+				return err
+			}
+			log.Infof("Reusing the node image %s of the existing cluster %q", image, clusterName)
+			flags.image = image
+		}
+	}
+
+	if flags.network == "" {
+		network, err := c.Network()
+		if err != nil {
+			return err
+		}
+		if network != "" {
+			log.Infof("Reusing the %s network of the existing cluster %q", network, clusterName)
+			flags.network = network
+		}
+	}
+	return nil
+}
+
+// createLockPath returns the host file locked for serializing the critical section of cluster creation
+// across kinder processes
+func createLockPath() string {
+	return filepath.Join(os.TempDir(), "kinder-create.lock")
+}
+
+// deleteNodes deletes all the node containers of a cluster, including volumes
+func deleteNodes(clusterName string) error {
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	for _, n := range c.AllNodes() {
+		if err := exec.NewHostCmd(
+			"docker",
+			"rm",
+			"-f", // force the container to be deleted now
+			"-v", // delete volumes
+			n.Name(),
+		).Run(); err != nil {
+			return errors.Wrapf(err, "failed to delete node %s", n.Name())
+		}
+	}
+	return nil
+}
+
+func createNodes(clusterName string, flags *CreateOptions, containersCreated func()) error {
+	// compute the desired nodes, and inform the user that we are setting them up
+	desiredNodes := nodesToCreate(clusterName, flags)
+	numberOfNodes := len(desiredNodes)
+	if flags.externalEtcd {
+		numberOfNodes += externalEtcdMembers(flags)
+	}
+	fmt.Fprintf(flags.progress, "Preparing nodes %s\n", strings.Repeat("📦", numberOfNodes))
+
+	// detect CRI runtime installed into images before actually creating nodes
+	runtime, err := status.InspectCRIinImage(flags.image)
+	if err != nil {
+		log.Errorf("Error detecting CRI for images %s! %v", flags.image, err)
+		return err
+	}
+	log.Infof("Detected %s container runtime for image %s", runtime, flags.image)
+
+	// node images of single nodes, if any, must have the same container runtime of the cluster node image
+	for _, image := range nodeImagesToCreate(desiredNodes) {
+		nodeRuntime, err := status.InspectCRIinImage(image)
+		if err != nil {
+			return errors.Wrapf(err, "failed to detect the container runtime for image %s", image)
+		}
+		if nodeRuntime != runtime {
+			return errors.Errorf("the node image %s uses the %s container runtime, while the cluster node image %s uses %s", image, nodeRuntime, flags.image, runtime)
+		}
+	}
+
+	if flags.externalRegistry && runtime != status.ContainerdRuntime {
+		return errors.Errorf("the external registry can be used only with the %s container runtime", status.ContainerdRuntime)
+	}
+
+	if flags.sandboxImage != "" && runtime != status.ContainerdRuntime {
+		return errors.Errorf("the sandbox image can be set only with the %s container runtime", status.ContainerdRuntime)
+	}
+
+	createHelper, err := nodes.NewCreateHelper(runtime, flags.network)
+	if err != nil {
+		log.Errorf("Error creating NewCreateHelper for CRI %s! %v", flags.image, err)
+		return err
+	}
+
+	// create all of the node containers
+	log.Info("Creating nodes...")
+	for _, desiredNode := range desiredNodes {
+		var err error
+		switch desiredNode.Role {
+		case constants.ExternalLoadBalancerNodeRoleValue:
+			// invalid load balancer implementations are rejected by validateLoadBalancer
+			implementation, _ := loadbalancer.ParseImplementation(flags.loadBalancer)
+			err = createHelper.CreateExternalLoadBalancer(clusterName, desiredNode.Name, implementation, status.NodeIPs{})
+		case constants.ExternalRegistryNodeRoleValue:
+			err = createHelper.CreateExternalRegistry(clusterName, desiredNode.Name)
+		case constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue:
+			image := flags.image
+			if desiredNode.Image != "" {
+				image = desiredNode.Image
+			}
+			err = createHelper.CreateNode(clusterName, desiredNode.Name, image, desiredNode.Role, flags.volumes, desiredNode.ExtraPortMappings, flags.apiServerBindPort, desiredNode.Labels, flags.dns, desiredNode.Resources, desiredNode.IPs, desiredNode.Command)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error creating node %v", desiredNode)
+		}
+	}
+
+	// add an external etcd if explicitly requested
+	if flags.externalEtcd {
+		log.Info("Getting required etcd image...")
+		c, err := status.FromDocker(clusterName)
+		if err != nil {
+			return err
+		}
+
+		defaultEtcdImage, err := c.BootstrapControlPlane().EtcdImage()
+		if err != nil {
+			return err
+		}
+		etcdImage := externalEtcdImage(defaultEtcdImage, flags.externalEtcdImage)
+		log.Infof("Using etcd image %s", etcdImage)
+
+		// attempt to explicitly pull the etcdImage if it doesn't exist locally
+		// we don't care if this errors, we'll still try to run which also pulls
+		_, _ = host.PullImage(etcdImage, 4)
+
+		if members := externalEtcdMembers(flags); members > 1 {
+			log.Infof("Creating external etcd cluster with %d members...", members)
+			if err := createHelper.CreateExternalEtcdCluster(clusterName, etcdImage, members, flags.etcdExtraArgs); err != nil {
+				return err
+			}
+		} else {
+			log.Info("Creating external etcd...")
+			if err := createHelper.CreateExternalEtcd(clusterName, fmt.Sprintf("%s-etcd", clusterName), etcdImage, flags.externalEtcdDataDir, flags.etcdExtraArgs); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the node containers exist, so concurrent creates can proceed
+	containersCreated()
+
+	// wait for all node containers to have a Running status
+	log.Info("Waiting for all nodes to start...")
+	timeout := time.Second * 40
+	for _, n := range desiredNodes {
+		var lastErr error
+		log.Infof("Waiting for node %s to start...", n.Name)
+		err = wait.PollImmediate(time.Second*1, timeout, func() (bool, error) {
+			lines, err := exec.NewHostCmd(
+				"docker",
+				"container",
+				"inspect",
+				"-f",
+				"'{{.State.Running}}'",
+				n.Name,
+			).RunAndCapture()
+			if err == nil && len(lines) > 0 && lines[0] == `'true'` {
+				return true, nil
+			}
+			lastErr = errors.Errorf("node state is not Running, error: %v, output lines: %+v, ", err, lines)
+			return false, nil
+		})
+		if err != nil {
+			return errors.Wrapf(lastErr, "node %s did not start in %v", n.Name, timeout)
+		}
+	}
+
+	// get the cluster
+	c, err := status.FromDocker(clusterName)
+	if err != nil {
+		return err
+	}
+
+	// configure the K8s nodes to use the external registry as a mirror, if any
+	if flags.externalRegistry {
+		endpoint, err := c.RegistryEndpoint()
+		if err != nil {
+			return err
+		}
+		log.Infof("Configuring nodes to use the registry mirror at %s...", endpoint)
+		for _, n := range c.K8sNodes() {
+			if err := createHelper.ConfigureRegistryMirror(n, endpoint); err != nil {
+				return errors.Wrapf(err, "failed to configure the registry mirror on node %s", n.Name())
+			}
+		}
+	}
+
+	// IPv6 and dual-stack clusters require an IPv6 address on all the K8s nodes
+	if flags.ipFamily != status.IPv4Family {
+		for _, n := range c.K8sNodes() {
+			_, ipv6, err := n.IP()
+			if err != nil {
+				return errors.Wrapf(err, "failed to get IP for node %s", n.Name())
+			}
+			if ipv6 == "" {
+				return errors.Errorf("node %s has no IPv6 address, that is required by %s clusters; check the %s network has IPv6 enabled", n.Name(), flags.ipFamily, flags.network)
+			}
+		}
+	}
+
+	// configure the K8s nodes to use the requested sandbox image, if any
+	if flags.sandboxImage != "" {
+		log.Infof("Configuring nodes to use the sandbox image %s...", flags.sandboxImage)
+		for _, n := range c.K8sNodes() {
+			if err := createHelper.ConfigureSandboxImage(n, flags.sandboxImage); err != nil {
+				return errors.Wrapf(err, "failed to configure the sandbox image on node %s", n.Name())
+			}
+		}
+	}
+
+	// apply the sysctls on the K8s nodes, if any, so they are set before the kubelet starts
+	if len(flags.sysctls) > 0 {
+		log.Info("Applying sysctls on the K8s nodes...")
+		for _, n := range c.K8sNodes() {
+			if err := applyNodeSysctls(n, flags.sysctls); err != nil {
+				return err
+			}
+		}
+	}
+
+	// reserve for the system the resources exceeding the limits of the K8s nodes, if any, so the node
+	// allocatable resources reported by the kubelet reflect the limits
+	for _, n := range c.K8sNodes() {
+		resources, err := n.Resources()
+		if err != nil {
+			return err
+		}
+		if resources.IsZero() {
+			continue
+		}
+		if err := reserveKubeletResources(n, resources); err != nil {
+			return err
+		}
+	}
+
+	// install the external etcd client certificates on the control-plane nodes, if any
+	if len(flags.externalEtcdCerts) > 0 {
+		log.Info("Installing the external etcd client certificates on control-plane nodes...")
+		for _, n := range c.ControlPlanes() {
+			if err := installExternalEtcdCerts(n, flags.externalEtcdCerts); err != nil {
+				return err
+			}
+		}
+	}
+
+	// install the service account signing key on the control-plane nodes, if any
+	if flags.serviceAccountKey != nil {
+		log.Info("Installing the service account signing key on control-plane nodes...")
+		for _, n := range c.ControlPlanes() {
+			if err := installServiceAccountKey(n, flags.serviceAccountKey, flags.serviceAccountPub); err != nil {
+				return err
+			}
+		}
+	}
+
+	// install the cluster CA on the control-plane nodes, if any
+	if flags.caCert != nil {
+		log.Info("Installing the cluster CA on control-plane nodes...")
+		for _, n := range c.ControlPlanes() {
+			if err := installClusterCA(n, flags.caCert, flags.caKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	// install the encryption config on the control-plane nodes, if any
+	if flags.encryptionConfig != "" {
+		log.Infof("Installing the %s encryption config on control-plane nodes...", flags.encryptionProvider)
+		for _, n := range c.ControlPlanes() {
+			if err := n.WriteFileWithPerm(kubeadm.EncryptionConfigPath, []byte(flags.encryptionConfig), 0600); err != nil {
+				return errors.Wrapf(err, "failed to install the encryption config on node %s", n.Name())
+			}
+		}
+	}
+
+	// install the audit policy on the control-plane nodes, if any
+	if len(flags.auditPolicy) > 0 {
+		log.Info("Installing the audit policy on control-plane nodes...")
+		for _, n := range c.ControlPlanes() {
+			if err := n.WriteFileWithPerm(kubeadm.AuditPolicyPath, flags.auditPolicy, 0600); err != nil {
+				return errors.Wrapf(err, "failed to install the audit policy on node %s", n.Name())
+			}
+		}
+	}
+
+	// copy the bootstrap manifest files on the control-plane nodes, if any
+	if len(flags.bootstrapManifestFiles) > 0 {
+		log.Info("Copying bootstrap manifests on control-plane nodes...")
+		for _, n := range c.ControlPlanes() {
+			for _, m := range flags.bootstrapManifestFiles {
+				if err := n.WriteFileWithPerm(m.nodePath, m.data, 0644); err != nil {
+					return errors.Wrapf(err, "failed to copy the bootstrap manifest %s on node %s", m.source, n.Name())
+				}
+			}
+		}
+	}
+
+	// copy the custom CNI manifest file on the control-plane nodes, if any
+	if m := flags.cniManifestFile; m != nil {
+		log.Info("Copying the CNI manifest on control-plane nodes...")
+		for _, n := range c.ControlPlanes() {
+			if err := n.WriteFileWithPerm(m.nodePath, m.data, 0644); err != nil {
+				return errors.Wrapf(err, "failed to copy the CNI manifest %s on node %s", m.source, n.Name())
+			}
+		}
+	}
+
+	c.Settings = &status.ClusterSettings{
+		IPFamily:          flags.ipFamily,
+		APIServerBindPort: flags.apiServerBindPort,
+		SkipKubeProxy:     flags.skipKubeProxy,
+		KubeProxyMode:     flags.kubeProxyMode,
+
+		NodeCIDRMaskSize:     flags.nodeCIDRMaskSize,
+		NodeCIDRMaskSizeIPv6: flags.nodeCIDRMaskSizeIPv6,
+
+		CNI:                cniInNode(flags),
+		KubeadmClusterName: flags.kubeadmClusterName,
+		EtcdExtraArgs:      flags.etcdExtraArgs,
+
+		ExternalEtcdEndpoints: flags.externalEtcdEndpoints,
+
+		KubeletServingCertRotation: flags.kubeletServingCertRotation,
+
+		NodeSysctls: flags.sysctls,
+	}
+
+	// TODO: the cluster and node settings are currently unused by kinder
+	// Enable these writes if settings have to stored on the nodes
+	//
+	// // write to the nodes the cluster settings that will be re-used by kinder during the cluster lifecycle.
+	//
+	// if err := c.WriteSettings(); err != nil {
+	// 	return err
+	// }
+	//
+	// for _, n := range c.K8sNodes() {
+	// 	if err := n.WriteNodeSettings(&status.NodeSettings{}); err != nil {
+	// 		return err
+	// 	}
+	// }
+
+	return nil
+}
+
+// nodeSpec describes a node to create purely from the container aspect
+// this does not include eg starting kubernetes (see actions for that)
+type nodeSpec struct {
+	Name              string
+	Role              string
+	Image             string
+	IPs               status.NodeIPs
+	ExtraPortMappings []status.PortMapping
+	Labels            map[string]string
+	Resources         status.NodeResources
+	Command           string
+}
+
+// nodesToCreate return the list of nodes to create for the cluster
+func nodesToCreate(clusterName string, flags *CreateOptions) []nodeSpec {
+	var desiredNodes []nodeSpec
+
+	// prepare nodes explicitly
+	for n := 0; n < flags.controlPlanes; n++ {
+		role := constants.ControlPlaneNodeRoleValue
+		desiredNode := nodeSpec{
+			Name:   fmt.Sprintf("%s-%s-%d", clusterName, role, n+1),
+			Role:   role,
+			Labels: controlPlaneLabels(flags),
+		}
+		if n+1 == flags.bootstrapControlPlane {
+			desiredNode.Labels[constants.BootstrapControlPlaneLabelKey] = "true"
+		}
+		setK8sNodeSettings(&desiredNode, flags)
+		desiredNodes = append(desiredNodes, desiredNode)
+	}
+	for n := 0; n < flags.workers; n++ {
+		role := constants.WorkerNodeRoleValue
+		desiredNode := nodeSpec{
+			Name:   fmt.Sprintf("%s-%s-%d", clusterName, role, n+1),
+			Role:   role,
+			Labels: workerLabels(flags),
+		}
+		setK8sNodeSettings(&desiredNode, flags)
+		desiredNodes = append(desiredNodes, desiredNode)
+	}
+
+	// worker pools nodes are numbered independently for each pool, e.g. kind-worker-spot-1
+	// invalid worker pools are rejected by validateWorkerPools
+	pools, _ := parseWorkerPools(flags.workerPools)
+	for _, p := range pools {
+		for n := 0; n < p.Count; n++ {
+			role := constants.WorkerNodeRoleValue
+			desiredNode := nodeSpec{
+				Name:   fmt.Sprintf("%s-%s-%s-%d", clusterName, role, p.Name, n+1),
+				Role:   role,
+				Labels: workerPoolLabels(p),
+			}
+			setK8sNodeSettings(&desiredNode, flags)
+			desiredNodes = append(desiredNodes, desiredNode)
+		}
+	}
+
+	// add an external load balancer if explicitly requested or if there are multiple control planes
+	if flags.externalLoadBalancer || flags.controlPlanes > 1 {
+		role := constants.ExternalLoadBalancerNodeRoleValue
+		desiredNodes = append(desiredNodes, nodeSpec{
+			Name: fmt.Sprintf("%s-lb", clusterName),
+			Role: role,
+		})
+	}
+
+	// add an external registry if explicitly requested
+	if flags.externalRegistry {
+		desiredNodes = append(desiredNodes, nodeSpec{
+			Name: fmt.Sprintf("%s-registry", clusterName),
+			Role: constants.ExternalRegistryNodeRoleValue,
+		})
+	}
+
+	return desiredNodes
+}
+
+// setK8sNodeSettings sets the settings common to all the K8s nodes, control-plane and workers
+func setK8sNodeSettings(n *nodeSpec, flags *CreateOptions) {
+	n.ExtraPortMappings = extraPortMappingsForRole(flags, n.Role)
+	n.Resources = resourcesForRole(flags, n.Role)
+	setNodeCommand(n, flags)
+	setKubeNodeName(n, flags)
+	setNodeImage(n, flags)
+	setNodeIPs(n, flags)
+	setSandboxImage(n, flags)
+	setKubeletEviction(n, flags)
+	setNodeSysctls(n, flags)
+	setCoreDNSImage(n, flags)
+	setEtcdVersion(n, flags)
+	setClusterMetadata(n, flags)
+}
+
+// setClusterMetadata records the cluster metadata as labels on a K8s node, so external tooling can read
+// them with docker ps, e.g. docker ps --format '{{.Label "io.x-k8s.kinder.kubernetes-version"}}'
+func setClusterMetadata(n *nodeSpec, flags *CreateOptions) {
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	ipFamily := flags.ipFamily
+	if ipFamily == "" {
+		ipFamily = status.IPv4Family
+	}
+	n.Labels[constants.IPFamilyLabelKey] = string(ipFamily)
+	kubernetesVersion := flags.kubernetesVersion
+	if n.Image != "" {
+		kubernetesVersion = flags.nodeKubernetesVersions[n.Image]
+	}
+	if kubernetesVersion != "" {
+		n.Labels[constants.KubernetesVersionLabelKey] = kubernetesVersion
+	}
+}
+
+// extraPortMappingsRoles returns the node roles extra port mappings should be applied to
+func extraPortMappingsRoles(flags *CreateOptions) []string {
+	if len(flags.extraPortMappingsRoles) == 0 {
+		return []string{constants.WorkerNodeRoleValue}
+	}
+	return flags.extraPortMappingsRoles
+}
+
+// extraPortMappingsForRole returns the extra port mappings to apply to nodes with the given role
+func extraPortMappingsForRole(flags *CreateOptions, role string) []status.PortMapping {
+	for _, r := range extraPortMappingsRoles(flags) {
+		if r == role {
+			return flags.extraPortMappings
+		}
+	}
+	return nil
+}
+
+// validateExtraPortMappings checks that extra port mappings can be applied to the nodes to create
+func validateExtraPortMappings(clusterName string, flags *CreateOptions) error {
+	if len(flags.extraPortMappings) == 0 {
+		return nil
+	}
+
+	for _, r := range extraPortMappingsRoles(flags) {
+		if r != constants.ControlPlaneNodeRoleValue && r != constants.WorkerNodeRoleValue {
+			return errors.Errorf("invalid role %q for extra port mappings. Use one of [%s, %s]", r, constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue)
+		}
+	}
+
+	targetNodes := 0
+	for _, n := range nodesToCreate(clusterName, flags) {
+		if len(n.ExtraPortMappings) > 0 {
+			targetNodes++
+		}
+	}
+	if targetNodes == 0 {
+		return errors.Errorf("there are no nodes with role %v to apply extra port mappings to", extraPortMappingsRoles(flags))
+	}
+	// the same host port can't be bound by more than one node container
+	if targetNodes > 1 {
+		return errors.Errorf("extra port mappings can't be applied to %d nodes, because host ports can be bound only once. Target a role with only one node", targetNodes)
+	}
+
+	seen := map[string]bool{}
+	for _, p := range flags.extraPortMappings {
+		key := fmt.Sprintf("%d/%s", p.HostPort, p.Protocol)
+		if seen[key] {
+			return errors.Errorf("host port %s is mapped more than once", key)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// validateAPIServerBindPort checks the API server bind port; ports below 1024 are accepted, because the API
+// server runs as root by default, but they can't be used with a rootless control-plane (checked at init time)
+func validateAPIServerBindPort(flags *CreateOptions) error {
+	port := flags.apiServerBindPort
+	if port == 0 {
+		return nil
+	}
+	if port < 1 || port > 65535 {
+		return errors.Errorf("invalid API server bind port %d. Use a port in the range [1-65535]", port)
+	}
+
+	// the bind port should not collide with extra port mappings on control-plane nodes
+	if extraPortMappingsForRole(flags, constants.ControlPlaneNodeRoleValue) != nil {
+		for _, p := range flags.extraPortMappings {
+			if p.ContainerPort == port && p.Protocol == "tcp" {
+				return errors.Errorf("the API server bind port %d is also used by an extra port mapping on control-plane nodes", port)
+			}
+		}
+	}
+	return nil
+}
+
+// workerLabels returns the container labels recording the Kubernetes labels and taints for worker nodes
+func workerLabels(flags *CreateOptions) map[string]string {
+	labels := map[string]string{}
+	if len(flags.workerLabels) > 0 {
+		labels[constants.NodeLabelsLabelKey] = strings.Join(flags.workerLabels, ",")
+	}
+	if len(flags.workerTaints) > 0 {
+		labels[constants.NodeTaintsLabelKey] = strings.Join(flags.workerTaints, ",")
+	}
+	return labels
+}
+
+// workerPool defines a pool of worker nodes sharing the same Kubernetes labels and taints
+type workerPool struct {
+	Name   string
+	Count  int
+	Labels []string
+	Taints []string
+}
+
+// workerPoolNameRegexp matches valid worker pool names, that are used as part of the node names
+var workerPoolNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// parseWorkerPools parses worker pools in the name=NAME;count=N[;labels=key=value,...][;taints=key=value:Effect,...] form
+func parseWorkerPools(values []string) ([]workerPool, error) {
+	var pools []workerPool
+	names := map[string]bool{}
+	for _, v := range values {
+		pool, err := parseWorkerPool(v)
+		if err != nil {
+			return nil, err
+		}
+		if names[pool.Name] {
+			return nil, errors.Errorf("the worker pool %q is defined more than once", pool.Name)
+		}
+		names[pool.Name] = true
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// parseWorkerPool parses a worker pool in the name=NAME;count=N[;labels=key=value,...][;taints=key=value:Effect,...] form
+func parseWorkerPool(value string) (workerPool, error) {
+	var pool workerPool
+	seen := map[string]bool{}
+	for _, field := range strings.Split(value, ";") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return workerPool{}, errors.Errorf("invalid worker pool %q. Use the name=NAME;count=N[;labels=key=value,...][;taints=key=value:Effect,...] form", value)
+		}
+		key := strings.TrimSpace(kv[0])
+		if seen[key] {
+			return workerPool{}, errors.Errorf("invalid worker pool %q. The %s field is set more than once", value, key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "name":
+			pool.Name = kv[1]
+		case "count":
+			count, err := strconv.Atoi(kv[1])
+			if err != nil || count < 1 {
+				return workerPool{}, errors.Errorf("invalid worker pool %q. The count should be a number greater than 0", value)
+			}
+			pool.Count = count
+		case "labels":
+			pool.Labels = strings.Split(kv[1], ",")
+		case "taints":
+			pool.Taints = strings.Split(kv[1], ",")
+		default:
+			return workerPool{}, errors.Errorf("invalid worker pool %q. Unknown field %q, use one of [name, count, labels, taints]", value, key)
+		}
+	}
+
+	if !workerPoolNameRegexp.MatchString(pool.Name) {
+		return workerPool{}, errors.Errorf("invalid worker pool %q. The name should consist of lower case alphanumeric characters or '-'", value)
+	}
+	if pool.Count == 0 {
+		return workerPool{}, errors.Errorf("invalid worker pool %q. The count is required", value)
+	}
+	for _, l := range pool.Labels {
+		if _, _, err := kubeadm.ParseNodeLabel(l); err != nil {
+			return workerPool{}, errors.Wrapf(err, "invalid worker pool %q", value)
+		}
+	}
+	for _, t := range pool.Taints {
+		if _, err := kubeadm.ParseNodeTaint(t); err != nil {
+			return workerPool{}, errors.Wrapf(err, "invalid worker pool %q", value)
+		}
+	}
+	return pool, nil
+}
+
+// validateWorkerPools checks the worker pools
+func validateWorkerPools(flags *CreateOptions) error {
+	_, err := parseWorkerPools(flags.workerPools)
+	return err
+}
+
+// workerPoolLabels returns the container labels recording the pool name and the Kubernetes labels and taints
+// for the worker nodes in a pool
+func workerPoolLabels(p workerPool) map[string]string {
+	labels := map[string]string{
+		constants.WorkerPoolLabelKey: p.Name,
+	}
+	if len(p.Labels) > 0 {
+		labels[constants.NodeLabelsLabelKey] = strings.Join(p.Labels, ",")
+	}
+	if len(p.Taints) > 0 {
+		labels[constants.NodeTaintsLabelKey] = strings.Join(p.Taints, ",")
+	}
+	return labels
+}
+
+// parseNodeCommands parses command overrides in the TARGET=COMMAND form
+func parseNodeCommands(values []string) (map[string]string, error) {
+	commands := map[string]string{}
+	for _, v := range values {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 || split[0] == "" || strings.TrimSpace(split[1]) == "" {
+			return nil, errors.Errorf("invalid node command %q. Use the TARGET=COMMAND form", v)
+		}
+		if _, ok := commands[split[0]]; ok {
+			return nil, errors.Errorf("multiple node commands for %q", split[0])
+		}
+		commands[split[0]] = split[1]
+	}
+	return commands, nil
+}
+
+// validateNodeCommands checks the command overrides for the K8s node containers; targets must be
+// a K8s node role or the name of a K8s node, and commands must exec the kind entrypoint, otherwise the node won't boot
+func validateNodeCommands(clusterName string, flags *CreateOptions) error {
+	commands, err := parseNodeCommands(flags.nodeCommands)
+	if err != nil {
+		return err
+	}
+
+	targets := map[string]bool{
+		constants.ControlPlaneNodeRoleValue: flags.controlPlanes > 0,
+		constants.WorkerNodeRoleValue:       flags.workers > 0 || len(flags.workerPools) > 0,
+	}
+	for _, n := range nodesToCreate(clusterName, flags) {
+		if n.Role == constants.ControlPlaneNodeRoleValue || n.Role == constants.WorkerNodeRoleValue {
+			targets[n.Name] = true
+		}
+	}
+
+	for target, command := range commands {
+		if !targets[target] {
+			return errors.Errorf("invalid node command target %q. Use the %s or %s role, or the name of a node to be created", target, constants.ControlPlaneNodeRoleValue, constants.WorkerNodeRoleValue)
+		}
+		if !execsNodeEntrypoint(command) {
+			return errors.Errorf("invalid node command for %q: the command must end with \"exec %s\" for booting the node", target, constants.NodeEntrypoint)
+		}
+	}
+	return nil
+}
+
+// execsNodeEntrypoint returns true if a node command ends by replacing the shell with the kind entrypoint, so the
+// entrypoint runs as PID 1 and receives the signals sent to the container
+func execsNodeEntrypoint(command string) bool {
+	command = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(command), ";"))
+	prefix := strings.TrimSuffix(command, "exec "+constants.NodeEntrypoint)
+	if prefix == command {
+		return false
+	}
+	prefix = strings.TrimRight(prefix, " \t\n")
+	return prefix == "" || strings.HasSuffix(prefix, ";") || strings.HasSuffix(prefix, "&&") || strings.HasSuffix(prefix, "||")
+}
+
+// setNodeCommand sets the command override for a node, if any; a command for the node name takes precedence
+// over a command for the node role. The command is also recorded in a label, so it is possible to know
+// the node was modified after create
+func setNodeCommand(n *nodeSpec, flags *CreateOptions) {
+	// invalid commands are rejected by validateNodeCommands
+	commands, _ := parseNodeCommands(flags.nodeCommands)
+	command, ok := commands[n.Name]
+	if !ok {
+		command = commands[n.Role]
+	}
+	if command == "" {
+		return
+	}
+
+	n.Command = command
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	n.Labels[constants.NodeCommandLabelKey] = command
+}
+
+// parseNodeNames parses the Kubernetes node names in the NODE=NAME form, indexed by node
+func parseNodeNames(values []string) (map[string]string, error) {
+	names := map[string]string{}
+	for _, v := range values {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, errors.Errorf("invalid node name %q. Use the NODE=NAME form", v)
+		}
+		if _, ok := names[split[0]]; ok {
+			return nil, errors.Errorf("multiple node names for %q", split[0])
+		}
+		names[split[0]] = split[1]
+	}
+	return names, nil
+}
+
+// validateNodeNames checks the Kubernetes node names; nodes must be K8s nodes to be created, and names must be
+// DNS-1123 labels not used by other nodes, otherwise the nodes would overwrite each other at join time
+func validateNodeNames(clusterName string, flags *CreateOptions) error {
+	names, err := parseNodeNames(flags.nodeNames)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	// the Kubernetes node name of each K8s node, that is the container name by default
+	nodeNames := map[string]string{}
+	for _, n := range nodesToCreate(clusterName, flags) {
+		if n.Role == constants.ControlPlaneNodeRoleValue || n.Role == constants.WorkerNodeRoleValue {
+			nodeNames[n.Name] = n.Name
+		}
+	}
+
+	for node, name := range names {
+		if _, ok := nodeNames[node]; !ok {
+			return errors.Errorf("invalid node name target %q. Use the name of a K8s node to be created", node)
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return errors.Errorf("invalid node name %q for %q: %s", name, node, strings.Join(errs, "; "))
+		}
+		nodeNames[node] = name
+	}
+
+	used := map[string]string{}
+	for node, name := range nodeNames {
+		if other, ok := used[name]; ok {
+			return errors.Errorf("the node name %q is used by both %q and %q", name, node, other)
+		}
+		used[name] = node
+	}
+	return nil
+}
+
+// setKubeNodeName records the Kubernetes node name for a node in a label, if any, so it can be used
+// at init/join time
+func setKubeNodeName(n *nodeSpec, flags *CreateOptions) {
+	// invalid names are rejected by validateNodeNames
+	names, _ := parseNodeNames(flags.nodeNames)
+	name, ok := names[n.Name]
+	if !ok {
+		return
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	n.Labels[constants.KubeNodeNameLabelKey] = name
+}
+
+// parseNodeImages parses the node images of single nodes in the NODE=IMAGE form, indexed by node
+func parseNodeImages(values []string) (map[string]string, error) {
+	images := map[string]string{}
+	for _, v := range values {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, errors.Errorf("invalid node image %q. Use the NODE=IMAGE form", v)
+		}
+		if _, ok := images[split[0]]; ok {
+			return nil, errors.Errorf("multiple node images for %q", split[0])
+		}
+		images[split[0]] = split[1]
+	}
+	return images, nil
+}
+
+// validateNodeImages checks the node images of single nodes; nodes must be K8s nodes to be created, and images
+// must be valid image references
+func validateNodeImages(clusterName string, flags *CreateOptions) error {
+	images, err := parseNodeImages(flags.nodeImages)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	k8sNodes := map[string]bool{}
+	for _, n := range nodesToCreate(clusterName, flags) {
+		if n.Role == constants.ControlPlaneNodeRoleValue || n.Role == constants.WorkerNodeRoleValue {
+			k8sNodes[n.Name] = true
+		}
+	}
+
+	for node, image := range images {
+		if !k8sNodes[node] {
+			return errors.Errorf("invalid node image target %q. Use the name of a K8s node to be created", node)
+		}
+		if !imageReferenceRegexp.MatchString(image) {
+			return errors.Errorf("invalid node image %q for %q. Use the [registry/]repository[:tag][@digest] form", image, node)
+		}
+	}
+	return nil
+}
+
+// setNodeImage sets the node image for a node, if different from the cluster node image
+func setNodeImage(n *nodeSpec, flags *CreateOptions) {
+	// invalid images are rejected by validateNodeImages
+	images, _ := parseNodeImages(flags.nodeImages)
+	n.Image = images[n.Name]
+}
+
+// nodeImagesToCreate returns the node images of single nodes, if any, without duplicates
+func nodeImagesToCreate(desiredNodes []nodeSpec) []string {
+	var images []string
+	seen := map[string]bool{}
+	for _, n := range desiredNodes {
+		if n.Image != "" && !seen[n.Image] {
+			seen[n.Image] = true
+			images = append(images, n.Image)
+		}
+	}
+	return images
+}
+
+// parseNodeIPs parses the static IP addresses of single nodes in the NODE=IP form, indexed by node; a node can
+// have an IPv4 and an IPv6 address
+func parseNodeIPs(values []string) (map[string]status.NodeIPs, error) {
+	ips := map[string]status.NodeIPs{}
+	for _, v := range values {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return nil, errors.Errorf("invalid node IP %q. Use the NODE=IP form", v)
+		}
+		ip := net.ParseIP(split[1])
+		if ip == nil {
+			return nil, errors.Errorf("invalid node IP %q for %q", split[1], split[0])
+		}
+		nodeIPs := ips[split[0]]
+		if ip.To4() != nil {
+			if nodeIPs.IPv4 != "" {
+				return nil, errors.Errorf("multiple IPv4 addresses for %q", split[0])
+			}
+			nodeIPs.IPv4 = ip.String()
+		} else {
+			if nodeIPs.IPv6 != "" {
+				return nil, errors.Errorf("multiple IPv6 addresses for %q", split[0])
+			}
+			nodeIPs.IPv6 = ip.String()
+		}
+		ips[split[0]] = nodeIPs
+	}
+	return ips, nil
+}
+
+// validateNodeIPs checks the static IP addresses of single nodes; nodes must be K8s nodes to be created, addresses
+// must match the IP family of the cluster and must not be used by other nodes. Static IP addresses can't be assigned
+// on the default network
+func validateNodeIPs(clusterName string, flags *CreateOptions) error {
+	ips, err := parseNodeIPs(flags.nodeIPs)
+	if err != nil {
+		return err
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+	if flags.network == "" {
+		return errors.New("node IPs can be set only for clusters using a user defined network")
+	}
+
+	k8sNodes := map[string]bool{}
+	for _, n := range nodesToCreate(clusterName, flags) {
+		if n.Role == constants.ControlPlaneNodeRoleValue || n.Role == constants.WorkerNodeRoleValue {
+			k8sNodes[n.Name] = true
+		}
+	}
+
+	used := map[string]string{}
+	for node, nodeIPs := range ips {
+		if !k8sNodes[node] {
+			return errors.Errorf("invalid node IP target %q. Use the name of a K8s node to be created", node)
+		}
+		if nodeIPs.IPv4 != "" && flags.ipFamily == status.IPv6Family {
+			return errors.Errorf("invalid node IP %s for %q: IPv4 addresses can't be used with the %s IP family", nodeIPs.IPv4, node, flags.ipFamily)
+		}
+		if nodeIPs.IPv6 != "" && (flags.ipFamily == "" || flags.ipFamily == status.IPv4Family) {
+			return errors.Errorf("invalid node IP %s for %q: IPv6 addresses can't be used with the %s IP family", nodeIPs.IPv6, node, status.IPv4Family)
+		}
+		for _, ip := range []string{nodeIPs.IPv4, nodeIPs.IPv6} {
+			if ip == "" {
+				continue
+			}
+			if other, ok := used[ip]; ok {
+				return errors.Errorf("the node IP %s is used by both %q and %q", ip, node, other)
+			}
+			used[ip] = node
+		}
+	}
+	return nil
+}
+
+// setNodeIPs sets the static IP addresses for a node, if any
+func setNodeIPs(n *nodeSpec, flags *CreateOptions) {
+	// invalid IPs are rejected by validateNodeIPs
+	ips, _ := parseNodeIPs(flags.nodeIPs)
+	n.IPs = ips[n.Name]
+}
+
+// imageReferenceRegexp matches image references in the [registry[:port]/]repository[:tag][@digest] form
+var imageReferenceRegexp = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)*(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(@sha256:[a-f0-9]{64})?$`)
+
+// validateSandboxImage checks the sandbox image reference, if any
+func validateSandboxImage(flags *CreateOptions) error {
+	if flags.sandboxImage == "" {
+		return nil
+	}
+	if !imageReferenceRegexp.MatchString(flags.sandboxImage) {
+		return errors.Errorf("invalid sandbox image %q. Use the [registry/]repository[:tag][@digest] form", flags.sandboxImage)
+	}
+	return nil
+}
+
+// setSandboxImage sets the sandbox image label of a node, if a sandbox image is defined
+func setSandboxImage(n *nodeSpec, flags *CreateOptions) {
+	if flags.sandboxImage == "" {
+		return
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	n.Labels[constants.SandboxImageLabelKey] = flags.sandboxImage
+}
+
+// validateCoreDNS checks the CoreDNS image and the number of CoreDNS replicas
+func validateCoreDNS(flags *CreateOptions) error {
+	if flags.coreDNSImage != "" {
+		if _, _, err := kubeadm.ParseCoreDNSImage(flags.coreDNSImage); err != nil {
+			return err
+		}
+	}
+	if flags.coreDNSReplicas < 0 {
+		return errors.Errorf("invalid number of CoreDNS replicas %d. Use a positive number", flags.coreDNSReplicas)
+	}
+	return nil
+}
+
+// setCoreDNSImage sets the CoreDNS image label of a node, if a CoreDNS image is defined
+func setCoreDNSImage(n *nodeSpec, flags *CreateOptions) {
+	if flags.coreDNSImage == "" {
+		return
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	n.Labels[constants.CoreDNSImageLabelKey] = flags.coreDNSImage
+}
+
+// validateBootstrapControlPlane checks the number of the bootstrap control-plane node refers to
+// one of the control-plane nodes to create
+func validateBootstrapControlPlane(flags *CreateOptions) error {
+	if flags.bootstrapControlPlane < 0 || flags.bootstrapControlPlane > flags.controlPlanes {
+		return errors.Errorf("invalid bootstrap control-plane %d. Use a number between 1 and %d", flags.bootstrapControlPlane, flags.controlPlanes)
+	}
+	return nil
+}
+
+// validateEtcdVersion checks the local etcd image tag, that should be a semantic version,
+// e.g. 3.5.12-0; the local etcd version can't be set when using an external etcd
+func validateEtcdVersion(flags *CreateOptions) error {
+	if flags.etcdVersion == "" {
+		return nil
+	}
+	if flags.externalEtcd {
+		return errors.New("the etcd version can't be set when creating an external etcd; set the external etcd image instead")
+	}
+	if strings.HasPrefix(flags.etcdVersion, "v") {
+		return errors.Errorf("invalid etcd version %q. Use the etcd image tag without the v prefix, e.g. 3.5.12-0", flags.etcdVersion)
+	}
+	if _, err := K8sVersion.ParseSemantic(flags.etcdVersion); err != nil {
+		return errors.Wrapf(err, "invalid etcd version %q. Use a semantic version, e.g. 3.5.12-0", flags.etcdVersion)
+	}
+	return nil
+}
+
+// etcd flags setting the backend quota and the auto-compaction
+const (
+	etcdQuotaBackendBytesArg       = "quota-backend-bytes"
+	etcdAutoCompactionModeArg      = "auto-compaction-mode"
+	etcdAutoCompactionRetentionArg = "auto-compaction-retention"
+)
+
+// etcdMaxQuotaBackendBytes defines the maximum backend quota suggested by etcd; larger quotas are accepted
+// with a warning, because etcd may not perform well
+const etcdMaxQuotaBackendBytes = 8 * 1024 * 1024 * 1024
+
+// validateEtcdQuotaAndCompaction checks the etcd backend quota and auto-compaction settings, and sets the
+// corresponding etcd extra args. The retention is a duration, e.g. 1h, or a number of hours for the periodic mode,
+// that is the etcd default, and a number of revisions for the revision mode
+func validateEtcdQuotaAndCompaction(flags *CreateOptions) error {
+	var args []string
+
+	if flags.etcdQuotaBackendBytes != "" {
+		quota, err := resource.ParseQuantity(flags.etcdQuotaBackendBytes)
+		if err != nil {
+			return errors.Wrapf(err, "invalid etcd quota backend bytes %q. Use a quantity, e.g. 2Gi", flags.etcdQuotaBackendBytes)
+		}
+		if quota.Sign() <= 0 {
+			return errors.Errorf("invalid etcd quota backend bytes %q. Use a positive quantity", flags.etcdQuotaBackendBytes)
+		}
+		if quota.Value() > etcdMaxQuotaBackendBytes {
+			log.Warnf("etcd quota backend bytes %s exceeds the maximum suggested by etcd (8Gi)", flags.etcdQuotaBackendBytes)
+		}
+		args = append(args, fmt.Sprintf("%s=%d", etcdQuotaBackendBytesArg, quota.Value()))
+	}
+
+	if flags.etcdCompactionMode != "" && flags.etcdCompactionRetain == "" {
+		return errors.New("the etcd auto-compaction mode requires the auto-compaction retention")
+	}
+	switch flags.etcdCompactionMode {
+	case "", "periodic":
+		if flags.etcdCompactionRetain != "" {
+			if _, err := strconv.ParseUint(flags.etcdCompactionRetain, 10, 64); err != nil {
+				if d, err := time.ParseDuration(flags.etcdCompactionRetain); err != nil || d <= 0 {
+					return errors.Errorf("invalid etcd auto-compaction retention %q. Use a positive duration, e.g. 1h, or a number of hours for the periodic mode", flags.etcdCompactionRetain)
+				}
+			}
+		}
+	case "revision":
+		if _, err := strconv.ParseUint(flags.etcdCompactionRetain, 10, 64); err != nil {
+			return errors.Errorf("invalid etcd auto-compaction retention %q. Use a number of revisions for the revision mode", flags.etcdCompactionRetain)
+		}
+	default:
+		return errors.Errorf("invalid etcd auto-compaction mode %q. Use one of [periodic, revision]", flags.etcdCompactionMode)
+	}
+	if flags.etcdCompactionMode != "" {
+		args = append(args, fmt.Sprintf("%s=%s", etcdAutoCompactionModeArg, flags.etcdCompactionMode))
+	}
+	if flags.etcdCompactionRetain != "" {
+		args = append(args, fmt.Sprintf("%s=%s", etcdAutoCompactionRetentionArg, flags.etcdCompactionRetain))
+	}
+
+	flags.etcdExtraArgs = args
+	return nil
+}
+
+// setEtcdVersion sets the local etcd image tag label of a node, if an etcd version is defined
+func setEtcdVersion(n *nodeSpec, flags *CreateOptions) {
+	if flags.etcdVersion == "" {
+		return
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	n.Labels[constants.EtcdVersionLabelKey] = flags.etcdVersion
+}
+
+// networkNameRegexp matches the names accepted by docker for user defined networks
+var networkNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// validateNetwork checks the name of the user defined docker network, if any
+func validateNetwork(flags *CreateOptions) error {
+	switch flags.network {
+	case "":
+		return nil
+	case "host", "none":
+		return errors.Errorf("the %s network can't be used for kinder clusters", flags.network)
+	}
+	if !networkNameRegexp.MatchString(flags.network) {
+		return errors.Errorf("invalid network name %q. Only [a-zA-Z0-9][a-zA-Z0-9_.-] are allowed", flags.network)
+	}
+	return nil
+}
+
+// validateLoadBalancer checks the load balancer implementation; the implementation can be set only
+// if the cluster has an external load balancer
+func validateLoadBalancer(flags *CreateOptions) error {
+	if flags.loadBalancer == "" {
+		return nil
+	}
+	if !flags.externalLoadBalancer && flags.controlPlanes <= 1 {
+		return errors.New("the load balancer implementation can be set only for clusters with an external load balancer")
+	}
+	_, err := loadbalancer.ParseImplementation(flags.loadBalancer)
+	return err
+}
+
+// ensureNetwork creates the user defined docker network, if it does not exist yet; the network is not
+// labeled with the cluster name, because it can be shared with other clusters or containers, and
+// for the same reason it is not removed when the cluster is deleted. IPv6 and dual-stack clusters
+// require a network with IPv6 enabled. If subnets are set, they are used when creating the network,
+// e.g. for assigning the same node IPs when importing a cluster.
+func ensureNetwork(network string, ipv6 bool, subnets []string) error {
+	if network == "" {
+		return nil
+	}
+	if lines, err := exec.NewHostCmd("docker", "network", "inspect", "--format={{.EnableIPv6}}", network).RunAndCapture(); err == nil {
+		if ipv6 && (len(lines) != 1 || strings.TrimSpace(lines[0]) != "true") {
+			return errors.Errorf("the existing %s network does not have IPv6 enabled, that is required by IPv6 and dual-stack clusters", network)
+		}
+		log.Infof("Using the existing %s network", network)
+		return nil
+	}
+	args := []string{"network", "create"}
+	if ipv6 {
+		args = append(args, "--ipv6")
+	}
+	for _, s := range subnets {
+		args = append(args, "--subnet", s)
+	}
+	log.Infof("Creating the %s network", network)
+	if err := exec.NewHostCmd("docker", append(args, network)...).Run(); err != nil {
+		return errors.Wrapf(err, "failed to create the %s network", network)
+	}
+	return nil
+}
+
+// validateAPIServerTLS checks the API server TLS min version and cipher suites; the compatibility of the cipher
+// suites with the key type is checked at init time. The settings can't be set also by the API server extra args
+func validateAPIServerTLS(flags *CreateOptions) error {
+	if flags.tlsMinVersion == "" && len(flags.tlsCipherSuites) == 0 {
+		return nil
+	}
+	if err := kubeadm.ValidateAPIServerTLSSettings(flags.tlsMinVersion, flags.tlsCipherSuites); err != nil {
+		return err
+	}
+
+	for _, v := range flags.controlPlaneExtraArgs[kubeadm.APIServerComponent] {
+		if strings.HasPrefix(v, kubeadm.TLSMinVersionArg+"=") || strings.HasPrefix(v, kubeadm.TLSCipherSuitesArg+"=") {
+			return errors.New("the API server TLS settings can't be set both by the API server extra args and by the API server TLS option")
+		}
+	}
+	return nil
+}
+
+// validateClusterSigningDuration checks the cluster signing duration, and adds it to the controller manager extra args;
+// the duration can't be set also by the controller manager extra args
+func validateClusterSigningDuration(flags *CreateOptions) error {
+	if flags.clusterSigningDuration == 0 {
+		return nil
+	}
+	if flags.clusterSigningDuration < 0 {
+		return errors.Errorf("invalid cluster signing duration %s. The duration can't be negative", flags.clusterSigningDuration)
+	}
+	if flags.clusterSigningDuration < time.Minute {
+		return errors.Errorf("invalid cluster signing duration %s. Use a duration of at least 1m", flags.clusterSigningDuration)
+	}
+
+	values := flags.controlPlaneExtraArgs[kubeadm.ControllerManagerComponent]
+	for _, v := range values {
+		if strings.HasPrefix(v, kubeadm.ClusterSigningDurationArg+"=") {
+			return errors.New("the cluster signing duration can't be set both by the controller manager extra args and by the cluster signing duration option")
+		}
+	}
+	if flags.controlPlaneExtraArgs == nil {
+		flags.controlPlaneExtraArgs = map[string][]string{}
+	}
+	flags.controlPlaneExtraArgs[kubeadm.ControllerManagerComponent] = append(values, fmt.Sprintf("%s=%s", kubeadm.ClusterSigningDurationArg, flags.clusterSigningDuration))
+	return nil
+}
+
+// serviceAccountIssuerArg is the API server flag setting the service account issuer
+const serviceAccountIssuerArg = "service-account-issuer"
+
+// validateServiceAccount checks the service account issuer, and adds it to the API server extra args, and reads
+// the service account signing key, if any; the issuer can't be set also by the API server extra args
+func validateServiceAccount(flags *CreateOptions) error {
+	if flags.serviceAccountKeyFile != "" {
+		keyPEM, err := os.ReadFile(flags.serviceAccountKeyFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the service account key")
+		}
+		flags.serviceAccountKey, flags.serviceAccountPub, err = kubeadm.ServiceAccountKeyPair(keyPEM)
+		if err != nil {
+			return errors.Wrapf(err, "invalid service account key %s", flags.serviceAccountKeyFile)
+		}
+	}
+
+	if flags.serviceAccountIssuer == "" {
+		return nil
+	}
+	if err := kubeadm.ValidateServiceAccountIssuer(flags.serviceAccountIssuer); err != nil {
+		return err
+	}
+
+	values := flags.controlPlaneExtraArgs[kubeadm.APIServerComponent]
+	for _, v := range values {
+		if strings.HasPrefix(v, serviceAccountIssuerArg+"=") {
+			return errors.New("the service account issuer can't be set both by the API server extra args and by the service account issuer option")
+		}
+	}
+	if flags.controlPlaneExtraArgs == nil {
+		flags.controlPlaneExtraArgs = map[string][]string{}
+	}
+	flags.controlPlaneExtraArgs[kubeadm.APIServerComponent] = append(values, fmt.Sprintf("%s=%s", serviceAccountIssuerArg, flags.serviceAccountIssuer))
+	return nil
+}
+
+// validateEncryptionAtRest checks the encryption provider, generates the encryption config, and adds it to the
+// API server extra args; the encryption config can't be set also by the API server extra args
+func validateEncryptionAtRest(flags *CreateOptions) error {
+	if flags.encryptionProvider == "" {
+		return nil
+	}
+	if err := kubeadm.ValidateEncryptionProvider(flags.encryptionProvider); err != nil {
+		return err
+	}
+
+	values := flags.controlPlaneExtraArgs[kubeadm.APIServerComponent]
+	for _, v := range values {
+		if strings.HasPrefix(v, kubeadm.EncryptionProviderConfigArg+"=") {
+			return errors.New("the encryption config can't be set both by the API server extra args and by the encryption provider option")
+		}
+	}
+
+	config, err := kubeadm.GetEncryptionConfig(flags.encryptionProvider)
+	if err != nil {
+		return err
+	}
+	flags.encryptionConfig = config
+
+	if flags.controlPlaneExtraArgs == nil {
+		flags.controlPlaneExtraArgs = map[string][]string{}
+	}
+	flags.controlPlaneExtraArgs[kubeadm.APIServerComponent] = append(values, fmt.Sprintf("%s=%s", kubeadm.EncryptionProviderConfigArg, kubeadm.EncryptionConfigPath))
+	return nil
+}
+
+// validateAuditLogging checks the audit policy and the audit log path, reads the audit policy, and adds them
+// to the API server extra args; the audit policy and log path can't be set also by the API server extra args
+func validateAuditLogging(flags *CreateOptions) error {
+	if flags.auditPolicyFile == "" && flags.auditLogPath == "" {
+		return nil
+	}
+
+	policy := []byte(kubeadm.DefaultAuditPolicy)
+	if flags.auditPolicyFile != "" {
+		var err error
+		if policy, err = os.ReadFile(flags.auditPolicyFile); err != nil {
+			return errors.Wrapf(err, "failed to read the audit policy %s", flags.auditPolicyFile)
+		}
+		if err := kubeadm.ValidateAuditPolicy(policy); err != nil {
+			return errors.Wrapf(err, "invalid audit policy %s", flags.auditPolicyFile)
+		}
+	}
+	if flags.auditLogPath == "" {
+		flags.auditLogPath = kubeadm.DefaultAuditLogPath
+	}
+	if err := kubeadm.ValidateAuditLogPath(flags.auditLogPath); err != nil {
+		return err
+	}
+
+	values := flags.controlPlaneExtraArgs[kubeadm.APIServerComponent]
+	for _, v := range values {
+		if strings.HasPrefix(v, kubeadm.AuditPolicyFileArg+"=") || strings.HasPrefix(v, kubeadm.AuditLogPathArg+"=") {
+			return errors.New("the audit policy and log path can't be set both by the API server extra args and by the audit logging options")
+		}
+	}
+	flags.auditPolicy = policy
+
+	if flags.controlPlaneExtraArgs == nil {
+		flags.controlPlaneExtraArgs = map[string][]string{}
+	}
+	flags.controlPlaneExtraArgs[kubeadm.APIServerComponent] = append(values,
+		fmt.Sprintf("%s=%s", kubeadm.AuditPolicyFileArg, kubeadm.AuditPolicyPath),
+		fmt.Sprintf("%s=%s", kubeadm.AuditLogPathArg, flags.auditLogPath),
+	)
+	return nil
+}
+
+const (
+	// nodeCIDRMaskSizeArg is the kube-controller-manager flag setting the size of the pod CIDR allocated to each
+	// node in single-stack clusters, while nodeCIDRMaskSizeIPv4Arg and nodeCIDRMaskSizeIPv6Arg are the flags used
+	// in dual-stack clusters; the kube-controller-manager fails if flags not matching the cluster IP family are set
+	nodeCIDRMaskSizeArg     = "node-cidr-mask-size"
+	nodeCIDRMaskSizeIPv4Arg = "node-cidr-mask-size-ipv4"
+	nodeCIDRMaskSizeIPv6Arg = "node-cidr-mask-size-ipv6"
+
+	// maxNodeCIDRMaskSizeDiff is the maximum difference between the node CIDR mask size and the cluster CIDR
+	// mask size accepted by the kube-controller-manager
+	maxNodeCIDRMaskSizeDiff = 16
+)
+
+// validateNodeCIDRMaskSize checks that the node CIDR mask sizes match the cluster IP family and allow to allocate
+// a pod CIDR to each K8s node within the pod subnet of the same family, and adds them to the kube-controller-manager
+// extra args; the node CIDR mask sizes can't be set also by the kube-controller-manager extra args.
+func validateNodeCIDRMaskSize(flags *CreateOptions) error {
+	if flags.nodeCIDRMaskSize == 0 && flags.nodeCIDRMaskSizeIPv6 == 0 {
+		return nil
+	}
+
+	// single-stack clusters use the generic flag, dual-stack clusters use a flag for each family
+	ipv4Arg, ipv6Arg := nodeCIDRMaskSizeIPv4Arg, nodeCIDRMaskSizeIPv6Arg
+	switch flags.ipFamily {
+	case status.IPv6Family:
+		if flags.nodeCIDRMaskSize != 0 {
+			return errors.New("the IPv4 node CIDR mask size can't be set for ipv6 clusters. Use the IPv6 node CIDR mask size")
+		}
+		ipv6Arg = nodeCIDRMaskSizeArg
+	case status.DualStackFamily:
+	default:
+		if flags.nodeCIDRMaskSizeIPv6 != 0 {
+			return errors.New("the IPv6 node CIDR mask size can be set only for ipv6 or dualstack clusters")
+		}
+		ipv4Arg = nodeCIDRMaskSizeArg
+	}
+
+	// invalid worker pools are rejected by validateWorkerPools
+	pools, _ := parseWorkerPools(flags.workerPools)
+	nodes := flags.controlPlanes + flags.workers
+	for _, p := range pools {
+		nodes += p.Count
+	}
+
+	var args []string
+	for _, s := range []struct {
+		maskSize  int
+		podSubnet string
+		arg       string
+	}{
+		{maskSize: flags.nodeCIDRMaskSize, podSubnet: constants.KinderPodSubnet, arg: ipv4Arg},
+		{maskSize: flags.nodeCIDRMaskSizeIPv6, podSubnet: constants.KinderPodSubnetIPv6, arg: ipv6Arg},
+	} {
+		if s.maskSize == 0 {
+			continue
+		}
+		_, podSubnet, _ := net.ParseCIDR(s.podSubnet)
+		clusterMaskSize, bits := podSubnet.Mask.Size()
+		if err := nodeCIDRMaskSizeFeasible(s.maskSize, clusterMaskSize, bits, nodes); err != nil {
+			return errors.Wrapf(err, "invalid node CIDR mask size for the %s pod subnet", s.podSubnet)
+		}
+		args = append(args, fmt.Sprintf("%s=%d", s.arg, s.maskSize))
+	}
+
+	values := flags.controlPlaneExtraArgs[kubeadm.ControllerManagerComponent]
+	for _, v := range values {
+		for _, arg := range []string{nodeCIDRMaskSizeArg, nodeCIDRMaskSizeIPv4Arg, nodeCIDRMaskSizeIPv6Arg} {
+			if strings.HasPrefix(v, arg+"=") {
+				return errors.New("the node CIDR mask size can't be set both by the controller-manager extra args and by the node CIDR mask size options")
+			}
+		}
+	}
+
+	if flags.controlPlaneExtraArgs == nil {
+		flags.controlPlaneExtraArgs = map[string][]string{}
+	}
+	flags.controlPlaneExtraArgs[kubeadm.ControllerManagerComponent] = append(values, args...)
+	return nil
+}
+
+// nodeCIDRMaskSizeFeasible checks that a node CIDR mask size is accepted by the kube-controller-manager for a
+// cluster CIDR mask size, and that the cluster CIDR has room for a pod CIDR for each node; pod CIDRs must have
+// at least four addresses, so there are usable addresses for pods besides the network and broadcast addresses
+func nodeCIDRMaskSizeFeasible(maskSize, clusterMaskSize, bits, nodes int) error {
+	if maskSize <= clusterMaskSize || maskSize > bits-2 {
+		return errors.Errorf("the node CIDR mask size %d must be between %d and %d", maskSize, clusterMaskSize+1, bits-2)
+	}
+	if maskSize-clusterMaskSize > maxNodeCIDRMaskSizeDiff {
+		return errors.Errorf("the node CIDR mask size %d can't be more than %d bits larger than the cluster CIDR mask size %d", maskSize, maxNodeCIDRMaskSizeDiff, clusterMaskSize)
+	}
+	if available := 1 << (maskSize - clusterMaskSize); available < nodes {
+		return errors.Errorf("the node CIDR mask size %d allows only %d pod CIDRs, but the cluster has %d nodes", maskSize, available, nodes)
+	}
+	return nil
+}
+
+// installServiceAccountKey writes the service account key pair in the kubeadm certificate dir of a node
+func installServiceAccountKey(n *status.Node, key, pub []byte) error {
+	if err := n.WriteFileWithPerm("/etc/kubernetes/pki/sa.key", key, 0600); err != nil {
+		return errors.Wrapf(err, "failed to install the service account key on node %s", n.Name())
+	}
+	if err := n.WriteFileWithPerm("/etc/kubernetes/pki/sa.pub", pub, 0644); err != nil {
+		return errors.Wrapf(err, "failed to install the service account public key on node %s", n.Name())
+	}
+	return nil
+}
+
+// validateClusterCA reads and checks the existing CA certificate and key, if any; the CA key is required, because
+// without it kubeadm can't sign the cluster certificates and requires all of them to be provided instead
+func validateClusterCA(flags *CreateOptions) error {
+	if flags.caCertFile == "" && flags.caKeyFile == "" {
+		return nil
+	}
+	if flags.caCertFile == "" {
+		return errors.New("the CA key can be set only with the CA certificate")
+	}
+	if flags.caKeyFile == "" {
+		return errors.New("the CA key is required for signing the cluster certificates. Use the setup-external-ca action for testing kubeadm with an external CA without key")
+	}
+
+	certPEM, err := os.ReadFile(flags.caCertFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the CA certificate")
+	}
+	keyPEM, err := os.ReadFile(flags.caKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the CA key")
+	}
+	if err := kubeadm.ValidateClusterCA(certPEM, keyPEM); err != nil {
+		return errors.Wrapf(err, "invalid CA %s", flags.caCertFile)
+	}
+	flags.caCert, flags.caKey = certPEM, keyPEM
+	return nil
+}
+
+// installClusterCA writes the CA certificate and key on a control-plane node, where kubeadm init reuses them;
+// a copy is kept in the kinder PKI backup dir, so the CA is re-installed by kubeadm-init after kubeadm reset
+func installClusterCA(n *status.Node, cert, key []byte) error {
+	if err := installPKIFile(n, kubeadm.ClusterCACertPath, cert, 0644); err != nil {
+		return errors.Wrapf(err, "failed to install the CA certificate on node %s", n.Name())
+	}
+	if err := installPKIFile(n, kubeadm.ClusterCAKeyPath, key, 0600); err != nil {
+		return errors.Wrapf(err, "failed to install the CA key on node %s", n.Name())
+	}
+	return nil
+}
+
+// validateControlPlaneExtraArgs checks the extra args for the control-plane components; values for a repeated key
+// are joined, so the extra args are normalized
+func validateControlPlaneExtraArgs(flags *CreateOptions) error {
+	for component, values := range flags.controlPlaneExtraArgs {
+		args, err := kubeadm.ParseExtraArgs(values)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s extra args", component)
+		}
+		flags.controlPlaneExtraArgs[component] = kubeadm.FormatExtraArgs(args)
+	}
+	return nil
+}
+
+// controlPlaneLabels returns the container labels recording the extra args for the control-plane components
+func controlPlaneLabels(flags *CreateOptions) map[string]string {
+	labels := map[string]string{}
+
+	extraArgs := map[string][]string{}
+	for component, values := range flags.controlPlaneExtraArgs {
+		if len(values) > 0 {
+			extraArgs[component] = values
+		}
+	}
+	if len(extraArgs) > 0 {
+		// invalid extra args are rejected by validateControlPlaneExtraArgs, so they can always be encoded
+		b, _ := json.Marshal(extraArgs)
+		labels[constants.ControlPlaneExtraArgsLabelKey] = string(b)
+	}
+
+	if flags.skipKubeProxy {
+		labels[constants.KubeProxyLabelKey] = constants.KubeProxyDisabled
+	} else if flags.kubeProxyMode != "" {
+		labels[constants.KubeProxyLabelKey] = flags.kubeProxyMode
+	}
+
+	if flags.coreDNSReplicas > 0 {
+		labels[constants.CoreDNSReplicasLabelKey] = strconv.Itoa(flags.coreDNSReplicas)
+	}
+
+	if flags.encryptionProvider != "" {
+		labels[constants.EncryptionProviderLabelKey] = flags.encryptionProvider
+	}
+
+	if flags.auditLogPath != "" {
+		labels[constants.AuditLogPathLabelKey] = flags.auditLogPath
+	}
+
+	if flags.tlsMinVersion != "" {
+		labels[constants.APIServerTLSMinVersionLabelKey] = flags.tlsMinVersion
+	}
+
+	if len(flags.tlsCipherSuites) > 0 {
+		labels[constants.APIServerTLSCipherSuitesLabelKey] = strings.Join(flags.tlsCipherSuites, ",")
+	}
+
+	if flags.nodeCIDRMaskSize > 0 || flags.nodeCIDRMaskSizeIPv6 > 0 {
+		sizes := map[string]int{}
+		if flags.nodeCIDRMaskSize > 0 {
+			sizes["ipv4"] = flags.nodeCIDRMaskSize
+		}
+		if flags.nodeCIDRMaskSizeIPv6 > 0 {
+			sizes["ipv6"] = flags.nodeCIDRMaskSizeIPv6
+		}
+		b, _ := json.Marshal(sizes)
+		labels[constants.NodeCIDRMaskSizeLabelKey] = string(b)
+	}
+
+	if flags.kubeadmClusterName != "" {
+		labels[constants.KubeadmClusterNameLabelKey] = flags.kubeadmClusterName
+	}
+
+	if len(flags.etcdExtraArgs) > 0 {
+		b, _ := json.Marshal(flags.etcdExtraArgs)
+		labels[constants.EtcdExtraArgsLabelKey] = string(b)
+	}
+
+	if len(flags.externalEtcdEndpoints) > 0 {
+		b, _ := json.Marshal(flags.externalEtcdEndpoints)
+		labels[constants.ExternalEtcdEndpointsLabelKey] = string(b)
+	}
+
+	if flags.kubeletServingCertRotation {
+		labels[constants.KubeletServingCertRotationLabelKey] = "true"
+	}
+
+	if plugin := cniInNode(flags); plugin != "" {
+		labels[constants.CNILabelKey] = plugin
+	}
+
+	if manifests := bootstrapManifestsInNode(flags); len(manifests) > 0 {
+		b, _ := json.Marshal(manifests)
+		labels[constants.BootstrapManifestsLabelKey] = string(b)
+	}
+
+	if flags.ignorePreflightErrors != "" {
+		labels[constants.IgnorePreflightErrorsLabelKey] = flags.ignorePreflightErrors
+	}
+
+	if len(flags.waitDaemonSets) > 0 {
+		b, _ := json.Marshal(flags.waitDaemonSets)
+		labels[constants.WaitDaemonSetsLabelKey] = string(b)
+	}
+	return labels
+}
+
+// validateIPFamily checks the IP family of the cluster, that defaults to ipv4; IPv6 and dual-stack clusters
+// require a user defined docker network, because the default bridge network has no IPv6 addresses
+func validateIPFamily(flags *CreateOptions) error {
+	switch flags.ipFamily {
+	case "":
+		flags.ipFamily = status.IPv4Family
+	case status.IPv4Family:
+	case status.IPv6Family, status.DualStackFamily:
+		if flags.network == "" {
+			return errors.Errorf("%s clusters require a user defined docker network with IPv6 enabled, use --network", flags.ipFamily)
+		}
+	default:
+		return errors.Errorf("invalid IP family %q. Use one of [%s, %s, %s]", flags.ipFamily, status.IPv4Family, status.IPv6Family, status.DualStackFamily)
+	}
+	return nil
+}
+
+// validateIPFamilyVersion checks that the Kubernetes version in the node image supports dual-stack clusters;
+// if the version is unknown, the check is executed when generating the kubeadm config
+func validateIPFamilyVersion(flags *CreateOptions) error {
+	if flags.ipFamily != status.DualStackFamily || flags.kubernetesVersion == "" {
+		return nil
+	}
+	v, err := K8sVersion.ParseSemantic(flags.kubernetesVersion)
+	if err != nil {
+		return errors.Wrapf(err, "invalid Kubernetes version %q in the node image", flags.kubernetesVersion)
+	}
+	if v.LessThan(kubeadm.DualStackMinVersion) {
+		return errors.Errorf("dual-stack clusters require Kubernetes %s or later, the node image has %s", kubeadm.DualStackMinVersion, flags.kubernetesVersion)
+	}
+	return nil
+}
+
+// validateIgnorePreflightErrors checks the kubeadm preflight errors to be ignored by kubeadm init against the
+// checks known for the kubeadm version in the node image; if the version is unknown, only the names are checked
+func validateIgnorePreflightErrors(flags *CreateOptions) error {
+	if flags.ignorePreflightErrors == "" {
+		return nil
+	}
+	var kubeadmVersion *K8sVersion.Version
+	if flags.kubernetesVersion != "" {
+		v, err := K8sVersion.ParseSemantic(flags.kubernetesVersion)
+		if err != nil {
+			return errors.Wrapf(err, "invalid Kubernetes version %q in the node image", flags.kubernetesVersion)
+		}
+		kubeadmVersion = v
+	}
+	return kubeadm.ValidateIgnorePreflightErrors(flags.ignorePreflightErrors, kubeadmVersion)
+}
+
+// validateWaitDaemonSets checks the DaemonSets to be waited for are valid [namespace/]name references, and
+// normalizes them in the namespace/name form
+func validateWaitDaemonSets(flags *CreateOptions) error {
+	daemonSets := make([]string, 0, len(flags.waitDaemonSets))
+	for _, ds := range flags.waitDaemonSets {
+		namespace, name := "kube-system", ds
+		if parts := strings.Split(ds, "/"); len(parts) == 2 {
+			namespace, name = parts[0], parts[1]
+		} else if len(parts) > 2 {
+			return errors.Errorf("invalid DaemonSet %q. Use the [namespace/]name form, e.g. kube-system/calico-node", ds)
+		}
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return errors.Errorf("invalid DaemonSet %q namespace: %s", ds, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return errors.Errorf("invalid DaemonSet %q name: %s", ds, strings.Join(errs, "; "))
+		}
+		daemonSets = append(daemonSets, namespace+"/"+name)
+	}
+	flags.waitDaemonSets = daemonSets
+	return nil
+}
+
+// bootstrapManifestsDir defines the directory on control-plane nodes where bootstrap manifest files are copied
+const bootstrapManifestsDir = "/kinder/bootstrap-manifests"
+
+// bootstrapManifest defines a bootstrap manifest file, validated and read at create time
+type bootstrapManifest struct {
+	source   string
+	nodePath string
+	data     []byte
+}
+
+// validateBootstrapManifests checks the bootstrap manifests: URLs must be valid http(s) URLs, while files must
+// exist and contain Kubernetes objects, so mistakes are detected before creating the nodes; files are read, so
+// the content applied after init is the one validated
+func validateBootstrapManifests(flags *CreateOptions) error {
+	flags.bootstrapManifestFiles = nil
+	for i, m := range flags.bootstrapManifests {
+		if isManifestURL(m) {
+			u, err := url.Parse(m)
+			if err != nil || u.Host == "" {
+				return errors.Errorf("invalid bootstrap manifest URL %q", m)
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(m)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the bootstrap manifest")
+		}
+		if err := validateManifest(data); err != nil {
+			return errors.Wrapf(err, "invalid bootstrap manifest %s", m)
+		}
+		flags.bootstrapManifestFiles = append(flags.bootstrapManifestFiles, bootstrapManifest{
+			source: m,
+			// files are prefixed with their position, so names are unique and sorted like the manifests
+			nodePath: path.Join(bootstrapManifestsDir, fmt.Sprintf("%02d-%s", i+1, filepath.Base(m))),
+			data:     data,
+		})
+	}
+	return nil
+}
+
+// bootstrapManifestsInNode returns the bootstrap manifests as seen from the control-plane nodes, in order:
+// files are replaced by the paths they are copied to, while URLs are unchanged
+func bootstrapManifestsInNode(flags *CreateOptions) []string {
+	var manifests []string
+	files := flags.bootstrapManifestFiles
+	for _, m := range flags.bootstrapManifests {
+		if isManifestURL(m) {
+			manifests = append(manifests, m)
+			continue
+		}
+		manifests = append(manifests, files[0].nodePath)
+		files = files[1:]
+	}
+	return manifests
+}
+
+// validateKubeadmClusterName checks the cluster name in the kubeadm config is a valid DNS label, as required
+// by kubeadm for the cluster name
+func validateKubeadmClusterName(flags *CreateOptions) error {
+	if flags.kubeadmClusterName == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(flags.kubeadmClusterName); len(errs) > 0 {
+		return errors.Errorf("invalid kubeadm cluster name %q: %s", flags.kubeadmClusterName, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// cniManifestsDir defines the directory on control-plane nodes where the custom CNI manifest file is copied
+const cniManifestsDir = "/kinder/cni"
+
+// validateCNI checks the CNI plugin is a known plugin or a custom manifest; like bootstrap manifests, custom
+// manifest URLs must be valid http(s) URLs, while files must exist and contain Kubernetes objects, and they
+// are read, so the content applied after init is the one validated
+func validateCNI(flags *CreateOptions) error {
+	flags.cniManifestFile = nil
+	if flags.cni == "" || cni.IsKnown(flags.cni) {
+		return nil
+	}
+
+	if isManifestURL(flags.cni) {
+		u, err := url.Parse(flags.cni)
+		if err != nil || u.Host == "" {
+			return errors.Errorf("invalid CNI manifest URL %q", flags.cni)
+		}
+	} else {
+		data, err := os.ReadFile(flags.cni)
+		if os.IsNotExist(err) {
+			return errors.Errorf("invalid CNI %q. Use one of [%s], or the path or the URL of a custom manifest", flags.cni, strings.Join(cni.Known(), ", "))
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read the CNI manifest")
+		}
+		if err := validateManifest(data); err != nil {
+			return errors.Wrapf(err, "invalid CNI manifest %s", flags.cni)
+		}
+		flags.cniManifestFile = &bootstrapManifest{
+			source:   flags.cni,
+			nodePath: path.Join(cniManifestsDir, filepath.Base(flags.cni)),
+			data:     data,
+		}
+	}
+
+	_, err := cni.Get(cniInNode(flags))
+	return err
+}
+
+// cniInNode returns the CNI plugin as seen from the control-plane nodes: custom manifest files are replaced
+// by the path they are copied to, while known plugins and URLs are unchanged
+func cniInNode(flags *CreateOptions) string {
+	if flags.cniManifestFile != nil {
+		return flags.cniManifestFile.nodePath
+	}
+	return flags.cni
+}
+
+func isManifestURL(manifest string) bool {
+	return strings.HasPrefix(manifest, "http://") || strings.HasPrefix(manifest, "https://")
+}
+
+// validateManifest checks that a manifest contains at least one Kubernetes object, and that each YAML
+// or JSON document is either empty or a Kubernetes object with apiVersion and kind
+func validateManifest(data []byte) error {
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	objects := 0
+	for i := 1; ; i++ {
+		var obj map[string]interface{}
+		if err := decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return errors.Wrapf(err, "failed to parse document %d", i)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		if obj["apiVersion"] == nil || obj["apiVersion"] == "" || obj["kind"] == nil || obj["kind"] == "" {
+			return errors.Errorf("document %d is not a Kubernetes object, apiVersion and kind are required", i)
+		}
+		objects++
+	}
+	if objects == 0 {
+		return errors.New("the manifest does not contain any Kubernetes object")
+	}
+	return nil
+}
+
+// kubeProxyModes defines the supported kube-proxy modes
+var kubeProxyModes = []string{"iptables", "ipvs", "nftables"}
+
+// validateKubeProxy checks the kube-proxy mode; the mode can't be set when the kube-proxy addon is skipped
+func validateKubeProxy(flags *CreateOptions) error {
+	if flags.kubeProxyMode == "" {
+		return nil
+	}
+	if flags.skipKubeProxy {
+		return errors.New("the kube-proxy mode can't be set when skipping the kube-proxy addon; set only one of them")
+	}
+	for _, m := range kubeProxyModes {
+		if flags.kubeProxyMode == m {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid kube-proxy mode %q. Use one of [%s]", flags.kubeProxyMode, strings.Join(kubeProxyModes, ", "))
+}
+
+// validateKubeletEviction checks the kubelet eviction thresholds, and computes the kubelet eviction settings
+func validateKubeletEviction(flags *CreateOptions) error {
+	hard, err := kubeadm.ParseEvictionThresholds(flags.evictionHard)
+	if err != nil {
+		return err
+	}
+	soft, err := kubeadm.ParseEvictionThresholds(flags.evictionSoft)
+	if err != nil {
+		return err
+	}
+	if len(soft) > 0 && flags.evictionSoftGrace <= 0 {
+		return errors.New("soft eviction thresholds require a grace period")
+	}
+	if len(soft) == 0 && flags.evictionSoftGrace != 0 {
+		return errors.New("the soft eviction grace period can be set only with soft eviction thresholds")
+	}
+
+	// relaxed thresholds are used for the signals without an explicit hard threshold
+	if flags.relaxEviction {
+		for signal, quantity := range kubeadm.RelaxedEvictionHard() {
+			if _, ok := hard[signal]; !ok {
+				hard[signal] = quantity
+			}
+		}
+	}
+
+	eviction := map[string]map[string]string{}
+	if len(hard) > 0 {
+		eviction[kubeadm.EvictionHardField] = hard
+	}
+	if len(soft) > 0 {
+		eviction[kubeadm.EvictionSoftField] = soft
+		gracePeriods := map[string]string{}
+		for signal := range soft {
+			gracePeriods[signal] = flags.evictionSoftGrace.String()
+		}
+		eviction[kubeadm.EvictionSoftGracePeriodField] = gracePeriods
+	}
+	if len(eviction) > 0 {
+		flags.kubeletEviction = eviction
+	}
+	return nil
+}
+
+// setKubeletEviction records the kubelet eviction settings for a node in a label, so they can be
+// set in the KubeletConfiguration at init time
+func setKubeletEviction(n *nodeSpec, flags *CreateOptions) {
+	if len(flags.kubeletEviction) == 0 {
+		return
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	// kubelet eviction settings are validated by validateKubeletEviction, so they can always be encoded
+	b, _ := json.Marshal(flags.kubeletEviction)
+	n.Labels[constants.KubeletEvictionLabelKey] = string(b)
+}
+
+// validateResources checks the CPU and memory limits for the K8s nodes
+func validateResources(flags *CreateOptions) error {
+	if err := flags.controlPlaneResources.Validate(); err != nil {
+		return errors.Wrap(err, "invalid control-plane resources")
+	}
+	if err := flags.workerResources.Validate(); err != nil {
+		return errors.Wrap(err, "invalid worker resources")
+	}
+	return nil
+}
+
+// resourcesForRole returns the CPU and memory limits to apply to nodes with the given role
+func resourcesForRole(flags *CreateOptions, role string) status.NodeResources {
+	if role == constants.ControlPlaneNodeRoleValue {
+		return flags.controlPlaneResources
+	}
+	return flags.workerResources
+}
+
+// sysctlKeyRegexp matches sysctl keys, with dots or slashes as separators, e.g. net.core.somaxconn; this is
+// the same format validated by the kubelet for pod sysctls
+var sysctlKeyRegexp = regexp.MustCompile(`^([a-z0-9]([-_a-z0-9]*[a-z0-9])?[./])*[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`)
+
+// validateNodeSysctls checks the node sysctls, in the key=value form, and parses them
+func validateNodeSysctls(flags *CreateOptions) error {
+	if len(flags.nodeSysctls) == 0 {
+		return nil
+	}
+	sysctls := map[string]string{}
+	for _, v := range flags.nodeSysctls {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 {
+			return errors.Errorf("invalid node sysctl %q. Use the key=value form, e.g. net.core.somaxconn=1024", v)
+		}
+		key, value := strings.TrimSpace(split[0]), strings.TrimSpace(split[1])
+		if !sysctlKeyRegexp.MatchString(key) {
+			return errors.Errorf("invalid node sysctl %q. The key must be a sysctl name, e.g. net.core.somaxconn", v)
+		}
+		if value == "" || strings.ContainsAny(value, "\n\r") {
+			return errors.Errorf("invalid node sysctl %q. The value must be a non empty single line", v)
+		}
+		if _, ok := sysctls[key]; ok {
+			return errors.Errorf("the node sysctl %s is set more than once", key)
+		}
+		sysctls[key] = value
+	}
+	flags.sysctls = sysctls
+	return nil
+}
+
+// setNodeSysctls records the sysctls for a node in a label, so it is possible to know the node kernel parameters
+// were modified at create time
+func setNodeSysctls(n *nodeSpec, flags *CreateOptions) {
+	if len(flags.sysctls) == 0 {
+		return
+	}
+	if n.Labels == nil {
+		n.Labels = map[string]string{}
+	}
+	// node sysctls are validated by validateNodeSysctls, so they can always be encoded
+	b, _ := json.Marshal(flags.sysctls)
+	n.Labels[constants.NodeSysctlsLabelKey] = string(b)
+}
+
+const (
+	// nodeSysctlsFile is the file with the sysctls applied at create time, that is applied again by systemd at boot
+	nodeSysctlsFile = "/etc/sysctl.d/99-kinder.conf"
+
+	// nodeSysctlsKubeletDropIn is the kubelet systemd unit drop-in applying the sysctls before the kubelet starts,
+	// so the sysctls are set before the kubelet also when the node container is restarted
+	nodeSysctlsKubeletDropIn = "/etc/systemd/system/kubelet.service.d/05-kinder-sysctls.conf"
+)
+
+// applyNodeSysctls writes the sysctls in the node sysctl.d dir and in a kubelet drop-in, applies them, and checks the
+// kernel parameters have the expected values; parameters not namespaced, e.g. fs.inotify.max_user_watches, are set
+// also for the host, because node containers are privileged
+func applyNodeSysctls(n *status.Node, sysctls map[string]string) error {
+	if err := n.WriteFileWithPerm(nodeSysctlsFile, []byte(nodeSysctlsConf(sysctls)), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s on node %s", nodeSysctlsFile, n.Name())
+	}
+	dropIn := fmt.Sprintf("[Service]\nExecStartPre=-/lib/systemd/systemd-sysctl %s\n", nodeSysctlsFile)
+	if err := n.WriteFileWithPerm(nodeSysctlsKubeletDropIn, []byte(dropIn), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s on node %s", nodeSysctlsKubeletDropIn, n.Name())
+	}
+	if err := n.Command("sh", "-c", fmt.Sprintf("/lib/systemd/systemd-sysctl %s && systemctl daemon-reload", nodeSysctlsFile)).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to apply the sysctls on node %s", n.Name())
+	}
+
+	keys := sortedSysctlKeys(sysctls)
+	for _, k := range keys {
+		lines, err := n.Command("cat", sysctlPath(k)).Silent().RunAndCapture()
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the sysctl %s on node %s. The sysctl might not exist in the node kernel", k, n.Name())
+		}
+		// multi value parameters, e.g. net.ipv4.ip_local_port_range, are separated by tabs
+		if actual := strings.Join(strings.Fields(strings.Join(lines, " ")), " "); actual != strings.Join(strings.Fields(sysctls[k]), " ") {
+			return errors.Errorf("failed to set the sysctl %s on node %s: expected %q, found %q", k, n.Name(), sysctls[k], actual)
+		}
+	}
+	n.Infof("applied sysctls %s", strings.Join(keys, ", "))
+	return nil
+}
+
+// nodeSysctlsConf returns the sysctl.d config for the given sysctls, sorted by key
+func nodeSysctlsConf(sysctls map[string]string) string {
+	var b strings.Builder
+	b.WriteString("# sysctls set by kinder at create time\n")
+	for _, k := range sortedSysctlKeys(sysctls) {
+		fmt.Fprintf(&b, "%s = %s\n", k, sysctls[k])
+	}
+	return b.String()
+}
+
+// sortedSysctlKeys returns the keys of the given sysctls, sorted
+func sortedSysctlKeys(sysctls map[string]string) []string {
+	keys := make([]string, 0, len(sysctls))
+	for k := range sysctls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sysctlPath returns the path in /proc/sys for a sysctl key; as in sysctl.d, if the first separator is a dot,
+// dots and slashes are swapped, because in keys using dots as separators slashes are part of the names,
+// e.g. for VLAN interfaces like eth0.100
+func sysctlPath(key string) string {
+	if i := strings.IndexAny(key, "./"); i >= 0 && key[i] == '.' {
+		key = strings.Map(func(r rune) rune {
+			switch r {
+			case '.':
+				return '/'
+			case '/':
+				return '.'
+			}
+			return r
+		}, key)
+	}
+	return "/proc/sys/" + key
+}
+
+// kubeletDefaultsFile is the file sourced by the kubelet systemd unit for the KUBELET_EXTRA_ARGS variable
+const kubeletDefaultsFile = "/etc/default/kubelet"
+
+// reserveKubeletResources sets the kubelet --system-reserved flag for a node with resource limits; the kubelet
+// reports the resources of the host as node capacity, so the resources exceeding the limits are reserved
+// for the system, and the node allocatable resources reflect the limits
+func reserveKubeletResources(n *status.Node, resources status.NodeResources) error {
+	hostCPUs, hostMemory, err := nodeHostResources(n)
+	if err != nil {
+		return err
+	}
+	reserved := resources.SystemReserved(hostCPUs, hostMemory)
+	if reserved == "" {
+		return nil
+	}
+
+	lines, err := n.Command("sh", "-c", fmt.Sprintf("cat %s 2>/dev/null || true", kubeletDefaultsFile)).Silent().RunAndCapture()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s on node %s", kubeletDefaultsFile, n.Name())
+	}
+	defaults := kubeletDefaultsWithExtraArg(lines, fmt.Sprintf("--system-reserved=%s", reserved))
+	if err := n.WriteFileWithPerm(kubeletDefaultsFile, []byte(defaults), 0644); err != nil {
+		return errors.Wrapf(err, "failed to reserve the kubelet resources on node %s", n.Name())
+	}
+	n.Infof("reserved %s for the system, so the kubelet allocatable resources reflect %s", reserved, resources)
+	return nil
+}
+
+// nodeHostResources returns the CPUs, in millicores, and the memory, in bytes, visible in a node,
+// that are the resources of the host regardless of the node container limits
+func nodeHostResources(n *status.Node) (int64, int64, error) {
+	lines, err := n.Command("sh", "-c", "nproc && grep MemTotal /proc/meminfo").Silent().RunAndCapture()
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed to get the host resources on node %s", n.Name())
+	}
+	if len(lines) != 2 {
+		return 0, 0, errors.Errorf("failed to get the host resources on node %s: unexpected output %q", n.Name(), lines)
+	}
+	cpus, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed to parse the number of CPUs on node %s", n.Name())
+	}
+	// MemTotal is reported in kB, e.g. "MemTotal:       16314976 kB"
+	fields := strings.Fields(lines[1])
+	if len(fields) < 2 {
+		return 0, 0, errors.Errorf("failed to parse the total memory on node %s: %q", n.Name(), lines[1])
+	}
+	memory, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed to parse the total memory on node %s", n.Name())
+	}
+	return cpus * 1000, memory * 1024, nil
+}
+
+// kubeletDefaultsWithExtraArg returns the content of the kubelet defaults file with an additional arg in the
+// KUBELET_EXTRA_ARGS variable, preserving other variables and the args already set, e.g. by the node image
+func kubeletDefaultsWithExtraArg(lines []string, arg string) string {
+	const prefix = "KUBELET_EXTRA_ARGS="
+
+	var out []string
+	found := false
+	for _, l := range lines {
+		if !strings.HasPrefix(l, prefix) {
+			out = append(out, l)
+			continue
+		}
+		found = true
+		value := strings.TrimPrefix(l, prefix)
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+		if value != "" {
+			value += " "
+		}
+		out = append(out, fmt.Sprintf(`%s"%s%s"`, prefix, value, arg))
+	}
+	if !found {
+		out = append(out, fmt.Sprintf(`%s"%s"`, prefix, arg))
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// validateWorkerLabelsAndTaints checks the Kubernetes labels and taints for worker nodes
+func validateWorkerLabelsAndTaints(flags *CreateOptions) error {
+	if (len(flags.workerLabels) > 0 || len(flags.workerTaints) > 0) && flags.workers == 0 {
+		return errors.New("worker labels and taints can be set only when creating worker nodes")
+	}
+	for _, l := range flags.workerLabels {
+		if _, _, err := kubeadm.ParseNodeLabel(l); err != nil {
+			return err
+		}
+	}
+	for _, t := range flags.workerTaints {
+		if _, err := kubeadm.ParseNodeTaint(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExternalEtcd checks the external etcd options; the data dir is converted to an absolute path,
+// as required for docker volumes
+func validateExternalEtcd(flags *CreateOptions) error {
+	if !flags.externalEtcd {
+		if flags.externalEtcdImage != "" || flags.externalEtcdDataDir != "" || flags.externalEtcdNodes != 0 {
+			return errors.New("the external etcd image, data dir and nodes can be set only when creating an external etcd")
+		}
+		return nil
+	}
+
+	if strings.ContainsAny(flags.externalEtcdImage, " \t\n") {
+		return errors.Errorf("invalid external etcd image %q", flags.externalEtcdImage)
+	}
+
+	// an etcd cluster with an even number of members has the same failure tolerance of the cluster with one
+	// member less, so only odd numbers are accepted
+	if flags.externalEtcdNodes < 0 || (flags.externalEtcdNodes != 0 && flags.externalEtcdNodes%2 == 0) {
+		return errors.Errorf("invalid number of external etcd nodes %d. Use an odd number, e.g. 3 for tolerating the failure of one member", flags.externalEtcdNodes)
+	}
+	if externalEtcdMembers(flags) > 1 && flags.externalEtcdDataDir != "" {
+		return errors.New("the external etcd data dir can be set only for an external etcd with one node")
+	}
+
+	if flags.externalEtcdDataDir == "" {
+		return nil
+	}
+
+	dataDir, err := filepath.Abs(flags.externalEtcdDataDir)
+	if err != nil {
+		return errors.Wrapf(err, "invalid external etcd data dir %q", flags.externalEtcdDataDir)
+	}
+	info, err := os.Stat(dataDir)
+	if err != nil {
+		return errors.Wrapf(err, "invalid external etcd data dir %q", flags.externalEtcdDataDir)
+	}
+	if !info.IsDir() {
+		return errors.Errorf("invalid external etcd data dir %q: not a directory", flags.externalEtcdDataDir)
+	}
+	flags.externalEtcdDataDir = dataDir
+
+	return nil
+}
+
+// validateExternalEtcdEndpoints checks the client endpoints of an external etcd not managed by kinder, and reads
+// the etcd client certificates, that are required when the endpoints use TLS; the endpoints can't be used together
+// with an external etcd created by kinder, nor with settings for a local etcd
+func validateExternalEtcdEndpoints(flags *CreateOptions) error {
+	tlsFiles := []string{flags.externalEtcdCAFile, flags.externalEtcdCertFile, flags.externalEtcdKeyFile}
+	if len(flags.externalEtcdEndpoints) == 0 {
+		for _, f := range tlsFiles {
+			if f != "" {
+				return errors.New("the external etcd client certificates can be set only with the external etcd endpoints")
+			}
+		}
+		return nil
+	}
+
+	if flags.externalEtcd {
+		return errors.New("the external etcd endpoints can't be used when creating an external etcd")
+	}
+	if flags.etcdVersion != "" || flags.etcdQuotaBackendBytes != "" || flags.etcdCompactionMode != "" || flags.etcdCompactionRetain != "" {
+		return errors.New("the etcd version, quota and compaction can't be set with the external etcd endpoints, because the external etcd is not managed by kinder")
+	}
+
+	useTLS := false
+	for i, e := range flags.externalEtcdEndpoints {
+		endpointTLS, err := kubeadm.ValidateExternalEtcdEndpoint(e)
+		if err != nil {
+			return err
+		}
+		if i > 0 && endpointTLS != useTLS {
+			return errors.New("the external etcd endpoints must all use https, or all use http")
+		}
+		useTLS = endpointTLS
+	}
+
+	set := 0
+	for _, f := range tlsFiles {
+		if f != "" {
+			set++
+		}
+	}
+	if !useTLS {
+		if set > 0 {
+			return errors.New("the external etcd client certificates can be set only with https external etcd endpoints")
+		}
+		return nil
+	}
+	if set != len(tlsFiles) {
+		return errors.New("the external etcd CA, client certificate and client key are all required with https external etcd endpoints")
+	}
+
+	certs, err := readExternalEtcdCerts(flags.externalEtcdCAFile, flags.externalEtcdCertFile, flags.externalEtcdKeyFile)
+	if err != nil {
+		return err
+	}
+	flags.externalEtcdCerts = certs
+	return nil
+}
+
+// readExternalEtcdCerts reads the etcd CA and the API server etcd client certificate and key from the host, checks
+// they are valid, and returns their contents indexed by the path on the control-plane nodes
+func readExternalEtcdCerts(caFile, certFile, keyFile string) (map[string][]byte, error) {
+	files := map[string]string{
+		kubeadm.ExternalEtcdCAFile:   caFile,
+		kubeadm.ExternalEtcdCertFile: certFile,
+		kubeadm.ExternalEtcdKeyFile:  keyFile,
+	}
+	certs := map[string][]byte{}
+	for nodePath, hostPath := range files {
+		b, err := os.ReadFile(hostPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the external etcd client certificates")
+		}
+		certs[nodePath] = b
+	}
+
+	if ok := x509.NewCertPool().AppendCertsFromPEM(certs[kubeadm.ExternalEtcdCAFile]); !ok {
+		return nil, errors.Errorf("invalid external etcd CA %s: no PEM certificates found", caFile)
+	}
+	if _, err := tls.X509KeyPair(certs[kubeadm.ExternalEtcdCertFile], certs[kubeadm.ExternalEtcdKeyFile]); err != nil {
+		return nil, errors.Wrapf(err, "invalid external etcd client certificate %s and key %s", certFile, keyFile)
+	}
+	return certs, nil
+}
+
+// installExternalEtcdCerts writes the etcd client certificates on a control-plane node, in the paths referenced
+// by the external etcd kubeadm config patch
+func installExternalEtcdCerts(n *status.Node, certs map[string][]byte) error {
+	for _, nodePath := range []string{kubeadm.ExternalEtcdCAFile, kubeadm.ExternalEtcdCertFile, kubeadm.ExternalEtcdKeyFile} {
+		var perm os.FileMode = 0644
+		if nodePath == kubeadm.ExternalEtcdKeyFile {
+			perm = 0600
+		}
+		if err := installPKIFile(n, nodePath, certs[nodePath], perm); err != nil {
+			return errors.Wrapf(err, "failed to install the external etcd client certificates on node %s", n.Name())
+		}
+	}
+	return nil
+}
+
+// installPKIFile writes a file in /etc/kubernetes/pki on a control-plane node, and a copy of it in the kinder PKI
+// backup dir, so the file can be re-installed before kubeadm init or join after kubeadm reset wiped it
+func installPKIFile(n *status.Node, nodePath string, data []byte, perm os.FileMode) error {
+	if err := n.WriteFileWithPerm(nodePath, data, perm); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(constants.PKIDir, nodePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return errors.Errorf("%s is not in %s", nodePath, constants.PKIDir)
+	}
+	return n.WriteFileWithPerm(filepath.Join(constants.PKIBackupDir, rel), data, perm)
+}
+
+// externalEtcdMembers returns the number of members of the external etcd cluster, that defaults to one
+func externalEtcdMembers(flags *CreateOptions) int {
+	if flags.externalEtcdNodes == 0 {
+		return 1
+	}
+	return flags.externalEtcdNodes
+}
+
+// externalEtcdImage returns the image to use for the external etcd; if the requested image is only a tag,
+// it is applied to the default etcd image
+func externalEtcdImage(defaultImage, image string) string {
+	if image == "" {
+		return defaultImage
+	}
+	if strings.ContainsAny(image, ":/@") {
+		return image
+	}
+
+	repository := defaultImage
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	return fmt.Sprintf("%s:%s", repository, image)
+}
+
+// printTopology prints a summary of the cluster topology, so it is possible to check that
+// the nodes to create match what is intended before any container is created
+func printTopology(desiredNodes []nodeSpec, flags *CreateOptions) {
+	controlPlanes, workers, loadBalancer := 0, 0, false
+	for _, n := range desiredNodes {
+		switch n.Role {
+		case constants.ControlPlaneNodeRoleValue:
+			controlPlanes++
+		case constants.WorkerNodeRoleValue:
+			workers++
+		case constants.ExternalLoadBalancerNodeRoleValue:
+			loadBalancer = true
+		}
+	}
+
+	fmt.Fprintf(flags.progress, " - control-plane nodes: %d\n", controlPlanes)
+	fmt.Fprintf(flags.progress, " - worker nodes: %d\n", workers)
+	fmt.Fprintf(flags.progress, " - external load balancer: %t\n", loadBalancer)
+	fmt.Fprintf(flags.progress, " - external etcd: %t\n", flags.externalEtcd)
+	if flags.externalEtcd && externalEtcdMembers(flags) > 1 {
+		fmt.Fprintf(flags.progress, " - external etcd nodes: %d\n", externalEtcdMembers(flags))
+	}
+	fmt.Fprintf(flags.progress, " - external registry: %t\n", flags.externalRegistry)
+	if flags.externalEtcd && flags.externalEtcdImage != "" {
+		fmt.Fprintf(flags.progress, " - external etcd image: %s\n", flags.externalEtcdImage)
+	}
+	if flags.externalEtcd && flags.externalEtcdDataDir != "" {
+		fmt.Fprintf(flags.progress, " - external etcd data dir: %s\n", flags.externalEtcdDataDir)
+	}
+	fmt.Fprintf(flags.progress, " - node image: %s\n", flags.image)
 }
 
 // ensureNodeImage ensures that the node image used by the create is present
-func ensureNodeImage(image string) {
-	fmt.Printf("Ensuring node image (%s) 🖼\n", image)
+func ensureNodeImage(progress io.Writer, image string) {
+	fmt.Fprintf(progress, "Ensuring node image (%s) 🖼\n", image)
 
 	// attempt to explicitly pull the image if it doesn't exist locally
 	// we don't care if this errors, we'll still try to run which also pulls
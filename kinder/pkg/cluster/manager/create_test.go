@@ -0,0 +1,1648 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+func TestExternalEtcdImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		expected string
+	}{
+		{
+			name:     "default image",
+			expected: "registry.k8s.io/etcd:3.5.10-0",
+		},
+		{
+			name:     "tag only",
+			image:    "3.5.12-0",
+			expected: "registry.k8s.io/etcd:3.5.12-0",
+		},
+		{
+			name:     "full image reference",
+			image:    "quay.io/coreos/etcd:v3.5.12",
+			expected: "quay.io/coreos/etcd:v3.5.12",
+		},
+		{
+			name:     "image without tag",
+			image:    "example.com/etcd",
+			expected: "example.com/etcd",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			image := externalEtcdImage("registry.k8s.io/etcd:3.5.10-0", test.image)
+			if image != test.expected {
+				t.Fatalf("expected image: %q, found %q", test.expected, image)
+			}
+		})
+	}
+}
+
+func TestValidateNodeNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeNames     []string
+		expectedError bool
+	}{
+		{
+			name: "no node names",
+		},
+		{
+			name:      "valid node names",
+			nodeNames: []string{"kind-control-plane-1=cp-a", "kind-worker-1=gpu-node-1"},
+		},
+		{
+			name:          "invalid format",
+			nodeNames:     []string{"gpu-node-1"},
+			expectedError: true,
+		},
+		{
+			name:          "duplicated node",
+			nodeNames:     []string{"kind-worker-1=a", "kind-worker-1=b"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown node",
+			nodeNames:     []string{"kind-worker-2=a"},
+			expectedError: true,
+		},
+		{
+			name:          "not a K8s node",
+			nodeNames:     []string{"kind-lb=a"},
+			expectedError: true,
+		},
+		{
+			name:          "not a DNS-1123 label",
+			nodeNames:     []string{"kind-worker-1=GPU.node"},
+			expectedError: true,
+		},
+		{
+			name:          "same name for two nodes",
+			nodeNames:     []string{"kind-control-plane-1=node-a", "kind-worker-1=node-a"},
+			expectedError: true,
+		},
+		{
+			name:          "name of another node container",
+			nodeNames:     []string{"kind-worker-1=kind-control-plane-2"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{controlPlanes: 2, workers: 1, nodeNames: test.nodeNames}
+			err := validateNodeNames("kind", flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestValidateNodeImages(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeImages    []string
+		expectedError bool
+	}{
+		{
+			name: "no node images",
+		},
+		{
+			name:       "valid node images",
+			nodeImages: []string{"kind-control-plane-1=kindest/node:v1.30.0", "kind-worker-1=kindest/node:v1.29.0"},
+		},
+		{
+			name:          "invalid format",
+			nodeImages:    []string{"kindest/node:v1.29.0"},
+			expectedError: true,
+		},
+		{
+			name:          "duplicated node",
+			nodeImages:    []string{"kind-worker-1=kindest/node:v1.29.0", "kind-worker-1=kindest/node:v1.30.0"},
+			expectedError: true,
+		},
+		{
+			name:          "not a K8s node",
+			nodeImages:    []string{"kind-lb=kindest/node:v1.29.0"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid image",
+			nodeImages:    []string{"kind-worker-1=Kindest/Node"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{controlPlanes: 2, workers: 1, nodeImages: test.nodeImages}
+			err := validateNodeImages("kind", flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestValidateNodeIPs(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodeIPs       []string
+		network       string
+		ipFamily      status.ClusterIPFamily
+		expected      map[string]status.NodeIPs
+		expectedError bool
+	}{
+		{
+			name: "no node IPs",
+		},
+		{
+			name:     "IPv4 addresses",
+			nodeIPs:  []string{"kind-control-plane-1=172.30.0.11", "kind-worker-1=172.30.0.21"},
+			network:  "kinder",
+			expected: map[string]status.NodeIPs{"kind-control-plane-1": {IPv4: "172.30.0.11"}, "kind-worker-1": {IPv4: "172.30.0.21"}},
+		},
+		{
+			name:     "dual-stack addresses",
+			nodeIPs:  []string{"kind-worker-1=172.30.0.21", "kind-worker-1=fd00:10::21"},
+			network:  "kinder",
+			ipFamily: status.DualStackFamily,
+			expected: map[string]status.NodeIPs{"kind-worker-1": {IPv4: "172.30.0.21", IPv6: "fd00:10::21"}},
+		},
+		{
+			name:          "default network",
+			nodeIPs:       []string{"kind-worker-1=172.30.0.21"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid IP",
+			nodeIPs:       []string{"kind-worker-1=172.30.0"},
+			network:       "kinder",
+			expectedError: true,
+		},
+		{
+			name:          "IPv6 address with the ipv4 family",
+			nodeIPs:       []string{"kind-worker-1=fd00:10::21"},
+			network:       "kinder",
+			expectedError: true,
+		},
+		{
+			name:          "IPv4 address with the ipv6 family",
+			nodeIPs:       []string{"kind-worker-1=172.30.0.21"},
+			network:       "kinder",
+			ipFamily:      status.IPv6Family,
+			expectedError: true,
+		},
+		{
+			name:          "multiple IPv4 addresses",
+			nodeIPs:       []string{"kind-worker-1=172.30.0.21", "kind-worker-1=172.30.0.22"},
+			network:       "kinder",
+			expectedError: true,
+		},
+		{
+			name:          "not a K8s node",
+			nodeIPs:       []string{"kind-lb=172.30.0.21"},
+			network:       "kinder",
+			expectedError: true,
+		},
+		{
+			name:          "same IP for two nodes",
+			nodeIPs:       []string{"kind-control-plane-1=172.30.0.21", "kind-worker-1=172.30.0.21"},
+			network:       "kinder",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{controlPlanes: 2, workers: 1, nodeIPs: test.nodeIPs, network: test.network, ipFamily: test.ipFamily}
+			err := validateNodeIPs("kind", flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err != nil {
+				return
+			}
+			for _, n := range nodesToCreate("kind", flags) {
+				if n.IPs != test.expected[n.Name] {
+					t.Errorf("expected IPs %v for node %s, got %v", test.expected[n.Name], n.Name, n.IPs)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateExtraPortMappings(t *testing.T) {
+	mappings := []status.PortMapping{{ContainerPort: 30080, HostPort: 8080, Protocol: "tcp"}}
+
+	tests := []struct {
+		name          string
+		controlPlanes int
+		workers       int
+		roles         []string
+		expectedNodes []string
+		expectedError bool
+	}{
+		{
+			name:          "default to workers",
+			controlPlanes: 1,
+			workers:       1,
+			expectedNodes: []string{"kind-worker-1"},
+		},
+		{
+			name:          "default to workers with more than one worker",
+			controlPlanes: 1,
+			workers:       2,
+			expectedError: true,
+		},
+		{
+			name:          "default to workers without workers",
+			controlPlanes: 1,
+			expectedError: true,
+		},
+		{
+			name:          "control-plane role",
+			controlPlanes: 1,
+			workers:       2,
+			roles:         []string{"control-plane"},
+			expectedNodes: []string{"kind-control-plane-1"},
+		},
+		{
+			name:          "invalid role",
+			controlPlanes: 1,
+			roles:         []string{"external-load-balancer"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{
+				controlPlanes:          test.controlPlanes,
+				workers:                test.workers,
+				extraPortMappings:      mappings,
+				extraPortMappingsRoles: test.roles,
+			}
+			err := validateExtraPortMappings("kind", flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err != nil {
+				return
+			}
+
+			var nodes []string
+			for _, n := range nodesToCreate("kind", flags) {
+				if len(n.ExtraPortMappings) > 0 {
+					nodes = append(nodes, n.Name)
+				}
+			}
+			if !reflect.DeepEqual(nodes, test.expectedNodes) {
+				t.Errorf("expected extra port mappings on %v, found %v", test.expectedNodes, nodes)
+			}
+		})
+	}
+}
+
+func TestValidateNodeCommands(t *testing.T) {
+	boot := "exec /usr/local/bin/entrypoint /sbin/init"
+	tests := []struct {
+		name          string
+		commands      []string
+		expectedError bool
+	}{
+		{
+			name: "no commands",
+		},
+		{
+			name:     "role and node commands",
+			commands: []string{"worker=touch /foo; " + boot, "kind-control-plane-1=" + boot},
+		},
+		{
+			name:          "invalid format",
+			commands:      []string{boot},
+			expectedError: true,
+		},
+		{
+			name:          "duplicated target",
+			commands:      []string{"worker=" + boot, "worker=" + boot},
+			expectedError: true,
+		},
+		{
+			name:          "unknown node",
+			commands:      []string{"kind-worker-2=" + boot},
+			expectedError: true,
+		},
+		{
+			name:          "not a K8s node",
+			commands:      []string{"kind-lb=" + boot},
+			expectedError: true,
+		},
+		{
+			name:     "trailing semicolon",
+			commands: []string{"worker=touch /foo && " + boot + ";"},
+		},
+		{
+			name:          "missing kind entrypoint",
+			commands:      []string{"worker=sleep infinity"},
+			expectedError: true,
+		},
+		{
+			name:          "kind entrypoint not exec'd",
+			commands:      []string{"worker=" + constants.NodeEntrypoint},
+			expectedError: true,
+		},
+		{
+			name:          "kind entrypoint not last",
+			commands:      []string{"worker=" + boot + "; sleep infinity"},
+			expectedError: true,
+		},
+		{
+			name:          "kind entrypoint in a comment",
+			commands:      []string{"worker=sleep infinity # " + boot},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{controlPlanes: 2, workers: 1, nodeCommands: test.commands}
+			err := validateNodeCommands("kind", flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestSetNodeCommand(t *testing.T) {
+	flags := &CreateOptions{
+		controlPlanes: 1,
+		workers:       2,
+		nodeCommands:  []string{"worker=role", "kind-worker-2=node"},
+	}
+
+	expected := map[string]string{
+		"kind-control-plane-1": "",
+		"kind-worker-1":        "role",
+		"kind-worker-2":        "node",
+	}
+	for _, n := range nodesToCreate("kind", flags) {
+		if n.Command != expected[n.Name] {
+			t.Errorf("expected command %q for node %s, found %q", expected[n.Name], n.Name, n.Command)
+		}
+		if n.Labels["io.x-k8s.kinder.node-command"] != expected[n.Name] {
+			t.Errorf("expected label %q for node %s, found %q", expected[n.Name], n.Name, n.Labels["io.x-k8s.kinder.node-command"])
+		}
+	}
+}
+
+// validateCheck verifies the CreateOptions after a successful validation
+type validateCheck func(t *testing.T, flags *CreateOptions)
+
+// validateTest is a test case for a func validating CreateOptions; options are applied to empty
+// CreateOptions before the validation, checks are run only if the validation succeeds
+type validateTest struct {
+	name          string
+	options       []CreateOption
+	checks        []validateCheck
+	expectedError bool
+}
+
+// runValidateTests runs the given test cases against a func validating CreateOptions
+func runValidateTests(t *testing.T, validate func(*CreateOptions) error, tests []validateTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{}
+			for _, o := range test.options {
+				o(flags)
+			}
+			err := validate(flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err != nil {
+				return
+			}
+			for _, check := range test.checks {
+				check(t, flags)
+			}
+		})
+	}
+}
+
+// expectExtraArgs checks the extra args of a control-plane component
+func expectExtraArgs(component string, expected ...string) validateCheck {
+	return func(t *testing.T, flags *CreateOptions) {
+		if got := flags.controlPlaneExtraArgs[component]; !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %s extra args %v, got %v", component, expected, got)
+		}
+	}
+}
+
+// expectControlPlaneLabel checks a label of the control-plane nodes
+func expectControlPlaneLabel(key, expected string) validateCheck {
+	return func(t *testing.T, flags *CreateOptions) {
+		if label := controlPlaneLabels(flags)[key]; label != expected {
+			t.Errorf("expected label %s=%q, got %q", key, expected, label)
+		}
+	}
+}
+
+// expectNodeLabel checks a label set on a node by the given setter
+func expectNodeLabel(set func(*nodeSpec, *CreateOptions), key, expected string) validateCheck {
+	return func(t *testing.T, flags *CreateOptions) {
+		n := nodeSpec{}
+		set(&n, flags)
+		if label := n.Labels[key]; label != expected {
+			t.Errorf("expected label %s=%q, got %q", key, expected, label)
+		}
+	}
+}
+
+func TestValidateSandboxImage(t *testing.T) {
+	runValidateTests(t, validateSandboxImage, []validateTest{
+		{
+			name: "no image",
+		},
+		{
+			name:    "repository and tag",
+			options: []CreateOption{SandboxImage("registry.k8s.io/pause:3.9")},
+		},
+		{
+			name:    "registry with port",
+			options: []CreateOption{SandboxImage("localhost:5000/library/pause:3.9")},
+		},
+		{
+			name:    "digest",
+			options: []CreateOption{SandboxImage("registry.k8s.io/pause@sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097")},
+		},
+		{
+			name:          "upper case repository",
+			options:       []CreateOption{SandboxImage("registry.k8s.io/Pause:3.9")},
+			expectedError: true,
+		},
+		{
+			name:          "whitespace",
+			options:       []CreateOption{SandboxImage("registry.k8s.io/pause: 3.9")},
+			expectedError: true,
+		},
+		{
+			name:          "invalid digest",
+			options:       []CreateOption{SandboxImage("registry.k8s.io/pause@sha256:abc")},
+			expectedError: true,
+		},
+	})
+}
+
+func TestControlPlaneExtraArgs(t *testing.T) {
+	runValidateTests(t, validateControlPlaneExtraArgs, []validateTest{
+		{
+			name:   "no extra args",
+			checks: []validateCheck{expectControlPlaneLabel(constants.ControlPlaneExtraArgsLabelKey, "")},
+		},
+		{
+			name: "repeated keys are joined",
+			options: []CreateOption{ControlPlaneExtraArgs(
+				[]string{"enable-admission-plugins=NodeRestriction", "enable-admission-plugins=AlwaysPullImages"}, nil, []string{"v=4"},
+			)},
+			checks: []validateCheck{expectControlPlaneLabel(constants.ControlPlaneExtraArgsLabelKey,
+				`{"apiServer":["enable-admission-plugins=NodeRestriction,AlwaysPullImages"],"scheduler":["v=4"]}`)},
+		},
+		{
+			name:          "invalid extra arg",
+			options:       []CreateOption{ControlPlaneExtraArgs([]string{"profiling"}, nil, nil)},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateNetwork(t *testing.T) {
+	tests := []struct {
+		name          string
+		network       string
+		expectedError bool
+	}{
+		{
+			name: "default network",
+		},
+		{
+			name:    "valid name",
+			network: "kinder_net-1.test",
+		},
+		{
+			name:          "host network",
+			network:       "host",
+			expectedError: true,
+		},
+		{
+			name:          "leading dash",
+			network:       "-net",
+			expectedError: true,
+		},
+		{
+			name:          "invalid chars",
+			network:       "my net",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateNetwork(&CreateOptions{network: test.network})
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestValidateExternalEtcdNodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		externalEtcd  bool
+		nodes         int
+		dataDir       string
+		expected      int
+		expectedError bool
+	}{
+		{
+			name:         "default",
+			externalEtcd: true,
+			expected:     1,
+		},
+		{
+			name:         "three nodes",
+			externalEtcd: true,
+			nodes:        3,
+			expected:     3,
+		},
+		{
+			name:          "even nodes",
+			externalEtcd:  true,
+			nodes:         2,
+			expectedError: true,
+		},
+		{
+			name:          "negative nodes",
+			externalEtcd:  true,
+			nodes:         -1,
+			expectedError: true,
+		},
+		{
+			name:          "nodes without external etcd",
+			nodes:         3,
+			expectedError: true,
+		},
+		{
+			name:          "data dir with many nodes",
+			externalEtcd:  true,
+			nodes:         3,
+			dataDir:       os.TempDir(),
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{externalEtcd: test.externalEtcd, externalEtcdNodes: test.nodes, externalEtcdDataDir: test.dataDir}
+			err := validateExternalEtcd(flags)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err == nil && externalEtcdMembers(flags) != test.expected {
+				t.Errorf("expected %d external etcd members, found %d", test.expected, externalEtcdMembers(flags))
+			}
+		})
+	}
+}
+
+func TestValidateIPFamily(t *testing.T) {
+	tests := []struct {
+		name              string
+		ipFamily          string
+		network           string
+		kubernetesVersion string
+		expected          status.ClusterIPFamily
+		expectedError     bool
+	}{
+		{
+			name:     "default",
+			expected: status.IPv4Family,
+		},
+		{
+			name:     "ipv4",
+			ipFamily: "ipv4",
+			expected: status.IPv4Family,
+		},
+		{
+			name:     "ipv6",
+			ipFamily: "ipv6",
+			network:  "kinder-ipv6",
+			expected: status.IPv6Family,
+		},
+		{
+			name:          "ipv6 without network",
+			ipFamily:      "ipv6",
+			expectedError: true,
+		},
+		{
+			name:              "dual-stack",
+			ipFamily:          "dualstack",
+			network:           "kinder-ipv6",
+			kubernetesVersion: "v1.30.0",
+			expected:          status.DualStackFamily,
+		},
+		{
+			name:     "dual-stack with unknown version",
+			ipFamily: "dualstack",
+			network:  "kinder-ipv6",
+			expected: status.DualStackFamily,
+		},
+		{
+			name:              "dual-stack with unsupported version",
+			ipFamily:          "dualstack",
+			network:           "kinder-ipv6",
+			kubernetesVersion: "v1.20.15",
+			expectedError:     true,
+		},
+		{
+			name:          "unknown",
+			ipFamily:      "ipv5",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &CreateOptions{network: test.network, kubernetesVersion: test.kubernetesVersion}
+			IPFamily(test.ipFamily)(flags)
+			err := validateIPFamily(flags)
+			if err == nil {
+				err = validateIPFamilyVersion(flags)
+			}
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err == nil && flags.ipFamily != test.expected {
+				t.Errorf("expected IP family %q, found %q", test.expected, flags.ipFamily)
+			}
+		})
+	}
+}
+
+func TestValidateKubeletEviction(t *testing.T) {
+	runValidateTests(t, validateKubeletEviction, []validateTest{
+		{
+			name:   "no eviction settings",
+			checks: []validateCheck{expectNodeLabel(setKubeletEviction, constants.KubeletEvictionLabelKey, "")},
+		},
+		{
+			name:    "relaxed thresholds do not override explicit thresholds",
+			options: []CreateOption{KubeletEviction([]string{"memory.available<100Mi"}, nil, 0, true)},
+			checks: []validateCheck{expectNodeLabel(setKubeletEviction, constants.KubeletEvictionLabelKey,
+				`{"evictionHard":{"imagefs.available":"0%","imagefs.inodesFree":"0%","memory.available":"100Mi","nodefs.available":"0%","nodefs.inodesFree":"0%"}}`)},
+		},
+		{
+			name:    "soft thresholds with grace period",
+			options: []CreateOption{KubeletEviction(nil, []string{"memory.available<200Mi"}, time.Minute, false)},
+			checks: []validateCheck{expectNodeLabel(setKubeletEviction, constants.KubeletEvictionLabelKey,
+				`{"evictionSoft":{"memory.available":"200Mi"},"evictionSoftGracePeriod":{"memory.available":"1m0s"}}`)},
+		},
+		{
+			name:          "soft thresholds without grace period",
+			options:       []CreateOption{KubeletEviction(nil, []string{"memory.available<200Mi"}, 0, false)},
+			expectedError: true,
+		},
+		{
+			name:          "grace period without soft thresholds",
+			options:       []CreateOption{KubeletEviction(nil, nil, time.Minute, false)},
+			expectedError: true,
+		},
+		{
+			name:          "invalid threshold",
+			options:       []CreateOption{KubeletEviction([]string{"memory.available"}, nil, 0, false)},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateNodeSysctls(t *testing.T) {
+	expectConf := func(expected string) validateCheck {
+		return func(t *testing.T, flags *CreateOptions) {
+			if conf := nodeSysctlsConf(flags.sysctls); conf != expected {
+				t.Errorf("expected conf %q, got %q", expected, conf)
+			}
+		}
+	}
+
+	runValidateTests(t, validateNodeSysctls, []validateTest{
+		{
+			name:   "no sysctls",
+			checks: []validateCheck{expectNodeLabel(setNodeSysctls, constants.NodeSysctlsLabelKey, "")},
+		},
+		{
+			name:    "valid sysctls",
+			options: []CreateOption{NodeSysctls([]string{"vm.max_map_count=262144", "net.ipv4.ip_local_port_range = 1024 65000"})},
+			checks: []validateCheck{
+				expectNodeLabel(setNodeSysctls, constants.NodeSysctlsLabelKey, `{"net.ipv4.ip_local_port_range":"1024 65000","vm.max_map_count":"262144"}`),
+				expectConf("# sysctls set by kinder at create time\nnet.ipv4.ip_local_port_range = 1024 65000\nvm.max_map_count = 262144\n"),
+			},
+		},
+		{
+			name:          "missing value",
+			options:       []CreateOption{NodeSysctls([]string{"vm.max_map_count"})},
+			expectedError: true,
+		},
+		{
+			name:          "empty value",
+			options:       []CreateOption{NodeSysctls([]string{"vm.max_map_count="})},
+			expectedError: true,
+		},
+		{
+			name:          "invalid key",
+			options:       []CreateOption{NodeSysctls([]string{"vm..max_map_count=1"})},
+			expectedError: true,
+		},
+		{
+			name:          "duplicated key",
+			options:       []CreateOption{NodeSysctls([]string{"vm.max_map_count=1", "vm.max_map_count=2"})},
+			expectedError: true,
+		},
+	})
+}
+
+func TestSysctlPath(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "net.core.somaxconn", expected: "/proc/sys/net/core/somaxconn"},
+		{key: "net/core/somaxconn", expected: "/proc/sys/net/core/somaxconn"},
+		{key: "net.ipv4.conf.eth0/100.forwarding", expected: "/proc/sys/net/ipv4/conf/eth0.100/forwarding"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			if path := sysctlPath(test.key); path != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, path)
+			}
+		})
+	}
+}
+
+func TestValidateKubeProxy(t *testing.T) {
+	runValidateTests(t, validateKubeProxy, []validateTest{
+		{
+			name:   "default kube-proxy",
+			checks: []validateCheck{expectControlPlaneLabel(constants.KubeProxyLabelKey, "")},
+		},
+		{
+			name:    "kube-proxy mode",
+			options: []CreateOption{KubeProxy("ipvs", false)},
+			checks:  []validateCheck{expectControlPlaneLabel(constants.KubeProxyLabelKey, "ipvs")},
+		},
+		{
+			name:    "skip kube-proxy",
+			options: []CreateOption{KubeProxy("", true)},
+			checks:  []validateCheck{expectControlPlaneLabel(constants.KubeProxyLabelKey, constants.KubeProxyDisabled)},
+		},
+		{
+			name:          "invalid kube-proxy mode",
+			options:       []CreateOption{KubeProxy("userspace", false)},
+			expectedError: true,
+		},
+		{
+			name:          "kube-proxy mode and skip kube-proxy are mutually exclusive",
+			options:       []CreateOption{KubeProxy("iptables", true)},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateCoreDNS(t *testing.T) {
+	runValidateTests(t, validateCoreDNS, []validateTest{
+		{
+			name: "kubeadm defaults",
+			checks: []validateCheck{
+				expectNodeLabel(setCoreDNSImage, constants.CoreDNSImageLabelKey, ""),
+				expectControlPlaneLabel(constants.CoreDNSReplicasLabelKey, ""),
+			},
+		},
+		{
+			name:    "pinned image and replicas",
+			options: []CreateOption{CoreDNS("localhost:5000/coredns:1.11.3", 3)},
+			checks: []validateCheck{
+				expectNodeLabel(setCoreDNSImage, constants.CoreDNSImageLabelKey, "localhost:5000/coredns:1.11.3"),
+				expectControlPlaneLabel(constants.CoreDNSReplicasLabelKey, "3"),
+			},
+		},
+		{
+			name:          "invalid image",
+			options:       []CreateOption{CoreDNS("localhost:5000/coredns", 0)},
+			expectedError: true,
+		},
+		{
+			name:          "negative replicas",
+			options:       []CreateOption{CoreDNS("", -1)},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateEtcdVersion(t *testing.T) {
+	runValidateTests(t, validateEtcdVersion, []validateTest{
+		{
+			name:   "default etcd version",
+			checks: []validateCheck{expectNodeLabel(setEtcdVersion, constants.EtcdVersionLabelKey, "")},
+		},
+		{
+			name:    "etcd image tag",
+			options: []CreateOption{EtcdVersion("3.5.12-0")},
+			checks:  []validateCheck{expectNodeLabel(setEtcdVersion, constants.EtcdVersionLabelKey, "3.5.12-0")},
+		},
+		{
+			name:          "v prefix",
+			options:       []CreateOption{EtcdVersion("v3.5.12")},
+			expectedError: true,
+		},
+		{
+			name:          "not a semantic version",
+			options:       []CreateOption{EtcdVersion("3.5")},
+			expectedError: true,
+		},
+		{
+			name:          "external etcd",
+			options:       []CreateOption{ExternalEtcd(true), EtcdVersion("3.5.12-0")},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateAPIServerTLS(t *testing.T) {
+	runValidateTests(t, validateAPIServerTLS, []validateTest{
+		{
+			name: "default TLS settings",
+		},
+		{
+			name: "TLS min version and cipher suites",
+			options: []CreateOption{
+				ControlPlaneExtraArgs([]string{"v=4"}, nil, nil),
+				APIServerTLS("VersionTLS12", []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}),
+			},
+		},
+		{
+			name:          "invalid min version",
+			options:       []CreateOption{APIServerTLS("TLS12", nil)},
+			expectedError: true,
+		},
+		{
+			name:          "unknown cipher suite",
+			options:       []CreateOption{APIServerTLS("", []string{"TLS_FOO"})},
+			expectedError: true,
+		},
+		{
+			name: "TLS min version set also by extra args",
+			options: []CreateOption{
+				ControlPlaneExtraArgs([]string{"tls-min-version=VersionTLS13"}, nil, nil),
+				APIServerTLS("VersionTLS12", nil),
+			},
+			expectedError: true,
+		},
+		{
+			name: "cipher suites set also by extra args",
+			options: []CreateOption{
+				ControlPlaneExtraArgs([]string{"tls-cipher-suites=TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}, nil, nil),
+				APIServerTLS("", []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}),
+			},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateClusterSigningDuration(t *testing.T) {
+	runValidateTests(t, validateClusterSigningDuration, []validateTest{
+		{
+			name:   "default duration",
+			checks: []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent)},
+		},
+		{
+			name:    "cluster signing duration",
+			options: []CreateOption{ClusterSigningDuration(time.Hour)},
+			checks:  []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "cluster-signing-duration=1h0m0s")},
+		},
+		{
+			name: "cluster signing duration with other extra args",
+			options: []CreateOption{
+				ControlPlaneExtraArgs(nil, []string{"v=4"}, nil),
+				ClusterSigningDuration(10 * time.Minute),
+			},
+			checks: []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "v=4", "cluster-signing-duration=10m0s")},
+		},
+		{
+			name:          "negative duration",
+			options:       []CreateOption{ClusterSigningDuration(-time.Hour)},
+			expectedError: true,
+		},
+		{
+			name:          "duration too short",
+			options:       []CreateOption{ClusterSigningDuration(30 * time.Second)},
+			expectedError: true,
+		},
+		{
+			name: "duration set also by extra args",
+			options: []CreateOption{
+				ControlPlaneExtraArgs(nil, []string{"cluster-signing-duration=2h"}, nil),
+				ClusterSigningDuration(time.Hour),
+			},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateServiceAccount(t *testing.T) {
+	runValidateTests(t, validateServiceAccount, []validateTest{
+		{
+			name:   "default issuer",
+			checks: []validateCheck{expectExtraArgs(kubeadm.APIServerComponent)},
+		},
+		{
+			name:    "service account issuer",
+			options: []CreateOption{ServiceAccount("https://issuer.example.com", "")},
+			checks:  []validateCheck{expectExtraArgs(kubeadm.APIServerComponent, "service-account-issuer=https://issuer.example.com")},
+		},
+		{
+			name: "service account issuer with other extra args",
+			options: []CreateOption{
+				ControlPlaneExtraArgs([]string{"v=4"}, nil, nil),
+				ServiceAccount("https://issuer.example.com", ""),
+			},
+			checks: []validateCheck{expectExtraArgs(kubeadm.APIServerComponent, "v=4", "service-account-issuer=https://issuer.example.com")},
+		},
+		{
+			name:          "invalid issuer",
+			options:       []CreateOption{ServiceAccount("http://issuer.example.com", "")},
+			expectedError: true,
+		},
+		{
+			name: "issuer set also by extra args",
+			options: []CreateOption{
+				ControlPlaneExtraArgs([]string{"service-account-issuer=https://other.example.com"}, nil, nil),
+				ServiceAccount("https://issuer.example.com", ""),
+			},
+			expectedError: true,
+		},
+		{
+			name:          "missing key file",
+			options:       []CreateOption{ServiceAccount("", "/this/file/does/not/exist")},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateExternalEtcdEndpoints(t *testing.T) {
+	runValidateTests(t, validateExternalEtcdEndpoints, []validateTest{
+		{
+			name:   "no endpoints",
+			checks: []validateCheck{expectControlPlaneLabel(constants.ExternalEtcdEndpointsLabelKey, "")},
+		},
+		{
+			name:    "http endpoints",
+			options: []CreateOption{ExternalEtcdEndpoints([]string{"http://10.0.0.10:2379", "http://10.0.0.11:2379"}, "", "", "")},
+			checks:  []validateCheck{expectControlPlaneLabel(constants.ExternalEtcdEndpointsLabelKey, `["http://10.0.0.10:2379","http://10.0.0.11:2379"]`)},
+		},
+		{
+			name:          "certificates without endpoints",
+			options:       []CreateOption{ExternalEtcdEndpoints(nil, "ca.crt", "", "")},
+			expectedError: true,
+		},
+		{
+			name:          "certificates with http endpoints",
+			options:       []CreateOption{ExternalEtcdEndpoints([]string{"http://10.0.0.10:2379"}, "ca.crt", "client.crt", "client.key")},
+			expectedError: true,
+		},
+		{
+			name:          "https endpoints without certificates",
+			options:       []CreateOption{ExternalEtcdEndpoints([]string{"https://10.0.0.10:2379"}, "", "", "")},
+			expectedError: true,
+		},
+		{
+			name: "https endpoints with missing certificate files",
+			options: []CreateOption{ExternalEtcdEndpoints([]string{"https://10.0.0.10:2379"},
+				"/this/file/does/not/exist", "/this/file/does/not/exist", "/this/file/does/not/exist")},
+			expectedError: true,
+		},
+		{
+			name:          "mixed http and https endpoints",
+			options:       []CreateOption{ExternalEtcdEndpoints([]string{"http://10.0.0.10:2379", "https://10.0.0.11:2379"}, "", "", "")},
+			expectedError: true,
+		},
+		{
+			name:          "invalid endpoint",
+			options:       []CreateOption{ExternalEtcdEndpoints([]string{"10.0.0.10:2379"}, "", "", "")},
+			expectedError: true,
+		},
+		{
+			name:          "endpoints with an external etcd created by kinder",
+			options:       []CreateOption{ExternalEtcd(true), ExternalEtcdEndpoints([]string{"http://10.0.0.10:2379"}, "", "", "")},
+			expectedError: true,
+		},
+		{
+			name:          "endpoints with the local etcd version",
+			options:       []CreateOption{EtcdVersion("3.5.12-0"), ExternalEtcdEndpoints([]string{"http://10.0.0.10:2379"}, "", "", "")},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateNodeCIDRMaskSize(t *testing.T) {
+	// options returns the options for a cluster with one control-plane node and the given node CIDR mask sizes
+	options := func(ipFamily status.ClusterIPFamily, ipv4, ipv6 int, others ...CreateOption) []CreateOption {
+		return append([]CreateOption{ControlPlanes(1), IPFamily(string(ipFamily)), NodeCIDRMaskSize(ipv4, ipv6)}, others...)
+	}
+
+	runValidateTests(t, validateNodeCIDRMaskSize, []validateTest{
+		{
+			name:    "not set",
+			options: options("", 0, 0),
+		},
+		{
+			name:    "ipv4",
+			options: options(status.IPv4Family, 28, 0, ControlPlaneExtraArgs(nil, []string{"v=4"}, nil)),
+			checks:  []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "v=4", "node-cidr-mask-size=28")},
+		},
+		{
+			name:          "ipv4 cluster with ipv6 size",
+			options:       options(status.IPv4Family, 28, 64),
+			expectedError: true,
+		},
+		{
+			name:    "ipv6",
+			options: options(status.IPv6Family, 0, 64),
+			checks:  []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "node-cidr-mask-size=64")},
+		},
+		{
+			name:          "ipv6 cluster with ipv4 size",
+			options:       options(status.IPv6Family, 28, 0),
+			expectedError: true,
+		},
+		{
+			name:          "ipv6 not larger than the pod subnet",
+			options:       options(status.IPv6Family, 0, 56),
+			expectedError: true,
+		},
+		{
+			name:          "ipv6 too far from the pod subnet",
+			options:       options(status.IPv6Family, 0, 120),
+			expectedError: true,
+		},
+		{
+			name:    "dualstack",
+			options: options(status.DualStackFamily, 28, 64),
+			checks:  []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "node-cidr-mask-size-ipv4=28", "node-cidr-mask-size-ipv6=64")},
+		},
+		{
+			name:    "dualstack with ipv6 only",
+			options: options(status.DualStackFamily, 0, 64),
+			checks:  []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "node-cidr-mask-size-ipv6=64")},
+		},
+		{
+			name:          "not larger than the pod subnet",
+			options:       options("", 16, 0),
+			expectedError: true,
+		},
+		{
+			name:          "too small pod CIDR",
+			options:       options("", 31, 0),
+			expectedError: true,
+		},
+		{
+			name:    "smallest pod CIDR",
+			options: options("", 30, 0, Workers(3)),
+			checks:  []validateCheck{expectExtraArgs(kubeadm.ControllerManagerComponent, "node-cidr-mask-size=30")},
+		},
+		{
+			name:          "not enough pod CIDRs for the nodes",
+			options:       options("", 18, 0, Workers(2), WorkerPools([]string{"name=spot;count=2"})),
+			expectedError: true,
+		},
+		{
+			name:          "set also by extra args",
+			options:       options("", 24, 0, ControlPlaneExtraArgs(nil, []string{"node-cidr-mask-size=26"}, nil)),
+			expectedError: true,
+		},
+		{
+			name:          "set also by family specific extra args",
+			options:       options(status.DualStackFamily, 24, 0, ControlPlaneExtraArgs(nil, []string{"node-cidr-mask-size-ipv6=64"}, nil)),
+			expectedError: true,
+		},
+	})
+}
+
+func TestNodeCIDRMaskSizeFeasible(t *testing.T) {
+	tests := []struct {
+		maskSize        int
+		clusterMaskSize int
+		bits            int
+		nodes           int
+		expectedError   bool
+	}{
+		{maskSize: 24, clusterMaskSize: 16, bits: 32, nodes: 256},
+		{maskSize: 24, clusterMaskSize: 16, bits: 32, nodes: 257, expectedError: true},
+		{maskSize: 30, clusterMaskSize: 16, bits: 32, nodes: 3},
+		{maskSize: 30, clusterMaskSize: 8, bits: 32, nodes: 3, expectedError: true},
+		{maskSize: 17, clusterMaskSize: 16, bits: 32, nodes: 2},
+		{maskSize: 17, clusterMaskSize: 16, bits: 32, nodes: 3, expectedError: true},
+		{maskSize: 126, clusterMaskSize: 112, bits: 128, nodes: 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("/%d in /%d with %d nodes", tt.maskSize, tt.clusterMaskSize, tt.nodes), func(t *testing.T) {
+			if err := nodeCIDRMaskSizeFeasible(tt.maskSize, tt.clusterMaskSize, tt.bits, tt.nodes); (err != nil) != tt.expectedError {
+				t.Errorf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateEncryptionAtRest(t *testing.T) {
+	expectConfig := func(expected bool) validateCheck {
+		return func(t *testing.T, flags *CreateOptions) {
+			if (flags.encryptionConfig != "") != expected {
+				t.Errorf("expected encryption config: %v, got %q", expected, flags.encryptionConfig)
+			}
+		}
+	}
+
+	runValidateTests(t, validateEncryptionAtRest, []validateTest{
+		{
+			name:   "no encryption",
+			checks: []validateCheck{expectExtraArgs(kubeadm.APIServerComponent), expectConfig(false)},
+		},
+		{
+			name:    "aescbc",
+			options: []CreateOption{ControlPlaneExtraArgs([]string{"v=4"}, nil, nil), EncryptionAtRest("aescbc")},
+			checks: []validateCheck{
+				expectExtraArgs(kubeadm.APIServerComponent, "v=4", "encryption-provider-config=/etc/kubernetes/encryption/config.yaml"),
+				expectConfig(true),
+			},
+		},
+		{
+			name:          "unsupported provider",
+			options:       []CreateOption{EncryptionAtRest("kms")},
+			expectedError: true,
+		},
+		{
+			name:          "encryption config set also by extra args",
+			options:       []CreateOption{ControlPlaneExtraArgs([]string{"encryption-provider-config=/etc/custom.yaml"}, nil, nil), EncryptionAtRest("secretbox")},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateAuditLogging(t *testing.T) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte("apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n- level: RequestResponse\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalidPolicyFile := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalidPolicyFile, []byte("apiVersion: v1\nkind: ConfigMap\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPolicy := func(expected string) validateCheck {
+		return func(t *testing.T, flags *CreateOptions) {
+			if !strings.Contains(string(flags.auditPolicy), expected) {
+				t.Errorf("expected audit policy with %q, got %q", expected, flags.auditPolicy)
+			}
+		}
+	}
+
+	runValidateTests(t, validateAuditLogging, []validateTest{
+		{
+			name:   "no audit logging",
+			checks: []validateCheck{expectExtraArgs(kubeadm.APIServerComponent)},
+		},
+		{
+			name:    "default policy",
+			options: []CreateOption{AuditLogging("", "/var/log/audit/kube.log")},
+			checks: []validateCheck{
+				expectExtraArgs(kubeadm.APIServerComponent, "audit-policy-file=/etc/kubernetes/audit/policy.yaml", "audit-log-path=/var/log/audit/kube.log"),
+				expectPolicy("level: Metadata"),
+			},
+		},
+		{
+			name:    "policy file with default log path",
+			options: []CreateOption{ControlPlaneExtraArgs([]string{"v=4"}, nil, nil), AuditLogging(policyFile, "")},
+			checks: []validateCheck{
+				expectExtraArgs(kubeadm.APIServerComponent, "v=4", "audit-policy-file=/etc/kubernetes/audit/policy.yaml", "audit-log-path=/var/log/kubernetes/audit/audit.log"),
+				expectPolicy("level: RequestResponse"),
+			},
+		},
+		{
+			name:          "missing policy file",
+			options:       []CreateOption{AuditLogging(filepath.Join(dir, "missing.yaml"), "")},
+			expectedError: true,
+		},
+		{
+			name:          "invalid policy file",
+			options:       []CreateOption{AuditLogging(invalidPolicyFile, "")},
+			expectedError: true,
+		},
+		{
+			name:          "relative log path",
+			options:       []CreateOption{AuditLogging("", "audit.log")},
+			expectedError: true,
+		},
+		{
+			name:          "audit log path set also by extra args",
+			options:       []CreateOption{ControlPlaneExtraArgs([]string{"audit-log-path=/var/log/custom.log"}, nil, nil), AuditLogging("", "/var/log/audit.log")},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateBootstrapManifests(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"cni.yaml":    "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: cni\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cni\n",
+		"rbac.json":   `{"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "ClusterRole", "metadata": {"name": "foo"}}`,
+		"empty.yaml":  "---\n",
+		"nokind.yaml": "apiVersion: v1\nmetadata:\n  name: foo\n",
+		"broken.yaml": "apiVersion: v1\nkind: [\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectManifests := func(expected ...string) validateCheck {
+		return func(t *testing.T, flags *CreateOptions) {
+			if got := bootstrapManifestsInNode(flags); !reflect.DeepEqual(got, expected) {
+				t.Errorf("expected manifests %v, got %v", expected, got)
+			}
+		}
+	}
+
+	runValidateTests(t, validateBootstrapManifests, []validateTest{
+		{
+			name:   "no manifests",
+			checks: []validateCheck{expectManifests()},
+		},
+		{
+			name:    "files and URLs",
+			options: []CreateOption{BootstrapManifests([]string{filepath.Join(dir, "cni.yaml"), "https://example.com/rbac.yaml", filepath.Join(dir, "rbac.json")})},
+			checks:  []validateCheck{expectManifests("/kinder/bootstrap-manifests/01-cni.yaml", "https://example.com/rbac.yaml", "/kinder/bootstrap-manifests/03-rbac.json")},
+		},
+		{
+			name:          "missing file",
+			options:       []CreateOption{BootstrapManifests([]string{filepath.Join(dir, "missing.yaml")})},
+			expectedError: true,
+		},
+		{
+			name:          "invalid URL",
+			options:       []CreateOption{BootstrapManifests([]string{"https://"})},
+			expectedError: true,
+		},
+		{
+			name:          "no objects",
+			options:       []CreateOption{BootstrapManifests([]string{filepath.Join(dir, "empty.yaml")})},
+			expectedError: true,
+		},
+		{
+			name:          "not a Kubernetes object",
+			options:       []CreateOption{BootstrapManifests([]string{filepath.Join(dir, "nokind.yaml")})},
+			expectedError: true,
+		},
+		{
+			name:          "invalid YAML",
+			options:       []CreateOption{BootstrapManifests([]string{filepath.Join(dir, "broken.yaml")})},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateCNI(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cni.yaml"), []byte("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: cni\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "empty.yaml"), []byte("---\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	expectCNI := func(expected string) validateCheck {
+		return func(t *testing.T, flags *CreateOptions) {
+			if got := cniInNode(flags); got != expected {
+				t.Errorf("expected CNI %q, got %q", expected, got)
+			}
+		}
+	}
+
+	runValidateTests(t, validateCNI, []validateTest{
+		{
+			name:   "default",
+			checks: []validateCheck{expectCNI("")},
+		},
+		{
+			name:    "known plugin",
+			options: []CreateOption{CNI("calico")},
+			checks:  []validateCheck{expectCNI("calico")},
+		},
+		{
+			name:    "custom manifest file",
+			options: []CreateOption{CNI(filepath.Join(dir, "cni.yaml"))},
+			checks:  []validateCheck{expectCNI("/kinder/cni/cni.yaml")},
+		},
+		{
+			name:    "custom manifest URL",
+			options: []CreateOption{CNI("https://example.com/cni.yaml")},
+			checks:  []validateCheck{expectCNI("https://example.com/cni.yaml")},
+		},
+		{
+			name:          "unknown plugin",
+			options:       []CreateOption{CNI("calicoo")},
+			expectedError: true,
+		},
+		{
+			name:          "invalid URL",
+			options:       []CreateOption{CNI("https://")},
+			expectedError: true,
+		},
+		{
+			name:          "no objects",
+			options:       []CreateOption{CNI(filepath.Join(dir, "empty.yaml"))},
+			expectedError: true,
+		},
+	})
+}
+
+func TestValidateEtcdQuotaAndCompaction(t *testing.T) {
+	expectArgs := func(expected ...string) validateCheck {
+		return func(t *testing.T, flags *CreateOptions) {
+			if !reflect.DeepEqual(flags.etcdExtraArgs, expected) {
+				t.Errorf("expected args %v, found %v", expected, flags.etcdExtraArgs)
+			}
+		}
+	}
+
+	runValidateTests(t, validateEtcdQuotaAndCompaction, []validateTest{
+		{name: "not set", checks: []validateCheck{expectArgs()}},
+		{
+			name:    "quota",
+			options: []CreateOption{EtcdQuotaAndCompaction("2Gi", "", "")},
+			checks:  []validateCheck{expectArgs("quota-backend-bytes=2147483648")},
+		},
+		{
+			name:    "quota in bytes",
+			options: []CreateOption{EtcdQuotaAndCompaction("1048576", "", "")},
+			checks:  []validateCheck{expectArgs("quota-backend-bytes=1048576")},
+		},
+		{name: "invalid quota", options: []CreateOption{EtcdQuotaAndCompaction("two", "", "")}, expectedError: true},
+		{name: "negative quota", options: []CreateOption{EtcdQuotaAndCompaction("-1Gi", "", "")}, expectedError: true},
+		{
+			name:    "periodic retention without mode",
+			options: []CreateOption{EtcdQuotaAndCompaction("", "", "1h")},
+			checks:  []validateCheck{expectArgs("auto-compaction-retention=1h")},
+		},
+		{
+			name:    "periodic retention in hours",
+			options: []CreateOption{EtcdQuotaAndCompaction("", "periodic", "5")},
+			checks:  []validateCheck{expectArgs("auto-compaction-mode=periodic", "auto-compaction-retention=5")},
+		},
+		{
+			name:    "revision",
+			options: []CreateOption{EtcdQuotaAndCompaction("8Gi", "revision", "1000")},
+			checks:  []validateCheck{expectArgs("quota-backend-bytes=8589934592", "auto-compaction-mode=revision", "auto-compaction-retention=1000")},
+		},
+		{name: "revision retention as duration", options: []CreateOption{EtcdQuotaAndCompaction("", "revision", "1h")}, expectedError: true},
+		{name: "invalid periodic retention", options: []CreateOption{EtcdQuotaAndCompaction("", "periodic", "-1h")}, expectedError: true},
+		{name: "mode without retention", options: []CreateOption{EtcdQuotaAndCompaction("", "periodic", "")}, expectedError: true},
+		{name: "unknown mode", options: []CreateOption{EtcdQuotaAndCompaction("", "daily", "1")}, expectedError: true},
+	})
+}
+
+func TestValidateKubeadmClusterName(t *testing.T) {
+	runValidateTests(t, validateKubeadmClusterName, []validateTest{
+		{name: "not set"},
+		{name: "valid DNS label", options: []CreateOption{KubeadmClusterName("east-1")}},
+		{name: "uppercase", options: []CreateOption{KubeadmClusterName("East")}, expectedError: true},
+		{name: "dots are not allowed", options: []CreateOption{KubeadmClusterName("east.example")}, expectedError: true},
+		{name: "too long", options: []CreateOption{KubeadmClusterName(strings.Repeat("a", 64))}, expectedError: true},
+	})
+}
+
+func TestValidateWaitDaemonSets(t *testing.T) {
+	runValidateTests(t, validateWaitDaemonSets, []validateTest{
+		{
+			name: "no DaemonSets",
+		},
+		{
+			name:    "namespace defaults to kube-system",
+			options: []CreateOption{WaitDaemonSets([]string{"calico-node", "tigera-operator/csi-node-driver"})},
+			checks: []validateCheck{func(t *testing.T, flags *CreateOptions) {
+				expected := []string{"kube-system/calico-node", "tigera-operator/csi-node-driver"}
+				if !reflect.DeepEqual(flags.waitDaemonSets, expected) {
+					t.Errorf("expected DaemonSets %v, got %v", expected, flags.waitDaemonSets)
+				}
+			}},
+		},
+		{
+			name:          "invalid name",
+			options:       []CreateOption{WaitDaemonSets([]string{"kube-system/Calico_Node"})},
+			expectedError: true,
+		},
+		{
+			name:          "too many separators",
+			options:       []CreateOption{WaitDaemonSets([]string{"kube-system/calico/node"})},
+			expectedError: true,
+		},
+	})
+}
+
+func TestParseWorkerPools(t *testing.T) {
+	tests := []struct {
+		name          string
+		pools         []string
+		expected      []workerPool
+		expectedError bool
+	}{
+		{
+			name: "no pools",
+		},
+		{
+			name:  "pools with labels and taints",
+			pools: []string{"name=spot;count=2;labels=pool=spot,tier=cheap;taints=spot=true:NoSchedule", "name=ondemand;count=1"},
+			expected: []workerPool{
+				{Name: "spot", Count: 2, Labels: []string{"pool=spot", "tier=cheap"}, Taints: []string{"spot=true:NoSchedule"}},
+				{Name: "ondemand", Count: 1},
+			},
+		},
+		{
+			name:          "missing count",
+			pools:         []string{"name=spot"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid count",
+			pools:         []string{"name=spot;count=0"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid name",
+			pools:         []string{"name=Spot_1;count=1"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown field",
+			pools:         []string{"name=spot;count=1;weight=2"},
+			expectedError: true,
+		},
+		{
+			name:          "repeated field",
+			pools:         []string{"name=spot;count=1;count=2"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid taint",
+			pools:         []string{"name=spot;count=1;taints=spot=true"},
+			expectedError: true,
+		},
+		{
+			name:          "duplicated pool",
+			pools:         []string{"name=spot;count=1", "name=spot;count=2"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pools, err := parseWorkerPools(test.pools)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if !reflect.DeepEqual(pools, test.expected) {
+				t.Errorf("expected %+v, found %+v", test.expected, pools)
+			}
+		})
+	}
+}
+
+func TestNodesToCreateWithWorkerPools(t *testing.T) {
+	flags := &CreateOptions{
+		controlPlanes: 1,
+		workers:       1,
+		workerLabels:  []string{"disktype=ssd"},
+		workerPools:   []string{"name=spot;count=2;labels=pool=spot;taints=spot=true:NoSchedule", "name=ondemand;count=1"},
+	}
+
+	expected := map[string]map[string]string{
+		"kind-control-plane-1": {
+			constants.IPFamilyLabelKey: "ipv4",
+		},
+		"kind-worker-1": {
+			constants.IPFamilyLabelKey:   "ipv4",
+			constants.NodeLabelsLabelKey: "disktype=ssd",
+		},
+		"kind-worker-spot-1": {
+			constants.IPFamilyLabelKey:   "ipv4",
+			constants.WorkerPoolLabelKey: "spot",
+			constants.NodeLabelsLabelKey: "pool=spot",
+			constants.NodeTaintsLabelKey: "spot=true:NoSchedule",
+		},
+		"kind-worker-spot-2": {
+			constants.IPFamilyLabelKey:   "ipv4",
+			constants.WorkerPoolLabelKey: "spot",
+			constants.NodeLabelsLabelKey: "pool=spot",
+			constants.NodeTaintsLabelKey: "spot=true:NoSchedule",
+		},
+		"kind-worker-ondemand-1": {
+			constants.IPFamilyLabelKey:   "ipv4",
+			constants.WorkerPoolLabelKey: "ondemand",
+		},
+	}
+
+	nodes := nodesToCreate("kind", flags)
+	if len(nodes) != len(expected) {
+		t.Fatalf("expected %d nodes, found %d", len(expected), len(nodes))
+	}
+	for _, n := range nodes {
+		labels, ok := expected[n.Name]
+		if !ok {
+			t.Errorf("unexpected node %s", n.Name)
+			continue
+		}
+		if !reflect.DeepEqual(n.Labels, labels) {
+			t.Errorf("expected labels %v for node %s, found %v", labels, n.Name, n.Labels)
+		}
+	}
+}
+
+func TestKubeletDefaultsWithExtraArg(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{
+			name:     "no defaults file",
+			expected: "KUBELET_EXTRA_ARGS=\"--system-reserved=cpu=1\"\n",
+		},
+		{
+			name:     "empty extra args",
+			lines:    []string{"KUBELET_EXTRA_ARGS="},
+			expected: "KUBELET_EXTRA_ARGS=\"--system-reserved=cpu=1\"\n",
+		},
+		{
+			name:     "existing extra args",
+			lines:    []string{"FOO=bar", `KUBELET_EXTRA_ARGS="--cgroups-per-qos=false --enforce-node-allocatable="""`},
+			expected: "FOO=bar\nKUBELET_EXTRA_ARGS=\"--cgroups-per-qos=false --enforce-node-allocatable=\"\" --system-reserved=cpu=1\"\n",
+		},
+		{
+			name:     "unquoted extra args",
+			lines:    []string{"KUBELET_EXTRA_ARGS=--v=2"},
+			expected: "KUBELET_EXTRA_ARGS=\"--v=2 --system-reserved=cpu=1\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kubeletDefaultsWithExtraArg(tt.lines, "--system-reserved=cpu=1"); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/kubeadm"
+)
+
+// CaptureKubeadmOutput instruct the cluster manager to capture the output of the kubeadm init and join commands,
+// including phases, executed on the K8s nodes; see WriteKubeadmOutput.
+// Nb. this should be set before KubeadmBinary, because commands are matched by the kubeadm command name.
+func (c *ClusterManager) CaptureKubeadmOutput() {
+	c.kubeadmOutputs = map[string]*bytes.Buffer{}
+	for _, n := range c.Cluster.K8sNodes() {
+		var out bytes.Buffer
+		c.kubeadmOutputs[n.Name()] = &out
+		n.CaptureKubeadmOutput(&out)
+	}
+}
+
+// WriteKubeadmOutput writes the kubeadm output captured on each node in a file in the given dir, normalized so the
+// output of two runs, e.g. with different kubeadm versions, can be compared with kubeadm.DiffOutput; files are named
+// after the action and the node name without the cluster name prefix, e.g. kubeadm-init-control-plane.log,
+// so the files for two clusters with the same topology have the same names. Nodes without output are skipped.
+func (c *ClusterManager) WriteKubeadmOutput(dir, action string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create the kubeadm output dir %s", dir)
+	}
+
+	for name, out := range c.kubeadmOutputs {
+		if out.Len() == 0 {
+			continue
+		}
+		node := strings.TrimPrefix(name, c.Cluster.Name()+"-")
+		path := filepath.Join(dir, action+"-"+node+".log")
+		normalized := kubeadm.NormalizeOutput(out.String(), c.Cluster.Name())
+		if err := os.WriteFile(path, []byte(normalized), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write the kubeadm output for node %s", name)
+		}
+		log.Infof("Kubeadm output for node %s written to %s", name, path)
+	}
+	return nil
+}
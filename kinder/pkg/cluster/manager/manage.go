@@ -17,7 +17,9 @@ limitations under the License.
 package manager
 
 import (
+	"bytes"
 	"fmt"
+	"os"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -30,6 +32,12 @@ import (
 // ClusterManager manages kind(er) clusters
 type ClusterManager struct {
 	*status.Cluster
+
+	// clusters resolves node selectors targeting other clusters, e.g. cluster2/@cp1
+	clusters *status.ClusterResolver
+
+	// kubeadmOutputs collects the output of the kubeadm init and join commands, by node name, if requested
+	kubeadmOutputs map[string]*bytes.Buffer
 }
 
 // NewClusterManager returns a new cluster manager ready to manage
@@ -61,7 +69,8 @@ func NewClusterManager(clusterName string) (c *ClusterManager, err error) {
 	}
 
 	return &ClusterManager{
-		Cluster: x,
+		Cluster:  x,
+		clusters: status.NewClusterResolver(x),
 	}, nil
 }
 
@@ -89,6 +98,75 @@ func (c *ClusterManager) OnlyNode(node string) error {
 	return nil
 }
 
+// IncludeIneligible instruct the cluster manager to run commands also on nodes excluded from actions
+// by the io.x-k8s.kinder.ineligible=true docker label or by the /kinder/ineligible file
+func (c *ClusterManager) IncludeIneligible() {
+	for _, n := range c.Cluster.AllNodes() {
+		if n.ExcludedFromActions() {
+			log.Infof("Including node %s, excluded from actions, because of --include-ineligible", n.Name())
+			n.IncludeInActions()
+		}
+	}
+}
+
+// BootstrapControlPlane instruct the cluster manager to use the control-plane node with the given name as
+// bootstrap control plane, overriding the io.x-k8s.kinder.bootstrap-control-plane=true docker label
+func (c *ClusterManager) BootstrapControlPlane(name string) error {
+	if err := c.Cluster.SetBootstrapControlPlane(name); err != nil {
+		return err
+	}
+	log.Infof("Using node %s as bootstrap control plane, because of --bootstrap-control-plane", name)
+	return nil
+}
+
+// copiedKubeadmBinaryPath defines the path on the nodes where CopyKubeadmBinary copies the kubeadm binary
+const copiedKubeadmBinaryPath = "/kinder/bin/kubeadm"
+
+// KubeadmBinary instruct the cluster manager to use the kubeadm binary at the given path on the nodes
+// for kubeadm commands, instead of the kubeadm binary in the PATH
+func (c *ClusterManager) KubeadmBinary(path string) {
+	for _, n := range c.Cluster.K8sNodes() {
+		n.KubeadmBinary(path)
+	}
+}
+
+// CopyKubeadmBinary copies a kubeadm binary from the host to the K8s nodes eligible for actions, and instructs
+// the cluster manager to use it for kubeadm commands, e.g. for testing a locally-built kubeadm
+// against an existing cluster without rebuilding the node image
+func (c *ClusterManager) CopyKubeadmBinary(hostPath string) error {
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the kubeadm binary %s", hostPath)
+	}
+	if info.IsDir() {
+		return errors.Errorf("the kubeadm binary %s is a directory", hostPath)
+	}
+
+	for _, n := range c.Cluster.K8sNodes().EligibleForActions() {
+		if err := copyKubeadmBinaryToNode(n, hostPath); err != nil {
+			return err
+		}
+	}
+	c.KubeadmBinary(copiedKubeadmBinaryPath)
+	return nil
+}
+
+func copyKubeadmBinaryToNode(n *status.Node, hostPath string) error {
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the kubeadm binary %s", hostPath)
+	}
+	defer f.Close()
+
+	n.Infof("copying %s to %s", hostPath, copiedKubeadmBinaryPath)
+	if err := n.Command(
+		"sh", "-c", `mkdir -p "$(dirname "$1")" && cat > "$1" && chmod 0755 "$1"`, "sh", copiedKubeadmBinaryPath,
+	).Stdin(f).Silent().Run(); err != nil {
+		return errors.Wrapf(err, "failed to copy the kubeadm binary to node %s", n.Name())
+	}
+	return nil
+}
+
 // DoAction actions on kind(er) cluster
 // Actions are repetitive, high level workflows composed
 // by one or more lower level commands
@@ -97,9 +175,10 @@ func (c *ClusterManager) DoAction(action string, options ...actions.Option) erro
 	return actions.Run(c.Cluster, action, options...)
 }
 
-// ExecCommand is a topology aware wrapper of docker exec
+// ExecCommand is a topology aware wrapper of docker exec; the node selector can target
+// another cluster using the clustername/ prefix
 func (c *ClusterManager) ExecCommand(nodeSelector string, args []string) error {
-	nodes, err := c.SelectNodes(nodeSelector)
+	nodes, err := c.clusters.SelectNodes(nodeSelector)
 	if err != nil {
 		return err
 	}
@@ -121,14 +200,15 @@ func (c *ClusterManager) ExecCommand(nodeSelector string, args []string) error {
 	return nil
 }
 
-// CopyFile is a topology aware wrapper of docker cp
+// CopyFile is a topology aware wrapper of docker cp; node selectors can target
+// another cluster using the clustername/ prefix
 func (c *ClusterManager) CopyFile(source, target string) error {
-	sourceNodes, sourcePath, err := c.ResolveNodesPath(source)
+	sourceNodes, sourcePath, err := c.clusters.ResolveNodesPath(source)
 	if err != nil {
 		return err
 	}
 
-	targetNodes, targetPath, err := c.ResolveNodesPath(target)
+	targetNodes, targetPath, err := c.clusters.ResolveNodesPath(target)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// OrphanContainers returns the containers named like the nodes of a cluster but without the cluster label,
+// that are left behind when deleting the cluster; if remove is set, the containers are deleted
+// together with their volumes, and the deleted containers are returned
+func OrphanContainers(clusterName string, remove bool) ([]string, error) {
+	orphans, err := status.ListOrphanContainers(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if !remove {
+		return orphans, nil
+	}
+
+	for _, name := range orphans {
+		if err := exec.NewHostCmd(
+			"docker",
+			"rm",
+			"-f", // force the container to be deleted now
+			"-v", // delete volumes
+			name,
+		).Run(); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete container %s", name)
+		}
+	}
+	return orphans, nil
+}
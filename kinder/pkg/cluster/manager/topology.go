@@ -0,0 +1,261 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	// TopologyKind is the kind of a kinder topology spec file
+	TopologyKind = "Topology"
+	// TopologyAPIVersion is the API version of a kinder topology spec file
+	TopologyAPIVersion = "kinder.x-k8s.io/v1alpha1"
+)
+
+// Topology defines a kinder cluster topology, as read from a topology spec file; it is an alternative
+// to the create cluster flags defining roles, counts, images, labels, taints and networking, for
+// reproducible, version-controllable cluster definitions
+type Topology struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+
+	// Image is the node image used for the K8s nodes
+	Image string `json:"image,omitempty"`
+
+	ControlPlane ControlPlaneTopology `json:"controlPlane,omitempty"`
+	Workers      WorkersTopology      `json:"workers,omitempty"`
+
+	ExternalLoadBalancer bool                  `json:"externalLoadBalancer,omitempty"`
+	LoadBalancer         string                `json:"loadBalancer,omitempty"`
+	ExternalEtcd         *ExternalEtcdTopology `json:"externalEtcd,omitempty"`
+	ExternalRegistry     bool                  `json:"externalRegistry,omitempty"`
+
+	Networking NetworkingTopology `json:"networking,omitempty"`
+
+	// Nodes defines settings for single K8s nodes, by node container name
+	Nodes []NodeTopology `json:"nodes,omitempty"`
+}
+
+// ControlPlaneTopology defines the control-plane nodes of a cluster topology
+type ControlPlaneTopology struct {
+	// Count is the number of control-plane nodes; it defaults to 1
+	Count *int `json:"count,omitempty"`
+	// Bootstrap is the number of the control-plane node where kubeadm init is executed, starting from 1
+	Bootstrap    int                           `json:"bootstrap,omitempty"`
+	Resources    status.NodeResources          `json:"resources,omitempty"`
+	ExtraArgs    ControlPlaneExtraArgsTopology `json:"extraArgs,omitempty"`
+	APIServerTLS APIServerTLSTopology          `json:"apiServerTLS,omitempty"`
+}
+
+// APIServerTLSTopology defines the TLS min version and the cipher suites of the API server
+type APIServerTLSTopology struct {
+	MinVersion   string   `json:"minVersion,omitempty"`
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+}
+
+// ControlPlaneExtraArgsTopology defines the extra args for the control-plane components, in the name=value form
+type ControlPlaneExtraArgsTopology struct {
+	APIServer         []string `json:"apiServer,omitempty"`
+	ControllerManager []string `json:"controllerManager,omitempty"`
+	Scheduler         []string `json:"scheduler,omitempty"`
+}
+
+// WorkersTopology defines the worker nodes of a cluster topology
+type WorkersTopology struct {
+	Count     int                  `json:"count,omitempty"`
+	Labels    []string             `json:"labels,omitempty"`
+	Taints    []string             `json:"taints,omitempty"`
+	Resources status.NodeResources `json:"resources,omitempty"`
+	// Pools defines groups of worker nodes, in addition to Count workers
+	Pools []WorkerPoolTopology `json:"pools,omitempty"`
+}
+
+// WorkerPoolTopology defines a group of worker nodes with the same labels and taints
+type WorkerPoolTopology struct {
+	Name   string   `json:"name"`
+	Count  int      `json:"count"`
+	Labels []string `json:"labels,omitempty"`
+	Taints []string `json:"taints,omitempty"`
+}
+
+// ExternalEtcdTopology defines the external etcd node of a cluster topology
+type ExternalEtcdTopology struct {
+	Image   string `json:"image,omitempty"`
+	DataDir string `json:"dataDir,omitempty"`
+	Nodes   int    `json:"nodes,omitempty"`
+}
+
+// NetworkingTopology defines the networking settings of a cluster topology
+type NetworkingTopology struct {
+	Network           string `json:"network,omitempty"`
+	APIServerBindPort int32  `json:"apiServerBindPort,omitempty"`
+	// ExtraPortMappings are in the containerPort:hostPort[:protocol] form
+	ExtraPortMappings      []string    `json:"extraPortMappings,omitempty"`
+	ExtraPortMappingsRoles []string    `json:"extraPortMappingsRoles,omitempty"`
+	DNS                    DNSTopology `json:"dns,omitempty"`
+}
+
+// DNSTopology defines the DNS settings of the nodes in a cluster topology
+type DNSTopology struct {
+	Servers []string `json:"servers,omitempty"`
+	Search  []string `json:"search,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// NodeTopology defines settings for a single K8s node
+type NodeTopology struct {
+	// Name is the name of the node container, e.g. kind-worker-1
+	Name string `json:"name"`
+	// NodeName is the Kubernetes node name, if different from the container name
+	NodeName string `json:"nodeName,omitempty"`
+	// Command is the container command override
+	Command string `json:"command,omitempty"`
+	// Image is the node image, if different from the cluster node image
+	Image string `json:"image,omitempty"`
+	// IP is the static IPv4 or IPv6 address of the node container; it requires a user defined network
+	IP string `json:"ip,omitempty"`
+}
+
+// LoadTopology reads a topology spec file; unknown fields are rejected, so typos or settings not supported
+// by kinder are not silently ignored
+func LoadTopology(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the topology file %s", path)
+	}
+	return parseTopology(data)
+}
+
+func parseTopology(data []byte) (*Topology, error) {
+	t := &Topology{}
+	if err := yaml.UnmarshalStrict(data, t); err != nil {
+		return nil, errors.Wrap(err, "invalid topology file")
+	}
+	if t.Kind != TopologyKind || t.APIVersion != TopologyAPIVersion {
+		return nil, errors.Errorf("invalid topology file: expected kind %s and apiVersion %s, got %q and %q", TopologyKind, TopologyAPIVersion, t.Kind, t.APIVersion)
+	}
+	return t, nil
+}
+
+// CreateOptions returns the create options corresponding to the topology; options are
+// validated when creating the cluster, as for the create cluster flags
+func (t *Topology) CreateOptions() ([]CreateOption, error) {
+	controlPlanes := 1
+	if t.ControlPlane.Count != nil {
+		controlPlanes = *t.ControlPlane.Count
+	}
+	if controlPlanes < 0 || t.Workers.Count < 0 {
+		return nil, errors.New("invalid topology file: node counts should not be negative numbers")
+	}
+
+	var extraPortMappings []status.PortMapping
+	for _, v := range t.Networking.ExtraPortMappings {
+		p, err := status.ParsePortMapping(v)
+		if err != nil {
+			return nil, err
+		}
+		extraPortMappings = append(extraPortMappings, p)
+	}
+	extraPortMappingsRoles := t.Networking.ExtraPortMappingsRoles
+	if len(extraPortMappingsRoles) == 0 {
+		extraPortMappingsRoles = []string{constants.WorkerNodeRoleValue}
+	}
+
+	apiServerBindPort := t.Networking.APIServerBindPort
+	if apiServerBindPort == 0 {
+		apiServerBindPort = constants.APIServerPort
+	}
+
+	var pools []string
+	for _, p := range t.Workers.Pools {
+		pools = append(pools, p.String())
+	}
+
+	var nodeNames, nodeCommands, nodeImages, nodeIPs []string
+	for _, n := range t.Nodes {
+		if n.Name == "" {
+			return nil, errors.New("invalid topology file: nodes should have a name")
+		}
+		if n.NodeName != "" {
+			nodeNames = append(nodeNames, fmt.Sprintf("%s=%s", n.Name, n.NodeName))
+		}
+		if n.Command != "" {
+			nodeCommands = append(nodeCommands, fmt.Sprintf("%s=%s", n.Name, n.Command))
+		}
+		if n.Image != "" {
+			nodeImages = append(nodeImages, fmt.Sprintf("%s=%s", n.Name, n.Image))
+		}
+		if n.IP != "" {
+			nodeIPs = append(nodeIPs, fmt.Sprintf("%s=%s", n.Name, n.IP))
+		}
+	}
+
+	options := []CreateOption{
+		Image(t.Image),
+		ControlPlanes(controlPlanes),
+		BootstrapControlPlane(t.ControlPlane.Bootstrap),
+		Workers(t.Workers.Count),
+		WorkerLabels(t.Workers.Labels),
+		WorkerTaints(t.Workers.Taints),
+		WorkerPools(pools),
+		ExternalLoadBalancer(t.ExternalLoadBalancer),
+		LoadBalancer(t.LoadBalancer),
+		ExternalEtcd(t.ExternalEtcd != nil),
+		ExternalRegistry(t.ExternalRegistry),
+		Network(t.Networking.Network),
+		APIServerBindPort(apiServerBindPort),
+		ExtraPortMappings(extraPortMappings, extraPortMappingsRoles...),
+		DNS(t.Networking.DNS.Servers, t.Networking.DNS.Search, t.Networking.DNS.Options),
+		Resources(t.ControlPlane.Resources, t.Workers.Resources),
+		ControlPlaneExtraArgs(t.ControlPlane.ExtraArgs.APIServer, t.ControlPlane.ExtraArgs.ControllerManager, t.ControlPlane.ExtraArgs.Scheduler),
+		APIServerTLS(t.ControlPlane.APIServerTLS.MinVersion, t.ControlPlane.APIServerTLS.CipherSuites),
+		NodeNames(nodeNames),
+		NodeCommands(nodeCommands),
+		NodeImages(nodeImages),
+		NodeIPs(nodeIPs),
+	}
+	if t.ExternalEtcd != nil {
+		options = append(options,
+			ExternalEtcdImage(t.ExternalEtcd.Image),
+			ExternalEtcdDataDir(t.ExternalEtcd.DataDir),
+			ExternalEtcdNodes(t.ExternalEtcd.Nodes),
+		)
+	}
+	return options, nil
+}
+
+// String returns the worker pool in the name=NAME;count=N[;labels=key=value,...][;taints=key=value:Effect,...] form
+// used by the --worker-pool flag
+func (p WorkerPoolTopology) String() string {
+	fields := []string{fmt.Sprintf("name=%s", p.Name), fmt.Sprintf("count=%d", p.Count)}
+	if len(p.Labels) > 0 {
+		fields = append(fields, fmt.Sprintf("labels=%s", strings.Join(p.Labels, ",")))
+	}
+	if len(p.Taints) > 0 {
+		fields = append(fields, fmt.Sprintf("taints=%s", strings.Join(p.Taints, ",")))
+	}
+	return strings.Join(fields, ";")
+}
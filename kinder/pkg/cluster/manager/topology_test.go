@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manager
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
+)
+
+func TestParseTopology(t *testing.T) {
+	cases := []struct {
+		name          string
+		data          string
+		expectedError bool
+	}{
+		{
+			name: "valid topology",
+			data: "kind: Topology\napiVersion: kinder.x-k8s.io/v1alpha1\ncontrolPlane:\n  count: 3\n",
+		},
+		{
+			name:          "invalid kind",
+			data:          "kind: Cluster\napiVersion: kind.x-k8s.io/v1alpha4\n",
+			expectedError: true,
+		},
+		{
+			name:          "unknown field",
+			data:          "kind: Topology\napiVersion: kinder.x-k8s.io/v1alpha1\nnodes:\n- name: kind-worker-1\n  extraMounts: [/tmp]\n",
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseTopology([]byte(c.data))
+			if (err != nil) != c.expectedError {
+				t.Fatalf("expected error: %v, got: %v", c.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestTopologyCreateOptions(t *testing.T) {
+	topology, err := parseTopology([]byte(`kind: Topology
+apiVersion: kinder.x-k8s.io/v1alpha1
+image: kindest/node:v1.30.0
+controlPlane:
+  count: 3
+  bootstrap: 2
+  resources:
+    cpus: "2"
+  apiServerTLS:
+    minVersion: VersionTLS12
+    cipherSuites: [TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256]
+workers:
+  count: 1
+  labels: [disktype=ssd]
+  pools:
+  - name: gpu
+    count: 2
+    labels: [accelerator=nvidia]
+    taints: [gpu=true:NoSchedule]
+externalLoadBalancer: true
+loadBalancer: nginx
+externalEtcd:
+  image: v3.5.15
+networking:
+  network: kinder
+  extraPortMappings: ["30080:8080"]
+  dns:
+    servers: [8.8.8.8]
+nodes:
+- name: kind-worker-1
+  nodeName: ssd-node
+  image: kindest/node:v1.29.0
+  ip: 172.30.0.21
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	options, err := topology.CreateOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flags := &CreateOptions{}
+	for _, o := range options {
+		o(flags)
+	}
+
+	if flags.image != "kindest/node:v1.30.0" || flags.controlPlanes != 3 || flags.bootstrapControlPlane != 2 || flags.workers != 1 {
+		t.Errorf("unexpected image or node counts: %q, %d, %d, %d", flags.image, flags.controlPlanes, flags.bootstrapControlPlane, flags.workers)
+	}
+	if !flags.externalLoadBalancer || flags.loadBalancer != "nginx" || !flags.externalEtcd || flags.externalEtcdImage != "v3.5.15" {
+		t.Errorf("unexpected external nodes: %t, %q, %t, %q", flags.externalLoadBalancer, flags.loadBalancer, flags.externalEtcd, flags.externalEtcdImage)
+	}
+	if expected := []string{"name=gpu;count=2;labels=accelerator=nvidia;taints=gpu=true:NoSchedule"}; !reflect.DeepEqual(flags.workerPools, expected) {
+		t.Errorf("expected worker pools %v, got %v", expected, flags.workerPools)
+	}
+	if expected := []string{"kind-worker-1=ssd-node"}; !reflect.DeepEqual(flags.nodeNames, expected) {
+		t.Errorf("expected node names %v, got %v", expected, flags.nodeNames)
+	}
+	if expected := []string{"kind-worker-1=kindest/node:v1.29.0"}; !reflect.DeepEqual(flags.nodeImages, expected) {
+		t.Errorf("expected node images %v, got %v", expected, flags.nodeImages)
+	}
+	if expected := []string{"kind-worker-1=172.30.0.21"}; !reflect.DeepEqual(flags.nodeIPs, expected) {
+		t.Errorf("expected node IPs %v, got %v", expected, flags.nodeIPs)
+	}
+	if flags.apiServerBindPort != 6443 || flags.network != "kinder" || len(flags.extraPortMappings) != 1 {
+		t.Errorf("unexpected networking: %d, %q, %v", flags.apiServerBindPort, flags.network, flags.extraPortMappings)
+	}
+	if expected := (status.NodeResources{CPUs: "2"}); flags.controlPlaneResources != expected {
+		t.Errorf("expected control-plane resources %v, got %v", expected, flags.controlPlaneResources)
+	}
+	if expected := []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}; flags.tlsMinVersion != "VersionTLS12" || !reflect.DeepEqual(flags.tlsCipherSuites, expected) {
+		t.Errorf("unexpected API server TLS settings: %q, %v", flags.tlsMinVersion, flags.tlsCipherSuites)
+	}
+}
+
+func TestTopologyCreateOptionsDefaults(t *testing.T) {
+	topology, err := parseTopology([]byte("kind: Topology\napiVersion: kinder.x-k8s.io/v1alpha1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	options, err := topology.CreateOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	flags := &CreateOptions{}
+	for _, o := range options {
+		o(flags)
+	}
+	if flags.controlPlanes != 1 || flags.workers != 0 || flags.externalEtcd {
+		t.Errorf("unexpected defaults: %d control planes, %d workers, external etcd %t", flags.controlPlanes, flags.workers, flags.externalEtcd)
+	}
+	if expected := []string{"worker"}; !reflect.DeepEqual(flags.extraPortMappingsRoles, expected) {
+		t.Errorf("expected extra port mappings roles %v, got %v", expected, flags.extraPortMappingsRoles)
+	}
+}
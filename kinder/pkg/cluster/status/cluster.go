@@ -18,8 +18,14 @@ package status
 
 import (
 	"fmt"
+	"net"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -28,6 +34,7 @@ import (
 
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 )
 
 // Cluster represents an existing kind(er) clusters
@@ -38,8 +45,9 @@ type Cluster struct {
 	k8sNodes             NodeList
 	controlPlanes        NodeList
 	workers              NodeList
-	externalEtcd         *Node
+	externalEtcds        NodeList
 	externalLoadBalancer *Node
+	externalRegistry     *Node
 }
 
 // ClusterSettings defines a set of settings that will be stored in the cluster and re-used
@@ -52,6 +60,46 @@ type ClusterSettings struct {
 	// kind configuration settings that are used to configure the cluster when
 	// generating the kubeadm config file.
 	IPFamily ClusterIPFamily `json:"ipFamily,omitempty"`
+
+	// APIServerBindPort defines the port the API server binds to on the control-plane nodes;
+	// when not set, the default API server port is used.
+	APIServerBindPort int32 `json:"apiServerBindPort,omitempty"`
+
+	// SkipKubeProxy defines if the kube-proxy addon should not be installed, e.g. when testing a CNI
+	// replacing kube-proxy; this is preserved across upgrades.
+	SkipKubeProxy bool `json:"skipKubeProxy,omitempty"`
+
+	// KubeProxyMode defines the kube-proxy mode; when not set, the kube-proxy default mode is used.
+	KubeProxyMode string `json:"kubeProxyMode,omitempty"`
+
+	// NodeCIDRMaskSize and NodeCIDRMaskSizeIPv6 define the size of the pod CIDR allocated to each node;
+	// when not set, the kube-controller-manager defaults are used.
+	NodeCIDRMaskSize     int `json:"nodeCIDRMaskSize,omitempty"`
+	NodeCIDRMaskSizeIPv6 int `json:"nodeCIDRMaskSizeIPv6,omitempty"`
+
+	// CNI defines the CNI plugin installed after init, as the name of a known plugin or a custom manifest;
+	// when not set, kindnet is installed.
+	CNI string `json:"cni,omitempty"`
+
+	// KubeadmClusterName defines the cluster name set in the kubeadm ClusterConfiguration, and thus in the
+	// kubeconfig files generated by kubeadm; when not set, the kinder cluster name is used.
+	KubeadmClusterName string `json:"kubeadmClusterName,omitempty"`
+
+	// EtcdExtraArgs defines the etcd extra args for the backend quota and auto-compaction, in the key=value form;
+	// when not set, the etcd defaults are used.
+	EtcdExtraArgs []string `json:"etcdExtraArgs,omitempty"`
+
+	// ExternalEtcdEndpoints defines the client endpoints of an external etcd not managed by kinder; when the
+	// endpoints use TLS, the etcd client certificates are copied on the control-plane nodes at create time.
+	ExternalEtcdEndpoints []string `json:"externalEtcdEndpoints,omitempty"`
+
+	// KubeletServingCertRotation defines if the kubelet serving certificates are signed by the controller manager
+	// via TLS bootstrap, and thus rotated, instead of being self-signed by the kubelet.
+	KubeletServingCertRotation bool `json:"kubeletServingCertRotation,omitempty"`
+
+	// NodeSysctls defines the sysctls applied on the K8s nodes before the kubelet starts, indexed by key;
+	// sysctls are applied again when the nodes are restarted.
+	NodeSysctls map[string]string `json:"nodeSysctls,omitempty"`
 }
 
 // ClusterIPFamily defines cluster network IP family
@@ -62,12 +110,13 @@ const (
 	IPv4Family ClusterIPFamily = "ipv4"
 	// IPv6Family sets ClusterIPFamily to ipv6
 	IPv6Family ClusterIPFamily = "ipv6"
+	// DualStackFamily sets ClusterIPFamily to dualstack, with IPv4 as the primary family
+	DualStackFamily ClusterIPFamily = "dualstack"
 )
 
 // ListClusters is part of the providers.Provider interface
 func ListClusters() ([]string, error) {
-	cmd := exec.NewHostCmd("docker",
-		"ps",
+	lines, err := dockerPS(
 		"-a",         // show stopped nodes
 		"--no-trunc", // don't truncate
 		// filter for nodes with the cluster label
@@ -75,13 +124,133 @@ func ListClusters() ([]string, error) {
 		// format to include the cluster name
 		"--format", fmt.Sprintf(`{{.Label "%s"}}`, constants.DeprecatedClusterLabelKey),
 	)
-	lines, err := cmd.RunAndCapture()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to list clusters: %s", lines)
 	}
 	return sets.NewString(lines...).List(), nil
 }
 
+// ClusterInfo defines the metadata of a cluster, as recorded by docker labels on the K8s nodes at create time;
+// metadata are empty for clusters created by older versions of kinder
+type ClusterInfo struct {
+	Name              string          `json:"name"`
+	IPFamily          ClusterIPFamily `json:"ipFamily,omitempty"`
+	KubernetesVersion string          `json:"kubernetesVersion,omitempty"`
+	Nodes             int             `json:"nodes"`
+}
+
+// ListClustersDetailed returns the existing clusters with their metadata; metadata are read from docker
+// labels with a single docker ps, without executing commands in the nodes
+func ListClustersDetailed() ([]ClusterInfo, error) {
+	lines, err := dockerPS(
+		"-a",         // show stopped nodes
+		"--no-trunc", // don't truncate
+		// filter for nodes with the cluster label
+		"--filter", "label="+constants.DeprecatedClusterLabelKey,
+		// format to include the cluster name and the cluster metadata
+		"--format", fmt.Sprintf(`{{.Label "%s"}}\t{{.Label "%s"}}\t{{.Label "%s"}}`,
+			constants.DeprecatedClusterLabelKey, constants.IPFamilyLabelKey, constants.KubernetesVersionLabelKey),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list clusters: %s", lines)
+	}
+	return parseClusterInfos(lines), nil
+}
+
+// parseClusterInfos aggregates by cluster the docker ps lines, with the cluster name and metadata of each
+// node separated by tabs; metadata are taken from the first node having them, because only K8s nodes
+// have metadata. Clusters are sorted by name.
+func parseClusterInfos(lines []string) []ClusterInfo {
+	byName := map[string]*ClusterInfo{}
+	var names []string
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if fields[0] == "" {
+			continue
+		}
+		info, ok := byName[fields[0]]
+		if !ok {
+			info = &ClusterInfo{Name: fields[0]}
+			byName[fields[0]] = info
+			names = append(names, fields[0])
+		}
+		info.Nodes++
+		if len(fields) > 1 && info.IPFamily == "" && fields[1] != "<no value>" {
+			info.IPFamily = ClusterIPFamily(fields[1])
+		}
+		if len(fields) > 2 && info.KubernetesVersion == "" && fields[2] != "<no value>" {
+			info.KubernetesVersion = fields[2]
+		}
+	}
+
+	sort.Strings(names)
+	infos := make([]ClusterInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, *byName[name])
+	}
+	return infos
+}
+
+// ListOrphanContainers returns the containers, also stopped, named like the nodes of a cluster, that is with
+// the cluster name followed by a dash as a prefix, but without the cluster label, e.g. an external etcd
+// created before the label was applied; such containers are not deleted when deleting the cluster
+func ListOrphanContainers(clusterName string) ([]string, error) {
+	clusters, err := ListClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := dockerPS(
+		"-a",         // show stopped containers
+		"--no-trunc", // don't truncate
+		// filter for containers named like the cluster nodes
+		"--filter", fmt.Sprintf("name=^%s-", regexp.QuoteMeta(clusterName)),
+		// format to include the container name and the cluster labels
+		"--format", fmt.Sprintf(`{{.Names}}\t{{.Label "%s"}}\t{{.Label "%s"}}`,
+			constants.ClusterLabelKey, constants.DeprecatedClusterLabelKey),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list containers for cluster %s", clusterName)
+	}
+	return parseOrphanContainers(clusterName, clusters, lines), nil
+}
+
+// parseOrphanContainers returns the container names, from the docker ps lines with the container name and
+// the cluster labels separated by tabs, with the cluster name followed by a dash as a prefix and without cluster
+// labels; containers labeled for another cluster, or named like the nodes of another existing cluster, e.g. kind-2
+// for kind, are not orphans. Names are sorted.
+func parseOrphanContainers(clusterName string, clusters, lines []string) []string {
+	var orphans []string
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if !strings.HasPrefix(fields[0], clusterName+"-") || hasOtherClusterPrefix(fields[0], clusterName, clusters) {
+			continue
+		}
+		labeled := false
+		for _, label := range fields[1:] {
+			if label != "" && label != "<no value>" {
+				labeled = true
+			}
+		}
+		if !labeled {
+			orphans = append(orphans, fields[0])
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// hasOtherClusterPrefix returns true if a container is named like the nodes of another cluster whose name
+// has the cluster name as a prefix, e.g. kind-2-etcd for kind-2 when looking for the containers of kind
+func hasOtherClusterPrefix(name, clusterName string, clusters []string) bool {
+	for _, c := range clusters {
+		if c != clusterName && len(c) > len(clusterName) && strings.HasPrefix(name, c+"-") {
+			return true
+		}
+	}
+	return false
+}
+
 // IsKnown returns true if a cluster exists with the given name.
 // If obtaining the list of known clusters fails the function returns an error.
 func IsKnown(name string) (bool, error) {
@@ -113,31 +282,98 @@ func FromDocker(name string) (c *Cluster, err error) {
 		name: name,
 	}
 
-	log.Debugf("Reading container list for cluster %s", name)
+	if err := c.readNodes(nil); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Refresh re-reads the nodes of the cluster, so the cluster reflects changes to the topology
+// after actions adding or removing nodes; nodes already known are preserved, including
+// the state set on them, e.g. SkipActions. If cluster settings were already read, they are
+// read again and replaced only if they changed; if settings can't be read, the cached ones are kept.
+func (c *Cluster) Refresh() error {
+	known := map[string]*Node{}
+	for _, n := range c.allNodes {
+		known[n.Name()] = n
+	}
+
+	if err := c.readNodes(known); err != nil {
+		return err
+	}
+
+	if c.Settings != nil {
+		cached := c.Settings
+		if err := c.ReadSettings(); err != nil {
+			log.Debugf("Keeping cached cluster settings: %v", err)
+			c.Settings = cached
+			return nil
+		}
+		if reflect.DeepEqual(cached, c.Settings) {
+			c.Settings = cached
+		} else {
+			log.Debugf("Cluster settings changed, using the new settings")
+		}
+	}
+
+	return nil
+}
+
+// readNodes discovers the container nodes of the cluster, and rebuilds the lists of nodes
+// by role; known nodes are reused instead of creating new ones, while new nodes are checked for
+// eligibility. Lists are replaced only if all the nodes are read successfully.
+func (c *Cluster) readNodes(known map[string]*Node) error {
+	log.Debugf("Reading container list for cluster %s", c.name)
 	nodes, err := c.listNodes()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	for _, n := range nodes {
-		log.Debugf("Adding node %s to the cluster", n)
-		node, err := NewNode(n)
-		if err != nil {
-			return nil, err
+	fresh := &Cluster{
+		name: c.name,
+	}
+	for _, line := range nodes {
+		// each line contains the node name and the container ID
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return errors.Errorf("failed to parse node %q", line)
 		}
 
-		if err = c.add(node); err != nil {
-			return nil, err
+		node, ok := known[fields[0]]
+		if !ok {
+			log.Debugf("Adding node %s to the cluster", fields[0])
+			node, err = NewNode(fields[0])
+			if err != nil {
+				return err
+			}
+			if err = node.CheckEligibility(); err != nil {
+				return err
+			}
+		}
+		node.id = fields[1]
+
+		if err = fresh.add(node); err != nil {
+			return err
 		}
 	}
 
 	// ensures nodes are sorted consistently
-	c.allNodes.Sort()
-	c.k8sNodes.Sort()
-	c.controlPlanes.Sort()
-	c.workers.Sort()
+	fresh.allNodes.Sort()
+	fresh.k8sNodes.Sort()
+	fresh.controlPlanes.Sort()
+	fresh.workers.Sort()
+	fresh.externalEtcds.Sort()
 
-	return c, nil
+	c.allNodes = fresh.allNodes
+	c.k8sNodes = fresh.k8sNodes
+	c.controlPlanes = fresh.controlPlanes
+	c.workers = fresh.workers
+	c.externalEtcds = fresh.externalEtcds
+	c.externalLoadBalancer = fresh.externalLoadBalancer
+	c.externalRegistry = fresh.externalRegistry
+
+	return nil
 }
 
 // Name returns the cluster's name
@@ -157,46 +393,226 @@ func (c *Cluster) KubeConfigPath() string {
 
 // ListNodes is part of the providers.Provider interface
 func (c *Cluster) listNodes() ([]string, error) {
-	cmd := exec.NewHostCmd("docker",
-		"ps",
+	nodes, err := dockerPS(
 		"-a",         // show stopped nodes
 		"--no-trunc", // don't truncate
 		// filter for nodes with the cluster label
 		"--filter", fmt.Sprintf("label=%s=%s", constants.DeprecatedClusterLabelKey, c.name),
-		// format to include the cluster name
-		"--format", `{{.Names}}`,
+		// format to include the node name and the container ID
+		"--format", `{{.Names}} {{.ID}}`,
 	)
-	nodes, err := cmd.RunAndCapture()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to list nodes for cluster %s", c.name)
 	}
 	return nodes, nil
 }
 
-// Validate the cluster has a consistent set of nodes
+const (
+	dockerPSAttempts = 4
+	dockerPSBackoff  = 500 * time.Millisecond
+)
+
+// dockerPS runs docker ps with the given args; in case of connection errors, e.g. when the docker
+// daemon is reloading, the command is retried a few times with an increasing backoff.
+func dockerPS(args ...string) ([]string, error) {
+	var lines []string
+	var err error
+	for attempt := 1; attempt <= dockerPSAttempts; attempt++ {
+		lines, err = exec.NewHostCmd("docker", append([]string{"ps"}, args...)...).RunAndCapture()
+		if err == nil {
+			return lines, nil
+		}
+		if !isDockerConnectionError(lines) {
+			return lines, err
+		}
+		if attempt < dockerPSAttempts {
+			log.Debugf("Failed to connect to the docker daemon (attempt %d/%d), retrying...", attempt, dockerPSAttempts)
+			time.Sleep(time.Duration(attempt) * dockerPSBackoff)
+		}
+	}
+	return lines, errors.Wrapf(err, "failed to connect to the docker daemon after %d attempts: %s", dockerPSAttempts, strings.Join(lines, "\n"))
+}
+
+// isDockerConnectionError returns true if the output of a docker command reports
+// that the client failed to connect to the docker daemon
+func isDockerConnectionError(lines []string) bool {
+	for _, l := range lines {
+		if strings.Contains(l, "Cannot connect to the Docker daemon") ||
+			strings.Contains(l, "error during connect") {
+			return true
+		}
+	}
+	return false
+}
+
+// TopologyRule identifies a rule the set of nodes of a cluster should satisfy
+type TopologyRule string
+
+const (
+	// RuleControlPlaneRequired requires at least one node with the control-plane role
+	RuleControlPlaneRequired TopologyRule = "control-plane-required"
+
+	// RuleLoadBalancerRequired requires a node with the external load balancer role if there are
+	// more than one node with the control-plane role
+	RuleLoadBalancerRequired TopologyRule = "load-balancer-required"
+
+	// RuleSingleBootstrapControlPlane requires at most one node designated as bootstrap control plane
+	RuleSingleBootstrapControlPlane TopologyRule = "single-bootstrap-control-plane"
+)
+
+// TopologyError reports the rule violated by the set of nodes of a cluster
+type TopologyError struct {
+	Rule    TopologyRule
+	Message string
+}
+
+// Error returns the error message, including the rule violated
+func (e *TopologyError) Error() string {
+	return fmt.Sprintf("invalid cluster topology (rule %s): %s", e.Rule, e.Message)
+}
+
+// Validate the cluster has a consistent set of nodes; if not, a *TopologyError reporting the rule violated is returned
 func (c *Cluster) Validate() error {
 
 	// There should be at least one control plane
 	if c.BootstrapControlPlane() == nil {
-		return errors.Errorf("please add at least one node with role %q", constants.ControlPlaneNodeRoleValue)
+		return &TopologyError{
+			Rule:    RuleControlPlaneRequired,
+			Message: fmt.Sprintf("please add at least one node with role %q", constants.ControlPlaneNodeRoleValue),
+		}
+	}
+	// There should be at most one node designated as bootstrap control plane
+	var bootstrap []string
+	for _, n := range c.ControlPlanes() {
+		if n.bootstrap {
+			bootstrap = append(bootstrap, n.Name())
+		}
+	}
+	if len(bootstrap) > 1 {
+		return &TopologyError{
+			Rule: RuleSingleBootstrapControlPlane,
+			Message: fmt.Sprintf("nodes %s are all designated as bootstrap control plane by the %s=true label; please label only one node",
+				strings.Join(bootstrap, ", "), constants.BootstrapControlPlaneLabelKey),
+		}
 	}
 	// There should be one load balancer if more than one control plane exists in the cluster
 	if len(c.ControlPlanes()) > 1 && c.ExternalLoadBalancer() == nil {
-		return errors.Errorf("please add a node with role %s because in the cluster there are more than one node with role %s",
-			constants.ExternalLoadBalancerNodeRoleValue, constants.ControlPlaneNodeRoleValue)
+		return &TopologyError{
+			Rule: RuleLoadBalancerRequired,
+			Message: fmt.Sprintf("please add a node with role %s because in the cluster there are more than one node with role %s",
+				constants.ExternalLoadBalancerNodeRoleValue, constants.ControlPlaneNodeRoleValue),
+		}
 	}
 
 	return nil
 }
 
-// ReadSettings read cluster settings from a control plane node
-func (c *Cluster) ReadSettings() (err error) {
+// ReadSettings read cluster settings from the first running K8s node having settings;
+// given that settings are written on all the K8s nodes, this makes settings retrieval
+// resilient to nodes being down, e.g. the bootstrap control plane.
+func (c *Cluster) ReadSettings() error {
 	log.Debug("Reading cluster settings...")
-	c.Settings, err = c.BootstrapControlPlane().ReadClusterSettings()
-	if err != nil {
-		return errors.Wrapf(err, "failed to read cluster settings from node %s", c.BootstrapControlPlane().name)
+	var errs []string
+	for _, n := range c.K8sNodes() {
+		running, err := n.IsRunning()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !running {
+			log.Debugf("Skipping node %s because it is not running", n.name)
+			errs = append(errs, fmt.Sprintf("node %s is not running", n.name))
+			continue
+		}
+
+		settings, err := n.ReadClusterSettings()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to read cluster settings from node %s: %v", n.name, err))
+			continue
+		}
+
+		// the API server bind port is recorded on control-plane nodes only, and it can be
+		// read also when the node is not running
+		if settings.APIServerBindPort == 0 && c.BootstrapControlPlane() != nil {
+			port, err := c.BootstrapControlPlane().APIServerBindPort()
+			if err != nil {
+				return err
+			}
+			settings.APIServerBindPort = port
+		}
+
+		// the kube-proxy settings are recorded on control-plane nodes only, and they can be
+		// read also when the node is not running
+		if !settings.SkipKubeProxy && settings.KubeProxyMode == "" && c.BootstrapControlPlane() != nil {
+			kubeProxy, err := c.BootstrapControlPlane().KubeProxy()
+			if err != nil {
+				return err
+			}
+			if kubeProxy == constants.KubeProxyDisabled {
+				settings.SkipKubeProxy = true
+			} else {
+				settings.KubeProxyMode = kubeProxy
+			}
+		}
+
+		// the node CIDR mask sizes are recorded on control-plane nodes only, and they can be
+		// read also when the node is not running
+		if settings.NodeCIDRMaskSize == 0 && settings.NodeCIDRMaskSizeIPv6 == 0 && c.BootstrapControlPlane() != nil {
+			if settings.NodeCIDRMaskSize, settings.NodeCIDRMaskSizeIPv6, err = c.BootstrapControlPlane().NodeCIDRMaskSize(); err != nil {
+				return err
+			}
+		}
+
+		// the CNI plugin is recorded on control-plane nodes only, and it can be read also when the node is not running
+		if settings.CNI == "" && c.BootstrapControlPlane() != nil {
+			if settings.CNI, err = c.BootstrapControlPlane().CNI(); err != nil {
+				return err
+			}
+		}
+
+		// the kubeadm cluster name is recorded on control-plane nodes only, and it can be read also when
+		// the node is not running
+		if settings.KubeadmClusterName == "" && c.BootstrapControlPlane() != nil {
+			if settings.KubeadmClusterName, err = c.BootstrapControlPlane().KubeadmClusterName(); err != nil {
+				return err
+			}
+		}
+
+		// the etcd extra args are recorded on control-plane nodes only, and they can be read also when
+		// the node is not running
+		if len(settings.EtcdExtraArgs) == 0 && c.BootstrapControlPlane() != nil {
+			if settings.EtcdExtraArgs, err = c.BootstrapControlPlane().EtcdExtraArgs(); err != nil {
+				return err
+			}
+		}
+
+		// the external etcd endpoints are recorded on control-plane nodes only, and they can be read also when
+		// the node is not running
+		if len(settings.ExternalEtcdEndpoints) == 0 && c.BootstrapControlPlane() != nil {
+			if settings.ExternalEtcdEndpoints, err = c.BootstrapControlPlane().ExternalEtcdEndpoints(); err != nil {
+				return err
+			}
+		}
+
+		// the kubelet serving certificate rotation is recorded on control-plane nodes only, and it can be read
+		// also when the node is not running
+		if !settings.KubeletServingCertRotation && c.BootstrapControlPlane() != nil {
+			if settings.KubeletServingCertRotation, err = c.BootstrapControlPlane().KubeletServingCertRotation(); err != nil {
+				return err
+			}
+		}
+
+		// the node sysctls are recorded on all the K8s nodes, and they can be read also when the node is not running
+		if len(settings.NodeSysctls) == 0 && c.BootstrapControlPlane() != nil {
+			if settings.NodeSysctls, err = c.BootstrapControlPlane().NodeSysctls(); err != nil {
+				return err
+			}
+		}
+
+		c.Settings = settings
+		return nil
 	}
-	return nil
+	return errors.Errorf("failed to read cluster settings from any node: %s", strings.Join(errs, "; "))
 }
 
 // WriteSettings writes cluster settings nodes
@@ -227,10 +643,7 @@ func (c *Cluster) add(node *Node) error {
 	}
 
 	if node.IsExternalEtcd() {
-		if c.externalEtcd != nil {
-			return errors.Errorf("unable to add the node to the cluster. A cluster can not have more than one node with role %q", constants.ExternalEtcdNodeRoleValue)
-		}
-		c.externalEtcd = node
+		c.externalEtcds = append(c.externalEtcds, node)
 	}
 
 	if node.IsExternalLoadBalancer() {
@@ -240,10 +653,17 @@ func (c *Cluster) add(node *Node) error {
 		c.externalLoadBalancer = node
 	}
 
+	if node.IsExternalRegistry() {
+		if c.externalRegistry != nil {
+			return errors.Errorf("unable to add the node to the cluster. A cluster can not have more than one node role %q", constants.ExternalRegistryNodeRoleValue)
+		}
+		c.externalRegistry = node
+	}
+
 	return nil
 }
 
-// AllNodes returns all the nodes in the cluster (including K8s nodes, external loadbalancer and external etcd)
+// AllNodes returns all the nodes in the cluster (including K8s nodes, external loadbalancer, external etcd and external registry)
 func (c *Cluster) AllNodes() NodeList {
 	return c.allNodes
 }
@@ -258,8 +678,8 @@ func (c *Cluster) ControlPlanes() NodeList {
 	return c.controlPlanes
 }
 
-// BootstrapControlPlane returns the first node with control-plane role.
-// This is the node where kubeadm init will be executed.
+// BootstrapControlPlane returns the node designated as bootstrap control plane, if any, otherwise
+// the first node with control-plane role. This is the node where kubeadm init will be executed.
 func (c *Cluster) BootstrapControlPlane() *Node {
 	if len(c.controlPlanes) == 0 {
 		return nil
@@ -267,6 +687,33 @@ func (c *Cluster) BootstrapControlPlane() *Node {
 	return c.controlPlanes[0]
 }
 
+// SetBootstrapControlPlane designates the control-plane node with the given name as bootstrap control plane,
+// overriding the BootstrapControlPlaneLabelKey docker label; if name is empty, the first node with
+// control-plane role is used.
+func (c *Cluster) SetBootstrapControlPlane(name string) error {
+	if name != "" {
+		found := false
+		for _, n := range c.controlPlanes {
+			if n.Name() == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("node %q is not a control-plane node of the cluster %s", name, c.name)
+		}
+	}
+
+	for _, n := range c.controlPlanes {
+		n.bootstrap = n.Name() == name
+	}
+
+	c.allNodes.Sort()
+	c.k8sNodes.Sort()
+	c.controlPlanes.Sort()
+	return nil
+}
+
 // SecondaryControlPlanes returns all the nodes with control-plane role
 // except the BootstrapControlPlane node, if any,
 func (c *Cluster) SecondaryControlPlanes() NodeList {
@@ -281,9 +728,32 @@ func (c *Cluster) Workers() NodeList {
 	return c.workers
 }
 
-// ExternalEtcd returns the node with external-etcd role, if defined
+// ExternalEtcd returns the first node with external-etcd role, if defined
 func (c *Cluster) ExternalEtcd() *Node {
-	return c.externalEtcd
+	if len(c.externalEtcds) == 0 {
+		return nil
+	}
+	return c.externalEtcds[0]
+}
+
+// UsesExternalEtcd returns true if the cluster uses an external etcd, either managed by kinder or
+// not managed by kinder and defined by its client endpoints at create time
+func (c *Cluster) UsesExternalEtcd() bool {
+	return c.ExternalEtcd() != nil || (c.Settings != nil && len(c.Settings.ExternalEtcdEndpoints) > 0)
+}
+
+// ExternalEtcds returns all the nodes with external-etcd role, if defined
+func (c *Cluster) ExternalEtcds() NodeList {
+	return c.externalEtcds
+}
+
+// Network returns the user defined docker network the cluster nodes are connected to, as defined at create time;
+// an empty string is returned for clusters using the default network
+func (c *Cluster) Network() (string, error) {
+	if len(c.allNodes) == 0 {
+		return "", nil
+	}
+	return c.allNodes[0].Network()
 }
 
 // ExternalLoadBalancer returns the node with external-load-balancer role, if defined
@@ -291,40 +761,167 @@ func (c *Cluster) ExternalLoadBalancer() *Node {
 	return c.externalLoadBalancer
 }
 
+// LoadBalancerBackends returns the status of the API server backends of the external load balancer,
+// as reported by the load balancer health checks
+func (c *Cluster) LoadBalancerBackends() ([]loadbalancer.BackendServer, error) {
+	lb := c.externalLoadBalancer
+	if lb == nil {
+		return nil, errors.New("the cluster does not have an external load balancer")
+	}
+	implementation, err := c.LoadBalancerImplementation()
+	if err != nil {
+		return nil, err
+	}
+	provider, err := loadbalancer.NewProvider(implementation)
+	if err != nil {
+		return nil, err
+	}
+	backends, err := provider.Backends(func(command string, args ...string) ([]string, error) {
+		return lb.Command(command, args...).Silent().RunAndCapture()
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the load balancer status from node %s. Please ensure the load balancer config is up to date by running the loadbalancer action", lb.Name())
+	}
+	return backends, nil
+}
+
+// LoadBalancerImplementation returns the implementation of the external load balancer, as selected at create time;
+// load balancers created before the implementation could be selected are reported as the default implementation
+func (c *Cluster) LoadBalancerImplementation() (loadbalancer.Implementation, error) {
+	lb := c.externalLoadBalancer
+	if lb == nil {
+		return "", errors.New("the cluster does not have an external load balancer")
+	}
+	name, err := inspectLabel(lb.Name(), constants.LoadBalancerLabelKey)
+	if err != nil {
+		return "", err
+	}
+	return loadbalancer.ParseImplementation(name)
+}
+
+// ExternalRegistry returns the node with external-registry role, if defined
+func (c *Cluster) ExternalRegistry() *Node {
+	return c.externalRegistry
+}
+
+// RegistryEndpoint returns the http://ip:port address of the external registry, to be used
+// by the K8s nodes as a registry mirror; an empty string is returned if there is no external registry.
+func (c *Cluster) RegistryEndpoint() (string, error) {
+	if c.externalRegistry == nil {
+		return "", nil
+	}
+	ipv4, _, err := c.externalRegistry.IP()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get IP for node: %s", c.externalRegistry.Name())
+	}
+	return fmt.Sprintf("http://%s:%d", ipv4, constants.RegistryPort), nil
+}
+
+// APIServerEndpoint returns the host:port address to be used for reaching the API server from
+// the nodes in the cluster; this is the external load balancer, if any, or the bootstrap control-plane node.
+func (c *Cluster) APIServerEndpoint() (string, error) {
+	n, port := c.apiServerNode()
+	if n == nil {
+		return "", errors.New("unable to identify a node exposing the API server")
+	}
+
+	ipv4, ipv6, err := n.IP()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+	}
+
+	host := ipv4
+	if c.Settings != nil && c.Settings.IPFamily == IPv6Family {
+		host = ipv6
+	}
+
+	return net.JoinHostPort(host, fmt.Sprintf("%d", port)), nil
+}
+
+// APIServerHostEndpoint returns the host:port address to be used for reaching the API server from
+// the host machine; this is the random host port mapped to the external load balancer, if any,
+// or to the bootstrap control-plane node.
+func (c *Cluster) APIServerHostEndpoint() (string, error) {
+	n, port := c.apiServerNode()
+	if n == nil {
+		return "", errors.New("unable to identify a node exposing the API server")
+	}
+
+	hostPort, err := n.Ports(port)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the host port for node: %s", n.Name())
+	}
+
+	return net.JoinHostPort("localhost", fmt.Sprintf("%d", hostPort)), nil
+}
+
+// APIServerBindPort returns the port the API server binds to on the control-plane nodes
+func (c *Cluster) APIServerBindPort() int32 {
+	if c.Settings != nil && c.Settings.APIServerBindPort != 0 {
+		return c.Settings.APIServerBindPort
+	}
+	return constants.APIServerPort
+}
+
+// apiServerNode returns the node exposing the API server, that is the external load balancer if any,
+// otherwise the bootstrap control-plane node, and the port the API server is exposed on.
+func (c *Cluster) apiServerNode() (*Node, int32) {
+	if c.ExternalLoadBalancer() != nil {
+		return c.ExternalLoadBalancer(), constants.ControlPlanePort
+	}
+	return c.BootstrapControlPlane(), c.APIServerBindPort()
+}
+
 // ResolveNodesPath takes a "topology aware" path and resolve to one (or more) real paths.
 //
 // Topology aware paths are in the form [selector:]path, where a selector is a shortcut for
 // a node or a set of nodes in the cluster. See SelectNodes
 func (c *Cluster) ResolveNodesPath(nodesPath string) (nodes NodeList, path string, err error) {
-	t := strings.Split(nodesPath, ":")
-	switch len(t) {
-	case 1:
-		nodes = nil
-		path = t[0]
-	case 2:
-		nodes, err = c.SelectNodes(t[0])
-		if err != nil {
-			return nil, "", err
-		}
-		path = t[1]
-	default:
-		return nil, "", errors.Errorf("invalid nodesPath %q", nodesPath)
+	selector, path := splitNodesPath(nodesPath)
+	if selector == "" {
+		return nil, path, nil
 	}
 
+	nodes, err = c.SelectNodes(selector)
+	if err != nil {
+		return nil, "", err
+	}
 	return nodes, path, nil
 }
 
+// splitNodesPath splits a "topology aware" path in the selector and the path; the path follows the last colon,
+// so selectors containing colons, e.g. @arch:arm64, are supported
+func splitNodesPath(nodesPath string) (selector, path string) {
+	i := strings.LastIndex(nodesPath, ":")
+	if i < 0 {
+		return "", nodesPath
+	}
+	return nodesPath[:i], nodesPath[i+1:]
+}
+
 // SelectNodes returns Nodes according to the given selector.
-// a selector is a shortcut for a node or a set of nodes in the cluster.
+// a selector is a shortcut for a node or a set of nodes in the cluster, a node name
+// without the cluster name prefix or a prefix of the node container ID; a comma separated
+// list of selectors returns the union of the selected nodes, e.g. @cp1,@arch:arm64.
 func (c *Cluster) SelectNodes(nodeSelector string) (nodes NodeList, err error) {
+	if strings.Contains(nodeSelector, ",") {
+		return c.selectNodesUnion(strings.Split(nodeSelector, ","))
+	}
+
+	if strings.HasPrefix(strings.ToLower(nodeSelector), archSelectorPrefix) {
+		return selectNodesByArch(c.K8sNodes(), nodeSelector)
+	}
+
+	if strings.HasPrefix(strings.ToLower(nodeSelector), poolSelectorPrefix) {
+		return selectNodesByPool(c.Workers(), nodeSelector)
+	}
+
 	if strings.HasPrefix(nodeSelector, "@") {
 		switch strings.ToLower(nodeSelector) {
 		case "@all": // all the kubernetes nodes
 			return c.K8sNodes(), nil
 		case "@cp*": // all the control-plane nodes
 			return c.ControlPlanes(), nil
-		case "@cp1": // the bootstrap-control plane
-			return toNodeList(c.BootstrapControlPlane()), nil
 		case "@cpn":
 			return c.SecondaryControlPlanes(), nil
 		case "@w*":
@@ -332,10 +929,25 @@ func (c *Cluster) SelectNodes(nodeSelector string) (nodes NodeList, err error) {
 		case "@lb":
 			return toNodeList(c.ExternalLoadBalancer()), nil
 		case "@etcd":
-			return toNodeList(c.ExternalEtcd()), nil
-		default:
-			return nil, errors.Errorf("Invalid node selector %q. Use one of [@all, @cp*, @cp1, @cpn, @w*, @lb, @etcd]", nodeSelector)
+			return c.ExternalEtcds(), nil
+		case "@registry":
+			return toNodeList(c.ExternalRegistry()), nil
+		}
+
+		// the Nth control-plane or worker node, e.g. @cp1 for the bootstrap-control plane, or a range of
+		// control-plane or worker nodes, e.g. @w1-2
+		if m := indexedSelectorRegex.FindStringSubmatch(strings.ToLower(nodeSelector)); m != nil {
+			nodes := c.Workers()
+			if m[1] == "cp" {
+				nodes = c.ControlPlanes()
+			}
+			if m[3] != "" {
+				return selectNodesByIndexRange(nodes, nodeSelector, m[2], m[3])
+			}
+			return selectNodeByIndex(nodes, nodeSelector, m[2])
 		}
+
+		return nil, errors.Errorf("Invalid node selector %q. Use one of [@all, @cp*, @cp<N>, @cp<N>-<M>, @cpn, @w*, @w<N>, @w<N>-<M>, @lb, @etcd, @registry, @arch:<arch>, @pool:<pool>]", nodeSelector)
 	}
 
 	nodeName := fmt.Sprintf("%s-%s", c.name, nodeSelector)
@@ -345,7 +957,174 @@ func (c *Cluster) SelectNodes(nodeSelector string) (nodes NodeList, err error) {
 		}
 	}
 
-	return nil, nil
+	// if no node matches by name, try to match the selector as a prefix of the container ID
+	return selectNodeByIDPrefix(c.K8sNodes(), nodeSelector)
+}
+
+// SelectRunningNodes returns the Nodes selected by the given selector, like SelectNodes, but only if
+// their containers are running; nodes not running are skipped, and an error listing them is returned
+// if the selector matches some nodes but none of them is running.
+func (c *Cluster) SelectRunningNodes(nodeSelector string) (NodeList, error) {
+	nodes, err := c.SelectNodes(nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	return filterRunningNodes(nodes, nodeSelector, (*Node).IsRunning)
+}
+
+// filterRunningNodes returns the nodes for which isRunning returns true, preserving their order
+func filterRunningNodes(nodes NodeList, nodeSelector string, isRunning func(*Node) (bool, error)) (NodeList, error) {
+	var running NodeList
+	var down []string
+	for _, n := range nodes {
+		ok, err := isRunning(n)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			down = append(down, n.Name())
+			continue
+		}
+		running = append(running, n)
+	}
+	if len(nodes) > 0 && len(running) == 0 {
+		return nil, errors.Errorf("none of the nodes matching %q is running: %s", nodeSelector, strings.Join(down, ", "))
+	}
+	if len(down) > 0 {
+		log.Infof("Skipping nodes matching %q that are not running: %s", nodeSelector, strings.Join(down, ", "))
+	}
+	return running, nil
+}
+
+// selectNodesUnion returns the union of the nodes selected by each selector, without duplicates;
+// nodes are returned in the order they are first selected
+func (c *Cluster) selectNodesUnion(nodeSelectors []string) (NodeList, error) {
+	var nodes NodeList
+	selected := map[string]bool{}
+	for _, s := range nodeSelectors {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, errors.New("Invalid node selector. Empty selectors are not allowed in a comma separated list of selectors")
+		}
+		sNodes, err := c.SelectNodes(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range sNodes {
+			if !selected[n.Name()] {
+				selected[n.Name()] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// archSelectorPrefix is the prefix of node selectors for the K8s nodes with a given architecture
+const archSelectorPrefix = "@arch:"
+
+// selectNodesByArch returns the nodes with the architecture in the @arch:<arch> selector, e.g. @arch:arm64
+func selectNodesByArch(nodes NodeList, nodeSelector string) (NodeList, error) {
+	arch := strings.ToLower(nodeSelector[len(archSelectorPrefix):])
+	if arch == "" {
+		return nil, errors.Errorf("Invalid node selector %q. Use the @arch:<arch> form, e.g. @arch:arm64", nodeSelector)
+	}
+
+	var selected NodeList
+	for _, n := range nodes {
+		nodeArch, err := n.Arch()
+		if err != nil {
+			return nil, err
+		}
+		if nodeArch == arch {
+			selected = append(selected, n)
+		}
+	}
+	return selected, nil
+}
+
+// poolSelectorPrefix is the prefix of node selectors for the worker nodes in a given worker pool
+const poolSelectorPrefix = "@pool:"
+
+// selectNodesByPool returns the nodes in the worker pool in the @pool:<pool> selector, e.g. @pool:spot
+func selectNodesByPool(nodes NodeList, nodeSelector string) (NodeList, error) {
+	pool := nodeSelector[len(poolSelectorPrefix):]
+	if pool == "" {
+		return nil, errors.Errorf("Invalid node selector %q. Use the @pool:<pool> form, e.g. @pool:spot", nodeSelector)
+	}
+
+	var selected NodeList
+	for _, n := range nodes {
+		nodePool, err := n.WorkerPool()
+		if err != nil {
+			return nil, err
+		}
+		if nodePool == pool {
+			selected = append(selected, n)
+		}
+	}
+	return selected, nil
+}
+
+// indexedSelectorRegex matches node selectors for the Nth control-plane or worker node, or for a range of
+// control-plane or worker nodes
+var indexedSelectorRegex = regexp.MustCompile(`^@(cp|w)([0-9]+)(?:-([0-9]+))?$`)
+
+// selectNodeByIndex returns the Nth node in the list, with N 1-based
+func selectNodeByIndex(nodes NodeList, nodeSelector, index string) (NodeList, error) {
+	i, err := strconv.Atoi(index)
+	if err != nil || i < 1 {
+		return nil, errors.Errorf("Invalid node selector %q. The node index should be a number greater than 0", nodeSelector)
+	}
+	if i > len(nodes) {
+		return nil, errors.Errorf("Invalid node selector %q. There are only %d nodes of this type", nodeSelector, len(nodes))
+	}
+	return toNodeList(nodes[i-1]), nil
+}
+
+// selectNodesByIndexRange returns the nodes in the list from the Nth to the Mth, included, with N and M 1-based
+func selectNodesByIndexRange(nodes NodeList, nodeSelector, from, to string) (NodeList, error) {
+	i, err := strconv.Atoi(from)
+	if err != nil || i < 1 {
+		return nil, errors.Errorf("Invalid node selector %q. The first node index should be a number greater than 0", nodeSelector)
+	}
+	j, err := strconv.Atoi(to)
+	if err != nil || j < i {
+		return nil, errors.Errorf("Invalid node selector %q. The last node index should be a number not lower than the first node index", nodeSelector)
+	}
+	if j > len(nodes) {
+		return nil, errors.Errorf("Invalid node selector %q. There are only %d nodes of this type", nodeSelector, len(nodes))
+	}
+	return append(NodeList{}, nodes[i-1:j]...), nil
+}
+
+// selectNodeByIDPrefix returns the node whose container ID starts with the given prefix;
+// an error is returned if more than one node matches
+func selectNodeByIDPrefix(nodes NodeList, prefix string) (NodeList, error) {
+	prefix = strings.ToLower(prefix)
+	if prefix == "" {
+		return nil, nil
+	}
+	for _, r := range prefix {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return nil, nil
+		}
+	}
+
+	var matches NodeList
+	for _, n := range nodes {
+		if n.ID() != "" && strings.HasPrefix(n.ID(), prefix) {
+			matches = append(matches, n)
+		}
+	}
+	if len(matches) > 1 {
+		var names []string
+		for _, n := range matches {
+			names = append(names, n.Name())
+		}
+		return nil, errors.Errorf("container ID prefix %q is ambiguous, it matches nodes %s", prefix, strings.Join(names, ", "))
+	}
+	return matches, nil
 }
 
 func toNodeList(node *Node) NodeList {
@@ -0,0 +1,638 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+func TestIsDockerConnectionError(t *testing.T) {
+	var tests = []struct {
+		name     string
+		lines    []string
+		expected bool
+	}{
+		{
+			name:     "daemon not reachable",
+			lines:    []string{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?"},
+			expected: true,
+		},
+		{
+			name:     "error during connect",
+			lines:    []string{"error during connect: Get http://%2F%2F.%2Fpipe%2Fdocker_engine/v1.40/containers/json: open //./pipe/docker_engine"},
+			expected: true,
+		},
+		{
+			name:     "invalid filter",
+			lines:    []string{`Error response from daemon: Invalid filter 'foo'`},
+			expected: false,
+		},
+		{
+			name:     "no output",
+			expected: false,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			if actual := isDockerConnectionError(rt.lines); actual != rt.expected {
+				t.Errorf("failed isDockerConnectionError:\n\texpected: %t\n\tactual: %t", rt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1"}
+	cp2 := &Node{name: "kind-control-plane-2"}
+	lb := &Node{name: "kind-lb"}
+
+	var tests = []struct {
+		name         string
+		cluster      *Cluster
+		expectedRule TopologyRule
+	}{
+		{
+			name:    "one control-plane",
+			cluster: &Cluster{controlPlanes: NodeList{cp1}},
+		},
+		{
+			name:    "more control-planes with a load balancer",
+			cluster: &Cluster{controlPlanes: NodeList{cp1, cp2}, externalLoadBalancer: lb},
+		},
+		{
+			name:         "no control-planes",
+			cluster:      &Cluster{},
+			expectedRule: RuleControlPlaneRequired,
+		},
+		{
+			name:         "more control-planes without a load balancer",
+			cluster:      &Cluster{controlPlanes: NodeList{cp1, cp2}},
+			expectedRule: RuleLoadBalancerRequired,
+		},
+		{
+			name: "more bootstrap control-planes",
+			cluster: &Cluster{controlPlanes: NodeList{
+				{name: "kind-control-plane-1", bootstrap: true},
+				{name: "kind-control-plane-2", bootstrap: true},
+			}, externalLoadBalancer: lb},
+			expectedRule: RuleSingleBootstrapControlPlane,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			err := rt.cluster.Validate()
+			if rt.expectedRule == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			topologyErr, ok := err.(*TopologyError)
+			if !ok {
+				t.Fatalf("expected a *TopologyError, got %T: %v", err, err)
+			}
+			if topologyErr.Rule != rt.expectedRule {
+				t.Errorf("expected rule %s, got %s", rt.expectedRule, topologyErr.Rule)
+			}
+		})
+	}
+}
+
+func TestSetBootstrapControlPlane(t *testing.T) {
+	newCluster := func() *Cluster {
+		cp1 := &Node{name: "kind-control-plane-1", role: constants.ControlPlaneNodeRoleValue}
+		cp2 := &Node{name: "kind-control-plane-2", role: constants.ControlPlaneNodeRoleValue}
+		cp3 := &Node{name: "kind-control-plane-3", role: constants.ControlPlaneNodeRoleValue, bootstrap: true}
+		w := &Node{name: "kind-worker", role: constants.WorkerNodeRoleValue}
+		return &Cluster{
+			name:          "kind",
+			allNodes:      NodeList{cp3, cp1, cp2, w},
+			k8sNodes:      NodeList{cp3, cp1, cp2, w},
+			controlPlanes: NodeList{cp3, cp1, cp2},
+			workers:       NodeList{w},
+		}
+	}
+
+	var tests = []struct {
+		name                string
+		bootstrap           string
+		expectedBootstrap   string
+		expectedSecondaries []string
+		expectError         bool
+	}{
+		{
+			name:                "another control plane",
+			bootstrap:           "kind-control-plane-2",
+			expectedBootstrap:   "kind-control-plane-2",
+			expectedSecondaries: []string{"kind-control-plane-1", "kind-control-plane-3"},
+		},
+		{
+			name:                "empty falls back to the first control plane",
+			expectedBootstrap:   "kind-control-plane-1",
+			expectedSecondaries: []string{"kind-control-plane-2", "kind-control-plane-3"},
+		},
+		{
+			name:        "not a control plane",
+			bootstrap:   "kind-worker",
+			expectError: true,
+		},
+		{
+			name:        "unknown node",
+			bootstrap:   "kind-control-plane-4",
+			expectError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			c := newCluster()
+			err := c.SetBootstrapControlPlane(rt.bootstrap)
+			if (err != nil) != rt.expectError {
+				t.Fatalf("expected error %t, got %v", rt.expectError, err)
+			}
+			if rt.expectError {
+				return
+			}
+			if got := c.BootstrapControlPlane().Name(); got != rt.expectedBootstrap {
+				t.Errorf("expected bootstrap control plane %s, got %s", rt.expectedBootstrap, got)
+			}
+			if got := c.SecondaryControlPlanes().Names(); !reflect.DeepEqual(got, rt.expectedSecondaries) {
+				t.Errorf("expected secondary control planes %v, got %v", rt.expectedSecondaries, got)
+			}
+			if got := c.K8sNodes().EligibleForActions()[0].Name(); got != rt.expectedBootstrap {
+				t.Errorf("expected %s as first node eligible for actions, got %s", rt.expectedBootstrap, got)
+			}
+		})
+	}
+}
+
+func TestSelectNodeByIDPrefix(t *testing.T) {
+	nodes := NodeList{
+		&Node{name: "kind-control-plane-1", id: "3f4e1a2b5c6d"},
+		&Node{name: "kind-worker-1", id: "3f9a8b7c6d5e"},
+		&Node{name: "kind-worker-2", id: "a1b2c3d4e5f6"},
+	}
+
+	var tests = []struct {
+		prefix        string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			prefix:   "a1b",
+			expected: []string{"kind-worker-2"},
+		},
+		{
+			prefix:   "3F4E",
+			expected: []string{"kind-control-plane-1"},
+		},
+		{
+			prefix:        "3f",
+			expectedError: true,
+		},
+		{
+			prefix: "ff",
+		},
+		{
+			prefix: "worker-1",
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.prefix, func(t *testing.T) {
+			selected, err := selectNodeByIDPrefix(nodes, rt.prefix)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("failed selectNodeByIDPrefix:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+			var names []string
+			for _, n := range selected {
+				names = append(names, n.Name())
+			}
+			if !reflect.DeepEqual(names, rt.expected) {
+				t.Errorf("failed selectNodeByIDPrefix:\n\texpected: %v\n\tactual: %v", rt.expected, names)
+			}
+		})
+	}
+}
+
+func TestSelectNodesByIndex(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1"}
+	cp2 := &Node{name: "kind-control-plane-2"}
+	cp3 := &Node{name: "kind-control-plane-3"}
+	w1 := &Node{name: "kind-worker-1"}
+	c := &Cluster{
+		name:          "kind",
+		controlPlanes: NodeList{cp1, cp2, cp3},
+		workers:       NodeList{w1},
+	}
+
+	var tests = []struct {
+		selector      string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			selector: "@cp1",
+			expected: []string{"kind-control-plane-1"},
+		},
+		{
+			selector: "@CP3",
+			expected: []string{"kind-control-plane-3"},
+		},
+		{
+			selector: "@w1",
+			expected: []string{"kind-worker-1"},
+		},
+		{
+			selector:      "@cp4",
+			expectedError: true,
+		},
+		{
+			selector:      "@w2",
+			expectedError: true,
+		},
+		{
+			selector:      "@cp0",
+			expectedError: true,
+		},
+		{
+			selector: "@cpn",
+			expected: []string{"kind-control-plane-2", "kind-control-plane-3"},
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.selector, func(t *testing.T) {
+			selected, err := c.SelectNodes(rt.selector)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("failed SelectNodes:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+			var names []string
+			for _, n := range selected {
+				names = append(names, n.Name())
+			}
+			if !reflect.DeepEqual(names, rt.expected) {
+				t.Errorf("failed SelectNodes:\n\texpected: %v\n\tactual: %v", rt.expected, names)
+			}
+		})
+	}
+}
+
+func TestSelectNodesByRange(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1"}
+	cp2 := &Node{name: "kind-control-plane-2"}
+	cp3 := &Node{name: "kind-control-plane-3"}
+	w1 := &Node{name: "kind-worker-1"}
+	w2 := &Node{name: "kind-worker-2"}
+	c := &Cluster{
+		name:          "kind",
+		controlPlanes: NodeList{cp1, cp2, cp3},
+		workers:       NodeList{w1, w2},
+	}
+
+	var tests = []struct {
+		selector      string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			selector: "@cp2-3",
+			expected: []string{"kind-control-plane-2", "kind-control-plane-3"},
+		},
+		{
+			selector: "@w1-2",
+			expected: []string{"kind-worker-1", "kind-worker-2"},
+		},
+		{
+			selector: "@w2-2",
+			expected: []string{"kind-worker-2"},
+		},
+		{
+			selector:      "@w2-4",
+			expectedError: true,
+		},
+		{
+			selector:      "@cp3-2",
+			expectedError: true,
+		},
+		{
+			selector:      "@cp0-2",
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.selector, func(t *testing.T) {
+			selected, err := c.SelectNodes(rt.selector)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("failed SelectNodes:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+			var names []string
+			for _, n := range selected {
+				names = append(names, n.Name())
+			}
+			if !reflect.DeepEqual(names, rt.expected) {
+				t.Errorf("failed SelectNodes:\n\texpected: %v\n\tactual: %v", rt.expected, names)
+			}
+		})
+	}
+}
+
+func TestSelectNodesByArchAndUnion(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1", arch: "amd64"}
+	w1 := &Node{name: "kind-worker-1", arch: "amd64"}
+	w2 := &Node{name: "kind-worker-2", arch: "arm64"}
+	c := &Cluster{
+		name:          "kind",
+		k8sNodes:      NodeList{cp1, w1, w2},
+		controlPlanes: NodeList{cp1},
+		workers:       NodeList{w1, w2},
+	}
+
+	var tests = []struct {
+		selector      string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			selector: "@arch:arm64",
+			expected: []string{"kind-worker-2"},
+		},
+		{
+			selector: "@ARCH:AMD64",
+			expected: []string{"kind-control-plane-1", "kind-worker-1"},
+		},
+		{
+			selector: "@arch:s390x",
+		},
+		{
+			selector:      "@arch:",
+			expectedError: true,
+		},
+		{
+			selector:      "@pool:",
+			expectedError: true,
+		},
+		{
+			selector: "@cp1,@arch:arm64",
+			expected: []string{"kind-control-plane-1", "kind-worker-2"},
+		},
+		{
+			selector: "@w*, @arch:arm64",
+			expected: []string{"kind-worker-1", "kind-worker-2"},
+		},
+		{
+			selector:      "@cp1,",
+			expectedError: true,
+		},
+		{
+			selector:      "@cp1,@foo",
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.selector, func(t *testing.T) {
+			selected, err := c.SelectNodes(rt.selector)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("failed SelectNodes:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+			var names []string
+			for _, n := range selected {
+				names = append(names, n.Name())
+			}
+			if !reflect.DeepEqual(names, rt.expected) {
+				t.Errorf("failed SelectNodes:\n\texpected: %v\n\tactual: %v", rt.expected, names)
+			}
+		})
+	}
+}
+
+func TestFilterRunningNodes(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1"}
+	w1 := &Node{name: "kind-worker-1"}
+	w2 := &Node{name: "kind-worker-2"}
+
+	var tests = []struct {
+		name          string
+		nodes         NodeList
+		running       map[string]bool
+		expected      []string
+		expectedError bool
+	}{
+		{
+			name:     "all nodes running",
+			nodes:    NodeList{cp1, w1, w2},
+			running:  map[string]bool{"kind-control-plane-1": true, "kind-worker-1": true, "kind-worker-2": true},
+			expected: []string{"kind-control-plane-1", "kind-worker-1", "kind-worker-2"},
+		},
+		{
+			name:     "stopped nodes are skipped",
+			nodes:    NodeList{cp1, w1, w2},
+			running:  map[string]bool{"kind-control-plane-1": true, "kind-worker-2": true},
+			expected: []string{"kind-control-plane-1", "kind-worker-2"},
+		},
+		{
+			name:     "no nodes selected",
+			expected: []string{},
+		},
+		{
+			name:          "all nodes stopped",
+			nodes:         NodeList{w1, w2},
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			isRunning := func(n *Node) (bool, error) {
+				return rt.running[n.Name()], nil
+			}
+			nodes, err := filterRunningNodes(rt.nodes, "@all", isRunning)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", rt.expectedError, err != nil, err)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "kind-worker-1, kind-worker-2") {
+					t.Errorf("expected the error to list the stopped nodes, got %v", err)
+				}
+				return
+			}
+			if got := nodes.Names(); !reflect.DeepEqual(got, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSplitNodesPath(t *testing.T) {
+	var tests = []struct {
+		nodesPath        string
+		expectedSelector string
+		expectedPath     string
+	}{
+		{
+			nodesPath:    "/tmp/file",
+			expectedPath: "/tmp/file",
+		},
+		{
+			nodesPath:        "@all:/tmp/file",
+			expectedSelector: "@all",
+			expectedPath:     "/tmp/file",
+		},
+		{
+			nodesPath:        "@arch:arm64:/tmp/file",
+			expectedSelector: "@arch:arm64",
+			expectedPath:     "/tmp/file",
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.nodesPath, func(t *testing.T) {
+			selector, path := splitNodesPath(rt.nodesPath)
+			if selector != rt.expectedSelector || path != rt.expectedPath {
+				t.Errorf("failed splitNodesPath:\n\texpected: %q %q\n\tactual: %q %q", rt.expectedSelector, rt.expectedPath, selector, path)
+			}
+		})
+	}
+}
+
+func TestResolveNodesPath(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1", role: constants.ControlPlaneNodeRoleValue}
+	w1 := &Node{name: "kind-worker-1", role: constants.WorkerNodeRoleValue}
+	w2 := &Node{name: "kind-worker-2", role: constants.WorkerNodeRoleValue}
+	c := &Cluster{
+		name:          "kind",
+		controlPlanes: NodeList{cp1},
+		workers:       NodeList{w1, w2},
+	}
+
+	var tests = []struct {
+		nodesPath     string
+		expected      []string
+		expectedPath  string
+		expectedError bool
+	}{
+		{
+			nodesPath:    "@w2:/etc/kubernetes",
+			expected:     []string{"kind-worker-2"},
+			expectedPath: "/etc/kubernetes",
+		},
+		{
+			nodesPath:    "@w1-2:/etc/kubernetes",
+			expected:     []string{"kind-worker-1", "kind-worker-2"},
+			expectedPath: "/etc/kubernetes",
+		},
+		{
+			nodesPath:    "/etc/kubernetes",
+			expected:     []string{},
+			expectedPath: "/etc/kubernetes",
+		},
+		{
+			nodesPath:     "@w5:/etc/kubernetes",
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.nodesPath, func(t *testing.T) {
+			nodes, path, err := c.ResolveNodesPath(rt.nodesPath)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", rt.expectedError, err != nil, err)
+			}
+			if err != nil {
+				return
+			}
+			if path != rt.expectedPath {
+				t.Errorf("expected path %q, got %q", rt.expectedPath, path)
+			}
+			if got := nodes.Names(); !reflect.DeepEqual(got, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseClusterInfos(t *testing.T) {
+	lines := []string{
+		"kind-b\tipv4\tv1.30.0",
+		"kind-a\t<no value>\t<no value>",
+		"kind-b\t<no value>\t<no value>",
+		"kind-a\tipv4\tv1.29.2",
+		"kind-c\t<no value>\t<no value>",
+		"\t\t",
+	}
+	expected := []ClusterInfo{
+		{Name: "kind-a", IPFamily: IPv4Family, KubernetesVersion: "v1.29.2", Nodes: 2},
+		{Name: "kind-b", IPFamily: IPv4Family, KubernetesVersion: "v1.30.0", Nodes: 2},
+		{Name: "kind-c", Nodes: 1},
+	}
+	if got := parseClusterInfos(lines); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestParseOrphanContainers(t *testing.T) {
+	lines := []string{
+		"kind-control-plane\tkind\tkind",
+		"kind-etcd\t\t",
+		"kind-registry\t<no value>\t<no value>",
+		"kind-2-control-plane\tkind-2\tkind-2",
+		"kind-2-etcd\t\t",
+		"kindred-worker\t\t",
+		"kind-lb\t\tkind",
+	}
+
+	tests := []struct {
+		name        string
+		clusterName string
+		clusters    []string
+		expected    []string
+	}{
+		{
+			name:        "other cluster deleted",
+			clusterName: "kind",
+			clusters:    []string{"kind"},
+			expected:    []string{"kind-2-etcd", "kind-etcd", "kind-registry"},
+		},
+		{
+			name:        "other cluster existing",
+			clusterName: "kind",
+			clusters:    []string{"kind", "kind-2"},
+			expected:    []string{"kind-etcd", "kind-registry"},
+		},
+		{
+			name:        "cluster deleted",
+			clusterName: "kind-2",
+			clusters:    []string{"kind"},
+			expected:    []string{"kind-2-etcd"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if orphans := parseOrphanContainers(test.clusterName, test.clusters, lines); !reflect.DeepEqual(orphans, test.expected) {
+				t.Errorf("expected %v, found %v", test.expected, orphans)
+			}
+		})
+	}
+}
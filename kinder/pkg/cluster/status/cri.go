@@ -17,6 +17,8 @@ limitations under the License.
 package status
 
 import (
+	"strings"
+
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
@@ -37,6 +39,40 @@ const (
 	ContainerdRuntime ContainerRuntime = "containerd"
 )
 
+// CgroupDriver returns the cgroup driver used by the container runtime on the node
+func (n *Node) CgroupDriver() (string, error) {
+	cri, err := n.CRI()
+	if err != nil {
+		return "", err
+	}
+
+	switch cri {
+	case ContainerdRuntime:
+		lines, err := n.Command(
+			"/bin/sh", "-c", `grep -E "SystemdCgroup\s*=\s*true" /etc/containerd/config.toml || true`,
+		).Silent().RunAndCapture()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read the containerd config")
+		}
+		if len(lines) > 0 {
+			return "systemd", nil
+		}
+		return "cgroupfs", nil
+	case DockerRuntime:
+		lines, err := n.Command(
+			"docker", "info", "--format={{.CgroupDriver}}",
+		).Silent().RunAndCapture()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read the docker cgroup driver")
+		}
+		if len(lines) != 1 {
+			return "", errors.Errorf("docker cgroup driver should only be one line, got %d lines", len(lines))
+		}
+		return strings.TrimSpace(lines[0]), nil
+	}
+	return "", errors.Errorf("unknown cri: %s", cri)
+}
+
 // InspectCRIinImage inspect an image and detects the installed container runtime
 func InspectCRIinImage(image string) (ContainerRuntime, error) {
 	// define docker default args
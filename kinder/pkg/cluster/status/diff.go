@@ -0,0 +1,231 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NodeDiff defines a node existing only in one of the compared clusters; nodes are
+// identified by their name without the cluster name prefix, e.g. control-plane-1
+type NodeDiff struct {
+	Node string `json:"node"`
+	Role string `json:"role"`
+}
+
+// FieldDiff defines a value that differs between the compared clusters
+type FieldDiff struct {
+	Node  string `json:"node,omitempty"`
+	Field string `json:"field"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// ClusterDiff defines a machine readable diff between two clusters; nodes added exist only
+// in the right cluster, while nodes removed exist only in the left cluster.
+// All the lists are sorted, so the diff is stable and can be compared across runs.
+type ClusterDiff struct {
+	Left          string      `json:"left"`
+	Right         string      `json:"right"`
+	NodesAdded    []NodeDiff  `json:"nodesAdded"`
+	NodesRemoved  []NodeDiff  `json:"nodesRemoved"`
+	VersionDiffs  []FieldDiff `json:"versionDiffs"`
+	SettingsDiffs []FieldDiff `json:"settingsDiffs"`
+}
+
+// IsEmpty returns true if there are no differences between the compared clusters
+func (d *ClusterDiff) IsEmpty() bool {
+	return len(d.NodesAdded) == 0 && len(d.NodesRemoved) == 0 && len(d.VersionDiffs) == 0 && len(d.SettingsDiffs) == 0
+}
+
+// clusterSnapshot defines the cluster topology, versions and settings compared by DiffClusters
+type clusterSnapshot struct {
+	name     string
+	nodes    map[string]nodeSnapshot
+	settings map[string]string
+}
+
+// nodeSnapshot defines the node role and versions compared by DiffClusters
+type nodeSnapshot struct {
+	role     string
+	versions map[string]string
+}
+
+// DiffClusters returns the differences in node roles, versions and settings between two clusters.
+// Versions are read only from running nodes, because they are read from the node binaries.
+func DiffClusters(left, right string) (*ClusterDiff, error) {
+	l, err := snapshotCluster(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := snapshotCluster(right)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(l, r), nil
+}
+
+// snapshotCluster reads the cluster from docker and takes a snapshot of the topology, versions and settings
+func snapshotCluster(name string) (*clusterSnapshot, error) {
+	c, err := FromDocker(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.AllNodes()) == 0 {
+		return nil, errors.Errorf("a cluster with the name %q does not exists", name)
+	}
+	if err := c.ReadSettings(); err != nil {
+		return nil, err
+	}
+
+	settings, err := settingsFields(c.Settings)
+	if err != nil {
+		return nil, err
+	}
+	s := &clusterSnapshot{
+		name:     name,
+		nodes:    map[string]nodeSnapshot{},
+		settings: settings,
+	}
+
+	for _, n := range c.AllNodes() {
+		node := nodeSnapshot{
+			role:     n.Role(),
+			versions: map[string]string{},
+		}
+		if n.IsControlPlane() || n.IsWorker() {
+			running, err := n.IsRunning()
+			if err != nil {
+				return nil, err
+			}
+			if running {
+				kubeVersion, err := n.KubeVersion()
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get the Kubernetes version for node %s", n.Name())
+				}
+				node.versions["kubernetesVersion"] = kubeVersion
+
+				kubeadmVersion, err := n.KubeadmVersion()
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to get the kubeadm version for node %s", n.Name())
+				}
+				node.versions["kubeadmVersion"] = fmt.Sprintf("v%s", kubeadmVersion)
+			}
+		}
+		s.nodes[strings.TrimPrefix(n.Name(), name+"-")] = node
+	}
+
+	return s, nil
+}
+
+// settingsFields returns the cluster settings as a map of json field names to values
+func settingsFields(settings *ClusterSettings) (map[string]string, error) {
+	fields := map[string]string{}
+	if settings == nil {
+		return fields, nil
+	}
+
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the cluster settings")
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the cluster settings")
+	}
+	for k, v := range values {
+		fields[k] = fmt.Sprintf("%v", v)
+	}
+	return fields, nil
+}
+
+// diffSnapshots returns the differences between two cluster snapshots
+func diffSnapshots(left, right *clusterSnapshot) *ClusterDiff {
+	d := &ClusterDiff{
+		Left:          left.name,
+		Right:         right.name,
+		NodesAdded:    []NodeDiff{},
+		NodesRemoved:  []NodeDiff{},
+		VersionDiffs:  []FieldDiff{},
+		SettingsDiffs: []FieldDiff{},
+	}
+
+	for _, name := range nodeNames(left.nodes, right.nodes) {
+		l, inLeft := left.nodes[name]
+		r, inRight := right.nodes[name]
+		switch {
+		case !inLeft:
+			d.NodesAdded = append(d.NodesAdded, NodeDiff{Node: name, Role: r.role})
+		case !inRight:
+			d.NodesRemoved = append(d.NodesRemoved, NodeDiff{Node: name, Role: l.role})
+		default:
+			d.VersionDiffs = append(d.VersionDiffs, diffFields(name, l.versions, r.versions)...)
+		}
+	}
+
+	d.SettingsDiffs = append(d.SettingsDiffs, diffFields("", left.settings, right.settings)...)
+	return d
+}
+
+// diffFields returns the fields with different values, sorted by field name
+func diffFields(node string, left, right map[string]string) []FieldDiff {
+	var diffs []FieldDiff
+	for _, field := range fieldNames(left, right) {
+		if left[field] != right[field] {
+			diffs = append(diffs, FieldDiff{Node: node, Field: field, Left: left[field], Right: right[field]})
+		}
+	}
+	return diffs
+}
+
+// nodeNames returns the union of the node names in two snapshots, sorted
+func nodeNames(left, right map[string]nodeSnapshot) []string {
+	set := map[string]bool{}
+	for k := range left {
+		set[k] = true
+	}
+	for k := range right {
+		set[k] = true
+	}
+	return sortedSet(set)
+}
+
+// fieldNames returns the union of the field names in two maps, sorted
+func fieldNames(left, right map[string]string) []string {
+	set := map[string]bool{}
+	for k := range left {
+		set[k] = true
+	}
+	for k := range right {
+		set[k] = true
+	}
+	return sortedSet(set)
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	versions := func(kube, kubeadm string) map[string]string {
+		return map[string]string{"kubernetesVersion": kube, "kubeadmVersion": kubeadm}
+	}
+
+	left := &clusterSnapshot{
+		name: "a",
+		nodes: map[string]nodeSnapshot{
+			"control-plane-1": {role: "control-plane", versions: versions("v1.30.0", "v1.30.0")},
+			"worker-1":        {role: "worker", versions: versions("v1.30.0", "v1.30.0")},
+			"worker-2":        {role: "worker", versions: versions("v1.30.0", "v1.30.0")},
+		},
+		settings: map[string]string{"ipFamily": "ipv4", "apiServerBindPort": "6443"},
+	}
+	right := &clusterSnapshot{
+		name: "b",
+		nodes: map[string]nodeSnapshot{
+			"control-plane-1": {role: "control-plane", versions: versions("v1.30.0", "v1.31.0")},
+			"worker-1":        {role: "worker", versions: versions("v1.30.0", "v1.30.0")},
+			"lb":              {role: "external-load-balancer", versions: map[string]string{}},
+		},
+		settings: map[string]string{"ipFamily": "ipv4", "apiServerBindPort": "7443"},
+	}
+
+	expected := &ClusterDiff{
+		Left:         "a",
+		Right:        "b",
+		NodesAdded:   []NodeDiff{{Node: "lb", Role: "external-load-balancer"}},
+		NodesRemoved: []NodeDiff{{Node: "worker-2", Role: "worker"}},
+		VersionDiffs: []FieldDiff{
+			{Node: "control-plane-1", Field: "kubeadmVersion", Left: "v1.30.0", Right: "v1.31.0"},
+		},
+		SettingsDiffs: []FieldDiff{
+			{Field: "apiServerBindPort", Left: "6443", Right: "7443"},
+		},
+	}
+
+	diff := diffSnapshots(left, right)
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected diff %+v, got %+v", expected, diff)
+	}
+	if diff.IsEmpty() {
+		t.Error("expected a non empty diff")
+	}
+
+	if diff := diffSnapshots(left, left); !diff.IsEmpty() {
+		t.Errorf("expected an empty diff, got %+v", diff)
+	}
+}
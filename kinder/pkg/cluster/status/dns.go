@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NodeDNS defines the DNS settings of a node container, overriding the DNS settings inherited from the host;
+// DNS settings are applied by docker when generating the container resolv.conf, so they persist across
+// restarts of the container
+type NodeDNS struct {
+	Servers []string
+	Search  []string
+	Options []string
+}
+
+// Validate checks that DNS servers are IP addresses, and that search domains and options are not empty
+func (d NodeDNS) Validate() error {
+	for _, s := range d.Servers {
+		if net.ParseIP(s) == nil {
+			return errors.Errorf("invalid DNS server %q. Use an IP address", s)
+		}
+	}
+	for _, s := range d.Search {
+		if s == "" || strings.ContainsAny(s, " \t\n") {
+			return errors.Errorf("invalid DNS search domain %q", s)
+		}
+	}
+	for _, o := range d.Options {
+		if o == "" || strings.ContainsAny(o, " \t\n") {
+			return errors.Errorf("invalid DNS option %q", o)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+func TestNodeDNSValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		dns           NodeDNS
+		expectedError bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "IPv4 and IPv6 servers, search domains and options",
+			dns: NodeDNS{
+				Servers: []string{"8.8.8.8", "2001:4860:4860::8888"},
+				Search:  []string{"example.com"},
+				Options: []string{"ndots:1"},
+			},
+		},
+		{
+			name:          "server is not an IP",
+			dns:           NodeDNS{Servers: []string{"dns.google"}},
+			expectedError: true,
+		},
+		{
+			name:          "empty search domain",
+			dns:           NodeDNS{Search: []string{""}},
+			expectedError: true,
+		},
+		{
+			name:          "option with spaces",
+			dns:           NodeDNS{Options: []string{"ndots: 1"}},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.dns.Validate()
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
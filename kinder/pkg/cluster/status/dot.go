@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// dotShapes defines the Graphviz shape of the nodes in the topology diagram, by role
+var dotShapes = map[string]string{
+	constants.ControlPlaneNodeRoleValue:         "box",
+	constants.WorkerNodeRoleValue:               "box",
+	constants.ExternalEtcdNodeRoleValue:         "cylinder",
+	constants.ExternalLoadBalancerNodeRoleValue: "hexagon",
+	constants.ExternalRegistryNodeRoleValue:     "folder",
+}
+
+// WriteDOT writes the topology of the cluster as a Graphviz DOT graph, e.g. for rendering it with dot -Tpng.
+// Edges represent connections between nodes: from the external load balancer to the control-plane nodes,
+// from the control-plane nodes to the external etcd members, and from the worker nodes to the API server
+// endpoint, that is the external load balancer or the bootstrap control-plane.
+func (c *Cluster) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "digraph %q {\n", c.Name())
+	fmt.Fprintf(b, "  label=%q;\n", c.Name())
+	fmt.Fprintln(b, "  node [shape=box];")
+
+	for _, n := range c.AllNodes() {
+		attrs := fmt.Sprintf("label=%q", n.Name()+"\n"+n.Role())
+		if shape, ok := dotShapes[n.Role()]; ok {
+			attrs += fmt.Sprintf(", shape=%s", shape)
+		}
+		if n.IsControlPlane() {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(b, "  %q [%s];\n", n.Name(), attrs)
+	}
+
+	edge := func(from, to *Node) {
+		fmt.Fprintf(b, "  %q -> %q;\n", from.Name(), to.Name())
+	}
+	if lb := c.ExternalLoadBalancer(); lb != nil {
+		for _, cp := range c.ControlPlanes() {
+			edge(lb, cp)
+		}
+	}
+	for _, cp := range c.ControlPlanes() {
+		for _, etcd := range c.ExternalEtcds() {
+			edge(cp, etcd)
+		}
+	}
+	endpoint := c.ExternalLoadBalancer()
+	if endpoint == nil {
+		endpoint = c.BootstrapControlPlane()
+	}
+	if endpoint != nil {
+		for _, worker := range c.Workers() {
+			edge(worker, endpoint)
+		}
+	}
+
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
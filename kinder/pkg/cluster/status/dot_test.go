@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+func TestWriteDOT(t *testing.T) {
+	cp1 := &Node{name: "kind-control-plane-1", role: constants.ControlPlaneNodeRoleValue}
+	cp2 := &Node{name: "kind-control-plane-2", role: constants.ControlPlaneNodeRoleValue}
+	w1 := &Node{name: "kind-worker-1", role: constants.WorkerNodeRoleValue}
+	lb := &Node{name: "kind-lb", role: constants.ExternalLoadBalancerNodeRoleValue}
+	etcd := &Node{name: "kind-etcd", role: constants.ExternalEtcdNodeRoleValue}
+
+	var tests = []struct {
+		name     string
+		cluster  *Cluster
+		expected string
+	}{
+		{
+			name: "single control-plane",
+			cluster: &Cluster{
+				name:          "kind",
+				allNodes:      NodeList{cp1, w1},
+				controlPlanes: NodeList{cp1},
+				workers:       NodeList{w1},
+			},
+			expected: `digraph "kind" {
+  label="kind";
+  node [shape=box];
+  "kind-control-plane-1" [label="kind-control-plane-1\ncontrol-plane", shape=box, style=bold];
+  "kind-worker-1" [label="kind-worker-1\nworker", shape=box];
+  "kind-worker-1" -> "kind-control-plane-1";
+}
+`,
+		},
+		{
+			name: "HA with external etcd",
+			cluster: &Cluster{
+				name:                 "kind",
+				allNodes:             NodeList{etcd, lb, cp1, cp2, w1},
+				controlPlanes:        NodeList{cp1, cp2},
+				workers:              NodeList{w1},
+				externalEtcds:        NodeList{etcd},
+				externalLoadBalancer: lb,
+			},
+			expected: `digraph "kind" {
+  label="kind";
+  node [shape=box];
+  "kind-etcd" [label="kind-etcd\nexternal-etcd", shape=cylinder];
+  "kind-lb" [label="kind-lb\nexternal-load-balancer", shape=hexagon];
+  "kind-control-plane-1" [label="kind-control-plane-1\ncontrol-plane", shape=box, style=bold];
+  "kind-control-plane-2" [label="kind-control-plane-2\ncontrol-plane", shape=box, style=bold];
+  "kind-worker-1" [label="kind-worker-1\nworker", shape=box];
+  "kind-lb" -> "kind-control-plane-1";
+  "kind-lb" -> "kind-control-plane-2";
+  "kind-control-plane-1" -> "kind-etcd";
+  "kind-control-plane-2" -> "kind-etcd";
+  "kind-worker-1" -> "kind-lb";
+}
+`,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := rt.cluster.WriteDOT(&b); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if b.String() != rt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", rt.expected, b.String())
+			}
+		})
+	}
+}
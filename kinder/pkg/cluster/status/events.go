@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Event defines a Kubernetes event, as returned by Cluster.Events
+type Event struct {
+	Timestamp time.Time
+	Type      string
+	Namespace string
+	Object    string
+	Reason    string
+	Message   string
+	Count     int
+}
+
+// String returns the event in a single line, similar to kubectl get events
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s/%s %s: %s", e.Timestamp.UTC().Format(time.RFC3339), e.Type, e.Namespace, e.Object, e.Reason, strings.TrimSpace(e.Message))
+	if e.Count > 1 {
+		s += fmt.Sprintf(" (x%d)", e.Count)
+	}
+	return s
+}
+
+// Events returns the events in all the namespaces of the cluster, sorted by timestamp, oldest first;
+// if warningsOnly is set, only events of type Warning are returned, and if limit is greater than zero,
+// only the most recent limit events are returned. Events are read from the bootstrap control-plane,
+// so kubeadm init should be already completed.
+func (c *Cluster) Events(warningsOnly bool, limit int) ([]Event, error) {
+	cp1 := c.BootstrapControlPlane()
+	if cp1 == nil {
+		return nil, errors.New("the cluster does not have a bootstrap control-plane node")
+	}
+
+	args := []string{"--kubeconfig=/etc/kubernetes/admin.conf", "get", "events", "--all-namespaces", "--request-timeout=10s", "-o=json"}
+	if warningsOnly {
+		args = append(args, "--field-selector=type=Warning")
+	}
+	lines, err := cp1.Command("kubectl", args...).Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get events")
+	}
+
+	return parseEvents([]byte(strings.Join(lines, "\n")), limit)
+}
+
+// eventList defines the subset of the event list returned by kubectl used by Cluster.Events
+type eventList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace         string    `json:"namespace"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+		InvolvedObject struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"involvedObject"`
+		Type          string    `json:"type"`
+		Reason        string    `json:"reason"`
+		Message       string    `json:"message"`
+		Count         int       `json:"count"`
+		LastTimestamp time.Time `json:"lastTimestamp"`
+		EventTime     time.Time `json:"eventTime"`
+		Series        *struct {
+			Count            int       `json:"count"`
+			LastObservedTime time.Time `json:"lastObservedTime"`
+		} `json:"series"`
+	} `json:"items"`
+}
+
+// parseEvents decodes the event list returned by kubectl, and returns the events sorted by timestamp;
+// the timestamp is the time the event was last observed, falling back to the event creation time
+func parseEvents(data []byte, limit int) ([]Event, error) {
+	var list eventList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode events")
+	}
+
+	events := make([]Event, 0, len(list.Items))
+	for _, i := range list.Items {
+		e := Event{
+			Type:      i.Type,
+			Namespace: i.Metadata.Namespace,
+			Object:    fmt.Sprintf("%s/%s", strings.ToLower(i.InvolvedObject.Kind), i.InvolvedObject.Name),
+			Reason:    i.Reason,
+			Message:   i.Message,
+			Count:     i.Count,
+		}
+		for _, t := range []time.Time{i.LastTimestamp, i.EventTime, i.Metadata.CreationTimestamp} {
+			if !t.IsZero() {
+				e.Timestamp = t
+				break
+			}
+		}
+		if i.Series != nil {
+			e.Count = i.Series.Count
+			if !i.Series.LastObservedTime.IsZero() {
+				e.Timestamp = i.Series.LastObservedTime
+			}
+		}
+		events = append(events, e)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+const eventsJSON = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "metadata": {"namespace": "kube-system", "creationTimestamp": "2024-05-01T10:00:05Z"},
+      "involvedObject": {"kind": "Pod", "name": "coredns-1"},
+      "type": "Warning",
+      "reason": "FailedScheduling",
+      "message": "0/1 nodes are available\n",
+      "count": 3,
+      "lastTimestamp": "2024-05-01T10:00:30Z",
+      "eventTime": null
+    },
+    {
+      "metadata": {"namespace": "default", "creationTimestamp": "2024-05-01T10:00:10Z"},
+      "involvedObject": {"kind": "Node", "name": "kind-worker"},
+      "type": "Normal",
+      "reason": "RegisteredNode",
+      "message": "Node kind-worker event: Registered Node",
+      "lastTimestamp": null,
+      "eventTime": "2024-05-01T10:00:10.123456Z"
+    },
+    {
+      "metadata": {"namespace": "kube-system", "creationTimestamp": "2024-05-01T10:00:01Z"},
+      "involvedObject": {"kind": "Pod", "name": "kube-proxy-1"},
+      "type": "Warning",
+      "reason": "BackOff",
+      "message": "Back-off restarting failed container",
+      "lastTimestamp": null,
+      "eventTime": null,
+      "series": {"count": 5, "lastObservedTime": "2024-05-01T10:00:20.000000Z"}
+    }
+  ]
+}`
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		expected []string
+	}{
+		{
+			name:  "all events, sorted by timestamp",
+			limit: 0,
+			expected: []string{
+				"2024-05-01T10:00:10Z Normal default/node/kind-worker RegisteredNode: Node kind-worker event: Registered Node",
+				"2024-05-01T10:00:20Z Warning kube-system/pod/kube-proxy-1 BackOff: Back-off restarting failed container (x5)",
+				"2024-05-01T10:00:30Z Warning kube-system/pod/coredns-1 FailedScheduling: 0/1 nodes are available (x3)",
+			},
+		},
+		{
+			name:  "most recent events",
+			limit: 1,
+			expected: []string{
+				"2024-05-01T10:00:30Z Warning kube-system/pod/coredns-1 FailedScheduling: 0/1 nodes are available (x3)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := parseEvents([]byte(eventsJSON), test.limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(events) != len(test.expected) {
+				t.Fatalf("expected %d events, got %d", len(test.expected), len(events))
+			}
+			for i, e := range events {
+				if e.String() != test.expected[i] {
+					t.Errorf("expected event %q, got %q", test.expected[i], e.String())
+				}
+			}
+		})
+	}
+
+	if _, err := parseEvents([]byte("not json"), 0); err == nil {
+		t.Error("expected error for invalid event list")
+	}
+}
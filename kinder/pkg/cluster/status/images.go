@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NB. code implemented in this file ideally should be in the CRI package, but ATM it is
+// implemented here to avoid circular references (see cri.go).
+
+// Image defines a container image available in the container runtime of a node
+type Image struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// NodeImages defines the list of container images available on a node
+type NodeImages struct {
+	Node   string  `json:"node"`
+	Images []Image `json:"images"`
+}
+
+// Images returns the list of container images available in the container runtime of the node,
+// sorted by name
+func (n *Node) Images() ([]Image, error) {
+	cri, err := n.CRI()
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	switch cri {
+	case ContainerdRuntime:
+		lines, err := n.Command(
+			"ctr", "--namespace=k8s.io", "images", "ls",
+		).Silent().RunAndCapture()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read images from %s", n.Name())
+		}
+		images = parseContainerdImages(lines)
+	case DockerRuntime:
+		lines, err := n.Command(
+			"docker", "images", "--digests", "--format={{.Repository}}:{{.Tag}} {{.Digest}}",
+		).Silent().RunAndCapture()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read images from %s", n.Name())
+		}
+		images = parseDockerImages(lines)
+	default:
+		return nil, errors.Errorf("unknown cri: %s", cri)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Name < images[j].Name
+	})
+	return images, nil
+}
+
+// Images returns, for each Kubernetes node in the cluster, the list of container images
+// available in the node container runtime
+func (c *Cluster) Images() ([]NodeImages, error) {
+	var res []NodeImages
+	for _, n := range c.K8sNodes() {
+		images, err := n.Images()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, NodeImages{
+			Node:   n.Name(),
+			Images: images,
+		})
+	}
+	return res, nil
+}
+
+// parseContainerdImages parses the output of `ctr images ls`, that is a table with
+// REF, TYPE, DIGEST, SIZE, PLATFORMS and LABELS columns
+func parseContainerdImages(lines []string) []Image {
+	var images []Image
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] == "REF" {
+			continue
+		}
+		// skip references by content ID, because they duplicate named references
+		if strings.HasPrefix(fields[0], "sha256:") {
+			continue
+		}
+		images = append(images, Image{
+			Name:   fields[0],
+			Digest: fields[2],
+		})
+	}
+	return images
+}
+
+// parseDockerImages parses the output of `docker images --digests` formatted as
+// "{{.Repository}}:{{.Tag}} {{.Digest}}"
+func parseDockerImages(lines []string) []Image {
+	var images []Image
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		image := Image{
+			Name: fields[0],
+		}
+		if len(fields) > 1 && fields[1] != "<none>" {
+			image.Digest = fields[1]
+		}
+		images = append(images, image)
+	}
+	return images
+}
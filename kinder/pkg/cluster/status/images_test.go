@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseContainerdImages(t *testing.T) {
+	lines := []string{
+		"REF                                    TYPE                                                 DIGEST                                                                  SIZE      PLATFORMS   LABELS",
+		"registry.k8s.io/pause:3.9              application/vnd.docker.distribution.manifest.v2+json sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097 311.6 KiB linux/amd64 io.cri-containerd.image=managed",
+		"sha256:e6f1816883972d4be47bd48879a08919b96afcd344132622e4d444987919323c application/vnd.docker.distribution.manifest.v2+json sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097 311.6 KiB linux/amd64 io.cri-containerd.image=managed",
+	}
+	expected := []Image{
+		{
+			Name:   "registry.k8s.io/pause:3.9",
+			Digest: "sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097",
+		},
+	}
+
+	if images := parseContainerdImages(lines); !reflect.DeepEqual(images, expected) {
+		t.Errorf("failed parseContainerdImages:\n\texpected: %v\n\tactual: %v", expected, images)
+	}
+}
+
+func TestParseDockerImages(t *testing.T) {
+	lines := []string{
+		"registry.k8s.io/pause:3.9 sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097",
+		"registry.k8s.io/kube-apiserver:v1.30.0 <none>",
+		"",
+	}
+	expected := []Image{
+		{
+			Name:   "registry.k8s.io/pause:3.9",
+			Digest: "sha256:7031c1b283388d2c2e09b57badb803c05ebed362dc88d84b480cc47f72a21097",
+		},
+		{
+			Name: "registry.k8s.io/kube-apiserver:v1.30.0",
+		},
+	}
+
+	if images := parseDockerImages(lines); !reflect.DeepEqual(images, expected) {
+		t.Errorf("failed parseDockerImages:\n\texpected: %v\n\tactual: %v", expected, images)
+	}
+}
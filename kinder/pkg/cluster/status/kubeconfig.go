@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// MergeKubeConfigs reads the kubeconfig files for a list of clusters and merges them into a
+// single kubeconfig, with one context for each cluster named as the cluster itself.
+//
+// Server URLs are rewritten to the API server endpoint reachable from the host, and
+// cluster entries with the same server and CA data are merged into one.
+func MergeKubeConfigs(names ...string) (*clientcmdapi.Config, error) {
+	merged := clientcmdapi.NewConfig()
+	for _, name := range names {
+		path := KubeConfigPath(name)
+		if _, err := os.Stat(path); err != nil {
+			return nil, errors.Errorf("kubeconfig for cluster %q does not exist at %s. Has the cluster been initialized?", name, path)
+		}
+
+		config, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read kubeconfig for cluster %q", name)
+		}
+
+		c, err := FromDocker(name)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint, err := c.APIServerHostEndpoint()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the API server endpoint for cluster %q", name)
+		}
+
+		if err := mergeKubeConfig(merged, name, config, fmt.Sprintf("https://%s", endpoint)); err != nil {
+			return nil, err
+		}
+	}
+
+	return merged, nil
+}
+
+// mergeKubeConfig adds the current context of a cluster kubeconfig to the merged kubeconfig
+func mergeKubeConfig(merged *clientcmdapi.Config, name string, config *clientcmdapi.Config, server string) error {
+	if _, ok := merged.Contexts[name]; ok {
+		return errors.Errorf("cluster %q is listed more than once", name)
+	}
+
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return errors.Errorf("invalid kubeconfig for cluster %q: current context %q does not exist", name, config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[context.Cluster]
+	if !ok {
+		return errors.Errorf("invalid kubeconfig for cluster %q: cluster %q does not exist", name, context.Cluster)
+	}
+	authInfo, ok := config.AuthInfos[context.AuthInfo]
+	if !ok {
+		return errors.Errorf("invalid kubeconfig for cluster %q: user %q does not exist", name, context.AuthInfo)
+	}
+
+	cluster = cluster.DeepCopy()
+	if server != "" {
+		cluster.Server = server
+	}
+
+	// reuse an existing cluster entry if it has the same server and CA data
+	clusterName := name
+	for n, c := range merged.Clusters {
+		if c.Server == cluster.Server && bytes.Equal(c.CertificateAuthorityData, cluster.CertificateAuthorityData) {
+			clusterName = n
+			break
+		}
+	}
+	if _, ok := merged.Clusters[clusterName]; !ok {
+		merged.Clusters[clusterName] = cluster
+	}
+
+	userName := fmt.Sprintf("%s-admin", name)
+	merged.AuthInfos[userName] = authInfo.DeepCopy()
+
+	merged.Contexts[name] = &clientcmdapi.Context{
+		Cluster:  clusterName,
+		AuthInfo: userName,
+	}
+	if merged.CurrentContext == "" {
+		merged.CurrentContext = name
+	}
+
+	return nil
+}
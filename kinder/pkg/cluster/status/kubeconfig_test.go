@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func newTestKubeConfig(server string, caData string) *clientcmdapi.Config {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["kubernetes"] = &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: []byte(caData),
+	}
+	config.AuthInfos["kubernetes-admin"] = &clientcmdapi.AuthInfo{
+		Token: "token",
+	}
+	config.Contexts["kubernetes-admin@kubernetes"] = &clientcmdapi.Context{
+		Cluster:  "kubernetes",
+		AuthInfo: "kubernetes-admin",
+	}
+	config.CurrentContext = "kubernetes-admin@kubernetes"
+	return config
+}
+
+func TestMergeKubeConfig(t *testing.T) {
+	var tests = []struct {
+		name             string
+		configs          map[string]*clientcmdapi.Config
+		order            []string
+		servers          map[string]string
+		expectedClusters map[string]string
+		expectedError    bool
+	}{
+		{
+			name: "clusters with different CA",
+			configs: map[string]*clientcmdapi.Config{
+				"a": newTestKubeConfig("https://172.17.0.2:6443", "ca-a"),
+				"b": newTestKubeConfig("https://172.17.0.3:6443", "ca-b"),
+			},
+			order:   []string{"a", "b"},
+			servers: map[string]string{"a": "https://localhost:32001", "b": "https://localhost:32002"},
+			expectedClusters: map[string]string{
+				"a": "a",
+				"b": "b",
+			},
+		},
+		{
+			name: "clusters with same server and CA are deduplicated",
+			configs: map[string]*clientcmdapi.Config{
+				"a": newTestKubeConfig("https://172.17.0.2:6443", "ca"),
+				"b": newTestKubeConfig("https://172.17.0.2:6443", "ca"),
+			},
+			order:   []string{"a", "b"},
+			servers: map[string]string{"a": "https://lb:6443", "b": "https://lb:6443"},
+			expectedClusters: map[string]string{
+				"a": "a",
+				"b": "a",
+			},
+		},
+		{
+			name: "invalid current context",
+			configs: map[string]*clientcmdapi.Config{
+				"a": func() *clientcmdapi.Config {
+					c := newTestKubeConfig("https://172.17.0.2:6443", "ca")
+					c.CurrentContext = "foo"
+					return c
+				}(),
+			},
+			order:         []string{"a"},
+			servers:       map[string]string{"a": "https://localhost:32001"},
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			merged := clientcmdapi.NewConfig()
+			var err error
+			for _, name := range rt.order {
+				if err = mergeKubeConfig(merged, name, rt.configs[name], rt.servers[name]); err != nil {
+					break
+				}
+			}
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("failed mergeKubeConfig:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if merged.CurrentContext != rt.order[0] {
+				t.Errorf("failed mergeKubeConfig:\n\texpected current context: %s\n\tactual: %s", rt.order[0], merged.CurrentContext)
+			}
+			if len(merged.Clusters) != len(unique(rt.expectedClusters)) {
+				t.Errorf("failed mergeKubeConfig:\n\texpected %d clusters\n\tactual: %d", len(unique(rt.expectedClusters)), len(merged.Clusters))
+			}
+			for context, cluster := range rt.expectedClusters {
+				ctx, ok := merged.Contexts[context]
+				if !ok {
+					t.Fatalf("failed mergeKubeConfig:\n\texpected context: %s", context)
+				}
+				if ctx.Cluster != cluster {
+					t.Errorf("failed mergeKubeConfig:\n\texpected context %s to use cluster %s\n\tactual: %s", context, cluster, ctx.Cluster)
+				}
+				if merged.Clusters[ctx.Cluster].Server != rt.servers[context] {
+					t.Errorf("failed mergeKubeConfig:\n\texpected server: %s\n\tactual: %s", rt.servers[context], merged.Clusters[ctx.Cluster].Server)
+				}
+				if _, ok := merged.AuthInfos[ctx.AuthInfo]; !ok {
+					t.Errorf("failed mergeKubeConfig:\n\texpected user: %s", ctx.AuthInfo)
+				}
+			}
+		})
+	}
+}
+
+func unique(m map[string]string) map[string]bool {
+	u := map[string]bool{}
+	for _, v := range m {
+		u[v] = true
+	}
+	return u
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+)
+
+// inspectLabel returns the value of a label of a container using docker inspect; an empty string is returned
+// if the label is not set
+func inspectLabel(containerName, key string) (string, error) {
+	lines, err := host.InspectContainer(containerName, fmt.Sprintf("{{index .Config.Labels %q}}", key))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get %q label", key)
+	}
+	return parseLabelValue(lines), nil
+}
+
+// parseLabelValue returns the label value from the docker inspect output, that is "<no value>" for labels not set
+func parseLabelValue(lines []string) string {
+	value := strings.Trim(strings.Join(lines, "\n"), "'")
+	if value == "<no value>" {
+		return ""
+	}
+	return value
+}
+
+// label returns the value of a label of the node container; an empty string is returned if the label is not set
+func (n *Node) label(key string) (string, error) {
+	return inspectLabel(n.name, key)
+}
+
+// jsonLabel decodes the value of a label of the node container holding a JSON document into v;
+// v is left unchanged if the label is not set
+func (n *Node) jsonLabel(key string, v interface{}) error {
+	value, err := n.label(key)
+	if err != nil || value == "" {
+		return err
+	}
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return errors.Wrapf(err, "failed to decode %q label", key)
+	}
+	return nil
+}
+
+// listLabel returns the values of a label of the node container holding a comma separated list
+func (n *Node) listLabel(key string) ([]string, error) {
+	value, err := n.label(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v == "" {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// APIServerBindPort returns the port the API server binds to on a control-plane node, as defined at create time
+func (n *Node) APIServerBindPort() (int32, error) {
+	value, err := n.label(constants.APIServerBindPortLabelKey)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return constants.APIServerPort, nil
+	}
+	return parsePort(value)
+}
+
+// NodeLabels returns the Kubernetes labels, in the key=value form, to be assigned to the node at join time,
+// as defined at create time
+func (n *Node) NodeLabels() ([]string, error) {
+	return n.listLabel(constants.NodeLabelsLabelKey)
+}
+
+// NodeTaints returns the Kubernetes taints, in the key=value:Effect form, to be assigned to the node at join time,
+// as defined at create time
+func (n *Node) NodeTaints() ([]string, error) {
+	return n.listLabel(constants.NodeTaintsLabelKey)
+}
+
+// SandboxImage returns the CRI sandbox (pause) image configured in the container runtime at create time, if any
+func (n *Node) SandboxImage() (string, error) {
+	return n.label(constants.SandboxImageLabelKey)
+}
+
+// ControlPlaneExtraArgs returns the extra args for the control-plane components, in the key=value form,
+// as defined at create time; extra args are indexed by component name, as in the kubeadm ClusterConfiguration
+func (n *Node) ControlPlaneExtraArgs() (map[string][]string, error) {
+	var extraArgs map[string][]string
+	if err := n.jsonLabel(constants.ControlPlaneExtraArgsLabelKey, &extraArgs); err != nil {
+		return nil, err
+	}
+	return extraArgs, nil
+}
+
+// Resources returns the CPU and memory limits of the node container as defined at create time
+func (n *Node) Resources() (NodeResources, error) {
+	resources := NodeResources{}
+	if err := n.jsonLabel(constants.ResourcesLabelKey, &resources); err != nil {
+		return NodeResources{}, err
+	}
+	return resources, nil
+}
+
+// KubeletEviction returns the kubelet eviction settings as defined at create time; settings are
+// indexed by field name, as in the KubeletConfiguration, e.g. evictionHard
+func (n *Node) KubeletEviction() (map[string]map[string]string, error) {
+	var eviction map[string]map[string]string
+	if err := n.jsonLabel(constants.KubeletEvictionLabelKey, &eviction); err != nil {
+		return nil, err
+	}
+	return eviction, nil
+}
+
+// NodeSysctls returns the sysctls applied on the node before the kubelet starts, indexed by key,
+// as defined at create time
+func (n *Node) NodeSysctls() (map[string]string, error) {
+	var sysctls map[string]string
+	if err := n.jsonLabel(constants.NodeSysctlsLabelKey, &sysctls); err != nil {
+		return nil, err
+	}
+	return sysctls, nil
+}
+
+// KubeProxy returns the kube-proxy mode for the cluster as defined at create time, or KubeProxyDisabled
+// if the kube-proxy addon should not be installed; an empty string is returned if not set
+func (n *Node) KubeProxy() (string, error) {
+	return n.label(constants.KubeProxyLabelKey)
+}
+
+// NodeCIDRMaskSize returns the size of the IPv4 and IPv6 pod CIDR allocated to each node as defined at create time;
+// zero is returned for sizes not set
+func (n *Node) NodeCIDRMaskSize() (ipv4, ipv6 int, err error) {
+	sizes := map[string]int{}
+	if err := n.jsonLabel(constants.NodeCIDRMaskSizeLabelKey, &sizes); err != nil {
+		return 0, 0, err
+	}
+	return sizes["ipv4"], sizes["ipv6"], nil
+}
+
+// CoreDNSImage returns the CoreDNS image as defined at create time, if any
+func (n *Node) CoreDNSImage() (string, error) {
+	return n.label(constants.CoreDNSImageLabelKey)
+}
+
+// CoreDNSReplicas returns the number of CoreDNS replicas as defined at create time;
+// zero is returned if not set, meaning that the kubeadm default is used
+func (n *Node) CoreDNSReplicas() (int, error) {
+	key := constants.CoreDNSReplicasLabelKey
+	value, err := n.label(key)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	replicas, err := strconv.Atoi(value)
+	if err != nil || replicas < 0 {
+		return 0, errors.Errorf("invalid %q label value %q", key, value)
+	}
+	return replicas, nil
+}
+
+// BootstrapManifests returns the manifests to be applied after init, as defined at create time, in order;
+// each manifest is a path in the node or an URL
+func (n *Node) BootstrapManifests() ([]string, error) {
+	var manifests []string
+	if err := n.jsonLabel(constants.BootstrapManifestsLabelKey, &manifests); err != nil {
+		return nil, err
+	}
+	return manifests, nil
+}
+
+// WaitDaemonSets returns the DaemonSets, in the namespace/name form, to be waited for rolling out on all the nodes,
+// as defined at create time
+func (n *Node) WaitDaemonSets() ([]string, error) {
+	var daemonSets []string
+	if err := n.jsonLabel(constants.WaitDaemonSetsLabelKey, &daemonSets); err != nil {
+		return nil, err
+	}
+	return daemonSets, nil
+}
+
+// EncryptionProvider returns the provider used for encrypting secrets at rest as defined at create time, if any
+func (n *Node) EncryptionProvider() (string, error) {
+	return n.label(constants.EncryptionProviderLabelKey)
+}
+
+// EtcdExtraArgs returns the etcd extra args for the backend quota and auto-compaction, in the key=value form,
+// as defined at create time; the args apply both to the local etcd and to the external etcd
+func (n *Node) EtcdExtraArgs() ([]string, error) {
+	var extraArgs []string
+	if err := n.jsonLabel(constants.EtcdExtraArgsLabelKey, &extraArgs); err != nil {
+		return nil, err
+	}
+	return extraArgs, nil
+}
+
+// ExternalEtcdEndpoints returns the client endpoints of an external etcd not managed by kinder as defined at
+// create time, if any
+func (n *Node) ExternalEtcdEndpoints() ([]string, error) {
+	var endpoints []string
+	if err := n.jsonLabel(constants.ExternalEtcdEndpointsLabelKey, &endpoints); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// KubeletServingCertRotation returns true if the kubelet serving certificates are signed by the controller manager,
+// and thus rotated, as defined at create time
+func (n *Node) KubeletServingCertRotation() (bool, error) {
+	value, err := n.label(constants.KubeletServingCertRotationLabelKey)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(value, "true"), nil
+}
+
+// KubeadmClusterName returns the cluster name set in the kubeadm ClusterConfiguration as defined at create time, if any;
+// when not set, the kinder cluster name is used
+func (n *Node) KubeadmClusterName() (string, error) {
+	return n.label(constants.KubeadmClusterNameLabelKey)
+}
+
+// CNI returns the CNI plugin installed after init as defined at create time, if any; the value is the name of a known
+// plugin, or a custom manifest as a path in the node or an URL
+func (n *Node) CNI() (string, error) {
+	return n.label(constants.CNILabelKey)
+}
+
+// IPFamily returns the IP family of the cluster as defined at create time; nodes created before the IP family
+// was recorded belong to IPv4 clusters
+func (n *Node) IPFamily() (ClusterIPFamily, error) {
+	family, err := n.label(constants.IPFamilyLabelKey)
+	if err != nil {
+		return "", err
+	}
+	if family == "" {
+		return IPv4Family, nil
+	}
+	return ClusterIPFamily(family), nil
+}
+
+// AuditLogPath returns the API server audit log path as defined at create time, if any
+func (n *Node) AuditLogPath() (string, error) {
+	return n.label(constants.AuditLogPathLabelKey)
+}
+
+// APIServerTLS returns the API server TLS min version and cipher suites as defined at create time, if any
+func (n *Node) APIServerTLS() (string, []string, error) {
+	minVersion, err := n.label(constants.APIServerTLSMinVersionLabelKey)
+	if err != nil {
+		return "", nil, err
+	}
+	cipherSuites, err := n.listLabel(constants.APIServerTLSCipherSuitesLabelKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return minVersion, cipherSuites, nil
+}
+
+// IgnorePreflightErrors returns the kubeadm preflight errors to be ignored by kubeadm init as defined at
+// create time, if any
+func (n *Node) IgnorePreflightErrors() (string, error) {
+	return n.label(constants.IgnorePreflightErrorsLabelKey)
+}
+
+// EtcdVersion returns the local etcd image tag as defined at create time, if any
+func (n *Node) EtcdVersion() (string, error) {
+	return n.label(constants.EtcdVersionLabelKey)
+}
+
+// Network returns the user defined docker network the node is connected to, as defined at create time;
+// an empty string is returned for nodes connected to the default network
+func (n *Node) Network() (string, error) {
+	return n.label(constants.NetworkLabelKey)
+}
+
+// WorkerPool returns the name of the worker pool the node belongs to, as defined at create time;
+// an empty string is returned for nodes not belonging to a worker pool
+func (n *Node) WorkerPool() (string, error) {
+	return n.label(constants.WorkerPoolLabelKey)
+}
+
+// CommandOverride returns the command overriding the node container command, as defined at create time;
+// nodes with a command override are considered modified, so they might not behave as expected
+func (n *Node) CommandOverride() (string, error) {
+	return n.label(constants.NodeCommandLabelKey)
+}
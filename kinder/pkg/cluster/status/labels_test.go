@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+func TestParseLabelValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{
+			name:     "label not set",
+			lines:    []string{"'<no value>'"},
+			expected: "",
+		},
+		{
+			name:     "empty label",
+			lines:    []string{"''"},
+			expected: "",
+		},
+		{
+			name:     "single line",
+			lines:    []string{"'calico'"},
+			expected: "calico",
+		},
+		{
+			name:     "multiple lines",
+			lines:    []string{"'{\"a\":", "\"b\"}'"},
+			expected: "{\"a\":\n\"b\"}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := parseLabelValue(test.lines); value != test.expected {
+				t.Errorf("expected %q, found %q", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ClusterResolver resolves node selectors across multiple clusters; selectors can have
+// an optional clustername/ prefix, e.g. cluster2/@cp1, otherwise the default cluster is used.
+//
+// Clusters are read from docker the first time they are used, and then cached for
+// the lifetime of the resolver, that usually is a single kinder command.
+type ClusterResolver struct {
+	defaultCluster *Cluster
+	clusters       map[string]*Cluster
+}
+
+// NewClusterResolver returns a new ClusterResolver using the given cluster for selectors without a cluster prefix
+func NewClusterResolver(defaultCluster *Cluster) *ClusterResolver {
+	return &ClusterResolver{
+		defaultCluster: defaultCluster,
+		clusters: map[string]*Cluster{
+			defaultCluster.Name(): defaultCluster,
+		},
+	}
+}
+
+// Cluster returns the cluster with the given name, reading it from docker if not already cached
+func (r *ClusterResolver) Cluster(name string) (*Cluster, error) {
+	if c, ok := r.clusters[name]; ok {
+		return c, nil
+	}
+
+	c, err := FromDocker(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.AllNodes()) == 0 {
+		return nil, errors.Errorf("a cluster with the name %q does not exists", name)
+	}
+
+	r.clusters[name] = c
+	return c, nil
+}
+
+// SelectNodes returns Nodes according to the given selector, with an optional clustername/ prefix;
+// see Cluster.SelectNodes for the supported selectors.
+func (r *ClusterResolver) SelectNodes(nodeSelector string) (NodeList, error) {
+	c, nodeSelector, err := r.resolve(nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	return c.SelectNodes(nodeSelector)
+}
+
+// ResolveNodesPath takes a "topology aware" path in the form [[clustername/]selector:]path, and resolve it
+// to one (or more) real paths; see Cluster.ResolveNodesPath.
+func (r *ClusterResolver) ResolveNodesPath(nodesPath string) (NodeList, string, error) {
+	selector, path := splitNodesPath(nodesPath)
+	if selector == "" {
+		return r.defaultCluster.ResolveNodesPath(nodesPath)
+	}
+
+	c, nodeSelector, err := r.resolve(selector)
+	if err != nil {
+		return nil, "", err
+	}
+	return c.ResolveNodesPath(nodeSelector + ":" + path)
+}
+
+// resolve returns the cluster targeted by a selector, and the selector without the cluster prefix
+func (r *ClusterResolver) resolve(nodeSelector string) (*Cluster, string, error) {
+	clusterName, nodeSelector := splitClusterSelector(nodeSelector)
+	if clusterName == "" {
+		return r.defaultCluster, nodeSelector, nil
+	}
+
+	c, err := r.Cluster(clusterName)
+	if err != nil {
+		return nil, "", err
+	}
+	return c, nodeSelector, nil
+}
+
+// splitClusterSelector splits a selector in the optional cluster name prefix and the node selector
+func splitClusterSelector(selector string) (string, string) {
+	parts := strings.SplitN(selector, "/", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", selector
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+func TestClusterResolver(t *testing.T) {
+	kindCP1 := &Node{name: "kind-control-plane-1"}
+	kind2CP1 := &Node{name: "kind2-control-plane-1"}
+	kind2W1 := &Node{name: "kind2-worker-1"}
+
+	kind := &Cluster{
+		name:          "kind",
+		allNodes:      NodeList{kindCP1},
+		k8sNodes:      NodeList{kindCP1},
+		controlPlanes: NodeList{kindCP1},
+	}
+	kind2 := &Cluster{
+		name:          "kind2",
+		allNodes:      NodeList{kind2CP1, kind2W1},
+		k8sNodes:      NodeList{kind2CP1, kind2W1},
+		controlPlanes: NodeList{kind2CP1},
+		workers:       NodeList{kind2W1},
+	}
+
+	// kind2 is pre-loaded in the cache, so the test does not require docker
+	r := NewClusterResolver(kind)
+	r.clusters["kind2"] = kind2
+
+	tests := []struct {
+		selector      string
+		expectedNodes []string
+	}{
+		{selector: "@cp1", expectedNodes: []string{"kind-control-plane-1"}},
+		{selector: "kind/@cp1", expectedNodes: []string{"kind-control-plane-1"}},
+		{selector: "kind2/@cp1", expectedNodes: []string{"kind2-control-plane-1"}},
+		{selector: "kind2/@all", expectedNodes: []string{"kind2-control-plane-1", "kind2-worker-1"}},
+		{selector: "kind2/worker-1", expectedNodes: []string{"kind2-worker-1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			nodes, err := r.SelectNodes(test.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var names []string
+			for _, n := range nodes {
+				names = append(names, n.Name())
+			}
+			if len(names) != len(test.expectedNodes) {
+				t.Fatalf("expected nodes: %v, found %v", test.expectedNodes, names)
+			}
+			for i := range names {
+				if names[i] != test.expectedNodes[i] {
+					t.Fatalf("expected nodes: %v, found %v", test.expectedNodes, names)
+				}
+			}
+		})
+	}
+
+	nodes, path, err := r.ResolveNodesPath("kind2/@w1:/etc/kubernetes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0] != kind2W1 || path != "/etc/kubernetes" {
+		t.Fatalf("unexpected nodes %v and path %q", nodes, path)
+	}
+}
+
+func TestSplitClusterSelector(t *testing.T) {
+	tests := []struct {
+		selector        string
+		expectedCluster string
+		expectedNode    string
+	}{
+		{selector: "@cp1", expectedNode: "@cp1"},
+		{selector: "cluster2/@cp1", expectedCluster: "cluster2", expectedNode: "@cp1"},
+		{selector: "cluster2/worker-1", expectedCluster: "cluster2", expectedNode: "worker-1"},
+		{selector: "/@cp1", expectedNode: "/@cp1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			cluster, node := splitClusterSelector(test.selector)
+			if cluster != test.expectedCluster || node != test.expectedNode {
+				t.Fatalf("expected %q, %q, found %q, %q", test.expectedCluster, test.expectedNode, cluster, node)
+			}
+		})
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package status
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -35,6 +37,9 @@ import (
 	ksigsyaml "sigs.k8s.io/yaml"
 )
 
+// defaultNetwork is the name of the docker network node containers are connected to by default
+const defaultNetwork = "bridge"
+
 // commandMutator define a function that can mutate commands on a node.
 // It is used to inject behaviours that should apply to all the command
 // executed on a node, like e.g. DryRun
@@ -44,13 +49,21 @@ type commandMutator = func(cmd *exec.NodeCmd) *exec.NodeCmd
 // one external dependency of the cluster, like etcd or the load balancer.
 type Node struct {
 	name            string
+	id              string
 	role            string
 	ports           map[int32]int32
 	ipv4            string
 	ipv6            string
 	cri             ContainerRuntime
 	etcdImage       string
+	arch            string
 	skip            bool
+	ineligible      bool
+	markerChecked   bool
+	included        bool
+	ineligibleShown bool
+	bootstrap       bool
+	kubeNodeName    string
 	commandMutators []commandMutator
 }
 
@@ -67,18 +80,37 @@ type NodeSettings struct {
 // NewNode returns a new kinder.Node wrapper
 func NewNode(name string) (n *Node, err error) {
 	// retrive the role the node using docker inspect
-	lines, err := host.InspectContainer(name, fmt.Sprintf("{{index .Config.Labels %q}}", constants.DeprecatedNodeRoleLabelKey))
+	role, err := inspectLabel(name, constants.DeprecatedNodeRoleLabelKey)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get %q label", constants.DeprecatedNodeRoleLabelKey)
+		return nil, err
 	}
-	if len(lines) != 1 {
-		return nil, errors.Errorf("%q label should only be one line, got %d lines", constants.DeprecatedNodeRoleLabelKey, len(lines))
+
+	// retrive if the node is excluded from actions using docker inspect
+	value, err := inspectLabel(name, constants.IneligibleLabelKey)
+	if err != nil {
+		return nil, err
+	}
+	ineligible := strings.EqualFold(value, "true")
+
+	// retrive if the node is designated as bootstrap control plane using docker inspect
+	value, err = inspectLabel(name, constants.BootstrapControlPlaneLabelKey)
+	if err != nil {
+		return nil, err
+	}
+	bootstrap := role == constants.ControlPlaneNodeRoleValue && strings.EqualFold(value, "true")
+
+	// retrive the Kubernetes node name, if different from the container name, using docker inspect
+	kubeNodeName, err := inspectLabel(name, constants.KubeNodeNameLabelKey)
+	if err != nil {
+		return nil, err
 	}
-	role := strings.Trim(lines[0], "'")
 
 	return &Node{
-		name: name,
-		role: role,
+		name:         name,
+		role:         role,
+		ineligible:   ineligible,
+		bootstrap:    bootstrap,
+		kubeNodeName: kubeNodeName,
 	}, nil
 }
 
@@ -87,6 +119,20 @@ func (n *Node) Name() string {
 	return n.name
 }
 
+// KubeNodeName returns the name of the Kubernetes node hosted by the node, that is the container name
+// unless a different node name was defined at create time
+func (n *Node) KubeNodeName() string {
+	if n.kubeNodeName != "" {
+		return n.kubeNodeName
+	}
+	return n.name
+}
+
+// ID returns the ID of the container hosting the node
+func (n *Node) ID() string {
+	return n.id
+}
+
 // Role returns the role of the node
 func (n *Node) Role() string {
 	// use the cached version populated by NewNode
@@ -114,6 +160,11 @@ func (n *Node) IsExternalLoadBalancer() bool {
 	return n.Role() == constants.ExternalLoadBalancerNodeRoleValue
 }
 
+// IsExternalRegistry returns true if the node hosts a registry used as a mirror by the K8s nodes
+func (n *Node) IsExternalRegistry() bool {
+	return n.Role() == constants.ExternalRegistryNodeRoleValue
+}
+
 // ProvisioningOrder returns the provisioning order for nodes, that
 // should be defined according to the assigned Role; is used to get consistent
 // and repeatable ordering in the list of nodes
@@ -125,12 +176,14 @@ func (n *Node) provisioningOrder() int {
 		return 1
 	case constants.ExternalLoadBalancerNodeRoleValue:
 		return 2
+	case constants.ExternalRegistryNodeRoleValue:
+		return 3
 	// Then control plane nodes
 	case constants.ControlPlaneNodeRoleValue:
-		return 3
+		return 4
 	// Finally workers
 	case constants.WorkerNodeRoleValue:
-		return 4
+		return 5
 	default:
 		return 99
 	}
@@ -154,6 +207,59 @@ func (n *Node) SkipActions() {
 	n.skip = true
 }
 
+// CheckEligibility checks if the node is excluded from actions by the IneligibleMarkerPath file, in addition to
+// the IneligibleLabelKey docker label; the file is checked only the first time this method succeeds, and the
+// result is cached for ExcludedFromActions.
+func (n *Node) CheckEligibility() error {
+	if n.ineligible || n.markerChecked {
+		return nil
+	}
+	marked, err := ineligibleMarkerExists(n)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check if node %s is excluded from actions", n.name)
+	}
+	n.ineligible = marked
+	n.markerChecked = true
+	return nil
+}
+
+// ExcludedFromActions returns true if the node is excluded from actions by the IneligibleLabelKey docker label or,
+// once CheckEligibility is called, by the IneligibleMarkerPath file; it does not run any command on the node.
+func (n *Node) ExcludedFromActions() bool {
+	return !n.included && n.ineligible
+}
+
+// ineligibleMarkerExists returns true if the IneligibleMarkerPath file exists on a node; the marker is checked also
+// when dry running, and stopped nodes are never marked. It is a variable so it can be replaced in tests.
+var ineligibleMarkerExists = func(n *Node) (bool, error) {
+	running, err := n.IsRunning()
+	if err != nil || !running {
+		return false, err
+	}
+	lines, err := exec.NewNodeCmd(n.name, "sh", "-c",
+		fmt.Sprintf("if [ -f %s ]; then echo true; else echo false; fi", constants.IneligibleMarkerPath),
+	).Silent().RunAndCapture()
+	if err != nil {
+		return false, err
+	}
+	if len(lines) != 1 {
+		return false, errors.Errorf("checking the %s file should return only one line, got %d lines", constants.IneligibleMarkerPath, len(lines))
+	}
+	return lines[0] == "true", nil
+}
+
+// IsBootstrapControlPlane returns true if the node is designated as bootstrap control plane, either by the
+// BootstrapControlPlaneLabelKey docker label or by Cluster.SetBootstrapControlPlane.
+func (n *Node) IsBootstrapControlPlane() bool {
+	return n.bootstrap
+}
+
+// IncludeInActions overrides the IneligibleLabelKey docker label and the IneligibleMarkerPath file, so the node
+// is eligible for actions.
+func (n *Node) IncludeInActions() {
+	n.included = true
+}
+
 // DryRun instruct the node to dry run all the commands that will be executed on this node.
 // DryRun differs from SkipRun, because in case of DryRun kinder prints all the details for running
 // the command manually.
@@ -169,12 +275,46 @@ func (n *Node) DryRun() {
 	)
 }
 
+// KubeadmBinary instructs the node to use the kubeadm binary at the given path for all the kubeadm commands
+// executed on this node, instead of the kubeadm binary in the PATH, e.g. for testing a locally-built kubeadm.
+// Nb. kubeadm invocations embedded in shell scripts are not affected.
+func (n *Node) KubeadmBinary(path string) {
+	if n.commandMutators == nil {
+		n.commandMutators = []commandMutator{}
+	}
+
+	n.commandMutators = append(n.commandMutators,
+		func(c *exec.NodeCmd) *exec.NodeCmd {
+			return c.ReplaceCommand("kubeadm", path)
+		},
+	)
+}
+
+// CaptureKubeadmOutput instructs the node to write the combined output of the kubeadm init and join commands, including
+// phases, executed on this node to the given io.Writer, e.g. for comparing the kubeadm output across versions.
+// Nb. this should be set before KubeadmBinary, because commands are matched by the kubeadm command name.
+func (n *Node) CaptureKubeadmOutput(w io.Writer) {
+	if n.commandMutators == nil {
+		n.commandMutators = []commandMutator{}
+	}
+
+	n.commandMutators = append(n.commandMutators,
+		func(c *exec.NodeCmd) *exec.NodeCmd {
+			return c.TeeCommand("kubeadm", w, "init", "join")
+		},
+	)
+}
+
 // Infof print an information message in the same format of commands on the node;
 // the message is print after the prompt containing the kind (er) node name.
 func (n *Node) Infof(message string, args ...interface{}) {
+	if exec.IsJSONLog() {
+		exec.Logger(n.Name()).Info(fmt.Sprintf(message, args...))
+		return
+	}
 	node := colors.Prompt(fmt.Sprintf("%s:$ ", n.Name()))
 	command := colors.Info(fmt.Sprintf(message, args...))
-	fmt.Printf("\n%s%s\n", node, command)
+	fmt.Fprintf(exec.EchoOutput(), "\n%s%s\n", node, command)
 }
 
 // MustKubeadmVersion returns the kubeadm version installed on the node or panics
@@ -205,12 +345,21 @@ func (n *Node) KubeadmVersion() (*K8sVersion.Version, error) {
 }
 
 // EtcdImage returns the etcdImage that should be used with the kubernetes version
-// installed on this node
+// installed on this node; for external etcd nodes, this is the image the node is running.
 func (n *Node) EtcdImage() (string, error) {
 	if n.etcdImage != "" {
 		return n.etcdImage, nil
 	}
 
+	if n.IsExternalEtcd() {
+		image, err := n.ContainerImage()
+		if err != nil {
+			return "", err
+		}
+		n.etcdImage = image
+		return n.etcdImage, nil
+	}
+
 	kubeVersion, err := n.KubeVersion()
 	if err != nil {
 		return "", err
@@ -273,8 +422,14 @@ func (n *Node) ReadClusterSettings() (*ClusterSettings, error) {
 
 			return &settings, nil
 	*/
+
+	// the IP family is recorded at create time on all the K8s nodes
+	family, err := n.IPFamily()
+	if err != nil {
+		return nil, err
+	}
 	return &ClusterSettings{
-		IPFamily: IPv4Family,
+		IPFamily: family,
 	}, nil
 }
 
@@ -322,6 +477,77 @@ func (n *Node) ReadNodeSettings() (*NodeSettings, error) {
 	return &settings, nil
 }
 
+// ContainerImage returns the image of the container hosting the node
+func (n *Node) ContainerImage() (string, error) {
+	lines, err := host.InspectContainer(n.name, "{{.Config.Image}}")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the image of node %s", n.name)
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("the image of node %s should only be one line, got %d lines", n.name, len(lines))
+	}
+	return strings.Trim(lines[0], "'"), nil
+}
+
+// Arch returns the architecture of the node, that is the architecture of the image the node container
+// was created from, e.g. amd64 or arm64
+func (n *Node) Arch() (string, error) {
+	if n.arch != "" {
+		return n.arch, nil
+	}
+
+	lines, err := host.InspectContainer(n.name, "{{.Image}}")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the image ID of node %s", n.name)
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("the image ID of node %s should only be one line, got %d lines", n.name, len(lines))
+	}
+
+	lines, err = host.InspectContainer(strings.Trim(lines[0], "'"), "{{.Architecture}}")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the architecture of node %s", n.name)
+	}
+	if len(lines) != 1 {
+		return "", errors.Errorf("the architecture of node %s should only be one line, got %d lines", n.name, len(lines))
+	}
+	n.arch = strings.Trim(lines[0], "'")
+	return n.arch, nil
+}
+
+// IsRunning returns true if the container hosting the node is running
+func (n *Node) IsRunning() (bool, error) {
+	lines, err := host.InspectContainer(n.name, "{{.State.Running}}")
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get the state of node %s", n.name)
+	}
+	if len(lines) != 1 {
+		return false, errors.Errorf("the state of node %s should only be one line, got %d lines", n.name, len(lines))
+	}
+	return strings.Trim(lines[0], "'") == "true", nil
+}
+
+// IsReady returns true if the container hosting the node is running and the kubelet is healthy, that is
+// the kubelet service is active and the kubelet healthz endpoint answers ok; differently from checking
+// the Node Ready condition, this does not require the API server to be available
+func (n *Node) IsReady() (bool, error) {
+	running, err := n.IsRunning()
+	if err != nil || !running {
+		return false, err
+	}
+
+	lines, err := n.Command("systemctl", "is-active", "kubelet").Silent().RunAndCapture()
+	if err != nil || len(lines) != 1 || strings.TrimSpace(lines[0]) != "active" {
+		return false, nil
+	}
+
+	lines, err = n.Command("curl", "-s", "http://127.0.0.1:10248/healthz").Silent().RunAndCapture()
+	if err != nil || len(lines) != 1 || strings.TrimSpace(lines[0]) != "ok" {
+		return false, nil
+	}
+	return true, nil
+}
+
 // CRI returns the ContainerRuntime installed on the node and that
 // should be used by kubeadm for creating the K8s cluster
 func (n *Node) CRI() (cri ContainerRuntime, err error) {
@@ -367,6 +593,13 @@ func (n *Node) Ports(containerPort int32) (hostPort int32, err error) {
 	return hostPort, nil
 }
 
+// NodeIPs defines static IP addresses for a node container, e.g. for preserving the node IPs when importing
+// a cluster; empty addresses are assigned by docker
+type NodeIPs struct {
+	IPv4 string `json:"ipv4,omitempty"`
+	IPv6 string `json:"ipv6,omitempty"`
+}
+
 // IP returns the IP address of the node
 func (n *Node) IP() (ipv4 string, ipv6 string, err error) {
 	// use the cached version first
@@ -374,16 +607,20 @@ func (n *Node) IP() (ipv4 string, ipv6 string, err error) {
 		return n.ipv4, n.ipv6, nil
 	}
 	// retrive the IP address of the node using docker inspect
-	lines, err := host.InspectContainer(n.name, "{{range .NetworkSettings.Networks}}{{.IPAddress}},{{.GlobalIPv6Address}}{{end}}")
+	lines, err := host.InspectContainer(n.name, "{{range $name, $net := .NetworkSettings.Networks}}{{$name}},{{$net.IPAddress}},{{$net.GlobalIPv6Address}};{{end}}")
 	if err != nil {
 		return "", "", errors.Wrap(err, "failed to get container details")
 	}
 	if len(lines) != 1 {
 		return "", "", errors.Errorf("file should only be one line, got %d lines: %v", len(lines), lines)
 	}
-	ips := strings.Split(lines[0], ",")
-	if len(ips) != 2 {
-		return "", "", errors.Errorf("container addresses should have 2 values, got %d values: %v", len(ips), ips)
+	network, err := n.Network()
+	if err != nil {
+		return "", "", err
+	}
+	ips, err := parseNetworkAddresses(lines[0], network)
+	if err != nil {
+		return "", "", err
 	}
 
 	n.ipv4 = ips[0]
@@ -392,6 +629,42 @@ func (n *Node) IP() (ipv4 string, ipv6 string, err error) {
 	return ips[0], ips[1], nil
 }
 
+// parseNetworkAddresses parses a list of name,ipv4,ipv6 network addresses separated by semicolons
+// and returns the ipv4 and ipv6 addresses of the node; if the node is connected to more than one network,
+// e.g. external etcd members, the addresses on the cluster network are returned, that is the given network
+// or the default bridge network if empty
+func parseNetworkAddresses(value, network string) ([]string, error) {
+	if network == "" {
+		network = defaultNetwork
+	}
+
+	var networks [][]string
+	for _, network := range strings.Split(strings.Trim(value, "'"), ";") {
+		if network == "" {
+			continue
+		}
+		fields := strings.Split(network, ",")
+		if len(fields) != 3 {
+			return nil, errors.Errorf("container addresses should have 2 values, got %d values: %v", len(fields)-1, fields[1:])
+		}
+		networks = append(networks, fields)
+	}
+
+	switch len(networks) {
+	case 0:
+		return nil, errors.New("container is not connected to any network")
+	case 1:
+		return networks[0][1:], nil
+	}
+
+	for _, fields := range networks {
+		if fields[0] == network {
+			return fields[1:], nil
+		}
+	}
+	return nil, errors.Errorf("container is connected to more than one network, but not to the %q network", network)
+}
+
 // CopyFrom copies the source file on the node to dest on the host.
 // Please note that this have limitations around symlinks.
 func (n *Node) CopyFrom(source, dest string) error {
@@ -413,29 +686,42 @@ func (n *Node) CopyTo(source, dest string) error {
 	return cmd.RunWithEcho()
 }
 
-// WriteFile writes a temporary file with the given contents and copies the file to the node container
+// fileErrorTailLines defines the number of lines of output reported in errors when reading or writing files
+const fileErrorTailLines = 5
+
+// WriteFile writes a file with the given contents on the node container, with 0600 permissions
 func (n *Node) WriteFile(containerPath string, contents []byte) error {
-	// Write the contents as a temporary file
-	tmpfile, err := os.CreateTemp("", fmt.Sprintf("%s-*", n.name))
-	if err != nil {
-		errors.Wrap(err, "could not create temporary file")
-	}
-	tmpPath := tmpfile.Name()
-	defer os.Remove(tmpPath)
-	if _, err := tmpfile.Write(contents); err != nil {
-		return errors.Wrapf(err, "failed to write temporary file %s", tmpPath)
-	}
-	if err := tmpfile.Close(); err != nil {
-		return errors.Wrapf(err, "failed to close temporary file %s", tmpPath)
-	}
+	return n.WriteFileWithPerm(containerPath, contents, 0600)
+}
 
-	// Copy the temporary file to the container
-	if err := n.CopyTo(tmpPath, containerPath); err != nil {
+// WriteFileWithPerm writes a file with the given contents and permissions on the node container, creating
+// the parent directories if missing. Contents are streamed to the node, so no temporary files are
+// used on the host; the file is written also when dry running, like when copying files.
+func (n *Node) WriteFileWithPerm(containerPath string, contents []byte, perm os.FileMode) error {
+	// the umask ensures the file is never readable by others before setting the permissions
+	const script = `umask 077 && mkdir -p "$(dirname "$1")" && cat > "$1" && chmod "$2" "$1"`
+	if err := exec.NewNodeCmd(
+		n.name,
+		"sh", "-c", script, "sh", containerPath, fmt.Sprintf("%o", perm.Perm()),
+	).Stdin(bytes.NewReader(contents)).Silent().TailOnError(fileErrorTailLines).Run(); err != nil {
 		return errors.Wrapf(err, "failed to write %s", containerPath)
 	}
 	return nil
 }
 
+// ReadFile reads a file on the node container and returns its contents; like WriteFile,
+// the file is read also when dry running
+func (n *Node) ReadFile(containerPath string) ([]byte, error) {
+	var out bytes.Buffer
+	if err := exec.NewNodeCmd(
+		n.name,
+		"cat", containerPath,
+	).Stdout(&out).Silent().TailOnError(fileErrorTailLines).Run(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", containerPath)
+	}
+	return out.Bytes(), nil
+}
+
 // KubeVersion returns the Kubernetes version installed on the node
 func (n *Node) KubeVersion() (version string, err error) {
 	// grab kubernetes version from the node image
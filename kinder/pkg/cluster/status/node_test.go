@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNetworkAddresses(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		network       string
+		expected      []string
+		expectedError bool
+	}{
+		{
+			name:     "valid: one network",
+			input:    "'bridge,172.17.0.2,;'",
+			expected: []string{"172.17.0.2", ""},
+		},
+		{
+			name:     "valid: one network with ipv6",
+			input:    "bridge,172.17.0.2,fc00:f853:ccd:e793::2;",
+			expected: []string{"172.17.0.2", "fc00:f853:ccd:e793::2"},
+		},
+		{
+			name:     "valid: more networks, the default network is preferred",
+			input:    "kind-etcd,172.18.0.2,;bridge,172.17.0.3,;",
+			expected: []string{"172.17.0.3", ""},
+		},
+		{
+			name:     "valid: more networks, the cluster network is preferred",
+			input:    "kind-etcd,172.18.0.2,;my-net,172.20.0.3,;",
+			network:  "my-net",
+			expected: []string{"172.20.0.3", ""},
+		},
+		{
+			name:          "invalid: more networks without the cluster network",
+			input:         "kind-etcd,172.18.0.2,;bridge,172.17.0.3,;",
+			network:       "my-net",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: more networks without the default network",
+			input:         "kind-etcd,172.18.0.2,;other,172.19.0.3,;",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: no networks",
+			input:         "''",
+			expectedError: true,
+		},
+		{
+			name:          "invalid: malformed",
+			input:         "bridge,172.17.0.2;",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ips, err := parseNetworkAddresses(test.input, test.network)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if !reflect.DeepEqual(ips, test.expected) {
+				t.Fatalf("expected: %v, found %v", test.expected, ips)
+			}
+		})
+	}
+}
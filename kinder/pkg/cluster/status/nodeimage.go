@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kubeadm/kinder/pkg/cri/host"
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// NodeImageInfo defines the kinder relevant metadata baked into a node image
+type NodeImageInfo struct {
+	Image             string           `json:"image"`
+	KubernetesVersion string           `json:"kubernetesVersion,omitempty"`
+	KubeadmVersion    string           `json:"kubeadmVersion,omitempty"`
+	ContainerRuntime  ContainerRuntime `json:"containerRuntime"`
+	CNI               string           `json:"cni,omitempty"`
+	BaseDistro        string           `json:"baseDistro,omitempty"`
+	PreloadedImages   []string         `json:"preloadedImages,omitempty"`
+}
+
+// InspectNodeImage inspect a node image and returns the metadata relevant for kinder,
+// like the Kubernetes version, the installed container runtime or the default CNI.
+// NB. similarly to InspectCRIinImage, all the information are read from a temporary container
+func InspectNodeImage(image string) (*NodeImageInfo, error) {
+	id := "kind-inspect-" + uuid.New().String()
+	runArgs := []string{
+		"-d", // make the client exit while the container continues to run
+		"--entrypoint=sleep",
+		"--name=" + id,
+	}
+	contatinerArgs := []string{"infinity"} // sleep infinitely to keep the container around
+
+	if err := host.Run(image, runArgs, contatinerArgs); err != nil {
+		return nil, errors.Wrap(err, "error creating a temporary container for image inspection")
+	}
+	defer func() {
+		exec.NewHostCmd("docker", "rm", "-f", id).Run()
+	}()
+
+	info := &NodeImageInfo{Image: image}
+
+	cri, err := InspectCRIinContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	info.ContainerRuntime = cri
+
+	// the version file and kubeadm are expected in every node image
+	lines, err := exec.NewNodeCmd(id, "cat", "/kind/version").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the Kubernetes version from the image")
+	}
+	info.KubernetesVersion = firstLine(lines)
+
+	lines, err = exec.NewNodeCmd(id, "kubeadm", "version", "-o", "short").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the kubeadm version from the image")
+	}
+	info.KubeadmVersion = firstLine(lines)
+
+	// the remaining information are optional, e.g. images built with --without-cni or custom base images
+	lines, err = exec.NewNodeCmd(id, "/bin/sh", "-c", "cat /kind/manifests/default-cni.yaml 2>/dev/null || true").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the default CNI manifest from the image")
+	}
+	if info.CNI, err = cniFromManifest([]byte(strings.Join(lines, "\n"))); err != nil {
+		return nil, err
+	}
+
+	lines, err = exec.NewNodeCmd(id, "/bin/sh", "-c", "cat /etc/os-release 2>/dev/null || true").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the os release from the image")
+	}
+	info.BaseDistro = distroFromOSRelease(lines)
+
+	lines, err = exec.NewNodeCmd(id, "/bin/sh", "-c", "ls /kind/images 2>/dev/null || true").Silent().RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the pre-loaded images in the image")
+	}
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			info.PreloadedImages = append(info.PreloadedImages, l)
+		}
+	}
+
+	return info, nil
+}
+
+// cniFromManifest returns the name of the first DaemonSet defined in a CNI manifest, if any
+func cniFromManifest(manifest []byte) (string, error) {
+	for _, doc := range bytes.Split(manifest, []byte("\n---")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj := struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return "", errors.Wrap(err, "failed to parse the default CNI manifest")
+		}
+		if obj.Kind == "DaemonSet" {
+			return obj.Metadata.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// distroFromOSRelease returns the human readable name of the distribution from /etc/os-release,
+// falling back to the distribution id when the pretty name is missing
+func distroFromOSRelease(lines []string) string {
+	values := map[string]string{}
+	for _, l := range lines {
+		i := strings.Index(l, "=")
+		if i < 0 {
+			continue
+		}
+		values[strings.TrimSpace(l[:i])] = strings.Trim(strings.TrimSpace(l[i+1:]), `"'`)
+	}
+	if v, ok := values["PRETTY_NAME"]; ok {
+		return v
+	}
+	return values["ID"]
+}
+
+func firstLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(lines[0])
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+func TestCNIFromManifest(t *testing.T) {
+	cases := []struct {
+		name     string
+		manifest string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "empty manifest",
+			manifest: "",
+			expected: "",
+		},
+		{
+			name: "daemonset after other objects",
+			manifest: "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: kindnet\n" +
+				"---\napiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: kindnet\n  namespace: kube-system\n",
+			expected: "kindnet",
+		},
+		{
+			name:     "no daemonset",
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cni\n",
+			expected: "",
+		},
+		{
+			name:     "invalid yaml",
+			manifest: "kind: [DaemonSet\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cni, err := cniFromManifest([]byte(c.manifest))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("expected error %t, got %v", c.wantErr, err)
+			}
+			if cni != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, cni)
+			}
+		})
+	}
+}
+
+func TestDistroFromOSRelease(t *testing.T) {
+	cases := []struct {
+		name     string
+		lines    []string
+		expected string
+	}{
+		{
+			name:     "pretty name",
+			lines:    []string{`NAME="Debian GNU/Linux"`, `ID=debian`, `PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"`},
+			expected: "Debian GNU/Linux 12 (bookworm)",
+		},
+		{
+			name:     "fallback to id",
+			lines:    []string{"ID=ubuntu", "VERSION_ID=22.04"},
+			expected: "ubuntu",
+		},
+		{
+			name:     "missing os-release",
+			lines:    nil,
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if distro := distroFromOSRelease(c.lines); distro != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, distro)
+			}
+		})
+	}
+}
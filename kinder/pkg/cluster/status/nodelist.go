@@ -18,27 +18,57 @@ package status
 
 import (
 	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
 )
 
 // NodeList defines a list of Node
 type NodeList []*Node
 
-// Sort the list of Node wrapper by node provisioning order and by name
+// Sort the list of Node wrapper by node provisioning order and by name; the designated bootstrap
+// control plane is sorted before the other control planes
 func (l NodeList) Sort() {
 	sort.Slice(l, func(i, j int) bool {
-		return l[i].provisioningOrder() < l[j].provisioningOrder() ||
-			(l[i].provisioningOrder() == l[j].provisioningOrder() && l[i].Name() < l[j].Name())
+		if l[i].provisioningOrder() != l[j].provisioningOrder() {
+			return l[i].provisioningOrder() < l[j].provisioningOrder()
+		}
+		// the designated bootstrap control plane, if any, goes before the other control planes
+		if l[i].bootstrap != l[j].bootstrap {
+			return l[i].bootstrap
+		}
+		return l[i].Name() < l[j].Name()
 	})
 }
 
-// EligibleForActions returns the list of nodes without nodes marked as SkipAction
+// EligibleForActions returns the list of nodes without nodes marked as SkipAction and without nodes
+// excluded by the IneligibleLabelKey docker label or by the IneligibleMarkerPath file, as checked when reading
+// the cluster nodes; the latter are logged once, so it is clear why actions skip them
 func (l NodeList) EligibleForActions() NodeList {
 	var res NodeList
 	for _, n := range l {
-		if !n.skip {
-			res = append(res, n)
+		if n.skip {
+			continue
+		}
+		if n.ExcludedFromActions() {
+			if !n.ineligibleShown {
+				log.Infof("Skipping node %s, excluded from actions by the %s=true label or the %s file", n.Name(), constants.IneligibleLabelKey, constants.IneligibleMarkerPath)
+				n.ineligibleShown = true
+			}
+			continue
 		}
+		res = append(res, n)
 	}
 	res.Sort()
 	return res
 }
+
+// Names returns the names of the nodes in the list
+func (l NodeList) Names() []string {
+	names := []string{}
+	for _, n := range l {
+		names = append(names, n.Name())
+	}
+	return names
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+func TestEligibleForActions(t *testing.T) {
+	// nodes named kind-marked have the ineligible marker file
+	defer func(f func(*Node) (bool, error)) { ineligibleMarkerExists = f }(ineligibleMarkerExists)
+	ineligibleMarkerExists = func(n *Node) (bool, error) { return n.name == "kind-marked", nil }
+
+	tests := []struct {
+		name     string
+		nodes    func() NodeList
+		expected []string
+	}{
+		{
+			name: "all nodes are eligible, sorted by provisioning order",
+			nodes: func() NodeList {
+				return NodeList{
+					{name: "kind-worker", role: constants.WorkerNodeRoleValue},
+					{name: "kind-control-plane", role: constants.ControlPlaneNodeRoleValue},
+				}
+			},
+			expected: []string{"kind-control-plane", "kind-worker"},
+		},
+		{
+			name: "nodes marked as SkipActions are excluded",
+			nodes: func() NodeList {
+				w := &Node{name: "kind-worker", role: constants.WorkerNodeRoleValue}
+				w.SkipActions()
+				return NodeList{w, {name: "kind-control-plane", role: constants.ControlPlaneNodeRoleValue}}
+			},
+			expected: []string{"kind-control-plane"},
+		},
+		{
+			name: "nodes excluded by label are excluded",
+			nodes: func() NodeList {
+				return NodeList{
+					{name: "kind-worker", role: constants.WorkerNodeRoleValue, ineligible: true},
+					{name: "kind-control-plane", role: constants.ControlPlaneNodeRoleValue},
+				}
+			},
+			expected: []string{"kind-control-plane"},
+		},
+		{
+			name: "nodes excluded by label can be included",
+			nodes: func() NodeList {
+				w := &Node{name: "kind-worker", role: constants.WorkerNodeRoleValue, ineligible: true}
+				w.IncludeInActions()
+				return NodeList{w, {name: "kind-control-plane", role: constants.ControlPlaneNodeRoleValue}}
+			},
+			expected: []string{"kind-control-plane", "kind-worker"},
+		},
+		{
+			name: "nodes excluded by the marker file are excluded",
+			nodes: func() NodeList {
+				return NodeList{
+					{name: "kind-marked", role: constants.WorkerNodeRoleValue},
+					{name: "kind-control-plane", role: constants.ControlPlaneNodeRoleValue},
+				}
+			},
+			expected: []string{"kind-control-plane"},
+		},
+		{
+			name: "nodes excluded by the marker file can be included",
+			nodes: func() NodeList {
+				w := &Node{name: "kind-marked", role: constants.WorkerNodeRoleValue}
+				w.IncludeInActions()
+				return NodeList{w, {name: "kind-control-plane", role: constants.ControlPlaneNodeRoleValue}}
+			},
+			expected: []string{"kind-control-plane", "kind-marked"},
+		},
+		{
+			name: "the bootstrap control plane is sorted before the other control planes",
+			nodes: func() NodeList {
+				return NodeList{
+					{name: "kind-worker", role: constants.WorkerNodeRoleValue},
+					{name: "kind-control-plane-1", role: constants.ControlPlaneNodeRoleValue},
+					{name: "kind-control-plane-2", role: constants.ControlPlaneNodeRoleValue, bootstrap: true},
+					{name: "kind-lb", role: constants.ExternalLoadBalancerNodeRoleValue},
+				}
+			},
+			expected: []string{"kind-lb", "kind-control-plane-2", "kind-control-plane-1", "kind-worker"},
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			nodes := rt.nodes()
+			for _, n := range nodes {
+				if err := n.CheckEligibility(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			got := nodes.EligibleForActions().Names()
+			if !reflect.DeepEqual(got, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheckEligibility(t *testing.T) {
+	// the marker check fails the first time, then it finds the marker file
+	defer func(f func(*Node) (bool, error)) { ineligibleMarkerExists = f }(ineligibleMarkerExists)
+	checks := 0
+	ineligibleMarkerExists = func(n *Node) (bool, error) {
+		checks++
+		if checks == 1 {
+			return false, errors.New("docker exec failed")
+		}
+		return true, nil
+	}
+
+	n := &Node{name: "kind-worker", role: constants.WorkerNodeRoleValue}
+	if err := n.CheckEligibility(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if n.ExcludedFromActions() {
+		t.Error("expected the node not to be excluded after a failed check")
+	}
+	for i := 0; i < 2; i++ {
+		if err := n.CheckEligibility(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !n.ExcludedFromActions() {
+		t.Error("expected the node to be excluded by the marker file")
+	}
+	if checks != 2 {
+		t.Errorf("expected the marker file to be checked until the first success, got %d checks", checks)
+	}
+
+	// nodes excluded by the label are not checked
+	labeled := &Node{name: "kind-worker-2", role: constants.WorkerNodeRoleValue, ineligible: true}
+	if err := labeled.CheckEligibility(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checks != 2 {
+		t.Errorf("expected the marker file not to be checked on nodes excluded by the label, got %d checks", checks)
+	}
+}
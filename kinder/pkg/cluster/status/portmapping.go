@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// PortMapping defines an extra mapping between a port of a node container and a port on the host
+type PortMapping struct {
+	ContainerPort int32
+	HostPort      int32
+	Protocol      string
+}
+
+// String returns the port mapping in the containerPort:hostPort:protocol form
+func (p PortMapping) String() string {
+	return fmt.Sprintf("%d:%d:%s", p.ContainerPort, p.HostPort, p.Protocol)
+}
+
+// ParsePortMapping parses a port mapping in the containerPort:hostPort[:protocol] form;
+// if not specified, the protocol defaults to tcp
+func ParsePortMapping(value string) (PortMapping, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return PortMapping{}, errors.Errorf("invalid port mapping %q. Use the containerPort:hostPort[:protocol] form", value)
+	}
+
+	containerPort, err := parsePort(parts[0])
+	if err != nil {
+		return PortMapping{}, errors.Wrapf(err, "invalid container port in port mapping %q", value)
+	}
+
+	hostPort, err := parsePort(parts[1])
+	if err != nil {
+		return PortMapping{}, errors.Wrapf(err, "invalid host port in port mapping %q", value)
+	}
+
+	protocol := "tcp"
+	if len(parts) == 3 {
+		protocol = strings.ToLower(parts[2])
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return PortMapping{}, errors.Errorf("invalid protocol %q in port mapping %q. Use one of [tcp, udp]", parts[2], value)
+	}
+
+	return PortMapping{
+		ContainerPort: containerPort,
+		HostPort:      hostPort,
+		Protocol:      protocol,
+	}, nil
+}
+
+func parsePort(value string) (int32, error) {
+	port, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("%q is not a number", value)
+	}
+	if port < 1 || port > 65535 {
+		return 0, errors.Errorf("%d is out of the valid range [1-65535]", port)
+	}
+	return int32(port), nil
+}
+
+// ExtraPortMappings returns the extra port mappings applied to the node at create time
+func (n *Node) ExtraPortMappings() ([]PortMapping, error) {
+	values, err := n.listLabel(constants.ExtraPortMappingsLabelKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []PortMapping
+	for _, value := range values {
+		mapping, err := ParsePortMapping(value)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
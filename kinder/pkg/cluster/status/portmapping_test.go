@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+func TestParsePortMapping(t *testing.T) {
+	var tests = []struct {
+		value         string
+		expected      PortMapping
+		expectedError bool
+	}{
+		{
+			value:    "30080:8080",
+			expected: PortMapping{ContainerPort: 30080, HostPort: 8080, Protocol: "tcp"},
+		},
+		{
+			value:    "53:5353:UDP",
+			expected: PortMapping{ContainerPort: 53, HostPort: 5353, Protocol: "udp"},
+		},
+		{
+			value:         "30080",
+			expectedError: true,
+		},
+		{
+			value:         "30080:8080:tcp:foo",
+			expectedError: true,
+		},
+		{
+			value:         "foo:8080",
+			expectedError: true,
+		},
+		{
+			value:         "30080:0",
+			expectedError: true,
+		},
+		{
+			value:         "70000:8080",
+			expectedError: true,
+		},
+		{
+			value:         "30080:8080:sctp",
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.value, func(t *testing.T) {
+			mapping, err := ParsePortMapping(rt.value)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("failed ParsePortMapping:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+			if err == nil && mapping != rt.expected {
+				t.Errorf("failed ParsePortMapping:\n\texpected: %v\n\tactual: %v", rt.expected, mapping)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// minNodeMemory is the minimum memory limit accepted by docker for a container
+const minNodeMemory = 6 * 1024 * 1024
+
+// NodeResources defines the CPU and memory limits of a node container, in the docker --cpus and --memory format,
+// e.g. 1.5 and 2g; limits are applied by docker to the container cgroup, so they persist across restarts of the container
+type NodeResources struct {
+	CPUs   string `json:"cpus,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// IsZero returns true if no limits are set
+func (r NodeResources) IsZero() bool {
+	return r.CPUs == "" && r.Memory == ""
+}
+
+// String returns the limits in the cpus=<value>,memory=<value> form
+func (r NodeResources) String() string {
+	var values []string
+	if r.CPUs != "" {
+		values = append(values, fmt.Sprintf("cpus=%s", r.CPUs))
+	}
+	if r.Memory != "" {
+		values = append(values, fmt.Sprintf("memory=%s", r.Memory))
+	}
+	return strings.Join(values, ",")
+}
+
+// Validate checks that the CPU limit is a positive number, and that the memory limit is a
+// quantity in the docker format, with an optional b, k, m or g unit, not lower than 6m
+func (r NodeResources) Validate() error {
+	if r.CPUs != "" {
+		if _, err := r.MilliCPUs(); err != nil {
+			return err
+		}
+	}
+	if r.Memory != "" {
+		if _, err := r.MemoryBytes(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MilliCPUs returns the CPU limit in millicores
+func (r NodeResources) MilliCPUs() (int64, error) {
+	cpus, err := strconv.ParseFloat(r.CPUs, 64)
+	if err != nil || cpus <= 0 || math.IsInf(cpus, 0) {
+		return 0, errors.Errorf("invalid CPU limit %q. Use a positive number, e.g. 1.5", r.CPUs)
+	}
+	return int64(math.Round(cpus * 1000)), nil
+}
+
+// MemoryBytes returns the memory limit in bytes
+func (r NodeResources) MemoryBytes() (int64, error) {
+	invalid := errors.Errorf("invalid memory limit %q. Use a positive number with an optional b, k, m or g unit, e.g. 2g", r.Memory)
+
+	value := strings.ToLower(r.Memory)
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'k':
+			multiplier = 1024
+		case 'm':
+			multiplier = 1024 * 1024
+		case 'g':
+			multiplier = 1024 * 1024 * 1024
+		}
+		if strings.ContainsAny(value[len(value)-1:], "bkmg") {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/multiplier {
+		return 0, invalid
+	}
+	bytes := n * multiplier
+	if bytes < minNodeMemory {
+		return 0, errors.Errorf("invalid memory limit %q. The minimum allowed value is 6m", r.Memory)
+	}
+	return bytes, nil
+}
+
+// SystemReserved returns the value for the kubelet --system-reserved flag that makes the node allocatable
+// resources reflect the limits, given the CPUs in millicores and the memory in bytes visible in the node;
+// the kubelet reports the resources of the host as node capacity, because limits are enforced by the
+// container cgroup. An empty value is returned if there is nothing to reserve
+func (r NodeResources) SystemReserved(hostMilliCPUs, hostMemoryBytes int64) string {
+	var values []string
+	if r.CPUs != "" {
+		if cpus, err := r.MilliCPUs(); err == nil && hostMilliCPUs > cpus {
+			values = append(values, fmt.Sprintf("cpu=%dm", hostMilliCPUs-cpus))
+		}
+	}
+	if r.Memory != "" {
+		if memory, err := r.MemoryBytes(); err == nil && hostMemoryBytes > memory {
+			values = append(values, fmt.Sprintf("memory=%dKi", (hostMemoryBytes-memory)/1024))
+		}
+	}
+	return strings.Join(values, ",")
+}
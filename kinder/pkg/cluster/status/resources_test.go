@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"testing"
+)
+
+func TestNodeResourcesValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		resources     NodeResources
+		expectedError bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:      "valid",
+			resources: NodeResources{CPUs: "1.5", Memory: "2g"},
+		},
+		{
+			name:      "valid memory in bytes",
+			resources: NodeResources{Memory: "104857600b"},
+		},
+		{
+			name:          "zero cpus",
+			resources:     NodeResources{CPUs: "0"},
+			expectedError: true,
+		},
+		{
+			name:          "negative cpus",
+			resources:     NodeResources{CPUs: "-1"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid cpus",
+			resources:     NodeResources{CPUs: "two"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid memory unit",
+			resources:     NodeResources{Memory: "2Gi"},
+			expectedError: true,
+		},
+		{
+			name:          "memory below the minimum",
+			resources:     NodeResources{Memory: "4m"},
+			expectedError: true,
+		},
+		{
+			name:          "empty memory with unit",
+			resources:     NodeResources{Memory: "g"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.resources.Validate()
+			if (err != nil) != tt.expectedError {
+				t.Errorf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNodeResourcesSystemReserved(t *testing.T) {
+	const gi = 1024 * 1024 * 1024
+
+	tests := []struct {
+		name      string
+		resources NodeResources
+		expected  string
+	}{
+		{
+			name: "no limits",
+		},
+		{
+			name:      "cpu and memory",
+			resources: NodeResources{CPUs: "1.5", Memory: "2g"},
+			expected:  "cpu=2500m,memory=6291456Ki",
+		},
+		{
+			name:      "limits above the host resources",
+			resources: NodeResources{CPUs: "8", Memory: "16g"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resources.SystemReserved(4000, 8*gi); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	ksigsyaml "sigs.k8s.io/yaml"
+)
+
+// NodeSummary defines a machine readable summary of a node
+type NodeSummary struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+	IPv4 string `json:"ipv4,omitempty"`
+	IPv6 string `json:"ipv6,omitempty"`
+}
+
+// NodeDetails defines a machine readable description of a node, with the versions installed on the K8s nodes;
+// this is the kinder get nodes --output=json format, so existing fields should not be changed
+type NodeDetails struct {
+	Name              string `json:"name"`
+	Role              string `json:"role"`
+	IPv4              string `json:"ipv4,omitempty"`
+	IPv6              string `json:"ipv6,omitempty"`
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	KubeadmVersion    string `json:"kubeadmVersion,omitempty"`
+}
+
+// CheckExists returns an error if the cluster has no nodes, e.g. because the cluster name is wrong
+func (c *Cluster) CheckExists() error {
+	if len(c.AllNodes()) == 0 {
+		return errors.Errorf("unknown cluster %q. Use kinder get clusters for listing the existing clusters", c.Name())
+	}
+	return nil
+}
+
+// NodesDetails returns the description of all the nodes in the cluster, sorted by provisioning order and by name;
+// versions are read only on running K8s nodes, and they are left empty if they can't be read
+func (c *Cluster) NodesDetails() ([]NodeDetails, error) {
+	if err := c.CheckExists(); err != nil {
+		return nil, err
+	}
+
+	nodes := append(NodeList{}, c.AllNodes()...)
+	nodes.Sort()
+
+	details := []NodeDetails{}
+	for _, n := range nodes {
+		ipv4, ipv6, err := n.IP()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+		}
+		d := NodeDetails{
+			Name: n.Name(),
+			Role: n.Role(),
+			IPv4: ipv4,
+			IPv6: ipv6,
+		}
+		if n.IsControlPlane() || n.IsWorker() {
+			if running, err := n.IsRunning(); err == nil && running {
+				if version, err := n.KubeVersion(); err == nil {
+					d.KubernetesVersion = version
+				}
+				if version, err := n.KubeadmVersion(); err == nil {
+					d.KubeadmVersion = "v" + version.String()
+				}
+			}
+		}
+		details = append(details, d)
+	}
+	return details, nil
+}
+
+// ClusterSummary defines a machine readable summary of a cluster
+type ClusterSummary struct {
+	Name              string        `json:"name"`
+	KubernetesVersion string        `json:"kubernetesVersion,omitempty"`
+	KubeConfigPath    string        `json:"kubeconfigPath"`
+	RegistryEndpoint  string        `json:"registryEndpoint,omitempty"`
+	Nodes             []NodeSummary `json:"nodes"`
+}
+
+// Summary returns a machine readable summary of the cluster, with the list of nodes, the kubeconfig path
+// the Kubernetes version in the bootstrap control-plane node image and the external registry endpoint, if any
+func (c *Cluster) Summary() (*ClusterSummary, error) {
+	summary := &ClusterSummary{
+		Name:           c.Name(),
+		KubeConfigPath: c.KubeConfigPath(),
+	}
+
+	if cp1 := c.BootstrapControlPlane(); cp1 != nil {
+		version, err := cp1.KubeVersion()
+		if err != nil {
+			return nil, err
+		}
+		summary.KubernetesVersion = version
+	}
+
+	registryEndpoint, err := c.RegistryEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	summary.RegistryEndpoint = registryEndpoint
+
+	for _, n := range c.AllNodes() {
+		ipv4, ipv6, err := n.IP()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get IP for node: %s", n.Name())
+		}
+		summary.Nodes = append(summary.Nodes, NodeSummary{
+			Name: n.Name(),
+			Role: n.Role(),
+			IPv4: ipv4,
+			IPv6: ipv6,
+		})
+	}
+
+	return summary, nil
+}
+
+// Format returns the summary encoded in the given output format, json or yaml
+func (s *ClusterSummary) Format(output string) ([]byte, error) {
+	switch output {
+	case "json":
+		out, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode the cluster summary")
+		}
+		return out, nil
+	case "yaml":
+		out, err := ksigsyaml.Marshal(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode the cluster summary")
+		}
+		return out, nil
+	default:
+		return nil, errors.Errorf("invalid output format %q. Use one of [json, yaml]", output)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+func TestClusterSummaryFormat(t *testing.T) {
+	summary := &ClusterSummary{
+		Name:              "kind",
+		KubernetesVersion: "v1.30.0",
+		KubeConfigPath:    "/root/.kube/kind-config-kind",
+		Nodes: []NodeSummary{
+			{Name: "kind-control-plane-1", Role: "control-plane", IPv4: "172.17.0.2"},
+		},
+	}
+
+	tests := []struct {
+		output        string
+		expected      string
+		expectedError bool
+	}{
+		{
+			output: "json",
+			expected: `{
+  "name": "kind",
+  "kubernetesVersion": "v1.30.0",
+  "kubeconfigPath": "/root/.kube/kind-config-kind",
+  "nodes": [
+    {
+      "name": "kind-control-plane-1",
+      "role": "control-plane",
+      "ipv4": "172.17.0.2"
+    }
+  ]
+}`,
+		},
+		{
+			output: "yaml",
+			expected: `kubeconfigPath: /root/.kube/kind-config-kind
+kubernetesVersion: v1.30.0
+name: kind
+nodes:
+- ipv4: 172.17.0.2
+  name: kind-control-plane-1
+  role: control-plane
+`,
+		},
+		{
+			output:        "table",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.output, func(t *testing.T) {
+			out, err := summary.Format(test.output)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if string(out) != test.expected {
+				t.Fatalf("expected:\n%s\nfound:\n%s", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestNodesDetails(t *testing.T) {
+	// IPs are cached, so they are not read from docker
+	c := &Cluster{
+		name: "kind",
+		allNodes: NodeList{
+			{name: "kind-worker", role: constants.WorkerNodeRoleValue, ipv4: "172.17.0.5", ipv6: "fc00::5"},
+			{name: "kind-control-plane-2", role: constants.ControlPlaneNodeRoleValue, ipv4: "172.17.0.4", ipv6: "fc00::4"},
+			{name: "kind-control-plane-1", role: constants.ControlPlaneNodeRoleValue, ipv4: "172.17.0.3", ipv6: "fc00::3"},
+			{name: "kind-lb", role: constants.ExternalLoadBalancerNodeRoleValue, ipv4: "172.17.0.2", ipv6: "fc00::2"},
+			{name: "kind-etcd", role: constants.ExternalEtcdNodeRoleValue, ipv4: "172.17.0.1", ipv6: "fc00::1"},
+		},
+	}
+
+	details, err := c.NodesDetails()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// nodes are sorted by provisioning order and by name
+	var names []string
+	for _, d := range details {
+		names = append(names, d.Name)
+	}
+	expected := []string{"kind-etcd", "kind-lb", "kind-control-plane-1", "kind-control-plane-2", "kind-worker"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected nodes %v, got %v", expected, names)
+	}
+
+	if d := details[0]; d.Role != constants.ExternalEtcdNodeRoleValue || d.IPv4 != "172.17.0.1" || d.IPv6 != "fc00::1" || d.KubernetesVersion != "" {
+		t.Errorf("unexpected details for the external etcd: %+v", d)
+	}
+}
+
+func TestNodesDetailsUnknownCluster(t *testing.T) {
+	c := &Cluster{name: "unknown"}
+	if err := c.CheckExists(); err == nil {
+		t.Error("expected an error for a cluster without nodes")
+	}
+	if _, err := c.NodesDetails(); err == nil {
+		t.Error("expected an error for a cluster without nodes")
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+const (
+	// Kindnet is the kindnet CNI plugin, installed from the manifest embedded in kinder
+	Kindnet = "kindnet"
+	// Calico is the Calico CNI plugin
+	Calico = "calico"
+	// Cilium is the Cilium CNI plugin, installed with the cilium CLI
+	Cilium = "cilium"
+	// Flannel is the flannel CNI plugin
+	Flannel = "flannel"
+	// Weave is the Weave Net CNI plugin
+	Weave = "weave"
+
+	// Default is the CNI plugin installed when none is selected
+	Default = Kindnet
+)
+
+const (
+	calicoVersion    = "v3.28.1"
+	ciliumVersion    = "1.16.1"
+	ciliumCLIVersion = "v0.16.16"
+	flannelVersion   = "v0.25.5"
+	weaveVersion     = "v2.8.1"
+
+	// flannelDefaultNetwork is the pod network in the flannel manifest, that must match the pod subnet
+	flannelDefaultNetwork = "10.244.0.0/16"
+)
+
+// Plugin defines how a CNI plugin is installed, and how its roll out is checked
+type Plugin struct {
+	// Name of the plugin, or the custom manifest for custom plugins
+	Name string
+
+	// DaemonSet running the plugin on each node, in the namespace/name form; empty for custom plugins
+	DaemonSet string
+
+	// Selector is the label selector of the DaemonSet pods; empty for custom plugins
+	Selector string
+
+	// Script installing the plugin, executed on the bootstrap control-plane node with KUBECONFIG set to the
+	// admin kubeconfig; empty for kindnet, that is installed from the manifest embedded in kinder
+	Script string
+}
+
+// plugins defines the known CNI plugins; manifests are applied from the upstream release URLs, so nodes
+// must be able to reach them. Pod networks are aligned to the pod subnet set by kinder in the kubeadm config
+var plugins = map[string]Plugin{
+	Kindnet: {
+		Name:      Kindnet,
+		DaemonSet: "kube-system/kindnet",
+		Selector:  "app=kindnet",
+	},
+	Calico: {
+		Name:      Calico,
+		DaemonSet: "kube-system/calico-node",
+		Selector:  "k8s-app=calico-node",
+		// the Calico default IP pool is the kinder pod subnet
+		Script: fmt.Sprintf("kubectl apply -f https://raw.githubusercontent.com/projectcalico/calico/%s/manifests/calico.yaml", calicoVersion),
+	},
+	Cilium: {
+		Name:      Cilium,
+		DaemonSet: "kube-system/cilium",
+		Selector:  "k8s-app=cilium",
+		// with the kubernetes IPAM mode, Cilium uses the pod CIDRs allocated to the nodes from the pod subnet
+		Script: fmt.Sprintf("curl -sSfL https://github.com/cilium/cilium-cli/releases/download/%s/cilium-linux-$(dpkg --print-architecture).tar.gz | tar -xz -C /usr/local/bin && ", ciliumCLIVersion) +
+			fmt.Sprintf("cilium install --version %s --set ipam.mode=kubernetes", ciliumVersion),
+	},
+	Flannel: {
+		Name:      Flannel,
+		DaemonSet: "kube-flannel/kube-flannel-ds",
+		Selector:  "app=flannel",
+		Script: fmt.Sprintf("curl -sSfL https://github.com/flannel-io/flannel/releases/download/%s/kube-flannel.yml | ", flannelVersion) +
+			fmt.Sprintf("sed 's#%s#%s#' | kubectl apply -f -", flannelDefaultNetwork, constants.KinderPodSubnet),
+	},
+	Weave: {
+		Name:      Weave,
+		DaemonSet: "kube-system/weave-net",
+		Selector:  "name=weave-net",
+		// Weave Net allocates pod IPs from its own range, so it does not depend on the pod subnet
+		Script: fmt.Sprintf("kubectl apply -f https://github.com/weaveworks/weave/releases/download/%s/weave-daemonset-k8s.yaml", weaveVersion),
+	},
+}
+
+// Known returns the names of the known CNI plugins
+func Known() []string {
+	return []string{Kindnet, Calico, Cilium, Flannel, Weave}
+}
+
+// IsKnown returns true if name is the name of a known CNI plugin
+func IsKnown(name string) bool {
+	_, ok := plugins[name]
+	return ok
+}
+
+// Get returns the CNI plugin with the given name; if the name is empty, the default plugin is returned,
+// while any other name is considered a custom manifest, as a path on the bootstrap control-plane node or an URL
+func Get(name string) (Plugin, error) {
+	if name == "" {
+		name = Default
+	}
+	if p, ok := plugins[name]; ok {
+		return p, nil
+	}
+	if strings.ContainsAny(name, "'\n") {
+		return Plugin{}, errors.Errorf("invalid CNI manifest %q", name)
+	}
+	return Plugin{
+		Name:   name,
+		Script: fmt.Sprintf("kubectl apply -f '%s'", name),
+	}, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cni
+
+import (
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name              string
+		expectedName      string
+		expectedDaemonSet string
+		expectedScript    bool
+		expectedError     bool
+	}{
+		{
+			name:              "",
+			expectedName:      Kindnet,
+			expectedDaemonSet: "kube-system/kindnet",
+		},
+		{
+			name:              Calico,
+			expectedName:      Calico,
+			expectedDaemonSet: "kube-system/calico-node",
+			expectedScript:    true,
+		},
+		{
+			name:              Flannel,
+			expectedName:      Flannel,
+			expectedDaemonSet: "kube-flannel/kube-flannel-ds",
+			expectedScript:    true,
+		},
+		{
+			name:           "/kinder/cni/custom.yaml",
+			expectedName:   "/kinder/cni/custom.yaml",
+			expectedScript: true,
+		},
+		{
+			name:          "/kinder/cni/it's.yaml",
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Get(tt.name)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if p.Name != tt.expectedName || p.DaemonSet != tt.expectedDaemonSet || (p.Script != "") != tt.expectedScript {
+				t.Errorf("unexpected plugin %+v", p)
+			}
+		})
+	}
+}
+
+func TestKnown(t *testing.T) {
+	for _, name := range Known() {
+		if !IsKnown(name) {
+			t.Errorf("%s should be a known CNI plugin", name)
+		}
+	}
+	if IsKnown("custom.yaml") {
+		t.Error("custom.yaml should not be a known CNI plugin")
+	}
+}
@@ -48,6 +48,12 @@ const (
 	// Please note that `kind` nodes (containers) hosting external etcd are not kubernetes nodes
 	ExternalEtcdNodeRoleValue string = "external-etcd"
 
+	// ExternalRegistryNodeRoleValue identifies a node that hosts a local image registry
+	// used as a registry mirror by the Kubernetes nodes.
+	//
+	// Please note that `kind` nodes (containers) hosting the registry are not kubernetes nodes
+	ExternalRegistryNodeRoleValue string = "external-registry"
+
 	// DefaultClusterName is the default cluster name
 	// TODO: consider if to switch to kinder
 	DefaultClusterName = "kind"
@@ -70,10 +76,178 @@ const (
 	// This is the deprecated value of NodeRoleKey, and will be removed in a future release
 	DeprecatedNodeRoleLabelKey = "io.k8s.sigs.kind.role"
 
+	// ExtraPortMappingsLabelKey is applied to "node" docker containers with extra port mappings,
+	// so it is possible to know the host ports assigned to a node after create
+	ExtraPortMappingsLabelKey = "io.x-k8s.kinder.extra-port-mappings"
+
+	// APIServerBindPortLabelKey is applied to control-plane "node" docker containers using a
+	// non default API server bind port, so it is possible to know the port after create
+	APIServerBindPortLabelKey = "io.x-k8s.kinder.apiserver-bind-port"
+
+	// NodeLabelsLabelKey is applied to "node" docker containers with the Kubernetes labels to be
+	// assigned to the node at join time
+	NodeLabelsLabelKey = "io.x-k8s.kinder.node-labels"
+
+	// NodeTaintsLabelKey is applied to "node" docker containers with the Kubernetes taints to be
+	// assigned to the node at join time
+	NodeTaintsLabelKey = "io.x-k8s.kinder.node-taints"
+
+	// NodeCommandLabelKey is applied to "node" docker containers created with a command override,
+	// so it is possible to know the node was modified after create
+	NodeCommandLabelKey = "io.x-k8s.kinder.node-command"
+
+	// LoadBalancerLabelKey is applied to the external load balancer docker container with the
+	// load balancer implementation selected at create time, e.g. haproxy
+	LoadBalancerLabelKey = "io.x-k8s.kinder.load-balancer"
+
+	// KubeNodeNameLabelKey is applied to "node" docker containers with the Kubernetes node name to be used
+	// at init/join time, if different from the container name
+	KubeNodeNameLabelKey = "io.x-k8s.kinder.kube-node-name"
+
+	// SandboxImageLabelKey is applied to "node" docker containers with the CRI sandbox (pause) image
+	// configured in the container runtime at create time
+	SandboxImageLabelKey = "io.x-k8s.kinder.sandbox-image"
+
+	// ControlPlaneExtraArgsLabelKey is applied to control-plane "node" docker containers with the extra args
+	// for the control-plane components, so they can be set in the kubeadm config at init time
+	ControlPlaneExtraArgsLabelKey = "io.x-k8s.kinder.control-plane-extra-args"
+
+	// ResourcesLabelKey is applied to K8s "node" docker containers with the CPU and memory limits,
+	// so it is possible to know them after create
+	ResourcesLabelKey = "io.x-k8s.kinder.resources"
+
+	// KubeletEvictionLabelKey is applied to K8s "node" docker containers with the kubelet eviction settings,
+	// so they can be set in the KubeletConfiguration at init time
+	KubeletEvictionLabelKey = "io.x-k8s.kinder.kubelet-eviction"
+
+	// NodeSysctlsLabelKey is applied to K8s "node" docker containers with the sysctls set on the node before the
+	// kubelet starts, as a JSON map of sysctl keys to values
+	NodeSysctlsLabelKey = "io.x-k8s.kinder.node-sysctls"
+
+	// KubeProxyLabelKey is applied to control-plane "node" docker containers with the kube-proxy mode,
+	// or with KubeProxyDisabled if the kube-proxy addon should not be installed
+	KubeProxyLabelKey = "io.x-k8s.kinder.kube-proxy"
+
+	// KubeProxyDisabled is the KubeProxyLabelKey value for clusters without the kube-proxy addon
+	KubeProxyDisabled = "disabled"
+
+	// NodeCIDRMaskSizeLabelKey is applied to control-plane "node" docker containers with the size of the IPv4 and
+	// IPv6 pod CIDR allocated to each node, in JSON form, e.g. {"ipv4":26}
+	NodeCIDRMaskSizeLabelKey = "io.x-k8s.kinder.node-cidr-mask-size"
+
+	// CoreDNSImageLabelKey is applied to K8s "node" docker containers with the CoreDNS image to be set in the
+	// kubeadm config at init time, so it is possible to check that the image is pre-loaded
+	CoreDNSImageLabelKey = "io.x-k8s.kinder.coredns-image"
+
+	// CoreDNSReplicasLabelKey is applied to control-plane "node" docker containers with the number of
+	// CoreDNS replicas, so the CoreDNS deployment can be scaled after init
+	CoreDNSReplicasLabelKey = "io.x-k8s.kinder.coredns-replicas"
+
+	// EncryptionProviderLabelKey is applied to control-plane "node" docker containers with the provider used for
+	// encrypting secrets at rest, so the encryption config can be mounted in the API server at init time
+	EncryptionProviderLabelKey = "io.x-k8s.kinder.encryption-provider"
+
+	// AuditLogPathLabelKey is applied to control-plane "node" docker containers with the API server audit log path,
+	// so the audit policy and the audit log dirs can be mounted in the API server at init time
+	AuditLogPathLabelKey = "io.x-k8s.kinder.audit-log-path"
+
+	// APIServerTLSMinVersionLabelKey is applied to control-plane "node" docker containers with the API server TLS
+	// min version, so it can be set in the kubeadm config at init time
+	APIServerTLSMinVersionLabelKey = "io.x-k8s.kinder.apiserver-tls-min-version"
+
+	// APIServerTLSCipherSuitesLabelKey is applied to control-plane "node" docker containers with the comma separated
+	// list of the API server TLS cipher suites, so they can be set in the kubeadm config at init time
+	APIServerTLSCipherSuitesLabelKey = "io.x-k8s.kinder.apiserver-tls-cipher-suites"
+
+	// BootstrapManifestsLabelKey is applied to control-plane "node" docker containers with the list, in JSON form,
+	// of the manifests to be applied after init; manifest files are copied in the nodes at create time, so the list
+	// contains paths in the node or URLs
+	BootstrapManifestsLabelKey = "io.x-k8s.kinder.bootstrap-manifests"
+
+	// IgnorePreflightErrorsLabelKey is applied to control-plane "node" docker containers with the comma separated
+	// list of the kubeadm preflight errors to be ignored by kubeadm init, as defined at create time
+	IgnorePreflightErrorsLabelKey = "io.x-k8s.kinder.ignore-preflight-errors"
+
+	// WaitDaemonSetsLabelKey is applied to control-plane "node" docker containers with the list, in JSON form,
+	// of the DaemonSets, in the namespace/name form, to be waited for rolling out on all the nodes after
+	// the bootstrap manifests are applied and after join
+	WaitDaemonSetsLabelKey = "io.x-k8s.kinder.wait-daemonsets"
+
+	// KubeadmClusterNameLabelKey is applied to control-plane "node" docker containers with the cluster name set in
+	// the kubeadm ClusterConfiguration, if different from the kinder cluster name
+	KubeadmClusterNameLabelKey = "io.x-k8s.kinder.kubeadm-cluster-name"
+
+	// EtcdExtraArgsLabelKey is applied to control-plane "node" docker containers with the etcd extra args for the
+	// backend quota and auto-compaction, as a JSON list of values in the key=value form
+	EtcdExtraArgsLabelKey = "io.x-k8s.kinder.etcd-extra-args"
+
+	// ExternalEtcdEndpointsLabelKey is applied to control-plane "node" docker containers with the client endpoints
+	// of an external etcd not managed by kinder, as a JSON list
+	ExternalEtcdEndpointsLabelKey = "io.x-k8s.kinder.external-etcd-endpoints"
+
+	// KubeletServingCertRotationLabelKey is applied to control-plane "node" docker containers when the kubelet
+	// serving certificates are signed by the controller manager, and thus rotated
+	KubeletServingCertRotationLabelKey = "io.x-k8s.kinder.kubelet-serving-cert-rotation"
+
+	// CNILabelKey is applied to control-plane "node" docker containers with the CNI plugin installed after init,
+	// that is the name of a known plugin, or the custom manifest as a path in the node or an URL
+	CNILabelKey = "io.x-k8s.kinder.cni"
+
+	// EtcdVersionLabelKey is applied to K8s "node" docker containers with the local etcd image tag to be set
+	// in the kubeadm config at init time; like for the CoreDNS image, the pre-loaded images check uses it
+	EtcdVersionLabelKey = "io.x-k8s.kinder.etcd-version"
+
+	// NetworkLabelKey is applied to docker containers connected to a user defined network,
+	// recording the network name
+	NetworkLabelKey = "io.x-k8s.kinder.network"
+
+	// WorkerPoolLabelKey is applied to worker "node" docker containers created as part of a worker pool,
+	// recording the pool name
+	WorkerPoolLabelKey = "io.x-k8s.kinder.worker-pool"
+
+	// IneligibleLabelKey can be applied to "node" docker containers with the "true" value, for excluding
+	// the node from kinder actions, e.g. during maintenance; it can be overridden with kinder do --include-ineligible.
+	// Nb. docker labels can't be changed on existing containers, use IneligibleMarkerPath instead
+	IneligibleLabelKey = "io.x-k8s.kinder.ineligible"
+
+	// IPFamilyLabelKey is applied to K8s "node" docker containers with the IP family of the cluster, so the
+	// cluster metadata can be read by external tooling with docker ps, without executing commands in the nodes
+	IPFamilyLabelKey = "io.x-k8s.kinder.ip-family"
+
+	// KubernetesVersionLabelKey is applied to K8s "node" docker containers with the Kubernetes version in the
+	// node image, so the cluster metadata can be read by external tooling with docker ps, without executing
+	// commands in the nodes
+	KubernetesVersionLabelKey = "io.x-k8s.kinder.kubernetes-version"
+
+	// BootstrapControlPlaneLabelKey can be applied to one "control-plane" docker container with the "true" value,
+	// for designating the node where kubeadm init is executed instead of the first control plane by name
+	BootstrapControlPlaneLabelKey = "io.x-k8s.kinder.bootstrap-control-plane"
+
+	// NodeEntrypoint defines the kind entrypoint and init, that must be executed for booting a node
+	NodeEntrypoint = "/usr/local/bin/entrypoint /sbin/init"
+
+	// DefaultDNSDomain defines the default DNS domain used by the cluster services
+	DefaultDNSDomain = "cluster.local"
+
+	// DefaultCgroupDriver defines the default cgroup driver used by the kubelet
+	DefaultCgroupDriver = "systemd"
+
 	// PodSubnet defines the default pod subnet used by kind
 	// TODO: send a PR to define this value in a kind constant (currently it is not)
 	PodSubnet = "10.244.0.0/16"
 
+	// KinderPodSubnet defines the pod subnet set in the kubeadm config by kinder, that is the default for kindnet
+	KinderPodSubnet = "192.168.0.0/16"
+
+	// KinderPodSubnetIPv6 and KinderServiceSubnetIPv6 define the IPv6 pod and service subnets set in the kubeadm
+	// config by kinder for IPv6 and dual-stack clusters
+	KinderPodSubnetIPv6     = "fd00:10:244::/56"
+	KinderServiceSubnetIPv6 = "fd00:10:96::/112"
+
+	// KinderServiceSubnet defines the IPv4 service subnet set in the kubeadm config by kinder for dual-stack
+	// clusters, that is the kubeadm default; IPv4 clusters use the kubeadm default
+	KinderServiceSubnet = "10.96.0.0/12"
+
 	// KubeadmConfigPath defines the path to the kubeadm config file in the K8s nodes
 	// TODO: send a PR to define this value in a kind constant (currently it is not)
 	KubeadmConfigPath = "/kind/kubeadm.conf"
@@ -97,6 +271,24 @@ const (
 
 	// ConfigPath defines the path to the config file in the load balancer node
 	LoadBalancerConfigPath = "/usr/local/etc/haproxy/haproxy.cfg"
+
+	// NginxLoadBalancerImage defines the nginx loadbalancer image:tag
+	NginxLoadBalancerImage = "nginx:1.27-alpine"
+
+	// NginxLoadBalancerConfigPath defines the path to the config file in the nginx load balancer node
+	NginxLoadBalancerConfigPath = "/etc/nginx/nginx.conf"
+
+	// EnvoyLoadBalancerImage defines the envoy loadbalancer image:tag
+	EnvoyLoadBalancerImage = "envoyproxy/envoy:v1.30.1"
+
+	// EnvoyLoadBalancerConfigPath defines the path to the config file in the envoy load balancer node
+	EnvoyLoadBalancerConfigPath = "/etc/envoy/envoy.yaml"
+
+	// RegistryImage defines the registry image:tag
+	RegistryImage = "registry:2"
+
+	// RegistryPort defines the port where the registry is listening on the registry node
+	RegistryPort = 5000
 )
 
 // constants used by the ClusterManager / inside actions
@@ -110,6 +302,17 @@ const (
 
 	// PatchesDir defines the path to patches stored on node
 	PatchesDir = "/kinder/patches"
+
+	// IneligibleMarkerPath defines the path of a file that, if existing on a node, excludes the node from kinder
+	// actions like the IneligibleLabelKey docker label; unlike the label, it can be toggled on existing nodes
+	IneligibleMarkerPath = "/kinder/ineligible"
+
+	// PKIDir defines the path on control-plane nodes where kubeadm stores the cluster certificates
+	PKIDir = "/etc/kubernetes/pki"
+
+	// PKIBackupDir defines the path on control-plane nodes where kinder keeps a copy of the certificates installed
+	// in /etc/kubernetes/pki at create time, so they can be re-installed after kubeadm reset
+	PKIBackupDir = "/kinder/pki"
 )
 
 // other constants
@@ -0,0 +1,35 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// ReadImageFile returns the lines of a file in an image, using a throwaway container that
+// does not boot the image entrypoint
+func ReadImageFile(image, path string) ([]string, error) {
+	output, err := exec.NewHostCmd("docker", "run", "--rm", "--entrypoint", "cat", image, path).RunAndCapture()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s from image %s: %s", path, image, strings.Join(output, " "))
+	}
+	return output, nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// lockRetryInterval is the interval between attempts to acquire a lock held by another process
+const lockRetryInterval = 200 * time.Millisecond
+
+// LockFile acquires an exclusive advisory lock on a host file, creating the file if it does not exist, waiting
+// for other processes to release the lock until the context is done. The lock is released by the returned
+// function, that can be safely invoked more than once, or when the process exits, e.g. if it is killed.
+func LockFile(ctx context.Context, path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0666)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the lock file %s", path)
+	}
+
+	waiting := false
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK && err != syscall.EINTR {
+			_ = f.Close()
+			return nil, errors.Wrapf(err, "failed to lock %s", path)
+		}
+		if !waiting {
+			log.Infof("Waiting for another kinder process to release the lock %s", path)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, errors.Wrapf(ctx.Err(), "failed to lock %s", path)
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+			_ = f.Close()
+		})
+	}, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kinder.lock")
+
+	unlock, err := LockFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring the lock: %v", err)
+	}
+
+	// the lock is held, so a second attempt waits until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := LockFile(ctx, path); err == nil {
+		t.Fatal("expected an error acquiring a lock already held")
+	}
+
+	// once released, the lock can be acquired again; releasing more than once is safe
+	unlock()
+	unlock()
+	unlock2, err := LockFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring the released lock: %v", err)
+	}
+	unlock2()
+}
+
+func TestLockFileWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kinder.lock")
+
+	unlock, err := LockFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error acquiring the lock: %v", err)
+	}
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	unlock2, err := LockFile(ctx, path)
+	if err != nil {
+		t.Fatalf("expected the lock to be acquired once released, got %v", err)
+	}
+	unlock2()
+}
@@ -29,3 +29,12 @@ func SendSignal(signal, containerNameOrID string) error {
 	)
 	return cmd.Run()
 }
+
+// RestartContainer restarts the container
+func RestartContainer(containerNameOrID string) error {
+	cmd := exec.NewHostCmd(
+		"docker", "restart",
+		containerNameOrID,
+	)
+	return cmd.Run()
+}
@@ -0,0 +1,28 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"k8s.io/kubeadm/kinder/pkg/exec"
+)
+
+// ContainerStats returns a single sample of the resource usage statistics of the containers
+func ContainerStats(format string, containerNamesOrIDs ...string) ([]string, error) {
+	args := []string{"stats", "--no-stream", "--format", format}
+	cmd := exec.NewHostCmd("docker", append(args, containerNamesOrIDs...)...)
+	return cmd.RunAndCapture()
+}
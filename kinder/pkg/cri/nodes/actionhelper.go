@@ -47,6 +47,17 @@ func (h *ActionHelper) PreLoadUpgradeImages(n *status.Node, srcFolder string) er
 	return errors.Errorf("unknown cri: %s", h.cri)
 }
 
+// PullImage pulls an image into the selected container runtime that exists inside a kind(er) node
+func (h *ActionHelper) PullImage(n *status.Node, image string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.PullImage(n, image)
+	case status.DockerRuntime:
+		return docker.PullImage(n, image)
+	}
+	return errors.Errorf("unknown cri: %s", h.cri)
+}
+
 // GetImages prints the images available in the node
 func (h *ActionHelper) GetImages(n *status.Node) ([]string, error) {
 	switch h.cri {
@@ -57,3 +68,14 @@ func (h *ActionHelper) GetImages(n *status.Node) ([]string, error) {
 	}
 	return nil, errors.Errorf("unknown cri: %s", h.cri)
 }
+
+// GetImageArch returns the architecture of an image available in the node, e.g. amd64 or arm64
+func (h *ActionHelper) GetImageArch(n *status.Node, image string) (string, error) {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.GetImageArch(n, image)
+	case status.DockerRuntime:
+		return docker.GetImageArch(n, image)
+	}
+	return "", errors.Errorf("unknown cri: %s", h.cri)
+}
@@ -17,20 +17,23 @@ limitations under the License.
 package common
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/constants"
 	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
 // BaseRunArgs computes docker arguments that apply to all containers
-func BaseRunArgs(cluster, name, role string) ([]string, error) {
+func BaseRunArgs(cluster, network, name, role string) ([]string, error) {
 	// standard arguments all nodes containers need, computed once
 	args := []string{
 		"run",
@@ -46,11 +49,17 @@ func BaseRunArgs(cluster, name, role string) ([]string, error) {
 		"--label", fmt.Sprintf("%s=%s", constants.DeprecatedNodeRoleLabelKey, role),
 	}
 
+	// the cluster network, if not the default one, is recorded in a label, so it is possible
+	// to connect containers created later, e.g. new external etcd members, to the same network
+	if network != "" && network != DefaultNetwork {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", constants.NetworkLabelKey, network))
+	}
+
 	// TODO: enable IPv6 if necessary
 	// args = append(args, "--sysctl=net.ipv6.conf.all.disable_ipv6=0", "--sysctl=net.ipv6.conf.all.forwarding=1")
 
 	// pass proxy environment variables
-	proxyEnv, err := getProxyEnvs()
+	proxyEnv, err := getProxyEnvs(network)
 	if err != nil {
 		return nil, errors.Wrap(err, "proxy setup error")
 	}
@@ -65,6 +74,32 @@ func BaseRunArgs(cluster, name, role string) ([]string, error) {
 	return args, nil
 }
 
+// RunArgsForNetwork computes docker run arguments for connecting containers to the cluster network, if not the default one
+func RunArgsForNetwork(network string, args []string) []string {
+	if network == "" || network == DefaultNetwork {
+		return args
+	}
+	return append(args, "--network", network)
+}
+
+// RunArgsForIPs computes the arguments for assigning static IPs to a container; static IPs can be assigned
+// only on user defined networks
+func RunArgsForIPs(network string, ips status.NodeIPs, args []string) ([]string, error) {
+	if ips.IPv4 == "" && ips.IPv6 == "" {
+		return args, nil
+	}
+	if network == "" || network == DefaultNetwork {
+		return nil, errors.New("static IPs can be assigned only on user defined docker networks")
+	}
+	if ips.IPv4 != "" {
+		args = append(args, "--ip", ips.IPv4)
+	}
+	if ips.IPv6 != "" {
+		args = append(args, "--ip6", ips.IPv6)
+	}
+	return args, nil
+}
+
 // UsernsRemap checks if userns-remap is enabled in dockerd
 func UsernsRemap() bool {
 	cmd := exec.NewHostCmd("docker", "info", "--format", "'{{json .SecurityOptions}}'")
@@ -80,14 +115,17 @@ func UsernsRemap() bool {
 	return false
 }
 
+// DefaultNetwork is the docker network node containers are connected to, if not differently specified;
+// Docker default bridge network is named "bridge" (https://docs.docker.com/network/bridge/#use-the-default-bridge-network)
+const DefaultNetwork = "bridge"
+
 const (
-	defaultNetwork = "bridge"
-	httpProxy      = "HTTP_PROXY"
-	httpsProxy     = "HTTPS_PROXY"
-	noProxy        = "NO_PROXY"
+	httpProxy  = "HTTP_PROXY"
+	httpsProxy = "HTTPS_PROXY"
+	noProxy    = "NO_PROXY"
 )
 
-func getProxyEnvs() (map[string]string, error) {
+func getProxyEnvs(network string) (map[string]string, error) {
 	envs := make(map[string]string)
 	for _, name := range []string{httpProxy, httpsProxy, noProxy} {
 		val := os.Getenv(name)
@@ -112,8 +150,10 @@ func getProxyEnvs() (map[string]string, error) {
 
 	// Specifically add the docker network subnets to NO_PROXY if we are using a proxy
 	if len(envs) > 0 {
-		// Docker default bridge network is named "bridge" (https://docs.docker.com/network/bridge/#use-the-default-bridge-network)
-		subnets, err := getSubnets(defaultNetwork)
+		if network == "" {
+			network = DefaultNetwork
+		}
+		subnets, err := getSubnets(network)
 		if err != nil {
 			return nil, err
 		}
@@ -135,8 +175,9 @@ func getSubnets(networkName string) ([]string, error) {
 	return strings.Split(strings.TrimSpace(lines[0]), " "), nil
 }
 
-// RunArgsForNode computes docker run arguments that apply to containers that should host K8s nodes
-func RunArgsForNode(role string, volumes []string, args []string) ([]string, error) {
+// RunArgsForNode computes docker run arguments that apply to containers that should host K8s nodes;
+// if apiServerBindPort is not set, the default API server port is used. Labels, DNS settings and resource limits are applied to the container.
+func RunArgsForNode(role string, volumes []string, portMappings []status.PortMapping, apiServerBindPort int32, labels map[string]string, dns status.NodeDNS, resources status.NodeResources, args []string) ([]string, error) {
 	args = append(args,
 		// running containers in a container requires privileged
 		// NOTE: we could try to replicate this with --cap-add, and use less
@@ -168,12 +209,79 @@ func RunArgsForNode(role string, volumes []string, args []string) ([]string, err
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to get host port for the API server address")
 		}
-		args = append(args, fmt.Sprintf("--publish=%d:%d/TCP", hostPort, constants.ControlPlanePort))
+		if apiServerBindPort == 0 {
+			apiServerBindPort = constants.APIServerPort
+		}
+		args = append(args, fmt.Sprintf("--publish=%d:%d/TCP", hostPort, apiServerBindPort))
+
+		// a non default bind port is recorded in a label, so it is possible to know it after create
+		if apiServerBindPort != constants.APIServerPort {
+			args = append(args, "--label", fmt.Sprintf("%s=%d", constants.APIServerBindPortLabelKey, apiServerBindPort))
+		}
+	}
+
+	// extra port mappings; they are also recorded in a label, so it is possible to know
+	// the host ports assigned to the node after create
+	if len(portMappings) > 0 {
+		var values []string
+		for _, p := range portMappings {
+			args = append(args, fmt.Sprintf("--publish=%d:%d/%s", p.HostPort, p.ContainerPort, strings.ToUpper(p.Protocol)))
+			values = append(values, p.String())
+		}
+		args = append(args, "--label", fmt.Sprintf("%s=%s", constants.ExtraPortMappingsLabelKey, strings.Join(values, ",")))
+	}
+
+	// DNS settings overriding the ones inherited from the host
+	for _, s := range dns.Servers {
+		args = append(args, "--dns", s)
+	}
+	for _, s := range dns.Search {
+		args = append(args, "--dns-search", s)
+	}
+	for _, o := range dns.Options {
+		args = append(args, "--dns-option", o)
+	}
+
+	// CPU and memory limits; they are also recorded in a label, so it is possible to know them after create
+	if resources.CPUs != "" {
+		args = append(args, "--cpus", resources.CPUs)
+	}
+	if resources.Memory != "" {
+		// swap is disabled, so the memory limit is a hard limit for the node
+		args = append(args, "--memory", resources.Memory, "--memory-swap", resources.Memory)
+	}
+	if !resources.IsZero() {
+		b, err := json.Marshal(resources)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode the node resources")
+		}
+		args = append(args, "--label", fmt.Sprintf("%s=%s", constants.ResourcesLabelKey, b))
+	}
+
+	// additional labels, sorted for stable args
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, labels[k]))
 	}
 
 	return args, nil
 }
 
+// RunArgsForNodeCommand computes docker run arguments for overriding the command of containers hosting K8s nodes;
+// the command is executed by a shell replacing the kind entrypoint, and it is expected to exec the kind entrypoint
+func RunArgsForNodeCommand(args []string) []string {
+	return append(args, "--entrypoint=/bin/sh")
+}
+
+// ContainerArgsForNodeCommand computes the container arguments for overriding the command of containers hosting K8s nodes
+func ContainerArgsForNodeCommand(command string, args []string) []string {
+	return append(args, "-c", command)
+}
+
 // helper used to get a free TCP port for the API server
 func getPort() (int32, error) {
 	dummyListener, err := net.Listen("tcp", ":0")
@@ -197,24 +305,100 @@ func RunArgsForExternalLoadBalancer(args []string) ([]string, error) {
 	return args, nil
 }
 
-// RunArgsForExternalEtcd computes docker run arguments that apply to containers that should host external etcd members
-func RunArgsForExternalEtcd(args []string) []string {
+// RunArgsForExternalEtcd computes docker run arguments that apply to containers that should host external etcd members;
+// if dataDir is set, the host directory is mounted as etcd data dir
+func RunArgsForExternalEtcd(dataDir string, args []string) []string {
+	if dataDir != "" {
+		args = append(args, "--volume", fmt.Sprintf("%s:%s", dataDir, ExternalEtcdDataDir))
+	}
 	return args
 }
 
-// ContainerArgsForExternalEtcd computes arguments to pass to the external etcd container's entry point
-func ContainerArgsForExternalEtcd(name string, args []string) []string {
+// ExternalEtcdDataDir defines the etcd data dir in external etcd containers, when mounted from the host
+const ExternalEtcdDataDir = "/var/lib/etcd"
+
+// ContainerArgsForExternalEtcd computes arguments to pass to the external etcd container's entry point;
+// etcd extra args, in the key=value form, are appended to the etcd flags
+func ContainerArgsForExternalEtcd(name, dataDir string, etcdExtraArgs, args []string) []string {
+	member := fmt.Sprintf("%s-etcd", name)
 	args = append(args,
 		// define a minimal etcd (insecure, single node, not exposed to the host machine)
 		"etcd",
-		"--name", fmt.Sprintf("%s-etcd", name),
+		"--name", member,
 		"--advertise-client-urls", "http://127.0.0.1:2379",
 		"--listen-client-urls", "http://0.0.0.0:2379",
+		// listen for peers, so it is possible to add members to the etcd cluster later
+		"--listen-peer-urls", "http://0.0.0.0:2380",
+		"--initial-advertise-peer-urls", ExternalEtcdPeerURL(member),
+		"--initial-cluster", fmt.Sprintf("%s=%s", member, ExternalEtcdPeerURL(member)),
 	)
+	if dataDir != "" {
+		args = append(args, "--data-dir", ExternalEtcdDataDir)
+	}
+
+	return appendEtcdExtraArgs(args, etcdExtraArgs)
+}
 
+// ContainerArgsForExternalEtcdMember computes arguments to pass to the entry point of a container
+// hosting an external etcd member; if dataDir is set, the data dir mounted from the host is used. initialClusterState
+// is "new" for members bootstrapping the etcd cluster together, and "existing" for members joining an existing
+// etcd cluster. etcd extra args, in the key=value form, are appended to the etcd flags
+func ContainerArgsForExternalEtcdMember(member, dataDir, initialCluster, initialClusterState string, etcdExtraArgs, args []string) []string {
+	args = append(args,
+		"etcd",
+		"--name", member,
+		"--advertise-client-urls", "http://127.0.0.1:2379",
+		"--listen-client-urls", "http://0.0.0.0:2379",
+		"--listen-peer-urls", "http://0.0.0.0:2380",
+		"--initial-advertise-peer-urls", ExternalEtcdPeerURL(member),
+		"--initial-cluster", initialCluster,
+		"--initial-cluster-state", initialClusterState,
+	)
+	if dataDir != "" {
+		args = append(args, "--data-dir", ExternalEtcdDataDir)
+	}
+
+	return appendEtcdExtraArgs(args, etcdExtraArgs)
+}
+
+// appendEtcdExtraArgs appends etcd extra args, in the key=value form, as etcd flags
+func appendEtcdExtraArgs(args, etcdExtraArgs []string) []string {
+	for _, a := range etcdExtraArgs {
+		args = append(args, fmt.Sprintf("--%s", a))
+	}
 	return args
 }
 
+// ExternalEtcdPeerURL returns the peer URL of an external etcd member; the member name is also
+// the name of the container, which is resolved by docker on the external etcd network
+func ExternalEtcdPeerURL(member string) string {
+	return fmt.Sprintf("http://%s:2380", member)
+}
+
+// ExternalEtcdMemberName returns the name of the i-th external etcd member, starting from 1; the member
+// name is also the name of the container
+func ExternalEtcdMemberName(cluster string, i int) string {
+	if i == 1 {
+		return fmt.Sprintf("%s-etcd", cluster)
+	}
+	return fmt.Sprintf("%s-etcd%d", cluster, i)
+}
+
+// ExternalEtcdInitialCluster returns the etcd initial cluster for a list of external etcd members
+func ExternalEtcdInitialCluster(members []string) string {
+	initialCluster := make([]string, 0, len(members))
+	for _, m := range members {
+		initialCluster = append(initialCluster, fmt.Sprintf("%s=%s", m, ExternalEtcdPeerURL(m)))
+	}
+	return strings.Join(initialCluster, ",")
+}
+
+// ExternalEtcdNetwork returns the name of the docker network used for communication
+// between external etcd members
+func ExternalEtcdNetwork(cluster string) string {
+	return fmt.Sprintf("%s-etcd", cluster)
+}
+
 // TryUntil implements an helper that calls `try()` in a loop until the deadline `until`
 // has passed or `try()`returns true, returns whether try ever returned true
 func TryUntil(until time.Time, try func() bool) bool {
@@ -17,6 +17,8 @@ limitations under the License.
 package containerd
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
@@ -31,6 +33,13 @@ func PreLoadUpgradeImages(n *status.Node, srcFolder string) error {
 	).Silent().Run()
 }
 
+// PullImage pulls an image into the containerd runtime that exists inside a kind(er) node
+func PullImage(n *status.Node, image string) error {
+	return n.Command(
+		"crictl", "pull", image,
+	).Silent().Run()
+}
+
 // GetImages returns the list of images available in the node
 func GetImages(n *status.Node) ([]string, error) {
 	current, err := n.Command(
@@ -43,3 +52,18 @@ func GetImages(n *status.Node) ([]string, error) {
 
 	return current, nil
 }
+
+// GetImageArch returns the architecture of an image available in the containerd runtime that exists inside a kind(er) node;
+// an empty string is returned if the architecture is not reported by the runtime
+func GetImageArch(n *status.Node, image string) (string, error) {
+	lines, err := n.Command(
+		"crictl", "inspecti", "-o", "go-template", "--template", "{{.info.imageSpec.architecture}}", image,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the architecture of image %s from %s", image, n.Name())
+	}
+	if len(lines) != 1 || lines[0] == "<no value>" {
+		return "", nil
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
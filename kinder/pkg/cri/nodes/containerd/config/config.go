@@ -17,7 +17,10 @@ limitations under the License.
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
@@ -30,6 +33,13 @@ var (
 
 var (
 	sandboxImageFieldPath = []string{"plugins", "io.containerd.grpc.v1.cri", "sandbox_image"}
+
+	// registryConfigPathFieldPaths are the paths of the registry config_path field, in config version 2 and 3
+	registryConfigPathFieldPaths = [][]string{
+		{"plugins", "io.containerd.grpc.v1.cri", "registry", "config_path"},
+		{"plugins", "io.containerd.cri.v1.images", "registry", "config_path"},
+	}
+	registryMirrorsFieldPath = []string{"plugins", "io.containerd.grpc.v1.cri", "registry", "mirrors"}
 )
 
 // GetCRISandboxImage returns the sandbox image defined in the containerd config file.
@@ -74,3 +84,57 @@ func SetCRISandboxImage(path string, sandboxImage string) error {
 
 	return nil
 }
+
+// GetCRIRegistryConfigPath returns the directory where containerd reads registry host configurations (hosts.toml),
+// as defined in the containerd config file; an empty string is returned if the field is not set.
+func GetCRIRegistryConfigPath(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	for _, fieldPath := range registryConfigPathFieldPaths {
+		if value, ok := tree.GetPath(fieldPath).(string); ok && value != "" {
+			// config_path could be a list of directories; the first one takes precedence
+			return strings.Split(value, ":")[0], nil
+		}
+	}
+	return "", nil
+}
+
+// SetCRIRegistryMirror sets the endpoint as mirror for all the registries in the containerd config file;
+// this applies only to config files not using a registry config_path.
+func SetCRIRegistryMirror(path string, endpoint string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	tree.SetPath(append(registryMirrorsFieldPath, "*", "endpoint"), []string{endpoint})
+
+	data, err := tree.ToTomlString()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+		return errors.Errorf("failed to write to config file %s, error: %v", path, err)
+	}
+
+	return nil
+}
+
+// RegistryHostsFile returns the path and the contents of the hosts.toml file that sets the endpoint
+// as mirror for all the registries, to be used when the containerd config file defines a registry config_path.
+func RegistryHostsFile(configPath, endpoint string) (string, string) {
+	contents := fmt.Sprintf("[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", endpoint)
+	return filepath.Join(configPath, "_default", "hosts.toml"), contents
+}
@@ -19,6 +19,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"path/filepath"
@@ -143,3 +144,60 @@ func TestSetCRISandboxImage(t *testing.T) {
 		})
 	}
 }
+
+func TestSetCRIRegistryMirror(t *testing.T) {
+	var tests = []struct {
+		name               string
+		data               string
+		expectedConfigPath string
+	}{
+		{
+			name: "the containerd config file doesn't define a registry config_path",
+			data: `version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+  sandbox_image = "registry.k8s.io/pause:3.7"
+`,
+		},
+		{
+			name: "the containerd config file defines a registry config_path",
+			data: `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+`,
+			expectedConfigPath: "/etc/containerd/certs.d",
+		},
+	}
+
+	endpoint := "http://172.17.0.2:5000"
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(path, []byte(rt.data), 0600); err != nil {
+				t.Fatalf("couldn't write to file %s: %v", path, err)
+			}
+
+			configPath, err := GetCRIRegistryConfigPath(path)
+			if err != nil {
+				t.Fatalf("failed GetCRIRegistryConfigPath: %v", err)
+			}
+			if configPath != rt.expectedConfigPath {
+				t.Fatalf("failed GetCRIRegistryConfigPath:\n\texpected config path: %q\n\tactual config path: %q", rt.expectedConfigPath, configPath)
+			}
+			if configPath != "" {
+				return
+			}
+
+			if err := SetCRIRegistryMirror(path, endpoint); err != nil {
+				t.Fatalf("failed SetCRIRegistryMirror: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("couldn't read file %s: %v", path, err)
+			}
+			if !strings.Contains(string(data), endpoint) || !strings.Contains(string(data), "sandbox_image") {
+				t.Errorf("failed SetCRIRegistryMirror, unexpected config file:\n%s", data)
+			}
+		})
+	}
+}
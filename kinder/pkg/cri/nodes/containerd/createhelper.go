@@ -17,25 +17,46 @@ limitations under the License.
 package containerd
 
 import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes/common"
+	"k8s.io/kubeadm/kinder/pkg/cri/nodes/containerd/config"
 	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
-// CreateNode creates a container that internally hosts the containerd cri runtime
-func CreateNode(cluster, name, image, role string, volumes []string) error {
-	args, err := common.BaseRunArgs(cluster, name, role)
+// CreateNode creates a container that internally hosts the containerd cri runtime;
+// if ips are set, they are assigned to the container, and if command is set, it overrides the container command
+func CreateNode(cluster, network, name, image, role string, volumes []string, portMappings []status.PortMapping, apiServerBindPort int32, labels map[string]string, dns status.NodeDNS, resources status.NodeResources, ips status.NodeIPs, command string) error {
+	args, err := common.BaseRunArgs(cluster, network, name, role)
 	if err != nil {
 		return err
 	}
+	args = common.RunArgsForNetwork(network, args)
+	if args, err = common.RunArgsForIPs(network, ips, args); err != nil {
+		return err
+	}
 
-	args, err = common.RunArgsForNode(role, volumes, args)
+	args, err = common.RunArgsForNode(role, volumes, portMappings, apiServerBindPort, labels, dns, resources, args)
 	if err != nil {
 		return err
 	}
 
+	// Override the container command, if requested
+	if command != "" {
+		args = common.RunArgsForNodeCommand(args)
+	}
+
 	// Specify the image to run
 	args = append(args, image)
 
+	if command != "" {
+		args = common.ContainerArgsForNodeCommand(command, args)
+	}
+
 	// creates the container
 	if err := exec.NewHostCmd("docker", args...).Run(); err != nil {
 		return err
@@ -43,3 +64,63 @@ func CreateNode(cluster, name, image, role string, volumes []string) error {
 
 	return nil
 }
+
+// ConfigureRegistryMirror configures the containerd runtime inside a kind(er) node to use the endpoint
+// as mirror for all the registries, and restarts containerd
+func ConfigureRegistryMirror(n *status.Node, endpoint string) error {
+	tmpDir, err := os.MkdirTemp("", n.Name())
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpConfigFileOnHost := filepath.Join(tmpDir, "config.toml")
+	if err := n.CopyFrom(config.DefaultConfigPath, tmpConfigFileOnHost); err != nil {
+		return errors.Wrapf(err, "failed to read %s from node %s", config.DefaultConfigPath, n.Name())
+	}
+
+	configPath, err := config.GetCRIRegistryConfigPath(tmpConfigFileOnHost)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s from node %s", config.DefaultConfigPath, n.Name())
+	}
+
+	if configPath != "" {
+		// registry host configurations are read from config_path, so a default hosts.toml is used
+		hostsFile, contents := config.RegistryHostsFile(configPath, endpoint)
+		if err := n.WriteFileWithPerm(hostsFile, []byte(contents), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", hostsFile, n.Name())
+		}
+	} else {
+		if err := config.SetCRIRegistryMirror(tmpConfigFileOnHost, endpoint); err != nil {
+			return errors.Wrapf(err, "failed to set the registry mirror in %s", config.DefaultConfigPath)
+		}
+		if err := n.CopyTo(tmpConfigFileOnHost, config.DefaultConfigPath); err != nil {
+			return errors.Wrapf(err, "failed to write %s on node %s", config.DefaultConfigPath, n.Name())
+		}
+	}
+
+	return n.Command("systemctl", "restart", "containerd").Silent().Run()
+}
+
+// ConfigureSandboxImage configures the containerd runtime inside a kind(er) node to use the given sandbox (pause) image
+func ConfigureSandboxImage(n *status.Node, image string) error {
+	tmpDir, err := os.MkdirTemp("", n.Name())
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpConfigFileOnHost := filepath.Join(tmpDir, "config.toml")
+	if err := n.CopyFrom(config.DefaultConfigPath, tmpConfigFileOnHost); err != nil {
+		return errors.Wrapf(err, "failed to read %s from node %s", config.DefaultConfigPath, n.Name())
+	}
+
+	if err := config.SetCRISandboxImage(tmpConfigFileOnHost, image); err != nil {
+		return errors.Wrapf(err, "failed to set the sandbox image in %s", config.DefaultConfigPath)
+	}
+	if err := n.CopyTo(tmpConfigFileOnHost, config.DefaultConfigPath); err != nil {
+		return errors.Wrapf(err, "failed to write %s on node %s", config.DefaultConfigPath, n.Name())
+	}
+
+	return n.Command("systemctl", "restart", "containerd").Silent().Run()
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package nodes
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 
 	"k8s.io/kubeadm/kinder/pkg/cluster/status"
@@ -25,58 +27,152 @@ import (
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes/containerd"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes/docker"
 	"k8s.io/kubeadm/kinder/pkg/exec"
+	"k8s.io/kubeadm/kinder/pkg/loadbalancer"
 )
 
 // CreateHelper provides CRI specific methods for node create
 type CreateHelper struct {
-	cri status.ContainerRuntime
+	cri     status.ContainerRuntime
+	network string
 }
 
-// NewCreateHelper returns a new CreateHelper
-func NewCreateHelper(cri status.ContainerRuntime) (*CreateHelper, error) {
+// NewCreateHelper returns a new CreateHelper; containers are connected to the given docker network,
+// or to the default network if empty
+func NewCreateHelper(cri status.ContainerRuntime, network string) (*CreateHelper, error) {
 	return &CreateHelper{
-		cri: cri,
+		cri:     cri,
+		network: network,
 	}, nil
 }
 
-// CreateNode creates a container that internally hosts the selected cri runtime
-func (h *CreateHelper) CreateNode(cluster, name, image, role string, volumes []string) error {
+// CreateNode creates a container that internally hosts the selected cri runtime;
+// if ips are set, they are assigned to the container, and if command is set, it overrides the container command
+func (h *CreateHelper) CreateNode(cluster, name, image, role string, volumes []string, portMappings []status.PortMapping, apiServerBindPort int32, labels map[string]string, dns status.NodeDNS, resources status.NodeResources, ips status.NodeIPs, command string) error {
 	switch h.cri {
 	case status.ContainerdRuntime:
-		return containerd.CreateNode(cluster, name, image, role, volumes)
+		return containerd.CreateNode(cluster, h.network, name, image, role, volumes, portMappings, apiServerBindPort, labels, dns, resources, ips, command)
 	case status.DockerRuntime:
-		return docker.CreateNode(cluster, name, image, role, volumes)
+		return docker.CreateNode(cluster, h.network, name, image, role, volumes, portMappings, apiServerBindPort, labels, dns, resources, ips, command)
 	}
 	return errors.Errorf("unknown cri: %s", h.cri)
 }
 
-// CreateExternalEtcd creates a container hosting a single node, insecure, external etcd cluster
-func (h *CreateHelper) CreateExternalEtcd(cluster, name, image string) error {
-	args, err := common.BaseRunArgs(cluster, name, constants.ExternalEtcdNodeRoleValue)
+// CreateExternalEtcd creates a container hosting a single node, insecure, external etcd cluster;
+// if dataDir is set, the host directory is used as etcd data dir. etcdExtraArgs, in the key=value form,
+// are passed to etcd as flags
+func (h *CreateHelper) CreateExternalEtcd(cluster, name, image, dataDir string, etcdExtraArgs []string) error {
+	args, err := common.BaseRunArgs(cluster, h.network, name, constants.ExternalEtcdNodeRoleValue)
 	if err != nil {
 		return err
 	}
+	args = common.RunArgsForNetwork(h.network, args)
 
 	// Add etcd run args
-	args = common.RunArgsForExternalEtcd(args)
+	args = common.RunArgsForExternalEtcd(dataDir, args)
 
 	// Specify the image to run
 	args = append(args, image)
 
 	// Add container args for starting a single node, insecure etcd
-	args = common.ContainerArgsForExternalEtcd(cluster, args)
+	args = common.ContainerArgsForExternalEtcd(cluster, dataDir, etcdExtraArgs, args)
 
 	// creates the container
 	return exec.NewHostCmd("docker", args...).Run()
 }
 
-// CreateExternalLoadBalancer creates a container hosting an external load balancer
-func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string) error {
-	args, err := common.BaseRunArgs(cluster, name, constants.ExternalLoadBalancerNodeRoleValue)
+// CreateExternalEtcdMember creates a container hosting an insecure external etcd member joining
+// an existing external etcd cluster; the container is connected to the external etcd network, for
+// communication between members, and to the cluster network, for communication with the control-plane.
+// etcdExtraArgs, in the key=value form, are passed to etcd as flags
+func (h *CreateHelper) CreateExternalEtcdMember(cluster, name, image, initialCluster string, etcdExtraArgs []string) error {
+	return h.createExternalEtcdMember(cluster, name, image, "", initialCluster, "existing", etcdExtraArgs)
+}
+
+// CreateExternalEtcdCluster creates the containers hosting an insecure external etcd cluster with the given
+// number of members, that bootstrap the etcd cluster together; members communicate on the external etcd network,
+// that is created if it does not exist yet. etcdExtraArgs, in the key=value form, are passed to etcd as flags
+func (h *CreateHelper) CreateExternalEtcdCluster(cluster, image string, members int, etcdExtraArgs []string) error {
+	return h.CreateExternalEtcdClusterWithDataDirs(cluster, image, make([]string, members), etcdExtraArgs)
+}
+
+// CreateExternalEtcdClusterWithDataDirs creates the containers hosting an insecure external etcd cluster with a
+// member for each data dir; if a data dir is set, the host directory is used as etcd data dir of the member,
+// e.g. for restoring a snapshot. etcdExtraArgs, in the key=value form, are passed to etcd as flags
+func (h *CreateHelper) CreateExternalEtcdClusterWithDataDirs(cluster, image string, dataDirs []string, etcdExtraArgs []string) error {
+	network := common.ExternalEtcdNetwork(cluster)
+	if err := exec.NewHostCmd("docker", "network", "inspect", network).Run(); err != nil {
+		if err := exec.NewHostCmd(
+			"docker", "network", "create",
+			"--label", fmt.Sprintf("%s=%s", constants.ClusterLabelKey, cluster),
+			network,
+		).Run(); err != nil {
+			return errors.Wrapf(err, "failed to create the %s network", network)
+		}
+	}
+
+	names := make([]string, 0, len(dataDirs))
+	for i := 1; i <= len(dataDirs); i++ {
+		names = append(names, common.ExternalEtcdMemberName(cluster, i))
+	}
+	initialCluster := common.ExternalEtcdInitialCluster(names)
+	for i, name := range names {
+		if err := h.createExternalEtcdMember(cluster, name, image, dataDirs[i], initialCluster, "new", etcdExtraArgs); err != nil {
+			return errors.Wrapf(err, "failed to create etcd member %s", name)
+		}
+	}
+	return nil
+}
+
+// createExternalEtcdMember creates a container hosting an insecure external etcd member with the given initial
+// cluster state, connected both to the external etcd network and to the cluster network; if dataDir is set,
+// the host directory is used as etcd data dir
+func (h *CreateHelper) createExternalEtcdMember(cluster, name, image, dataDir, initialCluster, initialClusterState string, etcdExtraArgs []string) error {
+	args, err := common.BaseRunArgs(cluster, h.network, name, constants.ExternalEtcdNodeRoleValue)
+	if err != nil {
+		return err
+	}
+
+	// Add etcd run args
+	args = common.RunArgsForExternalEtcd(dataDir, args)
+	args = append(args, "--network", common.ExternalEtcdNetwork(cluster))
+
+	// Specify the image to run
+	args = append(args, image)
+
+	// Add container args for starting an etcd member joining the existing cluster
+	args = common.ContainerArgsForExternalEtcdMember(name, dataDir, initialCluster, initialClusterState, etcdExtraArgs, args)
+
+	// creates the container
+	if err := exec.NewHostCmd("docker", args...).Run(); err != nil {
+		return err
+	}
+
+	network := h.network
+	if network == "" {
+		network = common.DefaultNetwork
+	}
+	return exec.NewHostCmd("docker", "network", "connect", network, name).Run()
+}
+
+// CreateExternalLoadBalancer creates a container hosting an external load balancer, using the given
+// load balancer implementation; the implementation is recorded in a label on the container. If ips are set,
+// they are assigned to the container
+func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string, implementation loadbalancer.Implementation, ips status.NodeIPs) error {
+	provider, err := loadbalancer.NewProvider(implementation)
 	if err != nil {
 		return err
 	}
 
+	args, err := common.BaseRunArgs(cluster, h.network, name, constants.ExternalLoadBalancerNodeRoleValue)
+	if err != nil {
+		return err
+	}
+	args = common.RunArgsForNetwork(h.network, args)
+	if args, err = common.RunArgsForIPs(h.network, ips, args); err != nil {
+		return err
+	}
+	args = append(args, "--label", fmt.Sprintf("%s=%s", constants.LoadBalancerLabelKey, implementation))
+
 	// Add load balancer run args
 	args, err = common.RunArgsForExternalLoadBalancer(args)
 	if err != nil {
@@ -84,8 +180,42 @@ func (h *CreateHelper) CreateExternalLoadBalancer(cluster, name string) error {
 	}
 
 	// Specify the image to run
-	args = append(args, constants.LoadBalancerImage)
+	args = append(args, provider.Image())
 
 	// creates the container
 	return exec.NewHostCmd("docker", args...).Run()
 }
+
+// CreateExternalRegistry creates a container hosting a local image registry
+func (h *CreateHelper) CreateExternalRegistry(cluster, name string) error {
+	args, err := common.BaseRunArgs(cluster, h.network, name, constants.ExternalRegistryNodeRoleValue)
+	if err != nil {
+		return err
+	}
+	args = common.RunArgsForNetwork(h.network, args)
+
+	// Specify the image to run
+	args = append(args, constants.RegistryImage)
+
+	// creates the container
+	return exec.NewHostCmd("docker", args...).Run()
+}
+
+// ConfigureRegistryMirror configures the container runtime inside a node to use the endpoint
+// as mirror for all the registries
+func (h *CreateHelper) ConfigureRegistryMirror(n *status.Node, endpoint string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.ConfigureRegistryMirror(n, endpoint)
+	}
+	return errors.Errorf("registry mirror is not supported for the %s cri", h.cri)
+}
+
+// ConfigureSandboxImage configures the container runtime inside a node to use the given sandbox (pause) image
+func (h *CreateHelper) ConfigureSandboxImage(n *status.Node, image string) error {
+	switch h.cri {
+	case status.ContainerdRuntime:
+		return containerd.ConfigureSandboxImage(n, image)
+	}
+	return errors.Errorf("sandbox image is not supported for the %s cri", h.cri)
+}
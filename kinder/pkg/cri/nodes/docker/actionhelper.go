@@ -18,6 +18,7 @@ package docker
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -32,6 +33,13 @@ func PreLoadUpgradeImages(n *status.Node, srcFolder string) error {
 	).Silent().Run()
 }
 
+// PullImage pulls an image into the docker runtime that exists inside a kind(er) node
+func PullImage(n *status.Node, image string) error {
+	return n.Command(
+		"docker", "pull", image,
+	).Silent().Run()
+}
+
 // GetImages returns the list of images available in the node
 func GetImages(n *status.Node) ([]string, error) {
 	current, err := n.Command(
@@ -48,3 +56,18 @@ func GetImages(n *status.Node) ([]string, error) {
 
 	return current, nil
 }
+
+// GetImageArch returns the architecture of an image available in the docker runtime that exists inside a kind(er) node;
+// an empty string is returned if the architecture is not reported by the runtime
+func GetImageArch(n *status.Node, image string) (string, error) {
+	lines, err := n.Command(
+		"docker", "image", "inspect", "--format", "{{.Architecture}}", image,
+	).Silent().RunAndCapture()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the architecture of image %s from %s", image, n.Name())
+	}
+	if len(lines) != 1 {
+		return "", nil
+	}
+	return strings.TrimSpace(lines[0]), nil
+}
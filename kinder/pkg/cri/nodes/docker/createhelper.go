@@ -22,30 +22,44 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"k8s.io/kubeadm/kinder/pkg/cluster/status"
 	"k8s.io/kubeadm/kinder/pkg/cri/nodes/common"
 	"k8s.io/kubeadm/kinder/pkg/exec"
 )
 
-// CreateNode creates a container that internally hosts the docker cri runtime
-func CreateNode(cluster, name, image, role string, volumes []string) error {
-	args, err := common.BaseRunArgs(cluster, name, role)
+// CreateNode creates a container that internally hosts the docker cri runtime;
+// if ips are set, they are assigned to the container, and if command is set, it overrides the container command
+func CreateNode(cluster, network, name, image, role string, volumes []string, portMappings []status.PortMapping, apiServerBindPort int32, labels map[string]string, dns status.NodeDNS, resources status.NodeResources, ips status.NodeIPs, command string) error {
+	args, err := common.BaseRunArgs(cluster, network, name, role)
 	if err != nil {
 		return err
 	}
+	args = common.RunArgsForNetwork(network, args)
+	if args, err = common.RunArgsForIPs(network, ips, args); err != nil {
+		return err
+	}
 
-	args, err = common.RunArgsForNode(role, volumes, args)
+	args, err = common.RunArgsForNode(role, volumes, portMappings, apiServerBindPort, labels, dns, resources, args)
 	if err != nil {
 		return err
 	}
 
-	// Add run args for docker in docker
-	args = runArgsForDocker(args)
+	// Add run args for docker in docker, or override the container command if requested
+	if command != "" {
+		args = common.RunArgsForNodeCommand(args)
+	} else {
+		args = runArgsForDocker(args)
+	}
 
 	// Specify the image to run
 	args = append(args, image)
 
 	// dd container args for docker in docker
-	args = containerArgsForDocker(args)
+	if command != "" {
+		args = common.ContainerArgsForNodeCommand(command, args)
+	} else {
+		args = containerArgsForDocker(args)
+	}
 
 	// creates the container
 	if err := exec.NewHostCmd("docker", args...).Run(); err != nil {
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CommandError is returned by commands run with TailOnError when the command fails; it embeds
+// the command text, the exit code and the last lines of the combined command output,
+// so failures carry actionable context instead of a bare exit status.
+type CommandError struct {
+	Node     string
+	Command  string
+	ExitCode int
+	Tail     []string
+	Err      error
+}
+
+// Error implements error
+func (e *CommandError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "command %q failed on node %s", e.Command, e.Node)
+	if e.ExitCode >= 0 {
+		fmt.Fprintf(&b, " with exit code %d", e.ExitCode)
+	} else {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	if len(e.Tail) > 0 {
+		fmt.Fprintf(&b, ", last %d lines of output:\n%s", len(e.Tail), strings.Join(e.Tail, "\n"))
+	}
+	return b.String()
+}
+
+// Unwrap returns the error returned by the command execution
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the error returned by the command execution, for compatibility with errors.Cause
+func (e *CommandError) Cause() error {
+	return e.Err
+}
+
+// newCommandError returns a CommandError for a failed command; the exit code is set to -1
+// if the command did not exit, e.g. it was not found
+func newCommandError(node, command string, tail []string, err error) *CommandError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	return &CommandError{
+		Node:     node,
+		Command:  command,
+		ExitCode: exitCode,
+		Tail:     tail,
+		Err:      err,
+	}
+}
+
+// tailWriter is an io.Writer keeping the last lines written to it; it is safe to use
+// the same tailWriter for both stdout and stderr.
+type tailWriter struct {
+	lineWriter *LineWriter
+	lines      []string
+}
+
+// newTailWriter returns a new tailWriter keeping the last n lines
+func newTailWriter(n int) *tailWriter {
+	w := &tailWriter{}
+	w.lineWriter = NewLineWriter(func(line string) {
+		w.lines = append(w.lines, line)
+		if len(w.lines) > n {
+			w.lines = w.lines[len(w.lines)-n:]
+		}
+	})
+	return w
+}
+
+// Write implements io.Writer
+func (w *tailWriter) Write(p []byte) (int, error) {
+	return w.lineWriter.Write(p)
+}
+
+// Tail returns the last lines written, including the incomplete last line, if any
+func (w *tailWriter) Tail() []string {
+	w.lineWriter.Flush()
+	return w.lines
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestTailWriter(t *testing.T) {
+	var tests = []struct {
+		name     string
+		n        int
+		writes   []string
+		expected []string
+	}{
+		{
+			name:   "no output",
+			n:      2,
+			writes: []string{},
+		},
+		{
+			name:     "less lines than tail",
+			n:        3,
+			writes:   []string{"foo\n", "bar\n"},
+			expected: []string{"foo", "bar"},
+		},
+		{
+			name:     "last lines are kept",
+			n:        2,
+			writes:   []string{"foo\nbar\n", "baz\n"},
+			expected: []string{"bar", "baz"},
+		},
+		{
+			name:     "incomplete last line is kept",
+			n:        2,
+			writes:   []string{"foo\nbar\nbaz"},
+			expected: []string{"bar", "baz"},
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			w := newTailWriter(rt.n)
+			for _, s := range rt.writes {
+				if _, err := w.Write([]byte(s)); err != nil {
+					t.Fatalf("failed Write: %v", err)
+				}
+			}
+
+			if lines := w.Tail(); !reflect.DeepEqual(lines, rt.expected) {
+				t.Errorf("failed tailWriter:\n\texpected: %q\n\tactual: %q", rt.expected, lines)
+			}
+		})
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	cause := errors.New("executable file not found")
+	err := newCommandError("kind-worker-1", "kubeadm join", []string{"error execution phase preflight"}, cause)
+
+	if err.ExitCode != -1 {
+		t.Errorf("expected exit code -1, got %d", err.ExitCode)
+	}
+	if errors.Cause(err) != cause {
+		t.Errorf("expected cause %v, got %v", cause, errors.Cause(err))
+	}
+	expected := "command \"kubeadm join\" failed on node kind-worker-1: executable file not found, last 1 lines of output:\nerror execution phase preflight"
+	if err.Error() != expected {
+		t.Errorf("expected error:\n%s\ngot:\n%s", expected, err.Error())
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	commandContextMu sync.RWMutex
+	commandContext   = context.Background()
+)
+
+// SetCommandContext sets the context used for running host and node commands, e.g. for enforcing
+// an overall deadline; when the context is done, running commands are killed and new commands fail
+// immediately. Setting a nil context restores the default, that never expires.
+func SetCommandContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	commandContextMu.Lock()
+	defer commandContextMu.Unlock()
+	commandContext = ctx
+}
+
+// getCommandContext returns the context used for running host and node commands
+func getCommandContext() context.Context {
+	commandContextMu.RLock()
+	defer commandContextMu.RUnlock()
+	return commandContext
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetCommandContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	SetCommandContext(ctx)
+	defer SetCommandContext(nil)
+
+	start := time.Now()
+	if err := NewHostCmd("sleep", "10").Run(); err == nil {
+		t.Fatal("expected the command to be killed when the context is done")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to be killed at the deadline, it ran for %s", elapsed)
+	}
+
+	SetCommandContext(nil)
+	if err := NewHostCmd("true").Run(); err != nil {
+		t.Errorf("expected the command to succeed after restoring the default context, got %v", err)
+	}
+}
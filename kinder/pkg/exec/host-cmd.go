@@ -80,6 +80,13 @@ func (c *HostCmd) Stdin(in io.Reader) *HostCmd {
 	return c
 }
 
+// Stdout sets an io.Writer to be used for collecting the output of the inner command; it is ignored
+// by RunWithEcho and RunAndCapture, which set their own output
+func (c *HostCmd) Stdout(out io.Writer) *HostCmd {
+	c.stdout = out
+	return c
+}
+
 // SetEnv sets env variables to be used when running the inner command
 func (c *HostCmd) SetEnv(env ...string) *HostCmd {
 	c.env = env
@@ -88,7 +95,7 @@ func (c *HostCmd) SetEnv(env ...string) *HostCmd {
 
 func (c *HostCmd) runInnnerCommand() error {
 	// create the commands
-	cmd := exec.Command(c.command, c.args...)
+	cmd := exec.CommandContext(getCommandContext(), c.command, c.args...)
 
 	// redirects flows if requested
 	if c.stdin != nil {
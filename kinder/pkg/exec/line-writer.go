@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LineWriter is an io.Writer that invokes a callback for each line written to it.
+// Incomplete lines are buffered until a newline is written or Flush is called.
+// It is safe to use the same LineWriter for both stdout and stderr.
+type LineWriter struct {
+	mu       sync.Mutex
+	buff     bytes.Buffer
+	callback func(line string)
+}
+
+// NewLineWriter returns a new LineWriter invoking callback for each line
+func NewLineWriter(callback func(line string)) *LineWriter {
+	return &LineWriter{
+		callback: callback,
+	}
+}
+
+// Write implements io.Writer
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buff.Write(p)
+	for {
+		i := bytes.IndexByte(w.buff.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := string(bytes.TrimSuffix(w.buff.Next(i + 1)[:i], []byte("\r")))
+		w.callback(line)
+	}
+	return len(p), nil
+}
+
+// Flush invokes the callback for the remaining incomplete line, if any
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buff.Len() > 0 {
+		w.callback(w.buff.String())
+		w.buff.Reset()
+	}
+}
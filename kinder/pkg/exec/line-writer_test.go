@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var tests = []struct {
+		name     string
+		writes   []string
+		expected []string
+	}{
+		{
+			name:     "one line per write",
+			writes:   []string{"foo\n", "bar\n"},
+			expected: []string{"foo", "bar"},
+		},
+		{
+			name:     "many lines in one write",
+			writes:   []string{"foo\nbar\r\nbaz\n"},
+			expected: []string{"foo", "bar", "baz"},
+		},
+		{
+			name:     "lines split across writes",
+			writes:   []string{"fo", "o\nb", "ar\n"},
+			expected: []string{"foo", "bar"},
+		},
+		{
+			name:     "incomplete line is flushed",
+			writes:   []string{"foo\nbar"},
+			expected: []string{"foo", "bar"},
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			var lines []string
+			w := NewLineWriter(func(line string) {
+				lines = append(lines, line)
+			})
+			for _, s := range rt.writes {
+				if _, err := w.Write([]byte(s)); err != nil {
+					t.Fatalf("failed Write: %v", err)
+				}
+			}
+			w.Flush()
+
+			if !reflect.DeepEqual(lines, rt.expected) {
+				t.Errorf("failed LineWriter:\n\texpected: %q\n\tactual: %q", rt.expected, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// LogFormat defines the format of the progress messages printed by kinder
+type LogFormat string
+
+const (
+	// LogFormatText prints progress messages as human readable text; this is the default
+	LogFormatText LogFormat = "text"
+
+	// LogFormatJSON prints progress messages as JSON log entries, with fields like node, action,
+	// phase and duration, e.g. for machine consumption of kinder runs in CI
+	LogFormatJSON LogFormat = "json"
+)
+
+// CommandOutput defines how the output of commands executed with echo, e.g. kubeadm, is printed
+type CommandOutput string
+
+const (
+	// CommandOutputEcho prints the raw output of commands; this is the default
+	CommandOutputEcho CommandOutput = "echo"
+
+	// CommandOutputTagged prints each line of output of commands tagged with the node name; with the JSON
+	// log format, each line is printed as a JSON log entry
+	CommandOutputTagged CommandOutput = "tagged"
+
+	// CommandOutputNone suppresses the output of commands; the output is still available for
+	// reporting errors and to the callers consuming it
+	CommandOutputNone CommandOutput = "none"
+)
+
+var (
+	logModeMu     sync.RWMutex
+	logFormat     = LogFormatText
+	commandOutput = CommandOutputEcho
+	logAction     string
+	echoOutput    io.Writer = os.Stdout
+)
+
+// SetLogMode sets the format of the progress messages and how the output of commands is printed
+func SetLogMode(format LogFormat, output CommandOutput) error {
+	switch format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		return errors.Errorf("invalid log format %q. Use one of [%s, %s]", format, LogFormatText, LogFormatJSON)
+	}
+	switch output {
+	case CommandOutputEcho, CommandOutputTagged, CommandOutputNone:
+	default:
+		return errors.Errorf("invalid command output %q. Use one of [%s, %s, %s]", output, CommandOutputEcho, CommandOutputTagged, CommandOutputNone)
+	}
+
+	logModeMu.Lock()
+	defer logModeMu.Unlock()
+	logFormat = format
+	commandOutput = output
+	return nil
+}
+
+// SetLogAction sets the name of the action being executed, that is added to the JSON log entries;
+// an empty name clears it
+func SetLogAction(action string) {
+	logModeMu.Lock()
+	defer logModeMu.Unlock()
+	logAction = action
+}
+
+// SetEchoOutput sets the writer for the progress messages and the echoed commands that are printed on stdout
+// by default, e.g. stderr when stdout is reserved for a machine readable output
+func SetEchoOutput(w io.Writer) {
+	logModeMu.Lock()
+	defer logModeMu.Unlock()
+	echoOutput = w
+}
+
+// EchoOutput returns the writer for the progress messages and the echoed commands
+func EchoOutput() io.Writer {
+	logModeMu.RLock()
+	defer logModeMu.RUnlock()
+	return echoOutput
+}
+
+// IsJSONLog returns true if progress messages are printed as JSON log entries
+func IsJSONLog() bool {
+	logModeMu.RLock()
+	defer logModeMu.RUnlock()
+	return logFormat == LogFormatJSON
+}
+
+// getCommandOutput returns how the output of commands is printed
+func getCommandOutput() CommandOutput {
+	logModeMu.RLock()
+	defer logModeMu.RUnlock()
+	return commandOutput
+}
+
+// Logger returns a log entry with the action being executed, if any, and with the given node, if not empty
+func Logger(node string) *log.Entry {
+	logModeMu.RLock()
+	action := logAction
+	logModeMu.RUnlock()
+
+	fields := log.Fields{}
+	if action != "" {
+		fields["action"] = action
+	}
+	if node != "" {
+		fields["node"] = node
+	}
+	return log.WithFields(fields)
+}
+
+// Printf prints a progress message; with the JSON log format, the message is printed as a JSON log entry instead,
+// and empty messages, e.g. used for spacing text output, are skipped
+func Printf(format string, args ...interface{}) {
+	if IsJSONLog() {
+		if message := strings.TrimSpace(fmt.Sprintf(format, args...)); message != "" {
+			Logger("").Info(message)
+		}
+		return
+	}
+	fmt.Fprintf(EchoOutput(), format, args...)
+}
+
+// Println prints a progress message followed by a newline, like Printf
+func Println(args ...interface{}) {
+	Printf("%s", fmt.Sprintln(args...))
+}
+
+// echoWriter returns the writer for echoing the output of a command executed on a node to w, according
+// to the command output mode, and a func flushing the remaining output, if any
+func echoWriter(node string, w io.Writer) (io.Writer, func()) {
+	switch getCommandOutput() {
+	case CommandOutputNone:
+		return io.Discard, func() {}
+	case CommandOutputTagged:
+		lw := NewLineWriter(func(line string) {
+			if IsJSONLog() {
+				Logger(node).WithField("stream", "output").Info(line)
+				return
+			}
+			fmt.Fprintf(w, "[%s] %s\n", node, line)
+		})
+		return lw, lw.Flush
+	default:
+		return w, func() {}
+	}
+}
+
+// commandPhase returns the kubeadm phase executed by a command, in the phase/sub-phase form,
+// or an empty string if the command isn't a kubeadm phase
+func commandPhase(command string, args []string) string {
+	if command != "kubeadm" {
+		return ""
+	}
+	for i, a := range args {
+		if a != "phase" {
+			continue
+		}
+		var phase []string
+		for _, p := range args[i+1:] {
+			if strings.HasPrefix(p, "-") {
+				break
+			}
+			phase = append(phase, p)
+		}
+		return strings.Join(phase, "/")
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSetLogMode(t *testing.T) {
+	defer func() { _ = SetLogMode(LogFormatText, CommandOutputEcho) }()
+
+	tests := []struct {
+		name          string
+		format        LogFormat
+		output        CommandOutput
+		expectedError bool
+	}{
+		{
+			name:   "text and echo",
+			format: LogFormatText,
+			output: CommandOutputEcho,
+		},
+		{
+			name:   "json and tagged",
+			format: LogFormatJSON,
+			output: CommandOutputTagged,
+		},
+		{
+			name:          "invalid format",
+			format:        "xml",
+			output:        CommandOutputEcho,
+			expectedError: true,
+		},
+		{
+			name:          "invalid command output",
+			format:        LogFormatText,
+			output:        "quiet",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetLogMode(tt.format, tt.output)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if IsJSONLog() != (tt.format == LogFormatJSON) {
+				t.Errorf("expected JSON log: %v", tt.format == LogFormatJSON)
+			}
+		})
+	}
+}
+
+func TestEchoWriter(t *testing.T) {
+	defer func() { _ = SetLogMode(LogFormatText, CommandOutputEcho) }()
+
+	tests := []struct {
+		name     string
+		output   CommandOutput
+		expected string
+	}{
+		{
+			name:     "echo",
+			output:   CommandOutputEcho,
+			expected: "line 1\nline 2",
+		},
+		{
+			name:     "tagged",
+			output:   CommandOutputTagged,
+			expected: "[kind-control-plane-1] line 1\n[kind-control-plane-1] line 2\n",
+		},
+		{
+			name:   "none",
+			output: CommandOutputNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetLogMode(LogFormatText, tt.output); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			w, flush := echoWriter("kind-control-plane-1", &out)
+			if _, err := io.WriteString(w, "line 1\nline 2"); err != nil {
+				t.Fatal(err)
+			}
+			flush()
+			if out.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestCommandPhase(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		expected string
+	}{
+		{
+			name:    "kubeadm init",
+			command: "kubeadm",
+			args:    []string{"init", "--config=/kind/kubeadm.conf"},
+		},
+		{
+			name:     "kubeadm init phase",
+			command:  "kubeadm",
+			args:     []string{"init", "phase", "control-plane", "all", "--config=/kind/kubeadm.conf"},
+			expected: "control-plane/all",
+		},
+		{
+			name:     "kubeadm join phase",
+			command:  "kubeadm",
+			args:     []string{"join", "phase", "preflight"},
+			expected: "preflight",
+		},
+		{
+			name:    "not kubeadm",
+			command: "kubectl",
+			args:    []string{"phase", "x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commandPhase(tt.command, tt.args); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPrintf(t *testing.T) {
+	defer func() { _ = SetLogMode(LogFormatText, CommandOutputEcho) }()
+	defer SetEchoOutput(os.Stdout)
+	defer log.SetOutput(log.StandardLogger().Out)
+
+	var echo, logs bytes.Buffer
+	SetEchoOutput(&echo)
+	log.SetOutput(&logs)
+
+	Printf("Node %s is ready\n", "kind-worker")
+	Println()
+	if echo.String() != "Node kind-worker is ready\n\n" || logs.Len() != 0 {
+		t.Errorf("expected the message to be printed as text, got %q and logs %q", echo.String(), logs.String())
+	}
+
+	echo.Reset()
+	if err := SetLogMode(LogFormatJSON, CommandOutputEcho); err != nil {
+		t.Fatal(err)
+	}
+	Printf("Node %s is ready\n", "kind-worker")
+	Println()
+	if echo.Len() != 0 || !strings.Contains(logs.String(), "Node kind-worker is ready") {
+		t.Errorf("expected the message to be logged, got %q and logs %q", echo.String(), logs.String())
+	}
+	if lines := strings.Count(logs.String(), "\n"); lines != 1 {
+		t.Errorf("expected 1 log entry, got %d: %q", lines, logs.String())
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -36,13 +38,15 @@ import (
 //	command text, that can help in debugging, please set the KINDER_COLORS environment variable to ON.
 //
 // By default, when the command is run it does not print any output generated during execution.
-// See Silent, Stdin, RunWithEcho, RunAndCapture, Skip and DryRun for possible variations to the default behavior.
+// See Silent, Stdin, TailOnError, RunWithEcho, RunAndCapture, Skip and DryRun for possible variations to the default behavior.
 type NodeCmd struct {
 	node    string
 	command string
 	args    []string
 	silent  bool
 	dryRun  bool
+	tail    int
+	tee     io.Writer
 	stdin   io.Reader
 	stdout  io.Writer
 	stderr  io.Writer
@@ -64,11 +68,38 @@ func (c *NodeCmd) Run() error {
 	return c.runInnnerCommand()
 }
 
-// RunWithEcho execute the inner command on a kind(er) node and echoes the command output to screen
+// RunWithEcho execute the inner command on a kind(er) node and echoes the command output to screen;
+// the echo can be tagged with the node name or suppressed, see SetLogMode
 func (c *NodeCmd) RunWithEcho() error {
-	c.stdout = os.Stderr
-	c.stderr = os.Stdout
-	return c.runInnnerCommand()
+	stdout, flushStdout := echoWriter(c.node, os.Stderr)
+	stderr, flushStderr := echoWriter(c.node, EchoOutput())
+	c.stdout = stdout
+	c.stderr = stderr
+	err := c.runInnnerCommand()
+	flushStdout()
+	flushStderr()
+	return err
+}
+
+// RunWithEchoTo execute the inner command on a kind(er) node, echoes the command output to screen
+// and also writes the command output to the given io.Writer, e.g. for teeing it into a custom log
+func (c *NodeCmd) RunWithEchoTo(w io.Writer) error {
+	stdout, flushStdout := echoWriter(c.node, os.Stderr)
+	stderr, flushStderr := echoWriter(c.node, EchoOutput())
+	c.stdout, c.stderr = combinedOutputWriters(stdout, stderr, w)
+	err := c.runInnnerCommand()
+	flushStdout()
+	flushStderr()
+	return err
+}
+
+// RunWithLineCallback execute the inner command on a kind(er) node, echoes the command output to screen
+// and also invokes the given callback for each line of output as soon as it is available
+func (c *NodeCmd) RunWithLineCallback(callback func(line string)) error {
+	w := NewLineWriter(callback)
+	err := c.RunWithEchoTo(w)
+	w.Flush()
+	return err
 }
 
 // RunAndCapture executes the inner command on a kind(er) node and return the output captured during execution
@@ -92,12 +123,55 @@ func (c *NodeCmd) Stdin(in io.Reader) *NodeCmd {
 	return c
 }
 
+// Stdout sets an io.Writer to be used for collecting the output of the inner command; it is ignored
+// by RunWithEcho, RunWithEchoTo, RunWithLineCallback and RunAndCapture, which set their own output
+func (c *NodeCmd) Stdout(out io.Writer) *NodeCmd {
+	c.stdout = out
+	return c
+}
+
 // Silent instructs the proxy command to not the command text to stdout before execution
 func (c *NodeCmd) Silent() *NodeCmd {
 	c.silent = true
 	return c
 }
 
+// TailOnError instructs the proxy command to capture the combined command output, and in case of failure
+// to return a *CommandError embedding the command text, the exit code and the last n lines of output
+func (c *NodeCmd) TailOnError(n int) *NodeCmd {
+	c.tail = n
+	return c
+}
+
+// ReplaceCommand instructs the proxy command to run replacement in place of the inner command, if the inner command
+// is the given command, e.g. for running a binary that is not in the PATH of the node
+func (c *NodeCmd) ReplaceCommand(command, replacement string) *NodeCmd {
+	if c.command == command {
+		c.command = replacement
+	}
+	return c
+}
+
+// TeeCommand instructs the proxy command to also write the combined command output to the given io.Writer, if the inner
+// command is the given command, with one of the given subcommands as first arg, if any; differently from Stdout, the output
+// is written regardless of how the command is run, e.g. for capturing the output of commands executed deep in an action
+func (c *NodeCmd) TeeCommand(command string, w io.Writer, subcommands ...string) *NodeCmd {
+	if c.command != command {
+		return c
+	}
+	if len(subcommands) == 0 {
+		c.tee = w
+		return c
+	}
+	for _, s := range subcommands {
+		if len(c.args) > 0 && c.args[0] == s {
+			c.tee = w
+			return c
+		}
+	}
+	return c
+}
+
 // DryRun instruct the proxy command to print the inner command text instead of running it.
 func (c *NodeCmd) DryRun() *NodeCmd {
 	c.dryRun = true
@@ -133,7 +207,7 @@ func (c *NodeCmd) runInnnerCommand() error {
 	)
 
 	// create the proxy commands
-	cmd := exec.Command(command, args...)
+	cmd := exec.CommandContext(getCommandContext(), command, args...)
 
 	// redirects flows if requested
 	if c.stdin != nil {
@@ -146,11 +220,21 @@ func (c *NodeCmd) runInnnerCommand() error {
 		cmd.Stderr = c.stderr
 	}
 
-	// if not silent, prints the screen echo for the command to be executed
+	// if not silent, prints the screen echo for the command to be executed, or logs it
+	// when using the JSON log format
+	var logger *log.Entry
 	if !c.silent {
-		prompt := colors.Prompt(fmt.Sprintf("%s:$ ", c.node))
-		command := colors.Command(fmt.Sprintf("%s %s", c.command, strings.Join(c.args, " ")))
-		fmt.Printf("\n%s%s\n", prompt, command)
+		if IsJSONLog() {
+			logger = Logger(c.node).WithField("command", fmt.Sprintf("%s %s", c.command, strings.Join(c.args, " ")))
+			if phase := commandPhase(c.command, c.args); phase != "" {
+				logger = logger.WithField("phase", phase)
+			}
+			logger.Info("running command")
+		} else {
+			prompt := colors.Prompt(fmt.Sprintf("%s:$ ", c.node))
+			command := colors.Command(fmt.Sprintf("%s %s", c.command, strings.Join(c.args, " ")))
+			fmt.Fprintf(EchoOutput(), "\n%s%s\n", prompt, command)
+		}
 	}
 
 	// if we are dry running, eventually print the proxy command and then exit
@@ -159,7 +243,66 @@ func (c *NodeCmd) runInnnerCommand() error {
 		return nil
 	}
 
+	// if requested, capture the last lines of the combined output for reporting errors,
+	// and writes the combined output also to the tee writer
+	var tail *tailWriter
+	var extra []io.Writer
+	if c.tail > 0 {
+		tail = newTailWriter(c.tail)
+		extra = append(extra, tail)
+	}
+	if c.tee != nil {
+		extra = append(extra, c.tee)
+	}
+	cmd.Stdout, cmd.Stderr = combinedOutputWriters(cmd.Stdout, cmd.Stderr, extra...)
+
 	// eventually print the proxy command, and then run the command to be executed
 	log.Debugf("Running: %s", strings.Join(cmd.Args, " "))
-	return cmd.Run()
+	start := time.Now()
+	err := cmd.Run()
+	if logger != nil {
+		logger = logger.WithField("duration", time.Since(start).String())
+		if err != nil {
+			logger.WithError(err).Error("command failed")
+		} else {
+			logger.Info("command completed")
+		}
+	}
+	if err != nil && tail != nil {
+		return newCommandError(c.node, fmt.Sprintf("%s %s", c.command, strings.Join(c.args, " ")), tail.Tail(), err)
+	}
+	return err
+}
+
+// combinedOutputWriters returns the writers for the stdout and the stderr of a command, duplicating writes
+// also to the given writers, if any. Stdout and stderr are copied by different goroutines, so writes to
+// the returned writers are serialized, thus allowing to share the given writers and the same writer
+// for stdout and stderr, e.g. when capturing the combined output.
+func combinedOutputWriters(stdout, stderr io.Writer, writers ...io.Writer) (io.Writer, io.Writer) {
+	if len(writers) == 0 {
+		return stdout, stderr
+	}
+	mu := &sync.Mutex{}
+	return &syncWriter{mu: mu, w: teeWriter(stdout, writers...)}, &syncWriter{mu: mu, w: teeWriter(stderr, writers...)}
+}
+
+// teeWriter returns a writer duplicating writes to w, if any, and to writers
+func teeWriter(w io.Writer, writers ...io.Writer) io.Writer {
+	if w == nil {
+		return io.MultiWriter(writers...)
+	}
+	return io.MultiWriter(append([]io.Writer{w}, writers...)...)
+}
+
+// syncWriter is an io.Writer serializing writes to w with a mutex, that can be shared by several syncWriters
+type syncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
 }
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestNodeCmdReplaceCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{name: "matching command", command: "kubeadm", expected: "/kinder/bin/kubeadm"},
+		{name: "other command", command: "kubectl", expected: "kubectl"},
+		{name: "command embedded in a script", command: "sh", expected: "sh"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := NewNodeCmd("kind-control-plane", test.command, "version").ReplaceCommand("kubeadm", "/kinder/bin/kubeadm")
+			if cmd.command != test.expected {
+				t.Errorf("expected command %q, got %q", test.expected, cmd.command)
+			}
+		})
+	}
+}
+
+func TestNodeCmdTeeCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		args     []string
+		expected bool
+	}{
+		{name: "matching subcommand", command: "kubeadm", args: []string{"init", "--v=1"}, expected: true},
+		{name: "matching subcommand with phases", command: "kubeadm", args: []string{"join", "phase", "preflight"}, expected: true},
+		{name: "other subcommand", command: "kubeadm", args: []string{"reset", "--force"}},
+		{name: "other command", command: "kubectl", args: []string{"init"}},
+		{name: "no args", command: "kubeadm"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var w bytes.Buffer
+			cmd := NewNodeCmd("kind-control-plane", test.command, test.args...).TeeCommand("kubeadm", &w, "init", "join")
+			if (cmd.tee != nil) != test.expected {
+				t.Errorf("expected tee %t, got %t", test.expected, cmd.tee != nil)
+			}
+		})
+	}
+}
+
+func TestCombinedOutputWriters(t *testing.T) {
+	// writes many lines both on stdout and stderr, so the streams are copied concurrently
+	script := "for i in $(seq 1 500); do echo out-$i; echo err-$i >&2; done"
+
+	var combined, tee bytes.Buffer
+	tail := newTailWriter(1)
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Stdout, cmd.Stderr = combinedOutputWriters(&combined, &combined, tail, &tee)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, output := range map[string]string{"combined": combined.String(), "tee": tee.String()} {
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 1000 {
+			t.Errorf("expected 1000 lines in the %s output, got %d", name, len(lines))
+		}
+		for _, l := range lines {
+			if !strings.HasPrefix(l, "out-") && !strings.HasPrefix(l, "err-") {
+				t.Errorf("unexpected interleaved line in the %s output: %q", name, l)
+				break
+			}
+		}
+	}
+	if len(tail.Tail()) != 1 {
+		t.Errorf("expected 1 line in the tail, got %v", tail.Tail())
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ParseChecksums parses SHA-256 checksums for the Kubernetes binaries in the BINARY=SHA256 form
+func ParseChecksums(values []string) (map[string]string, error) {
+	checksums := map[string]string{}
+	for _, v := range values {
+		split := strings.SplitN(v, "=", 2)
+		if len(split) != 2 {
+			return nil, errors.Errorf("invalid checksum %q. Use the BINARY=SHA256 form", v)
+		}
+		if !isKubernetesBinary(split[0]) {
+			return nil, errors.Errorf("invalid checksum %q. BINARY must be one of %s", v, allKubernetesBinaries)
+		}
+		checksum, err := parseChecksum(split[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid checksum for %s", split[0])
+		}
+		checksums[split[0]] = checksum
+	}
+	return checksums, nil
+}
+
+// readChecksum reads a SHA-256 checksum file, like the .sha256 files published with Kubernetes
+// release and ci builds; the file can contain only the checksum or the checksum followed by the file name
+func readChecksum(r io.Reader) (string, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading checksum")
+	}
+	fields := strings.Fields(string(buf))
+	if len(fields) == 0 {
+		return "", errors.New("empty checksum file")
+	}
+	return parseChecksum(fields[0])
+}
+
+func parseChecksum(value string) (string, error) {
+	checksum := strings.ToLower(strings.TrimSpace(value))
+	if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+		return "", errors.Errorf("%q is not a valid SHA-256 checksum", value)
+	}
+	return checksum, nil
+}
+
+// verifyChecksum checks that the SHA-256 checksum of a file matches the expected checksum;
+// if not, the file is deleted, so it can't be used by mistake
+func verifyChecksum(path, expected string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading %s", path)
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	if err != nil {
+		return errors.Wrapf(err, "error computing the checksum of %s", path)
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != expected {
+		if err := os.Remove(path); err != nil {
+			log.Warnf("failed to delete %s: %v", path, err)
+		}
+		return errors.Errorf("checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+	}
+	log.Debugf("checksum verified for %s", path)
+	return nil
+}
+
+// verifyPublishedChecksum checks a file downloaded from a Kubernetes release or ci build against
+// the .sha256 checksum file published next to it
+func verifyPublishedChecksum(srcFilePath, dstFilePath string) error {
+	uri := srcFilePath + ".sha256"
+	_, r, err := httpGet(uri)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the checksum for %s", srcFilePath)
+	}
+	defer r.Close()
+
+	checksum, err := readChecksum(r)
+	if err != nil {
+		return errors.Wrapf(err, "invalid checksum file %s", uri)
+	}
+	return verifyChecksum(dstFilePath, checksum)
+}
+
+func isKubernetesBinary(name string) bool {
+	for _, b := range allKubernetesBinaries {
+		if name == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testChecksum is the SHA-256 checksum of "kubeadm\n"
+const testChecksum = "668ca42808cc40823f3a0f5db66a9d3013e8d2b93fc0d1237ba056630aecbdf5"
+
+func TestParseChecksums(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        []string
+		expectedError bool
+	}{
+		{
+			name:   "valid checksums",
+			values: []string{"kubeadm=" + testChecksum, "kubelet=" + strings.ToUpper(testChecksum)},
+		},
+		{
+			name:          "invalid format",
+			values:        []string{testChecksum},
+			expectedError: true,
+		},
+		{
+			name:          "unknown binary",
+			values:        []string{"kube-proxy=" + testChecksum},
+			expectedError: true,
+		},
+		{
+			name:          "invalid checksum",
+			values:        []string{"kubeadm=abc"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checksums, err := ParseChecksums(test.values)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			for _, c := range checksums {
+				if c != testChecksum {
+					t.Errorf("expected checksum %s, got %s", testChecksum, c)
+				}
+			}
+		})
+	}
+}
+
+func TestReadChecksum(t *testing.T) {
+	for _, content := range []string{testChecksum + "\n", testChecksum + "  kubeadm\n"} {
+		checksum, err := readChecksum(strings.NewReader(content))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if checksum != testChecksum {
+			t.Errorf("expected checksum %s, got %s", testChecksum, checksum)
+		}
+	}
+	if _, err := readChecksum(strings.NewReader("")); err == nil {
+		t.Error("expected error for an empty checksum file")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeadm")
+	if err := os.WriteFile(path, []byte("kubeadm\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyChecksum(path, strings.Repeat("0", 64)); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected the file to be deleted after a checksum mismatch")
+	}
+
+	if err := os.WriteFile(path, []byte("kubeadm\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksum(path, testChecksum); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -173,6 +173,14 @@ func WithVersionFolder(versionFolder bool) Option {
 	}
 }
 
+// WithChecksums option instructs the Extractor to verify the Kubernetes binaries against the given SHA-256
+// checksums, keyed by binary name; this applies to all the source types, and it allows tamper-evident builds
+func WithChecksums(checksums map[string]string) Option {
+	return func(b *Extractor) {
+		b.checksums = checksums
+	}
+}
+
 // Extractor defines attributes for a Kubernetes artifact extractor
 type Extractor struct {
 	// src is the source from where to extract file
@@ -185,6 +193,8 @@ type Extractor struct {
 	dstMutator fileNameMutator
 	// add version file to dst
 	addVersionFileToDst bool
+	// expected checksums for the Kubernetes binaries
+	checksums map[string]string
 }
 
 // NewExtractor returns a new extractor configured with the given options
@@ -227,7 +237,23 @@ func (e *Extractor) Extract() (paths map[string]string, err error) {
 		return nil, errors.Errorf("source %s did not resolve to a valid source type", e.src)
 	}
 
-	return f(e.src, e.files, e.dst, e.dstMutator, e.addVersionFileToDst)
+	paths, err = f(e.src, e.files, e.dst, e.dstMutator, e.addVersionFileToDst)
+	if err != nil {
+		return nil, err
+	}
+
+	// verifies the Kubernetes binaries against the expected checksums, if any
+	for binary, checksum := range e.checksums {
+		path, ok := paths[binary]
+		if !ok {
+			return nil, errors.Errorf("a checksum is set for %s, but %s is not extracted", binary, binary)
+		}
+		if err := verifyChecksum(path, checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
 }
 
 // extractFunc define a function that implements an extractor method
@@ -312,7 +338,8 @@ func extractFromHTTP(src string, files []string, dst string, m fileNameMutator,
 	}
 
 	// in case the source is a Kubernetes build, add bin/OS/ARCH to the src uri
-	if strings.HasPrefix(src, releaseBuildURepository) || strings.HasPrefix(src, ciBuildRepository) {
+	kubernetesBuild := strings.HasPrefix(src, releaseBuildURepository) || strings.HasPrefix(src, ciBuildRepository)
+	if kubernetesBuild {
 		src = fmt.Sprintf("%s/bin/linux/amd64", src)
 	}
 
@@ -325,7 +352,13 @@ func extractFromHTTP(src string, files []string, dst string, m fileNameMutator,
 		if err := copyFromURI(srcFilePath, dstFilePath); err != nil {
 			return nil, errors.Wrapf(err, "failed to copy %s to %s", srcFilePath, dstFilePath)
 		}
-		if f == kubeadmBinary || f == kubeletBinary || f == kubectlBinary {
+		if isKubernetesBinary(f) {
+			// binaries from Kubernetes builds are verified against the published checksums
+			if kubernetesBuild {
+				if err := verifyPublishedChecksum(srcFilePath, dstFilePath); err != nil {
+					return nil, err
+				}
+			}
 			os.Chmod(dstFilePath, 0755)
 		}
 		paths[f] = dstFilePath
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failure
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Category defines the category of a failure
+type Category string
+
+const (
+	// None is the category of errors not categorized, e.g. a failed command
+	None = Category("")
+
+	// Partial is the category of failures leaving the action partially completed, e.g. when some nodes
+	// joined the cluster and others failed
+	Partial = Category("partial")
+
+	// Validation is the category of failures detected before changing anything, e.g. invalid flags
+	Validation = Category("validation")
+
+	// Timeout is the category of failures caused by a wait exceeding its timeout
+	Timeout = Category("timeout")
+)
+
+// Exit codes of the kinder command for each failure category
+const (
+	ExitSuccess    = 0
+	ExitFailure    = 1
+	ExitPartial    = 2
+	ExitValidation = 3
+	ExitTimeout    = 4
+)
+
+// categorizedError is an error with a failure category
+type categorizedError struct {
+	cause    error
+	category Category
+}
+
+func (e *categorizedError) Error() string { return e.cause.Error() }
+
+// Cause returns the underlying error, for compatibility with errors.Cause
+func (e *categorizedError) Cause() error { return e.cause }
+
+// Unwrap returns the underlying error, for compatibility with errors.As and errors.Is
+func (e *categorizedError) Unwrap() error { return e.cause }
+
+// WithCategory returns an error with the given failure category, and the same message of err;
+// if err is nil, or the category is None, err is returned unchanged
+func WithCategory(err error, category Category) error {
+	if err == nil || category == None {
+		return err
+	}
+	return &categorizedError{cause: err, category: category}
+}
+
+// CategoryOf returns the failure category of err; when errors with a category are wrapped one into
+// another, the outermost category wins. None is returned if err, or none of the errors it wraps, has a category
+func CategoryOf(err error) Category {
+	var categorized *categorizedError
+	if errors.As(err, &categorized) {
+		return categorized.category
+	}
+	return None
+}
+
+// ExitCode returns the exit code of the kinder command for err:
+// 0 on success, 1 for errors not categorized, 2 for partial failures, 3 for validation errors and 4 for timeouts
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	switch CategoryOf(err) {
+	case Partial:
+		return ExitPartial
+	case Validation:
+		return ExitValidation
+	case Timeout:
+		return ExitTimeout
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failure
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		expectedCategory Category
+		expectedExitCode int
+	}{
+		{
+			name:             "success",
+			expectedExitCode: ExitSuccess,
+		},
+		{
+			name:             "not categorized",
+			err:              errors.New("failed"),
+			expectedExitCode: ExitFailure,
+		},
+		{
+			name:             "partial",
+			err:              WithCategory(errors.New("failed"), Partial),
+			expectedCategory: Partial,
+			expectedExitCode: ExitPartial,
+		},
+		{
+			name:             "validation",
+			err:              WithCategory(errors.New("invalid flag"), Validation),
+			expectedCategory: Validation,
+			expectedExitCode: ExitValidation,
+		},
+		{
+			name:             "timeout wrapped",
+			err:              errors.Wrap(WithCategory(errors.New("timeout: node did not reach target state"), Timeout), "failed to exec action join"),
+			expectedCategory: Timeout,
+			expectedExitCode: ExitTimeout,
+		},
+		{
+			name:             "outermost category wins",
+			err:              WithCategory(errors.Wrap(WithCategory(errors.New("timeout"), Timeout), "failed to join node"), Partial),
+			expectedCategory: Partial,
+			expectedExitCode: ExitPartial,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if category := CategoryOf(test.err); category != test.expectedCategory {
+				t.Errorf("expected category %q, found %q", test.expectedCategory, category)
+			}
+			if code := ExitCode(test.err); code != test.expectedExitCode {
+				t.Errorf("expected exit code %d, found %d", test.expectedExitCode, code)
+			}
+		})
+	}
+}
+
+func TestWithCategory(t *testing.T) {
+	if err := WithCategory(nil, Timeout); err != nil {
+		t.Errorf("expected nil, found %v", err)
+	}
+
+	err := errors.New("failed")
+	if categorized := WithCategory(err, None); categorized != err {
+		t.Errorf("expected the error unchanged, found %v", categorized)
+	}
+
+	categorized := WithCategory(err, Timeout)
+	if categorized.Error() != err.Error() {
+		t.Errorf("expected message %q, found %q", err.Error(), categorized.Error())
+	}
+	if errors.Cause(categorized) != err {
+		t.Errorf("expected cause %v, found %v", err, errors.Cause(categorized))
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"path"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AuditPolicyDir is the directory on control-plane nodes hosting the audit policy; the directory
+	// is mounted in the API server pod
+	AuditPolicyDir = "/etc/kubernetes/audit"
+
+	// AuditPolicyPath is the path on control-plane nodes of the audit policy
+	AuditPolicyPath = AuditPolicyDir + "/policy.yaml"
+
+	// DefaultAuditLogPath is the path on control-plane nodes of the audit log, if not differently specified;
+	// the directory of the audit log is mounted in the API server pod
+	DefaultAuditLogPath = "/var/log/kubernetes/audit/audit.log"
+
+	// AuditPolicyFileArg is the API server flag setting the audit policy
+	AuditPolicyFileArg = "audit-policy-file"
+
+	// AuditLogPathArg is the API server flag setting the audit log path
+	AuditLogPathArg = "audit-log-path"
+)
+
+// DefaultAuditPolicy is the audit policy used if not differently specified; it logs the metadata of all the requests
+const DefaultAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: Metadata
+`
+
+// ValidateAuditPolicy checks that an audit policy is an audit.k8s.io/v1 Policy; the policy rules
+// are validated by the API server
+func ValidateAuditPolicy(policy []byte) error {
+	var typeMeta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(policy, &typeMeta); err != nil {
+		return errors.Wrap(err, "invalid audit policy")
+	}
+	if typeMeta.APIVersion != "audit.k8s.io/v1" || typeMeta.Kind != "Policy" {
+		return errors.Errorf("invalid audit policy: expected apiVersion audit.k8s.io/v1 and kind Policy, got %q and %q", typeMeta.APIVersion, typeMeta.Kind)
+	}
+	return nil
+}
+
+// ValidateAuditLogPath checks that the audit log path is an absolute path to a file on the control-plane nodes,
+// outside of the audit policy dir
+func ValidateAuditLogPath(logPath string) error {
+	if !path.IsAbs(logPath) || path.Clean(logPath) != logPath || path.Dir(logPath) == "/" {
+		return errors.Errorf("invalid audit log path %q. Use an absolute path to a file in a directory, e.g. %s", logPath, DefaultAuditLogPath)
+	}
+	if path.Dir(logPath) == AuditPolicyDir {
+		return errors.Errorf("invalid audit log path %q. The %s directory is mounted read-only for the audit policy", logPath, AuditPolicyDir)
+	}
+	return nil
+}
+
+// AuditVolumes returns the mounts of the audit policy dir and of the audit log dir in the API server pod
+func AuditVolumes(logPath string) []HostPathMount {
+	return []HostPathMount{
+		{
+			Name:      "audit-policy",
+			HostPath:  AuditPolicyDir,
+			MountPath: AuditPolicyDir,
+			ReadOnly:  true,
+			PathType:  "DirectoryOrCreate",
+		},
+		{
+			Name:      "audit-log",
+			HostPath:  path.Dir(logPath),
+			MountPath: path.Dir(logPath),
+			PathType:  "DirectoryOrCreate",
+		},
+	}
+}
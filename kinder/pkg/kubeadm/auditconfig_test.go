@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAuditPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		expectedError bool
+	}{
+		{
+			name:   "default policy",
+			policy: DefaultAuditPolicy,
+		},
+		{
+			name:          "not a policy",
+			policy:        "apiVersion: v1\nkind: ConfigMap\n",
+			expectedError: true,
+		},
+		{
+			name:          "unsupported version",
+			policy:        "apiVersion: audit.k8s.io/v1beta1\nkind: Policy\n",
+			expectedError: true,
+		},
+		{
+			name:          "invalid yaml",
+			policy:        "kind: [Policy",
+			expectedError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateAuditPolicy([]byte(tt.policy)); (err != nil) != tt.expectedError {
+				t.Errorf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuditLogPath(t *testing.T) {
+	tests := []struct {
+		logPath       string
+		expectedError bool
+	}{
+		{logPath: DefaultAuditLogPath},
+		{logPath: "/var/log/audit.log"},
+		{logPath: "audit.log", expectedError: true},
+		{logPath: "/audit.log", expectedError: true},
+		{logPath: "/var/log/../audit.log", expectedError: true},
+		{logPath: AuditPolicyDir + "/audit.log", expectedError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.logPath, func(t *testing.T) {
+			if err := ValidateAuditLogPath(tt.logPath); (err != nil) != tt.expectedError {
+				t.Errorf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestGetAPIServerExtraVolumesPatch(t *testing.T) {
+	volumes := append([]HostPathMount{EncryptionConfigVolume}, AuditVolumes("/var/log/audit/audit.log")...)
+	patch, err := GetAPIServerExtraVolumesPatch("v1beta4", volumes...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"  - name: encryption-config\n    hostPath: /etc/kubernetes/encryption\n",
+		"  - name: audit-policy\n    hostPath: /etc/kubernetes/audit\n    mountPath: /etc/kubernetes/audit\n    readOnly: true\n",
+		"  - name: audit-log\n    hostPath: /var/log/audit\n    mountPath: /var/log/audit\n    readOnly: false\n",
+	} {
+		if !strings.Contains(patch, expected) {
+			t.Errorf("expected %q in patch:\n%s", expected, patch)
+		}
+	}
+
+	if _, err := GetAPIServerExtraVolumesPatch("v1beta4", EncryptionConfigVolume, EncryptionConfigVolume); err == nil {
+		t.Error("expected error for a volume set more than once")
+	}
+	if _, err := GetAPIServerExtraVolumesPatch("v1beta2", EncryptionConfigVolume); err == nil {
+		t.Error("expected error for an unknown kubeadm config version")
+	}
+}
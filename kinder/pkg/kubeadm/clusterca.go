@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ClusterCACertPath and ClusterCAKeyPath are the paths of the cluster CA certificate and key on the
+	// control-plane nodes; when both files exist, kubeadm reuses the CA instead of generating a new one
+	ClusterCACertPath = "/etc/kubernetes/pki/ca.crt"
+	ClusterCAKeyPath  = "/etc/kubernetes/pki/ca.key"
+)
+
+// ValidateClusterCA checks that a PEM encoded certificate and key, RSA or ECDSA, are a valid CA key pair
+// kubeadm can use for signing the cluster certificates
+func ValidateClusterCA(certPEM, keyPEM []byte) error {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errors.Wrap(err, "invalid CA certificate and key")
+	}
+	switch k := pair.PrivateKey.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+	default:
+		return errors.Errorf("unsupported CA key type %T. Use an RSA or ECDSA key", k)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the CA certificate")
+	}
+	if !cert.IsCA || (cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0) {
+		return errors.Errorf("the certificate %q is not a CA certificate that can sign certificates", cert.Subject.CommonName)
+	}
+	if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return errors.Errorf("the CA certificate %q is valid from %s to %s", cert.Subject.CommonName, cert.NotBefore.UTC(), cert.NotAfter.UTC())
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestValidateClusterCA(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	ecKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})
+
+	newCert := func(key crypto.Signer, isCA bool, notAfter time.Time) []byte {
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "corporate-ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              notAfter,
+			IsCA:                  isCA,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		}
+		if !isCA {
+			template.KeyUsage = x509.KeyUsageDigitalSignature
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	valid := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		cert          []byte
+		key           []byte
+		expectedError bool
+	}{
+		{
+			name: "RSA CA",
+			cert: newCert(rsaKey, true, valid),
+			key:  rsaKeyPEM,
+		},
+		{
+			name: "ECDSA CA",
+			cert: newCert(ecKey, true, valid),
+			key:  ecKeyPEM,
+		},
+		{
+			name:          "mismatched key",
+			cert:          newCert(rsaKey, true, valid),
+			key:           ecKeyPEM,
+			expectedError: true,
+		},
+		{
+			name:          "not a CA",
+			cert:          newCert(ecKey, false, valid),
+			key:           ecKeyPEM,
+			expectedError: true,
+		},
+		{
+			name:          "expired CA",
+			cert:          newCert(ecKey, true, time.Now().Add(-time.Minute)),
+			key:           ecKeyPEM,
+			expectedError: true,
+		},
+		{
+			name:          "not PEM",
+			cert:          []byte("ca"),
+			key:           ecKeyPEM,
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			err := ValidateClusterCA(rt.cert, rt.key)
+			if (err != nil) != rt.expectedError {
+				t.Errorf("failed ValidateClusterCA:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+			}
+		})
+	}
+}
@@ -47,6 +47,18 @@ func Config(kubeadmConfigVersion string, data ConfigData) (config string, err er
 		return "", errors.Wrap(err, "failed to parse config template")
 	}
 
+	// the DNS domain is used both for the services and for the API server certificate SANs,
+	// so it can't be left empty
+	if data.DNSDomain == "" {
+		return "", errors.New("the DNS domain can not be empty")
+	}
+
+	// the kubelet fails to start if the cgroup driver does not match the one used by the container runtime,
+	// so only known values are accepted
+	if data.CgroupDriver != "systemd" && data.CgroupDriver != "cgroupfs" {
+		return "", errors.Errorf("invalid cgroup driver %q. Use one of [systemd, cgroupfs]", data.CgroupDriver)
+	}
+
 	// derive any automatic fields if not supplied
 	data.Derive()
 
@@ -71,6 +83,10 @@ func GetKubeadmConfigVersion(kubeadmVersion *K8sVersion.Version) string {
 	return "v1beta3"
 }
 
+// DualStackMinVersion is the minimum kubeadm version supporting dual-stack clusters, that is the first version
+// with the IPv6DualStack feature gate enabled by default
+var DualStackMinVersion = K8sVersion.MustParseSemantic("v1.21.0")
+
 // ConfigData is supplied to the kubeadm config template, with values populated
 // by the cluster package
 type ConfigData struct {
@@ -87,19 +103,56 @@ type ConfigData struct {
 	ControlPlane bool
 	// The main IP address of the node
 	NodeAddress string
+	// The IPv6 address of the node, set for dual-stack clusters only
+	NodeAddressIPv6 string
+	// The Kubernetes node name, if different from the node hostname
+	NodeName string
 	// The Token for TLS bootstrap
 	Token string
 	// The subnet used for pods
 	PodSubnet string
 	// The subnet used for services
 	ServiceSubnet string
+	// The DNS domain used for services
+	DNSDomain string
+	// The cgroup driver used by the kubelet
+	CgroupDriver string
 	// IPv4 values take precedence over IPv6 by default, if true set IPv6 default values
 	IPv6 bool
+	// DualStack sets both the IPv4 and the IPv6 address as kubelet node IPs
+	DualStack bool
 	// The kubeadm feature-gate
 	FeatureGateName  string
 	FeatureGateValue string
 	// The encryption algorithm
 	EncryptionAlgorithm string
+	// The API server TLS min version and cipher suites
+	TLSMinVersion   string
+	TLSCipherSuites []string
+	// The extra args for the control-plane components, indexed by component name
+	ExtraArgs map[string][]ExtraArg
+	// The kubelet eviction settings, indexed by KubeletConfiguration field name
+	KubeletEviction map[string]map[string]string
+	// KubeletServingCertRotation enables the kubelet serving certificates TLS bootstrap
+	KubeletServingCertRotation bool
+	// SkipKubeProxy disables the kube-proxy addon
+	SkipKubeProxy bool
+	// The kube-proxy mode
+	KubeProxyMode string
+	// The CoreDNS image repository and tag
+	CoreDNSImageRepository string
+	CoreDNSImageTag        string
+	// The local etcd image tag
+	EtcdImageTag string
+	// The extra args for the local etcd, e.g. the backend quota
+	EtcdExtraArgs []ExtraArg
+
+	// EncryptionProvider is the provider used for encrypting secrets at rest, if any; the encryption config
+	// dir is mounted in the API server pod
+	EncryptionProvider string
+	// AuditLogPath is the API server audit log path, if audit logging is enabled; the audit policy
+	// dir and the audit log dir are mounted in the API server pod
+	AuditLogPath string
 	// DerivedConfigData is populated by Derive()
 	// These auto-generated fields are available to Config templates,
 	// but not meant to be set by hand
@@ -134,7 +187,7 @@ controlPlaneEndpoint: "{{ .ControlPlaneEndpoint }}"
 # so we need to ensure the cert is valid for localhost so we can talk
 # to the cluster after rewriting the kubeconfig to point to localhost
 apiServer:
-  certSANs: [localhost, "{{.APIServerAddress}}"]
+  certSANs: [localhost, "{{.APIServerAddress}}", "kubernetes.default.svc.{{ .DNSDomain }}"]
 controllerManager:
   extraArgs:
   # configure ipv6 default addresses for IPv6 clusters
@@ -146,14 +199,27 @@ scheduler:
   extraArgs:
   # configure ipv6 default addresses for IPv6 clusters
   {{ if .IPv6 -}}
-  - name: address
-    value: "::"
   - name: bind-address
-    vaue: "::1"
+    value: "::1"
   {{- end }}
 networking:
   podSubnet: "{{ .PodSubnet }}"
   serviceSubnet: "{{ .ServiceSubnet }}"
+  dnsDomain: "{{ .DNSDomain }}"
+{{ if .SkipKubeProxy -}}
+proxy:
+  disabled: true
+{{ end -}}
+{{ if .EtcdImageTag -}}
+etcd:
+  local:
+    imageTag: "{{ .EtcdImageTag }}"
+{{ end -}}
+{{ if .CoreDNSImageTag -}}
+dns:
+  imageRepository: "{{ .CoreDNSImageRepository }}"
+  imageTag: "{{ .CoreDNSImageTag }}"
+{{ end -}}
 {{ if .FeatureGateName -}}
 featureGates:
   {{ .FeatureGateName }}: {{ .FeatureGateValue }}
@@ -170,10 +236,13 @@ localAPIEndpoint:
   advertiseAddress: "{{ .NodeAddress }}"
   bindPort: {{.APIBindPort}}
 nodeRegistration:
+  {{ if .NodeName -}}
+  name: "{{ .NodeName }}"
+  {{ end -}}
   criSocket: "/run/containerd/containerd.sock"
   kubeletExtraArgs:
   - name: node-ip
-    value: "{{ .NodeAddress }}"
+    value: "{{ .NodeAddress }}{{ if .DualStack }},{{ .NodeAddressIPv6 }}{{ end }}"
 ---
 # no-op entry that exists solely so it can be patched
 apiVersion: kubeadm.k8s.io/v1beta4
@@ -185,10 +254,13 @@ controlPlane:
     bindPort: {{.APIBindPort}}
 {{- end }}
 nodeRegistration:
+  {{ if .NodeName -}}
+  name: "{{ .NodeName }}"
+  {{ end -}}
   criSocket: "/run/containerd/containerd.sock"
   kubeletExtraArgs:
   - name: node-ip
-    value: "{{ .NodeAddress }}"
+    value: "{{ .NodeAddress }}{{ if .DualStack }},{{ .NodeAddressIPv6 }}{{ end }}"
 discovery:
   bootstrapToken:
     apiServerEndpoint: "{{ .ControlPlaneEndpoint }}"
@@ -211,9 +283,9 @@ evictionHard:
   nodefs.available: "0%"
   nodefs.inodesFree: "0%"
   imagefs.available: "0%"
-# pin the cgroup driver to systemd.
-# this assumes that the CR on the node image is configured accordingly.
-cgroupDriver: "systemd"
+# set the cgroup driver; this should match the cgroup driver
+# of the container runtime on the node image.
+cgroupDriver: "{{ .CgroupDriver }}"
 ---
 # no-op entry that exists solely so it can be patched
 apiVersion: kubeproxy.config.k8s.io/v1alpha1
@@ -222,6 +294,9 @@ conntrack:
   # Skip setting sysctl value "net.netfilter.nf_conntrack_max"
   # It is a global variable that affects other namespaces
   maxPerCore: 0
+{{ if .KubeProxyMode -}}
+mode: "{{ .KubeProxyMode }}"
+{{- end }}
 `
 
 // configTemplateBetaV3 is the kubeadm config template for API version v1beta3
@@ -235,7 +310,7 @@ controlPlaneEndpoint: "{{ .ControlPlaneEndpoint }}"
 # so we need to ensure the cert is valid for localhost so we can talk
 # to the cluster after rewriting the kubeconfig to point to localhost
 apiServer:
-  certSANs: [localhost, "{{.APIServerAddress}}"]
+  certSANs: [localhost, "{{.APIServerAddress}}", "kubernetes.default.svc.{{ .DNSDomain }}"]
 controllerManager:
   extraArgs:
     # configure ipv6 default addresses for IPv6 clusters
@@ -246,12 +321,22 @@ scheduler:
   extraArgs:
     # configure ipv6 default addresses for IPv6 clusters
     {{ if .IPv6 -}}
-    address: "::"
     bind-address: "::1"
     {{- end }}
 networking:
   podSubnet: "{{ .PodSubnet }}"
   serviceSubnet: "{{ .ServiceSubnet }}"
+  dnsDomain: "{{ .DNSDomain }}"
+{{ if .EtcdImageTag -}}
+etcd:
+  local:
+    imageTag: "{{ .EtcdImageTag }}"
+{{ end -}}
+{{ if .CoreDNSImageTag -}}
+dns:
+  imageRepository: "{{ .CoreDNSImageRepository }}"
+  imageTag: "{{ .CoreDNSImageTag }}"
+{{ end -}}
 {{ if .FeatureGateName -}}
 featureGates:
   {{ .FeatureGateName }}: {{ .FeatureGateValue }}
@@ -268,9 +353,12 @@ localAPIEndpoint:
   advertiseAddress: "{{ .NodeAddress }}"
   bindPort: {{.APIBindPort}}
 nodeRegistration:
+  {{ if .NodeName -}}
+  name: "{{ .NodeName }}"
+  {{ end -}}
   criSocket: "/run/containerd/containerd.sock"
   kubeletExtraArgs:
-    node-ip: "{{ .NodeAddress }}"
+    node-ip: "{{ .NodeAddress }}{{ if .DualStack }},{{ .NodeAddressIPv6 }}{{ end }}"
 ---
 # no-op entry that exists solely so it can be patched
 apiVersion: kubeadm.k8s.io/v1beta3
@@ -282,9 +370,12 @@ controlPlane:
     bindPort: {{.APIBindPort}}
 {{- end }}
 nodeRegistration:
+  {{ if .NodeName -}}
+  name: "{{ .NodeName }}"
+  {{ end -}}
   criSocket: "/run/containerd/containerd.sock"
   kubeletExtraArgs:
-    node-ip: "{{ .NodeAddress }}"
+    node-ip: "{{ .NodeAddress }}{{ if .DualStack }},{{ .NodeAddressIPv6 }}{{ end }}"
 discovery:
   bootstrapToken:
     apiServerEndpoint: "{{ .ControlPlaneEndpoint }}"
@@ -307,9 +398,9 @@ evictionHard:
   nodefs.available: "0%"
   nodefs.inodesFree: "0%"
   imagefs.available: "0%"
-# pin the cgroup driver to systemd.
-# this assumes that the CR on the node image is configured accordingly.
-cgroupDriver: "systemd"
+# set the cgroup driver; this should match the cgroup driver
+# of the container runtime on the node image.
+cgroupDriver: "{{ .CgroupDriver }}"
 ---
 # no-op entry that exists solely so it can be patched
 apiVersion: kubeproxy.config.k8s.io/v1alpha1
@@ -318,4 +409,7 @@ conntrack:
   # Skip setting sysctl value "net.netfilter.nf_conntrack_max"
   # It is a global variable that affects other namespaces
   maxPerCore: 0
+{{ if .KubeProxyMode -}}
+mode: "{{ .KubeProxyMode }}"
+{{- end }}
 `
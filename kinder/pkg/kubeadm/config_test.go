@@ -0,0 +1,338 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigDNSDomain(t *testing.T) {
+	var tests = []struct {
+		name          string
+		dnsDomain     string
+		expected      []string
+		notExpected   []string
+		expectedError bool
+	}{
+		{
+			name:      "default DNS domain",
+			dnsDomain: "cluster.local",
+			expected: []string{
+				`dnsDomain: "cluster.local"`,
+				`"kubernetes.default.svc.cluster.local"`,
+			},
+		},
+		{
+			name:      "custom DNS domain",
+			dnsDomain: "example.internal",
+			expected: []string{
+				`dnsDomain: "example.internal"`,
+				`"kubernetes.default.svc.example.internal"`,
+			},
+			notExpected: []string{
+				"cluster.local",
+			},
+		},
+		{
+			name:          "empty DNS domain",
+			dnsDomain:     "",
+			expectedError: true,
+		},
+	}
+
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		for _, rt := range tests {
+			t.Run(configVersion+" "+rt.name, func(t *testing.T) {
+				config, err := Config(configVersion, ConfigData{
+					KubernetesVersion: "v1.30.0",
+					DNSDomain:         rt.dnsDomain,
+					CgroupDriver:      "systemd",
+				})
+				if (err != nil) != rt.expectedError {
+					t.Fatalf("failed Config:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+				}
+				for _, e := range rt.expected {
+					if !strings.Contains(config, e) {
+						t.Errorf("failed Config:\n\texpected config to contain: %s\n\tactual config:\n%s", e, config)
+					}
+				}
+				for _, e := range rt.notExpected {
+					if strings.Contains(config, e) {
+						t.Errorf("failed Config:\n\texpected config to not contain: %s\n\tactual config:\n%s", e, config)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestConfigCgroupDriver(t *testing.T) {
+	var tests = []struct {
+		name          string
+		cgroupDriver  string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:         "systemd",
+			cgroupDriver: "systemd",
+			expected:     `cgroupDriver: "systemd"`,
+		},
+		{
+			name:         "cgroupfs",
+			cgroupDriver: "cgroupfs",
+			expected:     `cgroupDriver: "cgroupfs"`,
+		},
+		{
+			name:          "empty cgroup driver",
+			cgroupDriver:  "",
+			expectedError: true,
+		},
+		{
+			name:          "unknown cgroup driver",
+			cgroupDriver:  "foo",
+			expectedError: true,
+		},
+	}
+
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		for _, rt := range tests {
+			t.Run(configVersion+" "+rt.name, func(t *testing.T) {
+				config, err := Config(configVersion, ConfigData{
+					KubernetesVersion: "v1.30.0",
+					DNSDomain:         "cluster.local",
+					CgroupDriver:      rt.cgroupDriver,
+				})
+				if (err != nil) != rt.expectedError {
+					t.Fatalf("failed Config:\n\texpected error: %t\n\tactual error: %v", rt.expectedError, err)
+				}
+				if err == nil && !strings.Contains(config, rt.expected) {
+					t.Errorf("failed Config:\n\texpected config to contain: %s\n\tactual config:\n%s", rt.expected, config)
+				}
+			})
+		}
+	}
+}
+
+func TestConfigKubeProxy(t *testing.T) {
+	var tests = []struct {
+		name          string
+		configVersion string
+		skipKubeProxy bool
+		kubeProxyMode string
+		expected      string
+		notExpected   string
+	}{
+		{
+			name:          "v1beta4 skip kube-proxy",
+			configVersion: "v1beta4",
+			skipKubeProxy: true,
+			expected:      "proxy:\n  disabled: true\n",
+		},
+		{
+			name:          "v1beta3 skip kube-proxy",
+			configVersion: "v1beta3",
+			skipKubeProxy: true,
+			notExpected:   "disabled: true",
+		},
+		{
+			name:          "v1beta4 default",
+			configVersion: "v1beta4",
+			notExpected:   "disabled: true",
+		},
+		{
+			name:          "v1beta3 kube-proxy mode",
+			configVersion: "v1beta3",
+			kubeProxyMode: "ipvs",
+			expected:      `mode: "ipvs"`,
+		},
+		{
+			name:          "v1beta4 kube-proxy mode",
+			configVersion: "v1beta4",
+			kubeProxyMode: "nftables",
+			expected:      `mode: "nftables"`,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			config, err := Config(rt.configVersion, ConfigData{
+				KubernetesVersion: "v1.30.0",
+				DNSDomain:         "cluster.local",
+				CgroupDriver:      "systemd",
+				SkipKubeProxy:     rt.skipKubeProxy,
+				KubeProxyMode:     rt.kubeProxyMode,
+			})
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			if rt.expected != "" && !strings.Contains(config, rt.expected) {
+				t.Errorf("failed Config:\n\texpected config to contain: %s\n\tactual config:\n%s", rt.expected, config)
+			}
+			if rt.notExpected != "" && strings.Contains(config, rt.notExpected) {
+				t.Errorf("failed Config:\n\texpected config to not contain: %s\n\tactual config:\n%s", rt.notExpected, config)
+			}
+		})
+	}
+}
+
+func TestConfigCoreDNSImage(t *testing.T) {
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		t.Run(configVersion, func(t *testing.T) {
+			config, err := Config(configVersion, ConfigData{
+				KubernetesVersion:      "v1.30.0",
+				DNSDomain:              "cluster.local",
+				CgroupDriver:           "systemd",
+				CoreDNSImageRepository: "localhost:5000",
+				CoreDNSImageTag:        "1.11.3",
+			})
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			expected := "dns:\n  imageRepository: \"localhost:5000\"\n  imageTag: \"1.11.3\"\n"
+			if !strings.Contains(config, expected) {
+				t.Errorf("failed Config:\n\texpected config to contain: %s\n\tactual config:\n%s", expected, config)
+			}
+		})
+	}
+}
+
+func TestConfigEtcdImageTag(t *testing.T) {
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		t.Run(configVersion, func(t *testing.T) {
+			config, err := Config(configVersion, ConfigData{
+				KubernetesVersion: "v1.30.0",
+				DNSDomain:         "cluster.local",
+				CgroupDriver:      "systemd",
+				EtcdImageTag:      "3.5.15-0",
+			})
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			expected := "etcd:\n  local:\n    imageTag: \"3.5.15-0\"\n"
+			if !strings.Contains(config, expected) {
+				t.Errorf("failed Config:\n\texpected config to contain: %s\n\tactual config:\n%s", expected, config)
+			}
+		})
+	}
+}
+
+func TestConfigNodeName(t *testing.T) {
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		t.Run(configVersion, func(t *testing.T) {
+			config, err := Config(configVersion, ConfigData{
+				KubernetesVersion: "v1.30.0",
+				DNSDomain:         "cluster.local",
+				CgroupDriver:      "systemd",
+				NodeName:          "gpu-node-1",
+			})
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			expected := "nodeRegistration:\n  name: \"gpu-node-1\"\n  criSocket: \"/run/containerd/containerd.sock\"\n"
+			if n := strings.Count(config, expected); n != 2 {
+				t.Errorf("failed Config:\n\texpected node name in the init and join config, got %d occurrences\n\tactual config:\n%s", n, config)
+			}
+
+			config, err = Config(configVersion, ConfigData{
+				KubernetesVersion: "v1.30.0",
+				DNSDomain:         "cluster.local",
+				CgroupDriver:      "systemd",
+			})
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			expected = "nodeRegistration:\n  criSocket: \"/run/containerd/containerd.sock\"\n"
+			if n := strings.Count(config, expected); n != 2 {
+				t.Errorf("failed Config:\n\texpected default node registration in the init and join config, got %d occurrences\n\tactual config:\n%s", n, config)
+			}
+		})
+	}
+}
+
+func TestConfigIPFamily(t *testing.T) {
+	var tests = []struct {
+		name        string
+		data        ConfigData
+		expected    []string
+		notExpected []string
+	}{
+		{
+			name: "ipv4",
+			data: ConfigData{NodeAddress: "172.17.0.2", PodSubnet: "192.168.0.0/16"},
+			expected: []string{
+				`podSubnet: "192.168.0.0/16"`,
+				`"172.17.0.2"`,
+			},
+			notExpected: []string{
+				"bind-address",
+				"172.17.0.2,",
+			},
+		},
+		{
+			name: "ipv6",
+			data: ConfigData{NodeAddress: "fc00:f853:ccd:e793::2", PodSubnet: "fd00:10:244::/56", ServiceSubnet: "fd00:10:96::/112", IPv6: true},
+			expected: []string{
+				`podSubnet: "fd00:10:244::/56"`,
+				`serviceSubnet: "fd00:10:96::/112"`,
+				`"::1"`,
+				`address: "::"`,
+			},
+			notExpected: []string{
+				"vaue",
+			},
+		},
+		{
+			name: "dual-stack",
+			data: ConfigData{NodeAddress: "172.17.0.2", NodeAddressIPv6: "fc00:f853:ccd:e793::2", PodSubnet: "192.168.0.0/16,fd00:10:244::/56", DualStack: true},
+			expected: []string{
+				`podSubnet: "192.168.0.0/16,fd00:10:244::/56"`,
+				`advertiseAddress: "172.17.0.2"`,
+				`"172.17.0.2,fc00:f853:ccd:e793::2"`,
+			},
+			notExpected: []string{
+				"bind-address",
+			},
+		},
+	}
+
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		for _, rt := range tests {
+			t.Run(configVersion+" "+rt.name, func(t *testing.T) {
+				data := rt.data
+				data.KubernetesVersion = "v1.30.0"
+				data.DNSDomain = "cluster.local"
+				data.CgroupDriver = "systemd"
+				config, err := Config(configVersion, data)
+				if err != nil {
+					t.Fatalf("failed Config: %v", err)
+				}
+				for _, e := range rt.expected {
+					if !strings.Contains(config, e) {
+						t.Errorf("failed Config:\n\texpected config to contain: %s\n\tactual config:\n%s", e, config)
+					}
+				}
+				for _, e := range rt.notExpected {
+					if strings.Contains(config, e) {
+						t.Errorf("failed Config:\n\texpected config to not contain: %s\n\tactual config:\n%s", e, config)
+					}
+				}
+			})
+		}
+	}
+}
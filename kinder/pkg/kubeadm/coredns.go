@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// coreDNSImageName is the CoreDNS image name; kubeadm only allows to customize the image repository and tag
+const coreDNSImageName = "coredns"
+
+// coreDNSTagRegexp matches a valid image tag
+var coreDNSTagRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+
+// ParseCoreDNSImage parses a CoreDNS image in the repository/coredns:tag form, and returns the image repository
+// and tag to be set in the dns stanza of the kubeadm ClusterConfiguration, e.g. registry.k8s.io/coredns/coredns:v1.11.1
+// returns the registry.k8s.io/coredns repository and the v1.11.1 tag.
+func ParseCoreDNSImage(image string) (repository, tag string, err error) {
+	invalid := errors.Errorf("invalid CoreDNS image %q. Use the repository/%s:tag form, e.g. registry.k8s.io/coredns/coredns:v1.11.1", image, coreDNSImageName)
+
+	if strings.Contains(image, "@") {
+		return "", "", invalid
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "", "", invalid
+	}
+	name, tag := image[:i], image[i+1:]
+	if !coreDNSTagRegexp.MatchString(tag) {
+		return "", "", invalid
+	}
+	j := strings.LastIndex(name, "/")
+	if j <= 0 || name[j+1:] != coreDNSImageName {
+		return "", "", invalid
+	}
+	return name[:j], tag, nil
+}
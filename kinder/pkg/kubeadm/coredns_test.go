@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+)
+
+func TestParseCoreDNSImage(t *testing.T) {
+	tests := []struct {
+		name               string
+		image              string
+		expectedRepository string
+		expectedTag        string
+		expectedError      bool
+	}{
+		{
+			name:               "default registry",
+			image:              "registry.k8s.io/coredns/coredns:v1.11.1",
+			expectedRepository: "registry.k8s.io/coredns",
+			expectedTag:        "v1.11.1",
+		},
+		{
+			name:               "registry with port",
+			image:              "localhost:5000/coredns:1.11.1",
+			expectedRepository: "localhost:5000",
+			expectedTag:        "1.11.1",
+		},
+		{
+			name:          "missing tag",
+			image:         "registry.k8s.io/coredns/coredns",
+			expectedError: true,
+		},
+		{
+			name:          "registry with port and missing tag",
+			image:         "localhost:5000/coredns",
+			expectedError: true,
+		},
+		{
+			name:          "missing repository",
+			image:         "coredns:v1.11.1",
+			expectedError: true,
+		},
+		{
+			name:          "image name other than coredns",
+			image:         "registry.k8s.io/dns:v1.11.1",
+			expectedError: true,
+		},
+		{
+			name:          "digest",
+			image:         "registry.k8s.io/coredns/coredns@sha256:1eeb4c7316bacb1d4c8ead65571cd92dd21e27359f0d4917f1a5822a73b75db1",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository, tag, err := ParseCoreDNSImage(test.image)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if repository != test.expectedRepository || tag != test.expectedTag {
+				t.Errorf("expected %q and %q, got %q and %q", test.expectedRepository, test.expectedTag, repository, tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// criSocketScheme defines the URL scheme accepted by kubeadm for the CRI socket
+const criSocketScheme = "unix://"
+
+// ValidateCRISocket validates a CRI socket, that should be an absolute path, optionally with the unix:// scheme,
+// e.g. unix:///run/containerd/containerd.sock
+func ValidateCRISocket(criSocket string) error {
+	socketPath := strings.TrimPrefix(criSocket, criSocketScheme)
+	if strings.Contains(socketPath, "://") {
+		return errors.Errorf("invalid CRI socket %q. Only the %s scheme is supported", criSocket, criSocketScheme)
+	}
+	if !path.IsAbs(socketPath) || path.Clean(socketPath) != socketPath || strings.ContainsAny(socketPath, " \t\n") {
+		return errors.Errorf("invalid CRI socket %q. Use an absolute path, optionally with the %s scheme, e.g. %s/run/containerd/containerd.sock", criSocket, criSocketScheme, criSocketScheme)
+	}
+	return nil
+}
+
+// GetCRISocketPatches returns the kubeadm config patches that will instruct kubeadm
+// to use a specific CRI socket for init and join, instead of the default CRI socket for the node runtime
+func GetCRISocketPatches(kubeadmConfigVersion, criSocket string) ([]string, error) {
+	log.Debugf("Preparing criSocket patches for kubeadm config %s", kubeadmConfigVersion)
+
+	if err := ValidateCRISocket(criSocket); err != nil {
+		return nil, err
+	}
+
+	var basePatch string
+	switch kubeadmConfigVersion {
+	case "v1beta3":
+		basePatch = criSocketPatchv1beta3
+	case "v1beta4":
+		basePatch = criSocketPatchv1beta4
+	default:
+		return nil, errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	}
+
+	return []string{
+		fmt.Sprintf(basePatch, "InitConfiguration", criSocket),
+		fmt.Sprintf(basePatch, "JoinConfiguration", criSocket),
+	}, nil
+}
+
+const criSocketPatchv1beta3 = `apiVersion: kubeadm.k8s.io/v1beta3
+kind: %s
+nodeRegistration:
+  criSocket: %q`
+
+const criSocketPatchv1beta4 = `apiVersion: kubeadm.k8s.io/v1beta4
+kind: %s
+nodeRegistration:
+  criSocket: %q`
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCRISocket(t *testing.T) {
+	tests := []struct {
+		criSocket   string
+		expectError bool
+	}{
+		{criSocket: "/run/containerd/containerd.sock"},
+		{criSocket: "unix:///run/containerd/containerd.sock"},
+		{criSocket: "unix:///var/run/cri-dockerd.sock"},
+		{criSocket: "", expectError: true},
+		{criSocket: "run/containerd/containerd.sock", expectError: true},
+		{criSocket: "unix://run/containerd/containerd.sock", expectError: true},
+		{criSocket: "npipe:////./pipe/containerd-containerd", expectError: true},
+		{criSocket: "/run/containerd/../containerd.sock", expectError: true},
+		{criSocket: "/run/containerd/containerd sock", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.criSocket, func(t *testing.T) {
+			err := ValidateCRISocket(test.criSocket)
+			if (err != nil) != test.expectError {
+				t.Errorf("expected error %t, got %v", test.expectError, err)
+			}
+		})
+	}
+}
+
+func TestGetCRISocketPatches(t *testing.T) {
+	patches, err := GetCRISocketPatches("v1beta4", "unix:///run/containerd/containerd.sock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("expected 2 patches, got %d", len(patches))
+	}
+	for i, kind := range []string{"InitConfiguration", "JoinConfiguration"} {
+		if !strings.Contains(patches[i], "kind: "+kind) || !strings.Contains(patches[i], `criSocket: "unix:///run/containerd/containerd.sock"`) {
+			t.Errorf("unexpected patch for %s:\n%s", kind, patches[i])
+		}
+	}
+
+	if _, err := GetCRISocketPatches("v1beta2", "/run/containerd/containerd.sock"); err == nil {
+		t.Error("expected error for unknown kubeadm config version")
+	}
+}
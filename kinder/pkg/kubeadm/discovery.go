@@ -17,6 +17,10 @@ limitations under the License.
 package kubeadm
 
 import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -118,3 +122,54 @@ const tlsBootstrapPatchv1beta4 = `apiVersion: kubeadm.k8s.io/v1beta4
 kind: JoinConfiguration
 discovery:
   tlsBootstrapToken: %s`
+
+// CACertHash returns the hash of the public key of a PEM encoded CA certificate, in the sha256:<hex> form
+// used by kubeadm for --discovery-token-ca-cert-hash; the hash is computed on the DER encoded
+// Subject Public Key Info, like kubeadm does.
+func CACertHash(caCertPEM []byte) (string, error) {
+	block, _ := pem.Decode(caCertPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", errors.New("failed to decode the CA certificate: no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse the CA certificate")
+	}
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256:" + hex.EncodeToString(hash[:]), nil
+}
+
+// GetTokenDiscoveryCACertHashPatch returns the kubeadm config patch that will instruct kubeadm
+// to validate the cluster CA during token discovery, using the given CA cert hash.
+func GetTokenDiscoveryCACertHashPatch(kubeadmConfigVersion, caCertHash string) (string, error) {
+	// select the patches for the kubeadm config version
+	log.Debugf("Preparing tokenDiscoveryCACertHashPatch for kubeadm config %s", kubeadmConfigVersion)
+
+	var patch string
+	switch kubeadmConfigVersion {
+	case "v1beta3":
+		patch = tokenDiscoveryCACertHashPatchv1beta3
+	case "v1beta4":
+		patch = tokenDiscoveryCACertHashPatchv1beta4
+	default:
+		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	}
+
+	return fmt.Sprintf(patch, caCertHash), nil
+}
+
+const tokenDiscoveryCACertHashPatchv1beta3 = `apiVersion: kubeadm.k8s.io/v1beta3
+kind: JoinConfiguration
+discovery:
+  bootstrapToken:
+    caCertHashes:
+    - %s
+    unsafeSkipCAVerification: false`
+
+const tokenDiscoveryCACertHashPatchv1beta4 = `apiVersion: kubeadm.k8s.io/v1beta4
+kind: JoinConfiguration
+discovery:
+  bootstrapToken:
+    caCertHashes:
+    - %s
+    unsafeSkipCAVerification: false`
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"strings"
+	"testing"
+)
+
+// testCACert is a self-signed CA; its hash, as computed with the command documented by kubeadm, is
+// openssl x509 -pubkey -in ca.crt | openssl rsa -pubin -outform der | openssl dgst -sha256 -hex
+const testCACert = `-----BEGIN CERTIFICATE-----
+MIIDDTCCAfWgAwIBAgIUCvMcnHSZFmZOt13/9U1CHGO3tSMwDQYJKoZIhvcNAQEL
+BQAwFTETMBEGA1UEAwwKa3ViZXJuZXRlczAgFw0yNjEwMTgwMjAyMjFaGA8yMTI2
+MDkyNDAyMDIyMVowFTETMBEGA1UEAwwKa3ViZXJuZXRlczCCASIwDQYJKoZIhvcN
+AQEBBQADggEPADCCAQoCggEBANvruoAQncLy85Xlpg/2TlV3GO9ZGQKUS3ZNMwTd
+g9uQ2si0X8u4dOtLtGS7Tn2FeOqlN9ZzxwMgM9Vi4KMnhcZn3xfh+c7nFm2SG32i
+NRlQT81EUp3sw441FGosuhImqzB5fJFwkJsjufEr3OMGZbdROVSKqFGKJyqTa20N
+2jEa4ok+nVHwCe+1dWhgFLmJr+8x3Cw/BYM9jyPdQFNF+eY4DdqzA0+S7brpt5us
+UK73JTOE1URJOCay22ctGuY3qX+DsEde3/KW0TaoPFQEPcF0ommzeC4KmRSv5BrI
+TSYtr7hwieT0IbtIYEee8dXwXc0ATki+CVV2dhu+UndYkosCAwEAAaNTMFEwHQYD
+VR0OBBYEFHLUCmPCqaeWjrqj7oRJTlusBWNOMB8GA1UdIwQYMBaAFHLUCmPCqaeW
+jrqj7oRJTlusBWNOMA8GA1UdEwEB/wQFMAMBAf8wDQYJKoZIhvcNAQELBQADggEB
+ALR6DXdgBEmvK+GhkHQ84mueRsJRCH/NVhAAZOFvWDht+hTB5hWIh2MsjukwZ48Y
+voRO09nBxj/ZTxYMZD2japFF/jbOOj4PzqvKQqtl5dgxrbq3tMP6mqq5X9+/sI4V
+IQmeAnlVODM9GX9cgH3YdrmWVgNkXaLPCgobiSXNQEbUpYGJjWJEM8LwG/1LpivH
+ziY0om1Ofo4VrXeVSPmRKaBdtg4ERjc04gFOKZKF6N5+G7bjWDNBtr/NM/mh6+LC
+x4jYUfuowuvUqXTDJ4TxAethLylQbUby/SdqQoMPsr37CJO3XOCwsABT36P1T0PO
+dUcxwc9C7zfxXClJrBtyKyk=
+-----END CERTIFICATE-----
+`
+
+const testCACertHash = "sha256:3731304b59cf028dd8f50ae739abd233d368114eb6e14bc055daa1eaa9579886"
+
+func TestCACertHash(t *testing.T) {
+	tests := []struct {
+		name          string
+		caCert        string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "valid CA",
+			caCert:   testCACert,
+			expected: testCACertHash,
+		},
+		{
+			name:          "not a PEM",
+			caCert:        "foo",
+			expectedError: true,
+		},
+		{
+			name:          "not a certificate",
+			caCert:        "-----BEGIN CERTIFICATE-----\nZm9v\n-----END CERTIFICATE-----\n",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := CACertHash([]byte(test.caCert))
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if hash != test.expected {
+				t.Errorf("expected hash %q, got %q", test.expected, hash)
+			}
+		})
+	}
+}
+
+func TestTokenDiscoveryCACertHashPatch(t *testing.T) {
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		t.Run(configVersion, func(t *testing.T) {
+			config, err := Config(configVersion, ConfigData{
+				KubernetesVersion: "v1.30.0",
+				DNSDomain:         "cluster.local",
+				CgroupDriver:      "systemd",
+			})
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			patch, err := GetTokenDiscoveryCACertHashPatch(configVersion, testCACertHash)
+			if err != nil {
+				t.Fatalf("failed GetTokenDiscoveryCACertHashPatch: %v", err)
+			}
+			patched, err := Build(config, []string{patch}, nil)
+			if err != nil {
+				t.Fatalf("failed Build: %v", err)
+			}
+			for _, expected := range []string{"- " + testCACertHash, "unsafeSkipCAVerification: false"} {
+				if !strings.Contains(patched, expected) {
+					t.Errorf("expected config to contain %q, actual config:\n%s", expected, patched)
+				}
+			}
+		})
+	}
+}
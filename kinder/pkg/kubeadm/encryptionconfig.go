@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Encryption providers supported for encrypting secrets at rest
+const (
+	EncryptionProviderAESCBC    = "aescbc"
+	EncryptionProviderSecretbox = "secretbox"
+)
+
+// EncryptionProviders lists the encryption providers supported for encrypting secrets at rest
+var EncryptionProviders = []string{EncryptionProviderAESCBC, EncryptionProviderSecretbox}
+
+const (
+	// EncryptionConfigDir is the directory on control-plane nodes hosting the encryption config; the directory
+	// is mounted in the API server pod
+	EncryptionConfigDir = "/etc/kubernetes/encryption"
+
+	// EncryptionConfigPath is the path on control-plane nodes of the encryption config
+	EncryptionConfigPath = EncryptionConfigDir + "/config.yaml"
+
+	// EncryptionProviderConfigArg is the API server flag setting the encryption config
+	EncryptionProviderConfigArg = "encryption-provider-config"
+)
+
+// ValidateEncryptionProvider checks that an encryption provider is supported
+func ValidateEncryptionProvider(provider string) error {
+	for _, p := range EncryptionProviders {
+		if p == provider {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid encryption provider %q. Use one of [%s]", provider, strings.Join(EncryptionProviders, ", "))
+}
+
+// GetEncryptionConfig returns an EncryptionConfiguration encrypting secrets with the given provider and a new random
+// 32 bytes key; the identity provider is used as a fallback, so secrets stored before encryption can still be read
+func GetEncryptionConfig(provider string) (string, error) {
+	if err := ValidateEncryptionProvider(provider); err != nil {
+		return "", err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.Wrap(err, "failed to generate the encryption key")
+	}
+	return fmt.Sprintf(encryptionConfig, provider, base64.StdEncoding.EncodeToString(key)), nil
+}
+
+// EncryptedSecretPrefix returns the prefix of the values stored in etcd for resources encrypted with the given provider
+func EncryptedSecretPrefix(provider string) string {
+	return fmt.Sprintf("k8s:enc:%s:v1:", provider)
+}
+
+// EncryptionConfigVolume defines the mount of the encryption config dir in the API server pod
+var EncryptionConfigVolume = HostPathMount{
+	Name:      "encryption-config",
+	HostPath:  EncryptionConfigDir,
+	MountPath: EncryptionConfigDir,
+	ReadOnly:  true,
+	PathType:  "DirectoryOrCreate",
+}
+
+// GetEncryptionConfigVolumePatch returns the kubeadm config patch that will instruct kubeadm to mount
+// the encryption config dir in the API server pod
+func GetEncryptionConfigVolumePatch(kubeadmConfigVersion string) (string, error) {
+	return GetAPIServerExtraVolumesPatch(kubeadmConfigVersion, EncryptionConfigVolume)
+}
+
+const encryptionConfig = `apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+- resources:
+  - secrets
+  providers:
+  - %s:
+      keys:
+      - name: key1
+        secret: %s
+  - identity: {}
+`
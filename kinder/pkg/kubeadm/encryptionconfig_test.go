@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/base64"
+	"regexp"
+	"testing"
+)
+
+func TestGetEncryptionConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		provider      string
+		expectedError bool
+	}{
+		{
+			name:     "aescbc",
+			provider: EncryptionProviderAESCBC,
+		},
+		{
+			name:     "secretbox",
+			provider: EncryptionProviderSecretbox,
+		},
+		{
+			name:          "unsupported provider",
+			provider:      "kms",
+			expectedError: true,
+		},
+	}
+
+	secretRE := regexp.MustCompile(`secret: (\S+)`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := GetEncryptionConfig(tt.provider)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("expected error: %v, got: %v", tt.expectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if !regexp.MustCompile(`(?m)^  - ` + tt.provider + `:$`).MatchString(config) {
+				t.Errorf("expected provider %s in config:\n%s", tt.provider, config)
+			}
+			m := secretRE.FindStringSubmatch(config)
+			if m == nil {
+				t.Fatalf("expected a key in config:\n%s", config)
+			}
+			key, err := base64.StdEncoding.DecodeString(m[1])
+			if err != nil || len(key) != 32 {
+				t.Errorf("expected a base64 encoded 32 bytes key, got %q", m[1])
+			}
+		})
+	}
+}
+
+func TestGetEncryptionConfigVolumePatch(t *testing.T) {
+	for _, version := range []string{"v1beta3", "v1beta4"} {
+		if _, err := GetEncryptionConfigVolumePatch(version); err != nil {
+			t.Errorf("unexpected error for %s: %v", version, err)
+		}
+	}
+	if _, err := GetEncryptionConfigVolumePatch("v1beta2"); err == nil {
+		t.Error("expected error for an unknown kubeadm config version")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	K8sResource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Kubelet eviction fields, as named in the KubeletConfiguration
+const (
+	EvictionHardField            = "evictionHard"
+	EvictionSoftField            = "evictionSoft"
+	EvictionSoftGracePeriodField = "evictionSoftGracePeriod"
+)
+
+// evictionSignals defines the eviction signals supported by the kubelet
+var evictionSignals = map[string]bool{
+	"memory.available":            true,
+	"nodefs.available":            true,
+	"nodefs.inodesFree":           true,
+	"imagefs.available":           true,
+	"imagefs.inodesFree":          true,
+	"containerfs.available":       true,
+	"containerfs.inodesFree":      true,
+	"pid.available":               true,
+	"allocatableMemory.available": true,
+}
+
+// RelaxedEvictionHard returns CI friendly hard eviction thresholds, preventing small nodes from flapping
+// into NotReady under disk pressure; disk based eviction is disabled, while memory based eviction is
+// kept with a low threshold for preserving the node stability
+func RelaxedEvictionHard() map[string]string {
+	return map[string]string{
+		"memory.available":   "50Mi",
+		"nodefs.available":   "0%",
+		"nodefs.inodesFree":  "0%",
+		"imagefs.available":  "0%",
+		"imagefs.inodesFree": "0%",
+	}
+}
+
+// ParseEvictionThresholds parses and validates eviction thresholds in the signal<quantity form,
+// where quantity is a resource quantity, e.g. 100Mi, or a percentage, e.g. 10%
+func ParseEvictionThresholds(values []string) (map[string]string, error) {
+	thresholds := map[string]string{}
+	for _, v := range values {
+		parts := strings.SplitN(v, "<", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid eviction threshold %q. Use the signal<quantity form, e.g. memory.available<100Mi", v)
+		}
+		signal, quantity := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !evictionSignals[signal] {
+			return nil, errors.Errorf("invalid eviction threshold %q. Unknown signal %q", v, signal)
+		}
+		if _, ok := thresholds[signal]; ok {
+			return nil, errors.Errorf("the eviction threshold for %s is set more than once", signal)
+		}
+		if err := validateEvictionQuantity(quantity); err != nil {
+			return nil, errors.Wrapf(err, "invalid eviction threshold %q", v)
+		}
+		thresholds[signal] = quantity
+	}
+	return thresholds, nil
+}
+
+// validateEvictionQuantity checks an eviction threshold quantity, that is a non negative resource
+// quantity or a percentage between 0% and 100%
+func validateEvictionQuantity(quantity string) error {
+	if strings.HasSuffix(quantity, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(quantity, "%"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return errors.Errorf("invalid percentage %q", quantity)
+		}
+		return nil
+	}
+	q, err := K8sResource.ParseQuantity(quantity)
+	if err != nil {
+		return errors.Errorf("invalid quantity %q", quantity)
+	}
+	if q.Sign() < 0 {
+		return errors.Errorf("negative quantity %q", quantity)
+	}
+	return nil
+}
+
+// GetKubeletEvictionPatch returns the KubeletConfiguration patch that will instruct the kubelet to use the given
+// eviction settings, indexed by KubeletConfiguration field name; thresholds are merged with the thresholds
+// in the kubeadm config template, so unset signals keep their value.
+func GetKubeletEvictionPatch(eviction map[string]map[string]string) (string, error) {
+	log.Debug("Preparing kubelet eviction patch")
+
+	for field := range eviction {
+		switch field {
+		case EvictionHardField, EvictionSoftField, EvictionSoftGracePeriodField:
+		default:
+			return "", errors.Errorf("unknown kubelet eviction field: %s", field)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(kubeletEvictionPatch)
+	for _, field := range []string{EvictionHardField, EvictionSoftField, EvictionSoftGracePeriodField} {
+		values := eviction[field]
+		if len(values) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", field)
+		signals := make([]string, 0, len(values))
+		for s := range values {
+			signals = append(signals, s)
+		}
+		sort.Strings(signals)
+		for _, s := range signals {
+			fmt.Fprintf(&b, "  %s: %q\n", s, values[s])
+		}
+	}
+
+	return b.String(), nil
+}
+
+const kubeletEvictionPatch = `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+`
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEvictionThresholds(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        []string
+		expected      map[string]string
+		expectedError bool
+	}{
+		{
+			name:     "no thresholds",
+			expected: map[string]string{},
+		},
+		{
+			name:     "quantities and percentages",
+			values:   []string{"memory.available<100Mi", "nodefs.available<5%", "pid.available<1000"},
+			expected: map[string]string{"memory.available": "100Mi", "nodefs.available": "5%", "pid.available": "1000"},
+		},
+		{
+			name:          "invalid format",
+			values:        []string{"memory.available=100Mi"},
+			expectedError: true,
+		},
+		{
+			name:          "unknown signal",
+			values:        []string{"memory.free<100Mi"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid quantity",
+			values:        []string{"memory.available<100MB!"},
+			expectedError: true,
+		},
+		{
+			name:          "percentage out of range",
+			values:        []string{"nodefs.available<101%"},
+			expectedError: true,
+		},
+		{
+			name:          "negative quantity",
+			values:        []string{"memory.available<-1Mi"},
+			expectedError: true,
+		},
+		{
+			name:          "repeated signal",
+			values:        []string{"memory.available<100Mi", "memory.available<200Mi"},
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			thresholds, err := ParseEvictionThresholds(rt.values)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", rt.expectedError, err != nil, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(thresholds, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, thresholds)
+			}
+		})
+	}
+}
+
+func TestGetKubeletEvictionPatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		eviction      map[string]map[string]string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "hard and soft thresholds",
+			eviction: map[string]map[string]string{
+				EvictionHardField:            {"nodefs.available": "0%", "memory.available": "50Mi"},
+				EvictionSoftField:            {"memory.available": "200Mi"},
+				EvictionSoftGracePeriodField: {"memory.available": "1m0s"},
+			},
+			expected: `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+evictionHard:
+  memory.available: "50Mi"
+  nodefs.available: "0%"
+evictionSoft:
+  memory.available: "200Mi"
+evictionSoftGracePeriod:
+  memory.available: "1m0s"
+`,
+		},
+		{
+			name: "unknown field",
+			eviction: map[string]map[string]string{
+				"evictionMinimumReclaim": {"memory.available": "0Mi"},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			patch, err := GetKubeletEvictionPatch(rt.eviction)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", rt.expectedError, err != nil, err)
+			}
+			if patch != rt.expected {
+				t.Errorf("expected patch:\n%s\ngot:\n%s", rt.expected, patch)
+			}
+		})
+	}
+}
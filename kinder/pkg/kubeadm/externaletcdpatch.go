@@ -18,14 +18,40 @@ package kubeadm
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// ExternalEtcdCAFile is the path on control-plane nodes of the CA certificate of an external etcd using TLS
+	ExternalEtcdCAFile = "/etc/kubernetes/pki/etcd/ca.crt"
+
+	// ExternalEtcdCertFile is the path on control-plane nodes of the client certificate used by the API server
+	// for connecting to an external etcd using TLS
+	ExternalEtcdCertFile = "/etc/kubernetes/pki/etcd/apiserver-etcd-client.crt"
+
+	// ExternalEtcdKeyFile is the path on control-plane nodes of the key of the API server etcd client certificate
+	ExternalEtcdKeyFile = "/etc/kubernetes/pki/etcd/apiserver-etcd-client.key"
+)
+
 // GetExternalEtcdPatch returns the kubeadm config patch that will instruct kubeadm
-// to use external etcd.
-func GetExternalEtcdPatch(kubeadmConfigVersion string, etcdIP string) (string, error) {
+// to use external etcd, with one endpoint for each of the given etcd members.
+func GetExternalEtcdPatch(kubeadmConfigVersion string, etcdIPs ...string) (string, error) {
+	endpoints := make([]string, 0, len(etcdIPs))
+	for _, ip := range etcdIPs {
+		endpoints = append(endpoints, ExternalEtcdEndpoint(ip))
+	}
+	return GetExternalEtcdEndpointsPatch(kubeadmConfigVersion, endpoints, false)
+}
+
+// GetExternalEtcdEndpointsPatch returns the kubeadm config patch that will instruct kubeadm to use external etcd
+// at the given client endpoints; when useTLS is true, the patch references the etcd CA and the API server etcd
+// client certificate and key, that must exist on all the control-plane nodes.
+func GetExternalEtcdEndpointsPatch(kubeadmConfigVersion string, etcdEndpoints []string, useTLS bool) (string, error) {
 	// select the patches for the kubeadm config version
 	log.Debugf("Preparing externalEtcdPatch for kubeadm config %s", kubeadmConfigVersion)
 
@@ -39,19 +65,53 @@ func GetExternalEtcdPatch(kubeadmConfigVersion string, etcdIP string) (string, e
 		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
 	}
 
-	return fmt.Sprintf(externalEtcdPatch, etcdIP), nil
+	if len(etcdEndpoints) == 0 {
+		return "", errors.New("at least one external etcd member is required")
+	}
+
+	var endpoints strings.Builder
+	for _, e := range etcdEndpoints {
+		fmt.Fprintf(&endpoints, "\n    - %s", e)
+	}
+	if useTLS {
+		fmt.Fprintf(&endpoints, "\n    caFile: %s\n    certFile: %s\n    keyFile: %s", ExternalEtcdCAFile, ExternalEtcdCertFile, ExternalEtcdKeyFile)
+	}
+
+	return fmt.Sprintf(externalEtcdPatch, endpoints.String()), nil
+}
+
+// ValidateExternalEtcdEndpoint checks an external etcd client endpoint is an http or https URL with a host and
+// a port, e.g. https://10.0.0.10:2379, and returns true if the endpoint uses TLS
+func ValidateExternalEtcdEndpoint(endpoint string) (bool, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid external etcd endpoint %q", endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false, errors.Errorf("invalid external etcd endpoint %q. Use an http or https URL, e.g. https://10.0.0.10:2379", endpoint)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return false, errors.Errorf("invalid external etcd endpoint %q. The endpoint must include a host and a port, e.g. https://10.0.0.10:2379", endpoint)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return false, errors.Errorf("invalid external etcd endpoint %q. The endpoint can't include a path, a query or user info", endpoint)
+	}
+	return u.Scheme == "https", nil
+}
+
+// ExternalEtcdEndpoint returns the client endpoint for an external etcd member
+func ExternalEtcdEndpoint(ip string) string {
+	return fmt.Sprintf("http://%s", net.JoinHostPort(ip, "2379"))
 }
 
 const externalEtcdPatchv1beta3 = `apiVersion: kubeadm.k8s.io/v1beta3
 kind: ClusterConfiguration
 etcd:
   external:
-    endpoints:
-    - http://%s:2379`
+    endpoints:%s`
 
 const externalEtcdPatchv1beta4 = `apiVersion: kubeadm.k8s.io/v1beta4
 kind: ClusterConfiguration
 etcd:
   external:
-    endpoints:
-    - http://%s:2379`
+    endpoints:%s`
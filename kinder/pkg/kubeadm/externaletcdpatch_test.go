@@ -0,0 +1,87 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+)
+
+func TestGetExternalEtcdEndpointsPatch(t *testing.T) {
+	patch, err := GetExternalEtcdEndpointsPatch("v1beta4", []string{"https://10.0.0.10:2379", "https://10.0.0.11:2379"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `apiVersion: kubeadm.k8s.io/v1beta4
+kind: ClusterConfiguration
+etcd:
+  external:
+    endpoints:
+    - https://10.0.0.10:2379
+    - https://10.0.0.11:2379
+    caFile: /etc/kubernetes/pki/etcd/ca.crt
+    certFile: /etc/kubernetes/pki/etcd/apiserver-etcd-client.crt
+    keyFile: /etc/kubernetes/pki/etcd/apiserver-etcd-client.key`
+	if patch != expected {
+		t.Errorf("expected patch:\n%s\ngot:\n%s", expected, patch)
+	}
+
+	patch, err = GetExternalEtcdPatch("v1beta3", "172.17.0.2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = `apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+etcd:
+  external:
+    endpoints:
+    - http://172.17.0.2:2379`
+	if patch != expected {
+		t.Errorf("expected patch:\n%s\ngot:\n%s", expected, patch)
+	}
+
+	if _, err := GetExternalEtcdEndpointsPatch("v1beta4", nil, false); err == nil {
+		t.Error("expected error for no endpoints")
+	}
+}
+
+func TestValidateExternalEtcdEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint    string
+		expectTLS   bool
+		expectError bool
+	}{
+		{endpoint: "https://10.0.0.10:2379", expectTLS: true},
+		{endpoint: "http://etcd.example.com:2379"},
+		{endpoint: "https://[fd00::10]:2379", expectTLS: true},
+		{endpoint: "10.0.0.10:2379", expectError: true},
+		{endpoint: "https://10.0.0.10", expectError: true},
+		{endpoint: "unix:///run/etcd.sock", expectError: true},
+		{endpoint: "https://10.0.0.10:2379/health", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.endpoint, func(t *testing.T) {
+			useTLS, err := ValidateExternalEtcdEndpoint(test.endpoint)
+			if (err != nil) != test.expectError {
+				t.Fatalf("expected error %t, got %v", test.expectError, err)
+			}
+			if useTLS != test.expectTLS {
+				t.Errorf("expected TLS %t, got %t", test.expectTLS, useTLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Control-plane components supporting extraArgs, as named in the kubeadm ClusterConfiguration
+const (
+	APIServerComponent         = "apiServer"
+	ControllerManagerComponent = "controllerManager"
+	SchedulerComponent         = "scheduler"
+)
+
+// ClusterSigningDurationArg is the controller manager flag setting the duration of the certificates it signs
+const ClusterSigningDurationArg = "cluster-signing-duration"
+
+// ExtraArg defines an extra argument for a control-plane component
+type ExtraArg struct {
+	Name  string
+	Value string
+}
+
+// ParseExtraArgs parses and validates extra arguments in the key=value form; values for
+// a repeated key are joined in a comma separated list, as expected by flags taking list values
+func ParseExtraArgs(values []string) ([]ExtraArg, error) {
+	var args []ExtraArg
+	index := map[string]int{}
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid extra arg %q. Use the key=value form", v)
+		}
+		if strings.HasPrefix(parts[0], "-") || strings.ContainsAny(parts[0], " \t\n") {
+			return nil, errors.Errorf("invalid extra arg key %q. Use the flag name without leading dashes", parts[0])
+		}
+
+		if i, ok := index[parts[0]]; ok {
+			args[i].Value = fmt.Sprintf("%s,%s", args[i].Value, parts[1])
+			continue
+		}
+		index[parts[0]] = len(args)
+		args = append(args, ExtraArg{Name: parts[0], Value: parts[1]})
+	}
+	return args, nil
+}
+
+// FormatExtraArgs returns the extra arguments in the key=value form
+func FormatExtraArgs(args []ExtraArg) []string {
+	values := make([]string, 0, len(args))
+	for _, a := range args {
+		values = append(values, fmt.Sprintf("%s=%s", a.Name, a.Value))
+	}
+	return values
+}
+
+// ComponentExtraArgs returns all the extra arguments for a control-plane component, that are the API server TLS
+// settings, the extra arguments set by the kubeadm config template and the extra arguments defined at create time,
+// that take precedence. The template extra arguments are included because the extra args patch replaces the
+// component extraArgs, that in the v1beta4 config is a list.
+func ComponentExtraArgs(data *ConfigData, component string) []ExtraArg {
+	var args []ExtraArg
+	if component == APIServerComponent {
+		args = append(args, APIServerTLSArgs(data.TLSMinVersion, data.TLSCipherSuites)...)
+	}
+
+	// the template configures ipv6 default addresses for IPv6 clusters
+	if data.IPv6 {
+		switch component {
+		case ControllerManagerComponent:
+			args = append(args, ExtraArg{Name: "bind-address", Value: "::"})
+		case SchedulerComponent:
+			args = append(args, ExtraArg{Name: "bind-address", Value: "::1"})
+		}
+	}
+
+	for _, a := range data.ExtraArgs[component] {
+		replaced := false
+		for i := range args {
+			if args[i].Name == a.Name {
+				args[i].Value = a.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			args = append(args, a)
+		}
+	}
+	return args
+}
+
+// GetExtraArgsPatch returns the kubeadm config patch that will instruct kubeadm to configure a control-plane
+// component with the given extra arguments. The patch replaces the component extraArgs, so all the
+// extra arguments for a component, including the ones set by the kubeadm config template, must be set by
+// a single patch; see ComponentExtraArgs.
+func GetExtraArgsPatch(kubeadmConfigVersion, component string, args []ExtraArg) (string, error) {
+	log.Debugf("Preparing %s extra args patch for kubeadm config %s", component, kubeadmConfigVersion)
+
+	switch component {
+	case APIServerComponent, ControllerManagerComponent, SchedulerComponent:
+	default:
+		return "", errors.Errorf("unknown control-plane component: %s", component)
+	}
+
+	extraArgs, err := extraArgsYAML(kubeadmConfigVersion, component, "", args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(extraArgsPatch, kubeadmConfigVersion, component, extraArgs), nil
+}
+
+// GetEtcdExtraArgsPatch returns the kubeadm config patch that will instruct kubeadm to configure the local etcd
+// with the given extra arguments, e.g. the backend quota. The patch replaces the local etcd extraArgs,
+// so all the extra arguments for etcd must be set by a single patch.
+func GetEtcdExtraArgsPatch(kubeadmConfigVersion string, args []ExtraArg) (string, error) {
+	log.Debugf("Preparing etcd extra args patch for kubeadm config %s", kubeadmConfigVersion)
+
+	extraArgs, err := extraArgsYAML(kubeadmConfigVersion, "etcd", "    ", args)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(etcdExtraArgsPatch, kubeadmConfigVersion, extraArgs), nil
+}
+
+// extraArgsYAML returns the extraArgs of a component in the format of the kubeadm config version,
+// with each line prefixed by indent
+func extraArgsYAML(kubeadmConfigVersion, component, indent string, args []ExtraArg) (string, error) {
+	names := map[string]bool{}
+	for _, a := range args {
+		if names[a.Name] {
+			return "", errors.Errorf("the %s extra arg %q is set more than once", component, a.Name)
+		}
+		names[a.Name] = true
+	}
+
+	var extraArgs strings.Builder
+	switch kubeadmConfigVersion {
+	case "v1beta3":
+		for _, a := range args {
+			fmt.Fprintf(&extraArgs, "%s    %s: %q\n", indent, a.Name, a.Value)
+		}
+	case "v1beta4":
+		for _, a := range args {
+			fmt.Fprintf(&extraArgs, "%s  - name: %s\n%s    value: %q\n", indent, a.Name, indent, a.Value)
+		}
+	default:
+		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	}
+	return extraArgs.String(), nil
+}
+
+const extraArgsPatch = `apiVersion: kubeadm.k8s.io/%s
+kind: ClusterConfiguration
+%s:
+  extraArgs:
+%s`
+
+const etcdExtraArgsPatch = `apiVersion: kubeadm.k8s.io/%s
+kind: ClusterConfiguration
+etcd:
+  local:
+    extraArgs:
+%s`
@@ -0,0 +1,296 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExtraArgs(t *testing.T) {
+	var tests = []struct {
+		name          string
+		values        []string
+		expected      []ExtraArg
+		expectedError bool
+	}{
+		{
+			name: "no args",
+		},
+		{
+			name:   "key=value",
+			values: []string{"v=4", "profiling=false"},
+			expected: []ExtraArg{
+				{Name: "v", Value: "4"},
+				{Name: "profiling", Value: "false"},
+			},
+		},
+		{
+			name:   "repeated keys are joined",
+			values: []string{"enable-admission-plugins=NodeRestriction", "v=2", "enable-admission-plugins=PodSecurity"},
+			expected: []ExtraArg{
+				{Name: "enable-admission-plugins", Value: "NodeRestriction,PodSecurity"},
+				{Name: "v", Value: "2"},
+			},
+		},
+		{
+			name:     "value with equal sign",
+			values:   []string{"feature-gates=Foo=true"},
+			expected: []ExtraArg{{Name: "feature-gates", Value: "Foo=true"}},
+		},
+		{
+			name:          "missing value",
+			values:        []string{"profiling"},
+			expectedError: true,
+		},
+		{
+			name:          "missing key",
+			values:        []string{"=false"},
+			expectedError: true,
+		},
+		{
+			name:          "leading dashes",
+			values:        []string{"--profiling=false"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := ParseExtraArgs(test.values)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if !reflect.DeepEqual(args, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, args)
+			}
+		})
+	}
+}
+
+func TestGetExtraArgsPatch(t *testing.T) {
+	tlsArgs := APIServerTLSArgs("VersionTLS12",
+		[]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"})
+
+	var tests = []struct {
+		name          string
+		configVersion string
+		component     string
+		args          []ExtraArg
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:          "v1beta3",
+			configVersion: "v1beta3",
+			component:     APIServerComponent,
+			args:          tlsArgs,
+			expected: "apiVersion: kubeadm.k8s.io/v1beta3\nkind: ClusterConfiguration\napiServer:\n  extraArgs:\n" +
+				"    tls-min-version: \"VersionTLS12\"\n" +
+				"    tls-cipher-suites: \"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384\"\n",
+		},
+		{
+			name:          "v1beta4",
+			configVersion: "v1beta4",
+			component:     APIServerComponent,
+			args:          tlsArgs,
+			expected: "apiVersion: kubeadm.k8s.io/v1beta4\nkind: ClusterConfiguration\napiServer:\n  extraArgs:\n" +
+				"  - name: tls-min-version\n    value: \"VersionTLS12\"\n" +
+				"  - name: tls-cipher-suites\n    value: \"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384\"\n",
+		},
+		{
+			name:          "controller manager",
+			configVersion: "v1beta4",
+			component:     ControllerManagerComponent,
+			args:          []ExtraArg{{Name: "v", Value: "4"}},
+			expected: "apiVersion: kubeadm.k8s.io/v1beta4\nkind: ClusterConfiguration\ncontrollerManager:\n  extraArgs:\n" +
+				"  - name: v\n    value: \"4\"\n",
+		},
+		{
+			name:          "duplicated arg",
+			configVersion: "v1beta4",
+			component:     APIServerComponent,
+			args:          append(tlsArgs, ExtraArg{Name: "tls-min-version", Value: "VersionTLS13"}),
+			expectedError: true,
+		},
+		{
+			name:          "unknown component",
+			configVersion: "v1beta4",
+			component:     "etcd",
+			args:          tlsArgs,
+			expectedError: true,
+		},
+		{
+			name:          "unknown config version",
+			configVersion: "v1beta2",
+			component:     APIServerComponent,
+			args:          tlsArgs,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := GetExtraArgsPatch(test.configVersion, test.component, test.args)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if patch != test.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, patch)
+			}
+		})
+	}
+}
+
+func TestComponentExtraArgs(t *testing.T) {
+	var tests = []struct {
+		name      string
+		data      ConfigData
+		component string
+		expected  []ExtraArg
+	}{
+		{
+			name:      "no args",
+			component: ControllerManagerComponent,
+		},
+		{
+			name:      "ipv6 template args",
+			data:      ConfigData{IPv6: true},
+			component: SchedulerComponent,
+			expected:  []ExtraArg{{Name: "bind-address", Value: "::1"}},
+		},
+		{
+			name: "ipv6 template args and extra args",
+			data: ConfigData{
+				IPv6:      true,
+				ExtraArgs: map[string][]ExtraArg{ControllerManagerComponent: {{Name: "v", Value: "4"}}},
+			},
+			component: ControllerManagerComponent,
+			expected:  []ExtraArg{{Name: "bind-address", Value: "::"}, {Name: "v", Value: "4"}},
+		},
+		{
+			name: "extra args override template args",
+			data: ConfigData{
+				IPv6:      true,
+				ExtraArgs: map[string][]ExtraArg{ControllerManagerComponent: {{Name: "bind-address", Value: "::1"}}},
+			},
+			component: ControllerManagerComponent,
+			expected:  []ExtraArg{{Name: "bind-address", Value: "::1"}},
+		},
+		{
+			name: "api server TLS args",
+			data: ConfigData{
+				TLSMinVersion: "VersionTLS13",
+				ExtraArgs:     map[string][]ExtraArg{APIServerComponent: {{Name: "v", Value: "2"}}},
+			},
+			component: APIServerComponent,
+			expected:  []ExtraArg{{Name: "tls-min-version", Value: "VersionTLS13"}, {Name: "v", Value: "2"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if args := ComponentExtraArgs(&test.data, test.component); !reflect.DeepEqual(args, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, args)
+			}
+		})
+	}
+}
+
+func TestExtraArgsPatchKeepsTemplateArgs(t *testing.T) {
+	for _, configVersion := range []string{"v1beta3", "v1beta4"} {
+		t.Run(configVersion, func(t *testing.T) {
+			data := ConfigData{
+				KubernetesVersion: "v1.30.0",
+				DNSDomain:         "cluster.local",
+				CgroupDriver:      "systemd",
+				IPv6:              true,
+				ExtraArgs:         map[string][]ExtraArg{ControllerManagerComponent: {{Name: "v", Value: "4"}}},
+			}
+			config, err := Config(configVersion, data)
+			if err != nil {
+				t.Fatalf("failed Config: %v", err)
+			}
+			patch, err := GetExtraArgsPatch(configVersion, ControllerManagerComponent, ComponentExtraArgs(&data, ControllerManagerComponent))
+			if err != nil {
+				t.Fatalf("failed GetExtraArgsPatch: %v", err)
+			}
+			config, err = Build(config, []string{patch}, nil)
+			if err != nil {
+				t.Fatalf("failed Build: %v", err)
+			}
+			// the controller manager and the scheduler bind addresses set by the template
+			if n := strings.Count(config, "bind-address"); n != 2 {
+				t.Errorf("expected config to contain 2 bind-address args, found %d:\n%s", n, config)
+			}
+			if !strings.Contains(config, `"4"`) {
+				t.Errorf("expected config to contain the extra arg, got:\n%s", config)
+			}
+		})
+	}
+}
+
+func TestGetEtcdExtraArgsPatch(t *testing.T) {
+	args := []ExtraArg{
+		{Name: "quota-backend-bytes", Value: "2147483648"},
+		{Name: "auto-compaction-retention", Value: "1h"},
+	}
+
+	var tests = []struct {
+		name          string
+		configVersion string
+		args          []ExtraArg
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:          "v1beta3",
+			configVersion: "v1beta3",
+			args:          args,
+			expected: "apiVersion: kubeadm.k8s.io/v1beta3\nkind: ClusterConfiguration\netcd:\n  local:\n    extraArgs:\n" +
+				"        quota-backend-bytes: \"2147483648\"\n" +
+				"        auto-compaction-retention: \"1h\"\n",
+		},
+		{
+			name:          "v1beta4",
+			configVersion: "v1beta4",
+			args:          args,
+			expected: "apiVersion: kubeadm.k8s.io/v1beta4\nkind: ClusterConfiguration\netcd:\n  local:\n    extraArgs:\n" +
+				"      - name: quota-backend-bytes\n        value: \"2147483648\"\n" +
+				"      - name: auto-compaction-retention\n        value: \"1h\"\n",
+		},
+		{
+			name:          "duplicated arg",
+			configVersion: "v1beta4",
+			args:          append(args, ExtraArg{Name: "quota-backend-bytes", Value: "1024"}),
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := GetEtcdExtraArgsPatch(test.configVersion, test.args)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if patch != test.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, patch)
+			}
+		})
+	}
+}
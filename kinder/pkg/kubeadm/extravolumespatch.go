@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// HostPathMount defines a host path on the control-plane nodes to be mounted in the API server pod
+type HostPathMount struct {
+	Name      string
+	HostPath  string
+	MountPath string
+	ReadOnly  bool
+	PathType  string
+}
+
+// GetAPIServerExtraVolumesPatch returns the kubeadm config patch that will instruct kubeadm to mount
+// the given host paths in the API server pod. The patch replaces the API server extraVolumes, so all the
+// extra volumes must be set by a single patch.
+func GetAPIServerExtraVolumesPatch(kubeadmConfigVersion string, volumes ...HostPathMount) (string, error) {
+	log.Debugf("Preparing API server extra volumes patch for kubeadm config %s", kubeadmConfigVersion)
+
+	switch kubeadmConfigVersion {
+	case "v1beta3", "v1beta4":
+	default:
+		return "", errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	}
+
+	names := map[string]bool{}
+	var extraVolumes strings.Builder
+	for _, v := range volumes {
+		if names[v.Name] {
+			return "", errors.Errorf("the API server extra volume %q is set more than once", v.Name)
+		}
+		names[v.Name] = true
+		fmt.Fprintf(&extraVolumes, extraVolume, v.Name, v.HostPath, v.MountPath, v.ReadOnly, v.PathType)
+	}
+
+	return fmt.Sprintf(extraVolumesPatch, kubeadmConfigVersion, extraVolumes.String()), nil
+}
+
+const extraVolumesPatch = `apiVersion: kubeadm.k8s.io/%s
+kind: ClusterConfiguration
+apiServer:
+  extraVolumes:
+%s`
+
+const extraVolume = `  - name: %s
+    hostPath: %s
+    mountPath: %s
+    readOnly: %t
+    pathType: %s
+`
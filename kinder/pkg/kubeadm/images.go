@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Image defines a kustomize images transformer entry; the images of all the containers with Name, ignoring
+// tag and digest, are rewritten to NewName, if set, and to NewTag or Digest, if set. As in kustomize,
+// Digest takes precedence over NewTag.
+type Image struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// transformImages rewrites the container images of all the resources
+func transformImages(resources []resource, images []Image) error {
+	for _, img := range images {
+		if img.Name == "" {
+			return errors.New("images must have a name")
+		}
+	}
+
+	for i := range resources {
+		var obj interface{}
+		if err := json.Unmarshal(resources[i].json, &obj); err != nil {
+			return errors.WithStack(err)
+		}
+		setImages(obj, images)
+		patched, err := json.Marshal(obj)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		resources[i].json = patched
+	}
+	return nil
+}
+
+// setImages rewrites the images of the containers, init containers and ephemeral containers in a decoded
+// JSON object; containers are searched at any depth, so pod templates in workloads are included
+func setImages(obj interface{}, images []Image) {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			if k == "containers" || k == "initContainers" || k == "ephemeralContainers" {
+				if containers, ok := v.([]interface{}); ok {
+					for _, c := range containers {
+						if c, ok := c.(map[string]interface{}); ok {
+							if image, ok := c["image"].(string); ok {
+								c["image"] = rewriteImage(image, images)
+							}
+						}
+					}
+				}
+			}
+			setImages(v, images)
+		}
+	case []interface{}:
+		for _, v := range o {
+			setImages(v, images)
+		}
+	}
+}
+
+// rewriteImage returns the image reference rewritten by the first matching images transformer entry, if any
+func rewriteImage(image string, images []Image) string {
+	name, tag, digest := splitImage(image)
+	for _, img := range images {
+		if img.Name != name {
+			continue
+		}
+		if img.NewName != "" {
+			name = img.NewName
+		}
+		switch {
+		case img.Digest != "":
+			return name + "@" + img.Digest
+		case img.NewTag != "":
+			return name + ":" + img.NewTag
+		}
+		return joinImage(name, tag, digest)
+	}
+	return image
+}
+
+// splitImage splits an image reference in name, tag and digest; the colon of a registry port
+// is not a tag separator
+func splitImage(image string) (name, tag, digest string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image, digest = image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, tag = image[:i], image[i+1:]
+	}
+	return image, tag, digest
+}
+
+// joinImage is the inverse of splitImage
+func joinImage(name, tag, digest string) string {
+	if tag != "" {
+		name += ":" + tag
+	}
+	if digest != "" {
+		name += "@" + digest
+	}
+	return name
+}
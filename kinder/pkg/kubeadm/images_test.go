@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+)
+
+const testImagesManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: coredns
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - image: registry.k8s.io/coredns/coredns:v1.11.1
+        name: coredns
+      initContainers:
+      - image: localhost:5000/busybox
+        name: init
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd
+spec:
+  containers:
+  - image: registry.k8s.io/etcd:3.5.15-0@sha256:a6dc63e6e8cfa0307d7851762fa6b629afb18f28d8aa3fab5a6e91b4af60026a
+    name: etcd
+`
+
+func TestBuildWithImages(t *testing.T) {
+	var tests = []struct {
+		name          string
+		patches       []string
+		images        []Image
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:   "deployment image tag is rewritten",
+			images: []Image{{Name: "registry.k8s.io/coredns/coredns", NewTag: "v1.12.0"}},
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: coredns
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - image: registry.k8s.io/coredns/coredns:v1.12.0
+        name: coredns
+      initContainers:
+      - image: localhost:5000/busybox
+        name: init
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd
+spec:
+  containers:
+  - image: registry.k8s.io/etcd:3.5.15-0@sha256:a6dc63e6e8cfa0307d7851762fa6b629afb18f28d8aa3fab5a6e91b4af60026a
+    name: etcd
+`,
+		},
+		{
+			name: "images are rewritten after patches",
+			patches: []string{`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: registry.k8s.io/etcd:3.5.16-0
+    name: etcd`},
+			images: []Image{
+				{Name: "registry.k8s.io/etcd", NewName: "gcr.io/etcd-development/etcd", Digest: "sha256:1234"},
+				{Name: "localhost:5000/busybox", NewTag: "1.36"},
+			},
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: coredns
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - image: registry.k8s.io/coredns/coredns:v1.11.1
+        name: coredns
+      initContainers:
+      - image: localhost:5000/busybox:1.36
+        name: init
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd
+spec:
+  containers:
+  - image: gcr.io/etcd-development/etcd@sha256:1234
+    name: etcd
+`,
+		},
+		{
+			name:   "new name keeps tag and digest",
+			images: []Image{{Name: "registry.k8s.io/etcd", NewName: "example.com/etcd"}},
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: coredns
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - image: registry.k8s.io/coredns/coredns:v1.11.1
+        name: coredns
+      initContainers:
+      - image: localhost:5000/busybox
+        name: init
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd
+spec:
+  containers:
+  - image: example.com/etcd:3.5.15-0@sha256:a6dc63e6e8cfa0307d7851762fa6b629afb18f28d8aa3fab5a6e91b4af60026a
+    name: etcd
+`,
+		},
+		{
+			name:          "image without name",
+			images:        []Image{{NewTag: "latest"}},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := BuildWithImages(testImagesManifests, test.patches, nil, test.images)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if out != test.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestBuildWithoutImages(t *testing.T) {
+	patches := []string{`apiVersion: apps/v1
+kind: Deployment
+spec:
+  replicas: 3`}
+	patches6902 := []PatchJSON6902{{Group: "apps", Version: "v1", Kind: "Deployment", Patch: `- op: add
+  path: /metadata/labels
+  value: {app: coredns}`}}
+
+	expected, err := Build(testImagesManifests, patches, patches6902)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, images := range [][]Image{nil, {}} {
+		out, err := BuildWithImages(testImagesManifests, patches, patches6902, images)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+		}
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	var tests = []struct {
+		image          string
+		expectedName   string
+		expectedTag    string
+		expectedDigest string
+	}{
+		{image: "busybox", expectedName: "busybox"},
+		{image: "busybox:1.36", expectedName: "busybox", expectedTag: "1.36"},
+		{image: "localhost:5000/busybox", expectedName: "localhost:5000/busybox"},
+		{image: "localhost:5000/busybox:1.36", expectedName: "localhost:5000/busybox", expectedTag: "1.36"},
+		{image: "busybox@sha256:1234", expectedName: "busybox", expectedDigest: "sha256:1234"},
+		{image: "busybox:1.36@sha256:1234", expectedName: "busybox", expectedTag: "1.36", expectedDigest: "sha256:1234"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			name, tag, digest := splitImage(test.image)
+			if name != test.expectedName || tag != test.expectedTag || digest != test.expectedDigest {
+				t.Errorf("expected %q %q %q, got %q %q %q", test.expectedName, test.expectedTag, test.expectedDigest, name, tag, digest)
+			}
+			if joined := joinImage(name, tag, digest); joined != test.image {
+				t.Errorf("expected %q, got %q", test.image, joined)
+			}
+		})
+	}
+}
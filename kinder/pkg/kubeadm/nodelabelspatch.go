@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Taint defines a taint to be applied to a node at join time
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// ParseNodeLabel parses and validates a node label in the key=value form
+func ParseNodeLabel(value string) (string, string, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return "", "", errors.Errorf("invalid node label %q. Use the key=value form", value)
+	}
+	if errs := validation.IsQualifiedName(parts[0]); len(errs) > 0 {
+		return "", "", errors.Errorf("invalid node label key %q: %s", parts[0], strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(parts[1]); len(errs) > 0 {
+		return "", "", errors.Errorf("invalid node label value %q: %s", parts[1], strings.Join(errs, "; "))
+	}
+	return parts[0], parts[1], nil
+}
+
+// ParseNodeTaint parses and validates a node taint in the key[=value]:Effect form
+func ParseNodeTaint(value string) (Taint, error) {
+	i := strings.LastIndex(value, ":")
+	if i < 0 {
+		return Taint{}, errors.Errorf("invalid node taint %q. Use the key=value:Effect form", value)
+	}
+
+	taint := Taint{
+		Key:    value[:i],
+		Effect: value[i+1:],
+	}
+	if parts := strings.SplitN(taint.Key, "=", 2); len(parts) == 2 {
+		taint.Key = parts[0]
+		taint.Value = parts[1]
+	}
+
+	if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+		return Taint{}, errors.Errorf("invalid node taint key %q: %s", taint.Key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+		return Taint{}, errors.Errorf("invalid node taint value %q: %s", taint.Value, strings.Join(errs, "; "))
+	}
+	switch taint.Effect {
+	case "NoSchedule", "PreferNoSchedule", "NoExecute":
+	default:
+		return Taint{}, errors.Errorf("invalid node taint effect %q. Use one of [NoSchedule, PreferNoSchedule, NoExecute]", taint.Effect)
+	}
+	return taint, nil
+}
+
+// GetNodeLabelsAndTaintsPatch returns the kubeadm config patch that will instruct kubeadm
+// to register a node joining the cluster with the given labels and taints; labels should be in the key=value form.
+func GetNodeLabelsAndTaintsPatch(kubeadmConfigVersion string, labels []string, taints []Taint) (PatchJSON6902, error) {
+	log.Debugf("Preparing nodeLabelsAndTaintsPatch for kubeadm config %s", kubeadmConfigVersion)
+
+	if kubeadmConfigVersion != "v1beta3" && kubeadmConfigVersion != "v1beta4" {
+		return PatchJSON6902{}, errors.Errorf("unknown kubeadm config version: %s", kubeadmConfigVersion)
+	}
+
+	var ops []map[string]interface{}
+	if len(labels) > 0 {
+		nodeLabels := strings.Join(labels, ",")
+		if kubeadmConfigVersion == "v1beta3" {
+			// kubeletExtraArgs is a map
+			ops = append(ops, map[string]interface{}{
+				"op":    "add",
+				"path":  "/nodeRegistration/kubeletExtraArgs/node-labels",
+				"value": nodeLabels,
+			})
+		} else {
+			// kubeletExtraArgs is a list of name/value pairs
+			ops = append(ops, map[string]interface{}{
+				"op":   "add",
+				"path": "/nodeRegistration/kubeletExtraArgs/-",
+				"value": map[string]string{
+					"name":  "node-labels",
+					"value": nodeLabels,
+				},
+			})
+		}
+	}
+
+	if len(taints) > 0 {
+		ops = append(ops, map[string]interface{}{
+			"op":    "add",
+			"path":  "/nodeRegistration/taints",
+			"value": taints,
+		})
+	}
+
+	// JSON is valid YAML
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return PatchJSON6902{}, errors.Wrap(err, "failed to encode the node labels and taints patch")
+	}
+
+	return PatchJSON6902{
+		Group:   "kubeadm.k8s.io",
+		Version: kubeadmConfigVersion,
+		Kind:    "JoinConfiguration",
+		Patch:   string(patch),
+	}, nil
+}
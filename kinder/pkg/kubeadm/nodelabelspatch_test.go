@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNodeTaint(t *testing.T) {
+	tests := []struct {
+		value         string
+		expected      Taint
+		expectedError bool
+	}{
+		{
+			value:    "dedicated=gpu:NoSchedule",
+			expected: Taint{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"},
+		},
+		{
+			value:    "example.com/maintenance:NoExecute",
+			expected: Taint{Key: "example.com/maintenance", Effect: "NoExecute"},
+		},
+		{
+			value:         "dedicated=gpu",
+			expectedError: true,
+		},
+		{
+			value:         "dedicated=gpu:Never",
+			expectedError: true,
+		},
+		{
+			value:         "invalid key=gpu:NoSchedule",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			taint, err := ParseNodeTaint(test.value)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if taint != test.expected {
+				t.Fatalf("expected taint: %+v, found %+v", test.expected, taint)
+			}
+		})
+	}
+}
+
+func TestParseNodeLabel(t *testing.T) {
+	tests := []struct {
+		value         string
+		expectedError bool
+	}{
+		{value: "disktype=ssd"},
+		{value: "example.com/zone="},
+		{value: "disktype", expectedError: true},
+		{value: "disk type=ssd", expectedError: true},
+		{value: "disktype=not valid", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			_, _, err := ParseNodeLabel(test.value)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
+
+func TestGetNodeLabelsAndTaintsPatch(t *testing.T) {
+	taints := []Taint{{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"}}
+
+	tests := []struct {
+		version  string
+		raw      string
+		expected []string
+	}{
+		{
+			version: "v1beta3",
+			raw: `apiVersion: kubeadm.k8s.io/v1beta3
+kind: JoinConfiguration
+nodeRegistration:
+  kubeletExtraArgs:
+    node-ip: "172.17.0.3"
+`,
+			expected: []string{
+				"node-ip: 172.17.0.3",
+				"node-labels: disktype=ssd,zone=a",
+				"effect: NoSchedule",
+			},
+		},
+		{
+			version: "v1beta4",
+			raw: `apiVersion: kubeadm.k8s.io/v1beta4
+kind: JoinConfiguration
+nodeRegistration:
+  kubeletExtraArgs:
+  - name: node-ip
+    value: "172.17.0.3"
+`,
+			expected: []string{
+				"value: 172.17.0.3",
+				"name: node-labels",
+				"value: disktype=ssd,zone=a",
+				"effect: NoSchedule",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			patch, err := GetNodeLabelsAndTaintsPatch(test.version, []string{"disktype=ssd", "zone=a"}, taints)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			patched, err := Build(test.raw, nil, []PatchJSON6902{patch})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, e := range test.expected {
+				if !strings.Contains(patched, e) {
+					t.Fatalf("expected %q in the patched config:\n%s", e, patched)
+				}
+			}
+		})
+	}
+
+	if _, err := GetNodeLabelsAndTaintsPatch("v1beta2", nil, taints); err == nil {
+		t.Fatal("expected error for unknown kubeadm config version")
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// outputReplacement defines a pattern in the kubeadm output that changes across runs, and its replacement
+type outputReplacement struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// outputReplacements defines the patterns in the kubeadm output that change across runs; order matters, because
+// e.g. timestamps must be replaced before IPv6 addresses, and CA cert hashes before certificate keys
+var outputReplacements = []outputReplacement{
+	// klog header, e.g. I1018 02:12:35.123456    1234 certs.go:112]
+	{regexp.MustCompile(`(?m)^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ [^\]]+\] `), "$1 "},
+	// timestamps, e.g. 2024-10-18T02:12:35Z or 2024-10-18 02:12:35.123 +0000 UTC
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?( ?(Z|[+-]\d{2}:?\d{2}))?( [A-Z]{3,4})?`), "<timestamp>"},
+	// durations, e.g. 1.001234s or 4m0s
+	{regexp.MustCompile(`\b(\d+(\.\d+)?(ns|us|µs|ms|h|m|s))+\b`), "<duration>"},
+	// bootstrap tokens, e.g. abcdef.0123456789abcdef
+	{regexp.MustCompile(`\b[a-z0-9]{6}\.[a-z0-9]{16}\b`), "<token>"},
+	// CA cert hashes
+	{regexp.MustCompile(`\bsha256:[a-f0-9]{64}\b`), "sha256:<hash>"},
+	// certificate keys
+	{regexp.MustCompile(`\b[a-f0-9]{64}\b`), "<key>"},
+	// IPv4 addresses
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`), "<ip>"},
+	// IPv6 addresses, in the full or in the compressed form
+	{regexp.MustCompile(`(?i)\b([0-9a-f]{1,4}:){7}[0-9a-f]{1,4}\b|\b([0-9a-f]{1,4}:)*[0-9a-f]{0,4}::([0-9a-f]{1,4}:)*[0-9a-f]{0,4}`), "<ip>"},
+}
+
+// NormalizeOutput returns the kubeadm output without the values that change across runs, like timestamps, durations,
+// IPs, tokens, hashes and certificate keys, so the output of two runs can be compared; the cluster name prefix of
+// node names is replaced as well, so the output of two clusters with the same topology can be compared.
+func NormalizeOutput(output, clusterName string) string {
+	for _, r := range outputReplacements {
+		output = r.pattern.ReplaceAllString(output, r.replacement)
+	}
+	if clusterName != "" {
+		output = regexp.MustCompile(`\b`+regexp.QuoteMeta(clusterName)+`-`).ReplaceAllString(output, "<cluster>-")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffOutput returns the differences between two normalized kubeadm outputs, as lines prefixed by - for lines only
+// in the left output and by + for lines only in the right output, grouped in hunks with the line numbers of the left
+// and of the right output; an empty list is returned if the outputs are equal.
+func DiffOutput(left, right string) []string {
+	l, r := strings.Split(left, "\n"), strings.Split(right, "\n")
+
+	// computes the length of the longest common subsequence of the suffixes of the two outputs
+	lcs := make([][]int, len(l)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(r)+1)
+	}
+	for i := len(l) - 1; i >= 0; i-- {
+		for j := len(r) - 1; j >= 0; j-- {
+			switch {
+			case l[i] == r[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff, hunk []string
+	hunkLeft, hunkRight := 0, 0
+	flush := func() {
+		if len(hunk) > 0 {
+			diff = append(diff, fmt.Sprintf("@@ -%d +%d @@", hunkLeft+1, hunkRight+1))
+			diff = append(diff, hunk...)
+			hunk = nil
+		}
+	}
+	i, j := 0, 0
+	for i < len(l) || j < len(r) {
+		if i < len(l) && j < len(r) && l[i] == r[j] {
+			flush()
+			i++
+			j++
+			continue
+		}
+		if len(hunk) == 0 {
+			hunkLeft, hunkRight = i, j
+		}
+		if j == len(r) || (i < len(l) && lcs[i+1][j] >= lcs[i][j+1]) {
+			hunk = append(hunk, "-"+l[i])
+			i++
+		} else {
+			hunk = append(hunk, "+"+r[j])
+			j++
+		}
+	}
+	flush()
+	return diff
+}
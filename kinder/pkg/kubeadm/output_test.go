@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{
+			name:     "klog header",
+			output:   "I1018 02:12:35.123456    1234 certs.go:112] creating a new certificate authority for ca",
+			expected: "I creating a new certificate authority for ca",
+		},
+		{
+			name:     "durations",
+			output:   "[kubelet-check] The kubelet is healthy after 1.001234s  \r\n[api-check] Waiting for a healthy API server. This can take up to 4m0s",
+			expected: "[kubelet-check] The kubelet is healthy after <duration>\n[api-check] Waiting for a healthy API server. This can take up to <duration>",
+		},
+		{
+			name:     "join command",
+			output:   "kubeadm join 172.18.0.3:6443 --token abcdef.0123456789abcdef --discovery-token-ca-cert-hash sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			expected: "kubeadm join <ip>:6443 --token <token> --discovery-token-ca-cert-hash sha256:<hash>",
+		},
+		{
+			name:     "certificate key",
+			output:   "[upload-certs] Using certificate key:\nfedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+			expected: "[upload-certs] Using certificate key:\n<key>",
+		},
+		{
+			name:     "node names and IPv6 addresses",
+			output:   "[certs] apiserver serving cert is signed for DNS names [kind-control-plane kubernetes] and IPs [fc00:f853:ccd:e793::2 10.96.0.1]",
+			expected: "[certs] apiserver serving cert is signed for DNS names [<cluster>-control-plane kubernetes] and IPs [<ip> <ip>]",
+		},
+		{
+			name:     "timestamps",
+			output:   "certificate expires at 2025-10-18 02:12:35 +0000 UTC",
+			expected: "certificate expires at <timestamp>",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if normalized := NormalizeOutput(test.output, "kind"); normalized != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, normalized)
+			}
+		})
+	}
+}
+
+func TestDiffOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		expected []string
+	}{
+		{
+			name:  "equal",
+			left:  "a\nb\nc",
+			right: "a\nb\nc",
+		},
+		{
+			name:     "changed line",
+			left:     "a\nb\nc",
+			right:    "a\nB\nc",
+			expected: []string{"@@ -2 +2 @@", "-b", "+B"},
+		},
+		{
+			name:     "added and removed lines",
+			left:     "a\nb\nc\nd",
+			right:    "b\nc\nd\ne",
+			expected: []string{"@@ -1 +1 @@", "-a", "@@ -5 +4 @@", "+e"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if diff := DiffOutput(test.left, test.right); !reflect.DeepEqual(diff, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package kubeadm
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"strings"
 
@@ -56,6 +57,79 @@ type PatchJSON6902 struct {
 // Patches match if their kind and apiVersion match a document, with the exception
 // that if the patch does not set apiVersion it will be ignored.
 func Build(toPatch string, patches []string, patches6902 []PatchJSON6902) (string, error) {
+	return BuildWithSchemas(toPatch, patches, patches6902, nil)
+}
+
+// BuildWithSchemas is like Build, but it accepts additional openapi schemas (in JSON or YAML form)
+// describing custom resources; merge patches against documents matching a schema merge list fields
+// by their x-kubernetes-patch-merge-key instead of replacing them.
+// See parseOpenAPISchema for the supported schema format.
+func BuildWithSchemas(toPatch string, patches []string, patches6902 []PatchJSON6902, schemas []string) (string, error) {
+	return build(toPatch, patches, patches6902, schemas, nil, nil)
+}
+
+// BuildWithVars is like Build, but after patching it substitutes the legacy kustomize vars in the documents.
+// Vars are deprecated in kustomize, and replacements are preferred; BuildWithVars exists only for
+// compatibility with existing manifests, so vars must be explicitly opted in by using it.
+// See Var for the supported var definitions.
+func BuildWithVars(toPatch string, patches []string, patches6902 []PatchJSON6902, vars []Var) (string, error) {
+	return build(toPatch, patches, patches6902, nil, nil, vars)
+}
+
+// BuildWithImages is like Build, but after patching it rewrites the container images like the kustomize images
+// transformer, e.g. for pinning the tag of pre-release component images in all the documents at once.
+// See Image for the supported image definitions.
+func BuildWithImages(toPatch string, patches []string, patches6902 []PatchJSON6902, images []Image) (string, error) {
+	return build(toPatch, patches, patches6902, nil, images, nil)
+}
+
+// BuildSpec defines the inputs of one of the builds executed by BuildMany
+type BuildSpec struct {
+	// ToPatch is the Kubernetes object YAML document stream to patch
+	ToPatch string
+	// Patches are the merge patches
+	Patches []string
+	// Patches6902 are the JSON 6902 patches
+	Patches6902 []PatchJSON6902
+	// Images are the container images to rewrite after patching, see BuildWithImages
+	Images []Image
+	// Vars are the legacy kustomize vars to substitute after patching, see BuildWithVars
+	Vars []Var
+}
+
+// BuildMany executes several independent builds, like BuildWithSchemas, BuildWithImages or BuildWithVars, sharing the
+// openapi schemas, that are parsed only once. Results are returned in the same order of the specs;
+// if some builds fail, the results of the other builds are returned anyway, together with an error
+// reporting the index of each failed spec.
+func BuildMany(specs []BuildSpec, schemas []string) ([]string, error) {
+	mergeKeys, err := parseOpenAPISchemas(schemas)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse openapi schemas")
+	}
+
+	results := make([]string, len(specs))
+	var failures []string
+	for i, s := range specs {
+		results[i], err = buildWithMergeKeys(s.ToPatch, s.Patches, s.Patches6902, mergeKeys, s.Images, s.Vars)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("spec %d: %v", i, err))
+		}
+	}
+	if len(failures) > 0 {
+		return results, errors.Errorf("failed to build %d of %d specs: %s", len(failures), len(specs), strings.Join(failures, "; "))
+	}
+	return results, nil
+}
+
+func build(toPatch string, patches []string, patches6902 []PatchJSON6902, schemas []string, images []Image, vars []Var) (string, error) {
+	mergeKeys, err := parseOpenAPISchemas(schemas)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse openapi schemas")
+	}
+	return buildWithMergeKeys(toPatch, patches, patches6902, mergeKeys, images, vars)
+}
+
+func buildWithMergeKeys(toPatch string, patches []string, patches6902 []PatchJSON6902, mergeKeys map[matchInfo]map[string]string, images []Image, vars []Var) (string, error) {
 	// pre-process, including splitting up documents etc.
 	resources, err := parseResources(toPatch)
 	if err != nil {
@@ -69,12 +143,12 @@ func Build(toPatch string, patches []string, patches6902 []PatchJSON6902) (strin
 	if err != nil {
 		return "", errors.Wrap(err, "failed to parse JSON 6902 patches")
 	}
-	// apply patches and build result
-	builder := &strings.Builder{}
-	for i, r := range resources {
+	// apply patches
+	for i := range resources {
+		r := &resources[i]
 		// apply merge patches
 		for _, p := range mergePatches {
-			if _, err := r.applyMergePatch(p); err != nil {
+			if _, err := r.applyMergePatch(p, mergeKeys[r.matchInfo]); err != nil {
 				return "", errors.Wrap(err, "failed to apply patch")
 			}
 		}
@@ -84,6 +158,22 @@ func Build(toPatch string, patches []string, patches6902 []PatchJSON6902) (strin
 				return "", errors.Wrap(err, "failed to apply JSON 6902 patch")
 			}
 		}
+	}
+	// rewrite the container images of the patched resources
+	if len(images) > 0 {
+		if err := transformImages(resources, images); err != nil {
+			return "", errors.Wrap(err, "failed to transform images")
+		}
+	}
+	// substitute vars, resolving them against the patched resources
+	if len(vars) > 0 {
+		if err := substituteVars(resources, vars); err != nil {
+			return "", errors.Wrap(err, "failed to substitute vars")
+		}
+	}
+	// build result
+	builder := &strings.Builder{}
+	for i, r := range resources {
 		// write out result
 		if err := r.encodeTo(builder); err != nil {
 			return "", errors.Wrap(err, "failed to write patched resource")
@@ -117,10 +207,20 @@ func (r *resource) apply6902Patch(patch json6902Patch) (matches bool, err error)
 	return true, nil
 }
 
-func (r *resource) applyMergePatch(patch mergePatch) (matches bool, err error) {
+// applyMergePatch applies a RFC 7386 merge patch to the resource; if mergeKeys are provided,
+// list fields with a merge key are merged item by item instead of being replaced.
+func (r *resource) applyMergePatch(patch mergePatch, mergeKeys map[string]string) (matches bool, err error) {
 	if !r.matches(patch.matchInfo) {
 		return false, nil
 	}
+	if len(mergeKeys) > 0 {
+		patched, err := mergeWithKeys(r.json, patch.json, mergeKeys)
+		if err != nil {
+			return true, err
+		}
+		r.json = patched
+		return true, nil
+	}
 	patched, err := jsonpatch.MergePatch(r.json, patch.json)
 	if err != nil {
 		return true, errors.WithStack(err)
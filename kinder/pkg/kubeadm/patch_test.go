@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildMany(t *testing.T) {
+	var tests = []struct {
+		name          string
+		specs         []BuildSpec
+		schemas       []string
+		expected      []string
+		expectedError string
+	}{
+		{
+			name: "results are in input order and share the schemas",
+			specs: []BuildSpec{
+				{
+					ToPatch: testWidget,
+					Patches: []string{"apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n  - name: http\n    port: 8080\n"},
+				},
+				{
+					ToPatch: "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports: []\n",
+				},
+			},
+			schemas: []string{testOpenAPISchema},
+			expected: []string{
+				"apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n" +
+					"  - name: http\n    port: 8080\n" +
+					"  - name: https\n    port: 443\n",
+				"apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports: []\n",
+			},
+		},
+		{
+			name: "errors are reported with the spec index",
+			specs: []BuildSpec{
+				{
+					ToPatch: "apiVersion: example.io/v1\nkind: Widget\n",
+				},
+				{
+					ToPatch: "apiVersion: example.io/v1\nkind: Widget\n",
+					Patches: []string{"kind: ["},
+				},
+			},
+			expected: []string{
+				"apiVersion: example.io/v1\nkind: Widget\n",
+				"",
+			},
+			expectedError: "spec 1:",
+		},
+		{
+			name:          "invalid schema",
+			specs:         []BuildSpec{{ToPatch: testWidget}},
+			schemas:       []string{"foo: bar\n"},
+			expectedError: "failed to parse openapi schemas",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := BuildMany(test.specs, test.schemas)
+			if (err != nil) != (test.expectedError != "") {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError != "", err != nil, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), test.expectedError) {
+				t.Fatalf("expected error containing %q, found %v", test.expectedError, err)
+			}
+			if !reflect.DeepEqual(results, test.expected) {
+				t.Fatalf("expected:\n%q\nfound:\n%q", test.expected, results)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// knownPatchTargets defines the targets kubeadm accepts for files in the patches directory
+var knownPatchTargets = []string{
+	"etcd",
+	"kube-apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+	"kubeletconfiguration",
+	"corednsdeployment",
+}
+
+// knownPatchTypes defines the patch types kubeadm accepts for files in the patches directory
+var knownPatchTypes = []string{
+	"strategic",
+	"merge",
+	"json",
+}
+
+// NodeConfigPatchFile is the name of the file, in a node specific patches directory, containing
+// patches for the kubeadm config generated for that node only
+const NodeConfigPatchFile = "kubeadm-config.yaml"
+
+// ValidatePatchesDir checks that all the files in a patches directory can be consumed by kubeadm,
+// so it is possible to detect malformed patches before copying them to the nodes.
+//
+// Patch files are expected to be in the form target[suffix][+patchtype].extension, as documented in
+// https://kubernetes.io/docs/setup/production-environment/tools/kubeadm/control-plane-flags/#patches,
+// and their content should be valid YAML or JSON for the given patch type.
+//
+// Sub directories of the patches directory are node specific patches directories, named after the node
+// they apply to; they can contain patch files as well as a NodeConfigPatchFile with patches for the
+// kubeadm config of the node. Sub directories not matching any of the given node names are rejected,
+// because otherwise a typo in the node name silently skips the patches.
+func ValidatePatchesDir(dir string, nodeNames []string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read patches directory %s", dir)
+	}
+
+	knownNodes := map[string]bool{}
+	for _, n := range nodeNames {
+		knownNodes[n] = true
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			if !knownNodes[file.Name()] {
+				return errors.Errorf("the patches directory %s does not match any node. Use one of %v", file.Name(), nodeNames)
+			}
+			if err := validateNodePatchesDir(filepath.Join(dir, file.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := validatePatchFile(filepath.Join(dir, file.Name())); err != nil {
+			return errors.Wrapf(err, "invalid patch file %s", file.Name())
+		}
+	}
+
+	return nil
+}
+
+// validateNodePatchesDir checks the content of a node specific patches directory
+func validateNodePatchesDir(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read patches directory %s", dir)
+	}
+
+	for _, file := range files {
+		// kubeadm only reads files at the first level of the patches directory
+		if file.IsDir() {
+			continue
+		}
+
+		name := filepath.Join(filepath.Base(dir), file.Name())
+		if file.Name() == NodeConfigPatchFile {
+			if _, err := readConfigPatches(filepath.Join(dir, file.Name())); err != nil {
+				return errors.Wrapf(err, "invalid patch file %s", name)
+			}
+			continue
+		}
+
+		if err := validatePatchFile(filepath.Join(dir, file.Name())); err != nil {
+			return errors.Wrapf(err, "invalid patch file %s", name)
+		}
+	}
+
+	return nil
+}
+
+// NodeConfigPatches returns the patches for the kubeadm config of a node, if any, read from
+// the NodeConfigPatchFile in the node specific patches directory
+func NodeConfigPatches(dir, nodeName string) ([]string, error) {
+	if len(dir) == 0 {
+		return nil, nil
+	}
+
+	path := filepath.Join(dir, nodeName, NodeConfigPatchFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	patches, err := readConfigPatches(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid patch file %s", filepath.Join(nodeName, NodeConfigPatchFile))
+	}
+	return patches, nil
+}
+
+// readConfigPatches reads a file with patches for the kubeadm config; each YAML document
+// in the file should have apiVersion and kind set, so it is possible to match the patch
+// with the kubeadm config object to patch
+func readConfigPatches(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file")
+	}
+
+	documents, err := splitYAMLDocuments(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var patches []string
+	for _, document := range documents {
+		if len(strings.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		m, err := parseYAMLMatchInfo(document)
+		if err != nil {
+			return nil, err
+		}
+		if m.APIVersion == "" || m.Kind == "" {
+			return nil, errors.New("kubeadm config patches should have apiVersion and kind set")
+		}
+
+		patches = append(patches, document)
+	}
+	return patches, nil
+}
+
+// validatePatchFile checks the name and the content of a single patch file
+func validatePatchFile(path string) error {
+	patchType, err := parsePatchFileName(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read file")
+	}
+
+	documents, err := splitYAMLDocuments(string(data))
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		if len(strings.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		patchJSON, err := yaml.YAMLToJSON([]byte(document))
+		if err != nil {
+			return errors.Wrap(err, "failed to parse patch")
+		}
+
+		if patchType == "json" {
+			if _, err := jsonpatch.DecodePatch(patchJSON); err != nil {
+				return errors.Wrap(err, "failed to parse JSON patch")
+			}
+			continue
+		}
+
+		var patch map[string]interface{}
+		if err := yaml.Unmarshal(patchJSON, &patch); err != nil {
+			return errors.Wrapf(err, "failed to parse %s patch", patchType)
+		}
+	}
+
+	return nil
+}
+
+// parsePatchFileName checks that a file name is in the form target[suffix][+patchtype].extension
+// and returns the patch type
+func parsePatchFileName(fileName string) (string, error) {
+	extension := filepath.Ext(fileName)
+	if extension != ".yaml" && extension != ".json" {
+		return "", errors.Errorf("unknown extension %q. Use one of [.yaml, .json]", extension)
+	}
+	name := strings.TrimSuffix(fileName, extension)
+
+	// the patch type defaults to strategic, if not set
+	patchType := "strategic"
+	if i := strings.Index(name, "+"); i >= 0 {
+		patchType = name[i+1:]
+		name = name[:i]
+		if !contains(knownPatchTypes, patchType) {
+			return "", errors.Errorf("unknown patch type %q. Use one of %s", patchType, knownPatchTypes)
+		}
+	}
+
+	for _, target := range knownPatchTargets {
+		if strings.HasPrefix(name, target) {
+			return patchType, nil
+		}
+	}
+
+	return "", errors.Errorf("unknown patch target %q. Use one of %s", name, knownPatchTargets)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
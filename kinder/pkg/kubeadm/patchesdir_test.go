@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidatePatchesDir(t *testing.T) {
+	var tests = []struct {
+		name          string
+		files         map[string]string
+		expectedError string
+	}{
+		{
+			name: "valid patches",
+			files: map[string]string{
+				"kube-apiserver.yaml":            "metadata:\n  annotations:\n    foo: bar\n",
+				"etcd0+merge.yaml":               "spec:\n  priority: 0\n",
+				"kubeletconfiguration+json.json": `[{"op": "add", "path": "/maxPods", "value": 100}]`,
+			},
+		},
+		{
+			name: "valid node specific patches",
+			files: map[string]string{
+				"kube-apiserver.yaml":                     "metadata:\n  annotations:\n    foo: bar\n",
+				"kind-worker-1/kubeletconfiguration.yaml": "maxPods: 100\n",
+				"kind-worker-1/kubeadm-config.yaml":       "apiVersion: kubeadm.k8s.io/v1beta3\nkind: JoinConfiguration\nnodeRegistration:\n  taints: []\n",
+			},
+		},
+		{
+			name: "node specific patch with unknown target",
+			files: map[string]string{
+				"kind-worker-1/kube-foo.yaml": "metadata: {}\n",
+			},
+			expectedError: "kind-worker-1/kube-foo.yaml",
+		},
+		{
+			name: "node specific kubeadm config patch without kind",
+			files: map[string]string{
+				"kind-worker-1/kubeadm-config.yaml": "apiVersion: kubeadm.k8s.io/v1beta3\nnodeRegistration:\n  taints: []\n",
+			},
+			expectedError: "kind-worker-1/kubeadm-config.yaml",
+		},
+		{
+			name: "node specific patches for an unknown node",
+			files: map[string]string{
+				"kind-wroker-1/kubeletconfiguration.yaml": "maxPods: 100\n",
+			},
+			expectedError: "kind-wroker-1",
+		},
+		{
+			name: "unknown target",
+			files: map[string]string{
+				"kube-foo.yaml": "metadata: {}\n",
+			},
+			expectedError: "kube-foo.yaml",
+		},
+		{
+			name: "unknown patch type",
+			files: map[string]string{
+				"kube-scheduler+foo.yaml": "metadata: {}\n",
+			},
+			expectedError: "kube-scheduler+foo.yaml",
+		},
+		{
+			name: "unknown extension",
+			files: map[string]string{
+				"kube-scheduler.txt": "metadata: {}\n",
+			},
+			expectedError: "kube-scheduler.txt",
+		},
+		{
+			name: "malformed yaml",
+			files: map[string]string{
+				"kube-controller-manager.yaml": "metadata:\n  - foo\n bar: [\n",
+			},
+			expectedError: "kube-controller-manager.yaml",
+		},
+		{
+			name: "json patch is not a list of operations",
+			files: map[string]string{
+				"etcd+json.yaml": "metadata: {}\n",
+			},
+			expectedError: "etcd+json.yaml",
+		},
+		{
+			name: "strategic patch is not a map",
+			files: map[string]string{
+				"etcd.yaml": "- foo\n- bar\n",
+			},
+			expectedError: "etcd.yaml",
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range rt.files {
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700); err != nil {
+					t.Fatalf("couldn't create directory for file %s: %v", name, err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+					t.Fatalf("couldn't write to file %s: %v", name, err)
+				}
+			}
+
+			err := ValidatePatchesDir(dir, []string{"kind-control-plane-1", "kind-worker-1"})
+			if (err != nil) != (rt.expectedError != "") {
+				t.Fatalf("failed ValidatePatchesDir:\n\texpected error: %q\n\tactual error: %v", rt.expectedError, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), rt.expectedError) {
+				t.Errorf("failed ValidatePatchesDir:\n\texpected error containing: %q\n\tactual error: %v", rt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNodeConfigPatches(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "kind-worker-1"), 0700); err != nil {
+		t.Fatalf("couldn't create node patches directory: %v", err)
+	}
+	patch := "apiVersion: kubeadm.k8s.io/v1beta3\nkind: JoinConfiguration\nnodeRegistration:\n  taints: []\n"
+	if err := os.WriteFile(filepath.Join(dir, "kind-worker-1", NodeConfigPatchFile), []byte("---\n"+patch), 0600); err != nil {
+		t.Fatalf("couldn't write node config patch: %v", err)
+	}
+
+	var tests = []struct {
+		name     string
+		dir      string
+		node     string
+		expected int
+	}{
+		{
+			name:     "node with patches",
+			dir:      dir,
+			node:     "kind-worker-1",
+			expected: 1,
+		},
+		{
+			name: "node without patches",
+			dir:  dir,
+			node: "kind-worker-2",
+		},
+		{
+			name: "patches directory not set",
+			node: "kind-worker-1",
+		},
+	}
+
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			patches, err := NodeConfigPatches(rt.dir, rt.node)
+			if err != nil {
+				t.Fatalf("failed NodeConfigPatches: %v", err)
+			}
+			if len(patches) != rt.expected {
+				t.Errorf("failed NodeConfigPatches:\n\texpected: %d patches\n\tactual: %d", rt.expected, len(patches))
+			}
+		})
+	}
+}
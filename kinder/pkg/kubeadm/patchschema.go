@@ -0,0 +1,221 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+patchschema.go provides support for openapi schemas describing custom resources, so merge patches
+can merge list fields by their patch merge key like kustomize strategic merge patches do,
+instead of replacing the whole list.
+*/
+
+// maxSchemaDepth limits the walk of openapi schemas, that can be recursive via $ref
+const maxSchemaDepth = 32
+
+// openAPISchema represents the subset of an openapi v2 document used for detecting merge keys
+type openAPISchema struct {
+	Definitions map[string]map[string]interface{} `json:"definitions"`
+}
+
+// parseOpenAPISchemas parses the given openapi schemas and returns, for each resource type, the
+// list fields with a merge key; list fields are identified by the dot separated path of the field,
+// with "[]" marking list items e.g. spec.containers[].ports.
+func parseOpenAPISchemas(schemas []string) (map[matchInfo]map[string]string, error) {
+	mergeKeys := map[matchInfo]map[string]string{}
+	for _, raw := range schemas {
+		if err := parseOpenAPISchema(raw, mergeKeys); err != nil {
+			return nil, err
+		}
+	}
+	return mergeKeys, nil
+}
+
+// parseOpenAPISchema parses an openapi v2 document in JSON or YAML form, e.g. the document referenced
+// by the kustomize openapi field; only definitions with x-kubernetes-group-version-kind are considered
+// as resource types, while other definitions can be referenced via $ref. List fields are merged by key
+// if they have x-kubernetes-patch-strategy: merge and x-kubernetes-patch-merge-key.
+func parseOpenAPISchema(raw string, mergeKeys map[matchInfo]map[string]string) error {
+	schema := openAPISchema{}
+	if err := yaml.Unmarshal([]byte(raw), &schema); err != nil {
+		return errors.Wrap(err, "failed to parse openapi schema")
+	}
+	if len(schema.Definitions) == 0 {
+		return errors.New("invalid openapi schema: no definitions found")
+	}
+
+	for name, definition := range schema.Definitions {
+		gvks, _ := definition["x-kubernetes-group-version-kind"].([]interface{})
+		for _, item := range gvks {
+			gvk, _ := item.(map[string]interface{})
+			group, _ := gvk["group"].(string)
+			version, _ := gvk["version"].(string)
+			kind, _ := gvk["kind"].(string)
+			if version == "" || kind == "" {
+				return errors.Errorf("invalid x-kubernetes-group-version-kind in definition %q", name)
+			}
+
+			m := matchInfo{Kind: kind, APIVersion: groupVersionToAPIVersion(group, version)}
+			if mergeKeys[m] == nil {
+				mergeKeys[m] = map[string]string{}
+			}
+			collectMergeKeys(definition, "", schema.Definitions, mergeKeys[m], 0)
+		}
+	}
+	return nil
+}
+
+// collectMergeKeys walks a schema and collects the merge keys of list fields
+func collectMergeKeys(schema map[string]interface{}, path string, definitions map[string]map[string]interface{}, mergeKeys map[string]string, depth int) {
+	if depth > maxSchemaDepth {
+		return
+	}
+	schema = resolveSchemaRef(schema, definitions)
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, p := range properties {
+		property, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		property = resolveSchemaRef(property, definitions)
+		propertyPath := joinSchemaPath(path, name)
+
+		if items, ok := property["items"].(map[string]interface{}); ok {
+			strategy, _ := property["x-kubernetes-patch-strategy"].(string)
+			key, _ := property["x-kubernetes-patch-merge-key"].(string)
+			if key != "" && hasPatchStrategy(strategy, "merge") {
+				mergeKeys[propertyPath] = key
+			}
+			collectMergeKeys(items, propertyPath+"[]", definitions, mergeKeys, depth+1)
+			continue
+		}
+		collectMergeKeys(property, propertyPath, definitions, mergeKeys, depth+1)
+	}
+}
+
+// resolveSchemaRef returns the definition referenced by the schema, if any, e.g. {"$ref": "#/definitions/foo"}
+func resolveSchemaRef(schema map[string]interface{}, definitions map[string]map[string]interface{}) map[string]interface{} {
+	ref, _ := schema["$ref"].(string)
+	if ref == "" {
+		return schema
+	}
+	if definition, ok := definitions[strings.TrimPrefix(ref, "#/definitions/")]; ok {
+		return definition
+	}
+	return schema
+}
+
+// hasPatchStrategy returns true if a comma separated list of patch strategies includes the given strategy
+func hasPatchStrategy(strategies, strategy string) bool {
+	for _, s := range strings.Split(strategies, ",") {
+		if strings.TrimSpace(s) == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+func joinSchemaPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// mergeWithKeys applies a RFC 7386 merge patch to a JSON document, with the exception that
+// list fields with a merge key are merged item by item, like kustomize strategic merge patches do
+func mergeWithKeys(original, patch []byte, mergeKeys map[string]string) ([]byte, error) {
+	var o, p interface{}
+	if err := json.Unmarshal(original, &o); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the document to patch")
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the patch")
+	}
+	merged, err := json.Marshal(mergeValue(o, p, "", mergeKeys))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the patched document")
+	}
+	return merged, nil
+}
+
+func mergeValue(original, patch interface{}, path string, mergeKeys map[string]string) interface{} {
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		o, ok := original.(map[string]interface{})
+		if !ok {
+			o = map[string]interface{}{}
+		}
+		for field, value := range p {
+			if value == nil {
+				delete(o, field)
+				continue
+			}
+			o[field] = mergeValue(o[field], value, joinSchemaPath(path, field), mergeKeys)
+		}
+		return o
+	case []interface{}:
+		key, ok := mergeKeys[path]
+		o, isList := original.([]interface{})
+		if !ok || !isList {
+			return p
+		}
+		return mergeList(o, p, path, key, mergeKeys)
+	default:
+		return patch
+	}
+}
+
+// mergeList merges the patch items into the original list; items with the same value for the merge key
+// are merged, while other items are appended
+func mergeList(original, patch []interface{}, path, key string, mergeKeys map[string]string) []interface{} {
+	merged := append([]interface{}{}, original...)
+	for _, item := range patch {
+		i := indexOfMergeKey(merged, key, item)
+		if i < 0 {
+			merged = append(merged, item)
+			continue
+		}
+		merged[i] = mergeValue(merged[i], item, path+"[]", mergeKeys)
+	}
+	return merged
+}
+
+func indexOfMergeKey(list []interface{}, key string, item interface{}) int {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return -1
+	}
+	value, ok := m[key]
+	if !ok {
+		return -1
+	}
+	for i, o := range list {
+		if om, ok := o.(map[string]interface{}); ok && reflect.DeepEqual(om[key], value) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+)
+
+const testOpenAPISchema = `definitions:
+  io.example.v1.Widget:
+    x-kubernetes-group-version-kind:
+    - group: example.io
+      version: v1
+      kind: Widget
+    properties:
+      spec:
+        $ref: '#/definitions/io.example.v1.WidgetSpec'
+  io.example.v1.WidgetSpec:
+    properties:
+      ports:
+        type: array
+        x-kubernetes-patch-strategy: merge
+        x-kubernetes-patch-merge-key: name
+        items:
+          properties:
+            name:
+              type: string
+            port:
+              type: integer
+`
+
+const testWidget = `apiVersion: example.io/v1
+kind: Widget
+spec:
+  ports:
+  - name: http
+    port: 80
+  - name: https
+    port: 443
+`
+
+func TestBuildWithSchemas(t *testing.T) {
+	var tests = []struct {
+		name          string
+		schemas       []string
+		patch         string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:  "lists are replaced without schemas",
+			patch: "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n  - name: http\n    port: 8080\n",
+			expected: "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n" +
+				"  - name: http\n    port: 8080\n",
+		},
+		{
+			name:    "lists are merged by key with schemas",
+			schemas: []string{testOpenAPISchema},
+			patch:   "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n  - name: http\n    port: 8080\n  - name: metrics\n    port: 9090\n",
+			expected: "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n" +
+				"  - name: http\n    port: 8080\n" +
+				"  - name: https\n    port: 443\n" +
+				"  - name: metrics\n    port: 9090\n",
+		},
+		{
+			name:    "null deletes fields of merged items",
+			schemas: []string{testOpenAPISchema},
+			patch:   "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n  - name: https\n    port: null\n",
+			expected: "apiVersion: example.io/v1\nkind: Widget\nspec:\n  ports:\n" +
+				"  - name: http\n    port: 80\n" +
+				"  - name: https\n",
+		},
+		{
+			name:          "invalid schema",
+			schemas:       []string{"foo: bar\n"},
+			patch:         "apiVersion: example.io/v1\nkind: Widget\n",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := BuildWithSchemas(testWidget, []string{test.patch}, nil, test.schemas)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if test.expectedError {
+				return
+			}
+			if result != test.expected {
+				t.Fatalf("expected:\n%s\nfound:\n%s", test.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+)
+
+// preflightCheck defines a kubeadm preflight check that can be ignored by name; if removedIn is set,
+// the check does not exist in kubeadm versions greater or equal than it
+type preflightCheck struct {
+	name      string
+	removedIn string
+}
+
+// knownPreflightChecks lists the kubeadm preflight checks with a fixed name
+var knownPreflightChecks = []preflightCheck{
+	{name: "CRI"},
+	{name: "ExternalEtcdVersion"},
+	{name: "Firewalld"},
+	{name: "HTTPProxy"},
+	{name: "HTTPProxyCIDR"},
+	{name: "Hostname"},
+	{name: "ImagePull"},
+	{name: "IsDockerSystemdCheck", removedIn: "v1.24.0"},
+	{name: "IsPrivilegedUser"},
+	{name: "KubeletVersion"},
+	{name: "KubernetesVersion"},
+	{name: "Mem"},
+	{name: "NumCPU"},
+	{name: "Service-Docker", removedIn: "v1.24.0"},
+	{name: "Service-Kubelet"},
+	{name: "Swap"},
+	{name: "SystemVerification"},
+}
+
+// preflightCheckPrefixes lists the prefixes of the kubeadm preflight checks named after a port or a path,
+// e.g. Port-6443 or FileContent--proc-sys-net-bridge-bridge-nf-call-iptables
+var preflightCheckPrefixes = []string{
+	"DirAvailable--",
+	"FileAvailable--",
+	"FileContent--",
+	"FileExisting-",
+	"Port-",
+}
+
+// ValidateIgnorePreflightErrors checks a comma separated list of preflight errors to ignore against the checks
+// known for the given kubeadm version; if the version is nil, checks removed in some version are accepted.
+// Like in kubeadm, names are case insensitive and all can't be combined with other names.
+func ValidateIgnorePreflightErrors(ignorePreflightErrors string, kubeadmVersion *K8sVersion.Version) error {
+	names := strings.Split(ignorePreflightErrors, ",")
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return errors.Errorf("invalid preflight errors %q. Use a comma separated list of preflight check names", ignorePreflightErrors)
+		}
+		if strings.EqualFold(name, "all") {
+			if len(names) > 1 {
+				return errors.New("don't specify individual preflight errors if all is used")
+			}
+			continue
+		}
+		if !isKnownPreflightCheck(name, kubeadmVersion) {
+			version := "any version"
+			if kubeadmVersion != nil {
+				version = "v" + kubeadmVersion.String()
+			}
+			return errors.Errorf("unknown preflight error %q for kubeadm %s. Use all, one of %s, or a name starting with one of %s",
+				name, version, strings.Join(KnownPreflightChecks(kubeadmVersion), ", "), strings.Join(preflightCheckPrefixes, ", "))
+		}
+	}
+	return nil
+}
+
+// KnownPreflightChecks returns the sorted names of the kubeadm preflight checks with a fixed name
+// existing in the given kubeadm version; if the version is nil, all the checks are returned
+func KnownPreflightChecks(kubeadmVersion *K8sVersion.Version) []string {
+	var names []string
+	for _, c := range knownPreflightChecks {
+		if c.removedIn != "" && kubeadmVersion != nil && kubeadmVersion.AtLeast(K8sVersion.MustParseSemantic(c.removedIn)) {
+			continue
+		}
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isKnownPreflightCheck returns true if the name matches a known kubeadm preflight check
+func isKnownPreflightCheck(name string, kubeadmVersion *K8sVersion.Version) bool {
+	lower := strings.ToLower(name)
+	for _, prefix := range preflightCheckPrefixes {
+		if strings.HasPrefix(lower, strings.ToLower(prefix)) && len(lower) > len(prefix) {
+			return true
+		}
+	}
+	for _, known := range KnownPreflightChecks(kubeadmVersion) {
+		if strings.EqualFold(name, known) {
+			return true
+		}
+	}
+	return false
+}
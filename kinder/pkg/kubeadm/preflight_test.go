@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+
+	K8sVersion "k8s.io/apimachinery/pkg/util/version"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+func TestValidateIgnorePreflightErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		version       string
+		expectedError bool
+	}{
+		{name: "kinder default", value: constants.KubeadmIgnorePreflightErrors, version: "v1.30.0"},
+		{name: "all", value: "all", version: "v1.30.0"},
+		{name: "case insensitive", value: "swap,port-6443", version: "v1.30.0"},
+		{name: "checks named after a path", value: "DirAvailable--etc-kubernetes-manifests,FileExisting-crictl"},
+		{name: "all combined with other names", value: "all,Swap", expectedError: true},
+		{name: "unknown check", value: "Swap,Foo", version: "v1.30.0", expectedError: true},
+		{name: "prefix only", value: "Port-", expectedError: true},
+		{name: "empty name", value: "Swap,,Mem", expectedError: true},
+		{name: "check removed in the kubeadm version", value: "Service-Docker", version: "v1.30.0", expectedError: true},
+		{name: "check existing in the kubeadm version", value: "Service-Docker", version: "v1.23.17"},
+		{name: "unknown kubeadm version", value: "Service-Docker"},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			var version *K8sVersion.Version
+			if rt.version != "" {
+				version = K8sVersion.MustParseSemantic(rt.version)
+			}
+			err := ValidateIgnorePreflightErrors(rt.value, version)
+			if (err != nil) != rt.expectedError {
+				t.Errorf("expected error %t, got %v", rt.expectedError, err)
+			}
+		})
+	}
+}
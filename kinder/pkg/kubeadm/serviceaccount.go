@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ServiceAccountKeyPair parses a PEM encoded service account signing key, RSA or ECDSA, and returns the
+// private key and the public key in the PEM form kubeadm expects in the sa.key and sa.pub files; when
+// both files exist, kubeadm reuses them instead of generating a new signing key.
+func ServiceAccountKeyPair(keyPEM []byte) (key, pub []byte, err error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, errors.New("the service account key is not PEM encoded")
+	}
+
+	var signer crypto.Signer
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		signer, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		signer, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var k interface{}
+		k, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			switch k := k.(type) {
+			case *rsa.PrivateKey:
+				signer = k
+			case *ecdsa.PrivateKey:
+				signer = k
+			default:
+				err = errors.Errorf("unsupported private key type %T. Use an RSA or ECDSA key", k)
+			}
+		}
+	default:
+		err = errors.Errorf("unsupported PEM block type %q. Use a private key", block.Type)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse the service account key")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to encode the service account public key")
+	}
+	return pem.EncodeToMemory(block), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ValidateServiceAccountIssuer checks the service account issuer; like the API server, issuers containing
+// a colon must be URLs, and OIDC discovery additionally requires the https scheme
+func ValidateServiceAccountIssuer(issuer string) error {
+	if strings.TrimSpace(issuer) != issuer || issuer == "" || strings.Contains(issuer, ",") {
+		return errors.Errorf("invalid service account issuer %q", issuer)
+	}
+	if !strings.Contains(issuer, ":") {
+		return nil
+	}
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return errors.Wrapf(err, "invalid service account issuer %q", issuer)
+	}
+	if u.Scheme != "https" {
+		return errors.Errorf("invalid service account issuer %q. Use an https URL", issuer)
+	}
+	if u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.Errorf("invalid service account issuer %q. Use an https URL with a host and without query or fragment", issuer)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+)
+
+func TestServiceAccountKeyPair(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		key           []byte
+		public        interface{}
+		expectedError bool
+	}{
+		{
+			name:   "RSA key",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			public: &rsaKey.PublicKey,
+		},
+		{
+			name:   "ECDSA key",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+			public: &ecKey.PublicKey,
+		},
+		{
+			name:   "PKCS8 key",
+			key:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER}),
+			public: &rsaKey.PublicKey,
+		},
+		{
+			name:          "not PEM",
+			key:           []byte("foo"),
+			expectedError: true,
+		},
+		{
+			name:          "not a private key",
+			key:           pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("foo")}),
+			expectedError: true,
+		},
+		{
+			name:          "invalid key",
+			key:           pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("foo")}),
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, pub, err := ServiceAccountKeyPair(test.key)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+			if err != nil {
+				return
+			}
+			if string(key) != string(test.key) {
+				t.Errorf("expected key:\n%s\nfound:\n%s", test.key, key)
+			}
+			block, _ := pem.Decode(pub)
+			if block == nil || block.Type != "PUBLIC KEY" {
+				t.Fatalf("expected a PUBLIC KEY PEM block, found:\n%s", pub)
+			}
+			public, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(public, test.public) {
+				t.Errorf("the public key does not match the private key")
+			}
+		})
+	}
+}
+
+func TestValidateServiceAccountIssuer(t *testing.T) {
+	tests := []struct {
+		issuer        string
+		expectedError bool
+	}{
+		{issuer: "https://issuer.example.com"},
+		{issuer: "https://issuer.example.com/path"},
+		{issuer: "kubernetes-issuer"},
+		{issuer: "", expectedError: true},
+		{issuer: " https://issuer.example.com", expectedError: true},
+		{issuer: "https://a.example.com,https://b.example.com", expectedError: true},
+		{issuer: "http://issuer.example.com", expectedError: true},
+		{issuer: "https://issuer.example.com?foo=bar", expectedError: true},
+		{issuer: "https://", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.issuer, func(t *testing.T) {
+			err := ValidateServiceAccountIssuer(test.issuer)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v, error: %v", test.expectedError, err != nil, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// GetKubeletServingCertRotationPatch returns the KubeletConfiguration patch that will instruct the kubelet to request
+// its serving certificate to the controller manager, instead of using a self-signed certificate, and to rotate it
+// when it is close to expiration; the certificate signing requests must be approved, because differently from
+// the kubelet client certificates requests, they are not approved by the controller manager.
+func GetKubeletServingCertRotationPatch() string {
+	log.Debug("Preparing kubelet serving certificate rotation patch")
+
+	return kubeletServingCertRotationPatch
+}
+
+const kubeletServingCertRotationPatch = `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+serverTLSBootstrap: true
+rotateCertificates: true
+`
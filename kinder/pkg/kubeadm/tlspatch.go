@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TLSMinVersionArg is the API server flag setting the TLS min version
+	TLSMinVersionArg = "tls-min-version"
+	// TLSCipherSuitesArg is the API server flag setting the TLS cipher suites
+	TLSCipherSuitesArg = "tls-cipher-suites"
+)
+
+// tlsVersions defines the TLS versions supported by the API server --tls-min-version flag
+var tlsVersions = map[string]uint16{
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+	"VersionTLS13": tls.VersionTLS13,
+}
+
+// ValidateAPIServerTLS validates the TLS min version and the cipher suites for the API server; cipher suites
+// for TLS 1.2 and lower versions are bound to the key type, so they must be compatible with the encryption
+// algorithm used by kubeadm for generating the private keys, that defaults to RSA-2048.
+func ValidateAPIServerTLS(encryptionAlgorithm, minVersion string, cipherSuites []string) error {
+	if err := ValidateAPIServerTLSSettings(minVersion, cipherSuites); err != nil {
+		return err
+	}
+
+	keyType := "RSA"
+	if strings.HasPrefix(encryptionAlgorithm, "ECDSA") {
+		keyType = "ECDSA"
+	}
+
+	for _, name := range cipherSuites {
+		if suiteKeyType := cipherSuiteKeyType(name); suiteKeyType != "" && suiteKeyType != keyType {
+			return errors.Errorf("cipher suite %s requires %s keys, but the %s keys are generated with encryption algorithm %q", name, suiteKeyType, keyType, encryptionAlgorithm)
+		}
+	}
+	return nil
+}
+
+// ValidateAPIServerTLSSettings validates the TLS min version and the cipher suites for the API server without
+// checking the key type, e.g. at create time, when the encryption algorithm used by kubeadm is not known yet
+func ValidateAPIServerTLSSettings(minVersion string, cipherSuites []string) error {
+	version := uint16(tls.VersionTLS10)
+	if minVersion != "" {
+		v, ok := tlsVersions[minVersion]
+		if !ok {
+			return errors.Errorf("invalid TLS min version %q. Use one of [VersionTLS10, VersionTLS11, VersionTLS12, VersionTLS13]", minVersion)
+		}
+		version = v
+	}
+
+	// the API server does not allow to configure TLS 1.3 cipher suites
+	if version == tls.VersionTLS13 && len(cipherSuites) > 0 {
+		return errors.New("cipher suites can't be configured with TLS min version VersionTLS13")
+	}
+
+	known := map[string]bool{}
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[s.Name] = true
+	}
+	for _, name := range cipherSuites {
+		if !known[name] {
+			return errors.Errorf("unknown cipher suite %q", name)
+		}
+	}
+	return nil
+}
+
+// cipherSuiteKeyType returns the key type required by a cipher suite; TLS 1.3 cipher suites
+// don't depend on the key type, so an empty string is returned
+func cipherSuiteKeyType(name string) string {
+	switch {
+	case strings.HasPrefix(name, "TLS_ECDHE_ECDSA_"):
+		return "ECDSA"
+	case strings.HasPrefix(name, "TLS_ECDHE_RSA_"), strings.HasPrefix(name, "TLS_RSA_"):
+		return "RSA"
+	}
+	return ""
+}
+
+// APIServerTLSArgs returns the API server extra arguments for the given TLS min version and cipher suites
+func APIServerTLSArgs(minVersion string, cipherSuites []string) []ExtraArg {
+	var args []ExtraArg
+	if minVersion != "" {
+		args = append(args, ExtraArg{Name: TLSMinVersionArg, Value: minVersion})
+	}
+	if len(cipherSuites) > 0 {
+		args = append(args, ExtraArg{Name: TLSCipherSuitesArg, Value: strings.Join(cipherSuites, ",")})
+	}
+	return args
+}
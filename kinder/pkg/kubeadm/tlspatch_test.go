@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+)
+
+func TestValidateAPIServerTLS(t *testing.T) {
+	var tests = []struct {
+		name                string
+		encryptionAlgorithm string
+		minVersion          string
+		cipherSuites        []string
+		expectedError       bool
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:         "RSA cipher suites with default RSA keys",
+			minVersion:   "VersionTLS12",
+			cipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		},
+		{
+			name:                "ECDSA cipher suites with ECDSA keys",
+			encryptionAlgorithm: "ECDSA-P256",
+			cipherSuites:        []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		},
+		{
+			name:          "ECDSA cipher suites with RSA keys",
+			cipherSuites:  []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+			expectedError: true,
+		},
+		{
+			name:                "RSA cipher suites with ECDSA keys",
+			encryptionAlgorithm: "ECDSA-P384",
+			cipherSuites:        []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			expectedError:       true,
+		},
+		{
+			name:          "unknown cipher suite",
+			cipherSuites:  []string{"TLS_FOO"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid min version",
+			minVersion:    "TLS12",
+			expectedError: true,
+		},
+		{
+			name:          "cipher suites with TLS 1.3",
+			minVersion:    "VersionTLS13",
+			cipherSuites:  []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateAPIServerTLS(test.encryptionAlgorithm, test.minVersion, test.cipherSuites)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Var defines a legacy kustomize var; the value of the field referenced by FieldRef in the object referenced
+// by ObjRef is substituted to $(Name) in all the string values of the documents.
+//
+// Vars are deprecated in kustomize in favor of replacements, and they are supported only for compatibility
+// with existing manifests; as in kustomize, $$(Name) can be used for escaping a var reference.
+type Var struct {
+	Name     string      `json:"name"`
+	ObjRef   VarObjRef   `json:"objref"`
+	FieldRef VarFieldRef `json:"fieldref,omitempty"`
+}
+
+// VarObjRef references the object holding the var value; APIVersion is optional, and Name
+// is matched with the object metadata.name
+type VarObjRef struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+// VarFieldRef references the field holding the var value, e.g. spec.ports[0].port;
+// if not set, metadata.name is used
+type VarFieldRef struct {
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+// substituteVars resolves the vars against the resources, and then substitutes them in all the resources
+func substituteVars(resources []resource, vars []Var) error {
+	log.Warn("kustomize vars are deprecated, please use replacements instead")
+
+	values := map[string]string{}
+	for _, v := range vars {
+		if v.Name == "" {
+			return errors.New("vars must have a name")
+		}
+		if _, ok := values[v.Name]; ok {
+			return errors.Errorf("var %q is defined more than once", v.Name)
+		}
+		value, err := resolveVar(resources, v)
+		if err != nil {
+			return err
+		}
+		values[v.Name] = value
+	}
+
+	for i := range resources {
+		var obj interface{}
+		if err := json.Unmarshal(resources[i].json, &obj); err != nil {
+			return errors.WithStack(err)
+		}
+		patched, err := json.Marshal(expandVars(obj, values))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		resources[i].json = patched
+	}
+	return nil
+}
+
+// resolveVar returns the value of a var, reading it from the only resource matching the var objref
+func resolveVar(resources []resource, v Var) (string, error) {
+	var target interface{}
+	matches := 0
+	for _, r := range resources {
+		if r.matchInfo.Kind != v.ObjRef.Kind || (v.ObjRef.APIVersion != "" && r.matchInfo.APIVersion != v.ObjRef.APIVersion) {
+			continue
+		}
+		var obj interface{}
+		if err := json.Unmarshal(r.json, &obj); err != nil {
+			return "", errors.WithStack(err)
+		}
+		// objects without metadata.name, e.g. kubeadm config objects, are matched by an empty name
+		name, _ := getField(obj, "metadata.name")
+		if s, _ := name.(string); s != v.ObjRef.Name {
+			continue
+		}
+		target = obj
+		matches++
+	}
+	if matches != 1 {
+		return "", errors.Errorf("var %q must reference exactly one object, %d objects found for %s %q", v.Name, matches, v.ObjRef.Kind, v.ObjRef.Name)
+	}
+
+	fieldPath := v.FieldRef.FieldPath
+	if fieldPath == "" {
+		fieldPath = "metadata.name"
+	}
+	value, err := getField(target, fieldPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve var %q", v.Name)
+	}
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case float64:
+		// numbers are decoded as float64, so integers are formatted without exponent
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	}
+	return "", errors.Errorf("failed to resolve var %q: the field %s is not a string, number or boolean", v.Name, fieldPath)
+}
+
+// getField returns the value of a field in a decoded JSON object; the field path is a dot separated list
+// of field names, with optional list indexes, e.g. spec.ports[0].port
+func getField(obj interface{}, fieldPath string) (interface{}, error) {
+	current := obj
+	for _, part := range strings.Split(fieldPath, ".") {
+		name, indexes := part, []string{}
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+			if !strings.HasSuffix(part, "]") {
+				return nil, errors.Errorf("invalid field path %q", fieldPath)
+			}
+			indexes = strings.Split(part[i+1:len(part)-1], "][")
+		}
+
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("field %q not found in %q", name, fieldPath)
+		}
+		if current, ok = m[name]; !ok {
+			return nil, errors.Errorf("field %q not found in %q", name, fieldPath)
+		}
+
+		for _, index := range indexes {
+			l, ok := current.([]interface{})
+			if !ok {
+				return nil, errors.Errorf("field %q is not a list in %q", name, fieldPath)
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 || i >= len(l) {
+				return nil, errors.Errorf("invalid index %q for field %q in %q", index, name, fieldPath)
+			}
+			current = l[i]
+		}
+	}
+	return current, nil
+}
+
+// expandVars substitutes the var references in all the string values of a decoded JSON object
+func expandVars(obj interface{}, values map[string]string) interface{} {
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for k, v := range o {
+			o[k] = expandVars(v, values)
+		}
+	case []interface{}:
+		for i, v := range o {
+			o[i] = expandVars(v, values)
+		}
+	case string:
+		return expandString(o, values)
+	}
+	return obj
+}
+
+// expandString substitutes $(NAME) references with the var values; $$ is an escaped $,
+// and references to undefined vars are left unchanged
+func expandString(s string, values map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case '(':
+			if end := strings.IndexByte(s[i+2:], ')'); end >= 0 {
+				name := s[i+2 : i+2+end]
+				if value, ok := values[name]; ok {
+					b.WriteString(value)
+					i += 2 + end
+					continue
+				}
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeadm
+
+import (
+	"testing"
+)
+
+const testVarsManifests = `apiVersion: v1
+kind: Service
+metadata:
+  name: backend
+spec:
+  ports:
+  - port: 8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - name: frontend
+        env:
+        - name: BACKEND
+          value: $(BACKEND_SERVICE):$(BACKEND_PORT)
+        - name: ESCAPED
+          value: $$(BACKEND_SERVICE)
+        - name: UNDEFINED
+          value: $(UNDEFINED)
+`
+
+func TestBuildWithVars(t *testing.T) {
+	serviceVar := Var{
+		Name:   "BACKEND_SERVICE",
+		ObjRef: VarObjRef{APIVersion: "v1", Kind: "Service", Name: "backend"},
+	}
+	portVar := Var{
+		Name:     "BACKEND_PORT",
+		ObjRef:   VarObjRef{Kind: "Service", Name: "backend"},
+		FieldRef: VarFieldRef{FieldPath: "spec.ports[0].port"},
+	}
+
+	var tests = []struct {
+		name          string
+		patches       []string
+		vars          []Var
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "vars are substituted",
+			vars: []Var{serviceVar, portVar},
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: backend
+spec:
+  ports:
+  - port: 8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - env:
+        - name: BACKEND
+          value: backend:8080
+        - name: ESCAPED
+          value: $(BACKEND_SERVICE)
+        - name: UNDEFINED
+          value: $(UNDEFINED)
+        name: frontend
+`,
+		},
+		{
+			name: "vars are resolved after patches",
+			patches: []string{`apiVersion: v1
+kind: Service
+spec:
+  ports:
+  - port: 9090`},
+			vars: []Var{portVar},
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: backend
+spec:
+  ports:
+  - port: 9090
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - env:
+        - name: BACKEND
+          value: $(BACKEND_SERVICE):9090
+        - name: ESCAPED
+          value: $(BACKEND_SERVICE)
+        - name: UNDEFINED
+          value: $(UNDEFINED)
+        name: frontend
+`,
+		},
+		{
+			name:          "object not found",
+			vars:          []Var{{Name: "X", ObjRef: VarObjRef{Kind: "Service", Name: "foo"}}},
+			expectedError: true,
+		},
+		{
+			name:          "field not found",
+			vars:          []Var{{Name: "X", ObjRef: VarObjRef{Kind: "Service", Name: "backend"}, FieldRef: VarFieldRef{FieldPath: "spec.ports[1].port"}}},
+			expectedError: true,
+		},
+		{
+			name:          "field is not a scalar",
+			vars:          []Var{{Name: "X", ObjRef: VarObjRef{Kind: "Service", Name: "backend"}, FieldRef: VarFieldRef{FieldPath: "spec.ports"}}},
+			expectedError: true,
+		},
+		{
+			name:          "duplicated var",
+			vars:          []Var{serviceVar, serviceVar},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := BuildWithVars(testVarsManifests, test.patches, nil, test.vars)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if out != test.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"net"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// envoyConfigTemplate is the envoy loadbalancer config template; the endpoint hostnames are set
+// to the backend server names, so they are reported by the admin clusters endpoint, see parseEnvoyClusters
+const envoyConfigTemplate = `# generated by kinder
+# the admin interface is served on the loopback interface only, see envoyClustersScript
+admin:
+  address:
+    socket_address: { address: 127.0.0.1, port_value: 9901 }
+
+static_resources:
+  listeners:
+  - name: control-plane
+    address:
+      socket_address: { address: "{{ if .IPv6 }}::{{ else }}0.0.0.0{{ end }}", port_value: {{ .ControlPlanePort }}{{ if .IPv6 }}, ipv4_compat: true{{ end }} }
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.tcp_proxy
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy
+          stat_prefix: kube-apiservers
+          cluster: kube-apiservers
+
+  clusters:
+  - name: kube-apiservers
+    connect_timeout: 5s
+    type: STATIC
+    lb_policy: ROUND_ROBIN
+    health_checks:
+    - timeout: 2s
+      interval: 2s
+      no_traffic_interval: 2s
+      unhealthy_threshold: 2
+      healthy_threshold: 1
+      tcp_health_check: {}
+    load_assignment:
+      cluster_name: kube-apiservers
+      endpoints:
+      - lb_endpoints:
+        {{- range $server, $address := .BackendServers }}
+        - endpoint:
+            hostname: {{ $server }}
+            address:
+              socket_address: { address: "{{ host $address }}", port_value: {{ port $address }} }
+        {{- end }}
+`
+
+// envoyClustersScript reads the envoy admin clusters endpoint; it uses bash, because
+// neither curl nor wget are available in the envoy image
+const envoyClustersScript = `exec 3<>/dev/tcp/127.0.0.1/9901 && printf 'GET /clusters HTTP/1.0\r\n\r\n' >&3 && cat <&3`
+
+// envoyProvider implements Provider for envoy
+type envoyProvider struct{}
+
+func (envoyProvider) Image() string { return constants.EnvoyLoadBalancerImage }
+
+func (envoyProvider) ConfigPath() string { return constants.EnvoyLoadBalancerConfigPath }
+
+func (envoyProvider) Config(data *ConfigData) (string, error) {
+	return executeTemplate("envoy-config", envoyConfigTemplate, template.FuncMap{
+		"host": func(address string) (string, error) {
+			host, _, err := net.SplitHostPort(address)
+			return host, err
+		},
+		"port": func(address string) (string, error) {
+			_, port, err := net.SplitHostPort(address)
+			return port, err
+		},
+	}, data)
+}
+
+func (envoyProvider) ValidateCommand() []string {
+	return []string{"envoy", "--mode", "validate", "-c", constants.EnvoyLoadBalancerConfigPath}
+}
+
+// GracefulReload returns false, because envoy doesn't reload a static config file;
+// the envoy hot restart requires a wrapper script that isn't part of the envoy image
+func (envoyProvider) GracefulReload() bool { return false }
+
+// Backends reads the envoy admin clusters endpoint, reporting the status of the envoy health checks
+func (envoyProvider) Backends(run RunFunc) ([]BackendServer, error) {
+	lines, err := run("bash", "-c", envoyClustersScript)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the envoy clusters")
+	}
+	return parseEnvoyClusters(lines)
+}
+
+// parseEnvoyClusters parses the output of the envoy admin clusters endpoint, and returns the API server backends;
+// each line has the cluster::address::key::value form, and the status of an endpoint is defined by the
+// health_flags key, e.g. healthy or /failed_active_hc
+func parseEnvoyClusters(lines []string) ([]BackendServer, error) {
+	names := map[string]string{}
+	flags := map[string]string{}
+	seen := map[string]bool{}
+	addresses := []string{}
+	for _, line := range lines {
+		fields := strings.Split(strings.TrimSpace(line), "::")
+		if len(fields) != 4 || fields[0] != backendName {
+			continue
+		}
+		// only the endpoint keys used for the backend status are considered, because the
+		// cluster-wide keys, e.g. default_priority::max_connections, have the same form
+		address, key, value := fields[1], fields[2], fields[3]
+		switch key {
+		case "hostname":
+			names[address] = value
+		case "health_flags":
+			flags[address] = value
+		default:
+			continue
+		}
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	servers := []BackendServer{}
+	for _, address := range addresses {
+		name, ok := names[address]
+		if !ok || name == "" {
+			return nil, errors.Errorf("invalid envoy clusters: missing hostname for endpoint %s", address)
+		}
+		status := "UP"
+		if f := flags[address]; f != "healthy" {
+			status = strings.TrimSpace("DOWN " + f)
+		}
+		servers = append(servers, BackendServer{Name: name, Status: status})
+	}
+	return servers, nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// Implementation defines a load balancer implementation
+type Implementation string
+
+const (
+	// HAProxy is the HAProxy load balancer implementation
+	HAProxy Implementation = "haproxy"
+	// Nginx is the nginx load balancer implementation, using the nginx stream module
+	Nginx Implementation = "nginx"
+	// Envoy is the envoy load balancer implementation, using the envoy TCP proxy
+	Envoy Implementation = "envoy"
+
+	// DefaultImplementation is the load balancer implementation used when none is selected
+	DefaultImplementation = HAProxy
+)
+
+// Implementations lists the supported load balancer implementations
+var Implementations = []Implementation{HAProxy, Nginx, Envoy}
+
+// ParseImplementation returns the load balancer implementation with the given name;
+// if the name is empty, the default implementation is returned
+func ParseImplementation(name string) (Implementation, error) {
+	if name == "" {
+		return DefaultImplementation, nil
+	}
+	for _, i := range Implementations {
+		if Implementation(name) == i {
+			return i, nil
+		}
+	}
+	names := []string{}
+	for _, i := range Implementations {
+		names = append(names, string(i))
+	}
+	return "", errors.Errorf("invalid load balancer implementation %q. Use one of [%s]", name, strings.Join(names, ", "))
+}
+
+// RunFunc executes a command in the load balancer container, and returns the command output
+type RunFunc func(command string, args ...string) ([]string, error)
+
+// Provider generates the config for a load balancer implementation, and knows how to operate it
+type Provider interface {
+	// Image returns the image:tag of the load balancer container
+	Image() string
+
+	// ConfigPath returns the path of the config file in the load balancer container
+	ConfigPath() string
+
+	// Config returns the load balancer config generated from config data
+	Config(data *ConfigData) (string, error)
+
+	// ValidateCommand returns the command that validates the config file in the load balancer container
+	ValidateCommand() []string
+
+	// GracefulReload returns true if the load balancer reloads the config file on SIGHUP without dropping
+	// existing connections; otherwise the load balancer container must be restarted for using a new config
+	GracefulReload() bool
+
+	// Backends returns the status of the API server backends, using run for executing commands
+	// in the load balancer container
+	Backends(run RunFunc) ([]BackendServer, error)
+}
+
+// NewProvider returns the Provider for a load balancer implementation
+func NewProvider(implementation Implementation) (Provider, error) {
+	switch implementation {
+	case HAProxy:
+		return haproxyProvider{}, nil
+	case Nginx:
+		return nginxProvider{}, nil
+	case Envoy:
+		return envoyProvider{}, nil
+	}
+	return nil, errors.Errorf("unknown load balancer implementation %q", implementation)
+}
+
+// haproxyProvider implements Provider for HAProxy
+type haproxyProvider struct{}
+
+func (haproxyProvider) Image() string { return constants.LoadBalancerImage }
+
+func (haproxyProvider) ConfigPath() string { return constants.LoadBalancerConfigPath }
+
+func (haproxyProvider) Config(data *ConfigData) (string, error) { return Config(data) }
+
+func (haproxyProvider) ValidateCommand() []string {
+	return []string{"haproxy", "-c", "-q", "-f", constants.LoadBalancerConfigPath}
+}
+
+// GracefulReload returns true, because the haproxy image handles SIGHUP as a graceful reload
+func (haproxyProvider) GracefulReload() bool { return true }
+
+// Backends reads the HAProxy stats, reporting the status of the HAProxy health checks
+func (haproxyProvider) Backends(run RunFunc) ([]BackendServer, error) {
+	lines, err := run("wget", "-q", "-O", "-", StatsURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the HAProxy stats")
+	}
+	return ParseBackendServers(lines)
+}
+
+// executeTemplate executes a load balancer config template with the given config data
+func executeTemplate(name, configTemplate string, funcs template.FuncMap, data *ConfigData) (string, error) {
+	t, err := template.New(name).Funcs(funcs).Parse(configTemplate)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse config template")
+	}
+	// execute the template
+	var buff bytes.Buffer
+	err = t.Execute(&buff, data)
+	if err != nil {
+		return "", errors.Wrap(err, "error executing config template")
+	}
+	return buff.String(), nil
+}
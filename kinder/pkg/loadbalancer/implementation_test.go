@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImplementation(t *testing.T) {
+	tests := []struct {
+		name          string
+		expected      Implementation
+		expectedError bool
+	}{
+		{name: "", expected: HAProxy},
+		{name: "haproxy", expected: HAProxy},
+		{name: "nginx", expected: Nginx},
+		{name: "envoy", expected: Envoy},
+		{name: "traefik", expectedError: true},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			implementation, err := ParseImplementation(rt.name)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error %t, got %v", rt.expectedError, err)
+			}
+			if implementation != rt.expected {
+				t.Errorf("expected %q, got %q", rt.expected, implementation)
+			}
+		})
+	}
+}
+
+func TestProviderConfig(t *testing.T) {
+	data := &ConfigData{
+		ControlPlanePort: 6443,
+		BackendServers: map[string]string{
+			"kind-control-plane-1": "172.17.0.2:6443",
+			"kind-control-plane-2": "172.17.0.3:6443",
+		},
+	}
+	tests := []struct {
+		implementation Implementation
+		expected       []string
+	}{
+		{
+			implementation: HAProxy,
+			expected:       []string{"server kind-control-plane-1 172.17.0.2:6443", "server kind-control-plane-2 172.17.0.3:6443"},
+		},
+		{
+			implementation: Nginx,
+			expected:       []string{"server 172.17.0.2:6443; # kind-control-plane-1", "server 172.17.0.3:6443; # kind-control-plane-2", "listen 6443;"},
+		},
+		{
+			implementation: Envoy,
+			expected:       []string{"hostname: kind-control-plane-1", `address: "172.17.0.3", port_value: 6443`, `address: "0.0.0.0", port_value: 6443`},
+		},
+	}
+	for _, rt := range tests {
+		t.Run(string(rt.implementation), func(t *testing.T) {
+			provider, err := NewProvider(rt.implementation)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			config, err := provider.Config(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, e := range rt.expected {
+				if !strings.Contains(config, e) {
+					t.Errorf("expected config to contain %q, got:\n%s", e, config)
+				}
+			}
+		})
+	}
+}
+
+func TestParseNginxBackends(t *testing.T) {
+	config, err := nginxProvider{}.Config(&ConfigData{
+		ControlPlanePort: 6443,
+		BackendServers: map[string]string{
+			"kind-control-plane-1": "[fd00::2]:6443",
+			"kind-control-plane-2": "[fd00::3]:6443",
+		},
+		IPv6: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	backends, err := parseNginxBackends(strings.Split(config, "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []nginxBackend{
+		{name: "kind-control-plane-1", host: "fd00::2", port: "6443"},
+		{name: "kind-control-plane-2", host: "fd00::3", port: "6443"},
+	}
+	if !reflect.DeepEqual(backends, expected) {
+		t.Errorf("expected %v, got %v", expected, backends)
+	}
+
+	if _, err := parseNginxBackends([]string{"    server fd00::2:6443; # kind-control-plane-1"}); err == nil {
+		t.Error("expected error for invalid address, got nil")
+	}
+}
+
+func TestNginxConfigLoaded(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []string
+		expected      bool
+		expectedError bool
+	}{
+		{
+			name:     "workers started after the config change",
+			lines:    []string{"config 1700000100", "btime 1700000000", "worker 10000", "worker 10050"},
+			expected: true,
+		},
+		{
+			name:  "worker started before the config change",
+			lines: []string{"config 1700000100", "btime 1700000000", "worker 10000", "worker 9900"},
+		},
+		{
+			name:  "no workers",
+			lines: []string{"config 1700000100", "btime 1700000000"},
+		},
+		{
+			name:          "missing config time",
+			lines:         []string{"btime 1700000000", "worker 10000"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid value",
+			lines:         []string{"config 1700000100", "btime 1700000000", "worker x"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loaded, err := nginxConfigLoaded(test.lines)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, found %v", test.expectedError, err)
+			}
+			if loaded != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, loaded)
+			}
+		})
+	}
+}
+
+func TestParseEnvoyClusters(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []string
+		expected      []BackendServer
+		expectedError bool
+	}{
+		{
+			name: "backends",
+			lines: []string{
+				"HTTP/1.0 200 OK",
+				"content-type: text/plain; charset=UTF-8",
+				"",
+				"kube-apiservers::observability_name::kube-apiservers",
+				"kube-apiservers::default_priority::max_connections::1024",
+				"kube-apiservers::172.17.0.2:6443::cx_active::1",
+				"kube-apiservers::172.17.0.2:6443::hostname::kind-control-plane-1",
+				"kube-apiservers::172.17.0.2:6443::health_flags::healthy",
+				"kube-apiservers::172.17.0.3:6443::hostname::kind-control-plane-2",
+				"kube-apiservers::172.17.0.3:6443::health_flags::/failed_active_hc",
+			},
+			expected: []BackendServer{
+				{Name: "kind-control-plane-1", Status: "UP"},
+				{Name: "kind-control-plane-2", Status: "DOWN /failed_active_hc"},
+			},
+		},
+		{
+			name:     "no backends",
+			lines:    []string{"HTTP/1.0 200 OK", ""},
+			expected: []BackendServer{},
+		},
+		{
+			name: "missing hostname",
+			lines: []string{
+				"kube-apiservers::172.17.0.2:6443::health_flags::healthy",
+			},
+			expectedError: true,
+		},
+	}
+	for _, rt := range tests {
+		t.Run(rt.name, func(t *testing.T) {
+			servers, err := parseEnvoyClusters(rt.lines)
+			if (err != nil) != rt.expectedError {
+				t.Fatalf("expected error %t, got %v", rt.expectedError, err)
+			}
+			if !rt.expectedError && !reflect.DeepEqual(servers, rt.expected) {
+				t.Errorf("expected %v, got %v", rt.expected, servers)
+			}
+		})
+	}
+}
@@ -16,13 +16,6 @@ limitations under the License.
 
 package loadbalancer
 
-import (
-	"bytes"
-	"text/template"
-
-	"github.com/pkg/errors"
-)
-
 // ConfigData is supplied to the loadbalancer config template
 type ConfigData struct {
 	ControlPlanePort int
@@ -30,7 +23,7 @@ type ConfigData struct {
 	IPv6             bool
 }
 
-// DefaultConfigTemplate is the loadbalancer config template
+// DefaultConfigTemplate is the HAProxy loadbalancer config template
 const DefaultConfigTemplate = `# generated by kind
 global
   log /dev/log local0
@@ -46,6 +39,13 @@ defaults
   timeout client 50000
   timeout server 50000
 
+# stats in CSV format are served on the loopback interface only, see StatsURL
+listen stats
+  bind 127.0.0.1:8404
+  mode http
+  stats enable
+  stats uri /stats
+
 frontend control-plane
   bind *:{{ .ControlPlanePort }}
   {{ if .IPv6 -}}
@@ -64,15 +64,5 @@ backend kube-apiservers
 // Config returns a kubeadm config generated from config data, in particular
 // the kubernetes version
 func Config(data *ConfigData) (config string, err error) {
-	t, err := template.New("loadbalancer-config").Parse(DefaultConfigTemplate)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to parse config template")
-	}
-	// execute the template
-	var buff bytes.Buffer
-	err = t.Execute(&buff, data)
-	if err != nil {
-		return "", errors.Wrap(err, "error executing config template")
-	}
-	return buff.String(), nil
+	return executeTemplate("loadbalancer-config", DefaultConfigTemplate, nil, data)
 }
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/kubeadm/kinder/pkg/constants"
+)
+
+// nginxConfigTemplate is the nginx loadbalancer config template; each backend server
+// is followed by a comment with the server name, see parseNginxBackends
+const nginxConfigTemplate = `# generated by kinder
+events {
+  worker_connections 1024;
+}
+
+stream {
+  upstream kube-apiservers {
+    {{- range $server, $address := .BackendServers }}
+    server {{ $address }}; # {{ $server }}
+    {{- end }}
+  }
+
+  server {
+    listen {{ .ControlPlanePort }};
+    {{- if .IPv6 }}
+    listen [::]:{{ .ControlPlanePort }};
+    {{- end }}
+    proxy_connect_timeout 5s;
+    proxy_pass kube-apiservers;
+  }
+}
+`
+
+// nginxProvider implements Provider for nginx
+type nginxProvider struct{}
+
+func (nginxProvider) Image() string { return constants.NginxLoadBalancerImage }
+
+func (nginxProvider) ConfigPath() string { return constants.NginxLoadBalancerConfigPath }
+
+func (nginxProvider) Config(data *ConfigData) (string, error) {
+	return executeTemplate("nginx-config", nginxConfigTemplate, nil, data)
+}
+
+func (nginxProvider) ValidateCommand() []string {
+	return []string{"nginx", "-t", "-q", "-c", constants.NginxLoadBalancerConfigPath}
+}
+
+// GracefulReload returns true, because the nginx master process handles SIGHUP as a graceful reload
+func (nginxProvider) GracefulReload() bool { return true }
+
+// nginxWorkersScript prints the modification time of the nginx config, the boot time and the start time of the
+// nginx worker processes serving requests, so it is possible to check that the running process loaded the config;
+// workers of a previous config are renamed "nginx: worker process is shutting down" on reload, so they are skipped
+const nginxWorkersScript = `echo "config $(stat -c %Y ` + constants.NginxLoadBalancerConfigPath + `)"
+awk '/^btime/ {print "btime", $2}' /proc/stat
+for p in /proc/[0-9]*; do
+  if [ "$(tr '\0' ' ' < $p/cmdline 2>/dev/null)" = "nginx: worker process " ]; then
+    awk '{sub(/.*\) /, ""); print "worker", $20}' $p/stat
+  fi
+done`
+
+// clockTicks defines the number of clock ticks per second used for process start times in /proc/<pid>/stat
+const clockTicks = 100
+
+// Backends probes the backend servers defined in the nginx config with a TCP connect from the
+// load balancer container, because nginx open source doesn't expose the status of the backends;
+// the config is used only once the running nginx workers were started after the last config change,
+// otherwise the backends of a config not yet reloaded would be reported
+func (nginxProvider) Backends(run RunFunc) ([]BackendServer, error) {
+	workers, err := run("sh", "-c", nginxWorkersScript)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the nginx workers")
+	}
+	loaded, err := nginxConfigLoaded(workers)
+	if err != nil {
+		return nil, err
+	}
+	if !loaded {
+		return nil, errors.New("the running nginx process has not loaded the current config yet")
+	}
+
+	lines, err := run("cat", constants.NginxLoadBalancerConfigPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the nginx config")
+	}
+	servers, err := parseNginxBackends(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	backends := []BackendServer{}
+	for _, s := range servers {
+		status := "UP"
+		if _, err := run("nc", "-z", "-w", "2", s.host, s.port); err != nil {
+			status = "DOWN"
+		}
+		backends = append(backends, BackendServer{Name: s.name, Status: status})
+	}
+	return backends, nil
+}
+
+// nginxConfigLoaded parses the output of nginxWorkersScript, and returns true if there are nginx workers and all of
+// them were started after the last modification of the config
+func nginxConfigLoaded(lines []string) (bool, error) {
+	var configTime, bootTime int64 = -1, -1
+	var workerTicks []int64
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid nginx workers info %q", line)
+		}
+		switch fields[0] {
+		case "config":
+			configTime = value
+		case "btime":
+			bootTime = value
+		case "worker":
+			workerTicks = append(workerTicks, value)
+		}
+	}
+	if configTime < 0 || bootTime < 0 {
+		return false, errors.New("invalid nginx workers info: missing the config modification time or the boot time")
+	}
+
+	if len(workerTicks) == 0 {
+		return false, nil
+	}
+	for _, ticks := range workerTicks {
+		if bootTime+ticks/clockTicks < configTime {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// nginxBackend defines a backend server in the nginx config
+type nginxBackend struct {
+	name string
+	host string
+	port string
+}
+
+// nginxServerRegex matches the backend server lines generated by nginxConfigTemplate
+var nginxServerRegex = regexp.MustCompile(`^\s*server\s+(\S+);\s*#\s*(\S+)\s*$`)
+
+// parseNginxBackends parses the backend servers from a nginx config generated by nginxConfigTemplate
+func parseNginxBackends(config []string) ([]nginxBackend, error) {
+	backends := []nginxBackend{}
+	for _, line := range config {
+		m := nginxServerRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		host, port, err := net.SplitHostPort(m[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid nginx config: invalid address for backend server %s", m[2])
+		}
+		backends = append(backends, nginxBackend{name: m[2], host: host, port: port})
+	}
+	return backends, nil
+}
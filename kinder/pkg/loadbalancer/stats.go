@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"encoding/csv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StatsURL is the URL of the HAProxy stats in CSV format, as defined in DefaultConfigTemplate;
+	// it is reachable only from inside the load balancer container
+	StatsURL = "http://127.0.0.1:8404/stats;csv"
+
+	// backendName is the name of the backend for the API servers, as defined in DefaultConfigTemplate;
+	// the same name is used for the nginx upstream and for the envoy cluster
+	backendName = "kube-apiservers"
+)
+
+// BackendServer defines the status of an API server backend, as reported by the load balancer health checks;
+// Status is e.g. UP, DOWN, MAINT, or UP 1/3 or DOWN 1/2 while transitioning with HAProxy
+type BackendServer struct {
+	Name   string
+	Status string
+}
+
+// IsUp returns true if the backend server is receiving traffic
+func (s BackendServer) IsUp() bool {
+	return strings.HasPrefix(s.Status, "UP")
+}
+
+// ParseBackendServers parses the HAProxy stats in CSV format, and returns the API server backends
+func ParseBackendServers(stats []string) ([]BackendServer, error) {
+	if len(stats) == 0 || !strings.HasPrefix(stats[0], "# ") {
+		return nil, errors.New("invalid HAProxy stats: missing header")
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(strings.Join(stats, "\n"), "# ")))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid HAProxy stats")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, name := range []string{"pxname", "svname", "status"} {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.Errorf("invalid HAProxy stats: missing %s column", name)
+		}
+	}
+
+	servers := []BackendServer{}
+	for _, record := range records[1:] {
+		if len(record) <= columns["status"] || record[columns["pxname"]] != backendName {
+			continue
+		}
+		// skips the aggregated backend status
+		if name := record[columns["svname"]]; name != "BACKEND" {
+			servers = append(servers, BackendServer{Name: name, Status: record[columns["status"]]})
+		}
+	}
+	return servers, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBackendServers(t *testing.T) {
+	tests := []struct {
+		name          string
+		stats         []string
+		expected      []BackendServer
+		expectedError bool
+	}{
+		{
+			name: "backends",
+			stats: []string{
+				"# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,",
+				"stats,FRONTEND,,,1,1,2000,1,0,0,0,0,0,,,,,OPEN,,",
+				"control-plane,FRONTEND,,,0,2,2000,10,0,0,0,0,0,,,,,OPEN,,",
+				"kube-apiservers,kind-control-plane-1,0,0,0,1,,5,0,0,,0,,0,0,0,0,UP,1,",
+				"kube-apiservers,kind-control-plane-2,0,0,0,1,,5,0,0,,0,,0,0,0,0,DOWN 1/2,1,",
+				"kube-apiservers,BACKEND,0,0,0,2,200,10,0,0,0,0,,0,0,0,0,UP,1,",
+			},
+			expected: []BackendServer{
+				{Name: "kind-control-plane-1", Status: "UP"},
+				{Name: "kind-control-plane-2", Status: "DOWN 1/2"},
+			},
+		},
+		{
+			name:          "missing header",
+			stats:         []string{"kube-apiservers,kind-control-plane-1,0,UP"},
+			expectedError: true,
+		},
+		{
+			name:          "missing status column",
+			stats:         []string{"# pxname,svname", "kube-apiservers,kind-control-plane-1"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			servers, err := ParseBackendServers(test.stats)
+			if (err != nil) != test.expectedError {
+				t.Fatalf("expected error: %v, got: %v", test.expectedError, err)
+			}
+			if !test.expectedError && !reflect.DeepEqual(servers, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, servers)
+			}
+			for _, s := range servers {
+				if s.IsUp() != (s.Status == "UP") {
+					t.Errorf("unexpected IsUp for status %q", s.Status)
+				}
+			}
+		})
+	}
+}